@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	maxActivity        = 50 // Rule changes kept in the activity trail
+	recentActivityRows = 5  // Rule changes the rules pane shows
+)
+
+// ruleChange is a rule as it was before a toggle and after it, with the
+// files the toggle moved
+type ruleChange struct {
+	pattern       string
+	before, after FilterState // FilterNone when there was or is no rule
+	impact        toggleImpact
+}
+
+// fileCount formats n files, or a single file
+func fileCount(n int) string {
+	if n == 1 {
+		return "1 file"
+	}
+	return formatCount(n) + " files"
+}
+
+// String summarizes the change in one line, e.g.
+// "added `- TV/Show/**` — 1,024 files, 48.0 GiB now excluded"
+func (c ruleChange) String() string {
+	var action string
+	switch {
+	case c.before == FilterNone && c.after == FilterNone:
+		action = "no rule for " + c.pattern
+	case c.before == FilterNone:
+		action = "added `" + FilterRule{Pattern: c.pattern, State: c.after}.String() + "`"
+	case c.after == FilterNone:
+		action = "removed `" + FilterRule{Pattern: c.pattern, State: c.before}.String() + "`"
+	default:
+		action = "changed to `" + FilterRule{Pattern: c.pattern, State: c.after}.String() + "`"
+	}
+
+	var effects []string
+	if c.impact.excludedFiles > 0 {
+		effects = append(effects, fmt.Sprintf("%s, %s now excluded", fileCount(c.impact.excludedFiles), formatSize(c.impact.excludedSize)))
+	}
+	if c.impact.includedFiles > 0 {
+		effects = append(effects, fmt.Sprintf("%s, %s included again", fileCount(c.impact.includedFiles), formatSize(c.impact.includedSize)))
+	}
+	if len(effects) == 0 {
+		effects = append(effects, "no files change")
+	}
+	return action + " — " + strings.Join(effects, "; ")
+}
+
+// applyChange gives node state like setNodeFilter, then flashes what the
+// change did in the status line and adds it to the activity trail, so that
+// every keypress shows its effect
+func (m *Model) applyChange(node *FileNode, state FilterState) {
+	if m.filesFrom != nil {
+		m.setNodeFilter(node, state)
+		return
+	}
+	m.activateRootFor(node)
+	pattern := nodeRulePattern(node)
+	change := ruleChange{pattern: pattern, before: m.filters.state(pattern), after: state}
+	change.impact = m.previewChange(node, state)
+	m.setNodeFilter(node, state)
+	m.recordChange(change)
+	if state == FilterInclude {
+		m.checkParents(node)
+	}
+}
+
+// recordChange shows what a change did in the status line and adds it to
+// the activity trail
+func (m *Model) recordChange(change ruleChange) {
+	m.noteSizeDelta(change.impact)
+	summary := change.String()
+	m.statusMsg = summary
+	m.noteActivity(summary)
+}
+
+// noteActivity adds a line to the activity trail, dropping the oldest
+func (m *Model) noteActivity(summary string) {
+	m.activity = append(m.activity, summary)
+	if len(m.activity) > maxActivity {
+		m.activity = m.activity[len(m.activity)-maxActivity:]
+	}
+}
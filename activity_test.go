@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestToggleSummaries(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, _, _ := newGuardTestModel()
+	model.width, model.height = 120, 40
+	space := tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")}
+
+	// The cursor starts on the root, big is the next row
+	m := pressKeys(*model, runeKey("j"))
+	expected := []string{
+		"added `+ big/**` — no files change",
+		"changed to `- big/**` — 8 files, 8.0 KiB now excluded",
+		"removed `- big/**` — 8 files, 8.0 KiB included again",
+	}
+	for _, summary := range expected {
+		m = pressKeys(m, space)
+		if m.statusMsg != summary {
+			t.Errorf("Status = %q; want %q", m.statusMsg, summary)
+		}
+	}
+
+	// A single file
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyRight}, runeKey("j"), space, space)
+	if m.statusMsg != "changed to `- big/0.bin` — 1 file, 1.0 KiB now excluded" {
+		t.Errorf("Unexpected status for a file %q", m.statusMsg)
+	}
+
+	// The trail shows in the rules pane
+	if len(m.activity) != 5 || m.activity[0] != expected[0] {
+		t.Fatalf("Expected every toggle in the activity trail, got %q", m.activity)
+	}
+	m = pressKeys(m, runeKey("p"))
+	view := m.View()
+	if !strings.Contains(view, "Recent changes:") || !strings.Contains(view, "removed `- big/**`") {
+		t.Errorf("Expected the recent changes in the rules pane:\n%s", view)
+	}
+}
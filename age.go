@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ageUnits are the suffixes of rclone's --min-age and --max-age durations
+var ageUnits = map[string]time.Duration{
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+	"d":  24 * time.Hour,
+	"w":  7 * 24 * time.Hour,
+	"M":  30 * 24 * time.Hour,
+	"y":  365 * 24 * time.Hour,
+}
+
+// parseAge parses an age as rclone writes it, such as "1y", "6M" or
+// "2w3d". A number without a unit is in seconds, as in rclone.
+func parseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("empty age")
+	}
+	if n, err := strconv.ParseFloat(s, 64); err == nil && n > 0 {
+		return time.Duration(n * float64(time.Second)), nil
+	}
+	var age time.Duration
+	for s != "" {
+		i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+		if i <= 0 {
+			return 0, fmt.Errorf("invalid age %q", s)
+		}
+		n, err := strconv.ParseFloat(s[:i], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid age %q", s)
+		}
+		s = s[i:]
+		unit := strings.TrimLeft(s, "ymMwdhs")
+		unit = s[:len(s)-len(unit)]
+		d, ok := ageUnits[unit]
+		if !ok {
+			return 0, fmt.Errorf("unknown unit %q, use ms, s, m, h, d, w, M or y", unit)
+		}
+		age += time.Duration(n * float64(d))
+		s = s[len(unit):]
+	}
+	if age <= 0 {
+		return 0, fmt.Errorf("the age must be positive")
+	}
+	return age, nil
+}
+
+// ageLimits are the --max-age and --min-age of the transfer. rclone applies
+// them to every file it looks at, so they can't be limited to a directory
+// the way a filter rule can; they are passed along with the filter file
+// instead of being written into it.
+type ageLimits struct {
+	maxAge string // Leave out files older than this
+	minAge string // Leave out files newer than this
+}
+
+// flags returns the rclone flags of the limits that are set
+func (a ageLimits) flags() []string {
+	var flags []string
+	if a.maxAge != "" {
+		flags = append(flags, "--max-age", a.maxAge)
+	}
+	if a.minAge != "" {
+		flags = append(flags, "--min-age", a.minAge)
+	}
+	return flags
+}
+
+// excludes reports whether rclone leaves out the file at node because of
+// its age. Files without a modification time are kept.
+func (a ageLimits) excludes(node *FileNode, now time.Time) bool {
+	for _, limit := range []struct {
+		age   string
+		newer bool
+	}{{a.maxAge, false}, {a.minAge, true}} {
+		if age, err := parseAge(limit.age); err == nil && ageMatches(node, now.Add(-age), limit.newer) {
+			return true
+		}
+	}
+	return false
+}
+
+// agePrompt asks for the age of the files to leave out of the transfer
+type agePrompt struct {
+	dir   *FileNode // Selected directory, which the age can't be limited to
+	input string
+	newer bool // Leave out the files newer than the age rather than older
+}
+
+// ageMatches reports whether a file is on the excluded side of the cutoff.
+// Files without a modification time are kept.
+func ageMatches(node *FileNode, cutoff time.Time, newer bool) bool {
+	if node.ModTime.IsZero() {
+		return false
+	}
+	if newer {
+		return node.ModTime.After(cutoff)
+	}
+	return node.ModTime.Before(cutoff)
+}
+
+// countAgeExcluded counts the files below node that the rules let through
+// and the limits leave out
+func countAgeExcluded(node *FileNode, limits ageLimits, now time.Time) int {
+	count := 0
+	for _, child := range node.Children {
+		switch {
+		case child.Filter == FilterExclude:
+		case child.IsDir:
+			count += countAgeExcluded(child, limits, now)
+		case limits.excludes(child, now):
+			count++
+		}
+	}
+	return count
+}
+
+// startAgePrompt asks for the age of the files to leave out of the transfer
+func (m *Model) startAgePrompt() {
+	if m.filesFrom != nil {
+		m.statusMsg = "Age limits can't be used with --files-from"
+		return
+	}
+	m.agePrompt = &agePrompt{dir: m.selectedDir()}
+	m.openModal(modalAgePrompt)
+}
+
+// applyAgeLimit sets --max-age, or --min-age with newer, for the whole
+// transfer, or clears it when age is empty. rclone can't apply an age to
+// one directory, which the status line says when one other than the root
+// was selected.
+func (m *Model) applyAgeLimit(dir *FileNode, age string, newer bool) {
+	side, flag, limit, alone := "older", "--max-age", &m.ageLimits.maxAge, ageLimits{maxAge: age}
+	if newer {
+		side, flag, limit, alone = "newer", "--min-age", &m.ageLimits.minAge, ageLimits{minAge: age}
+	}
+	*limit = age
+	if age == "" {
+		m.statusMsg = "Cleared " + flag
+		return
+	}
+	files := 0
+	for _, top := range m.topLevelNodes() {
+		files += countAgeExcluded(top, alone, time.Now())
+	}
+	m.statusMsg = fmt.Sprintf("Set %s %s for the whole transfer, leaving out %s files %s than that",
+		flag, age, formatCount(files), side)
+	if dir != nil && dir.Path != rootPathFor(dir.Path) {
+		m.statusMsg += fmt.Sprintf("; rclone can't limit it to %s", dir.Name)
+	}
+}
+
+// updateAgePrompt handles typing the age of an age limit
+func (m Model) updateAgePrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := m.agePrompt
+	if used, cmd := m.editPrompt(&p.input, msg); used {
+		return m, cmd
+	}
+	switch msg.Type {
+	case tea.KeyEnter:
+		age := strings.Join(strings.Fields(p.input), "")
+		if _, err := parseAge(age); age != "" && err != nil {
+			return m, nil
+		}
+		m.agePrompt = nil
+		m.closeModal(modalAgePrompt)
+		m.applyAgeLimit(p.dir, age, p.newer)
+	case tea.KeyEsc:
+		m.agePrompt = nil
+		m.closeModal(modalAgePrompt)
+	case tea.KeyTab:
+		p.newer = !p.newer
+	}
+	return m, nil
+}
+
+// promptText is the status line while the age is being typed
+func (p *agePrompt) promptText() string {
+	side := "older"
+	if p.newer {
+		side = "newer"
+	}
+	text := fmt.Sprintf("Leave out files %s than: %s█ (e.g. 30d, 6M, 1y, for the whole transfer; empty clears it, Tab switches older/newer, Esc cancels)", side, p.input)
+	if p.dir != nil && p.dir.Path != rootPathFor(p.dir.Path) {
+		text += fmt.Sprintf(" rclone can't limit an age to %s", p.dir.Name)
+	}
+	if _, err := parseAge(p.input); strings.TrimSpace(p.input) != "" && err != nil {
+		text += " " + err.Error()
+	}
+	return text
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestParseAge(t *testing.T) {
+	for input, want := range map[string]time.Duration{
+		"1y":    365 * 24 * time.Hour,
+		"6M":    180 * 24 * time.Hour,
+		"2w3d":  17 * 24 * time.Hour,
+		"90m":   90 * time.Minute,
+		"1.5h":  90 * time.Minute,
+		"500ms": 500 * time.Millisecond,
+		"30":    30 * time.Second,
+	} {
+		if got, err := parseAge(input); err != nil || got != want {
+			t.Errorf("parseAge(%q) = %v, %v, want %v", input, got, err, want)
+		}
+	}
+	for _, input := range []string{"", "y", "1x", "1mo", "0d", "-1d"} {
+		if _, err := parseAge(input); err == nil {
+			t.Errorf("Expected an error for %q", input)
+		}
+	}
+}
+
+func TestAgeLimits(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/root"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	now := time.Now()
+	old, recent := now.AddDate(-2, 0, 0), now.AddDate(0, -1, 0)
+
+	root := &FileNode{Name: "root", Path: "/root", IsDir: true, Expanded: true}
+	photos := &FileNode{Name: "Photos", Path: "/root/Photos", IsDir: true, Parent: root}
+	photos.Children = []*FileNode{
+		{Name: "old.jpg", Path: "/root/Photos/old.jpg", Parent: photos, ModTime: old},
+		{Name: "new.jpg", Path: "/root/Photos/new.jpg", Parent: photos, ModTime: recent},
+		{Name: "gone.jpg", Path: "/root/Photos/gone.jpg", Parent: photos, ModTime: old, Filter: FilterExclude},
+		{Name: "unknown.jpg", Path: "/root/Photos/unknown.jpg", Parent: photos},
+	}
+	root.Children = []*FileNode{photos}
+
+	model := newTestModel()
+	model.root = root
+	model.updateVisibleNodes()
+
+	// The age is kept out of the filter file, and counted over the tree
+	model.applyAgeLimit(photos, "1y", false)
+	if model.filters.count() != 0 || model.ageLimits.maxAge != "1y" {
+		t.Errorf("Expected --max-age set without rules, got %v and %+v", model.filters.states(), model.ageLimits)
+	}
+	if want := "Set --max-age 1y for the whole transfer, leaving out 1 files older than that; rclone can't limit it to Photos"; model.statusMsg != want {
+		t.Errorf("Unexpected status %q", model.statusMsg)
+	}
+	model.applyAgeLimit(root, "3M", true)
+	if !strings.HasSuffix(model.statusMsg, "leaving out 1 files newer than that") || countAgeExcluded(root, model.ageLimits, now) != 2 {
+		t.Errorf("Expected new.jpg left out too, got %q", model.statusMsg)
+	}
+	if got := strings.Join(model.ageLimits.flags(), " "); got != "--max-age 1y --min-age 3M" {
+		t.Errorf("Unexpected flags %q", got)
+	}
+	if command := model.rcloneCommands()[0]; !strings.HasSuffix(command, " --max-age 1y --min-age 3M") {
+		t.Errorf("Expected the flags in the command, got %q", command)
+	}
+
+	model.applyAgeLimit(root, "", true)
+	if model.ageLimits.minAge != "" || model.statusMsg != "Cleared --min-age" {
+		t.Errorf("Expected --min-age cleared, got %+v %q", model.ageLimits, model.statusMsg)
+	}
+}
+
+func TestAgePrompt(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/root"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	root := &FileNode{Name: "root", Path: "/root", IsDir: true, Expanded: true}
+	docs := &FileNode{Name: "docs", Path: "/root/docs", IsDir: true, Parent: root, Expanded: true}
+	docs.Children = []*FileNode{{Name: "new.txt", Path: "/root/docs/new.txt", Parent: docs, ModTime: time.Now()}}
+	root.Children = []*FileNode{docs}
+
+	model := newTestModel()
+	model.root = root
+	model.updateVisibleNodes()
+	model.cursor = 1 // new.txt, in docs
+
+	m := pressKeys(*model, runeKey("a"), runeKey("2w"), tea.KeyMsg{Type: tea.KeySpace}, runeKey("1é"),
+		tea.KeyMsg{Type: tea.KeyBackspace})
+	if !m.modalOpen(modalAgePrompt) || !strings.Contains(m.promptText(), "Leave out files older than: 2w 1█") ||
+		!strings.Contains(m.promptText(), "rclone can't limit an age to docs") {
+		t.Fatalf("Expected the age prompt, got %q", m.promptText())
+	}
+	m = pressKeys(m, runeKey("d"), tea.KeyMsg{Type: tea.KeyTab}, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.modalOpen(modalAgePrompt) || m.ageLimits.minAge != "2w1d" || m.filters.count() != 0 {
+		t.Errorf("Expected --min-age 2w1d, got %+v", m.ageLimits)
+	}
+}
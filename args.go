@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// checkFilterFileArg checks a filter file given on the command line before
+// anything is scanned. A missing file is fine, it is created on save, as
+// long as the directory it goes in exists.
+func checkFilterFileArg(filename string) error {
+	if filename == "" || filename == stdioFilterFile {
+		return nil
+	}
+	info, err := os.Stat(filename)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		dir := filepath.Dir(filename)
+		if _, err := os.Stat(dir); err != nil {
+			return fmt.Errorf("%s can't be created, directory %s doesn't exist", filename, dir)
+		}
+		return nil
+	case err != nil:
+		return err
+	case info.IsDir():
+		return fmt.Errorf("%s is a directory, give a file such as %s", filename, filepath.Join(filename, "filter.txt"))
+	}
+
+	file, err := os.Open(filename)
+	if errors.Is(err, fs.ErrPermission) {
+		return fmt.Errorf("%s can't be read, check its permissions", filename)
+	}
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// checkDirArg checks a local directory given on the command line before the
+// scan starts
+func checkDirArg(dir string) error {
+	info, err := os.Stat(dir)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("directory %s doesn't exist", dir)
+	case errors.Is(err, fs.ErrPermission):
+		return fmt.Errorf("%s can't be opened, check its permissions", dir)
+	case err != nil:
+		return err
+	case !info.IsDir():
+		return fmt.Errorf("%s is a file, not a directory; pass filter files with -f", dir)
+	}
+	return nil
+}
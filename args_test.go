@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckArgs(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "filter.txt")
+	os.WriteFile(file, []byte("- a/**\n"), 0644)
+
+	for _, ok := range []string{file, filepath.Join(dir, "new.txt"), stdioFilterFile} {
+		if err := checkFilterFileArg(ok); err != nil {
+			t.Errorf("Expected %s to be accepted, got %v", ok, err)
+		}
+	}
+	tests := []struct {
+		filterFile string
+		want       string
+	}{
+		{dir, "is a directory"},
+		{filepath.Join(dir, "missing", "filter.txt"), "doesn't exist"},
+	}
+	for _, tt := range tests {
+		if err := checkFilterFileArg(tt.filterFile); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("checkFilterFileArg(%s) = %v, want an error saying %q", tt.filterFile, err, tt.want)
+		}
+	}
+
+	if err := checkDirArg(dir); err != nil {
+		t.Errorf("Expected the directory to be accepted, got %v", err)
+	}
+	if err := checkDirArg(file); err == nil || !strings.Contains(err.Error(), "is a file") {
+		t.Errorf("Expected a file to be refused as the directory, got %v", err)
+	}
+	if err := checkDirArg(filepath.Join(dir, "missing")); err == nil || !strings.Contains(err.Error(), "doesn't exist") {
+		t.Errorf("Expected a missing directory to be refused, got %v", err)
+	}
+}
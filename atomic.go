@@ -0,0 +1,62 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// writeFileAtomic replaces filename with what write produces. The content
+// goes to a temporary file next to it, which is synced and renamed over the
+// old file only once it is complete, so a crash or a full disk leaves the old
+// file as it was. A symlink is followed, and the file keeps its permissions.
+func writeFileAtomic(filename string, write func(io.Writer) error) (err error) {
+	if target, err := filepath.EvalSymlinks(filename); err == nil {
+		filename = target
+	}
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(filename); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	dir, base := filepath.Split(filename)
+	tmp, err := os.CreateTemp(dir, "."+base+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if err := write(tmp); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		return err
+	}
+	syncDir(filepath.Dir(filename))
+	return nil
+}
+
+// syncDir makes a rename in dir durable. Not every system can sync a
+// directory, and the rename has happened either way, so errors are ignored.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "filter.txt")
+	os.WriteFile(filename, []byte("- old/**\n"), 0600)
+
+	// A failed write leaves the old file and no temporary file behind
+	err := writeFileAtomic(filename, func(w io.Writer) error {
+		io.WriteString(w, "- half")
+		return errors.New("disk full")
+	})
+	if err == nil {
+		t.Fatalf("Expected the write error to be returned")
+	}
+	if data, _ := os.ReadFile(filename); string(data) != "- old/**\n" {
+		t.Errorf("Expected the old file to be kept, got %q", data)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Expected the temporary file to be removed, got %d files", len(entries))
+	}
+
+	// Writes through a symlink replace its target, keeping the permissions
+	link := filepath.Join(dir, "link.txt")
+	os.Symlink(filename, link)
+	if err := writeFileAtomic(link, func(w io.Writer) error {
+		_, err := io.WriteString(w, "- new/**\n")
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	info, _ := os.Lstat(link)
+	data, _ := os.ReadFile(filename)
+	if info.Mode()&os.ModeSymlink == 0 || string(data) != "- new/**\n" {
+		t.Errorf("Expected the target to be replaced, got %q", data)
+	}
+	if info, _ := os.Stat(filename); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the permissions to be kept, got %v", info.Mode().Perm())
+	}
+}
+
+func TestSaveErrorShown(t *testing.T) {
+	model := newTestModel()
+	model.filterFile = filepath.Join(t.TempDir(), "missing", "filter.txt")
+
+	updated, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("s")})
+	result := updated.(Model)
+	if result.saved || !strings.HasPrefix(result.statusMsg, "Save failed: ") {
+		t.Errorf("Expected the failed save in the status line, got %q", result.statusMsg)
+	}
+
+	// Saving on quit stays open to try again
+	result.openModal(modalSaveConfirm)
+	updated, cmd := result.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	if result = updated.(Model); cmd != nil || result.topModal() != modalNone || !strings.HasPrefix(result.statusMsg, "Save failed: ") {
+		t.Errorf("Expected the editor to stay open after a failed save, got %q", result.statusMsg)
+	}
+}
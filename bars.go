@@ -0,0 +1,52 @@
+package main
+
+import "strings"
+
+// barEighths are the blocks drawing the last cell of a bar, by eighths filled
+var barEighths = []rune(" ▏▎▍▌▋▊▉")
+
+const (
+	rowBarWidth      = 8  // Cells of the size bar in front of each row
+	progressBarWidth = 30 // Cells of the scan progress bar
+)
+
+// sizeBar draws value as a share of total in width cells, in eighths of a
+// cell, padded so that bars line up. Any non-zero value shows at least a
+// sliver.
+func sizeBar(value, total int64, width int) string {
+	if value <= 0 || total <= 0 {
+		return strings.Repeat(" ", width)
+	}
+	eighths := int(min(value, total) * int64(width*8) / total)
+	eighths = max(eighths, 1)
+
+	bar := strings.Repeat("█", eighths/8)
+	cells := eighths / 8
+	if eighths%8 > 0 {
+		bar += string(barEighths[eighths%8])
+		cells++
+	}
+	return bar + strings.Repeat(" ", width-cells)
+}
+
+// shownSize is the size shown for node: its estimate while it has one
+func (m *Model) shownSize(node *FileNode) int64 {
+	if est, ok := m.estimateFor(node); ok {
+		return est.size
+	}
+	if node.IsDir {
+		return node.TotalSize
+	}
+	return node.Size
+}
+
+// rowBar draws the share node has of the size of its directory
+func (m *Model) rowBar(node *FileNode) string {
+	if node.Skipped {
+		return sizeBar(0, 0, rowBarWidth)
+	}
+	if node.Parent == nil {
+		return sizeBar(1, 1, rowBarWidth)
+	}
+	return sizeBar(m.shownSize(node), m.shownSize(node.Parent), rowBarWidth)
+}
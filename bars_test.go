@@ -0,0 +1,32 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSizeBar(t *testing.T) {
+	tests := []struct {
+		value, total int64
+		want         string
+	}{
+		{0, 100, "    "},
+		{100, 100, "████"},
+		{50, 100, "██  "},
+		{1, 1000, "▏   "}, // Too small to see, but not nothing
+		{45, 100, "█▊  "},
+		{200, 100, "████"},
+	}
+	for _, tt := range tests {
+		if got := sizeBar(tt.value, tt.total, 4); got != tt.want {
+			t.Errorf("sizeBar(%d, %d) = %q, want %q", tt.value, tt.total, got, tt.want)
+		}
+	}
+
+	model, big, _ := newGuardTestModel()
+	model.root.TotalSize, big.TotalSize = 10, 8
+	model.sizeBars = true
+	if view := model.View(); !strings.Contains(view, "██████▍    ▶ [ ] big") {
+		t.Errorf("Expected big to fill 80%% of its bar, got:\n%s", view)
+	}
+}
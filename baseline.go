@@ -0,0 +1,62 @@
+package main
+
+// takeBaseline keeps a copy of each root's rules as they were loaded, so
+// that the states they gave can be compared with the edited ones
+func (m *Model) takeBaseline() {
+	m.baseline = make(map[string]*Model)
+	m.baselineStates = make(map[*FileNode]FilterState)
+	for _, top := range m.topLevelNodes() {
+		m.activateRootFor(top)
+		m.baseline[top.Path] = &Model{
+			filters:   m.filters.clone(),
+			filesFrom: m.filesFrom.clone(),
+		}
+	}
+	m.activateRootFor(m.topLevelNodes()[0])
+}
+
+// baselineState returns the state node had under the rules as loaded. The
+// loaded rules never change, so each node is evaluated once.
+func (m *Model) baselineState(node *FileNode) FilterState {
+	if state, ok := m.baselineStates[node]; ok {
+		return state
+	}
+	top := node
+	for top.Parent != nil {
+		top = top.Parent
+	}
+	eval := m.baseline[top.Path]
+	if eval == nil {
+		return node.Filter
+	}
+	state := eval.getEffectiveFilterWithMap(getFilterPath(node.Path))
+	m.baselineStates[node] = state
+	return state
+}
+
+// changedSinceLoad reports whether this session's edits changed whether
+// node is transferred. Unmatched nodes count as included, as for rclone.
+func (m *Model) changedSinceLoad(node *FileNode) bool {
+	if m.baseline == nil {
+		return false
+	}
+	return (m.baselineState(node) == FilterExclude) != (node.Filter == FilterExclude)
+}
+
+// toggleChangesView switches between the whole tree and only the entries
+// whose state changed since the rules were loaded
+func (m *Model) toggleChangesView() {
+	if m.viewMode == ViewChanged {
+		m.viewMode = ViewAll
+	} else {
+		m.viewMode = ViewChanged
+	}
+	m.updateVisibleNodes()
+	if m.viewMode == ViewChanged && !m.viewMatches[m.root] {
+		m.viewMode = ViewAll
+		m.updateVisibleNodes()
+		m.statusMsg = "Nothing changed since the rules were loaded"
+	}
+	m.cursor = max(0, min(m.cursor, len(m.visibleNodes)-1))
+	m.adjustScroll()
+}
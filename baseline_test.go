@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestChangesSinceLoad(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, big, small := newGuardTestModel()
+	model.filters.set("small/**", FilterExclude)
+	model.reapplyFiltersToTree(model.root)
+	model.takeBaseline()
+	big.Expanded = true
+	model.updateVisibleNodes()
+
+	updated, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("b")})
+	if result := updated.(Model); result.viewMode != ViewAll || !strings.Contains(result.statusMsg, "Nothing changed") {
+		t.Fatalf("Expected no changes view before any edit")
+	}
+
+	// An explicit include of an already transferred file changes nothing
+	model.setNodeFilter(big.Children[0], FilterInclude)
+	model.setNodeFilter(big.Children[1], FilterExclude)
+	model.setNodeFilter(small, FilterNone)
+	model.reapplyFiltersToTree(model.root)
+
+	updated, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("b")})
+	result := updated.(Model)
+	var shown []string
+	for _, node := range result.visibleNodes {
+		shown = append(shown, node.Name)
+	}
+	if got := strings.Join(shown, " "); got != "test big 1.bin small" {
+		t.Fatalf("Expected the changed entries with their parents, got %q", got)
+	}
+	if view := result.View(); !strings.Contains(view, "was "+currentGlyphs.Exclude) {
+		t.Errorf("Expected the old state of small in the view, got:\n%s", view)
+	}
+
+	updated, _ = result.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("b")})
+	if result = updated.(Model); result.viewMode != ViewAll || len(result.visibleNodes) != 11 {
+		t.Errorf("Expected b to show the whole tree again, got %d rows", len(result.visibleNodes))
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// maxCheckers caps the number of concurrent listings set with [ and ]
+const maxCheckers = 64
+
+// checkerLimit bounds the number of directories listed at once. It is shared
+// by the running scans, so that changing it at runtime applies to the scans
+// in flight: raising it lets waiting listings start right away, and lowering
+// it lets the running ones finish before others start.
+type checkerLimit struct {
+	mu     sync.Mutex
+	limit  int
+	active int
+	wake   chan struct{} // Closed and replaced whenever a slot may be free
+}
+
+func newCheckerLimit(n int) *checkerLimit {
+	return &checkerLimit{limit: max(1, n), wake: make(chan struct{})}
+}
+
+// acquire waits for a free slot, returning false if ctx is cancelled first
+func (l *checkerLimit) acquire(ctx context.Context) bool {
+	for {
+		l.mu.Lock()
+		if l.active < l.limit {
+			l.active++
+			l.mu.Unlock()
+			return true
+		}
+		wake := l.wake
+		l.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-wake:
+		}
+	}
+}
+
+func (l *checkerLimit) release() {
+	l.mu.Lock()
+	l.active--
+	l.wakeWaiters()
+	l.mu.Unlock()
+}
+
+func (l *checkerLimit) set(n int) {
+	l.mu.Lock()
+	l.limit = max(1, n)
+	l.wakeWaiters()
+	l.mu.Unlock()
+}
+
+// current returns the number of concurrent listings allowed
+func (l *checkerLimit) current() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+// wakeWaiters lets every waiting acquire check again; l.mu must be held
+func (l *checkerLimit) wakeWaiters() {
+	close(l.wake)
+	l.wake = make(chan struct{})
+}
+
+// setCheckers changes the number of concurrent listings, for the running
+// scans as well as later ones
+func (m *Model) setCheckers(n int) {
+	m.checkers = min(max(1, n), maxCheckers)
+	if m.checkerLimit != nil {
+		m.checkerLimit.set(m.checkers)
+	}
+	m.statusMsg = fmt.Sprintf("Scanning with %d checkers ([ fewer, ] more)", m.checkers)
+}
+
+// scanRate measures how fast the running scan lists directories and files
+type scanRate struct {
+	at          time.Time // When the last sample was taken
+	dirs, files int64     // Counts at the last sample
+	dirsPerSec  float64
+	filesPerSec float64
+}
+
+// rateSampleInterval is how long progress is collected before the rate is
+// updated, so that it doesn't jump with every message
+const rateSampleInterval = time.Second
+
+// sample updates the rate from the counts of a progress message. A scanner
+// starting over, as for a rescan, restarts the measurement.
+func (r *scanRate) sample(now time.Time, dirs, files int64) {
+	if r.at.IsZero() || dirs < r.dirs {
+		*r = scanRate{at: now, dirs: dirs, files: files}
+		return
+	}
+	elapsed := now.Sub(r.at)
+	if elapsed < rateSampleInterval {
+		return
+	}
+	r.dirsPerSec = float64(dirs-r.dirs) / elapsed.Seconds()
+	r.filesPerSec = float64(files-r.files) / elapsed.Seconds()
+	r.at, r.dirs, r.files = now, dirs, files
+}
+
+// String describes the rate, or nothing before the first measurement
+func (r scanRate) String() string {
+	if r.dirsPerSec == 0 && r.filesPerSec == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s dirs/s, %s files/s", formatCount(int(r.dirsPerSec+0.5)), formatCount(int(r.filesPerSec+0.5)))
+}
+
+// rescanRate is the throughput shown in the header while a rescan runs
+// below the tree, as the loading screen only shows for full scans
+func (m *Model) rescanRate() string {
+	for _, j := range m.jobs {
+		if j.kind == JobRescan && j.status == JobRunning {
+			return m.scanRate.String()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestCheckerLimit(t *testing.T) {
+	l := newCheckerLimit(1)
+	ctx := context.Background()
+	if !l.acquire(ctx) {
+		t.Fatal("Expected a free slot")
+	}
+
+	acquired := make(chan bool)
+	go func() { acquired <- l.acquire(ctx) }()
+	select {
+	case <-acquired:
+		t.Fatal("Expected the second listing to wait for a slot")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// Raising the limit lets the waiting listing start
+	l.set(2)
+	select {
+	case ok := <-acquired:
+		if !ok {
+			t.Fatal("Expected the waiting listing to get a slot")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected raising the limit to start the waiting listing")
+	}
+
+	// Lowering it makes new listings wait until enough have finished
+	l.set(1)
+	cancelled, cancel := context.WithCancel(ctx)
+	go func() { acquired <- l.acquire(cancelled) }()
+	l.release()
+	select {
+	case <-acquired:
+		t.Fatal("Expected the listing to wait while the limit is still used up")
+	case <-time.After(20 * time.Millisecond):
+	}
+	cancel()
+	if ok := <-acquired; ok {
+		t.Errorf("Expected a cancelled listing not to get a slot")
+	}
+}
+
+func TestCheckersKeys(t *testing.T) {
+	model, _, _ := newGuardTestModel()
+	model.checkers = 4
+	model.checkerLimit = newCheckerLimit(4)
+
+	var m tea.Model = *model
+	for _, key := range []string{"]", "]", "["} {
+		m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+	}
+	if got := m.(Model); got.checkers != 5 || got.checkerLimit.limit != 5 {
+		t.Errorf("Expected 5 checkers, got %d with a limit of %d", got.checkers, got.checkerLimit.limit)
+	}
+	for range maxCheckers {
+		m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("[")})
+	}
+	if got := m.(Model); got.checkers != 1 {
+		t.Errorf("Expected at least one checker, got %d", got.checkers)
+	}
+}
+
+func TestScanRate(t *testing.T) {
+	var r scanRate
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	r.sample(start, 0, 0)
+	r.sample(start.Add(500*time.Millisecond), 50, 1000)
+	if r.String() != "" {
+		t.Errorf("Expected no rate before a full interval, got %q", r.String())
+	}
+	r.sample(start.Add(2*time.Second), 200, 4000)
+	if r.String() != "100 dirs/s, 2,000 files/s" {
+		t.Errorf("Unexpected rate %q", r.String())
+	}
+
+	// A rescan starts counting from zero again
+	r.sample(start.Add(3*time.Second), 10, 10)
+	if r.dirs != 10 || r.String() != "" {
+		t.Errorf("Expected the measurement to restart, got %+v", r)
+	}
+}
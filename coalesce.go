@@ -0,0 +1,167 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// redundantRule is a rule for a single path that a broader rule of the same
+// sign already implies, such as "Photos/2024/**" below "Photos/**"
+type redundantRule struct {
+	top       *FileNode
+	pattern   string
+	state     FilterState
+	coveredBy string
+}
+
+// literalAnchor returns the filter path a rule generated for a node stands
+// for, or false for patterns written with wildcards
+func literalAnchor(pattern string) (string, bool) {
+	base := strings.TrimPrefix(strings.TrimSuffix(pattern, "/**"), "/")
+	if base == "" || escapeGlob(unescapeGlob(base)) != base {
+		return "", false
+	}
+	return "/" + unescapeGlob(base), true
+}
+
+// findRedundantRules lists the rules that can go without changing the state
+// of anything in the tree, because a broader rule of the same sign covers
+// them. Rules are dropped one at a time from the most specific, so that a
+// chain such as a/**, a/b/**, a/b/c/** comes down to a/**.
+func (m *Model) findRedundantRules() []redundantRule {
+	if m.filesFrom != nil || m.root == nil {
+		return nil
+	}
+	var redundant []redundantRule
+	for _, top := range m.topLevelNodes() {
+		m.activateRootFor(top)
+		byPath := make(map[string]*FileNode)
+		for _, node := range collectNodes(top, nil) {
+			byPath[getFilterPath(node.Path)] = node
+		}
+
+		work := m.filters.clone()
+		patterns := slices.SortedFunc(maps.Keys(work.states()), func(a, b string) int {
+			return cmp.Or(cmp.Compare(len(b), len(a)), strings.Compare(a, b))
+		})
+		for _, pattern := range patterns {
+			anchor, ok := literalAnchor(pattern)
+			if !ok || m.keptRedundant[pattern] {
+				continue
+			}
+			state, kept := work.state(pattern), work.clone()
+			work.remove(pattern)
+			if cover, ok := coveredWithout(kept, work, anchor, byPath[anchor]); ok {
+				redundant = append(redundant, redundantRule{top: top, pattern: pattern, state: state, coveredBy: cover})
+			} else {
+				work = kept
+			}
+		}
+	}
+	return redundant
+}
+
+// coveredWithout reports whether the rules without a pattern give anchor
+// and everything below its node the same state as the rules with it, and
+// returns the rule that takes over at anchor
+func coveredWithout(with, without *FilterDocument, anchor string, node *FileNode) (string, bool) {
+	_, state := with.firstMatch(anchor)
+	cover, coverState := without.firstMatch(anchor)
+	if cover == "" || coverState != state {
+		return "", false
+	}
+	if node == nil {
+		return cover, true
+	}
+	for _, below := range collectNodes(node, nil)[1:] {
+		path := getFilterPath(below.Path)
+		_, before := with.firstMatch(path)
+		if _, after := without.firstMatch(path); before != after {
+			return "", false
+		}
+	}
+	return cover, true
+}
+
+// coalesceRules removes the redundant rules from their filter sets
+func (m *Model) coalesceRules(rules []redundantRule) {
+	for _, rule := range rules {
+		m.activateRootFor(rule.top)
+		m.deleteRule(rule.pattern)
+	}
+}
+
+// keepRedundantRules stops offering to coalesce the rules
+func (m *Model) keepRedundantRules(rules []redundantRule) {
+	if m.keptRedundant == nil {
+		m.keptRedundant = make(map[string]bool)
+	}
+	for _, rule := range rules {
+		m.keptRedundant[rule.pattern] = true
+	}
+}
+
+// updateCoalescePane handles keys while the redundant rules are offered for
+// removal before saving
+func (m Model) updateCoalescePane(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "y", "Y", "enter":
+		m.closeModal(modalCoalesce)
+		m.coalesceRules(m.redundant)
+		count := len(m.redundant)
+		m.redundant = nil
+		return m, m.finishSave(fmt.Sprintf(", %d redundant rules removed", count))
+	case "n", "N":
+		m.closeModal(modalCoalesce)
+		m.keepRedundantRules(m.redundant)
+		m.redundant = nil
+		return m, m.finishSave("")
+	case "esc", "q":
+		m.closeModal(modalCoalesce)
+		m.redundant = nil
+		m.quitAfterSave = false
+	case "ctrl+c":
+		m.cancel()
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m Model) renderCoalesce() string {
+	paneStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(currentTheme.Border).
+		Padding(1, 2)
+
+	var lines []string
+	for _, rule := range m.redundant {
+		line := FilterRule{Pattern: rule.pattern, State: rule.state}.String()
+		if m.multiRoot() {
+			line = rule.top.Name + ": " + line
+		}
+		lines = append(lines, fmt.Sprintf("%s  (implied by %s)", line, rule.coveredBy))
+	}
+
+	// Keep the list within the screen
+	visibleHeight := m.height - 12
+	if visibleHeight < 5 {
+		visibleHeight = 20
+	}
+	if len(lines) > visibleHeight {
+		lines = append(lines[:visibleHeight-1], fmt.Sprintf("... and %d more", len(lines)-visibleHeight+1))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d rules are implied by broader rules of the same sign:\n\n", len(m.redundant))
+	b.WriteString(strings.Join(lines, "\n"))
+	b.WriteString("\n\nRemoving them keeps the filter file minimal and changes nothing else.\n")
+	b.WriteString("y/Enter remove them and save, n save them as they are, Esc cancel")
+
+	return m.placePane(paneStyle, b.String())
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestFindRedundantRules(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, _, _ := newGuardTestModel()
+	model.filters = testDocument(nil, map[string]FilterState{
+		"big/**":      FilterExclude,
+		"big/0.bin":   FilterExclude, // Implied by big/**
+		"big/1.bin":   FilterInclude,
+		"small/**":    FilterInclude,
+		"small/8.bin": FilterExclude,
+		"*.tmp":       FilterExclude,
+	})
+	redundant := model.findRedundantRules()
+	if len(redundant) != 1 || redundant[0].pattern != "big/0.bin" || redundant[0].coveredBy != "big/**" {
+		t.Fatalf("Expected only big/0.bin to be redundant, got %+v", redundant)
+	}
+
+	// A chain comes down to its broadest rule
+	model.filters = testDocument(nil, map[string]FilterState{
+		"big/**":     FilterInclude,
+		"/big/**":    FilterInclude,
+		"big/0.bin":  FilterInclude,
+		"small/**":   FilterExclude,
+		"small/9.bi": FilterInclude, // Matches nothing, but isn't implied either
+	})
+	var patterns []string
+	for _, rule := range model.findRedundantRules() {
+		patterns = append(patterns, rule.pattern)
+	}
+	if strings.Join(patterns, " ") != "big/0.bin /big/**" {
+		t.Errorf("Expected big/0.bin and /big/** to be redundant, got %v", patterns)
+	}
+}
+
+func TestCoalesceOnSave(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, big, _ := newGuardTestModel()
+	model.filterFile = filepath.Join(t.TempDir(), "filter.txt")
+	model.width, model.height = 100, 30
+	model.setNodeFilter(big, FilterExclude)
+	model.setNodeFilter(big.Children[3], FilterExclude)
+
+	m := pressKeys(*model, runeKey("s"))
+	if m.topModal() != modalCoalesce {
+		t.Fatalf("Expected the redundant rule to be offered before saving")
+	}
+	if view := m.View(); !strings.Contains(view, "- big/3.bin  (implied by big/**)") {
+		t.Errorf("Expected the redundant rule in the pane:\n%s", view)
+	}
+
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEnter})
+	data, err := os.ReadFile(m.filterFile)
+	if err != nil {
+		t.Fatalf("Expected the filter file to be saved: %v", err)
+	}
+	if string(data) != "- big/**\n" || !strings.Contains(m.statusMsg, "1 redundant rules removed") {
+		t.Errorf("Expected only big/** to be saved, got %q (%s)", data, m.statusMsg)
+	}
+	if big.Children[3].Filter != FilterExclude {
+		t.Errorf("Coalescing should not change any state")
+	}
+
+	// Rules kept once aren't offered again
+	m.setNodeFilter(big.Children[4], FilterExclude)
+	m = pressKeys(m, runeKey("s"), runeKey("n"))
+	data, _ = os.ReadFile(m.filterFile)
+	if m.topModal() != modalNone || !strings.Contains(string(data), "- big/4.bin") {
+		t.Fatalf("Expected the rule to be saved as it is, got %q", data)
+	}
+	m = pressKeys(m, runeKey("s"))
+	if m.topModal() != modalNone || !m.saved {
+		t.Errorf("Expected a kept rule to be saved without asking again")
+	}
+}
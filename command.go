@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// defaultRcloneCommand is the command shown with X when the config file has
+// no rclone-command
+const defaultRcloneCommand = "rclone sync {{root}} {{dest}} {{filter}}"
+
+// defaultRcloneDest stands in for the destination when rclone-dest isn't set
+const defaultRcloneDest = "remote:backup"
+
+// rcloneCommandVars are the variables of rclone-command:
+//
+//	{{root}}    the directory being edited
+//	{{dest}}    rclone-dest from the config file
+//	{{file}}    the filter file (or file list)
+//	{{filter}}  the flags reading it, such as --filter-from FILE, the
+//	            metadata filter when one is edited and the age limits
+var rcloneCommandVars = []string{"root", "dest", "file", "filter"}
+
+// checkRcloneCommand reports variables of an rclone-command template that
+// aren't known
+func checkRcloneCommand(template string) error {
+	for _, match := range templateVar.FindAllStringSubmatch(template, -1) {
+		if !slices.Contains(rcloneCommandVars, match[1]) {
+			return fmt.Errorf("unknown variable {{%s}}, use %s", match[1], "{{"+strings.Join(rcloneCommandVars, "}}, {{")+"}}")
+		}
+	}
+	return nil
+}
+
+// shellQuote quotes s for a POSIX shell when it holds anything but plain
+// path characters
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789@%+=:,./_-") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// commandPath makes a local path absolute, so that the command works from any
+// directory, and quotes it
+func commandPath(path string) string {
+	if path != stdioFilterFile && !isRemotePath(path) {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+	}
+	return shellQuote(path)
+}
+
+// rcloneCommands returns the rclone invocation implied by the session, one
+// per root as each root has its own filter file
+func (m *Model) rcloneCommands() []string {
+	template := m.rcloneCommand
+	if template == "" {
+		template = defaultRcloneCommand
+	}
+	dest := m.rcloneDest
+	if dest == "" {
+		dest = defaultRcloneDest
+	}
+
+	type session struct{ root, file string }
+	var sessions []session
+	switch {
+	case m.filesFrom != nil:
+		sessions = append(sessions, session{m.root.Path, m.filesFrom.path})
+	case len(m.roots) < 2:
+		sessions = append(sessions, session{m.root.Path, m.filterFile})
+	default:
+		for _, r := range m.roots {
+			sessions = append(sessions, session{r.path, r.filterFile})
+		}
+	}
+
+	var commands []string
+	for _, s := range sessions {
+		flag := "--filter-from"
+		if m.filesFrom != nil {
+			flag = "--files-from"
+		}
+		file := commandPath(s.file)
+		filter := flag + " " + file
+		if m.metadata != nil {
+			filter += " --metadata-filter-from " + commandPath(m.metadata.path)
+		}
+		for _, f := range m.ageLimits.flags() {
+			filter += " " + shellQuote(f)
+		}
+		values := map[string]string{
+			"root":   commandPath(s.root),
+			"dest":   dest,
+			"file":   file,
+			"filter": filter,
+		}
+		commands = append(commands, templateVar.ReplaceAllStringFunc(template, func(v string) string {
+			return values[templateVar.FindStringSubmatch(v)[1]]
+		}))
+	}
+	return commands
+}
+
+// copyToClipboard returns the command putting text on the clipboard with the
+// OSC 52 escape sequence, which works over SSH in terminals supporting it
+func copyToClipboard(out io.Writer, text string) tea.Cmd {
+	return func() tea.Msg {
+		if out != nil {
+			fmt.Fprintf(out, "\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(text)))
+		}
+		return nil
+	}
+}
+
+func (m Model) updateCommandPane(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "c", "y":
+		m.closeModal(modalCommand)
+		m.statusMsg = "Copied the rclone command to the clipboard"
+		return m, copyToClipboard(m.clipboard, strings.Join(m.rcloneCommands(), "\n"))
+	case "X", "esc", "q":
+		m.closeModal(modalCommand)
+	case "ctrl+c":
+		m.cancel()
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m Model) renderCommand() string {
+	paneStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(currentTheme.Border).
+		Padding(1, 2).
+		MaxWidth(m.width)
+
+	var b strings.Builder
+	b.WriteString("Run the job with:\n\n")
+	b.WriteString(strings.Join(m.rcloneCommands(), "\n"))
+	b.WriteString("\n\n")
+	b.WriteString("Set rclone-command and rclone-dest in the config file to change it.\n")
+	b.WriteString("c copy to the clipboard, Esc close")
+
+	return m.placePane(paneStyle, b.String())
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestRcloneCommands(t *testing.T) {
+	model, _, _ := newGuardTestModel()
+	m := *model
+	m.width, m.height = 120, 30
+	m.filterFile = "/home/me/my filters.txt"
+	m.rcloneDest = "b2:backup"
+
+	if got, want := m.rcloneCommands(), "rclone sync /test b2:backup --filter-from '/home/me/my filters.txt'"; len(got) != 1 || got[0] != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	m.rcloneCommand = "rclone copy {{root}} {{dest}} --filter-from {{file}} -P"
+	m.metadata = &metadataFilter{path: "/home/me/meta.txt"}
+	if got, want := m.rcloneCommands()[0], "rclone copy /test b2:backup --filter-from '/home/me/my filters.txt' -P"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	m.rcloneCommand = ""
+	if got := m.rcloneCommands()[0]; !strings.HasSuffix(got, "--metadata-filter-from /home/me/meta.txt") {
+		t.Errorf("Expected the metadata filter in %q", got)
+	}
+
+	var out bytes.Buffer
+	m.clipboard = &out
+	m.openModal(modalCommand)
+	if !strings.Contains(m.View(), "rclone sync /test b2:backup") {
+		t.Errorf("Expected the command in the pane:\n%s", m.View())
+	}
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'c'}})
+	cmd()
+	want := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(m.rcloneCommands()[0])) + "\a"
+	if out.String() != want || updated.(Model).modalOpen(modalCommand) {
+		t.Errorf("Expected the command to be copied and the pane closed, got %q", out.String())
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]string{
+		"gdrive:Photos/2024": "gdrive:Photos/2024",
+		"my files":           "'my files'",
+		"it's":               `'it'\''s'`,
+		"":                   "''",
+	}
+	for in, want := range tests {
+		if got := shellQuote(in); got != want {
+			t.Errorf("shellQuote(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// commandName is the name the completion scripts complete
+const commandName = "rclone-filter-editor"
+
+// Kinds of flag values the completion scripts complete, besides fixed words
+const (
+	completeDir  = "<dir>"  // A local directory or a configured rclone remote
+	completeFile = "<file>" // A local file
+)
+
+// flagCompletions says what the value of a flag is completed with. Value
+// flags missing here get no completion.
+var flagCompletions = map[string]string{
+	"path":           completeDir,
+	"p":              completeDir,
+	"file":           completeFile,
+	"f":              completeFile,
+	"config":         completeFile,
+	"files-from":     completeFile,
+	"export-file":    completeFile,
+	"metadata-file":  completeFile,
+	"import-listing": completeFile,
+	"local-mirror":   completeDir,
+	"dest-listing":   completeFile,
+	"script":         completeFile,
+	"export":         strings.Join([]string{exportIncluded, exportExcluded, exportIncludeExclude, exportCSV, exportJSON}, " "),
+}
+
+// completionShells are the shells "completion" writes scripts for
+var completionShells = []string{"bash", "zsh", "fish"}
+
+// completionFlag is a flag as the completion scripts see it
+type completionFlag struct {
+	name  string
+	usage string
+	bool  bool
+	value string // From flagCompletions
+}
+
+// completionFlags lists the flags of fs, so that the scripts can't fall
+// behind the flags themselves
+func completionFlags(fs *flag.FlagSet) []completionFlag {
+	var flags []completionFlag
+	fs.VisitAll(func(f *flag.Flag) {
+		b, ok := f.Value.(interface{ IsBoolFlag() bool })
+		flags = append(flags, completionFlag{
+			name:  f.Name,
+			usage: f.Usage,
+			bool:  ok && b.IsBoolFlag(),
+			value: flagCompletions[f.Name],
+		})
+	})
+	return flags
+}
+
+// dashed returns the flag as typed, -p or --path
+func (f completionFlag) dashed() string {
+	if len(f.name) == 1 {
+		return "-" + f.name
+	}
+	return "--" + f.name
+}
+
+// completionScript returns the completion script for shell, completing the
+// flags of fs for the program name
+func completionScript(shell, name string, fs *flag.FlagSet) (string, error) {
+	flags := completionFlags(fs)
+	fn := "_" + strings.NewReplacer("-", "_", ".", "_").Replace(name)
+	var b strings.Builder
+
+	switch shell {
+	case "bash":
+		byKind := make(map[string][]string)
+		var all []string
+		for _, f := range flags {
+			all = append(all, f.dashed())
+			if !f.bool {
+				byKind[f.value] = append(byKind[f.value], f.dashed())
+			}
+		}
+		fmt.Fprintf(&b, "# bash completion for %s\n", name)
+		fmt.Fprintf(&b, "%s() {\n", fn)
+		b.WriteString("    local cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+		b.WriteString("    local remotes=\"$(rclone listremotes 2>/dev/null)\"\n")
+		b.WriteString("    case \"$prev\" in\n")
+		kinds := make([]string, 0, len(byKind))
+		for kind := range byKind {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+		for _, kind := range kinds {
+			fmt.Fprintf(&b, "    %s)\n", strings.Join(byKind[kind], "|"))
+			switch kind {
+			case completeDir:
+				b.WriteString("        COMPREPLY=($(compgen -d -- \"$cur\") $(compgen -W \"$remotes\" -- \"$cur\"))\n")
+				b.WriteString("        [[ $COMPREPLY == *: ]] && compopt -o nospace; return ;;\n")
+			case completeFile:
+				b.WriteString("        COMPREPLY=($(compgen -f -- \"$cur\")); return ;;\n")
+			case "":
+				b.WriteString("        return ;;\n")
+			default:
+				fmt.Fprintf(&b, "        COMPREPLY=($(compgen -W %q -- \"$cur\")); return ;;\n", kind)
+			}
+		}
+		b.WriteString("    esac\n")
+		b.WriteString("    if [[ \"$cur\" == -* ]]; then\n")
+		fmt.Fprintf(&b, "        COMPREPLY=($(compgen -W %q -- \"$cur\")); return\n", strings.Join(all, " "))
+		b.WriteString("    fi\n")
+		b.WriteString("    COMPREPLY=($(compgen -f -- \"$cur\") $(compgen -W \"$remotes\" -- \"$cur\"))\n")
+		b.WriteString("    [[ $COMPREPLY == *: ]] && compopt -o nospace\n")
+		b.WriteString("}\n")
+		fmt.Fprintf(&b, "complete -o filenames -F %s %s\n", fn, name)
+
+	case "zsh":
+		fmt.Fprintf(&b, "#compdef %s\n\n", name)
+		fmt.Fprintf(&b, "%s_paths() {\n", fn)
+		b.WriteString("    local -a remotes\n")
+		b.WriteString("    remotes=(${(f)\"$(rclone listremotes 2>/dev/null)\"})\n")
+		b.WriteString("    _alternative \"files:${1:-file}:${2:-_files}\" \"remotes:rclone remote:compadd -S '' -a remotes\"\n")
+		b.WriteString("}\n\n")
+		b.WriteString("_arguments \\\n")
+		for _, f := range flags {
+			usage := strings.NewReplacer("'", "'\\''", "[", "\\[", "]", "\\]", ":", "\\:").Replace(f.usage)
+			spec := fmt.Sprintf("%s[%s]", f.dashed(), usage)
+			switch {
+			case f.bool:
+			case f.value == completeDir:
+				spec += fmt.Sprintf(":directory:{%s_paths directory _directories}", fn)
+			case f.value == completeFile:
+				spec += ":file:_files"
+			case f.value != "":
+				spec += fmt.Sprintf(":value:(%s)", f.value)
+			default:
+				spec += ":value: "
+			}
+			fmt.Fprintf(&b, "    '%s' \\\n", spec)
+		}
+		fmt.Fprintf(&b, "    '*:filter file or directory:%s_paths'\n", fn)
+
+	case "fish":
+		fmt.Fprintf(&b, "# fish completion for %s\n", name)
+		fmt.Fprintf(&b, "complete -c %s -a '(rclone listremotes 2>/dev/null)'\n", name)
+		for _, f := range flags {
+			line := fmt.Sprintf("complete -c %s -l %s", name, f.name)
+			if len(f.name) == 1 {
+				line = fmt.Sprintf("complete -c %s -s %s", name, f.name)
+			}
+			switch {
+			case f.bool:
+			case f.value == completeDir:
+				line += " -x -a '(__fish_complete_directories; rclone listremotes 2>/dev/null)'"
+			case f.value == completeFile:
+				line += " -r -F"
+			case f.value != "":
+				line += fmt.Sprintf(" -x -a '%s'", f.value)
+			default:
+				line += " -x"
+			}
+			line += fmt.Sprintf(" -d '%s'", strings.ReplaceAll(f.usage, "'", "\\'"))
+			b.WriteString(line + "\n")
+		}
+
+	default:
+		return "", fmt.Errorf("unknown shell %q, choose one of %s", shell, strings.Join(completionShells, ", "))
+	}
+	return b.String(), nil
+}
+
+// runCompletion prints the completion script for the shell named in args
+// and returns the process exit code
+func runCompletion(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: %s completion %s\n", commandName, strings.Join(completionShells, "|"))
+		return exitNotSaved
+	}
+	script, err := completionScript(args[0], commandName, flag.CommandLine)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitNotSaved
+	}
+	fmt.Print(script)
+	return exitSaved
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompletionScripts(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("path", "", "Base directory to browse")
+	fs.String("export", "", "What to export")
+	fs.Bool("stdout", false, "Print the rules")
+
+	bash, err := completionScript("bash", commandName, fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"--path)", "rclone listremotes", `compgen -W "included excluded include-exclude csv json"`, `"--export --path --stdout"`} {
+		if !strings.Contains(bash, want) {
+			t.Errorf("Expected %q in the bash script:\n%s", want, bash)
+		}
+	}
+	if path, err := exec.LookPath("bash"); err == nil {
+		script := filepath.Join(t.TempDir(), "completion.bash")
+		os.WriteFile(script, []byte(bash), 0644)
+		if out, err := exec.Command(path, "-n", script).CombinedOutput(); err != nil {
+			t.Errorf("The bash script doesn't parse: %s", out)
+		}
+	}
+
+	zsh, _ := completionScript("zsh", commandName, fs)
+	if !strings.Contains(zsh, "'--stdout[Print the rules]'") || !strings.Contains(zsh, "--path[Base directory to browse]:directory:") {
+		t.Errorf("Unexpected zsh script:\n%s", zsh)
+	}
+	fish, _ := completionScript("fish", commandName, fs)
+	if !strings.Contains(fish, "-l export -x -a 'included excluded include-exclude csv json'") {
+		t.Errorf("Unexpected fish script:\n%s", fish)
+	}
+
+	if _, err := completionScript("tcsh", commandName, fs); err == nil {
+		t.Errorf("Expected an error for an unknown shell")
+	}
+}
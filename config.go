@@ -0,0 +1,573 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Config holds user preferences loaded from the config file.
+//
+// The file uses a simple "key = value" format. Values may be quoted, lines
+// starting with '#' are comments and "[section]" headers prefix the keys
+// that follow them with "section.":
+//
+//	theme = deuteranopia
+//	glyph-include = "[✓]"
+//	glyph-exclude = "[✗]"
+type Config struct {
+	Theme  string
+	Glyphs Glyphs
+
+	// Render is how much the terminal is trusted to display: "full" for 256
+	// colors and UTF-8, "basic" for 8 colors and ASCII, "auto" to tell from
+	// TERM and the locale
+	Render string
+
+	// RemoteCacheTTL is how long rclone remote listings are reused
+	RemoteCacheTTL time.Duration
+
+	// ConfirmToggleFiles is the number of files a single toggle may change
+	// before a second keypress is required (0 disables the confirmation)
+	ConfirmToggleFiles int
+
+	// MaxExcludePercent blocks toggles that would exclude more than this
+	// share of the tree's files, and makes saving such rules ask twice
+	// (0 disables the limit)
+	MaxExcludePercent float64
+
+	// WarnIncludedSize is the total size of included files above which
+	// toggles warn and saving asks twice (0 disables the warning)
+	WarnIncludedSize int64
+
+	// Notify announces jobs that took at least NotifyAfter when they finish:
+	// "bell" rings the terminal bell, "desktop" shows a desktop notification
+	// and "off" stays silent
+	Notify      string
+	NotifyAfter time.Duration
+
+	// Templates are the rules with variables from the [templates] section,
+	// sorted by name
+	Templates []RuleTemplate
+
+	// SortDirsFirst lists directories before files; when false they are
+	// mixed and sorted together by the chosen sort key
+	SortDirsFirst bool
+
+	// ShowFileTypes breaks the size of each directory down by its biggest
+	// file types, such as video or images
+	ShowFileTypes bool
+
+	// SizeBars draws a bar in front of each row for its share of the size of
+	// its parent directory
+	SizeBars bool
+
+	// TreeGuides joins each row to its parent directory with lines instead
+	// of indenting it with spaces alone
+	TreeGuides bool
+
+	// MinSize is the size below which H hides entries
+	MinSize int64
+
+	// Heatmap colors directories by their file count or size percentile
+	// within the tree, until O switches it
+	Heatmap heatMode
+
+	// RcloneCommand is the template of the rclone command shown with X, and
+	// RcloneDest the destination it syncs to
+	RcloneCommand string
+	RcloneDest    string
+
+	// PrivilegedLister is a command standing in for rclone, such as
+	// "sudo -n rclone", that lists the directories the scan was denied
+	PrivilegedLister string
+
+	// SnapshotDir is where snapshots of directory sizes are stored
+	SnapshotDir string
+
+	// ScanDeny are local directories that are never scanned: a root inside
+	// one is refused and one below a root is skipped
+	ScanDeny []string
+
+	// WarnHomeSize is the space used on the disk of a home directory above
+	// which scanning the home directory asks first (0 disables the question)
+	WarnHomeSize int64
+
+	// RuleOrder is the order rules are saved in, see ruleOrders
+	RuleOrder ruleOrder
+
+	// IncludeParents is what including a file or directory does when a rule
+	// such as "- *" keeps rclone out of a directory above it: "ask" offers
+	// the "+ dir/" rules that let rclone in, "auto" adds them and "off"
+	// leaves them out
+	IncludeParents string
+
+	// Numbers is how sizes and counts are written: size-units, the
+	// thousands-separator of counts and fixed-width-sizes
+	Numbers numberFormat
+
+	// values holds every key from the file so that settings can be looked up
+	// by name, including ones in sections
+	values map[string]string
+}
+
+// Glyphs are the markers shown in front of each row for its filter state
+type Glyphs struct {
+	None    string
+	Include string
+	Exclude string
+}
+
+// Theme is a set of colors used throughout the UI
+type Theme struct {
+	None     lipgloss.Color
+	Include  lipgloss.Color
+	Exclude  lipgloss.Color
+	Header   lipgloss.Color
+	Muted    lipgloss.Color
+	CursorBg lipgloss.Color
+	CursorFg lipgloss.Color
+	Border   lipgloss.Color
+	Warning  lipgloss.Color
+	Heat     [5]lipgloss.Color // Heatmap gradient, from cold to hot
+}
+
+// themeAuto picks the default or light theme to suit the terminal background
+const themeAuto = "auto"
+
+var themes = map[string]Theme{
+	"default": {
+		None:     lipgloss.Color("8"),
+		Include:  lipgloss.Color("10"),
+		Exclude:  lipgloss.Color("9"),
+		Header:   lipgloss.Color("12"),
+		Muted:    lipgloss.Color("8"),
+		CursorBg: lipgloss.Color("8"),
+		CursorFg: lipgloss.Color("15"),
+		Border:   lipgloss.Color("12"),
+		Warning:  lipgloss.Color("11"),
+		Heat:     [5]lipgloss.Color{"67", "74", "179", "208", "196"},
+	},
+	// Darker shades for terminals with a light background, where the grey
+	// and bright colors of the default theme are hard to read
+	"light": {
+		None:     lipgloss.Color("240"),
+		Include:  lipgloss.Color("28"),
+		Exclude:  lipgloss.Color("160"),
+		Header:   lipgloss.Color("25"),
+		Muted:    lipgloss.Color("240"),
+		CursorBg: lipgloss.Color("252"),
+		CursorFg: lipgloss.Color("0"),
+		Border:   lipgloss.Color("25"),
+		Warning:  lipgloss.Color("130"),
+		Heat:     [5]lipgloss.Color{"24", "30", "136", "166", "160"},
+	},
+	// Blue/orange from the Okabe-Ito palette, distinguishable with red-green
+	// color vision deficiencies
+	"deuteranopia": {
+		None:     lipgloss.Color("#999999"),
+		Include:  lipgloss.Color("#56B4E9"),
+		Exclude:  lipgloss.Color("#E69F00"),
+		Header:   lipgloss.Color("#0072B2"),
+		Muted:    lipgloss.Color("#999999"),
+		CursorBg: lipgloss.Color("#555555"),
+		CursorFg: lipgloss.Color("#FFFFFF"),
+		Border:   lipgloss.Color("#0072B2"),
+		Warning:  lipgloss.Color("#F0E442"),
+		Heat:     [5]lipgloss.Color{"#0072B2", "#56B4E9", "#F0E442", "#E69F00", "#D55E00"},
+	},
+	// The 8 colors every terminal has, for bare consoles; the terminal's
+	// own foreground stands in for grey
+	"basic": {
+		None:     lipgloss.Color(""),
+		Include:  lipgloss.Color("2"),
+		Exclude:  lipgloss.Color("1"),
+		Header:   lipgloss.Color("4"),
+		Muted:    lipgloss.Color(""),
+		CursorBg: lipgloss.Color("4"),
+		CursorFg: lipgloss.Color("7"),
+		Border:   lipgloss.Color("4"),
+		Warning:  lipgloss.Color("3"),
+		Heat:     [5]lipgloss.Color{"4", "6", "2", "3", "1"},
+	},
+}
+
+var defaultGlyphs = Glyphs{
+	None:    "[ ]",
+	Include: "[+]",
+	Exclude: "[-]",
+}
+
+// currentTheme and currentGlyphs are used by all rendering code
+var (
+	currentTheme  = themes["default"]
+	currentGlyphs = defaultGlyphs
+)
+
+// stateStyle returns the glyph of a filter state and the style it is shown
+// in, as in the tree
+func stateStyle(state FilterState) (string, lipgloss.Style) {
+	switch state {
+	case FilterInclude:
+		return currentGlyphs.Include, lipgloss.NewStyle().Foreground(currentTheme.Include)
+	case FilterExclude:
+		return currentGlyphs.Exclude, lipgloss.NewStyle().Foreground(currentTheme.Exclude)
+	}
+	return currentGlyphs.None, lipgloss.NewStyle().Foreground(currentTheme.None)
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		Theme:  themeAuto,
+		Glyphs: defaultGlyphs,
+		Render: renderAuto,
+
+		RemoteCacheTTL:     5 * time.Minute,
+		ConfirmToggleFiles: 1000,
+		SortDirsFirst:      true,
+		SizeBars:           true,
+		TreeGuides:         true,
+		MinSize:            defaultMinSize,
+		SnapshotDir:        defaultSnapshotDir(),
+		ScanDeny:           defaultScanDeny,
+		WarnHomeSize:       defaultWarnHomeSize,
+		RcloneCommand:      defaultRcloneCommand,
+		RcloneDest:         defaultRcloneDest,
+		Notify:             notifyOff,
+		NotifyAfter:        30 * time.Second,
+		Numbers:            defaultNumberFormat,
+		RuleOrder:          ruleOrders[0],
+		IncludeParents:     parentsAsk,
+
+		values: make(map[string]string),
+	}
+}
+
+// defaultConfigPath returns the location of the config file when --config
+// isn't given
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "rclone-filter-editor", "config")
+}
+
+// defaultSnapshotDir returns the directory snapshots are stored in when the
+// config file doesn't set snapshot-dir
+func defaultSnapshotDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "rclone-filter-editor", "snapshots")
+}
+
+// loadConfig reads the config file at path. A missing file yields the
+// default configuration.
+func loadConfig(path string) (*Config, error) {
+	cfg := defaultConfig()
+	if path == "" {
+		return cfg, nil
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			fmt.Printf("Warning: failed to close file: %v\n", closeErr)
+		}
+	}()
+
+	section := ""
+	lineNum := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return cfg, fmt.Errorf("%s:%d: expected key = value", path, lineNum)
+		}
+		key = strings.TrimSpace(key)
+		value = unquoteConfigValue(strings.TrimSpace(value))
+		if section != "" {
+			key = section + "." + key
+		}
+		cfg.values[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return cfg, err
+	}
+
+	return cfg, cfg.apply()
+}
+
+// apply copies known keys from the raw values into the typed fields
+func (c *Config) apply() error {
+	if v, ok := c.values["theme"]; ok {
+		if _, known := themes[v]; !known && v != themeAuto {
+			return fmt.Errorf("unknown theme %q", v)
+		}
+		c.Theme = v
+	}
+	if v, ok := c.values["render"]; ok {
+		if v != renderAuto && v != renderFull && v != renderBasic {
+			return fmt.Errorf("invalid render: %q (use auto, full or basic)", v)
+		}
+		c.Render = v
+	}
+	if v, ok := c.values["glyph-none"]; ok {
+		c.Glyphs.None = v
+	}
+	if v, ok := c.values["glyph-include"]; ok {
+		c.Glyphs.Include = v
+	}
+	if v, ok := c.values["glyph-exclude"]; ok {
+		c.Glyphs.Exclude = v
+	}
+	if v, ok := c.values["remote-cache-ttl"]; ok {
+		ttl, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid remote-cache-ttl: %v", err)
+		}
+		c.RemoteCacheTTL = ttl
+	}
+	if v, ok := c.values["confirm-toggle-files"]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid confirm-toggle-files: %q", v)
+		}
+		c.ConfirmToggleFiles = n
+	}
+	if v, ok := c.values["max-exclude-percent"]; ok {
+		p, err := strconv.ParseFloat(strings.TrimSuffix(v, "%"), 64)
+		if err != nil || p < 0 || p > 100 {
+			return fmt.Errorf("invalid max-exclude-percent: %q", v)
+		}
+		c.MaxExcludePercent = p
+	}
+	if v, ok := c.values["warn-included-size"]; ok {
+		size, err := parseSize(v)
+		if err != nil {
+			return fmt.Errorf("invalid warn-included-size: %v", err)
+		}
+		c.WarnIncludedSize = size
+	}
+	if v, ok := c.values["notify"]; ok {
+		if v != notifyOff && v != notifyBell && v != notifyDesktop {
+			return fmt.Errorf("invalid notify: %q (use off, bell or desktop)", v)
+		}
+		c.Notify = v
+	}
+	if v, ok := c.values["notify-after"]; ok {
+		after, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid notify-after: %v", err)
+		}
+		c.NotifyAfter = after
+	}
+	if v, ok := c.values["sort-dirs-first"]; ok {
+		dirsFirst, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid sort-dirs-first: %q", v)
+		}
+		c.SortDirsFirst = dirsFirst
+	}
+	if v, ok := c.values["show-file-types"]; ok {
+		showTypes, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid show-file-types: %q", v)
+		}
+		c.ShowFileTypes = showTypes
+	}
+	if v, ok := c.values["size-bars"]; ok {
+		bars, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid size-bars: %q", v)
+		}
+		c.SizeBars = bars
+	}
+	if v, ok := c.values["tree-guides"]; ok {
+		guides, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid tree-guides: %q", v)
+		}
+		c.TreeGuides = guides
+	}
+	if v, ok := c.values["min-size"]; ok {
+		size, err := parseSize(v)
+		if err != nil || size == 0 {
+			return fmt.Errorf("invalid min-size: %q", v)
+		}
+		c.MinSize = size
+	}
+	if v, ok := c.values["heatmap"]; ok {
+		mode, err := parseHeatMode(v)
+		if err != nil {
+			return fmt.Errorf("invalid heatmap: %v", err)
+		}
+		c.Heatmap = mode
+	}
+	if v, ok := c.values["snapshot-dir"]; ok {
+		c.SnapshotDir = v
+	}
+	if v, ok := c.values["rclone-command"]; ok {
+		if err := checkRcloneCommand(v); err != nil {
+			return fmt.Errorf("invalid rclone-command: %v", err)
+		}
+		c.RcloneCommand = v
+	}
+	if v, ok := c.values["rclone-dest"]; ok {
+		c.RcloneDest = v
+	}
+	if v, ok := c.values["privileged-lister"]; ok {
+		c.PrivilegedLister = strings.TrimSpace(v)
+	}
+	if v, ok := c.values["scan-deny"]; ok {
+		c.ScanDeny = nil
+		for _, dir := range strings.Split(v, ",") {
+			if dir = strings.TrimSpace(dir); dir != "" {
+				c.ScanDeny = append(c.ScanDeny, dir)
+			}
+		}
+	}
+	if v, ok := c.values["warn-home-size"]; ok {
+		size, err := parseSize(v)
+		if err != nil {
+			return fmt.Errorf("invalid warn-home-size: %v", err)
+		}
+		c.WarnHomeSize = size
+	}
+	if v, ok := c.values["rule-order"]; ok {
+		order, err := findRuleOrder(v)
+		if err != nil {
+			return fmt.Errorf("invalid rule-order: %v", err)
+		}
+		c.RuleOrder = order
+	}
+	if v, ok := c.values["include-parents"]; ok {
+		if v != parentsAsk && v != parentsAuto && v != parentsOff {
+			return fmt.Errorf("invalid include-parents: %q (use ask, auto or off)", v)
+		}
+		c.IncludeParents = v
+	}
+	if v, ok := c.values["size-units"]; ok {
+		units, err := parseUnits(v)
+		if err != nil {
+			return fmt.Errorf("invalid size-units: %v", err)
+		}
+		c.Numbers.Units = units
+	}
+	if v, ok := c.values["thousands-separator"]; ok {
+		if strings.ContainsAny(v, "0123456789") {
+			return fmt.Errorf("invalid thousands-separator: %q", v)
+		}
+		c.Numbers.Separator = v
+	}
+	if v, ok := c.values["fixed-width-sizes"]; ok {
+		fixed, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid fixed-width-sizes: %q", v)
+		}
+		c.Numbers.FixedWidth = fixed
+	}
+
+	var templateNames []string
+	for key := range c.values {
+		if name, ok := strings.CutPrefix(key, "templates."); ok {
+			templateNames = append(templateNames, name)
+		}
+	}
+	sort.Strings(templateNames)
+	for _, name := range templateNames {
+		t, err := parseRuleTemplate(name, c.values["templates."+name])
+		if err != nil {
+			return fmt.Errorf("invalid template %s: %v", name, err)
+		}
+		c.Templates = append(c.Templates, t)
+	}
+	return nil
+}
+
+// parseSize parses a size such as "500G", "1.5 TiB" or "2048" (bytes). Units
+// are 1024 based, as in rclone, whatever size-units sizes are shown in.
+func parseSize(s string) (int64, error) {
+	v := strings.ToUpper(strings.TrimSpace(s))
+	v = strings.TrimSuffix(v, "B")
+	v = strings.TrimSuffix(v, "I")
+
+	multiplier := int64(1)
+	if v != "" {
+		if i := strings.IndexByte("KMGTPE", v[len(v)-1]); i >= 0 {
+			multiplier = 1 << (10 * (i + 1))
+			v = v[:len(v)-1]
+		}
+	}
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%q is not a size", s)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+// Get returns the raw value of a key, using "section.key" for keys inside
+// a section
+func (c *Config) Get(key string) (string, bool) {
+	v, ok := c.values[key]
+	return v, ok
+}
+
+// activate makes the configured theme, glyphs and number format the ones used
+// for rendering.
+// An automatic theme starts out as the default one until detectTheme has
+// looked at the terminal.
+func (c *Config) activate() {
+	currentTheme = themes[c.Theme]
+	if c.Theme == themeAuto {
+		currentTheme = themes["default"]
+	}
+	currentGlyphs = c.Glyphs
+	currentNumbers = c.Numbers
+}
+
+// detectTheme switches an automatic theme to the light one when the terminal
+// has a light background, as reported by the terminal itself or COLORFGBG.
+// It is only called before the interface opens, as asking the terminal
+// waits for its answer.
+func (c *Config) detectTheme(hasDarkBackground func() bool) {
+	if c.Theme == themeAuto && !hasDarkBackground() {
+		currentTheme = themes["light"]
+	}
+}
+
+func unquoteConfigValue(value string) string {
+	if len(value) >= 2 {
+		if (value[0] == '"' && value[len(value)-1] == '"') || (value[0] == '\'' && value[len(value)-1] == '\'') {
+			return value[1 : len(value)-1]
+		}
+	}
+	return value
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLoadConfigMissingFile(t *testing.T) {
+	cfg, err := loadConfig(filepath.Join(t.TempDir(), "missing"))
+	if err != nil {
+		t.Fatalf("Missing config should not be an error: %v", err)
+	}
+	if cfg.Theme != themeAuto || cfg.Glyphs != defaultGlyphs {
+		t.Errorf("Missing config should yield defaults, got %+v", cfg)
+	}
+}
+
+func TestLoadConfigThemeAndGlyphs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	content := `# Color-blind friendly setup
+theme = deuteranopia
+glyph-include = "[✓]"
+glyph-exclude = '[✗]'
+
+[extra]
+answer = 42
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.Theme != "deuteranopia" {
+		t.Errorf("Theme = %q; want deuteranopia", cfg.Theme)
+	}
+	expected := Glyphs{None: "[ ]", Include: "[✓]", Exclude: "[✗]"}
+	if cfg.Glyphs != expected {
+		t.Errorf("Glyphs = %+v; want %+v", cfg.Glyphs, expected)
+	}
+	if v, ok := cfg.Get("extra.answer"); !ok || v != "42" {
+		t.Errorf("Section keys should be prefixed, got %q", v)
+	}
+
+	originalTheme, originalGlyphs := currentTheme, currentGlyphs
+	defer func() { currentTheme, currentGlyphs = originalTheme, originalGlyphs }()
+	cfg.activate()
+	if currentTheme != themes["deuteranopia"] || currentGlyphs != expected {
+		t.Errorf("activate should switch the rendering theme and glyphs")
+	}
+}
+
+func TestDetectTheme(t *testing.T) {
+	originalTheme := currentTheme
+	defer func() { currentTheme = originalTheme }()
+
+	dark := func() bool { return true }
+	light := func() bool { return false }
+
+	cfg := defaultConfig()
+	cfg.activate()
+	cfg.detectTheme(dark)
+	if currentTheme != themes["default"] {
+		t.Errorf("Expected the default theme on a dark background")
+	}
+	cfg.detectTheme(light)
+	if currentTheme != themes["light"] {
+		t.Errorf("Expected the light theme on a light background")
+	}
+
+	// A theme chosen in the config file wins over the background
+	cfg.Theme = "deuteranopia"
+	cfg.activate()
+	cfg.detectTheme(light)
+	if currentTheme != themes["deuteranopia"] {
+		t.Errorf("Expected the configured theme to be kept")
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"unknown theme", "theme = neon\n"},
+		{"missing equals", "theme deuteranopia\n"},
+		{"negative confirm threshold", "confirm-toggle-files = -1\n"},
+		{"exclude percent over 100", "max-exclude-percent = 150\n"},
+		{"bad included size", "warn-included-size = lots\n"},
+		{"unknown notify mode", "notify = email\n"},
+		{"bad notify delay", "notify-after = soon\n"},
+		{"bad sort-dirs-first", "sort-dirs-first = sometimes\n"},
+		{"bad show-file-types", "show-file-types = maybe\n"},
+		{"bad size-bars", "size-bars = wide\n"},
+		{"bad tree-guides", "tree-guides = dotted\n"},
+		{"bad render", "render = 16colors\n"},
+		{"bad include-parents", "include-parents = always\n"},
+		{"bad min-size", "min-size = 0\n"},
+		{"unknown heatmap", "heatmap = temperature\n"},
+		{"bad warn-home-size", "warn-home-size = huge\n"},
+		{"unknown size-units", "size-units = metric\n"},
+		{"digit thousands-separator", "thousands-separator = 0\n"},
+		{"bad fixed-width-sizes", "fixed-width-sizes = narrow\n"},
+		{"unknown rule-order", "rule-order = random\n"},
+		{"bad rclone-command", "rclone-command = rclone copy {{src}} {{dest}}\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config")
+			os.WriteFile(path, []byte(tt.content), 0644)
+			if _, err := loadConfig(path); err == nil {
+				t.Errorf("Expected an error for %q", tt.content)
+			}
+		})
+	}
+}
+
+func TestLoadConfigGuardRails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	os.WriteFile(path, []byte("max-exclude-percent = 50%\nwarn-included-size = 1.5 GiB\n"), 0644)
+
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.MaxExcludePercent != 50 {
+		t.Errorf("MaxExcludePercent = %v; want 50", cfg.MaxExcludePercent)
+	}
+	if cfg.WarnIncludedSize != 1536*1024*1024 {
+		t.Errorf("WarnIncludedSize = %d; want 1.5 GiB", cfg.WarnIncludedSize)
+	}
+}
+
+func TestLoadConfigScanDeny(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	os.WriteFile(path, []byte("scan-deny = /proc, /mnt/nas ,\nwarn-home-size = 0\n"), 0644)
+
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if !slices.Equal(cfg.ScanDeny, []string{"/proc", "/mnt/nas"}) || cfg.WarnHomeSize != 0 {
+		t.Errorf("ScanDeny = %q, WarnHomeSize = %d", cfg.ScanDeny, cfg.WarnHomeSize)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"2048", 2048},
+		{"10B", 10},
+		{"4k", 4096},
+		{"500G", 500 << 30},
+		{"2 TB", 2 << 40},
+		{"1.5MiB", 1536 << 10},
+	}
+
+	for _, tt := range tests {
+		got, err := parseSize(tt.input)
+		if err != nil || got != tt.expected {
+			t.Errorf("parseSize(%q) = %d, %v; want %d", tt.input, got, err, tt.expected)
+		}
+	}
+
+	if _, err := parseSize("-1G"); err == nil {
+		t.Errorf("Negative sizes should be rejected")
+	}
+}
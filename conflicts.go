@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// ruleConflict is a pattern the filter file both includes and excludes,
+// which can come from editing the file by hand or pasting rules. rclone and
+// the tree apply the first of the two, so the last one never takes effect,
+// which is rarely what was meant.
+type ruleConflict struct {
+	top     *FileNode
+	pattern string
+	first   FilterState // The sign applied
+	last    FilterState // The sign of the rule never reached
+}
+
+// findRuleConflicts lists the patterns of each root given both as an
+// include and an exclude, in the order of the file. Rules pulled in by an
+// include directive are left out, since they can't be changed here.
+func (m *Model) findRuleConflicts() []ruleConflict {
+	if m.filesFrom != nil || m.root == nil {
+		return nil
+	}
+	var conflicts []ruleConflict
+	for _, top := range m.topLevelNodes() {
+		m.activateRootFor(top)
+		first, last := make(map[string]FilterState), make(map[string]FilterState)
+		var order []string
+		for _, rule := range m.filters.rules {
+			if !rule.isActive() || m.keptConflicts[rule.Pattern] {
+				continue
+			}
+			state, seen := first[rule.Pattern]
+			if !seen {
+				first[rule.Pattern] = rule.State
+			} else if state != rule.State && !slices.Contains(order, rule.Pattern) {
+				order = append(order, rule.Pattern)
+			}
+			last[rule.Pattern] = rule.State
+		}
+		for _, pattern := range order {
+			conflicts = append(conflicts, ruleConflict{top: top, pattern: pattern, first: first[pattern], last: last[pattern]})
+		}
+	}
+	return conflicts
+}
+
+// resolveConflict keeps the first rule of the conflict with state, where it
+// is in the file, and removes the other rules with its pattern
+func (m *Model) resolveConflict(c ruleConflict, state FilterState) {
+	m.activateRootFor(c.top)
+	m.filterGen++
+	kept := false
+	m.filters.rules = slices.DeleteFunc(m.filters.rules, func(rule FilterRule) bool {
+		if rule.Pattern != c.pattern || !rule.isActive() {
+			return false
+		}
+		if rule.State == state && !kept {
+			kept = true
+			return false
+		}
+		return true
+	})
+	m.filterCache.clear()
+	m.reapplyFiltersToTree(c.top)
+	m.updateVisibleNodes()
+}
+
+// keepConflicts stops asking about the conflicts, saving both rules of each
+// as they are
+func (m *Model) keepConflicts(conflicts []ruleConflict) {
+	if m.keptConflicts == nil {
+		m.keptConflicts = make(map[string]bool)
+	}
+	for _, c := range conflicts {
+		m.keptConflicts[c.pattern] = true
+	}
+}
+
+// startSave saves for s after its checks: the patterns both included and
+// excluded are offered for resolving, then the redundant rules for
+// coalescing.
+func (m *Model) startSave() tea.Cmd {
+	m.pendingSave = false
+	if conflicts := m.findRuleConflicts(); len(conflicts) > 0 {
+		m.conflicts = conflicts
+		m.conflictCursor = 0
+		m.openModal(modalConflicts)
+		return nil
+	}
+	return m.saveChecked()
+}
+
+// saveChecked saves after offering to coalesce the redundant rules
+func (m *Model) saveChecked() tea.Cmd {
+	if redundant := m.findRedundantRules(); len(redundant) > 0 {
+		m.redundant = redundant
+		m.openModal(modalCoalesce)
+		return nil
+	}
+	return m.finishSave("")
+}
+
+// finishSave writes the rules once the checks are through, adding note to
+// the status line. A save confirmed when quitting quits once it succeeds.
+func (m *Model) finishSave(note string) tea.Cmd {
+	quit := m.quitAfterSave
+	m.quitAfterSave = false
+	if !m.save() {
+		// Stay open on failure, so that the changes aren't lost
+		return nil
+	}
+	m.statusMsg += note
+	if quit {
+		m.cancel()
+		return tea.Quit
+	}
+	return nil
+}
+
+// updateConflictsPane handles keys while the conflicting rules are offered
+// for resolving before saving. Once the last is resolved the save goes on.
+func (m Model) updateConflictsPane(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		if m.conflictCursor > 0 {
+			m.conflictCursor--
+		}
+	case "down", "j":
+		if m.conflictCursor < len(m.conflicts)-1 {
+			m.conflictCursor++
+		}
+	case "+", "-":
+		c := m.conflicts[m.conflictCursor]
+		state := FilterInclude
+		if msg.String() == "-" {
+			state = FilterExclude
+		}
+		m.resolveConflict(c, state)
+		m.noteActivity(fmt.Sprintf("kept %s over its opposite", FilterRule{Pattern: c.pattern, State: state}))
+		m.conflicts = slices.Delete(m.conflicts, m.conflictCursor, m.conflictCursor+1)
+		m.conflictCursor = max(0, min(m.conflictCursor, len(m.conflicts)-1))
+		if len(m.conflicts) == 0 {
+			m.closeModal(modalConflicts)
+			return m, m.saveChecked()
+		}
+	case "b", "B":
+		m.closeModal(modalConflicts)
+		m.keepConflicts(m.conflicts)
+		m.conflicts = nil
+		return m, m.saveChecked()
+	case "esc", "q":
+		m.closeModal(modalConflicts)
+		m.conflicts = nil
+		m.quitAfterSave = false
+	case "ctrl+c":
+		m.cancel()
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m Model) renderConflicts() string {
+	paneStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(currentTheme.Warning).
+		Padding(1, 2)
+	cursorStyle := lipgloss.NewStyle().Background(currentTheme.CursorBg).Foreground(currentTheme.CursorFg)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d patterns are both included and excluded. rclone and the tree\n", len(m.conflicts))
+	b.WriteString("apply the first rule of each, the last is never reached:\n\n")
+	for i, c := range m.conflicts {
+		line := fmt.Sprintf("%s  (applied), then %s  (never reached)",
+			FilterRule{Pattern: c.pattern, State: c.first}, FilterRule{Pattern: c.pattern, State: c.last})
+		if m.multiRoot() {
+			line = c.top.Name + ": " + line
+		}
+		if i == m.conflictCursor {
+			line = cursorStyle.Render(line)
+		}
+		b.WriteString(line + "\n")
+	}
+	b.WriteString("\n+ keep the include, - keep the exclude, b save both as they are, Esc cancel")
+
+	return m.placePane(paneStyle, b.String())
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func newConflictTestModel(t *testing.T) (*Model, *FileNode) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	t.Cleanup(func() { globalRootPath = originalGlobalRootPath })
+
+	model, big, _ := newGuardTestModel()
+	model.filterFile = filepath.Join(t.TempDir(), "filter.txt")
+	model.width, model.height = 100, 30
+	model.filters.replace([]FilterRule{
+		{Pattern: "*.tmp", State: FilterExclude},
+		{Pattern: "big/**", State: FilterInclude},
+		{Pattern: "small/**", State: FilterExclude},
+		{Pattern: "big/**", State: FilterExclude},
+	})
+	model.reapplyFiltersToTree(model.root)
+	return model, big
+}
+
+func TestFindRuleConflicts(t *testing.T) {
+	model, _ := newConflictTestModel(t)
+	conflicts := model.findRuleConflicts()
+	if len(conflicts) != 1 || conflicts[0].pattern != "big/**" || conflicts[0].first != FilterInclude || conflicts[0].last != FilterExclude {
+		t.Fatalf("Expected big/** included first and excluded last, got %+v", conflicts)
+	}
+
+	// The same sign twice is no conflict, nor is a disabled rule
+	model.filters.rules[3].Disabled = true
+	model.filters.rules = append(model.filters.rules, FilterRule{Pattern: "small/**", State: FilterExclude})
+	if conflicts := model.findRuleConflicts(); len(conflicts) != 0 {
+		t.Errorf("Expected no conflicts, got %+v", conflicts)
+	}
+}
+
+func TestResolveConflictOnSave(t *testing.T) {
+	model, big := newConflictTestModel(t)
+	if big.Children[0].Filter != FilterInclude {
+		t.Fatalf("Expected the tree to show the first rule, got %v", big.Children[0].Filter)
+	}
+
+	m := pressKeys(*model, runeKey("s"))
+	if m.topModal() != modalConflicts {
+		t.Fatalf("Expected the conflict to be offered before saving")
+	}
+	if view := m.View(); !strings.Contains(view, "+ big/**  (applied), then - big/**  (never reached)") {
+		t.Errorf("Expected the conflict in the pane:\n%s", view)
+	}
+	if _, err := os.Stat(m.filterFile); !os.IsNotExist(err) {
+		t.Fatalf("Expected nothing saved while the conflict is open")
+	}
+
+	// Keeping the include drops the exclude, and the save goes on
+	m = pressKeys(m, runeKey("+"))
+	if m.topModal() != modalNone {
+		t.Fatalf("Expected the pane closed once resolved, got %v", m.topModal())
+	}
+	data, err := os.ReadFile(m.filterFile)
+	if err != nil {
+		t.Fatalf("Expected the filter file to be saved: %v", err)
+	}
+	if string(data) != "- *.tmp\n+ big/**\n- small/**\n" {
+		t.Errorf("Expected only the include of big/** saved, got %q", data)
+	}
+	if big.Children[0].Filter != FilterInclude {
+		t.Errorf("Expected the tree to show what rclone does, got %v", big.Children[0].Filter)
+	}
+}
+
+func TestKeepConflictsOnSave(t *testing.T) {
+	model, _ := newConflictTestModel(t)
+
+	m := pressKeys(*model, runeKey("s"), tea.KeyMsg{Type: tea.KeyEsc})
+	if m.topModal() != modalNone || m.saved {
+		t.Fatalf("Expected Esc to cancel the save")
+	}
+
+	m = pressKeys(m, runeKey("s"), runeKey("b"))
+	data, _ := os.ReadFile(m.filterFile)
+	if !m.saved || !strings.Contains(string(data), "+ big/**") || !strings.Contains(string(data), "- big/**") {
+		t.Fatalf("Expected both rules saved as they are, got %q", data)
+	}
+	m = pressKeys(m, runeKey("s"))
+	if m.topModal() != modalNone {
+		t.Errorf("Expected kept conflicts saved without asking again")
+	}
+}
+
+func TestQuitSaveResolvesConflicts(t *testing.T) {
+	model, _ := newConflictTestModel(t)
+	model.ctx, model.cancel = context.WithCancel(context.Background())
+	model.openModal(modalSaveConfirm)
+
+	updated, cmd := model.Update(runeKey("y"))
+	m := updated.(Model)
+	if m.topModal() != modalConflicts || cmd != nil {
+		t.Fatalf("Expected the conflict to be offered before saving on quit, got %v", m.topModal())
+	}
+	if _, err := os.Stat(m.filterFile); !os.IsNotExist(err) {
+		t.Fatalf("Expected nothing saved while the conflict is open")
+	}
+
+	// Keeping both rules saves and quits
+	updated, cmd = m.Update(runeKey("b"))
+	m = updated.(Model)
+	if !m.saved || cmd == nil {
+		t.Fatalf("Expected the save to go on and quit, got %q", m.statusMsg)
+	}
+	if _, ok := cmd().(tea.QuitMsg); !ok {
+		t.Errorf("Expected the editor to quit after saving")
+	}
+
+	// Esc in the pane keeps the editor open
+	model, _ = newConflictTestModel(t)
+	model.openModal(modalSaveConfirm)
+	m = pressKeys(*model, runeKey("y"), tea.KeyMsg{Type: tea.KeyEsc})
+	if m.quitAfterSave || m.saved || m.topModal() != modalNone {
+		t.Errorf("Expected Esc to cancel the save and stay open")
+	}
+}
+
+func TestQuitSaveNeedsSecondPressOverWarnings(t *testing.T) {
+	model, _ := newConflictTestModel(t)
+	model.filters.replace(nil)
+	model.reapplyFiltersToTree(model.root)
+	model.guards.warnIncludedSize = 1024
+	model.ctx, model.cancel = context.WithCancel(context.Background())
+	model.openModal(modalSaveConfirm)
+
+	m := pressKeys(*model, runeKey("y"))
+	if m.saved || m.topModal() != modalSaveConfirm || !strings.Contains(m.View(), "Press Y again to save anyway") {
+		t.Fatalf("Expected a second press over the warning:\n%s", m.View())
+	}
+	updated, cmd := m.Update(runeKey("y"))
+	if m = updated.(Model); !m.saved || cmd == nil {
+		t.Errorf("Expected the second press to save, got %q", m.statusMsg)
+	}
+}
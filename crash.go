@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// crashHandler takes over a panic anywhere in the editor: it gives the
+// terminal back in a usable state, writes a crash report with the stack
+// trace, releases the locks and exits. bubbletea's own recovery is turned
+// off, as it prints the trace to the screen it is restoring.
+type crashHandler struct {
+	program   *tea.Program
+	output    io.Writer // Terminal the escape sequences are written to
+	stderr    io.Writer // Where the crash is reported
+	reportDir string
+	cleanup   func() // Releases the locks of the session
+	exit      func(code int)
+
+	once sync.Once
+}
+
+// terminalReset leaves the alternate screen and shows the cursor again, in
+// case the program couldn't restore the terminal itself
+const terminalReset = "\x1b[?1049l\x1b[?25h\x1b[?2004l"
+
+func defaultCrashDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return os.TempDir()
+	}
+	return filepath.Join(dir, "rclone-filter-editor")
+}
+
+// recover is deferred where the editor's code runs: in Init, Update and View
+// and in the commands they return
+func (h *crashHandler) recover() {
+	if r := recover(); r != nil {
+		h.crash(r, debug.Stack())
+	}
+}
+
+// crash restores the terminal, reports the panic and exits, once even if
+// several goroutines panic
+func (h *crashHandler) crash(r any, stack []byte) {
+	h.once.Do(func() {
+		if h.program != nil {
+			h.program.ReleaseTerminal()
+		}
+		fmt.Fprint(h.output, terminalReset)
+		if h.cleanup != nil {
+			h.cleanup()
+		}
+
+		fmt.Fprintf(h.stderr, "rclone-filter-editor crashed: %v\n", r)
+		if file, err := writeCrashReport(h.reportDir, r, stack, time.Now()); err != nil {
+			fmt.Fprintf(h.stderr, "Failed to write the crash report (%v):\n\n%s", err, stack)
+		} else {
+			fmt.Fprintf(h.stderr, "The crash report is in %s, please attach it to a bug report\n", file)
+		}
+		h.exit(exitCrash)
+	})
+}
+
+// writeCrashReport writes the panic, the command line and the stack trace to
+// a new file in dir and returns its name
+func writeCrashReport(dir string, r any, stack []byte, now time.Time) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	file := filepath.Join(dir, "crash-"+now.Format("20060102-150405")+".txt")
+	var b strings.Builder
+	fmt.Fprintf(&b, "rclone-filter-editor crashed at %s\n", now.Format(time.RFC3339))
+	fmt.Fprintf(&b, "Command: %s\n", strings.Join(os.Args, " "))
+	fmt.Fprintf(&b, "Go: %s %s/%s\n\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&b, "panic: %v\n\n%s", r, stack)
+	return file, os.WriteFile(file, []byte(b.String()), 0644)
+}
+
+// crashGuard runs a model under the crash handler, along with the commands
+// it returns, which bubbletea runs in goroutines of their own
+type crashGuard struct {
+	tea.Model
+	handler *crashHandler
+}
+
+func (g crashGuard) Init() tea.Cmd {
+	defer g.handler.recover()
+	return g.guard(g.Model.Init())
+}
+
+func (g crashGuard) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	defer g.handler.recover()
+	model, cmd := g.Model.Update(msg)
+	return crashGuard{Model: model, handler: g.handler}, g.guard(cmd)
+}
+
+func (g crashGuard) View() string {
+	defer g.handler.recover()
+	return g.Model.View()
+}
+
+// guard wraps cmd so that a panic in it reaches the handler, along with the
+// commands of a batch it returns
+func (g crashGuard) guard(cmd tea.Cmd) tea.Cmd {
+	if cmd == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		defer g.handler.recover()
+		msg := cmd()
+		if batch, ok := msg.(tea.BatchMsg); ok {
+			guarded := make(tea.BatchMsg, len(batch))
+			for i, c := range batch {
+				guarded[i] = g.guard(c)
+			}
+			return guarded
+		}
+		return msg
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// panicModel panics on any message
+type panicModel struct{}
+
+func (panicModel) Init() tea.Cmd                       { return nil }
+func (panicModel) Update(tea.Msg) (tea.Model, tea.Cmd) { panic("boom") }
+func (panicModel) View() string                        { return "" }
+
+func newTestCrashHandler(t *testing.T) (*crashHandler, *bytes.Buffer, *int) {
+	code := -1
+	output := &bytes.Buffer{}
+	h := &crashHandler{
+		output:    output,
+		stderr:    output,
+		reportDir: t.TempDir(),
+		exit:      func(c int) { code = c },
+	}
+	return h, output, &code
+}
+
+func TestWriteCrashReport(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "config")
+	now := time.Date(2026, 10, 1, 12, 30, 0, 0, time.UTC)
+	file, err := writeCrashReport(dir, "index out of range", []byte("goroutine 1 [running]:\nmain.main()\n"), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(file) != "crash-20261001-123000.txt" {
+		t.Errorf("Unexpected report name %s", file)
+	}
+	data, _ := os.ReadFile(file)
+	for _, expected := range []string{"crashed at 2026-10-01T12:30:00Z", "panic: index out of range", "goroutine 1 [running]:"} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("Expected %q in the report:\n%s", expected, data)
+		}
+	}
+}
+
+func TestCrashGuardReportsPanics(t *testing.T) {
+	h, output, code := newTestCrashHandler(t)
+	released := false
+	h.cleanup = func() { released = true }
+	guard := crashGuard{Model: panicModel{}, handler: h}
+
+	guard.Update(tea.KeyMsg{})
+	if *code != exitCrash || !released {
+		t.Fatalf("Expected the handler to release the locks and exit with %d, got %d", exitCrash, *code)
+	}
+	if !strings.HasPrefix(output.String(), terminalReset) || !strings.Contains(output.String(), "crashed: boom") {
+		t.Errorf("Expected the terminal reset before the message, got %q", output.String())
+	}
+	reports, _ := filepath.Glob(filepath.Join(h.reportDir, "crash-*.txt"))
+	if len(reports) != 1 {
+		t.Fatalf("Expected one crash report, got %v", reports)
+	}
+	if data, _ := os.ReadFile(reports[0]); !strings.Contains(string(data), "panicModel.Update") {
+		t.Errorf("Expected the stack trace of the panic in the report:\n%s", data)
+	}
+}
+
+func TestCrashGuardCoversBatchedCommands(t *testing.T) {
+	h, _, code := newTestCrashHandler(t)
+	guard := crashGuard{Model: panicModel{}, handler: h}
+	cmd := guard.guard(tea.Batch(
+		func() tea.Msg { return nil },
+		func() tea.Msg { panic("in a command") },
+	))
+
+	batch, ok := cmd().(tea.BatchMsg)
+	if !ok || len(batch) != 2 {
+		t.Fatalf("Expected the batch to be passed on")
+	}
+	batch[1]()
+	if *code != exitCrash {
+		t.Errorf("Expected a panic in a batched command to be handled")
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// destListing is the destination of a sync, read from an "rclone lsjson -R"
+// dump given with --dest-listing. With its annotations on (A), the tree
+// marks the destination files that "rclone sync --delete-excluded" would
+// delete because the current rules exclude them.
+type destListing struct {
+	files map[string]int64 // Sizes by path relative to the root
+	show  bool
+
+	// Deletions under the rules of filterGen, worked out again once the
+	// rules or the tree change
+	key     deletionKey
+	deleted map[string]bool          // Files that would be deleted
+	dirs    map[string]deletionCount // Totals by directory, "" for the root
+}
+
+// deletionKey is what the deletions were worked out for
+type deletionKey struct {
+	gen, files int
+}
+
+// deletionCount sums up the deletions below a directory
+type deletionCount struct {
+	files    int
+	size     int64
+	onlyDest int // Files the source doesn't have, so the tree can't show them
+}
+
+// readDestListing reads an "rclone lsjson -R" dump of the destination
+func readDestListing(file string) (*destListing, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var items []lsjsonItem
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("not an rclone lsjson listing: %w", err)
+	}
+	return newDestListing(items), nil
+}
+
+func newDestListing(items []lsjsonItem) *destListing {
+	d := &destListing{files: make(map[string]int64), key: deletionKey{gen: -1}}
+	for _, item := range items {
+		if p := strings.Trim(item.Path, "/"); p != "" && !item.IsDir {
+			d.files[p] = item.Size
+		}
+	}
+	return d
+}
+
+// deletions works out which destination files the rules exclude, unless it
+// did so for the same rules and tree already
+func (m *Model) deletions() *destListing {
+	d := m.destListing
+	top := m.topLevelNodes()[0]
+	key := deletionKey{gen: m.filterGen, files: top.TotalFiles}
+	if d.key == key {
+		return d
+	}
+	d.key = key
+	d.deleted = make(map[string]bool)
+	d.dirs = make(map[string]deletionCount)
+
+	source := make(map[string]bool)
+	for _, node := range collectNodes(top, nil) {
+		if !node.IsDir {
+			_, rel := nodeLocation(node)
+			source[rel] = true
+		}
+	}
+	m.activateRootFor(top)
+	for rel, size := range d.files {
+		if m.getEffectiveFilterWithMap("/"+rel) != FilterExclude {
+			continue
+		}
+		d.deleted[rel] = true
+		for dir := rel; dir != ""; {
+			dir = listingParent(dir)
+			count := d.dirs[dir]
+			count.files++
+			count.size += size
+			if !source[rel] {
+				count.onlyDest++
+			}
+			d.dirs[dir] = count
+		}
+	}
+	return d
+}
+
+// deletionLabel is the marker shown after the size of node while the
+// annotations are on
+func (m *Model) deletionLabel(node *FileNode) string {
+	if m.destListing == nil || !m.destListing.show || m.isHiddenRoot(node) {
+		return ""
+	}
+	d := m.deletions()
+	_, rel := nodeLocation(node)
+	if !node.IsDir {
+		if d.deleted[rel] {
+			return " deleted on dest"
+		}
+		return ""
+	}
+	count := d.dirs[rel]
+	if count.files == 0 {
+		return ""
+	}
+	label := fmt.Sprintf(" %d deleted on dest", count.files)
+	if count.onlyDest > 0 {
+		label += fmt.Sprintf(" (%d only there)", count.onlyDest)
+	}
+	return label
+}
+
+// toggleDeletions switches the annotations of --dest-listing on or off
+func (m *Model) toggleDeletions() {
+	if m.destListing == nil {
+		m.statusMsg = "No destination listing, start with --dest-listing FILE"
+		return
+	}
+	m.destListing.show = !m.destListing.show
+	if !m.destListing.show {
+		m.statusMsg = "Destination deletions hidden"
+		return
+	}
+	m.statusMsg = m.deletionSummary()
+}
+
+// deletionSummary tells how much --delete-excluded would delete
+func (m *Model) deletionSummary() string {
+	total := m.deletions().dirs[""]
+	if total.files == 0 {
+		return "--delete-excluded deletes nothing on the destination"
+	}
+	return fmt.Sprintf("--delete-excluded deletes %d files (%s) on the destination, %d of them not in the source",
+		total.files, formatSize(total.size), total.onlyDest)
+}
+
+// deletionHeader is the part of the header counting the deletions
+func (m *Model) deletionHeader() string {
+	if m.destListing == nil || !m.destListing.show {
+		return ""
+	}
+	total := m.deletions().dirs[""]
+	return fmt.Sprintf(" | --delete-excluded deletes %d files (%s) (A)", total.files, formatSize(total.size))
+}
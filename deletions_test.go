@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestDeleteExcludedAnnotations(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, big, small := newGuardTestModel()
+	model.width, model.height = 120, 30
+	model.destListing = newDestListing([]lsjsonItem{
+		{Path: "big", IsDir: true},
+		{Path: "big/0.bin", Size: 1024},
+		{Path: "big/old.bin", Size: 2048},
+		{Path: "small/8.bin", Size: 1024},
+	})
+	model.setNodeFilter(big, FilterExclude)
+
+	updated, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("A")})
+	m := updated.(Model)
+	if want := "--delete-excluded deletes 2 files (3.0 KiB) on the destination, 1 of them not in the source"; m.statusMsg != want {
+		t.Errorf("Status = %q; want %q", m.statusMsg, want)
+	}
+	if label := m.deletionLabel(big); label != " 2 deleted on dest (1 only there)" {
+		t.Errorf("Label of big = %q", label)
+	}
+	if label := m.deletionLabel(big.Children[0]); label != " deleted on dest" {
+		t.Errorf("Label of big/0.bin = %q", label)
+	}
+	if label := m.deletionLabel(big.Children[1]); label != "" {
+		t.Errorf("A file missing from the destination should not be marked, got %q", label)
+	}
+	if label := m.deletionLabel(small); label != "" {
+		t.Errorf("Nothing under small is excluded, got %q", label)
+	}
+	if view := m.View(); !strings.Contains(view, "--delete-excluded deletes 2 files (3.0 KiB) (A)") {
+		t.Errorf("Expected the deletions in the header:\n%s", view)
+	}
+
+	// Changing the rules counts again
+	m.setNodeFilter(small, FilterExclude)
+	if label := m.deletionLabel(small); label != " 1 deleted on dest" {
+		t.Errorf("Label of small after excluding it = %q", label)
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("A")})
+	m = updated.(Model)
+	if label := m.deletionLabel(big); label != "" {
+		t.Errorf("Annotations should be hidden again, got %q", label)
+	}
+}
+
+func TestDeleteExcludedWithoutListing(t *testing.T) {
+	model := newTestModel()
+	updated, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("A")})
+	if msg := updated.(Model).statusMsg; !strings.Contains(msg, "--dest-listing") {
+		t.Errorf("Expected a hint at --dest-listing, got %q", msg)
+	}
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// remoteMirror compares the files of the tree with the destination of a
+// remote-to-remote sync given with --dest, listed once with
+// "rclone lsjson -R", so that the files already there can be left out
+// before syncing
+type remoteMirror struct {
+	files  map[string]lsjsonItem     // By path relative to the destination
+	states map[*FileNode]mirrorState // Files compared so far
+
+	byHash   map[string][]lsjsonItem // By "type:value" of each listed checksum
+	hashKind string                  // Checksum local files can be hashed with to match, if any
+	hashes   map[*FileNode]string    // Checksums of local files hashed so far
+}
+
+func newRemoteMirror(items []lsjsonItem) *remoteMirror {
+	rm := &remoteMirror{
+		files:    make(map[string]lsjsonItem),
+		states:   make(map[*FileNode]mirrorState),
+		byHash:   make(map[string][]lsjsonItem),
+		hashKind: destHashKind(items),
+	}
+	for _, item := range items {
+		if p := strings.Trim(item.Path, "/"); p != "" && !item.IsDir {
+			rm.files[p] = item
+			for kind, value := range item.Hashes {
+				if value != "" {
+					key := hashKey(kind + ":" + value)
+					rm.byHash[key] = append(rm.byHash[key], item)
+				}
+			}
+		}
+	}
+	return rm
+}
+
+func (rm *remoteMirror) name() string {
+	return "dest"
+}
+
+func (rm *remoteMirror) compare(node *FileNode) mirrorState {
+	if state, ok := rm.states[node]; ok {
+		return state
+	}
+	_, rel := nodeLocation(node)
+	hash := node.Hash
+	if hash == "" {
+		hash = rm.hashes[node]
+	}
+	state := mirrorMissing
+	if item, ok := rm.files[rel]; ok {
+		state = mirrorSame
+		if !sameFileHash(node, hash, item) {
+			state = mirrorDiffers
+		}
+	} else if rm.renamedCopy(node, hash) {
+		state = mirrorRenamed
+	}
+	rm.states[node] = state
+	return state
+}
+
+// renamedCopy reports whether the destination has a file of the size and
+// checksum of node under another path
+func (rm *remoteMirror) renamedCopy(node *FileNode, hash string) bool {
+	if hash == "" {
+		return false
+	}
+	for _, item := range rm.byHash[hashKey(hash)] {
+		if item.Size == node.Size {
+			return true
+		}
+	}
+	return false
+}
+
+// sameFile reports whether item on the destination is a copy of node. The
+// sizes must match, then the checksums when both sides have one of the same
+// type (with --hashes), or else the modification times.
+func sameFile(node *FileNode, item lsjsonItem) bool {
+	return sameFileHash(node, node.Hash, item)
+}
+
+// sameFileHash is sameFile with the checksum of node given as "type:value",
+// such as one hashed in the background
+func sameFileHash(node *FileNode, hash string, item lsjsonItem) bool {
+	if node.Size != item.Size {
+		return false
+	}
+	if kind, value, ok := strings.Cut(hash, ":"); ok && item.Hashes[kind] != "" {
+		return strings.EqualFold(item.Hashes[kind], value)
+	}
+	diff := item.ModTime.Sub(node.ModTime)
+	return diff <= modifyWindow && diff >= -modifyWindow
+}
+
+// destListedMsg carries the listing of a remote --dest
+type destListedMsg struct {
+	items   []lsjsonItem
+	backend string // Type of the remote, "" when unknown
+	err     error
+}
+
+// listSyncDest lists a remote --dest in the background, while the source is
+// scanned
+func (m Model) listSyncDest() tea.Cmd {
+	if m.syncDest == "" || m.mirror != nil {
+		return nil
+	}
+	run := m.rcloneRun
+	if run == nil {
+		run = runRclone
+	}
+	ctx, dest := m.ctx, m.syncDest
+	args := []string{"lsjson", "-R", "--files-only", "--no-mimetype", dest}
+	if m.showDetails || m.hashLocal {
+		args = append(args, "--hash")
+	}
+	return func() tea.Msg {
+		// Without the type the names are just not checked against its encoding
+		var backend string
+		if remotes, err := run(ctx, "listremotes", "--long"); err == nil {
+			backend = remoteBackend(remotes, dest[:strings.Index(dest, ":")])
+		}
+		out, err := run(ctx, args...)
+		if err != nil {
+			return destListedMsg{err: err}
+		}
+		var items []lsjsonItem
+		if err := json.Unmarshal(out, &items); err != nil {
+			return destListedMsg{err: fmt.Errorf("failed to parse rclone lsjson output: %w", err)}
+		}
+		return destListedMsg{items: items, backend: backend}
+	}
+}
+
+// applyDestListing compares the tree with the listed destination from now
+// on. Unless --dest-listing gave another one, the listing also shows what
+// --delete-excluded would delete (A). With --hash-local the local files are
+// hashed next, to be matched by checksum.
+func (m *Model) applyDestListing(msg destListedMsg) tea.Cmd {
+	if msg.err != nil {
+		m.statusMsg = fmt.Sprintf("Listing %s failed: %v", m.syncDest, msg.err)
+		m.syncDest = ""
+		return nil
+	}
+	mirror := newRemoteMirror(msg.items)
+	m.mirror = mirror
+	if m.destListing == nil {
+		m.destListing = newDestListing(msg.items)
+	}
+	m.statusMsg = fmt.Sprintf("Listed %s on %s, = excludes those already there", fileCount(len(mirror.files)), m.syncDest)
+	m.destBackend = msg.backend
+	if warning := m.destEncodingWarning(); warning != "" {
+		m.statusMsg += ". " + warning
+	}
+	if !m.hashLocal {
+		return nil
+	}
+	if mirror.hashKind == "" {
+		m.statusMsg += fmt.Sprintf(" (not hashing local files: %s lists no md5, sha1, sha256 or crc32)", m.syncDest)
+		return nil
+	}
+	return tea.Batch(m.enqueue(m.hashLocalJob(mirror)), refreshTick())
+}
+
+// identicalEntries returns the outermost entries below dir whose kept files
+// all have an identical copy on the destination, and whether that holds for
+// everything dir keeps. Files already excluded don't count either way, and
+// neither do directories keeping none.
+func (m *Model) identicalEntries(dir *FileNode) ([]*FileNode, bool) {
+	if len(dir.Children) == 0 && dir.TotalFiles > 0 {
+		return nil, false // Files not listed, as with --dirs-only
+	}
+	var entries []*FileNode
+	whole := true
+	for _, child := range dir.Children {
+		if child.Filter == FilterExclude {
+			continue
+		}
+		if !child.IsDir {
+			if state := m.mirror.compare(child); state == mirrorSame || state == mirrorRenamed {
+				entries = append(entries, child)
+			} else {
+				whole = false
+			}
+			continue
+		}
+		below, all := m.identicalEntries(child)
+		switch {
+		case all && len(below) > 0:
+			entries = append(entries, child)
+		case !all:
+			whole = false
+			entries = append(entries, below...)
+		}
+	}
+	return entries, whole
+}
+
+// excludeIdentical adds exclude rules for the files already identical on the
+// destination, a directory at a time where all it keeps is there, so that
+// the sync only moves what is new or changed
+func (m *Model) excludeIdentical() {
+	switch {
+	case m.filesFrom != nil:
+		m.statusMsg = "A file list has no rules"
+		return
+	case m.mirror == nil && m.syncDest != "":
+		m.statusMsg = "Still listing " + m.syncDest
+		return
+	case m.mirror == nil:
+		m.statusMsg = "No destination to compare with, start with --dest REMOTE:PATH"
+		return
+	case m.hashingLocal():
+		m.statusMsg = "Still hashing local files (J shows how far along)"
+		return
+	}
+
+	top := m.topLevelNodes()[0]
+	m.activateRootFor(top)
+	entries, _ := m.identicalEntries(top)
+	if len(entries) == 0 {
+		m.statusMsg = "No file kept by the rules is identical on the " + m.mirror.name()
+		return
+	}
+	var files []*FileNode
+	for _, entry := range entries {
+		for _, node := range collectNodes(entry, nil) {
+			if !node.IsDir && node.Filter != FilterExclude {
+				files = append(files, node)
+			}
+		}
+	}
+	applied := m.guardChange(func() {
+		for _, entry := range entries {
+			m.setNodeFilter(entry, FilterExclude)
+		}
+		m.reapplyFiltersToTree(top)
+	})
+	if !applied {
+		return
+	}
+	m.refreshView()
+
+	var impact toggleImpact
+	renamed := 0
+	for _, file := range files {
+		if file.Filter == FilterExclude {
+			impact.excludedFiles++
+			impact.excludedSize += file.Size
+			if m.mirror.compare(file) == mirrorRenamed {
+				renamed++
+			}
+		}
+	}
+	rules := "1 rule"
+	if len(entries) > 1 {
+		rules = fmt.Sprintf("%d rules", len(entries))
+	}
+	m.statusMsg = fmt.Sprintf("Excluded %s, %s already on the %s with %s",
+		fileCount(impact.excludedFiles), formatSize(impact.excludedSize), m.mirror.name(), rules)
+	if renamed > 0 {
+		// Their copies on the destination don't match any rule, so a sync
+		// would delete them as missing from the source
+		m.statusMsg += fmt.Sprintf("; %s there under another name, which rclone sync deletes: use rclone copy", fileCount(renamed))
+	}
+}
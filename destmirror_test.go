@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSameFile(t *testing.T) {
+	modTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	node := &FileNode{Name: "a.txt", Size: 3, ModTime: modTime}
+	hashed := &FileNode{Name: "b.txt", Size: 3, ModTime: modTime, Hash: "md5:ABC"}
+	tests := []struct {
+		name     string
+		node     *FileNode
+		item     lsjsonItem
+		expected bool
+	}{
+		{"same size and time", node, lsjsonItem{Size: 3, ModTime: modTime.Add(500 * time.Millisecond)}, true},
+		{"other size", node, lsjsonItem{Size: 4, ModTime: modTime}, false},
+		{"other time", node, lsjsonItem{Size: 3, ModTime: modTime.Add(time.Hour)}, false},
+		{"same checksum, other time", hashed, lsjsonItem{Size: 3, ModTime: modTime.Add(time.Hour), Hashes: map[string]string{"md5": "abc"}}, true},
+		{"other checksum", hashed, lsjsonItem{Size: 3, ModTime: modTime, Hashes: map[string]string{"md5": "def"}}, false},
+		{"checksum of another type", hashed, lsjsonItem{Size: 3, ModTime: modTime, Hashes: map[string]string{"sha1": "def"}}, true},
+	}
+	for _, tt := range tests {
+		if got := sameFile(tt.node, tt.item); got != tt.expected {
+			t.Errorf("%s: sameFile = %v; want %v", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestExcludeIdenticalOnDest(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, big, small := newGuardTestModel()
+	model.syncDest = "b2:backup"
+	small.Expanded = true
+	model.updateVisibleNodes()
+	var args []string
+	model.rcloneRun = func(ctx context.Context, a ...string) ([]byte, error) {
+		args = a
+		// Everything in big is there already, small/9.bin changed since
+		var items []lsjsonItem
+		for _, file := range big.Children {
+			items = append(items, lsjsonItem{Path: "big/" + file.Name, Size: 1024})
+		}
+		items = append(items, lsjsonItem{Path: "small/8.bin", Size: 1024}, lsjsonItem{Path: "small/9.bin", Size: 10})
+		return json.Marshal(items)
+	}
+
+	m := pressKeys(*model, runeKey("="))
+	if m.statusMsg != "Still listing b2:backup" {
+		t.Errorf("Expected = to wait for the listing, got %q", m.statusMsg)
+	}
+	updated, _ := m.Update(m.listSyncDest()())
+	m = updated.(Model)
+	if strings.Join(args, " ") != "lsjson -R --files-only --no-mimetype b2:backup" {
+		t.Errorf("Unexpected rclone arguments %q", args)
+	}
+	if !strings.Contains(m.View(), "9.bin (1.0 KiB) ≠ dest") {
+		t.Errorf("Expected the rows compared with the destination:\n%s", m.View())
+	}
+	if m.destListing == nil || len(m.destListing.files) != 10 {
+		t.Errorf("Expected the listing to stand in for --dest-listing")
+	}
+
+	m = pressKeys(m, runeKey("="))
+	if m.filters.state("big/**") != FilterExclude || m.filters.state("small/8.bin") != FilterExclude || m.filters.count() != 2 {
+		t.Fatalf("Expected big and small/8.bin excluded, got %v", m.filters.states())
+	}
+	if small.Children[1].Filter == FilterExclude {
+		t.Errorf("Expected the changed file to stay")
+	}
+	if m.statusMsg != "Excluded 9 files, 9.0 KiB already on the dest with 2 rules" {
+		t.Errorf("Unexpected status %q", m.statusMsg)
+	}
+
+	// Excluded files aren't excluded again
+	m = pressKeys(m, runeKey("="))
+	if m.filters.count() != 2 || !strings.HasPrefix(m.statusMsg, "No file kept") {
+		t.Errorf("Expected nothing left to exclude, got %q", m.statusMsg)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// filterDiagnostic is a problem with a line of a filter file
+type filterDiagnostic struct {
+	file    string
+	line    int
+	text    string // The line as written
+	message string
+	dropped bool // The line is left out of the rules, and out of the file when saved
+}
+
+func (d filterDiagnostic) String() string {
+	location := fmt.Sprintf("line %d", d.line)
+	if d.file != "" {
+		location = fmt.Sprintf("%s:%d", d.file, d.line)
+	}
+	return fmt.Sprintf("%s: %s: %s", location, d.text, d.message)
+}
+
+// withFile names the file the diagnostics were found in
+func withFile(diagnostics []filterDiagnostic, file string) []filterDiagnostic {
+	for i := range diagnostics {
+		diagnostics[i].file = file
+	}
+	return diagnostics
+}
+
+// conditionPattern matches patterns written as if filter rules could test
+// sizes or ages, such as "size<1M" or "age > 30d"
+var conditionPattern = regexp.MustCompile(`^(?i)(size|age|min-size|max-size|min-age|max-age|modtime)\s*[<>=]|^[<>]=?\s*\d`)
+
+// checkFilterLine reads a line that isn't blank or a comment as a rule. The
+// diagnostic says what is wrong with it for rclone, which stops at a line
+// that isn't a rule; lines that can't be read as one are dropped.
+func checkFilterLine(line string) (FilterRule, *filterDiagnostic) {
+	var rule FilterRule
+	switch {
+	case strings.HasPrefix(line, "+ "):
+		rule = FilterRule{Pattern: strings.TrimPrefix(line, "+ "), State: FilterInclude}
+	case strings.HasPrefix(line, "- "):
+		rule = FilterRule{Pattern: strings.TrimPrefix(line, "- "), State: FilterExclude}
+	case line == "!":
+		return rule, &filterDiagnostic{text: line, dropped: true,
+			message: "clears the rules above it in rclone, which the editor doesn't support; left out"}
+	case line == "+" || line == "-":
+		return rule, &filterDiagnostic{text: line, dropped: true, message: "has no pattern; left out"}
+	case strings.HasPrefix(line, "+") || strings.HasPrefix(line, "-"):
+		// "+foo/**" was surely meant as "+ foo/**", and is saved that way
+		rule = FilterRule{Pattern: strings.TrimSpace(line[1:]), State: FilterInclude}
+		if line[0] == '-' {
+			rule.State = FilterExclude
+		}
+		return rule, &filterDiagnostic{text: line,
+			message: fmt.Sprintf("missing space after %c, which rclone refuses; read as %q", line[0], rule.String())}
+	default:
+		return rule, &filterDiagnostic{text: line, dropped: true,
+			message: `not a rule, which rclone refuses; rules start with "+ " or "- "; left out`}
+	}
+
+	if conditionPattern.MatchString(rule.Pattern) {
+		return rule, &filterDiagnostic{text: line,
+			message: "only matches a file of that name; filter rules can't test sizes or ages, use rclone's --min-size, --max-size, --min-age or --max-age"}
+	}
+	return rule, nil
+}
+
+// duplicateDiagnostic reports a rule whose pattern was given on an earlier
+// line. rclone and the editor use the first of them.
+func duplicateDiagnostic(line int, text string, first int, otherSign bool) filterDiagnostic {
+	message := fmt.Sprintf("repeats the pattern of line %d", first)
+	if otherSign {
+		message += fmt.Sprintf(" with the other sign; line %d is applied, this line never is", first)
+	}
+	return filterDiagnostic{line: line, text: text, message: message}
+}
+
+// lintFilterFile writes the problems found in a filter file and returns how
+// many there are
+func lintFilterFile(w io.Writer, filename string) (int, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	_, diagnostics, err := parseFilterRulesReport(file)
+	if err != nil {
+		return 0, err
+	}
+	for _, d := range withFile(diagnostics, filename) {
+		fmt.Fprintln(w, d)
+	}
+	return len(diagnostics), nil
+}
+
+// runLint implements "lint FILE...", which checks filter files without
+// opening the editor. It exits with exitFilterError when a file has problems
+// or can't be read.
+func runLint(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Usage: %s lint FILTER_FILE...\n", commandName)
+		return exitNotSaved
+	}
+	code := exitSaved
+	for _, filename := range args {
+		n, err := lintFilterFile(os.Stdout, filename)
+		switch {
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			code = exitFilterError
+		case n > 0:
+			code = exitFilterError
+		}
+	}
+	return code
+}
+
+// updateLoadReport closes the report of problems in the filter files
+func (m Model) updateLoadReport(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter", "esc", "q":
+		m.closeModal(modalLoadReport)
+	case "ctrl+c":
+		m.cancel()
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m Model) renderLoadReport() string {
+	paneStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(currentTheme.Border).
+		Padding(1, 2)
+
+	var lines []string
+	dropped := 0
+	for _, d := range m.loadReport {
+		style := lipgloss.NewStyle().Foreground(currentTheme.Warning)
+		if d.dropped {
+			style = style.Foreground(currentTheme.Exclude)
+			dropped++
+		}
+		lines = append(lines, style.Render(d.String()))
+	}
+
+	// Keep the list within the screen
+	visibleHeight := m.height - 12
+	if visibleHeight < 5 {
+		visibleHeight = 20
+	}
+	if len(lines) > visibleHeight {
+		lines = append(lines[:visibleHeight-1], fmt.Sprintf("... and %d more, see the lint command", len(lines)-visibleHeight+1))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d lines of the filter files need a look:\n\n", len(m.loadReport))
+	b.WriteString(strings.Join(lines, "\n"))
+	b.WriteString("\n\n")
+	if dropped > 0 {
+		fmt.Fprintf(&b, "%d lines were left out and are not written back when saving.\n", dropped)
+	}
+	b.WriteString("Enter/Esc close")
+
+	return m.placePane(paneStyle, b.String())
+}
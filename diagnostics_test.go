@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestParseFilterRulesReport(t *testing.T) {
+	input := strings.Join([]string{
+		"# Photos",    // 1
+		"+ Photos/**", // 2
+		"-*.tmp",      // 3 missing space, kept
+		"; comment",   // 4
+		"!",           // 5 dropped
+		"Videos/**",   // 6 dropped
+		"- size>1G",   // 7 kept
+		"- Photos/**", // 8 repeats line 2
+		"-",           // 9 dropped
+		"- cache/**",  // 10
+	}, "\n")
+	doc, diagnostics, err := parseFilterRulesReport(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var patterns []string
+	for _, rule := range doc.rules {
+		patterns = append(patterns, rule.String())
+	}
+	if want := "+ Photos/**|- *.tmp|- size>1G|- Photos/**|- cache/**"; strings.Join(patterns, "|") != want {
+		t.Errorf("Rules = %q; want %q", strings.Join(patterns, "|"), want)
+	}
+	if doc.state("*.tmp") != FilterExclude {
+		t.Errorf("Expected the rule without a space to be read")
+	}
+
+	expected := []struct {
+		line    int
+		message string
+		dropped bool
+	}{
+		{3, `missing space after -, which rclone refuses; read as "- *.tmp"`, false},
+		{5, "clears the rules above it", true},
+		{6, "not a rule", true},
+		{7, "can't test sizes or ages", false},
+		{8, "repeats the pattern of line 2 with the other sign; line 2 is applied", false},
+		{9, "has no pattern", true},
+	}
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("Got %d diagnostics, want %d: %v", len(diagnostics), len(expected), diagnostics)
+	}
+	for i, e := range expected {
+		d := diagnostics[i]
+		if d.line != e.line || !strings.Contains(d.message, e.message) || d.dropped != e.dropped {
+			t.Errorf("Diagnostic %d = %+v; want line %d with %q, dropped %v", i, d, e.line, e.message, e.dropped)
+		}
+	}
+}
+
+func TestLint(t *testing.T) {
+	dir := t.TempDir()
+	clean := filepath.Join(dir, "clean.txt")
+	broken := filepath.Join(dir, "broken.txt")
+	os.WriteFile(clean, []byte("- *.tmp\n+ **\n"), 0644)
+	os.WriteFile(broken, []byte("- *.tmp\n+docs/**\n"), 0644)
+
+	var out bytes.Buffer
+	if n, err := lintFilterFile(&out, clean); n != 0 || err != nil || out.Len() != 0 {
+		t.Errorf("Expected no problems in %s, got %d, %v, %q", clean, n, err, out.String())
+	}
+	n, err := lintFilterFile(&out, broken)
+	if n != 1 || err != nil || !strings.HasPrefix(out.String(), broken+":2: +docs/**: missing space after +") {
+		t.Errorf("Expected the missing space on line 2, got %d, %v, %q", n, err, out.String())
+	}
+	if _, err := lintFilterFile(&out, filepath.Join(dir, "missing.txt")); err == nil {
+		t.Errorf("Expected an error for a missing file")
+	}
+
+	if code := runLint(nil); code != exitNotSaved {
+		t.Errorf("Expected a usage error without files, got %d", code)
+	}
+}
+
+func TestLoadReportPane(t *testing.T) {
+	model := newTestModel()
+	model.loadReport = []filterDiagnostic{
+		{file: "filter.txt", line: 3, text: "!", message: "clears the rules above it", dropped: true},
+	}
+	model.openModal(modalLoadReport)
+	view := model.View()
+	if !strings.Contains(view, "filter.txt:3: !: clears the rules above it") || !strings.Contains(view, "1 lines were left out") {
+		t.Errorf("Expected the report to list the line, got:\n%s", view)
+	}
+	result := pressKeys(*model, tea.KeyMsg{Type: tea.KeyEsc})
+	if result.modalOpen(modalLoadReport) {
+		t.Errorf("Expected Esc to close the report")
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// filesListedMsg delivers the files of a directory the --dirs-only scan left
+// out, listed once it was expanded
+type filesListedMsg struct {
+	dir   *FileNode
+	files []*FileNode
+	err   error
+}
+
+// listFiles lists the files of dir in the background if the scan left them
+// out, or returns nil
+func (m *Model) listFiles(dir *FileNode) tea.Cmd {
+	if dir == nil || !dir.FilesPending || dir.Loading {
+		return nil
+	}
+	dir.Loading = true
+	s := m.newScanner()
+	s.rootPath = rootPathFor(dir.Path)
+	return func() tea.Msg {
+		files, err := s.listFiles(dir)
+		return filesListedMsg{dir: dir, files: files, err: err}
+	}
+}
+
+// listFiles lists the files in node, leaving out the directories the scan
+// already delivered. Only the immutable fields of node are read.
+func (s *scanner) listFiles(node *FileNode) ([]*FileNode, error) {
+	entries, err := s.lister.List(s.ctx, node.Path)
+	if err != nil {
+		return nil, err
+	}
+	var files []*FileNode
+	for _, entry := range entries {
+		if entry.IsDir {
+			continue
+		}
+		if child, ok := s.childNode(node, entry); ok && !scanExcluded(s.exclude, getFilterPath(child.Path)) {
+			files = append(files, child)
+		}
+	}
+	return files, nil
+}
+
+// applyFilesListed adds the files listed for a directory next to its
+// subdirectories and updates the totals above it
+func (m *Model) applyFilesListed(msg filesListedMsg) {
+	dir := msg.dir
+	dir.Loading = false
+	if msg.err != nil {
+		if !errors.Is(msg.err, context.Canceled) {
+			dir.ListErr = msg.err
+		}
+		return
+	}
+	if !dir.FilesPending {
+		return
+	}
+	m.activateRootFor(dir)
+	for _, file := range msg.files {
+		file.Filter = m.getEffectiveFilterWithMap(getFilterPath(file.Path))
+	}
+	children := append(dir.Children[:len(dir.Children):len(dir.Children)], msg.files...)
+	m.sortChildren(children)
+	dir.Children = children
+	dir.FilesPending = false
+	for node := dir; node != nil; node = node.Parent {
+		sumChildStats(node)
+	}
+	if m.isShown(dir) && dir.Expanded {
+		m.updateVisibleNodes()
+	}
+}
+
+// unlistedStats describes the size of a directory whose files, or some of
+// those below it, weren't listed yet, or returns "" if they all were
+func unlistedStats(node *FileNode) string {
+	switch {
+	case node.UnlistedDirs == 0:
+		return ""
+	case node.FilesPending && node.TotalFiles == 0:
+		return " (files not listed, → lists them)"
+	}
+	return fmt.Sprintf(" (≥%s, ≥%d files)", formatSize(node.TotalSize), node.TotalFiles)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestDirsOnlyScan(t *testing.T) {
+	rootDir := t.TempDir()
+	os.MkdirAll(filepath.Join(rootDir, "a", "deep"), 0755)
+	os.WriteFile(filepath.Join(rootDir, "a", "deep", "1.txt"), []byte("1"), 0644)
+	os.WriteFile(filepath.Join(rootDir, "a", "2.txt"), []byte("22"), 0644)
+	os.WriteFile(filepath.Join(rootDir, "top.txt"), []byte("333"), 0644)
+
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = rootDir
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, apply := newScanTestModel(rootDir)
+	defer model.cancel()
+	model.dirsOnly = true
+	model.filters.set("a/2.txt", FilterExclude)
+
+	if err := model.newScanner().scan(model.root); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	m := apply(*model)
+	root := m.root
+	if len(root.Children) != 1 || root.Children[0].Name != "a" || len(root.Children[0].Children) != 1 {
+		t.Fatalf("Expected only the directories to be scanned, got %v", root.Children)
+	}
+	a := root.Children[0]
+	if !root.FilesPending || !a.FilesPending || root.UnlistedDirs != 3 {
+		t.Errorf("Expected the files of every directory to be pending, got %v, %v, %d", root.FilesPending, a.FilesPending, root.UnlistedDirs)
+	}
+	if got := unlistedStats(a); got != " (files not listed, → lists them)" {
+		t.Errorf("Unexpected stats %q", got)
+	}
+
+	// The root is open, so its files are listed once its scan comes in
+	for _, msg := range runCmd(m.applyDirScan(dirScannedMsg{parent: root, children: root.Children, dirsOnly: true})) {
+		updated, _ := m.Update(msg)
+		m = updated.(Model)
+	}
+	if root.FilesPending || len(root.Children) != 2 || root.TotalFiles != 1 || root.UnlistedDirs != 2 {
+		t.Fatalf("Expected top.txt to be listed, got %v with %d files", root.Children, root.TotalFiles)
+	}
+	if got := unlistedStats(root); got != " (≥3 B, ≥1 files)" {
+		t.Errorf("Unexpected stats %q", got)
+	}
+
+	// Expanding a lists its files, keeping deep as it was
+	m.cursor = 0
+	for i, node := range m.visibleNodes {
+		if node == a {
+			m.cursor = i
+		}
+	}
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRight})
+	m = updated.(Model)
+	if !a.Loading {
+		t.Errorf("Expected a to show as loading while its files are listed")
+	}
+	for _, msg := range runCmd(cmd) {
+		updated, _ := m.Update(msg)
+		m = updated.(Model)
+	}
+	if a.Loading || a.FilesPending || len(a.Children) != 2 || a.Children[0].Name != "deep" && a.Children[1].Name != "deep" {
+		t.Fatalf("Expected a to list 2.txt next to deep, got %v", a.Children)
+	}
+	for _, child := range a.Children {
+		if child.Name == "2.txt" && (child.Filter != FilterExclude || child.Size != 2) {
+			t.Errorf("Expected the listed file to get its size and state, got %+v", child)
+		}
+	}
+	if root.TotalFiles != 2 || root.UnlistedDirs != 1 {
+		t.Errorf("Expected the totals above to include the new file, got %d files, %d unlisted", root.TotalFiles, root.UnlistedDirs)
+	}
+	if m.listFiles(a) != nil {
+		t.Errorf("A directory whose files are listed shouldn't be listed again")
+	}
+}
+
+func TestLocalListerListDirs(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "sub"), 0755)
+	os.WriteFile(filepath.Join(dir, "file.txt"), []byte("x"), 0644)
+
+	entries, err := localLister{}.ListDirs(t.Context(), dir)
+	if err != nil || len(entries) != 1 || entries[0].Name != "sub" || !entries[0].IsDir {
+		t.Errorf("Expected only the subdirectory, got %+v (%v)", entries, err)
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !(linux || darwin || freebsd)
+
+package main
+
+import "io/fs"
+
+// diskUsed returns the space used on the filesystem holding dir. It isn't
+// known on this platform, so home directories are scanned without asking.
+func diskUsed(dir string) (int64, bool) {
+	return 0, false
+}
+
+// deviceOf returns the device of the filesystem holding the file described
+// by info. It isn't known on this platform, so --one-file-system has no
+// effect.
+func deviceOf(info fs.FileInfo) (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,27 @@
+//go:build linux || darwin || freebsd
+
+package main
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// diskUsed returns the space used on the filesystem holding dir
+func diskUsed(dir string) (int64, bool) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return 0, false
+	}
+	return int64(st.Blocks-st.Bfree) * int64(st.Bsize), true
+}
+
+// deviceOf returns the device of the filesystem holding the file described
+// by info
+func deviceOf(info fs.FileInfo) (uint64, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(st.Dev), true
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// duplicatesFoundMsg carries the groups of likely duplicate files found by a
+// duplicate detection job
+type duplicatesFoundMsg struct {
+	groups [][]*FileNode
+}
+
+// duplicatesJob looks for files with the same content anywhere in the tree.
+// Files are grouped by size first; same-sized local files are then compared
+// by SHA-256, while files on remotes, which can't be read cheaply, are
+// matched by size and name.
+func (m *Model) duplicatesJob() *job {
+	return &job{
+		kind:  JobDuplicates,
+		title: "Find duplicates",
+		start: func(m *Model) jobFunc {
+			var files []*FileNode
+			for _, top := range m.topLevelNodes() {
+				for _, node := range collectNodes(top, nil) {
+					if !node.IsDir && node.Size > 0 {
+						files = append(files, node)
+					}
+				}
+			}
+
+			return func(ctx context.Context) jobResult {
+				groups, err := findDuplicates(ctx, files)
+				if err != nil {
+					return jobResult{err: err}
+				}
+
+				var copies int
+				var wasted int64
+				for _, group := range groups {
+					copies += len(group) - 1
+					wasted += int64(len(group)-1) * group[0].Size
+				}
+				return jobResult{
+					msg: duplicatesFoundMsg{groups: groups},
+					detail: fmt.Sprintf("%s groups, %s extra copies using %s",
+						formatCount(len(groups)), formatCount(copies), formatSize(wasted)),
+				}
+			}
+		},
+	}
+}
+
+// findDuplicates groups files that are likely identical. Only the immutable
+// fields of the nodes are read. Groups are ordered by the space taken up by
+// the extra copies, largest first.
+func findDuplicates(ctx context.Context, files []*FileNode) ([][]*FileNode, error) {
+	bySize := make(map[int64][]*FileNode)
+	for _, file := range files {
+		bySize[file.Size] = append(bySize[file.Size], file)
+	}
+
+	var groups [][]*FileNode
+	for _, candidates := range bySize {
+		if len(candidates) < 2 {
+			continue
+		}
+
+		byKey := make(map[string][]*FileNode)
+		for _, file := range candidates {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			key := "name:" + file.Name
+			if !isRemotePath(file.Path) {
+				sum, err := hashFile(file.Path)
+				if err != nil {
+					// Unreadable files can't be compared, leave them out
+					continue
+				}
+				key = "sha256:" + sum
+			}
+			byKey[key] = append(byKey[key], file)
+		}
+
+		for _, group := range byKey {
+			if len(group) < 2 {
+				continue
+			}
+			sort.Slice(group, func(i, j int) bool {
+				return group[i].Path < group[j].Path
+			})
+			groups = append(groups, group)
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		wi := int64(len(groups[i])-1) * groups[i][0].Size
+		wj := int64(len(groups[j])-1) * groups[j][0].Size
+		if wi != wj {
+			return wi > wj
+		}
+		return groups[i][0].Path < groups[j][0].Path
+	})
+	return groups, nil
+}
+
+// hashFile returns the hex encoded SHA-256 of a local file's content
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// applyDuplicates stores the result of a duplicate detection job
+func (m *Model) applyDuplicates(msg duplicatesFoundMsg) {
+	m.duplicates = msg.groups
+	m.duplicateOf = make(map[*FileNode]bool)
+	for _, group := range msg.groups {
+		for _, file := range group {
+			m.duplicateOf[file] = true
+		}
+	}
+	m.dupCursor = 0
+	if len(msg.groups) > 0 && m.pendingToggle == nil {
+		m.statusMsg += " - press D to review"
+	}
+}
+
+// clearDuplicates forgets duplicate results, whose nodes a scan is replacing
+func (m *Model) clearDuplicates() {
+	m.duplicates = nil
+	m.duplicateOf = nil
+	m.closeModal(modalDuplicates)
+}
+
+// duplicateAt returns the group and file at a position in the flattened list
+// shown by the duplicates pane
+func (m *Model) duplicateAt(index int) ([]*FileNode, *FileNode) {
+	for _, group := range m.duplicates {
+		if index < len(group) {
+			return group, group[index]
+		}
+		index -= len(group)
+	}
+	return nil, nil
+}
+
+// duplicateCount returns the number of files listed in the duplicates pane
+func (m *Model) duplicateCount() int {
+	count := 0
+	for _, group := range m.duplicates {
+		count += len(group)
+	}
+	return count
+}
+
+// keepOnlyCopy excludes every file of the group except keep, and makes sure
+// keep itself isn't excluded
+func (m *Model) keepOnlyCopy(group []*FileNode, keep *FileNode) {
+	for _, file := range group {
+		if file == keep {
+			if file.Filter == FilterExclude {
+				m.setNodeFilter(file, FilterNone)
+			}
+			continue
+		}
+		if file.Filter != FilterExclude {
+			m.setNodeFilter(file, FilterExclude)
+		}
+	}
+}
+
+// revealNode expands the ancestors of node and moves the cursor to it
+func (m *Model) revealNode(node *FileNode) {
+	for n := node.Parent; n != nil; n = n.Parent {
+		n.Expanded = true
+	}
+	m.updateVisibleNodes()
+	if i := m.indexOfVisible(node); i >= 0 {
+		m.cursor = i
+		m.adjustScroll()
+	}
+}
+
+// updateDuplicatesPane handles keys while the duplicates pane is open
+func (m Model) updateDuplicatesPane(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		if m.dupCursor > 0 {
+			m.dupCursor--
+		}
+	case "down", "j":
+		if m.dupCursor < m.duplicateCount()-1 {
+			m.dupCursor++
+		}
+	case "e":
+		if group, file := m.duplicateAt(m.dupCursor); file != nil {
+			if m.guardChange(func() { m.keepOnlyCopy(group, file) }) {
+				m.refreshView()
+			}
+		}
+	case "enter":
+		if _, file := m.duplicateAt(m.dupCursor); file != nil {
+			m.closeModal(modalDuplicates)
+			m.revealNode(file)
+		}
+	case "r":
+		m.closeModal(modalDuplicates)
+		return m, tea.Batch(m.enqueue(m.duplicatesJob()), refreshTick())
+	case "D", "esc", "q":
+		m.closeModal(modalDuplicates)
+	case "ctrl+c":
+		m.cancel()
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m Model) renderDuplicates() string {
+	paneStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(currentTheme.Border).
+		Padding(1, 2)
+
+	var lines []string
+	cursorLine := 0
+	index := 0
+	for _, group := range m.duplicates {
+		header := fmt.Sprintf("%d copies of %s", len(group), formatSize(group[0].Size))
+		lines = append(lines, lipgloss.NewStyle().Foreground(currentTheme.Header).Render(header))
+		for _, file := range group {
+			glyph, style := stateStyle(file.Filter)
+			line := fmt.Sprintf("  %s %s", style.Render(glyph), strings.TrimPrefix(getFilterPath(file.Path), "/"))
+			if index == m.dupCursor {
+				line = lipgloss.NewStyle().Background(currentTheme.CursorBg).Foreground(currentTheme.CursorFg).Render(line)
+				cursorLine = len(lines)
+			}
+			lines = append(lines, line)
+			index++
+		}
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "No duplicates found")
+	}
+
+	// Keep the selected file in view when the list is taller than the screen
+	visibleHeight := m.height - 10
+	if visibleHeight < 5 {
+		visibleHeight = 20
+	}
+	start := 0
+	if cursorLine >= visibleHeight {
+		start = cursorLine - visibleHeight + 1
+	}
+	end := start + visibleHeight
+	if end > len(lines) {
+		end = len(lines)
+	}
+
+	var b strings.Builder
+	b.WriteString("Duplicate Files:\n\n")
+	b.WriteString(strings.Join(lines[start:end], "\n"))
+	b.WriteString("\n\n↑/↓ select, e keep only this copy, Enter show in tree, r search again, D or Esc close")
+
+	return m.placePane(paneStyle, b.String())
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) *FileNode {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(content), 0644)
+		return &FileNode{Name: name, Path: path, Size: int64(len(content))}
+	}
+
+	a := write("a.txt", "same content")
+	b := write("b.txt", "same content")
+	c := write("c.txt", "same length!")
+	big1 := write("big1.bin", "a much longer duplicated payload")
+	big2 := write("big2.bin", "a much longer duplicated payload")
+	remote1 := &FileNode{Name: "photo.jpg", Path: "gdrive:x/photo.jpg", Size: 100}
+	remote2 := &FileNode{Name: "photo.jpg", Path: "gdrive:y/photo.jpg", Size: 100}
+	remote3 := &FileNode{Name: "other.jpg", Path: "gdrive:y/other.jpg", Size: 100}
+
+	groups, err := findDuplicates(context.Background(), []*FileNode{a, b, c, big1, big2, remote1, remote2, remote3})
+	if err != nil {
+		t.Fatalf("findDuplicates failed: %v", err)
+	}
+	if len(groups) != 3 {
+		t.Fatalf("Expected 3 groups, got %d", len(groups))
+	}
+
+	// Largest waste first, same-sized files with other content left out
+	if groups[0][0] != remote1 || groups[0][1] != remote2 || len(groups[0]) != 2 {
+		t.Errorf("Remote files should be matched by size and name, got %v", groups[0])
+	}
+	if groups[1][0] != big1 || groups[1][1] != big2 {
+		t.Errorf("Unexpected second group %v", groups[1])
+	}
+	if len(groups[2]) != 2 || groups[2][0] != a || groups[2][1] != b {
+		t.Errorf("Files with the same size but different content should not match, got %v", groups[2])
+	}
+}
+
+func TestKeepOnlyCopy(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/root"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model := newTestModel()
+	a := &FileNode{Name: "a.txt", Path: "/root/a.txt", Filter: FilterExclude}
+	b := &FileNode{Name: "b.txt", Path: "/root/b.txt"}
+	c := &FileNode{Name: "c.txt", Path: "/root/c.txt", Filter: FilterInclude}
+	model.filters.set("a.txt", FilterExclude)
+	model.applyDuplicates(duplicatesFoundMsg{groups: [][]*FileNode{{a, b, c}}})
+
+	group, keep := model.duplicateAt(0)
+	model.keepOnlyCopy(group, keep)
+
+	if a.Filter != FilterNone || b.Filter != FilterExclude || c.Filter != FilterExclude {
+		t.Errorf("Only the kept copy should stay, got %v %v %v", a.Filter, b.Filter, c.Filter)
+	}
+	if _, exists := model.filters.lookup("a.txt"); exists {
+		t.Errorf("The kept copy's exclude rule should be removed")
+	}
+	if model.filters.state("b.txt") != FilterExclude || model.filters.state("c.txt") != FilterExclude {
+		t.Errorf("Other copies should get exclude rules, got %v", model.filters.states())
+	}
+	if !model.duplicateOf[b] || model.duplicateCount() != 3 {
+		t.Errorf("Duplicates should be marked for the tree overlay")
+	}
+}
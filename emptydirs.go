@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// emptyAfterExclusions reports whether everything in a directory is
+// excluded, so that rclone would only create the empty directory on the
+// destination. Directories that were empty to begin with aren't counted.
+func emptyAfterExclusions(node *FileNode) bool {
+	if !node.IsDir || node.Filter == FilterExclude || len(node.Children) == 0 {
+		return false
+	}
+	for _, child := range node.Children {
+		if child.Filter != FilterExclude && !emptyAfterExclusions(child) {
+			return false
+		}
+	}
+	return true
+}
+
+// emptyDirs returns the outermost directories left empty by the exclusions,
+// in tree order
+func (m *Model) emptyDirs() []*FileNode {
+	var dirs []*FileNode
+	var walk func(node *FileNode)
+	walk = func(node *FileNode) {
+		for _, child := range node.Children {
+			if emptyAfterExclusions(child) {
+				dirs = append(dirs, child)
+				continue
+			}
+			walk(child)
+		}
+	}
+	for _, top := range m.topLevelNodes() {
+		walk(top)
+	}
+	return dirs
+}
+
+// excludeEmptyDirs adds an exclude rule for each of dirs
+func (m *Model) excludeEmptyDirs(dirs []*FileNode) {
+	applied := m.guardChange(func() {
+		for _, dir := range dirs {
+			m.setNodeFilter(dir, FilterExclude)
+		}
+	})
+	if !applied {
+		return
+	}
+	m.refreshView()
+	m.statusMsg = fmt.Sprintf("Excluded %d empty directories", len(dirs))
+}
+
+// openEmptyDirs shows the directories left empty by the exclusions, if there
+// are any
+func (m *Model) openEmptyDirs() {
+	if len(m.emptyDirs()) == 0 {
+		m.statusMsg = "No directory is left empty by the exclusions"
+		return
+	}
+	m.openModal(modalEmptyDirs)
+	m.emptyDirCursor = 0
+}
+
+// updateEmptyDirsPane handles keys while the empty directories pane is open
+func (m Model) updateEmptyDirsPane(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	dirs := m.emptyDirs()
+	var selected *FileNode
+	if m.emptyDirCursor >= 0 && m.emptyDirCursor < len(dirs) {
+		selected = dirs[m.emptyDirCursor]
+	}
+
+	switch msg.String() {
+	case "up", "k":
+		if m.emptyDirCursor > 0 {
+			m.emptyDirCursor--
+		}
+	case "down", "j":
+		if m.emptyDirCursor < len(dirs)-1 {
+			m.emptyDirCursor++
+		}
+	case "e":
+		if selected != nil {
+			m.excludeEmptyDirs([]*FileNode{selected})
+			if m.emptyDirCursor >= len(dirs)-1 {
+				m.emptyDirCursor = max(0, len(dirs)-2)
+			}
+			m.setModal(modalEmptyDirs, len(dirs) > 1)
+		}
+	case "a":
+		if len(dirs) > 0 {
+			m.excludeEmptyDirs(dirs)
+		}
+		m.closeModal(modalEmptyDirs)
+	case "enter":
+		if selected != nil {
+			m.closeModal(modalEmptyDirs)
+			m.revealNode(selected)
+		}
+	case "Z", "esc", "q":
+		m.closeModal(modalEmptyDirs)
+	case "ctrl+c":
+		m.cancel()
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m Model) renderEmptyDirs() string {
+	paneStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(currentTheme.Border).
+		Padding(1, 2)
+
+	dirs := m.emptyDirs()
+	var lines []string
+	for i, node := range dirs {
+		top, rel := nodeLocation(node)
+		if m.multiRoot() {
+			rel = top.Name + "/" + rel
+		}
+		line := fmt.Sprintf("%s %s/  (%s excluded files)", currentGlyphs.None, rel, formatCount(node.TotalFiles))
+		if i == m.emptyDirCursor {
+			line = lipgloss.NewStyle().Background(currentTheme.CursorBg).Foreground(currentTheme.CursorFg).Render(line)
+		}
+		lines = append(lines, line)
+	}
+
+	// Keep the selected entry in view when the list is taller than the screen
+	visibleHeight := m.height - 12
+	if visibleHeight < 5 {
+		visibleHeight = 20
+	}
+	start := 0
+	if m.emptyDirCursor >= visibleHeight {
+		start = m.emptyDirCursor - visibleHeight + 1
+	}
+	end := min(start+visibleHeight, len(lines))
+
+	var b strings.Builder
+	b.WriteString("Directories left empty by the exclusions:\n\n")
+	b.WriteString(strings.Join(lines[start:end], "\n"))
+	b.WriteString("\n\nrclone may still create them on the destination.\n")
+	b.WriteString("↑/↓ select, e exclude, a exclude all, Enter show in tree, Esc close")
+
+	return m.placePane(paneStyle, b.String())
+}
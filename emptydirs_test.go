@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestEmptyDirsAfterExclusions(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/root"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	root := &FileNode{Name: "root", Path: "/root", IsDir: true, Expanded: true}
+	cache := &FileNode{Name: "cache", Path: "/root/cache", IsDir: true, Parent: root}
+	inner := &FileNode{Name: "inner", Path: "/root/cache/inner", IsDir: true, Parent: cache}
+	inner.Children = []*FileNode{{Name: "b.tmp", Path: "/root/cache/inner/b.tmp", Parent: inner, Filter: FilterExclude}}
+	cache.Children = []*FileNode{{Name: "a.tmp", Path: "/root/cache/a.tmp", Parent: cache, Filter: FilterExclude}, inner}
+	docs := &FileNode{Name: "docs", Path: "/root/docs", IsDir: true, Parent: root}
+	docs.Children = []*FileNode{
+		{Name: "c.tmp", Path: "/root/docs/c.tmp", Parent: docs, Filter: FilterExclude},
+		{Name: "d.txt", Path: "/root/docs/d.txt", Parent: docs},
+	}
+	empty := &FileNode{Name: "empty", Path: "/root/empty", IsDir: true, Parent: root}
+	root.Children = []*FileNode{cache, docs, empty}
+
+	model := newTestModel()
+	model.root = root
+	model.updateVisibleNodes()
+
+	dirs := model.emptyDirs()
+	if len(dirs) != 1 || dirs[0] != cache {
+		t.Fatalf("Expected only the outermost empty directory cache, got %v", dirs)
+	}
+
+	model.openEmptyDirs()
+	updated, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")})
+	result := updated.(Model)
+	if result.filters.state("cache/**") != FilterExclude {
+		t.Errorf("Expected an exclude rule for cache, got %v", result.filters.states())
+	}
+	if len(result.emptyDirs()) != 0 || result.modalOpen(modalEmptyDirs) {
+		t.Errorf("Expected no empty directories left and the pane closed")
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// rcloneQuote is put before a character to keep rclone from decoding it
+const rcloneQuote = '‛'
+
+// windowsEncoded maps the look-alikes rclone's local backend stores on
+// Windows, for characters Windows doesn't allow in names, back to the
+// characters rclone lists and matches filters against
+var windowsEncoded = map[rune]rune{
+	'＂': '"',
+	'＊': '*',
+	'：': ':',
+	'＜': '<',
+	'＞': '>',
+	'？': '?',
+	'＼': '\\',
+	'｜': '|',
+}
+
+// Look-alikes of a space and a period, stored on Windows at the end of a
+// name, which Windows would drop
+const (
+	windowsRightSpace  = '␠'
+	windowsRightPeriod = '．'
+)
+
+// rcloneLocalName returns a name read from the local filesystem as rclone
+// lists it. Filters match the names rclone lists, which for remotes are what
+// "rclone lsjson" prints, decoded already. Local names on Windows may hold
+// the look-alikes rclone stores for characters Windows doesn't allow, such
+// as "：" for ":", which rclone decodes unless they are quoted with ‛.
+func rcloneLocalName(name string, windows bool) string {
+	if !windows || !strings.ContainsFunc(name, func(r rune) bool { return r >= 0x2400 }) {
+		return name
+	}
+	runes := []rune(name)
+	var b strings.Builder
+	for i := 0; i < len(runes); i++ {
+		r, last := runes[i], i == len(runes)-1
+		if r == rcloneQuote && !last && windowsDecoded(runes[i+1], i+1 == len(runes)-1) != runes[i+1] {
+			// A quoted look-alike is part of the name
+			i++
+			b.WriteRune(runes[i])
+			continue
+		}
+		b.WriteRune(windowsDecoded(r, last))
+	}
+	return b.String()
+}
+
+// windowsDecoded returns the character a look-alike stands for, or r itself
+func windowsDecoded(r rune, last bool) rune {
+	switch {
+	case windowsEncoded[r] != 0:
+		return windowsEncoded[r]
+	case r >= 0x2400 && r <= 0x241f:
+		// Control pictures such as ␀ stand for control characters
+		return r - 0x2400
+	case last && r == windowsRightSpace:
+		return ' '
+	case last && r == windowsRightPeriod:
+		return '.'
+	}
+	return r
+}
+
+// rcloneLocalPath applies rcloneLocalName to each name of a slash separated
+// path on this platform
+func rcloneLocalPath(p string) string {
+	if runtime.GOOS != "windows" {
+		return p
+	}
+	names := strings.Split(p, "/")
+	for i, name := range names {
+		names[i] = rcloneLocalName(name, true)
+	}
+	return strings.Join(names, "/")
+}
+
+// nameEncoding is the part of a backend's default encoding that changes
+// names a local file can have: rclone stores those characters as look-alikes
+// on the remote, and decodes them again when listing it
+type nameEncoding struct {
+	chars       string // Stored as look-alikes anywhere in a name
+	ctl         bool   // Control characters
+	leftSpace   bool
+	leftTilde   bool
+	leftPeriod  bool
+	rightSpace  bool
+	rightPeriod bool
+}
+
+// backendEncodings are the default encodings of the backends that change
+// more than invalid UTF-8, by backend type as "rclone listremotes --long"
+// prints it. An encoding set in the remote's configuration isn't read.
+var backendEncodings = map[string]nameEncoding{
+	"onedrive":   {chars: `"*:<>?\|`, ctl: true, leftSpace: true, leftTilde: true, rightSpace: true, rightPeriod: true},
+	"sharefile":  {chars: `"*:<>?\|`, ctl: true, leftSpace: true, leftPeriod: true, rightSpace: true, rightPeriod: true},
+	"jottacloud": {chars: `"*:<>?|`, ctl: true},
+	"box":        {chars: `\`, ctl: true, rightSpace: true},
+	"dropbox":    {chars: `\`, rightSpace: true},
+	"b2":         {chars: `\`, ctl: true},
+	"pcloud":     {chars: `\`, ctl: true},
+	"ftp":        {ctl: true, rightSpace: true},
+}
+
+// changes reports whether rclone stores name otherwise on the backend
+func (e nameEncoding) changes(name string) bool {
+	switch {
+	case strings.ContainsAny(name, e.chars):
+		return true
+	case e.ctl && strings.ContainsFunc(name, func(r rune) bool { return r < 0x20 || r == 0x7f }):
+		return true
+	case e.leftSpace && strings.HasPrefix(name, " "),
+		e.leftTilde && strings.HasPrefix(name, "~"),
+		e.leftPeriod && strings.HasPrefix(name, "."),
+		e.rightSpace && strings.HasSuffix(name, " "),
+		e.rightPeriod && strings.HasSuffix(name, "."):
+		return true
+	}
+	return false
+}
+
+// remoteBackend returns the type of the remote called name in the output of
+// "rclone listremotes --long", or "" when it isn't listed
+func remoteBackend(listing []byte, name string) string {
+	for _, line := range strings.Split(string(listing), "\n") {
+		if fields := strings.Fields(line); len(fields) >= 2 && fields[0] == name+":" {
+			return fields[1]
+		}
+	}
+	return ""
+}
+
+// destEncodingWarning says how many names kept by the rules the encoding of
+// a remote --dest stores otherwise, or returns "" when there are none. The
+// rules still match them, as rclone lists them decoded, but they show up
+// with look-alikes in the remote's own apps.
+func (m *Model) destEncodingWarning() string {
+	encoding, ok := backendEncodings[m.destBackend]
+	if !ok || m.root == nil {
+		return ""
+	}
+	var changed []string
+	for _, top := range m.topLevelNodes() {
+		for _, node := range collectNodes(top, nil) {
+			if node != top && node.Filter != FilterExclude && encoding.changes(node.Name) {
+				changed = append(changed, node.Name)
+			}
+		}
+	}
+	if len(changed) == 0 {
+		return ""
+	}
+	names := "1 name"
+	if len(changed) > 1 {
+		names = formatCount(len(changed)) + " names"
+	}
+	return fmt.Sprintf("%s kept by the rules will be stored with look-alike characters on %s (%s), such as %q",
+		names, m.syncDest, m.destBackend, changed[0])
+}
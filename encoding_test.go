@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRcloneLocalName(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"plain.txt", "plain.txt"},
+		{"Report： draft？.docx", "Report: draft?.docx"},
+		{"a＜b＞c＂d＊e｜f＼g", `a<b>c"d*e|f\g`},
+		{"ends with space␠", "ends with space "},
+		{"ends with period．", "ends with period."},
+		{"mid␠and．mid", "mid␠and．mid"},
+		{"bell␇", "bell\a"},
+		{"quoted ‛： stays", "quoted ： stays"},
+		{"lone ‛ quote", "lone ‛ quote"},
+		{"日本語", "日本語"},
+	}
+	for _, tt := range tests {
+		if got := rcloneLocalName(tt.name, true); got != tt.want {
+			t.Errorf("rcloneLocalName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if got := rcloneLocalName("Report：.docx", false); got != "Report：.docx" {
+		t.Errorf("Names on other platforms are listed as they are, got %q", got)
+	}
+}
+
+func TestNameEncodingChanges(t *testing.T) {
+	onedrive := backendEncodings["onedrive"]
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"plain.txt", false},
+		{"Report: draft.docx", true},
+		{"ends with space ", true},
+		{"ends with period.", true},
+		{" starts with space", true},
+		{"~lock.docx", true},
+		{"mid~dle.txt", false},
+		{".hidden", false},
+	}
+	for _, tt := range tests {
+		if got := onedrive.changes(tt.name); got != tt.want {
+			t.Errorf("onedrive changes %q = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if backendEncodings["dropbox"].changes("Report: draft.docx") {
+		t.Errorf("Dropbox stores : as it is")
+	}
+}
+
+func TestDestEncodingWarning(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, _, small := newGuardTestModel()
+	small.Children = append(small.Children, &FileNode{Name: "Report: draft.docx", Path: "/test/small/Report: draft.docx", Parent: small})
+	model.syncDest = "od:backup"
+	model.rcloneRun = func(ctx context.Context, a ...string) ([]byte, error) {
+		if a[0] == "listremotes" {
+			return []byte("b2:   b2\nod:   onedrive\n"), nil
+		}
+		return []byte("[]"), nil
+	}
+
+	updated, _ := model.Update(model.listSyncDest()())
+	m := updated.(Model)
+	if want := `1 name kept by the rules will be stored with look-alike characters on od:backup (onedrive), such as "Report: draft.docx"`; !strings.HasSuffix(m.statusMsg, want) {
+		t.Errorf("Expected a warning about the name, got %q", m.statusMsg)
+	}
+
+	// Excluded names are not synced
+	m.setNodeFilter(small.Children[2], FilterExclude)
+	if warning := m.destEncodingWarning(); warning != "" {
+		t.Errorf("Expected no warning for an excluded name, got %q", warning)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"rclone-filter-editor/rclonefilter"
+)
+
+// escapeGlob escapes a path so that, as an rclone pattern, it matches only
+// itself. Besides the glob characters this covers what rclone reads before
+// the pattern: it trims spaces off the ends of lines, and in files without
+// "+ " and "- " prefixes a leading "#" or ";" starts a comment. Those
+// characters are escaped too, with a space written as "[ ]" because a
+// trailing backslash would be trimmed with it.
+func escapeGlob(path string) string {
+	var b strings.Builder
+	last := len(path) - 1
+	for i, r := range path {
+		switch {
+		case strings.ContainsRune(`\*?[]{}`, r):
+			b.WriteRune('\\')
+		case i == 0 && strings.ContainsRune("-+#;!", r):
+			b.WriteRune('\\')
+		case r == ' ' && (i == 0 || i == last):
+			b.WriteString("[ ]")
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// unescapeGlob undoes escapeGlob, returning the path an escaped pattern
+// stands for. Unescaped glob characters are left as they are.
+func unescapeGlob(pattern string) string {
+	return rclonefilter.Unescape(pattern)
+}
+
+// isEscapedPattern reports whether pattern had characters escaped for rclone
+func isEscapedPattern(pattern string) bool {
+	return unescapeGlob(pattern) != pattern
+}
+
+// excludeFromLine returns pattern as a line for --exclude-from, which has no
+// "- " prefix to keep a leading "#" or ";" from starting a comment
+func excludeFromLine(pattern string) string {
+	if strings.HasPrefix(pattern, "#") || strings.HasPrefix(pattern, ";") {
+		return `\` + pattern
+	}
+	return pattern
+}
+
+// warnEscapedPattern tells the user when the rule just written for node
+// needed escaping, so that the rule in the file doesn't come as a surprise
+func (m *Model) warnEscapedPattern(node *FileNode) {
+	if m.filesFrom != nil || node.Filter == FilterNone {
+		return
+	}
+	if pattern := nodeRulePattern(node); isEscapedPattern(pattern) {
+		m.statusMsg = fmt.Sprintf("Wrote the rule as %s, escaped so that rclone matches only %s", pattern, node.Name)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestEscapeGlob(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"Photos/2024", "Photos/2024"},
+		{"Best of [2024]", `Best of \[2024\]`},
+		{"what?*.txt", `what\?\*.txt`},
+		{`back\slash`, `back\\slash`},
+		{"-dash", `\-dash`},
+		{"+plus", `\+plus`},
+		{"#notes", `\#notes`},
+		{"a-b#c", "a-b#c"},
+		{" padded ", "[ ]padded[ ]"},
+		{"in side", "in side"},
+	}
+
+	for _, tt := range tests {
+		got := escapeGlob(tt.path)
+		if got != tt.expected {
+			t.Errorf("escapeGlob(%q) = %q; want %q", tt.path, got, tt.expected)
+		}
+		if back := unescapeGlob(got); back != tt.path {
+			t.Errorf("unescapeGlob(%q) = %q; want %q", got, back, tt.path)
+		}
+		if !matchesRclonePattern(got, "/"+tt.path) {
+			t.Errorf("%q should match %q", got, tt.path)
+		}
+		if !matchesRclonePattern(got+"/**", "/"+tt.path+"/file") {
+			t.Errorf("%q should match below %q", got+"/**", tt.path)
+		}
+	}
+
+	// Escaped patterns match only themselves
+	for _, path := range []string{"/what.txt", "/Best of 2", "/padded"} {
+		for _, pattern := range []string{`what\?\*.txt`, `Best of \[2024\]`, "[ ]padded[ ]"} {
+			if matchesRclonePattern(pattern, path) {
+				t.Errorf("%q should not match %q", pattern, path)
+			}
+		}
+	}
+}
+
+func TestEscapedRuleRoundTrip(t *testing.T) {
+	originalRoot := globalRootPath
+	defer func() { globalRootPath = originalRoot }()
+	globalRootPath = "/test"
+
+	m := newTestModel()
+	root := &FileNode{Name: "test", Path: "/test", IsDir: true}
+	dir := &FileNode{Name: "#drafts [old]", Path: "/test/#drafts [old]", IsDir: true, Parent: root}
+	file := &FileNode{Name: "notes ", Path: "/test/#drafts [old]/notes ", Parent: dir}
+	dir.Children = []*FileNode{file}
+	root.Children = []*FileNode{dir}
+	m.root = root
+
+	m.setNodeFilter(dir, FilterExclude)
+	m.warnEscapedPattern(dir)
+	if !strings.Contains(m.statusMsg, `\#drafts \[old\]/**`) {
+		t.Errorf("Expected a warning naming the escaped rule, got %q", m.statusMsg)
+	}
+	m.setNodeFilter(file, FilterInclude)
+
+	var buf bytes.Buffer
+	if err := m.filters.write(&buf); err != nil {
+		t.Fatalf("Failed to write rules: %v", err)
+	}
+	for _, line := range []string{`- \#drafts \[old\]/**`, `+ \#drafts \[old\]/notes[ ]`} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("Expected %q in:\n%s", line, buf.String())
+		}
+	}
+
+	doc, err := parseFilterRules(&buf)
+	if err != nil {
+		t.Fatalf("Failed to parse rules: %v", err)
+	}
+	reloaded := &Model{filters: doc}
+	if got := reloaded.getEffectiveFilterWithMap("/#drafts [old]"); got != FilterExclude {
+		t.Errorf("Directory state after reload = %v; want exclude", got)
+	}
+	if got := reloaded.getEffectiveFilterWithMap("/#drafts [old]/notes "); got != FilterInclude {
+		t.Errorf("File state after reload = %v; want include", got)
+	}
+	if got := reloaded.getEffectiveFilterWithMap("/#drafts o/notes"); got != FilterNone {
+		t.Errorf("Escaped rules should not match other paths, got %v", got)
+	}
+}
+
+func TestExcludeFromLine(t *testing.T) {
+	for pattern, expected := range map[string]string{
+		"*.tmp":   "*.tmp",
+		"#notes":  `\#notes`,
+		";backup": `\;backup`,
+	} {
+		if got := excludeFromLine(pattern); got != expected {
+			t.Errorf("excludeFromLine(%q) = %q; want %q", pattern, got, expected)
+		}
+	}
+}
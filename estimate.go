@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// estimateListings is how many directories the estimate lists per root,
+// shared between the root's subdirectories
+const estimateListings = 400
+
+// minEstimateListings is the least number of listings each subdirectory of a
+// root gets, however many there are
+const minEstimateListings = 4
+
+// sizeEstimate is the extrapolated size of a directory that hasn't been
+// scanned completely yet
+type sizeEstimate struct {
+	size  int64
+	files int
+}
+
+// estimateReadyMsg carries the listing of each root and the estimated size of
+// their subdirectories
+type estimateReadyMsg struct {
+	root      *FileNode
+	listings  map[*FileNode][]*FileNode
+	estimates map[string]sizeEstimate // Keyed by node path
+	err       error
+}
+
+// estimateJob lists the roots and estimates the size of every subdirectory
+// from a sample of its tree, so the tree can be shown long before a full
+// scan of a giant tree would finish. The exact scan follows as a rescan of
+// each root.
+func (m *Model) estimateJob() *job {
+	root := m.root
+	var names []string
+	for _, node := range m.topLevelNodes() {
+		names = append(names, node.Name)
+	}
+
+	return &job{
+		kind:  JobEstimate,
+		title: "Estimate " + strings.Join(names, ", "),
+		node:  root,
+		start: func(m *Model) jobFunc {
+			m.loadProgress = "Estimating directory sizes..."
+			s := m.newScanner()
+			topLevel := m.topLevelNodes()
+
+			return func(ctx context.Context) jobResult {
+				msg := estimateReadyMsg{
+					root:      root,
+					listings:  make(map[*FileNode][]*FileNode),
+					estimates: make(map[string]sizeEstimate),
+				}
+				var sampled int
+				for _, top := range topLevel {
+					children, err := listChildren(ctx, s.lister, top)
+					if err != nil {
+						msg.err = fmt.Errorf("%s: %w", top.Path, err)
+						return jobResult{msg: msg, err: msg.err}
+					}
+					msg.listings[top] = children
+
+					var dirs []*FileNode
+					total := sizeEstimate{}
+					for _, child := range children {
+						if child.IsDir {
+							dirs = append(dirs, child)
+						} else {
+							total.size += child.Size
+							total.files++
+						}
+					}
+					budget := max(minEstimateListings, estimateListings/max(len(dirs), 1))
+					for _, dir := range dirs {
+						est, listed := sampleDirectory(ctx, s.lister, dir.Path, budget)
+						if err := ctx.Err(); err != nil {
+							return jobResult{err: err}
+						}
+						msg.estimates[dir.Path] = est
+						total.size += est.size
+						total.files += est.files
+						sampled += listed
+					}
+					msg.estimates[top.Path] = total
+				}
+				return jobResult{msg: msg, detail: fmt.Sprintf("sampled %s directories", formatCount(sampled))}
+			}
+		},
+	}
+}
+
+// listChildren lists a directory into new nodes, like a scan does
+func listChildren(ctx context.Context, l lister, node *FileNode) ([]*FileNode, error) {
+	entries, err := l.List(ctx, node.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	rootPath := rootPathFor(node.Path)
+	var children []*FileNode
+	for _, entry := range entries {
+		childPath := joinChildPath(node.Path, entry.Name)
+		if err := validatePath(childPath, rootPath); err != nil {
+			continue
+		}
+		children = append(children, &FileNode{
+			Name:    entry.Name,
+			Path:    childPath,
+			IsDir:   entry.IsDir,
+			Size:    entry.Size,
+			ModTime: entry.ModTime,
+			Hash:    entry.Hash,
+			Loading: entry.IsDir,
+			Parent:  node,
+
+			Unreadable: entry.Unreadable,
+		})
+	}
+	return children, nil
+}
+
+// sampleDirectory lists up to budget directories of the tree below dir,
+// breadth-first, and extrapolates the size of the rest from the average of
+// the directories listed. Returns the estimate and the number of listings.
+func sampleDirectory(ctx context.Context, l lister, dir string, budget int) (sizeEstimate, int) {
+	var est sizeEstimate
+	queue := []string{dir}
+	listed := 0
+	for len(queue) > 0 && listed < budget && ctx.Err() == nil {
+		path := queue[0]
+		queue = queue[1:]
+
+		entries, err := l.List(ctx, path)
+		if err != nil {
+			continue
+		}
+		listed++
+		for _, entry := range entries {
+			if entry.IsDir {
+				queue = append(queue, joinChildPath(path, entry.Name))
+				continue
+			}
+			est.size += entry.Size
+			est.files++
+		}
+	}
+
+	// Directories left unlisted are assumed to hold as much as the average
+	// listed one
+	if listed > 0 && len(queue) > 0 {
+		est.size += est.size / int64(listed) * int64(len(queue))
+		est.files += est.files / listed * len(queue)
+	}
+	return est, listed
+}
+
+// applyEstimate shows the listed roots with their estimated sizes and queues
+// the exact scan of each root. If a root couldn't be listed, a regular scan
+// takes over to report the error.
+func (m *Model) applyEstimate(msg estimateReadyMsg) tea.Cmd {
+	if msg.root != m.root {
+		return nil
+	}
+	if msg.err != nil {
+		return m.enqueue(m.scanJob())
+	}
+
+	m.estimates = msg.estimates
+	var cmds []tea.Cmd
+	for _, top := range m.topLevelNodes() {
+		children, ok := msg.listings[top]
+		if !ok {
+			continue
+		}
+		m.applyDirScan(dirScannedMsg{parent: top, children: children})
+		cmds = append(cmds, m.rescanSubtree(top))
+	}
+	m.updateVisibleNodes()
+	return tea.Batch(cmds...)
+}
+
+// estimateFor returns the estimated size of a directory whose exact size is
+// still being scanned
+func (m *Model) estimateFor(node *FileNode) (sizeEstimate, bool) {
+	est, ok := m.estimates[node.Path]
+	return est, ok
+}
+
+// clearEstimates drops the estimates for node and everything below it once
+// its exact size is known, and reports whether no estimates are left
+func (m *Model) clearEstimates(node *FileNode) bool {
+	if m.estimates == nil {
+		return false
+	}
+	for _, n := range collectNodes(node, nil) {
+		delete(m.estimates, n.Path)
+	}
+	if len(m.estimates) > 0 {
+		return false
+	}
+	m.estimates = nil
+	return true
+}
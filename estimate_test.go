@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// mapLister lists directories from a map of paths to entries
+type mapLister map[string][]dirEntry
+
+func (l mapLister) List(ctx context.Context, dir string) ([]dirEntry, error) {
+	entries, ok := l[dir]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return entries, nil
+}
+
+func TestSampleDirectory(t *testing.T) {
+	l := mapLister{
+		"/data":   {{Name: "x", IsDir: true}, {Name: "y", IsDir: true}, {Name: "z", IsDir: true}, {Name: "f", Size: 100}},
+		"/data/x": {{Name: "f", Size: 100}},
+		"/data/y": {{Name: "f", Size: 100}},
+		"/data/z": {{Name: "f", Size: 100}},
+	}
+
+	est, listed := sampleDirectory(context.Background(), l, "/data", 2)
+	if listed != 2 {
+		t.Errorf("Expected the budget of 2 listings to be used, got %d", listed)
+	}
+	// Two of four directories listed with 100 bytes each, two more assumed alike
+	if est.size != 400 || est.files != 4 {
+		t.Errorf("Expected an estimate of 400 bytes in 4 files, got %+v", est)
+	}
+
+	est, listed = sampleDirectory(context.Background(), l, "/data", 10)
+	if listed != 4 || est.size != 400 || est.files != 4 {
+		t.Errorf("A budget covering the tree should be exact, got %+v after %d listings", est, listed)
+	}
+}
+
+// runCmd runs cmd and any commands batched inside it, returning their
+// messages
+func runCmd(cmd tea.Cmd) []tea.Msg {
+	if cmd == nil {
+		return nil
+	}
+	msg := cmd()
+	if batch, ok := msg.(tea.BatchMsg); ok {
+		var msgs []tea.Msg
+		for _, c := range batch {
+			msgs = append(msgs, runCmd(c)...)
+		}
+		return msgs
+	}
+	return []tea.Msg{msg}
+}
+
+func TestEstimateThenExactScan(t *testing.T) {
+	rootDir := t.TempDir()
+	os.MkdirAll(filepath.Join(rootDir, "a", "deep"), 0755)
+	os.MkdirAll(filepath.Join(rootDir, "b"), 0755)
+	os.WriteFile(filepath.Join(rootDir, "a", "deep", "1.txt"), []byte("1"), 0644)
+	os.WriteFile(filepath.Join(rootDir, "b", "2.txt"), []byte("22"), 0644)
+
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = rootDir
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, apply := newScanTestModel(rootDir)
+	defer model.cancel()
+	model.height = 20
+
+	cmd := model.enqueue(model.estimateJob())
+	if !model.scanning() {
+		t.Errorf("The loading screen should show while estimating")
+	}
+	updated, next := model.Update(cmd())
+	result := updated.(Model)
+
+	if len(result.root.Children) != 2 || result.estimates == nil {
+		t.Fatalf("Expected the root listing with estimates, got %d children", len(result.root.Children))
+	}
+	if est, ok := result.estimateFor(result.root); !ok || est.size != 3 || est.files != 2 {
+		t.Errorf("Unexpected root estimate %+v", est)
+	}
+	if result.scanning() || !strings.Contains(result.View(), "(~1 B, ~1 files)") {
+		t.Errorf("The tree should show estimated sizes while the exact scan runs")
+	}
+
+	// Let the exact scan of the root finish
+	var done tea.Msg
+	for _, msg := range runCmd(next) {
+		if _, ok := msg.(jobDoneMsg); ok {
+			done = msg
+		}
+	}
+	if done == nil {
+		t.Fatalf("Expected the exact scan to run after the estimate")
+	}
+	result = apply(result)
+	updated, _ = result.Update(done)
+	result = updated.(Model)
+
+	if result.estimates != nil {
+		t.Errorf("Estimates should be dropped once the exact scan is done")
+	}
+	if result.root.TotalSize != 3 || result.root.TotalFiles != 2 {
+		t.Errorf("Expected exact totals, got %d bytes in %d files", result.root.TotalSize, result.root.TotalFiles)
+	}
+	if strings.Contains(result.View(), "~") {
+		t.Errorf("No estimates should be shown after the exact scan")
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// expandAll opens every directory on the way of an expansion
+const expandAll = -1
+
+// collapseBelow closes every open directory below node. Closed directories
+// are skipped, so the walk costs no more than the rows that were open.
+func collapseBelow(node *FileNode) {
+	for _, child := range node.Children {
+		if child.IsDir && child.Expanded {
+			child.Expanded = false
+			collapseBelow(child)
+		}
+	}
+}
+
+// expandTree opens each of dirs and the directories below them down to depth
+// levels, or all of them for expandAll, closing those deeper down. The rows
+// are added a chunk at a time like those of a single expansion, so that
+// expanding a huge tree doesn't hold up the keys.
+func (m *Model) expandTree(dirs []*FileNode, depth int) tea.Cmd {
+	for _, dir := range dirs {
+		collapseBelow(dir)
+		dir.Expanded = false
+	}
+	m.updateVisibleNodes()
+
+	m.expandGen++
+	m.expanding = &expandProgress{gen: m.expandGen, depth: depth, queue: dirs}
+	return m.continueExpand()
+}
+
+// collapseTree closes each of dirs and everything below them
+func (m *Model) collapseTree(dirs []*FileNode) {
+	for _, dir := range dirs {
+		collapseBelow(dir)
+		dir.Expanded = false
+	}
+	m.updateVisibleNodes()
+}
+
+// selectedDir returns the selected directory, or the one containing the
+// selected file
+func (m *Model) selectedDir() *FileNode {
+	node := m.selectedNode()
+	if node != nil && !node.IsDir {
+		node = node.Parent
+	}
+	return node
+}
+
+// updateDepthPrompt handles typing the depth to expand the tree to
+func (m Model) updateDepthPrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	input := *m.depthInput
+	if used, cmd := m.editPrompt(&input, msg); used {
+		// A depth is a number
+		input = strings.Map(func(r rune) rune {
+			if r < '0' || r > '9' {
+				return -1
+			}
+			return r
+		}, input)
+		m.depthInput = &input
+		return m, cmd
+	}
+	switch msg.Type {
+	case tea.KeyEnter:
+		depth, err := strconv.Atoi(input)
+		if err != nil || depth < 1 {
+			return m, nil
+		}
+		m.depthInput = nil
+		m.closeModal(modalDepthPrompt)
+		m.statusMsg = fmt.Sprintf("Expanded to depth %d", depth)
+		return m, m.expandTree(m.topLevelNodes(), depth)
+	case tea.KeyEsc:
+		m.depthInput = nil
+		m.closeModal(modalDepthPrompt)
+	}
+	return m, nil
+}
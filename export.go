@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Values accepted by --export
+const (
+	exportIncluded       = "included"
+	exportExcluded       = "excluded"
+	exportIncludeExclude = "include-exclude"
+)
+
+// exportFileList walks the tree below root and writes the path of every file
+// whose effective filter state matches the export mode, one per line and
+// relative to the root, in the format read by rclone's --files-from. Files
+// without a matching rule count as included, as they do for rclone.
+func (m *Model) exportFileList(ctx context.Context, w io.Writer, root, mode string) error {
+	l := m.lister
+	if l == nil {
+		l = localLister{}
+	}
+	rootPath := rootPathFor(root)
+	wantExcluded := mode == exportExcluded
+
+	var walk func(dir string) error
+	walk = func(dir string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		entries, err := l.List(ctx, dir)
+		if err != nil {
+			return err
+		}
+		// Listings from remotes come in no particular order
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Name < entries[j].Name
+		})
+
+		for _, entry := range entries {
+			childPath := joinChildPath(dir, entry.Name)
+			if !isRemotePath(childPath) {
+				if err := validatePath(childPath, rootPath); err != nil {
+					continue
+				}
+			}
+
+			if entry.IsDir {
+				// Rules further down may include files in an excluded directory
+				if err := walk(childPath); err != nil {
+					return fmt.Errorf("%s: %w", childPath, err)
+				}
+				continue
+			}
+
+			filterPath := getFilterPath(childPath)
+			excluded := m.getEffectiveFilterWithMap(filterPath) == FilterExclude
+			if excluded == wantExcluded {
+				if _, err := fmt.Fprintln(w, strings.TrimPrefix(filterPath, "/")); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	return walk(root)
+}
+
+// runExport writes the file list or tree for --export to output ("-" for
+// stdout) and returns the process exit code
+func runExport(m *Model, mode, output string) int {
+	if mode == exportIncludeExclude {
+		return runIncludeExcludeExport(m, output)
+	}
+	if tool, ok := findBackupTool(mode); ok {
+		return runToolExport(m, tool, output)
+	}
+	export := m.exportFileList
+	switch mode {
+	case exportIncluded, exportExcluded:
+	case exportCSV, exportJSON:
+		export = m.exportTree
+	default:
+		fmt.Fprintf(os.Stderr, "Error: --export must be %q, %q, %q, %q, %q, %q, %q or %q\n",
+			exportIncluded, exportExcluded, exportIncludeExclude, exportCSV, exportJSON, exportRestic, exportBorg, exportRsync)
+		return exitNotSaved
+	}
+
+	var out io.Writer = os.Stdout
+	if output != stdioFilterFile {
+		if err := validateFilterFilePath(output); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid export file: %v\n", err)
+			return exitNotSaved
+		}
+		file, err := os.Create(output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitNotSaved
+		}
+		defer func() {
+			if closeErr := file.Close(); closeErr != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to close file: %v\n", closeErr)
+			}
+		}()
+		out = file
+	}
+
+	writer := bufio.NewWriter(out)
+	if err := export(m.ctx, writer, m.root.Path, mode); err != nil {
+		fmt.Fprintf(os.Stderr, "Error scanning: %v\n", err)
+		return exitScanError
+	}
+	if err := writer.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing export: %v\n", err)
+		return exitNotSaved
+	}
+
+	switch {
+	case output == stdioFilterFile:
+	case mode == exportCSV || mode == exportJSON:
+		infof("Wrote the tree as %s to %s\n", strings.ToUpper(mode), output)
+	default:
+		infof("Wrote %s files to %s\n", mode, output)
+	}
+	return exitSaved
+}
+
+// includeExcludeRules turns the rules into the lines of the files for
+// rclone's --include-from and --exclude-from, leaving the one not needed
+// empty. rclone reads include rules before exclude rules and adds an
+// implied "- **" after them whenever --include-from is given, so:
+//
+//   - Rules without includes become an exclude file of their patterns.
+//   - Rules with includes can't be expressed with patterns in that order,
+//     so the include file lists what they let through instead: whole
+//     directories as "/dir/**" and single files below partly excluded ones.
+func (m *Model) includeExcludeRules(ctx context.Context, root string) (include, exclude []string, err error) {
+	hasIncludes := m.filesFrom != nil
+	for _, rule := range m.filters.rules {
+		hasIncludes = hasIncludes || (rule.Include == "" && !rule.Disabled && rule.State == FilterInclude)
+	}
+	if !hasIncludes {
+		for _, rule := range m.savedRules() {
+			if !rule.Disabled && rule.Include == "" {
+				exclude = append(exclude, excludeFromLine(rule.Pattern))
+			}
+		}
+		return nil, exclude, nil
+	}
+
+	l := m.lister
+	if l == nil {
+		l = localLister{}
+	}
+	rootPath := rootPathFor(root)
+
+	// walk adds the included entries below dir and reports whether all of
+	// them are included, so that the caller can use dir/** instead
+	var walk func(dir string) ([]string, bool, error)
+	walk = func(dir string) ([]string, bool, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, false, err
+		}
+		entries, err := l.List(ctx, dir)
+		if err != nil {
+			return nil, false, err
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Name < entries[j].Name
+		})
+
+		var lines []string
+		all := true
+		for _, entry := range entries {
+			childPath := joinChildPath(dir, entry.Name)
+			if !isRemotePath(childPath) {
+				if err := validatePath(childPath, rootPath); err != nil {
+					continue
+				}
+			}
+			filterPath := getFilterPath(childPath)
+			// Anchored, so that the pattern doesn't match at any depth
+			pattern := "/" + escapeGlob(strings.TrimPrefix(filterPath, "/"))
+
+			if entry.IsDir {
+				below, whole, err := walk(childPath)
+				if err != nil {
+					return nil, false, fmt.Errorf("%s: %w", childPath, err)
+				}
+				if whole {
+					lines = append(lines, pattern+"/**")
+				} else {
+					lines = append(lines, below...)
+					all = false
+				}
+				continue
+			}
+
+			if m.getEffectiveFilterWithMap(filterPath) == FilterExclude {
+				all = false
+				continue
+			}
+			lines = append(lines, pattern)
+		}
+		return lines, all, nil
+	}
+
+	include, _, err = walk(root)
+	if include == nil && err == nil {
+		// Nothing gets through, which an empty include file says: rclone
+		// excludes everything, where an empty exclude file would keep it all
+		include = []string{}
+	}
+	return include, nil, err
+}
+
+// runIncludeExcludeExport writes the rules as output.include or
+// output.exclude, whichever rclone needs, and returns the process exit code.
+// Only one of them is written: an empty --include-from still makes rclone
+// exclude everything.
+func runIncludeExcludeExport(m *Model, output string) int {
+	if output == stdioFilterFile {
+		fmt.Fprintf(os.Stderr, "Error: --export %s needs --export-file to name the files after\n", exportIncludeExclude)
+		return exitNotSaved
+	}
+
+	include, exclude, err := m.includeExcludeRules(m.ctx, m.root.Path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error scanning: %v\n", err)
+		return exitScanError
+	}
+	name, flagName, lines := output+".exclude", "--exclude-from", exclude
+	if include != nil {
+		name, flagName, lines = output+".include", "--include-from", include
+	}
+	if err := validateFilterFilePath(name); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid export file: %v\n", err)
+		return exitNotSaved
+	}
+
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(line + "\n")
+	}
+	if err := os.WriteFile(name, []byte(b.String()), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitNotSaved
+	}
+	infof("Wrote %s, use it with rclone %s %s\n", name, flagName, name)
+	return exitSaved
+}
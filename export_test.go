@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportFileList(t *testing.T) {
+	rootDir := t.TempDir()
+	os.MkdirAll(filepath.Join(rootDir, "cache", "keep"), 0755)
+	os.MkdirAll(filepath.Join(rootDir, "docs"), 0755)
+	os.WriteFile(filepath.Join(rootDir, "cache", "tmp.bin"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(rootDir, "cache", "keep", "notes.txt"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(rootDir, "docs", "readme.md"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(rootDir, "top.txt"), []byte("x"), 0644)
+
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = rootDir
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model := newTestModelWithStates(map[string]FilterState{
+		"cache/**":      FilterExclude,
+		"cache/keep/**": FilterInclude,
+	})
+
+	tests := []struct {
+		mode     string
+		expected string
+	}{
+		{exportIncluded, "cache/keep/notes.txt\ndocs/readme.md\ntop.txt\n"},
+		{exportExcluded, "cache/tmp.bin\n"},
+	}
+
+	for _, tc := range tests {
+		var b strings.Builder
+		if err := model.exportFileList(context.Background(), &b, rootDir, tc.mode); err != nil {
+			t.Fatalf("Export of %s files failed: %v", tc.mode, err)
+		}
+		if b.String() != tc.expected {
+			t.Errorf("Export of %s files = %q; want %q", tc.mode, b.String(), tc.expected)
+		}
+	}
+}
+
+func TestExportMissingRoot(t *testing.T) {
+	model := newTestModel()
+	var b strings.Builder
+	if err := model.exportFileList(context.Background(), &b, filepath.Join(t.TempDir(), "missing"), exportIncluded); err == nil {
+		t.Errorf("Exporting a missing directory should fail")
+	}
+}
+
+func TestIncludeExcludeRules(t *testing.T) {
+	rootDir := t.TempDir()
+	os.MkdirAll(filepath.Join(rootDir, "cache", "keep"), 0755)
+	os.MkdirAll(filepath.Join(rootDir, "docs"), 0755)
+	os.WriteFile(filepath.Join(rootDir, "cache", "tmp.bin"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(rootDir, "cache", "keep", "notes.txt"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(rootDir, "a[1].txt"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(rootDir, "docs", "readme.md"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(rootDir, "top.txt"), []byte("x"), 0644)
+
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = rootDir
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	// Without includes the patterns go to the exclude file as they are
+	model := newTestModelWithStates(map[string]FilterState{
+		"cache/**": FilterExclude,
+		"*.md":     FilterExclude,
+	})
+	include, exclude, err := model.includeExcludeRules(context.Background(), rootDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if include != nil || strings.Join(exclude, ",") != "*.md,cache/**" {
+		t.Errorf("Expected only excludes, got %q and %q", include, exclude)
+	}
+
+	// With an include the implied "- **" of rclone would exclude the files
+	// no rule matches, so the include file lists what gets through
+	model = newTestModelWithStates(map[string]FilterState{
+		"cache/**":      FilterExclude,
+		"cache/keep/**": FilterInclude,
+	})
+	include, exclude, err = model.includeExcludeRules(context.Background(), rootDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"/a\\[1\\].txt", "/cache/keep/**", "/docs/**", "/top.txt"}
+	if exclude != nil || strings.Join(include, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected includes %q, got %q and %q", want, include, exclude)
+	}
+}
+
+func TestIncludeExcludeExportWithNothingIncluded(t *testing.T) {
+	rootDir := t.TempDir()
+	os.MkdirAll(filepath.Join(rootDir, "docs"), 0755)
+	os.WriteFile(filepath.Join(rootDir, "docs", "readme.md"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(rootDir, "top.txt"), []byte("x"), 0644)
+
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = rootDir
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	// The include matches nothing that is there, so nothing gets through
+	model := newTestModelWithStates(map[string]FilterState{
+		"photos/**": FilterInclude,
+		"**":        FilterExclude,
+	})
+	model.ctx = context.Background()
+	model.root = &FileNode{Name: filepath.Base(rootDir), Path: rootDir, IsDir: true}
+	output := filepath.Join(t.TempDir(), "rules")
+	if code := runIncludeExcludeExport(model, output); code != exitSaved {
+		t.Fatalf("Export failed with exit code %d", code)
+	}
+
+	// An empty include file makes rclone exclude everything, as the rules
+	// do, where an empty exclude file would copy it all
+	data, err := os.ReadFile(output + ".include")
+	if err != nil || len(data) != 0 {
+		t.Errorf("Expected an empty include file, got %q, %v", data, err)
+	}
+	if _, err := os.Stat(output + ".exclude"); err == nil {
+		t.Errorf("Expected no exclude file")
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// extensionRule is a rule for every file with an extension, in the
+// directory of the selected file or anywhere in the tree, with the effect it
+// would have
+type extensionRule struct {
+	pattern string
+	state   FilterState
+	files   int // Files with the extension it applies to
+	size    int64
+	impact  toggleImpact
+}
+
+// extensionMenu offers the rules for the extension of a file
+type extensionMenu struct {
+	file  *FileNode
+	ext   string
+	rules []extensionRule // Exclude and include in the directory, then anywhere
+}
+
+// extensionRuleKeys apply the rules of the menu in its order with one key
+var extensionRuleKeys = []string{"d", "D", "t", "T"}
+
+// fileExtension returns the extension of a file name without the dot, or ""
+// for a name without one or a hidden file such as .profile
+func fileExtension(name string) string {
+	ext := path.Ext(name)
+	if ext == "" || ext == name {
+		return ""
+	}
+	return ext[1:]
+}
+
+// extensionPatterns returns the pattern matching the files with ext in dir
+// alone, and the one matching them at any depth
+func extensionPatterns(dir *FileNode, ext string) (string, string) {
+	glob := "*." + escapeGlob(ext)
+	prefix := strings.Trim(getFilterPath(dir.Path), "/")
+	if prefix == "" || prefix == "." {
+		return "/" + glob, "**/" + glob
+	}
+	return escapeGlob(prefix) + "/" + glob, "**/" + glob
+}
+
+// openExtensionMenu offers to exclude or include the files with the
+// extension of the selected file, showing what each rule would change
+func (m *Model) openExtensionMenu() {
+	if m.filesFrom != nil {
+		m.statusMsg = "A file list has no rules"
+		return
+	}
+	file := m.selectedNode()
+	if file == nil || file.IsDir || file.Parent == nil {
+		m.statusMsg = "Select a file to add rules for its extension"
+		return
+	}
+	ext := fileExtension(file.Name)
+	if ext == "" {
+		m.statusMsg = fmt.Sprintf("%s has no extension", file.Name)
+		return
+	}
+
+	m.activateRootFor(file)
+	top := file
+	for top.Parent != nil {
+		top = top.Parent
+	}
+	dirPattern, treePattern := extensionPatterns(file.Parent, ext)
+	menu := &extensionMenu{file: file, ext: ext}
+	for _, scope := range []struct {
+		pattern string
+		dir     *FileNode
+		deep    bool
+	}{
+		{dirPattern, file.Parent, false},
+		{treePattern, top, true},
+	} {
+		var files []*FileNode
+		collectExtensionFiles(scope.dir, "."+ext, scope.deep, &files)
+		for _, state := range []FilterState{FilterExclude, FilterInclude} {
+			rule := extensionRule{pattern: scope.pattern, state: state, files: len(files)}
+			for _, f := range files {
+				rule.size += f.Size
+			}
+			m.tryRule(scope.pattern, state, func() {
+				for _, f := range files {
+					m.addFileImpact(f, &rule.impact)
+				}
+			})
+			menu.rules = append(menu.rules, rule)
+		}
+	}
+	m.extensionMenu = menu
+	m.extensionCursor = 0
+	m.openModal(modalExtensionMenu)
+}
+
+// collectExtensionFiles adds the files of dir whose name ends with suffix,
+// and with deep those of the directories below it
+func collectExtensionFiles(dir *FileNode, suffix string, deep bool, files *[]*FileNode) {
+	for _, child := range dir.Children {
+		switch {
+		case child.IsDir:
+			if deep {
+				collectExtensionFiles(child, suffix, deep, files)
+			}
+		case strings.HasSuffix(child.Name, suffix) && child.Name != suffix:
+			*files = append(*files, child)
+		}
+	}
+}
+
+// applyExtensionRule adds the rule, shows its effect on the tree and in the
+// status line, and keeps it in the activity trail like a toggle
+func (m *Model) applyExtensionRule(rule extensionRule) {
+	if blocked := m.toggleBlocked(rule.impact); blocked != "" {
+		m.statusMsg = blocked
+		return
+	}
+	file := m.extensionMenu.file
+	m.filterGen++
+	m.activateRootFor(file)
+	change := ruleChange{pattern: rule.pattern, before: m.filters.state(rule.pattern), after: rule.state, impact: rule.impact}
+	m.setRule(rule.pattern, rule.state)
+	top := file
+	for top.Parent != nil {
+		top = top.Parent
+	}
+	m.reapplyFiltersToTree(top)
+	m.updateVisibleNodes()
+	m.recordChange(change)
+}
+
+// updateExtensionMenu handles keys while the extension rules are offered
+func (m Model) updateExtensionMenu(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	rules := m.extensionMenu.rules
+	switch key := msg.String(); key {
+	case "up", "k":
+		if m.extensionCursor > 0 {
+			m.extensionCursor--
+		}
+	case "down", "j":
+		if m.extensionCursor < len(rules)-1 {
+			m.extensionCursor++
+		}
+	case "enter", "d", "D", "t", "T":
+		i := m.extensionCursor
+		if key != "enter" {
+			i = slices.Index(extensionRuleKeys, key)
+		}
+		m.closeModal(modalExtensionMenu)
+		m.applyExtensionRule(rules[i])
+		m.extensionMenu = nil
+		return m, m.sizeDeltaTimeout()
+	case "esc", "q", "n":
+		m.closeModal(modalExtensionMenu)
+		m.extensionMenu = nil
+	case "ctrl+c":
+		m.cancel()
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m Model) renderExtensionMenu() string {
+	paneStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(currentTheme.Border).
+		Padding(1, 2)
+
+	menu := m.extensionMenu
+	var b strings.Builder
+	fmt.Fprintf(&b, "Rules for the .%s files in %s, then anywhere in the tree:\n\n", menu.ext, menu.file.Parent.Name)
+	width := 0
+	for _, rule := range menu.rules {
+		width = max(width, len(rule.pattern))
+	}
+	for i, rule := range menu.rules {
+		if i == 2 {
+			b.WriteString("\n")
+		}
+		row := fmt.Sprintf("%s  %-*s  %s, %s. %s", extensionRuleKeys[i], width+2, FilterRule{Pattern: rule.pattern, State: rule.state},
+			fileCount(rule.files), formatSize(rule.size), rule.impact)
+		style := lipgloss.NewStyle()
+		if i == m.extensionCursor {
+			style = style.Background(currentTheme.CursorBg).Foreground(currentTheme.CursorFg)
+		}
+		b.WriteString(style.Render(row) + "\n")
+	}
+	b.WriteString("\n↑/↓ select, Enter or d/D/t/T add the rule, Esc close")
+
+	return m.placePane(paneStyle, b.String())
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestFileExtension(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"a.jpg", "jpg"},
+		{"archive.tar.gz", "gz"},
+		{"README", ""},
+		{".profile", ""},
+		{"trailing.", ""},
+	}
+	for _, tt := range tests {
+		if got := fileExtension(tt.name); got != tt.expected {
+			t.Errorf("fileExtension(%q) = %q; want %q", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestExtensionPatterns(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	dir, tree := extensionPatterns(&FileNode{Path: "/test/Best of [2024]", IsDir: true}, "jpg")
+	if dir != `Best of \[2024\]/*.jpg` || tree != "**/*.jpg" {
+		t.Errorf("extensionPatterns = %q, %q", dir, tree)
+	}
+	if dir, _ := extensionPatterns(&FileNode{Path: "/test", IsDir: true}, "jpg"); dir != "/*.jpg" {
+		t.Errorf("Expected the files at the top to be matched there alone, got %q", dir)
+	}
+}
+
+func TestExtensionMenu(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, big, small := newGuardTestModel()
+	small.Expanded = true
+	model.updateVisibleNodes()
+	model.cursor = model.indexOfVisible(small.Children[0])
+
+	m := pressKeys(*model, runeKey("n"))
+	if m.topModal() != modalExtensionMenu {
+		t.Fatalf("Expected n on a file to offer rules for its extension")
+	}
+	view := m.renderExtensionMenu()
+	for _, expected := range []string{
+		"d  - small/*.bin  2 files, 2.0 KiB. This excludes 2 files / 2.0 KiB",
+		"T  + **/*.bin     10 files, 10.0 KiB. This changes no files",
+	} {
+		if !strings.Contains(view, expected) {
+			t.Errorf("Expected %q in the menu:\n%s", expected, view)
+		}
+	}
+	if m.filters.count() != 0 {
+		t.Errorf("Expected the preview to leave the rules alone, got %v", m.filters.states())
+	}
+
+	// t excludes the extension anywhere in the tree
+	m = pressKeys(m, runeKey("t"))
+	if m.topModal() == modalExtensionMenu || m.filters.state("**/*.bin") != FilterExclude {
+		t.Fatalf("Expected t to add - **/*.bin, got %v", m.filters.states())
+	}
+	if big.Children[0].Filter != FilterExclude || !strings.HasPrefix(m.statusMsg, "added `- **/*.bin` — 10 files") {
+		t.Errorf("Expected every .bin file to be excluded, got %q", m.statusMsg)
+	}
+
+	// A directory has no extension to offer rules for
+	m.cursor = m.indexOfVisible(small)
+	if m = pressKeys(m, runeKey("n")); m.topModal() == modalExtensionMenu {
+		t.Errorf("Expected n on a directory to leave the menu closed")
+	}
+}
+
+func TestExtensionMenuWhileScanning(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, _, small := newGuardTestModel()
+	small.Expanded = true
+	model.updateVisibleNodes()
+	model.cursor = model.indexOfVisible(small.Children[0])
+	model.enqueue(&job{kind: JobScan, title: "scan", start: func(m *Model) jobFunc {
+		return func(ctx context.Context) jobResult {
+			<-ctx.Done()
+			return jobResult{err: ctx.Err()}
+		}
+	}})
+
+	// n offers the rules while the scan goes on, x cancels the scan
+	m := pressKeys(*model, runeKey("n"))
+	if m.topModal() != modalExtensionMenu || !m.scanning() {
+		t.Fatalf("Expected the menu open with the scan still running")
+	}
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEsc}, runeKey("x"))
+	if m.topModal() == modalExtensionMenu || m.scanning() {
+		t.Errorf("Expected x to cancel the scan and leave the menu closed")
+	}
+}
+
+func TestTryRuleKeepsRulesInPlace(t *testing.T) {
+	model := newTestModel()
+	model.filters = newFilterDocument([]FilterRule{
+		{Pattern: "*", State: FilterExclude},
+		{Pattern: "a/**", State: FilterInclude},
+	})
+	model.tryRule("*", FilterNone, func() {
+		if model.filters.count() != 1 {
+			t.Errorf("Expected the rule to be gone while trying")
+		}
+	})
+	if rules := model.filters.rules; len(rules) != 2 || rules[0].Pattern != "*" {
+		t.Errorf("Expected the rule back in its place, got %+v", rules)
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// fileList is a list of paths in the format read by rclone's --files-from,
+// edited instead of filter rules when the editor runs with --files-from.
+// Listed files show as included, as do the directories containing them.
+type fileList struct {
+	path  string
+	lines []string // Lines as read, so comments survive saving
+
+	entries map[string]bool // Listed paths, relative to the root
+	dirs    map[string]int  // Number of listed files below each directory
+}
+
+func newFileList(filename string) *fileList {
+	return &fileList{
+		path:    filename,
+		entries: make(map[string]bool),
+		dirs:    make(map[string]int),
+	}
+}
+
+// normalizeListPath turns a list entry or filter path into the relative form
+// used as key, without leading slash
+func normalizeListPath(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+strings.TrimSpace(p)), "/")
+}
+
+// isListComment reports whether a --files-from line is blank or a comment
+func isListComment(line string) bool {
+	line = strings.TrimSpace(line)
+	return line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";")
+}
+
+// readFileList reads a --files-from list. A missing file yields an empty list
+// that is created on save; "-" reads the list from standard input.
+func readFileList(filename string) (*fileList, error) {
+	if filename == stdioFilterFile {
+		return parseFileList(filename, os.Stdin)
+	}
+
+	if err := validateFilterFilePath(filename); err != nil {
+		return nil, fmt.Errorf("security error: %v", err)
+	}
+
+	file, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return newFileList(filename), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			fmt.Printf("Warning: failed to close file: %v\n", closeErr)
+		}
+	}()
+
+	return parseFileList(filename, file)
+}
+
+func parseFileList(filename string, r io.Reader) (*fileList, error) {
+	list := newFileList(filename)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		list.lines = append(list.lines, line)
+		if !isListComment(line) {
+			list.set(line, true)
+		}
+	}
+	return list, scanner.Err()
+}
+
+// set adds p to the list or removes it
+func (l *fileList) set(p string, listed bool) {
+	p = normalizeListPath(p)
+	if p == "" || l.entries[p] == listed {
+		return
+	}
+
+	delta := 1
+	if listed {
+		l.entries[p] = true
+	} else {
+		delete(l.entries, p)
+		delta = -1
+	}
+	for dir := path.Dir(p); ; dir = path.Dir(dir) {
+		if dir == "." {
+			dir = ""
+		}
+		l.dirs[dir] += delta
+		if l.dirs[dir] == 0 {
+			delete(l.dirs, dir)
+		}
+		if dir == "" {
+			break
+		}
+	}
+}
+
+// state returns FilterInclude for listed files and for directories that
+// contain listed files
+func (l *fileList) state(filterPath string) FilterState {
+	p := normalizeListPath(filterPath)
+	if l.entries[p] || l.dirs[p] > 0 {
+		return FilterInclude
+	}
+	return FilterNone
+}
+
+// clear removes every entry from the list
+func (l *fileList) clear() {
+	clear(l.entries)
+	clear(l.dirs)
+}
+
+// clone returns an independent copy of the list, or nil for a nil list
+func (l *fileList) clone() *fileList {
+	if l == nil {
+		return nil
+	}
+	c := newFileList(l.path)
+	c.lines = append([]string(nil), l.lines...)
+	for p := range l.entries {
+		c.set(p, true)
+	}
+	return c
+}
+
+// write writes the list back, keeping comments and the order of entries that
+// are still listed, followed by new entries in sorted order
+func (l *fileList) write(w io.Writer) error {
+	writer := bufio.NewWriter(w)
+	written := make(map[string]bool)
+
+	for _, line := range l.lines {
+		if isListComment(line) {
+			fmt.Fprintln(writer, line)
+			continue
+		}
+		p := normalizeListPath(line)
+		if l.entries[p] && !written[p] {
+			fmt.Fprintln(writer, line)
+			written[p] = true
+		}
+	}
+
+	var added []string
+	for p := range l.entries {
+		if !written[p] {
+			added = append(added, p)
+		}
+	}
+	sort.Strings(added)
+	for _, p := range added {
+		fmt.Fprintln(writer, p)
+	}
+
+	return writer.Flush()
+}
+
+// save writes the list to its file
+func (l *fileList) save() error {
+	if err := validateFilterFilePath(l.path); err != nil {
+		return fmt.Errorf("security error: %v", err)
+	}
+
+	return writeFileAtomic(l.path, l.write)
+}
+
+// toggleListEntry adds the file, or every scanned file below the directory,
+// to the --files-from list, or removes them when already listed
+func (m *Model) toggleListEntry(node *FileNode) {
+	m.filterGen++
+	listed := node.Filter != FilterInclude
+
+	var mark func(n *FileNode)
+	mark = func(n *FileNode) {
+		if !n.IsDir {
+			m.filesFrom.set(getFilterPath(n.Path), listed)
+			return
+		}
+		for _, child := range n.Children {
+			mark(child)
+		}
+	}
+	mark(node)
+
+	// Directories above and below show whether they contain listed files
+	for n := node; n != nil; n = n.Parent {
+		n.Filter = m.filesFrom.state(getFilterPath(n.Path))
+	}
+	if node.IsDir {
+		m.updateChildrenFilters(node)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFileListRoundTrip(t *testing.T) {
+	input := "# photos to back up\n/2024/a.jpg\n2024/b.jpg\n\n; old\n2023/c.jpg\n"
+	list, err := parseFileList("files.txt", strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Failed to parse list: %v", err)
+	}
+
+	if list.state("/2024/a.jpg") != FilterInclude || list.state("/2024") != FilterInclude {
+		t.Errorf("Listed files and their directories should be included")
+	}
+	if list.state("/2024/z.jpg") != FilterNone || list.state("/2025") != FilterNone {
+		t.Errorf("Unlisted paths should have no state")
+	}
+
+	list.set("/2023/c.jpg", false)
+	list.set("/2022/d.jpg", true)
+	list.set("/2021/e.jpg", true)
+	if list.state("/2023") != FilterNone {
+		t.Errorf("Directory without listed files should have no state")
+	}
+
+	var b strings.Builder
+	if err := list.write(&b); err != nil {
+		t.Fatalf("Failed to write list: %v", err)
+	}
+	expected := "# photos to back up\n/2024/a.jpg\n2024/b.jpg\n\n; old\n2021/e.jpg\n2022/d.jpg\n"
+	if b.String() != expected {
+		t.Errorf("Written list = %q; want %q", b.String(), expected)
+	}
+}
+
+func TestToggleListEntry(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/root"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model := newTestModel()
+	model.filesFrom = newFileList("files.txt")
+
+	root := &FileNode{Name: "root", Path: "/root", IsDir: true}
+	dir := &FileNode{Name: "dir", Path: "/root/dir", IsDir: true, Parent: root}
+	a := &FileNode{Name: "a.txt", Path: "/root/dir/a.txt", Parent: dir}
+	b := &FileNode{Name: "b.txt", Path: "/root/dir/b.txt", Parent: dir}
+	dir.Children = []*FileNode{a, b}
+	root.Children = []*FileNode{dir}
+	model.root = root
+
+	model.toggleNode(dir)
+	if !model.filesFrom.entries["dir/a.txt"] || !model.filesFrom.entries["dir/b.txt"] {
+		t.Errorf("Toggling a directory should list every file below it, got %v", model.filesFrom.entries)
+	}
+	if root.Filter != FilterInclude || a.Filter != FilterInclude {
+		t.Errorf("Listed files and their parents should show as included")
+	}
+
+	model.toggleNode(a)
+	if model.filesFrom.entries["dir/a.txt"] || a.Filter != FilterNone {
+		t.Errorf("Toggling a listed file should remove it")
+	}
+	if dir.Filter != FilterInclude {
+		t.Errorf("Directory still containing listed files should stay included")
+	}
+
+	model.toggleNode(dir)
+	if len(model.filesFrom.entries) != 0 || root.Filter != FilterNone || b.Filter != FilterNone {
+		t.Errorf("Toggling a directory with listed files should remove them, got %v", model.filesFrom.entries)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// fileType is a broad kind of file, told apart by extension
+type fileType int
+
+const (
+	typeOther fileType = iota
+	typeVideo
+	typeImages
+	typeAudio
+	typeDocuments
+	typeArchives
+	fileTypeCount
+)
+
+var fileTypeNames = [fileTypeCount]string{"other", "video", "images", "audio", "documents", "archives"}
+
+// fileTypeExtensions maps lowercase extensions to their file type
+var fileTypeExtensions = map[string]fileType{}
+
+func init() {
+	for t, exts := range map[fileType]string{
+		typeVideo:     "mp4 mkv avi mov wmv flv webm m4v mpg mpeg ts m2ts 3gp vob",
+		typeImages:    "jpg jpeg png gif bmp tif tiff webp heic heif raw cr2 cr3 nef arw dng orf rw2 svg psd xcf",
+		typeAudio:     "mp3 flac wav aac ogg oga m4a opus wma aif aiff alac",
+		typeDocuments: "pdf doc docx xls xlsx ppt pptx odt ods odp txt md rtf epub csv",
+		typeArchives:  "zip tar gz tgz bz2 xz 7z rar zst iso dmg",
+	} {
+		for _, ext := range strings.Fields(exts) {
+			fileTypeExtensions["."+ext] = t
+		}
+	}
+}
+
+// fileTypeOf returns the type of a file from its name
+func fileTypeOf(name string) fileType {
+	return fileTypeExtensions[strings.ToLower(path.Ext(name))]
+}
+
+// typeSizes is the total size of the files of each type in a directory
+type typeSizes [fileTypeCount]int64
+
+func (s *typeSizes) add(other *typeSizes) {
+	if other == nil {
+		return
+	}
+	for t, size := range other {
+		s[t] += size
+	}
+}
+
+// breakdown describes the biggest types, e.g. "60.0 GiB video, 20.0 GiB images,
+// 4.0 GiB other", folding everything past the first two into other
+func (s *typeSizes) breakdown() string {
+	if s == nil {
+		return ""
+	}
+	var types []fileType
+	for t := typeVideo; t < fileTypeCount; t++ {
+		if s[t] > 0 {
+			types = append(types, t)
+		}
+	}
+	sort.SliceStable(types, func(i, j int) bool { return s[types[i]] > s[types[j]] })
+
+	var parts []string
+	other := s[typeOther]
+	for i, t := range types {
+		if i >= 2 {
+			other += s[t]
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s %s", formatSize(s[t]), fileTypeNames[t]))
+	}
+	if other > 0 && len(parts) > 0 {
+		parts = append(parts, fmt.Sprintf("%s %s", formatSize(other), fileTypeNames[typeOther]))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFileTypeBreakdown(t *testing.T) {
+	root := &FileNode{Name: "root", Path: "/root", IsDir: true}
+	movies := &FileNode{Name: "movies", Path: "/root/movies", IsDir: true, Parent: root}
+	root.Children = []*FileNode{movies}
+	for _, f := range []struct {
+		parent *FileNode
+		name   string
+		size   int64
+	}{
+		{movies, "a.MKV", 60 << 30},
+		{root, "b.jpg", 12 << 30},
+		{root, "c.png", 8 << 30},
+		{root, "d.flac", 3 << 30},
+		{root, "notes", 1 << 30},
+	} {
+		f.parent.Children = append(f.parent.Children, &FileNode{Name: f.name, Path: f.parent.Path + "/" + f.name, Size: f.size, Parent: f.parent})
+	}
+
+	calculateStats(root)
+	// Audio is third and counts as other
+	want := formatSize(60<<30) + " video, " + formatSize(20<<30) + " images, " + formatSize(4<<30) + " other"
+	if got := root.Types.breakdown(); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if got := movies.Types.breakdown(); got != formatSize(60<<30)+" video" {
+		t.Errorf("Expected only video in movies, got %q", got)
+	}
+
+	// Rescanning a directory sums the same way
+	sumChildStats(root)
+	if got := root.Types.breakdown(); got != want {
+		t.Errorf("Expected %q after summing the children, got %q", want, got)
+	}
+
+	model := newTestModel()
+	model.root = root
+	model.updateVisibleNodes()
+	model.showFileTypes = true
+	if view := model.View(); !strings.Contains(view, want) {
+		t.Errorf("Expected the breakdown in the stats line, got:\n%s", view)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import "strings"
+
+// filterCache remembers the rule deciding the state of each filter path, so
+// that a path is matched against all the rules only once. Changing a rule
+// updates the entries it can match rather than dropping them all, which
+// keeps toggling a directory near the root of a large tree fast.
+type filterCache struct {
+	entries map[string]cachedMatch
+}
+
+// cachedMatch is the rule deciding a path, as found by evaluateRules
+type cachedMatch struct {
+	pattern string
+	state   FilterState
+}
+
+func newFilterCache() *filterCache {
+	return &filterCache{entries: make(map[string]cachedMatch)}
+}
+
+// clear drops every entry, for changes to the rules other than setting a
+// single pattern
+func (c *filterCache) clear() {
+	if c != nil {
+		clear(c.entries)
+	}
+}
+
+// ruleChanged updates the entries after pattern was set to state in the
+// filter document, or removed from it when state is FilterNone. Patterns generated
+// for a node only match paths at or below it, so only those entries are
+// looked at. A path decided by the pattern, or by no rule, takes the new
+// state; which rule comes first for another path the pattern matches
+// depends on where the rule was put, so those entries are dropped and
+// evaluated again when needed.
+func (c *filterCache) ruleChanged(pattern string, state FilterState) {
+	if c == nil {
+		return
+	}
+	anchor, ok := literalAnchor(pattern)
+	if !ok {
+		// A wildcard pattern can match anywhere
+		c.clear()
+		return
+	}
+	anchor = strings.TrimPrefix(anchor, "/")
+	for path, match := range c.entries {
+		clean := strings.TrimPrefix(path, "/")
+		if clean != anchor && !strings.HasPrefix(clean, anchor+"/") {
+			continue
+		}
+		switch {
+		case state == FilterNone:
+			// Another rule, or none, decides the paths the removed one did
+			if match.pattern == pattern {
+				delete(c.entries, path)
+			}
+		case pattern != path && !matchesRclonePattern(pattern, path):
+		case match.pattern == pattern || match.pattern == "":
+			// The pattern decides the path, or is the only rule matching it
+			c.entries[path] = cachedMatch{pattern: pattern, state: state}
+		default:
+			delete(c.entries, path)
+		}
+	}
+}
+
+// setRule gives pattern a state in the active filter document, FilterNone
+// removing it, and updates the filter cache to match
+func (m *Model) setRule(pattern string, state FilterState) {
+	m.filters.set(pattern, state)
+	m.filterCache.ruleChanged(pattern, state)
+}
+
+// tryRule gives pattern a state while measure runs, then puts the rules back
+// as they were, each in its place
+func (m *Model) tryRule(pattern string, state FilterState, measure func()) {
+	saved := m.filters.clone()
+	oldState := m.filters.state(pattern)
+	m.setRule(pattern, state)
+	measure()
+	*m.filters = *saved
+	m.filterCache.ruleChanged(pattern, oldState)
+}
+
+// clearFilterCaches drops the cached matches of every root, for edits that
+// replace the rules wholesale
+func (m *Model) clearFilterCaches() {
+	m.filterCache.clear()
+	for _, r := range m.roots {
+		r.filterCache.clear()
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// checkCachedStates compares the cached state of every node with the one
+// found by going through all the rules
+func checkCachedStates(t *testing.T, model *Model, step string) {
+	t.Helper()
+	uncached := &Model{filters: model.filters}
+	for _, node := range collectNodes(model.root, nil) {
+		path := getFilterPath(node.Path)
+		cachedPattern, cachedState := model.matchingRule(path)
+		pattern, state := uncached.matchingRule(path)
+		if cachedPattern != pattern || cachedState != state {
+			t.Errorf("%s: %s cached as %q %v, the rules give %q %v", step, path, cachedPattern, cachedState, pattern, state)
+		}
+	}
+}
+
+func TestFilterCacheFollowsToggles(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, big, small := newGuardTestModel()
+	model.filters = newFilterDocument([]FilterRule{{Pattern: "small/9.bin", State: FilterExclude}})
+	model.filterCache = newFilterCache()
+	model.reapplyFiltersToTree(model.root)
+	checkCachedStates(t, model, "loaded")
+
+	steps := []struct {
+		node  *FileNode
+		state FilterState
+	}{
+		{big, FilterExclude},
+		{big.Children[3], FilterInclude},
+		{big, FilterInclude},
+		{big.Children[3], FilterNone},
+		{small, FilterExclude},
+		{small.Children[1], FilterNone}, // Removes the loaded rule
+		{big, FilterNone},
+		{small, FilterNone},
+	}
+	for _, s := range steps {
+		model.setNodeFilter(s.node, s.state)
+		checkCachedStates(t, model, fmt.Sprintf("%s to %d", s.node.Name, s.state))
+	}
+}
+
+func TestFilterCacheKeepsOtherEntries(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, big, _ := newGuardTestModel()
+	model.filterCache = newFilterCache()
+	model.reapplyFiltersToTree(model.root)
+	entries := len(model.filterCache.entries)
+
+	model.setNodeFilter(big, FilterExclude)
+	if len(model.filterCache.entries) != entries {
+		t.Errorf("Expected the entries to be updated in place, %d of %d left", len(model.filterCache.entries), entries)
+	}
+	if match := model.filterCache.entries["/big/0.bin"]; match.pattern != "big/**" || match.state != FilterExclude {
+		t.Errorf("Expected big/0.bin to follow big/**, got %+v", match)
+	}
+	if match, ok := model.filterCache.entries["/small/8.bin"]; !ok || match.pattern != "" {
+		t.Errorf("Expected small/8.bin to be kept without a rule, got %+v", match)
+	}
+
+	// A wildcard pattern can match anywhere
+	model.setRule("*.bin", FilterInclude)
+	if len(model.filterCache.entries) != 0 {
+		t.Errorf("Expected a wildcard rule to drop the cache")
+	}
+	checkCachedStates(t, model, "wildcard")
+}
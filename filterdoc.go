@@ -0,0 +1,271 @@
+package main
+
+import (
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"rclone-filter-editor/rclonefilter"
+)
+
+// FilterDocument is the rules of a filter file in the order rclone reads
+// them, as edited: the file's own rules, the disabled ones, include
+// directives each followed by the rules they pulled in, and the rules added
+// in the editor. Evaluation, the rules pane and saving all read this one
+// list, so what the tree shows is what gets written.
+type FilterDocument struct {
+	rules []FilterRule
+	// Patterns of the rules set in the editor rather than read or placed by
+	// hand, which go where they take effect, see placement
+	added map[string]bool
+}
+
+func newFilterDocument(rules []FilterRule) *FilterDocument {
+	return &FilterDocument{rules: rules, added: make(map[string]bool)}
+}
+
+// clone copies the document, to keep the rules as they are now
+func (d *FilterDocument) clone() *FilterDocument {
+	return &FilterDocument{rules: slices.Clone(d.rules), added: maps.Clone(d.added)}
+}
+
+// isActive reports whether a rule is one of the file's own rules and in
+// effect
+func (r FilterRule) isActive() bool {
+	return r.isOwnRule() && !r.Disabled
+}
+
+// appliedPattern is the pattern of a rule rclone reads, the file's own or
+// one pulled in by an include directive, for rclonefilter.FirstMatch
+func (r FilterRule) appliedPattern() (string, bool) {
+	return r.Pattern, r.Include == "" && !r.Disabled
+}
+
+// index returns the position of the active rule with pattern, the first one
+// when the file gives it twice, or -1
+func (d *FilterDocument) index(pattern string) int {
+	return slices.IndexFunc(d.rules, func(r FilterRule) bool {
+		return r.Pattern == pattern && r.isActive()
+	})
+}
+
+// lookup returns the state the active rule with pattern gives
+func (d *FilterDocument) lookup(pattern string) (FilterState, bool) {
+	if i := d.index(pattern); i >= 0 {
+		return d.rules[i].State, true
+	}
+	return FilterNone, false
+}
+
+// state returns the state the active rule with pattern gives, FilterNone
+// without one
+func (d *FilterDocument) state(pattern string) FilterState {
+	state, _ := d.lookup(pattern)
+	return state
+}
+
+// states returns the state of every active pattern, the first rule's when
+// the file gives one twice, for trying out changes on a copy
+func (d *FilterDocument) states() map[string]FilterState {
+	states := make(map[string]FilterState)
+	for _, rule := range d.rules {
+		if _, seen := states[rule.Pattern]; rule.isActive() && !seen {
+			states[rule.Pattern] = rule.State
+		}
+	}
+	return states
+}
+
+// count returns the number of active patterns
+func (d *FilterDocument) count() int {
+	return len(d.states())
+}
+
+// firstMatch returns the first rule matching path and its state, which is
+// the rule rclone applies to path and the tree shows
+func (d *FilterDocument) firstMatch(path string) (string, FilterState) {
+	i := rclonefilter.FirstMatch(d.rules, FilterRule.appliedPattern, path)
+	if i < 0 {
+		return "", FilterNone
+	}
+	return d.rules[i].Pattern, d.rules[i].State
+}
+
+// set gives pattern a state. The active rules with the pattern take it, a
+// disabled one is switched back on, and otherwise a new rule is inserted
+// where it takes effect. A rule read after a broader one matching its path
+// would never be reached, so it is moved in front of it. FilterNone removes
+// the rule.
+func (d *FilterDocument) set(pattern string, state FilterState) {
+	if state == FilterNone {
+		d.remove(pattern)
+		return
+	}
+	found := -1
+	for i := range d.rules {
+		if d.rules[i].Pattern == pattern && d.rules[i].isOwnRule() {
+			d.rules[i].State = state
+			d.rules[i].Disabled = false
+			if found < 0 {
+				found = i
+			}
+		}
+	}
+	if found >= 0 {
+		if limit := d.coveredFrom(pattern); limit < found {
+			d.move(found, limit)
+		}
+		return
+	}
+	i, section := d.placement(pattern)
+	d.insertAt(i, FilterRule{Pattern: pattern, State: state, Section: section})
+	d.added[pattern] = true
+}
+
+// coveredFrom returns the position of the first rule matching the path a
+// rule for pattern stands for, which a rule for pattern has to come before
+// to take effect, or the end of the rules. A rule pulled in by an include
+// directive is covered from the directive. Patterns with wildcards don't
+// stand for a single path, and are covered by nothing.
+func (d *FilterDocument) coveredFrom(pattern string) int {
+	anchor, ok := literalAnchor(pattern)
+	if !ok {
+		return len(d.rules)
+	}
+	for i, rule := range d.rules {
+		p, applied := rule.appliedPattern()
+		if !applied || p == pattern || p != anchor && !matchesRclonePattern(p, anchor) {
+			continue
+		}
+		for i > 0 && d.rules[i].Origin != "" {
+			i--
+		}
+		return i
+	}
+	return len(d.rules)
+}
+
+// placement returns where a new rule for pattern goes and the section it
+// joins. rclone applies the first rule matching a path, so the rule goes
+// before the rules covering its path, see coveredFrom, and after the file's
+// own rules for paths it matches, which are exceptions to it. In between it
+// goes before the first rule it is more specific than, see
+// shouldInsertBefore, and otherwise as late as it can. A rule for a single
+// file is broader than nothing, so nothing goes before it that way. New rules placed
+// together are kept in the order of their patterns, so that the same edits
+// always give the same file.
+func (d *FilterDocument) placement(pattern string) (int, string) {
+	isAdded := func(i int) bool {
+		return d.rules[i].isOwnRule() && d.added[d.rules[i].Pattern]
+	}
+	first, last := 0, d.coveredFrom(pattern)
+	for k := 0; k < last; k++ {
+		if anchor, ok := literalAnchor(d.rules[k].Pattern); ok && d.rules[k].isActive() && matchesRclonePattern(pattern, anchor) {
+			first = k + 1
+		}
+	}
+	i := last
+	for k := first; k < last; k++ {
+		if d.rules[k].isOwnRule() && !isAdded(k) && !singleFile(d.rules[k].Pattern) && shouldInsertBefore(pattern, d.rules[k].Pattern) {
+			i = k
+			break
+		}
+	}
+	for i > first && isAdded(i-1) && d.rules[i-1].Pattern > pattern {
+		i--
+	}
+
+	switch {
+	case i < len(d.rules) && i > 0:
+		return i, d.rules[i-1].Section
+	case i < len(d.rules):
+		return i, d.rules[i].Section
+	}
+	// At the end, in the last section written
+	section := ""
+	for _, rule := range d.rules {
+		if rule.Section != "" {
+			section = rule.Section
+		}
+	}
+	return i, section
+}
+
+// singleFile reports whether pattern stands for one file rather than a
+// directory or many paths
+func singleFile(pattern string) bool {
+	_, ok := literalAnchor(pattern)
+	return ok && !strings.HasSuffix(pattern, "/") && !strings.HasSuffix(pattern, "/**")
+}
+
+// insertAt inserts rule at position i, where it stays
+func (d *FilterDocument) insertAt(i int, rule FilterRule) {
+	d.rules = slices.Insert(d.rules, i, rule)
+}
+
+// remove removes the active rules with pattern and reports whether there
+// were any. Disabled rules stay.
+func (d *FilterDocument) remove(pattern string) bool {
+	n := len(d.rules)
+	d.rules = slices.DeleteFunc(d.rules, func(r FilterRule) bool {
+		return r.Pattern == pattern && r.isActive()
+	})
+	delete(d.added, pattern)
+	return len(d.rules) < n
+}
+
+// move moves the rule at from to position to, where it stays
+func (d *FilterDocument) move(from, to int) {
+	if from == to || from < 0 || to < 0 || from >= len(d.rules) || to >= len(d.rules) {
+		return
+	}
+	rule := d.rules[from]
+	d.rules = slices.Insert(slices.Delete(d.rules, from, from+1), to, rule)
+	if rule.isOwnRule() {
+		delete(d.added, rule.Pattern)
+	}
+}
+
+// annotate sets the comment written above the rule at i, a line of its own
+// for each line of note; an empty note removes it
+func (d *FilterDocument) annotate(i int, note string) {
+	if i < 0 || i >= len(d.rules) {
+		return
+	}
+	var lines []string
+	for line := range strings.SplitSeq(strings.TrimSpace(note), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, "# "+line)
+		}
+	}
+	d.rules[i].Comment = strings.Join(lines, "\n")
+}
+
+// note returns the comment of a rule without its comment markers, as
+// annotate takes it
+func (r FilterRule) note() string {
+	var lines []string
+	for line := range strings.SplitSeq(r.Comment, "\n") {
+		lines = append(lines, strings.TrimSpace(strings.TrimLeft(line, "#;")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// replace puts rules in place of the document's, in the order given
+func (d *FilterDocument) replace(rules []FilterRule) {
+	d.rules = rules
+	clear(d.added)
+}
+
+// clearRules removes every active rule, keeping the disabled ones and the
+// include directives
+func (d *FilterDocument) clearRules() {
+	d.rules = slices.DeleteFunc(d.rules, FilterRule.isActive)
+	clear(d.added)
+}
+
+// write writes the rules in filter file format in the order they are in
+func (d *FilterDocument) write(w io.Writer) error {
+	return writeFilterRules(w, d.rules)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestFilterDocumentPlacesNewRules(t *testing.T) {
+	doc, err := parseFilterRules(strings.NewReader("+ dir1/**\n- *\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// New rules go before the broader rule they are an exception to, in
+	// the order of their patterns whatever the order they were set in
+	doc.set("temp", FilterExclude)
+	doc.set("dir1/sub/**", FilterExclude)
+	doc.set("dir1/**", FilterExclude)
+
+	var out bytes.Buffer
+	if err := doc.write(&out); err != nil {
+		t.Fatal(err)
+	}
+	if want := "- dir1/sub/**\n- dir1/**\n- temp\n- *\n"; out.String() != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, out.String())
+	}
+	if state, _ := doc.lookup("dir1/**"); state != FilterExclude || doc.count() != 4 {
+		t.Errorf("Expected the changed rule to keep its place with its new state, got %v", doc.states())
+	}
+}
+
+func TestFilterDocumentEdits(t *testing.T) {
+	doc := newFilterDocument([]FilterRule{
+		{Pattern: "a/**", State: FilterExclude},
+		{Pattern: "b/**", State: FilterInclude, Disabled: true},
+		{Pattern: "*", State: FilterExclude},
+	})
+
+	// Setting a disabled rule switches it back on where it is
+	doc.set("b/**", FilterExclude)
+	if doc.rules[1].Disabled || doc.state("b/**") != FilterExclude {
+		t.Errorf("Expected b/** to be enabled in place, got %+v", doc.rules)
+	}
+
+	// A moved rule stays where it was put, and so does a new rule inserted
+	doc.move(2, 0)
+	doc.insertAt(1, FilterRule{Pattern: "c.txt", State: FilterInclude})
+	var patterns []string
+	for _, rule := range doc.rules {
+		patterns = append(patterns, rule.Pattern)
+	}
+	if got := strings.Join(patterns, " "); got != "* c.txt a/** b/**" {
+		t.Errorf("Rules in the order %q", got)
+	}
+
+	if !doc.remove("a/**") || doc.remove("a/**") {
+		t.Errorf("Expected a/** to be removed once")
+	}
+	// The first rule matching decides, as in rclone
+	if pattern, state := doc.firstMatch("c.txt"); pattern != "*" || state != FilterExclude {
+		t.Errorf("firstMatch(c.txt) = %q %v", pattern, state)
+	}
+
+	// A copy is left alone by the edits of the document
+	copied := doc.clone()
+	doc.set("c.txt", FilterNone)
+	if copied.state("c.txt") != FilterInclude || doc.state("c.txt") != FilterNone {
+		t.Errorf("Expected the copy to keep c.txt")
+	}
+}
+
+func TestFilterDocumentKeepsComments(t *testing.T) {
+	input := "# Keep the photos\n+ Photos/**\n; old\n# disabled: - Photos/raw/**\n- *\n"
+	doc, err := parseFilterRules(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.rules[0].note() != "Keep the photos" || doc.rules[1].Comment != "; old" {
+		t.Fatalf("Expected the comments with the rules below them, got %+v", doc.rules)
+	}
+
+	doc.annotate(2, "Everything else\nis left out")
+	doc.annotate(1, "")
+	var out bytes.Buffer
+	doc.write(&out)
+	want := "# Keep the photos\n+ Photos/**\n# disabled: - Photos/raw/**\n# Everything else\n# is left out\n- *\n"
+	if out.String() != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, out.String())
+	}
+}
+
+func TestRulesPaneMovesAndAnnotates(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, big, _ := newGuardTestModel()
+	model.filters = newFilterDocument([]FilterRule{
+		{Pattern: "big/**", State: FilterExclude},
+		{Pattern: "big/0.bin", State: FilterInclude},
+	})
+	model.reapplyFiltersToTree(model.root)
+	if big.Children[0].Filter != FilterExclude {
+		t.Fatalf("Expected the first rule to exclude big/0.bin")
+	}
+
+	// K moves the exception above the rule it is an exception to, which
+	// then decides the file
+	m := pressKeys(*model, runeKey("p"), runeKey("j"), runeKey("K"))
+	if rules := m.filters.rules; rules[0].Pattern != "big/0.bin" || m.rulesCursor != 0 {
+		t.Fatalf("Expected big/0.bin to move up with the cursor, got %+v at %d", rules, m.rulesCursor)
+	}
+	if big.Children[0].Filter != FilterInclude {
+		t.Errorf("Expected big/0.bin to be included once its rule comes first")
+	}
+
+	m = pressKeys(m, runeKey("c"), runeKey("kept"), tea.KeyMsg{Type: tea.KeySpace}, runeKey("on purpose"))
+	if !strings.Contains(m.View(), "Note above + big/0.bin: kept on purpose") {
+		t.Errorf("Expected the note prompt below the pane:\n%s", m.View())
+	}
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEnter})
+	var out bytes.Buffer
+	m.ruleOrder.write(&out, m.filters)
+	if want := "# kept on purpose\n+ big/0.bin\n- big/**\n"; out.String() != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, out.String())
+	}
+
+	// Rules sorted on save can't be moved by hand
+	m.ruleOrder = ruleOrders[1]
+	m = pressKeys(m, runeKey("J"))
+	if m.filters.rules[0].Pattern != "big/0.bin" || !strings.Contains(m.statusMsg, "(o)") {
+		t.Errorf("Expected the move to be refused, got %q", m.statusMsg)
+	}
+
+	// Moving the exception back below the broader rule excludes the file
+	// again, as rclone would
+	m.ruleOrder = ruleOrders[0]
+	m = pressKeys(m, runeKey("J"))
+	if m.filters.rules[1].Pattern != "big/0.bin" || m.rulesCursor != 1 {
+		t.Errorf("Expected big/0.bin to move down with the cursor, got %+v at %d", m.filters.rules, m.rulesCursor)
+	}
+	if big.Children[0].Filter != FilterExclude {
+		t.Errorf("Expected big/0.bin to be excluded by the rule now first")
+	}
+}
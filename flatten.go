@@ -0,0 +1,214 @@
+package main
+
+import (
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// expandChunk is the number of rows an expansion adds to the visible list in
+// one step. The first step covers any screen, the rest of a large directory
+// follows in later steps so keys are handled in between.
+const expandChunk = 2000
+
+// expandMsg asks for the next step of the expansion with the same generation
+type expandMsg struct {
+	gen int
+}
+
+// expandProgress is an expansion whose rows are still being spliced into the
+// visible list, walking the expanded subtree depth first
+type expandProgress struct {
+	gen   int
+	next  int // Index in visibleNodes where the next rows go
+	stack []expandFrame
+	depth int         // Levels of directories opened on the way, 0 for none, expandAll for all
+	queue []*FileNode // Directories to expand once the rows of this one are added
+}
+
+type expandFrame struct {
+	children []*FileNode
+	index    int
+	depth    int // Levels of the children below the expanded directory
+}
+
+// expandNode expands the directory shown at index and splices its rows into
+// the visible list after it, instead of flattening the whole tree again
+func (m *Model) expandNode(index int) tea.Cmd {
+	node := m.visibleNodes[index]
+	node.Expanded = true
+
+	m.expandGen++
+	m.expanding = &expandProgress{
+		gen:   m.expandGen,
+		next:  index + 1,
+		stack: []expandFrame{{children: node.Children, depth: 1}},
+	}
+	return tea.Batch(m.listFiles(node), m.continueExpand())
+}
+
+// continueExpand adds the next rows of the pending expansion and returns the
+// command for the step after, or nil once the expansion is complete
+func (m *Model) continueExpand() tea.Cmd {
+	p := m.expanding
+	var rows []*FileNode
+	var listings []tea.Cmd // Of the directories opened on the way, see listFiles
+	for len(rows) < expandChunk {
+		if len(p.stack) == 0 {
+			// The rows of the next queued directory go elsewhere
+			if len(rows) > 0 {
+				break
+			}
+			dir := m.startQueuedExpand(p)
+			if dir == nil {
+				break
+			}
+			listings = append(listings, m.listFiles(dir))
+			continue
+		}
+		frame := &p.stack[len(p.stack)-1]
+		if frame.index >= len(frame.children) {
+			p.stack = p.stack[:len(p.stack)-1]
+			continue
+		}
+		child := frame.children[frame.index]
+		frame.index++
+		depth := frame.depth
+
+		if m.viewMatches != nil && !m.viewMatches[child] {
+			continue
+		}
+		rows = append(rows, child)
+		if child.IsDir && (p.depth == expandAll || depth < p.depth) {
+			child.Expanded = true
+			listings = append(listings, m.listFiles(child))
+		}
+		if child.IsDir && child.Expanded && len(child.Children) > 0 {
+			p.stack = append(p.stack, expandFrame{children: child.Children, depth: depth + 1})
+		}
+	}
+
+	m.visibleNodes = slices.Insert(m.visibleNodes, p.next, rows...)
+	// Rows above the cursor push it down along with the node it is on
+	if m.cursor >= p.next {
+		m.cursor += len(rows)
+		m.scrollOffset += len(rows)
+	}
+	p.next += len(rows)
+
+	if len(p.stack) == 0 && len(p.queue) == 0 {
+		m.expanding = nil
+		return tea.Batch(listings...)
+	}
+	gen := p.gen
+	return tea.Batch(append(listings, func() tea.Msg {
+		return expandMsg{gen: gen}
+	})...)
+}
+
+// startQueuedExpand opens the next queued directory that is shown and
+// returns it, or nil if there is none
+func (m *Model) startQueuedExpand(p *expandProgress) *FileNode {
+	for len(p.queue) > 0 {
+		dir := p.queue[0]
+		p.queue = p.queue[1:]
+		if i := slices.Index(m.visibleNodes, dir); i >= 0 {
+			dir.Expanded = true
+			p.next = i + 1
+			p.stack = []expandFrame{{children: dir.Children, depth: 1}}
+			return dir
+		}
+	}
+	return nil
+}
+
+// applyExpandStep continues the pending expansion, ignoring steps of one that
+// was superseded by a full rebuild of the visible list
+func (m *Model) applyExpandStep(msg expandMsg) tea.Cmd {
+	if m.expanding == nil || m.expanding.gen != msg.gen {
+		return nil
+	}
+	return m.continueExpand()
+}
+
+// finishExpand adds all remaining rows of a pending expansion at once, for
+// operations that need the complete visible list
+func (m *Model) finishExpand() {
+	for m.expanding != nil {
+		m.continueExpand()
+	}
+}
+
+// selectedNode returns the node under the cursor, or nil
+func (m *Model) selectedNode() *FileNode {
+	if m.cursor >= 0 && m.cursor < len(m.visibleNodes) {
+		return m.visibleNodes[m.cursor]
+	}
+	return nil
+}
+
+// updateVisibleNodes rebuilds the visible list and keeps the cursor on the
+// node it was on, at the same height on screen, so that resorting or
+// refreshing doesn't lose the user's place. A node replaced by a rescan is
+// found again by its path; one that is gone leaves the cursor on its nearest
+// shown parent. When the view mode hides the node, as after excluding it in
+// the included-only view, the cursor stays on the same row instead.
+func (m *Model) updateVisibleNodes() {
+	// While a refresh restores the old place, the cursor is only on its way
+	var selected *FileNode
+	target := m.anchorPath
+	if target == "" {
+		selected = m.selectedNode()
+	}
+	if selected != nil {
+		target = selected.Path
+	}
+	row := m.cursor - m.scrollOffset
+
+	m.buildVisibleNodes()
+	if target == "" || len(m.visibleNodes) == 0 {
+		return
+	}
+
+	var i int
+	if selected != nil && m.viewMatches != nil && !m.viewMatches[selected] {
+		i = min(m.cursor, len(m.visibleNodes)-1)
+	} else {
+		i = m.anchorIndex(selected, target)
+	}
+	if i < 0 {
+		return
+	}
+	if m.visibleNodes[i].Path == m.anchorPath {
+		m.anchorPath = ""
+	}
+	m.cursor = i
+	m.scrollOffset = max(0, i-row)
+	m.adjustScroll()
+}
+
+// anchorIndex returns the index of selected in the visible list, or else of
+// the deepest shown node at or above path, or -1
+func (m *Model) anchorIndex(selected *FileNode, path string) int {
+	best, bestLen := -1, -1
+	for i, node := range m.visibleNodes {
+		if node == selected {
+			return i
+		}
+		if len(node.Path) > bestLen && pathContains(node.Path, path) {
+			best, bestLen = i, len(node.Path)
+		}
+	}
+	return best
+}
+
+// pathContains reports whether p is dir or lies below it, for local and
+// remote paths alike
+func pathContains(dir, p string) bool {
+	if p == dir {
+		return true
+	}
+	prefix := strings.TrimSuffix(joinChildPath(dir, "x"), "x")
+	return strings.HasPrefix(p, prefix)
+}
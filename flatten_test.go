@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestExpandLargeDirectoryProgressively(t *testing.T) {
+	root := &FileNode{Name: "root", Path: "/root", IsDir: true, Expanded: true}
+	big := &FileNode{Name: "big", Path: "/root/big", IsDir: true, Parent: root}
+	after := &FileNode{Name: "z.txt", Path: "/root/z.txt", Parent: root}
+	for i := 0; i < 2*expandChunk+10; i++ {
+		name := fmt.Sprintf("f%05d", i)
+		big.Children = append(big.Children, &FileNode{Name: name, Path: "/root/big/" + name, Parent: big})
+	}
+	// An expanded subdirectory keeps showing its rows
+	sub := big.Children[1]
+	sub.IsDir = true
+	sub.Expanded = true
+	sub.Children = []*FileNode{{Name: "inner", Path: sub.Path + "/inner", Parent: sub}}
+	root.Children = []*FileNode{big, after}
+
+	model := newTestModel()
+	model.root = root
+	model.height = 30
+	model.updateVisibleNodes()
+	model.cursor = 1
+
+	updated, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	result := updated.(Model)
+	if cmd == nil || result.expanding == nil {
+		t.Fatalf("Expected the expansion to continue in the background")
+	}
+	if len(result.visibleNodes) != 3+expandChunk {
+		t.Fatalf("Expected the first chunk of rows, got %d", len(result.visibleNodes))
+	}
+	if result.visibleNodes[2] != big.Children[0] || result.visibleNodes[4] != sub.Children[0] {
+		t.Errorf("First rows should follow the expanded directory in tree order")
+	}
+	if result.visibleNodes[len(result.visibleNodes)-1] != after {
+		t.Errorf("Rows after the directory should stay in place")
+	}
+
+	for steps := 0; cmd != nil; steps++ {
+		if steps > 10 {
+			t.Fatalf("Expansion did not finish")
+		}
+		updated, cmd = result.Update(cmd())
+		result = updated.(Model)
+	}
+
+	got := result.visibleNodes
+	result.updateVisibleNodes()
+	if len(got) != len(result.visibleNodes) {
+		t.Fatalf("Expected %d rows, got %d", len(result.visibleNodes), len(got))
+	}
+	for i := range got {
+		if got[i] != result.visibleNodes[i] {
+			t.Fatalf("Row %d differs from a full rebuild", i)
+		}
+	}
+}
+
+func TestExpandStepAfterRebuildIsIgnored(t *testing.T) {
+	root := &FileNode{Name: "root", Path: "/root", IsDir: true, Expanded: true}
+	big := &FileNode{Name: "big", Path: "/root/big", IsDir: true, Parent: root}
+	for i := 0; i < expandChunk+1; i++ {
+		name := fmt.Sprintf("f%05d", i)
+		big.Children = append(big.Children, &FileNode{Name: name, Path: "/root/big/" + name, Parent: big})
+	}
+	root.Children = []*FileNode{big}
+
+	model := newTestModel()
+	model.root = root
+	model.updateVisibleNodes()
+
+	cmd := model.expandNode(1)
+	if cmd == nil {
+		t.Fatalf("Expected a pending expansion")
+	}
+	model.updateVisibleNodes()
+	rows := len(model.visibleNodes)
+
+	if next := model.applyExpandStep(cmd().(expandMsg)); next != nil || len(model.visibleNodes) != rows {
+		t.Errorf("A step of a superseded expansion should change nothing")
+	}
+}
+
+func TestCursorStaysOnNodeAfterResort(t *testing.T) {
+	root := &FileNode{Name: "root", Path: "/root", IsDir: true, Expanded: true}
+	for i, size := range []int64{30, 10, 20} {
+		name := fmt.Sprintf("f%d", i)
+		root.Children = append(root.Children, &FileNode{Name: name, Path: "/root/" + name, Size: size, Parent: root})
+	}
+
+	model := newTestModel()
+	model.root = root
+	model.updateVisibleNodes()
+	model.cursor = 2 // f1, the smallest
+	selected := model.visibleNodes[model.cursor]
+
+	// Size is the second row of the sort menu
+	result := pressKeys(*model, runeKey("o"), runeKey("j"), tea.KeyMsg{Type: tea.KeyEnter})
+	if result.visibleNodes[result.cursor] != selected {
+		t.Errorf("Expected the cursor to stay on %s after sorting by size, got %s",
+			selected.Name, result.visibleNodes[result.cursor].Name)
+	}
+	if result.cursor != 3 {
+		t.Errorf("Expected the smallest file last, at row 3, got %d", result.cursor)
+	}
+}
+
+func TestRefreshReturnsToSelectedPath(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/data"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model := newTestModel()
+	model.ctx, model.cancel = context.WithCancel(context.Background())
+	defer model.cancel()
+	model.root = &FileNode{Name: "data", Path: "/data", IsDir: true, Expanded: true}
+	top := model.root
+	model.applyDirScan(dirScannedMsg{parent: top, children: []*FileNode{
+		{Name: "a", Path: "/data/a", IsDir: true, Parent: top},
+		{Name: "b", Path: "/data/b", IsDir: true, Parent: top},
+	}})
+	b := top.Children[1]
+	b.Expanded = true
+	model.applyDirScan(dirScannedMsg{parent: b, children: []*FileNode{
+		{Name: "x.txt", Path: "/data/b/x.txt", Parent: b},
+	}})
+	model.cursor = model.indexOfVisible(b.Children[0])
+
+	// The refresh starts over from a collapsed tree, rescanned step by step
+	model.refreshDirectory()
+	top = model.root
+	model.applyDirScan(dirScannedMsg{parent: top, children: []*FileNode{
+		{Name: "a", Path: "/data/a", IsDir: true, Parent: top},
+		{Name: "b", Path: "/data/b", IsDir: true, Parent: top},
+	}})
+	if got := model.selectedNode(); got == nil || got.Path != "/data/b" {
+		t.Fatalf("Expected the cursor on the rescanned parent b, got %v", got)
+	}
+	b = top.Children[1]
+	model.applyDirScan(dirScannedMsg{parent: b, children: []*FileNode{
+		{Name: "x.txt", Path: "/data/b/x.txt", Parent: b},
+	}})
+	if got := model.selectedNode(); got == nil || got.Path != "/data/b/x.txt" {
+		t.Fatalf("Expected the cursor back on x.txt, got %v", got)
+	}
+	if model.anchorPath != "" {
+		t.Errorf("Expected the anchor to be cleared once reached")
+	}
+}
+
+// newNestedTree builds a root with the given number of directories, each
+// holding a subdirectory with a file, all of them closed
+func newNestedTree(name string, dirs int) *FileNode {
+	root := &FileNode{Name: name, Path: "/" + name, IsDir: true}
+	for i := range dirs {
+		dir := &FileNode{Name: fmt.Sprintf("d%04d", i), IsDir: true, Parent: root}
+		dir.Path = root.Path + "/" + dir.Name
+		sub := &FileNode{Name: "sub", Path: dir.Path + "/sub", IsDir: true, Parent: dir}
+		sub.Children = []*FileNode{{Name: "f", Path: sub.Path + "/f", Parent: sub}}
+		dir.Children = []*FileNode{sub}
+		root.Children = append(root.Children, dir)
+	}
+	return root
+}
+
+func runExpansion(t *testing.T, model Model, cmd tea.Cmd) Model {
+	t.Helper()
+	for steps := 0; cmd != nil; steps++ {
+		if steps > 100 {
+			t.Fatalf("Expansion did not finish")
+		}
+		updated, next := model.Update(cmd())
+		model, cmd = updated.(Model), next
+	}
+	return model
+}
+
+func TestExpandWholeTreeProgressively(t *testing.T) {
+	root := &FileNode{Name: "", Path: "", IsDir: true, Expanded: true}
+	data, media := newNestedTree("data", expandChunk), newNestedTree("media", 3)
+	data.Parent, media.Parent = root, root
+	root.Children = []*FileNode{data, media}
+
+	model := newTestModel()
+	model.roots = []*sessionRoot{{path: "/data"}, {path: "/media"}}
+	model.root = root
+	model.height = 30
+	model.updateVisibleNodes()
+	model.cursor = 1 // media
+
+	updated, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(">")})
+	result := updated.(Model)
+	if cmd == nil || len(result.visibleNodes) != 2+expandChunk {
+		t.Fatalf("Expected the first chunk of rows, got %d", len(result.visibleNodes))
+	}
+	result = runExpansion(t, result, cmd)
+
+	got := result.visibleNodes
+	if len(got) != 2+3*(expandChunk+3) {
+		t.Fatalf("Expected every row shown, got %d", len(got))
+	}
+	if got[result.cursor] != media {
+		t.Errorf("Expected the cursor to stay on media, got %s", got[result.cursor].Path)
+	}
+	result.updateVisibleNodes()
+	for i := range got {
+		if got[i] != result.visibleNodes[i] {
+			t.Fatalf("Row %d differs from a full rebuild", i)
+		}
+	}
+
+	// Collapsing leaves the first level of each root
+	updated, cmd = result.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("<")})
+	result = runExpansion(t, updated.(Model), cmd)
+	if len(result.visibleNodes) != 2+expandChunk+3 || data.Children[0].Children[0].Expanded {
+		t.Errorf("Expected only the first level below the roots, got %d rows", len(result.visibleNodes))
+	}
+}
+
+func TestExpandToDepth(t *testing.T) {
+	root := newNestedTree("root", 3)
+	root.Expanded = true
+	model := newTestModel()
+	model.root = root
+	model.updateVisibleNodes()
+
+	m := *model
+	for _, key := range []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune("L")},
+		{Type: tea.KeyRunes, Runes: []rune("2")},
+		{Type: tea.KeyEnter},
+	} {
+		updated, cmd := m.Update(key)
+		m = runExpansion(t, updated.(Model), cmd)
+	}
+	// The root, its directories and their subdirectories
+	if m.depthInput != nil || len(m.visibleNodes) != 1+3+3 {
+		t.Fatalf("Expected two levels below the root, got %d rows", len(m.visibleNodes))
+	}
+
+	// Everything below the selected directory
+	m.cursor = 1
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("+")})
+	m = runExpansion(t, updated.(Model), cmd)
+	if len(m.visibleNodes) != 1+3+3+1 || m.visibleNodes[3].Name != "f" {
+		t.Errorf("Expected the file of the first directory to show, got %d rows", len(m.visibleNodes))
+	}
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("-")})
+	if m = updated.(Model); len(m.visibleNodes) != 1+3+2 || root.Children[0].Children[0].Expanded {
+		t.Errorf("Expected the first directory closed with everything below it, got %d rows", len(m.visibleNodes))
+	}
+}
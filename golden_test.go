@@ -0,0 +1,157 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// updateGolden rewrites the golden frames from the current rendering:
+//
+//	go test -run Golden -update
+var updateGolden = flag.Bool("update", false, "Rewrite the golden frames in testdata/golden")
+
+// ansiEscape matches the color and style sequences of a frame
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*[A-Za-z]")
+
+// uiHarness drives a Model through Update with synthetic messages, the way
+// the event loop does, and renders its frames
+type uiHarness struct {
+	t     *testing.T
+	model Model
+	cmd   tea.Cmd // Returned by the last Update, not run
+}
+
+// newUIHarness sizes the model's screen and fixes its clock, so that frames
+// are the same on every run
+func newUIHarness(t *testing.T, model *Model, width, height int) *uiHarness {
+	t.Helper()
+	model.now = func() time.Time { return time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC) }
+	h := &uiHarness{t: t, model: *model}
+	h.send(tea.WindowSizeMsg{Width: width, Height: height})
+	return h
+}
+
+// send passes messages to Update one after the other
+func (h *uiHarness) send(msgs ...tea.Msg) *uiHarness {
+	for _, msg := range msgs {
+		updated, cmd := h.model.Update(msg)
+		h.model, h.cmd = updated.(Model), cmd
+	}
+	return h
+}
+
+// press types keys given by name, such as "j", "enter", "esc" or "ctrl+c";
+// anything else is typed as runes
+func (h *uiHarness) press(keys ...string) *uiHarness {
+	named := map[string]tea.KeyType{
+		"enter": tea.KeyEnter, "esc": tea.KeyEsc, "space": tea.KeySpace, "tab": tea.KeyTab,
+		"up": tea.KeyUp, "down": tea.KeyDown, "left": tea.KeyLeft, "right": tea.KeyRight,
+		"pgup": tea.KeyPgUp, "pgdown": tea.KeyPgDown, "ctrl+c": tea.KeyCtrlC,
+	}
+	for _, key := range keys {
+		if keyType, ok := named[key]; ok {
+			h.send(tea.KeyMsg{Type: keyType})
+			continue
+		}
+		h.send(runeKey(key))
+	}
+	return h
+}
+
+// frame renders the screen as plain text, without styles or trailing spaces
+func (h *uiHarness) frame() string {
+	lines := strings.Split(ansiEscape.ReplaceAllString(h.model.View(), ""), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// assertGolden compares the frame with testdata/golden/name.golden
+func (h *uiHarness) assertGolden(name string) {
+	h.t.Helper()
+	path := filepath.Join("testdata", "golden", name+".golden")
+	got := h.frame()
+	if *updateGolden {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			h.t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			h.t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		h.t.Fatalf("Reading golden frame: %v (run go test -run Golden -update to write it)", err)
+	}
+	if got != string(want) {
+		h.t.Errorf("Frame %s differs from %s (run go test -run Golden -update if the change is intended)\ngot:\n%s\nwant:\n%s", name, path, got, want)
+	}
+}
+
+// newGoldenModel is a small tree with a rule, rendered the same on every run
+func newGoldenModel(t *testing.T) *Model {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/data"
+	t.Cleanup(func() { globalRootPath = originalGlobalRootPath })
+
+	modTime := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	root := &FileNode{Name: "data", Path: "/data", IsDir: true, Expanded: true, ModTime: modTime}
+	add := func(parent *FileNode, name string, size int64, dir bool) *FileNode {
+		node := &FileNode{Name: name, Path: parent.Path + "/" + name, Size: size, IsDir: dir, Parent: parent, ModTime: modTime}
+		parent.Children = append(parent.Children, node)
+		return node
+	}
+	photos := add(root, "photos", 0, true)
+	add(photos, "a.jpg", 3<<20, false)
+	add(photos, "b.jpg", 1<<20, false)
+	cache := add(root, "cache", 0, true)
+	add(cache, "tmp.bin", 5<<20, false)
+	add(root, "notes.txt", 2048, false)
+	calculateStats(root)
+
+	model := newTestModelWithStates(map[string]FilterState{"cache/**": FilterExclude})
+	model.root = root
+	model.filterFile = "filter.txt"
+	model.showGuides = true
+	model.reapplyFiltersToTree(root)
+	model.resortTree(root)
+	model.updateVisibleNodes()
+	return model
+}
+
+func TestGoldenTree(t *testing.T) {
+	h := newUIHarness(t, newGoldenModel(t), 80, 16)
+	h.assertGolden("tree")
+
+	// Expanding and excluding a file shows on its row and in the counts
+	h.press("j", "j", "enter", "j", "space", "space")
+	h.assertGolden("tree-edited")
+}
+
+func TestGoldenHelp(t *testing.T) {
+	h := newUIHarness(t, newGoldenModel(t), 80, 200)
+	h.press("?")
+	h.assertGolden("help")
+}
+
+func TestGoldenModals(t *testing.T) {
+	h := newUIHarness(t, newGoldenModel(t), 80, 20)
+	h.press("o")
+	h.assertGolden("sort-menu")
+	h.press("esc")
+	if h.model.topModal() != modalNone {
+		t.Fatalf("Expected Esc to close the sort menu")
+	}
+
+	h.press("l")
+	h.assertGolden("survivors")
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// guardRails are limits checked when toggling and saving, so that a stray
+// rule such as "- **" can't silently exclude everything on a shared machine
+type guardRails struct {
+	maxExcludePercent float64 // Share of the tree's files a toggle may exclude (0 disables)
+	warnIncludedSize  int64   // Included bytes above which to warn (0 disables)
+}
+
+// treeTotals counts the files in the tree and how the filters treat them
+type treeTotals struct {
+	files         int
+	excludedFiles int
+	includedSize  int64
+}
+
+func (m *Model) treeTotals() treeTotals {
+	var totals treeTotals
+	var walk func(node *FileNode)
+	walk = func(node *FileNode) {
+		for _, child := range node.Children {
+			if child.IsDir {
+				walk(child)
+				continue
+			}
+			totals.files++
+			if child.Filter == FilterExclude {
+				totals.excludedFiles++
+			} else {
+				totals.includedSize += child.Size
+			}
+		}
+	}
+	if m.root != nil {
+		walk(m.root)
+	}
+	return totals
+}
+
+// percentOf returns n as a percentage of total
+func percentOf(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) * 100 / float64(total)
+}
+
+// toggleBlocked explains why a toggle with the given impact is refused, or
+// returns "" when it stays within the limits
+func (m *Model) toggleBlocked(impact toggleImpact) string {
+	if m.guards.maxExcludePercent <= 0 || impact.excludedFiles == 0 {
+		return ""
+	}
+	totals := m.treeTotals()
+	share := percentOf(impact.excludedFiles, totals.files)
+	if share <= m.guards.maxExcludePercent {
+		return ""
+	}
+	return fmt.Sprintf("Blocked: this would exclude %.0f%% of all files (limit %.0f%%, see max-exclude-percent)",
+		share, m.guards.maxExcludePercent)
+}
+
+// guardChange makes a change of the rules other than a toggle, such as a
+// template, inverting the view or excluding a list of paths, and holds it to
+// the same limit as a toggle. When the files it newly excludes are more than
+// max-exclude-percent of the tree, the rules are put back as they were, the
+// reason is shown in the status line and false is returned.
+func (m *Model) guardChange(change func()) bool {
+	if m.guards.maxExcludePercent <= 0 || m.filesFrom != nil || m.root == nil {
+		change()
+		return true
+	}
+
+	excluded := make(map[*FileNode]bool)
+	for _, node := range collectNodes(m.root, nil) {
+		if !node.IsDir && node.Filter == FilterExclude {
+			excluded[node] = true
+		}
+	}
+	docs := []*FilterDocument{m.filters}
+	if m.multiRoot() {
+		docs = nil
+		for _, r := range m.roots {
+			docs = append(docs, r.filters)
+		}
+	}
+	saved := make([]*FilterDocument, len(docs))
+	for i, doc := range docs {
+		saved[i] = doc.clone()
+	}
+
+	change()
+
+	var impact toggleImpact
+	for _, node := range collectNodes(m.root, nil) {
+		if !node.IsDir && node.Filter == FilterExclude && !excluded[node] {
+			impact.excludedFiles++
+			impact.excludedSize += node.Size
+		}
+	}
+	blocked := m.toggleBlocked(impact)
+	if blocked == "" {
+		return true
+	}
+
+	// Put the rules back in place, as the roots share the documents
+	for i, doc := range docs {
+		*doc = *saved[i]
+	}
+	filters, cache, file := m.filters, m.filterCache, m.filterFile
+	m.filterGen++
+	m.clearFilterCaches()
+	for _, top := range m.topLevelNodes() {
+		m.activateRootFor(top)
+		m.reapplyFiltersToTree(top)
+	}
+	m.filters, m.filterCache, m.filterFile = filters, cache, file
+	m.refreshView()
+	m.statusMsg = blocked
+	return false
+}
+
+// confirmChange checks giving node state against the guard rails. A change
+// beyond them is refused, and a large one waits for key to be pressed again;
+// both return false with the reason in the status line.
+func (m *Model) confirmChange(node *FileNode, state FilterState, key string) bool {
+	if node.IsDir && m.filesFrom == nil && m.pendingToggle != node &&
+		(m.confirmFiles > 0 || m.guards.maxExcludePercent > 0) {
+		impact := m.previewChange(node, state)
+		if blocked := m.toggleBlocked(impact); blocked != "" {
+			m.statusMsg = blocked
+			return false
+		}
+		if m.confirmFiles > 0 && impact.files() >= m.confirmFiles {
+			m.pendingToggle = node
+			m.pendingToggleKey = key
+			keyName := key
+			if key == " " {
+				keyName = "Space"
+			}
+			m.statusMsg = impact.String() + " - press " + keyName + " again to confirm"
+			return false
+		}
+	}
+	m.pendingToggle = nil
+	m.statusMsg = ""
+	return true
+}
+
+// warnIncludedSize shows the included size warning after a change
+func (m *Model) warnIncludedSize() {
+	if m.guards.warnIncludedSize > 0 {
+		if warning := m.includedSizeWarning(m.treeTotals()); warning != "" {
+			m.statusMsg = "Warning: " + warning
+		}
+	}
+}
+
+// includedSizeWarning warns when the included files add up to more than the
+// configured size, or returns ""
+func (m *Model) includedSizeWarning(totals treeTotals) string {
+	if m.guards.warnIncludedSize <= 0 || totals.includedSize <= m.guards.warnIncludedSize {
+		return ""
+	}
+	return fmt.Sprintf("%s is included (warning above %s)",
+		formatSize(totals.includedSize), formatSize(m.guards.warnIncludedSize))
+}
+
+// saveWarnings lists the guard rails the current filters exceed, or returns
+// "" when saving needs no second thought
+func (m *Model) saveWarnings() string {
+	if m.guards.maxExcludePercent <= 0 && m.guards.warnIncludedSize <= 0 {
+		return ""
+	}
+	totals := m.treeTotals()
+	var warnings []string
+	if m.guards.maxExcludePercent > 0 {
+		if share := percentOf(totals.excludedFiles, totals.files); share > m.guards.maxExcludePercent {
+			warnings = append(warnings, fmt.Sprintf("%.0f%% of all files are excluded (limit %.0f%%)",
+				share, m.guards.maxExcludePercent))
+		}
+	}
+	if warning := m.includedSizeWarning(totals); warning != "" {
+		warnings = append(warnings, warning)
+	}
+	return strings.Join(warnings, ", ")
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// newGuardTestModel returns a model over a tree with 8 files of 1 KB in
+// "big" and 2 in "small"
+func newGuardTestModel() (*Model, *FileNode, *FileNode) {
+	root := &FileNode{Name: "test", Path: "/test", IsDir: true, Expanded: true}
+	big := &FileNode{Name: "big", Path: "/test/big", IsDir: true, Parent: root}
+	small := &FileNode{Name: "small", Path: "/test/small", IsDir: true, Parent: root}
+	root.Children = []*FileNode{big, small}
+	for i := 0; i < 10; i++ {
+		dir := big
+		if i >= 8 {
+			dir = small
+		}
+		dir.Children = append(dir.Children, &FileNode{
+			Name: fmt.Sprintf("%d.bin", i), Path: fmt.Sprintf("%s/%d.bin", dir.Path, i), Size: 1024, Parent: dir,
+		})
+	}
+
+	model := newTestModel()
+	model.root = root
+	model.updateVisibleNodes()
+	return model, big, small
+}
+
+func TestGuardRailBlocksLargeExclusion(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, big, small := newGuardTestModel()
+	model.guards.maxExcludePercent = 50
+	space := tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")}
+
+	// none -> include -> exclude on "big" would exclude 80% of the files
+	model.cursor = model.indexOfVisible(big)
+	updated, _ := model.Update(space)
+	updated, _ = updated.(Model).Update(space)
+	result := updated.(Model)
+	if big.Filter != FilterInclude {
+		t.Errorf("Exclusion over the limit should be blocked, got %v", big.Filter)
+	}
+	if !strings.Contains(result.statusMsg, "Blocked: this would exclude 80% of all files (limit 50%") {
+		t.Errorf("Expected blocked message, got %q", result.statusMsg)
+	}
+
+	// Excluding "small" stays within the limit
+	result.cursor = result.indexOfVisible(small)
+	updated, _ = result.Update(space)
+	updated, _ = updated.(Model).Update(space)
+	if small.Filter != FilterExclude {
+		t.Errorf("Exclusion within the limit should be applied, got %v", small.Filter)
+	}
+}
+
+func TestGuardRailSaveNeedsSecondPress(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, _, _ := newGuardTestModel()
+	model.guards.warnIncludedSize = 4096
+	model.filterFile = filepath.Join(t.TempDir(), "filter.txt")
+	save := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("s")}
+
+	updated, _ := model.Update(save)
+	result := updated.(Model)
+	if result.saved {
+		t.Fatalf("Save over the size warning should wait for a second press")
+	}
+	if !strings.Contains(result.statusMsg, "10.0 KiB is included (warning above 4.0 KiB)") {
+		t.Errorf("Expected size warning, got %q", result.statusMsg)
+	}
+
+	// Another key in between cancels the pending save
+	updated, _ = result.Update(tea.KeyMsg{Type: tea.KeyDown})
+	updated, _ = updated.(Model).Update(save)
+	if updated.(Model).saved {
+		t.Errorf("Pending save should be cancelled by other keys")
+	}
+
+	updated, _ = updated.(Model).Update(save)
+	result = updated.(Model)
+	if !result.saved {
+		t.Errorf("Second press should save despite the warning")
+	}
+	if _, err := os.Stat(result.filterFile); err != nil {
+		t.Errorf("Filter file should have been written: %v", err)
+	}
+}
+
+func TestGuardRailBlocksBulkChanges(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	// A template excluding everything is refused and leaves no rule behind
+	model, big, _ := newGuardTestModel()
+	model.guards.maxExcludePercent = 50
+	model.applyTemplate(RuleTemplate{Pattern: "**", State: FilterExclude}, nil)
+	if !strings.HasPrefix(model.statusMsg, "Blocked: this would exclude 100% of all files") {
+		t.Errorf("Expected the template blocked, got %q", model.statusMsg)
+	}
+	if model.filters.count() != 0 || big.Children[0].Filter != FilterNone {
+		t.Errorf("Blocked template should leave the rules alone, got %v", model.filters.states())
+	}
+
+	// Inverting an included "big" would exclude 80% of the files
+	model, big, _ = newGuardTestModel()
+	model.guards.maxExcludePercent = 50
+	model.setNodeFilter(big, FilterInclude)
+	result := pressKeys(*model, runeKey("i"))
+	if !strings.HasPrefix(result.statusMsg, "Blocked: this would exclude 80% of all files") {
+		t.Errorf("Expected the invert blocked, got %q", result.statusMsg)
+	}
+	if big.Filter != FilterInclude || big.Children[0].Filter != FilterInclude || result.filters.count() != 1 {
+		t.Errorf("Blocked invert should leave the rules alone, got %v", result.filters.states())
+	}
+}
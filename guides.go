@@ -0,0 +1,65 @@
+package main
+
+import "strings"
+
+// treeGuides draws the indentation of rows as lines joining each entry to
+// its parent directory, so that deep in the tree it stays clear which
+// directory a row belongs to. Each level takes two columns, as the plain
+// indentation does.
+type treeGuides struct {
+	m    *Model
+	last map[*FileNode]*FileNode // Last shown child of each directory
+}
+
+func (m *Model) newTreeGuides() *treeGuides {
+	return &treeGuides{m: m, last: make(map[*FileNode]*FileNode)}
+}
+
+// isLast reports whether node is the last entry its directory shows
+func (g *treeGuides) isLast(node *FileNode) bool {
+	parent := node.Parent
+	if parent == nil {
+		return true
+	}
+	last, ok := g.last[parent]
+	if !ok {
+		// Look from the end, the last child is shown unless a view mode
+		// hides it
+		for i := len(parent.Children) - 1; i >= 0; i-- {
+			child := parent.Children[i]
+			if g.m.viewMatches == nil || g.m.viewMatches[child] {
+				last = child
+				break
+			}
+		}
+		g.last[parent] = last
+	}
+	return node == last
+}
+
+// prefix returns the indentation of node's row: a branch joining it to its
+// directory, and a line through each level where a directory above it has
+// more entries below
+func (g *treeGuides) prefix(node *FileNode) string {
+	depth := getNodeDepth(node)
+	if depth == 0 {
+		return ""
+	}
+
+	levels := make([]string, depth)
+	if g.isLast(node) {
+		levels[depth-1] = "└ "
+	} else {
+		levels[depth-1] = "├ "
+	}
+	ancestor := node.Parent
+	for level := depth - 2; level >= 0; level-- {
+		if g.isLast(ancestor) {
+			levels[level] = "  "
+		} else {
+			levels[level] = "│ "
+		}
+		ancestor = ancestor.Parent
+	}
+	return strings.Join(levels, "")
+}
@@ -0,0 +1,61 @@
+package main
+
+import "testing"
+
+func TestTreeGuidePrefixes(t *testing.T) {
+	root := &FileNode{Name: "root", Path: "/root", IsDir: true, Expanded: true}
+	add := func(parent *FileNode, name string) *FileNode {
+		node := &FileNode{Name: name, Path: parent.Path + "/" + name, IsDir: true, Expanded: true, Parent: parent}
+		parent.Children = append(parent.Children, node)
+		return node
+	}
+	a := add(root, "a")
+	b := add(a, "b")
+	c := add(b, "c")
+	d := add(a, "d")
+	e := add(root, "e")
+
+	model := newTestModel()
+	model.root = root
+	guides := model.newTreeGuides()
+	tests := []struct {
+		node     *FileNode
+		expected string
+	}{
+		{root, ""},
+		{a, "├ "},
+		{b, "│ ├ "},
+		{c, "│ │ └ "},
+		{d, "│ └ "},
+		{e, "└ "},
+	}
+	for _, tt := range tests {
+		if got := guides.prefix(tt.node); got != tt.expected {
+			t.Errorf("prefix(%s) = %q; want %q", tt.node.Name, got, tt.expected)
+		}
+	}
+
+	// A directory hidden by the view mode doesn't keep the line going
+	model.viewMatches = map[*FileNode]bool{root: true, a: true, b: true, c: true}
+	guides = model.newTreeGuides()
+	if got := guides.prefix(c); got != "    └ " {
+		t.Errorf("prefix(c) = %q with d and e hidden; want %q", got, "    └ ")
+	}
+}
+
+func TestTreeGuidesOfSessionRoots(t *testing.T) {
+	model := newTestModel()
+	model.roots = []*sessionRoot{{path: "/data"}, {path: "/media"}}
+	model.root = model.newRootNode()
+	data := model.root.Children[0]
+	photos := &FileNode{Name: "photos", Path: "/data/photos", Parent: data}
+	data.Children = []*FileNode{photos}
+
+	guides := model.newTreeGuides()
+	if got := guides.prefix(data); got != "" {
+		t.Errorf("Expected a root of the session to have no guide, got %q", got)
+	}
+	if got := guides.prefix(photos); got != "└ " {
+		t.Errorf("prefix(photos) = %q; want %q", got, "└ ")
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"strings"
+)
+
+// localHashers are the checksums rclone lists that can be computed here for
+// local files, to match those of a remote destination
+var localHashers = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"crc32":  func() hash.Hash { return crc32.NewIEEE() },
+}
+
+// destHashKind picks the checksum to hash local files with: the first in
+// hashPreference that the destination lists and can be computed here, or ""
+// when there is none
+func destHashKind(items []lsjsonItem) string {
+	listed := make(map[string]bool)
+	for _, item := range items {
+		for kind, value := range item.Hashes {
+			if value != "" {
+				listed[kind] = true
+			}
+		}
+	}
+	for _, kind := range hashPreference {
+		if listed[kind] && localHashers[kind] != nil {
+			return kind
+		}
+	}
+	return ""
+}
+
+// localHashesMsg carries the checksums computed by a hashing job, as
+// "type:value" by file
+type localHashesMsg struct {
+	hashes map[*FileNode]string
+}
+
+// hashLocalJob hashes the local files kept by the rules with the checksum
+// the destination lists, so that those already there are recognized even
+// when they were renamed or their modification time changed. Only files of
+// a size found on the destination are read, as no other can have a copy.
+func (m *Model) hashLocalJob(rm *remoteMirror) *job {
+	return &job{
+		kind:  JobHash,
+		title: "Hash local files (" + rm.hashKind + ")",
+		start: func(m *Model) jobFunc {
+			sizes := make(map[int64]bool)
+			for _, item := range rm.files {
+				sizes[item.Size] = true
+			}
+			var files []*FileNode
+			for _, top := range m.topLevelNodes() {
+				for _, node := range collectNodes(top, nil) {
+					if !node.IsDir && node.Hash == "" && node.Filter != FilterExclude && sizes[node.Size] && !isRemotePath(node.Path) {
+						files = append(files, node)
+					}
+				}
+			}
+			kind, newHash := rm.hashKind, localHashers[rm.hashKind]
+
+			return func(ctx context.Context) jobResult {
+				hashes := make(map[*FileNode]string)
+				var size int64
+				for _, file := range files {
+					if err := ctx.Err(); err != nil {
+						return jobResult{err: err}
+					}
+					sum, err := hashLocalFile(file.Path, newHash())
+					if err != nil {
+						// Unreadable files keep being compared by time
+						continue
+					}
+					hashes[file] = kind + ":" + sum
+					size += file.Size
+				}
+				return jobResult{
+					msg:    localHashesMsg{hashes: hashes},
+					detail: fmt.Sprintf("%s, %s", fileCount(len(hashes)), formatSize(size)),
+				}
+			}
+		},
+	}
+}
+
+// hashLocalFile returns the hex encoded checksum of a local file's content,
+// as rclone lists it
+func hashLocalFile(path string, h hash.Hash) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// applyLocalHashes compares the hashed files with the destination by
+// checksum from now on, and says how many are there under another name
+func (m *Model) applyLocalHashes(msg localHashesMsg) {
+	rm, ok := m.mirror.(*remoteMirror)
+	if !ok {
+		return
+	}
+	rm.hashes = msg.hashes
+	clear(rm.states)
+
+	renamed := 0
+	for node := range msg.hashes {
+		if rm.compare(node) == mirrorRenamed {
+			renamed++
+		}
+	}
+	if renamed > 0 {
+		m.statusMsg = fmt.Sprintf("Hashed %s: %s already on %s under another name, = excludes them",
+			fileCount(len(msg.hashes)), fileCount(renamed), m.syncDest)
+	}
+}
+
+// hashingLocal reports whether a hashing job is queued or running
+func (m *Model) hashingLocal() bool {
+	for _, j := range m.jobs {
+		if j.kind == JobHash && (j.status == JobQueued || j.status == JobRunning) {
+			return true
+		}
+	}
+	return false
+}
+
+// hashKey is how checksums are looked up across the destination, whose
+// listing may not use the same case
+func hashKey(hash string) string {
+	return strings.ToLower(hash)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDestHashKind(t *testing.T) {
+	tests := []struct {
+		name     string
+		hashes   []map[string]string
+		expected string
+	}{
+		{"none", nil, ""},
+		{"md5 over sha1", []map[string]string{{"sha1": "a"}, {"md5": "b"}}, "md5"},
+		{"only what can be computed", []map[string]string{{"quickxor": "a", "sha256": "b"}}, "sha256"},
+		{"unknown kinds", []map[string]string{{"dropbox": "a"}}, ""},
+		{"empty values", []map[string]string{{"md5": ""}}, ""},
+	}
+	for _, tt := range tests {
+		var items []lsjsonItem
+		for _, hashes := range tt.hashes {
+			items = append(items, lsjsonItem{Path: "f", Hashes: hashes})
+		}
+		if got := destHashKind(items); got != tt.expected {
+			t.Errorf("%s: destHashKind = %q; want %q", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestHashLocalFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.txt")
+	os.WriteFile(path, []byte("a"), 0644)
+	for kind, expected := range map[string]string{
+		"md5":   "0cc175b9c0f1b6a831c399e269772661",
+		"sha1":  "86f7e437faa5a7fce15d1ddcb9eaeaea377667b8",
+		"crc32": "e8b7be43",
+	} {
+		if got, err := hashLocalFile(path, localHashers[kind]()); err != nil || got != expected {
+			t.Errorf("%s: got %q, %v; want %q", kind, got, err, expected)
+		}
+	}
+}
+
+func TestHashLocalFindsRenamedCopies(t *testing.T) {
+	rootDir := t.TempDir()
+	os.WriteFile(filepath.Join(rootDir, "moved.jpg"), []byte("hello"), 0644)
+	os.WriteFile(filepath.Join(rootDir, "edited.jpg"), []byte("world"), 0644)
+	os.WriteFile(filepath.Join(rootDir, "new.jpg"), []byte("brand new"), 0644)
+
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = rootDir
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, apply := newScanTestModel(rootDir)
+	defer model.cancel()
+	if err := model.newScanner().scan(model.root); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	m := apply(*model)
+	m.syncDest = "b2:backup"
+	m.hashLocal = true
+	var args []string
+	m.rcloneRun = func(ctx context.Context, a ...string) ([]byte, error) {
+		args = a
+		// moved.jpg is there under its old name, edited.jpg has the size of
+		// a file there but not its content
+		return json.Marshal([]lsjsonItem{
+			{Path: "2023/old.jpg", Size: 5, Hashes: map[string]string{"md5": fmt.Sprintf("%X", md5.Sum([]byte("hello")))}},
+			{Path: "2023/other.jpg", Size: 5, Hashes: map[string]string{"md5": fmt.Sprintf("%x", md5.Sum([]byte("other")))}},
+		})
+	}
+
+	updated, cmd := m.Update(m.listSyncDest()())
+	m = updated.(Model)
+	if !strings.HasSuffix(strings.Join(args, " "), " --hash") {
+		t.Errorf("Expected the destination listed with checksums, got %q", args)
+	}
+	if !m.hashingLocal() {
+		t.Fatalf("Expected a hashing job after the listing")
+	}
+	m = pressKeys(m, runeKey("="))
+	if !strings.HasPrefix(m.statusMsg, "Still hashing") || m.filters.count() != 0 {
+		t.Errorf("Expected = to wait for the hashes, got %q", m.statusMsg)
+	}
+
+	var done []jobDoneMsg
+	for _, msg := range runCmd(cmd) {
+		if msg, ok := msg.(jobDoneMsg); ok {
+			done = append(done, msg)
+		}
+	}
+	if len(done) != 1 {
+		t.Fatalf("Expected the hashing job to finish, got %d", len(done))
+	}
+	updated, _ = m.Update(done[0])
+	m = updated.(Model)
+	if m.statusMsg != "Hashed 2 files: 1 file already on b2:backup under another name, = excludes them" {
+		t.Errorf("Unexpected status %q", m.statusMsg)
+	}
+	if !strings.Contains(m.View(), "moved.jpg (5 B) = dest, renamed") {
+		t.Errorf("Expected the renamed copy marked:\n%s", m.View())
+	}
+
+	m = pressKeys(m, runeKey("="))
+	if m.filters.state("moved.jpg") != FilterExclude || m.filters.count() != 1 {
+		t.Fatalf("Expected only moved.jpg excluded, got %v", m.filters.states())
+	}
+	if !strings.Contains(m.statusMsg, "1 file there under another name, which rclone sync deletes") {
+		t.Errorf("Expected a warning about sync, got %q", m.statusMsg)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// heatMode is what the heatmap colors directories by
+type heatMode int
+
+const (
+	heatOff heatMode = iota
+	heatFiles
+	heatSize
+)
+
+// heatModeNames are the modes as the config file and the header name them
+var heatModeNames = []string{"off", "files", "size"}
+
+// parseHeatMode reads a heatmap mode from its name
+func parseHeatMode(name string) (heatMode, error) {
+	i := slices.Index(heatModeNames, name)
+	if i < 0 {
+		return heatOff, fmt.Errorf("%q is not off, files or size", name)
+	}
+	return heatMode(i), nil
+}
+
+// heatmap colors directories on the gradient of the theme by their
+// percentile within the tree, so that the busiest ones stand out wherever
+// they are sorted. The ranks are kept until the totals of the tree change.
+type heatmap struct {
+	mode  heatMode
+	key   heatKey
+	ranks map[*FileNode]float64
+}
+
+// heatKey identifies the state of the tree the ranks were computed for
+type heatKey struct {
+	mode  heatMode
+	root  *FileNode
+	files int
+	size  int64
+}
+
+// heatValue is what node is ranked by
+func (h *heatmap) heatValue(node *FileNode) int64 {
+	if h.mode == heatSize {
+		return node.TotalSize
+	}
+	return int64(node.TotalFiles)
+}
+
+// ranked reports whether node takes part in the heatmap: directories below
+// the top of the tree whose contents are known
+func ranked(node *FileNode) bool {
+	return node.IsDir && node.Parent != nil && !node.Loading && !node.Skipped
+}
+
+// heatRank returns the percentile of node among the directories of the tree,
+// from 0 for the smallest to 1 for the biggest
+func (m *Model) heatRank(node *FileNode) (float64, bool) {
+	h := m.heat
+	if h == nil || h.mode == heatOff || m.root == nil || !ranked(node) {
+		return 0, false
+	}
+	key := heatKey{mode: h.mode, root: m.root}
+	for _, top := range m.topLevelNodes() {
+		key.files += top.TotalFiles
+		key.size += top.TotalSize
+	}
+	rank, ok := h.ranks[node]
+	if key == h.key && ok {
+		return rank, true
+	}
+
+	// The tree changed, or a rescan replaced the node
+	h.key = key
+	h.ranks = make(map[*FileNode]float64)
+	var dirs []*FileNode
+	var walk func(node *FileNode)
+	walk = func(node *FileNode) {
+		if ranked(node) {
+			dirs = append(dirs, node)
+		}
+		for _, child := range node.Children {
+			if child.IsDir {
+				walk(child)
+			}
+		}
+	}
+	walk(m.root)
+
+	values := make([]int64, len(dirs))
+	for i, dir := range dirs {
+		values[i] = h.heatValue(dir)
+	}
+	slices.Sort(values)
+	for _, dir := range dirs {
+		// Equal values share the rank of the first of them
+		below := sort.Search(len(values), func(i int) bool { return values[i] >= h.heatValue(dir) })
+		if len(values) > 1 {
+			h.ranks[dir] = float64(below) / float64(len(values)-1)
+		}
+	}
+	rank, ok = h.ranks[node]
+	return rank, ok
+}
+
+// heatName renders the name of node in its heatmap color
+func (m *Model) heatName(node *FileNode) string {
+	rank, ok := m.heatRank(node)
+	if !ok {
+		return node.Name
+	}
+	gradient := currentTheme.Heat
+	step := min(int(rank*float64(len(gradient))), len(gradient)-1)
+	return lipgloss.NewStyle().Foreground(gradient[step]).Render(node.Name)
+}
+
+// cycleHeatmap colors directories by file count, then by size, then not
+func (m *Model) cycleHeatmap() {
+	if m.heat == nil {
+		m.heat = &heatmap{}
+	}
+	m.heat.mode = (m.heat.mode + 1) % heatMode(len(heatModeNames))
+	m.heat.ranks = nil
+
+	switch m.heat.mode {
+	case heatFiles:
+		m.statusMsg = "Coloring directories by their number of files, O colors them by size"
+	case heatSize:
+		m.statusMsg = "Coloring directories by size, O turns the colors off"
+	default:
+		m.statusMsg = "Heatmap off"
+	}
+}
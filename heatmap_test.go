@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestHeatmap(t *testing.T) {
+	root := &FileNode{Name: "test", Path: "/test", IsDir: true, Expanded: true}
+	var dirs []*FileNode
+	for i, files := range []int{1, 4, 4, 9} {
+		dir := &FileNode{Name: fmt.Sprintf("d%d", i), Path: fmt.Sprintf("/test/d%d", i), IsDir: true, Parent: root}
+		for j := range files {
+			// The directory with the most files is the smallest
+			dir.Children = append(dir.Children, &FileNode{Name: fmt.Sprintf("%d", j), Size: int64(100 / (files * files)), Parent: dir})
+		}
+		dirs = append(dirs, dir)
+	}
+	root.Children = dirs
+	calculateStats(root)
+
+	model := newTestModel()
+	model.root = root
+	model.width, model.height = 100, 20
+	model.updateVisibleNodes()
+
+	if _, ok := model.heatRank(dirs[0]); ok {
+		t.Errorf("Directories should not be ranked with the heatmap off")
+	}
+
+	updated, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("O")})
+	m := updated.(Model)
+	for i, expected := range []float64{0, 1.0 / 3, 1.0 / 3, 1} {
+		if rank, ok := m.heatRank(dirs[i]); !ok || rank != expected {
+			t.Errorf("Rank of d%d by files = %v; want %v", i, rank, expected)
+		}
+	}
+	if _, ok := m.heatRank(root); ok {
+		t.Errorf("The top of the tree should not be ranked")
+	}
+	if view := m.View(); !strings.Contains(view, "Heat: files (O)") {
+		t.Errorf("Expected the heatmap mode in the header:\n%s", view)
+	}
+
+	// By size the order turns around
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("O")})
+	m = updated.(Model)
+	if rank, _ := m.heatRank(dirs[0]); rank != 1 {
+		t.Errorf("Rank of d0 by size = %v; want 1", rank)
+	}
+	if rank, _ := m.heatRank(dirs[3]); rank != 0 {
+		t.Errorf("Rank of d3 by size = %v; want 0", rank)
+	}
+
+	// New files change the ranks
+	dirs[3].Children = append(dirs[3].Children, &FileNode{Name: "big", Size: 1000, Parent: dirs[3]})
+	calculateStats(root)
+	if rank, _ := m.heatRank(dirs[3]); rank != 1 {
+		t.Errorf("Rank of d3 after growing = %v; want 1", rank)
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("O")})
+	m = updated.(Model)
+	if m.heat.mode != heatOff || m.heatName(dirs[3]) != "d3" {
+		t.Errorf("Expected the third O to turn the heatmap off")
+	}
+}
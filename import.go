@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// pathImport is the state of the pane excluding a list of paths, as made by
+// another tool: pasted lines, or the name of a file holding them
+type pathImport struct {
+	input  string   // Typed file name, or a single path
+	pasted []string // Lines pasted so far
+	err    string
+}
+
+// openPathImport opens the pane for a new list
+func (m *Model) openPathImport() {
+	if m.filesFrom != nil {
+		m.statusMsg = "Paths can't be excluded from a --files-from list, remove them with Space"
+		return
+	}
+	m.pathImport = &pathImport{}
+	m.openModal(modalImport)
+}
+
+// splitPathList returns the paths of a list, one per line, skipping blank
+// lines and # comments
+func splitPathList(text string) []string {
+	var paths []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(strings.TrimSuffix(line, "\r"))
+		if line != "" && !strings.HasPrefix(line, "#") {
+			paths = append(paths, line)
+		}
+	}
+	return paths
+}
+
+// readPathList reads the paths listed in a file
+func readPathList(filename string) ([]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var b strings.Builder
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		b.WriteString(scanner.Text() + "\n")
+	}
+	return splitPathList(b.String()), scanner.Err()
+}
+
+// listedNode finds the node of a listed path. Paths may be relative to the
+// root, as in scripts, or full paths starting with a root's path.
+func (m *Model) listedNode(p string) (*FileNode, error) {
+	for _, top := range m.topLevelNodes() {
+		rest, ok := strings.CutPrefix(filepath.ToSlash(p), strings.TrimSuffix(top.Path, "/"))
+		if !ok || (rest != "" && rest[0] != '/' && !strings.HasSuffix(top.Path, ":")) {
+			continue
+		}
+		if m.multiRoot() {
+			rest = top.Name + "/" + strings.TrimPrefix(rest, "/")
+		}
+		return m.scriptNode(rest)
+	}
+	if filepath.IsAbs(p) {
+		return nil, fmt.Errorf("%s is not below the root", p)
+	}
+	return m.scriptNode(filepath.ToSlash(p))
+}
+
+// excludeListedPaths excludes the node of each path. It returns how many
+// were excluded by a new rule, how many were excluded already and the paths
+// not found in the tree.
+func (m *Model) excludeListedPaths(paths []string) (excluded, already int, missing []string) {
+	for _, p := range paths {
+		node, err := m.listedNode(p)
+		if err != nil || node.Parent == nil || m.isHiddenRoot(node) {
+			missing = append(missing, p)
+			continue
+		}
+		if node.Filter == FilterExclude {
+			already++
+			continue
+		}
+		m.setNodeFilter(node, FilterExclude)
+		excluded++
+	}
+	return excluded, already, missing
+}
+
+// importSummary describes the outcome of excludeListedPaths
+func importSummary(excluded, already int, missing []string) string {
+	summary := fmt.Sprintf("Excluded %d paths", excluded)
+	if already > 0 {
+		summary += fmt.Sprintf(", %d were excluded already", already)
+	}
+	if len(missing) > 0 {
+		shown := missing[:min(3, len(missing))]
+		summary += fmt.Sprintf(", %d not in the tree: %s", len(missing), strings.Join(shown, ", "))
+		if len(missing) > len(shown) {
+			summary += ", ..."
+		}
+	}
+	return summary
+}
+
+func (m Model) updateImportPane(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	imp := *m.pathImport
+	imp.err = ""
+	if !msg.Paste {
+		if used, cmd := m.editPrompt(&imp.input, msg); used {
+			m.pathImport = &imp
+			return m, cmd
+		}
+	}
+
+	switch {
+	case msg.Paste:
+		// A pasted list arrives at once, a single pasted line may be a file name
+		text := string(msg.Runes)
+		if strings.Contains(strings.TrimSpace(text), "\n") {
+			imp.pasted = append(imp.pasted, splitPathList(text)...)
+		} else {
+			imp.input += strings.TrimSpace(text)
+		}
+	case msg.Type == tea.KeyEnter:
+		paths := imp.pasted
+		if input := strings.TrimSpace(imp.input); input != "" {
+			if info, err := os.Stat(input); err == nil && !info.IsDir() {
+				listed, err := readPathList(input)
+				if err != nil {
+					imp.err = err.Error()
+					break
+				}
+				paths = append(paths, listed...)
+			} else {
+				// Not a file, so a path to exclude itself
+				paths = append(paths, input)
+			}
+		}
+		if len(paths) == 0 {
+			imp.err = "Paste the paths or type the name of a file listing them first"
+			break
+		}
+		m.closeModal(modalImport)
+		m.pathImport = nil
+		var excluded, already int
+		var missing []string
+		if !m.guardChange(func() { excluded, already, missing = m.excludeListedPaths(paths) }) {
+			return m, nil
+		}
+		m.refreshView()
+		m.statusMsg = importSummary(excluded, already, missing)
+		m.warnIncludedSize()
+		return m, nil
+	case msg.Type == tea.KeyEsc:
+		m.closeModal(modalImport)
+		m.pathImport = nil
+		return m, nil
+	case msg.Type == tea.KeyCtrlU:
+		imp = pathImport{}
+	}
+	m.pathImport = &imp
+	return m, nil
+}
+
+func (m Model) renderImport() string {
+	paneStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(currentTheme.Border).
+		Padding(1, 2)
+
+	imp := m.pathImport
+	var b strings.Builder
+	b.WriteString("Exclude a list of paths, one per line, relative to the root or full paths.\n")
+	b.WriteString("Paste the list, or type the name of a file holding it:\n\n")
+	fmt.Fprintf(&b, "> %s█\n", imp.input)
+	if len(imp.pasted) > 0 {
+		fmt.Fprintf(&b, "\n%d pasted paths:\n", len(imp.pasted))
+		for _, p := range imp.pasted[:min(5, len(imp.pasted))] {
+			b.WriteString("  " + p + "\n")
+		}
+		if len(imp.pasted) > 5 {
+			b.WriteString("  ...\n")
+		}
+	}
+	if imp.err != "" {
+		b.WriteString("\n" + lipgloss.NewStyle().Foreground(currentTheme.Warning).Render(imp.err) + "\n")
+	}
+	b.WriteString("\nEnter excludes them, Ctrl+U clears, Esc cancels")
+
+	return m.placePane(paneStyle, b.String())
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestImportPathList(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, big, small := newGuardTestModel()
+	var m tea.Model = *model
+	press := func(msgs ...tea.KeyMsg) {
+		for _, msg := range msgs {
+			m, _ = m.Update(msg)
+		}
+	}
+
+	// A pasted list with full and relative paths, a comment and a stray path
+	press(
+		tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("I")},
+		tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("# from du\n/test/big/0.bin\nbig/1.bin\r\n\nsmall/\nmissing.txt\n"), Paste: true},
+	)
+	if !strings.Contains(m.View(), "4 pasted paths:") {
+		t.Errorf("Expected the pasted paths in the pane:\n%s", m.View())
+	}
+	press(tea.KeyMsg{Type: tea.KeyEnter})
+
+	result := m.(Model)
+	if result.modalOpen(modalImport) {
+		t.Fatalf("Expected the pane to close")
+	}
+	if big.Children[0].Filter != FilterExclude || big.Children[1].Filter != FilterExclude || small.Filter != FilterExclude {
+		t.Errorf("Expected the listed paths to be excluded")
+	}
+	if result.filters.state("small/**") != FilterExclude || result.filters.state("big/0.bin") != FilterExclude {
+		t.Errorf("Expected a rule per path, got %v", result.filters.states())
+	}
+	if want := "Excluded 3 paths, 1 not in the tree: missing.txt"; result.statusMsg != want {
+		t.Errorf("Expected %q, got %q", want, result.statusMsg)
+	}
+
+	// A file holding the list, with a path that is excluded already
+	list := filepath.Join(t.TempDir(), "kill-list.txt")
+	os.WriteFile(list, []byte("big/2.bin\nsmall/8.bin\n"), 0644)
+	press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("I")}, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(list)}, tea.KeyMsg{Type: tea.KeyEnter})
+	result = m.(Model)
+	if big.Children[2].Filter != FilterExclude || result.statusMsg != "Excluded 1 paths, 1 were excluded already" {
+		t.Errorf("Expected the paths of the file to be excluded, got %q", result.statusMsg)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// includeDirective starts a line that pulls in the rules of another filter
+// file, so that exclusions shared by many filter files live in one place.
+// rclone reads the line as a comment.
+const includeDirective = "#include "
+
+// parseIncludeDirective returns the file named by an include line
+func parseIncludeDirective(line string) (string, bool) {
+	name, ok := strings.CutPrefix(line, includeDirective)
+	name = strings.TrimSpace(name)
+	return name, ok && name != ""
+}
+
+// isOwnRule reports whether a rule belongs to the filter file being edited,
+// rather than being an include directive or a rule it pulled in
+func (r FilterRule) isOwnRule() bool {
+	return r.Include == "" && r.Origin == ""
+}
+
+// expandIncludes adds the rules of every file included by rules right after
+// its directive, with Origin naming the file. Names are relative to the
+// directory of filename, the file holding the directives. Included files may
+// include others, but not themselves.
+func expandIncludes(rules []FilterRule, filename string) ([]FilterRule, error) {
+	path, err := filepath.Abs(filename)
+	if err != nil {
+		return rules, err
+	}
+	return expandIncludesFrom(rules, filepath.Dir(path), []string{path})
+}
+
+// expandIncludesFrom expands the directives of rules read from a file in
+// dir, stack holding the files being expanded
+func expandIncludesFrom(rules []FilterRule, dir string, stack []string) ([]FilterRule, error) {
+	var expanded []FilterRule
+	var errs []error
+	for _, rule := range rules {
+		expanded = append(expanded, rule)
+		if rule.Include == "" {
+			continue
+		}
+
+		path := rule.Include
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		if slices.Contains(stack, path) {
+			errs = append(errs, fmt.Errorf("%s includes itself", rule.Include))
+			continue
+		}
+		included, err := readIncludedRules(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("include %s: %w", rule.Include, err))
+			continue
+		}
+		included, err = expandIncludesFrom(included, filepath.Dir(path), append(stack, path))
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for _, r := range included {
+			if r.Origin == "" {
+				r.Origin = rule.Include
+			}
+			// Shown with the directive that pulled them in
+			r.Section = rule.Section
+			expanded = append(expanded, r)
+		}
+	}
+	return expanded, errors.Join(errs...)
+}
+
+// readIncludedRules reads the rules of an included file. Unlike the filter
+// file itself, an included file has to exist.
+func readIncludedRules(path string) ([]FilterRule, error) {
+	if err := validateFilterFilePath(path); err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	doc, err := parseFilterRules(file)
+	if err != nil {
+		return nil, err
+	}
+	return doc.rules, nil
+}
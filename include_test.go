@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestIncludeDirective(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "shared.txt"), []byte("- *.tmp\n- small/**\n"), 0644)
+	filterFile := filepath.Join(dir, "filter.txt")
+	os.WriteFile(filterFile, []byte("- big/0.bin\n#include shared.txt\n"), 0644)
+
+	doc, err := readFilterFile(filterFile)
+	if err != nil {
+		t.Fatalf("Failed to read filter file: %v", err)
+	}
+	if rules := doc.rules; len(rules) != 4 || rules[1].Include != "shared.txt" || rules[3].Origin != "shared.txt" || rules[3].Pattern != "small/**" {
+		t.Fatalf("Expected the included rules after the directive, got %+v", rules)
+	}
+	if _, ok := doc.lookup("small/**"); ok {
+		t.Errorf("Included rules should not be part of the edited rules")
+	}
+
+	model, big, small := newGuardTestModel()
+	model.filters = doc
+	model.reapplyFiltersToTree(model.root)
+	if small.Filter != FilterExclude || big.Children[0].Filter != FilterExclude {
+		t.Errorf("Expected both the own and the included rules to apply")
+	}
+
+	// Included rules are shown but can't be switched off
+	m := *model
+	for _, key := range []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune("p")},
+		{Type: tea.KeyDown},
+		{Type: tea.KeyDown},
+		{Type: tea.KeySpace},
+	} {
+		updated, _ := m.Update(key)
+		m = updated.(Model)
+	}
+	if view := m.View(); !strings.Contains(view, "- *.tmp  (from shared.txt, read-only)") {
+		t.Errorf("Expected the included rule with its origin:\n%s", view)
+	}
+	if !strings.Contains(m.statusMsg, "read-only") || m.savedRules()[2].Disabled {
+		t.Errorf("Expected the included rule to stay enabled, got %q", m.statusMsg)
+	}
+
+	// The directive is saved, the rules it pulls in stay in their file
+	m.setNodeFilter(big.Children[1], FilterExclude)
+	var buf bytes.Buffer
+	if err := m.filters.write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "- big/0.bin\n#include shared.txt\n- big/1.bin\n" {
+		t.Errorf("Unexpected saved file:\n%s", got)
+	}
+	if rules := m.savedRules(); len(rules) != 5 || rules[3].Origin != "shared.txt" {
+		t.Errorf("Expected the saved rules to keep the included ones, got %+v", rules)
+	}
+}
+
+func TestIncludeDirectiveErrors(t *testing.T) {
+	dir := t.TempDir()
+	filterFile := filepath.Join(dir, "filter.txt")
+
+	os.WriteFile(filterFile, []byte("#include missing.txt\n"), 0644)
+	if _, err := readFilterFile(filterFile); err == nil || !strings.Contains(err.Error(), "missing.txt") {
+		t.Errorf("Expected an error naming the missing file, got %v", err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("- a/**\n#include b.txt\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "b.txt"), []byte("- b/**\n#include a.txt\n"), 0644)
+	os.WriteFile(filterFile, []byte("#include a.txt\n"), 0644)
+	doc, err := readFilterFile(filterFile)
+	if err == nil || !strings.Contains(err.Error(), "a.txt includes itself") {
+		t.Errorf("Expected an include loop to be reported, got %v", err)
+	}
+	if rules := doc.rules; len(rules) != 5 || rules[3].Pattern != "b/**" || rules[3].Origin != "b.txt" {
+		t.Errorf("Expected the rules read before the loop, got %+v", rules)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// collectIssues returns the files and directories that can't be read or
+// listed, which rclone would fail on or skip, in tree order
+func (m *Model) collectIssues() []*FileNode {
+	var issues []*FileNode
+	for _, top := range m.topLevelNodes() {
+		for _, node := range collectNodes(top, nil) {
+			if node.Unreadable || node.ListErr != nil {
+				issues = append(issues, node)
+			}
+		}
+	}
+	return issues
+}
+
+// issueReason describes why rclone would have trouble with node
+func issueReason(node *FileNode) string {
+	if node.Unreadable {
+		return "permission denied"
+	}
+	if node.ListErr != nil {
+		return node.ListErr.Error()
+	}
+	return ""
+}
+
+// issueMarker is appended to the row of a node with a problem
+func issueMarker(node *FileNode) string {
+	if node.Unreadable {
+		return " ⚠ unreadable"
+	}
+	if node.ListErr != nil {
+		return " ⚠ listing failed"
+	}
+	return ""
+}
+
+// pendingIssues counts the problems rclone would run into, leaving out
+// excluded nodes it never reads
+func (m *Model) pendingIssues() int {
+	count := 0
+	for _, node := range m.issues {
+		if node.Filter != FilterExclude {
+			count++
+		}
+	}
+	return count
+}
+
+// updateIssuesPane handles keys while the errors pane is open
+func (m Model) updateIssuesPane(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var selected *FileNode
+	if m.issueCursor >= 0 && m.issueCursor < len(m.issues) {
+		selected = m.issues[m.issueCursor]
+	}
+
+	switch msg.String() {
+	case "up", "k":
+		if m.issueCursor > 0 {
+			m.issueCursor--
+		}
+	case "down", "j":
+		if m.issueCursor < len(m.issues)-1 {
+			m.issueCursor++
+		}
+	case "r":
+		m.closeModal(modalIssues)
+		return m, m.rescanDenied()
+	case "e":
+		if selected != nil && selected.Filter != FilterExclude {
+			m.activateRootFor(selected)
+			if blocked := m.toggleBlocked(m.previewChange(selected, FilterExclude)); blocked != "" {
+				m.statusMsg = blocked
+				break
+			}
+			m.setNodeFilter(selected, FilterExclude)
+			m.refreshView()
+		}
+	case "enter":
+		if selected != nil {
+			m.closeModal(modalIssues)
+			m.revealNode(selected)
+		}
+	case "W", "esc", "q":
+		m.closeModal(modalIssues)
+	case "ctrl+c":
+		m.cancel()
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m Model) renderIssues() string {
+	paneStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(currentTheme.Warning).
+		Padding(1, 2)
+
+	var lines []string
+	for i, node := range m.issues {
+		glyph, style := stateStyle(node.Filter)
+
+		top, rel := nodeLocation(node)
+		if rel == "" || m.multiRoot() {
+			rel = strings.TrimSuffix(top.Name+"/"+rel, "/")
+		}
+		line := fmt.Sprintf("%s %s  %s", style.Render(glyph), rel, issueReason(node))
+		if node.Filter == FilterExclude {
+			line += " (excluded, rclone skips it)"
+		}
+		if i == m.issueCursor {
+			line = lipgloss.NewStyle().Background(currentTheme.CursorBg).Foreground(currentTheme.CursorFg).Render(line)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "Everything in the tree can be read")
+	}
+
+	// Keep the selected entry in view when the list is taller than the screen
+	visibleHeight := m.height - 10
+	if visibleHeight < 5 {
+		visibleHeight = 20
+	}
+	start := 0
+	if m.issueCursor >= visibleHeight {
+		start = m.issueCursor - visibleHeight + 1
+	}
+	end := start + visibleHeight
+	if end > len(lines) {
+		end = len(lines)
+	}
+
+	var b strings.Builder
+	b.WriteString("Errors:\n\n")
+	b.WriteString(strings.Join(lines[start:end], "\n"))
+	if denied := len(m.deniedDirs()); denied > 0 && m.privilegedLister != "" {
+		fmt.Fprintf(&b, "\n\n%s left empty because permission was denied, r rescans them with %s", dirCount(denied), m.privilegedLister)
+	} else if denied > 0 {
+		fmt.Fprintf(&b, "\n\n%s left empty because permission was denied: run as a user who can\nread them, or set privileged-lister in the config, such as sudo -n rclone", dirCount(denied))
+	}
+	b.WriteString("\n\n↑/↓ select, e exclude, Enter show in tree, W or Esc close")
+
+	return m.placePane(paneStyle, b.String())
+}
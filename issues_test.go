@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalListerMarksUnreadable(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can read everything")
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "open.txt"), []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "secret.txt"), []byte("b"), 0o000); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "locked"), 0o000); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(filepath.Join(dir, "locked"), 0o755)
+
+	entries, err := localLister{}.List(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unreadable := make(map[string]bool)
+	for _, e := range entries {
+		unreadable[e.Name] = e.Unreadable
+	}
+	if unreadable["open.txt"] || !unreadable["secret.txt"] || !unreadable["locked"] {
+		t.Errorf("Expected only secret.txt and locked to be unreadable, got %v", unreadable)
+	}
+}
+
+func TestCollectIssues(t *testing.T) {
+	root := &FileNode{Name: "root", Path: "/root", IsDir: true, Expanded: true}
+	secret := &FileNode{Name: "secret", Path: "/root/secret", Parent: root, Unreadable: true}
+	broken := &FileNode{Name: "broken", Path: "/root/broken", IsDir: true, Parent: root, ListErr: fmt.Errorf("i/o timeout")}
+	root.Children = []*FileNode{
+		broken,
+		{Name: "ok", Path: "/root/ok", Parent: root},
+		secret,
+	}
+
+	model := newTestModel()
+	model.root = root
+	model.issues = model.collectIssues()
+	if len(model.issues) != 2 || model.issues[0] != broken || model.issues[1] != secret {
+		t.Fatalf("Expected broken and secret as issues, got %v", model.issues)
+	}
+	if issueReason(secret) != "permission denied" || issueReason(broken) != "i/o timeout" {
+		t.Errorf("Unexpected reasons %q and %q", issueReason(secret), issueReason(broken))
+	}
+
+	// Excluded entries are skipped by rclone, so they don't count
+	secret.Filter = FilterExclude
+	if n := model.pendingIssues(); n != 1 {
+		t.Errorf("Expected 1 pending issue after excluding one, got %d", n)
+	}
+}
+
+func TestDirScanPermissionError(t *testing.T) {
+	dir := &FileNode{Name: "locked", Path: "/root/locked", IsDir: true, Loading: true}
+	model := newTestModel()
+	model.root = dir
+
+	err := &fs.PathError{Op: "open", Path: dir.Path, Err: fs.ErrPermission}
+	model.applyDirScan(dirScannedMsg{parent: dir, err: err})
+	if !dir.Unreadable || dir.ListErr == nil {
+		t.Errorf("Expected a directory failing with a permission error to be unreadable")
+	}
+	if issueMarker(dir) != " ⚠ unreadable" {
+		t.Errorf("Unexpected marker %q", issueMarker(dir))
+	}
+}
@@ -0,0 +1,593 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// JobKind identifies what a background job does
+type JobKind int
+
+const (
+	JobScan JobKind = iota
+	JobRescan
+	JobRecompute
+	JobDryRun
+	JobDuplicates
+	JobEstimate
+	JobHash
+)
+
+// JobStatus is the lifecycle state of a background job
+type JobStatus int
+
+const (
+	JobQueued JobStatus = iota
+	JobRunning
+	JobDone
+	JobFailed
+	JobCancelled
+)
+
+// maxFinishedJobs is how many finished jobs the jobs pane keeps around
+const maxFinishedJobs = 20
+
+// jobResult is what the work of a job reports back when it is done
+type jobResult struct {
+	msg    tea.Msg // Handled by Update after the job has been marked finished
+	detail string  // Summary shown in the jobs pane and the notification
+	err    error
+}
+
+// jobFunc does the work of a job off the event loop. It may only use data
+// captured when the job was started and should stop early once ctx is done.
+type jobFunc func(ctx context.Context) jobResult
+
+// job is a long running operation shown in the jobs pane. Its fields are only
+// modified in Update; the work runs as a tea.Cmd and reports back with a
+// jobDoneMsg.
+type job struct {
+	id     int
+	kind   JobKind
+	title  string
+	node   *FileNode // Directory being scanned, for scan jobs
+	status JobStatus
+	detail string
+	err    error
+
+	started  time.Time
+	finished time.Time
+	cancel   context.CancelFunc
+
+	// start runs on the event loop when the job leaves the queue and captures
+	// everything the work needs from the model
+	start func(m *Model) jobFunc
+}
+
+// jobDoneMsg is sent when the work of a job returns
+type jobDoneMsg struct {
+	id     int
+	result jobResult
+}
+
+// filtersRecomputedMsg carries the filter states computed by a recompute job
+type filtersRecomputedMsg struct {
+	gen    int
+	states map[*FileNode]FilterState
+}
+
+// refreshTick schedules the next refreshMsg, which keeps the display moving
+// while jobs are active
+func refreshTick() tea.Cmd {
+	return tea.Tick(50*time.Millisecond, func(t time.Time) tea.Msg {
+		return refreshMsg{}
+	})
+}
+
+// queueJob adds a job to the queue without starting it
+func (m *Model) queueJob(j *job) {
+	m.nextJobID++
+	j.id = m.nextJobID
+	j.status = JobQueued
+	m.jobs = append(m.jobs, j)
+}
+
+// enqueue adds a job to the queue and starts it if nothing else is running
+func (m *Model) enqueue(j *job) tea.Cmd {
+	m.queueJob(j)
+	return m.dispatchJobs()
+}
+
+// dispatchJobs starts the oldest queued job when no job is running. Jobs run
+// one at a time, so a rescan never races a full scan over the same tree.
+func (m *Model) dispatchJobs() tea.Cmd {
+	var next *job
+	for _, j := range m.jobs {
+		if j.status == JobRunning {
+			return nil
+		}
+		if j.status == JobQueued && next == nil {
+			next = j
+		}
+	}
+	if next == nil {
+		return nil
+	}
+
+	parent := m.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	next.cancel = cancel
+	next.status = JobRunning
+	next.started = time.Now()
+
+	work := next.start(m)
+	id := next.id
+	return func() (msg tea.Msg) {
+		defer func() {
+			if r := recover(); r != nil {
+				// Report panics as a failed job instead of taking down the UI
+				msg = jobDoneMsg{id: id, result: jobResult{err: fmt.Errorf("panic: %v", r)}}
+			}
+		}()
+		return jobDoneMsg{id: id, result: work(ctx)}
+	}
+}
+
+// finishJob records the outcome of a job, notifies the user and starts the
+// next queued job. It reports whether the job's result message should still
+// be applied: results of cancelled or forgotten jobs are dropped, so a scan
+// cancelled halfway doesn't graft its partial tree onto the model.
+func (m *Model) finishJob(msg jobDoneMsg) (tea.Cmd, bool) {
+	j := m.jobByID(msg.id)
+	if j == nil {
+		return m.dispatchJobs(), false
+	}
+	if j.cancel != nil {
+		j.cancel()
+	}
+	j.finished = time.Now()
+
+	apply := j.status == JobRunning
+	if j.status == JobRunning {
+		j.detail = msg.result.detail
+		j.err = msg.result.err
+		j.status = JobDone
+		if j.err != nil {
+			j.status = JobFailed
+		}
+	}
+	if j.status == JobCancelled && j.node != nil {
+		// Directories the scan never reached would otherwise spin forever
+		clearLoading(j.node)
+	}
+
+	m.logScan(j)
+	if m.pendingToggle == nil {
+		m.statusMsg = j.notification()
+	}
+	m.pruneJobs()
+	return tea.Batch(m.notify.jobFinished(j), m.dispatchJobs()), apply
+}
+
+// cancelJob stops a queued or running job. A running job still reports back
+// with a jobDoneMsg once its work has wound down. Callers dispatch the next
+// job themselves.
+func (m *Model) cancelJob(j *job) {
+	switch j.status {
+	case JobQueued:
+		j.status = JobCancelled
+		j.finished = time.Now()
+	case JobRunning:
+		j.status = JobCancelled
+		j.cancel()
+	}
+}
+
+// cancelScans cancels every queued or running scan and rescan
+func (m *Model) cancelScans() {
+	for _, j := range m.jobs {
+		if j.kind == JobScan || j.kind == JobRescan || j.kind == JobEstimate {
+			m.cancelJob(j)
+		}
+	}
+}
+
+func (m *Model) jobByID(id int) *job {
+	for _, j := range m.jobs {
+		if j.id == id {
+			return j
+		}
+	}
+	return nil
+}
+
+// activeJobs returns the number of running and queued jobs
+func (m *Model) activeJobs() (running, queued int) {
+	for _, j := range m.jobs {
+		switch j.status {
+		case JobRunning:
+			running++
+		case JobQueued:
+			queued++
+		}
+	}
+	return running, queued
+}
+
+// scanning reports whether a full scan is queued or running, during which the
+// loading screen replaces the tree
+func (m *Model) scanning() bool {
+	for _, j := range m.jobs {
+		if (j.kind == JobScan || j.kind == JobEstimate) && (j.status == JobQueued || j.status == JobRunning) {
+			return true
+		}
+	}
+	return false
+}
+
+// pruneJobs drops the oldest finished jobs beyond maxFinishedJobs
+func (m *Model) pruneJobs() {
+	finished := 0
+	for _, j := range m.jobs {
+		if j.finishedState() {
+			finished++
+		}
+	}
+	if finished <= maxFinishedJobs {
+		return
+	}
+
+	kept := m.jobs[:0]
+	for _, j := range m.jobs {
+		if j.finishedState() && finished > maxFinishedJobs {
+			finished--
+			continue
+		}
+		kept = append(kept, j)
+	}
+	m.jobs = kept
+	m.clampJobCursor()
+}
+
+// clearFinishedJobs removes every finished job from the jobs pane
+func (m *Model) clearFinishedJobs() {
+	kept := m.jobs[:0]
+	for _, j := range m.jobs {
+		if !j.finishedState() {
+			kept = append(kept, j)
+		}
+	}
+	m.jobs = kept
+	m.clampJobCursor()
+}
+
+func (m *Model) clampJobCursor() {
+	if m.jobCursor >= len(m.jobs) {
+		m.jobCursor = len(m.jobs) - 1
+	}
+	if m.jobCursor < 0 {
+		m.jobCursor = 0
+	}
+}
+
+func (j *job) finishedState() bool {
+	return j.status == JobDone || j.status == JobFailed || j.status == JobCancelled
+}
+
+// elapsed returns how long the job has been running, or ran for
+func (j *job) elapsed() time.Duration {
+	if j.started.IsZero() {
+		return 0
+	}
+	end := j.finished
+	if end.IsZero() {
+		end = time.Now()
+	}
+	return end.Sub(j.started).Round(100 * time.Millisecond)
+}
+
+// statusText describes the state of the job for the jobs pane
+func (j *job) statusText() string {
+	switch j.status {
+	case JobQueued:
+		return "queued"
+	case JobRunning:
+		return fmt.Sprintf("running %s", j.elapsed())
+	case JobDone:
+		if j.detail != "" {
+			return fmt.Sprintf("done in %s, %s", j.elapsed(), j.detail)
+		}
+		return fmt.Sprintf("done in %s", j.elapsed())
+	case JobFailed:
+		return fmt.Sprintf("failed: %v", j.err)
+	}
+	return "cancelled"
+}
+
+// notification is the status line message shown when the job finishes
+func (j *job) notification() string {
+	switch j.status {
+	case JobDone:
+		text := fmt.Sprintf("✓ %s finished in %s", j.title, j.elapsed())
+		if j.detail != "" {
+			text += ": " + j.detail
+		}
+		return text
+	case JobFailed:
+		return fmt.Sprintf("✗ %s failed: %v", j.title, j.err)
+	}
+	return j.title + " cancelled"
+}
+
+// clearLoading marks node and everything below it as no longer loading
+func clearLoading(node *FileNode) {
+	node.Loading = false
+	for _, child := range node.Children {
+		clearLoading(child)
+	}
+}
+
+// recomputeJob re-evaluates the filter state of every node in the tree,
+// including those below collapsed directories. The states are computed in
+// the background from a copy of the rules and applied by Update, unless the
+// filters were edited in the meantime.
+func (m *Model) recomputeJob() *job {
+	return &job{
+		kind:  JobRecompute,
+		title: "Recompute filters",
+		start: func(m *Model) jobFunc {
+			type group struct {
+				eval  *Model
+				nodes []*FileNode
+			}
+
+			gen := m.filterGen
+			var groups []group
+			for _, top := range m.topLevelNodes() {
+				m.activateRootFor(top)
+				g := group{eval: &Model{
+					filters:   m.filters.clone(),
+					filesFrom: m.filesFrom.clone(),
+				}}
+				g.nodes = collectNodes(top, g.nodes)
+				groups = append(groups, g)
+			}
+
+			return func(ctx context.Context) jobResult {
+				states := make(map[*FileNode]FilterState)
+				for _, g := range groups {
+					for i, node := range g.nodes {
+						if i%1000 == 0 && ctx.Err() != nil {
+							return jobResult{err: ctx.Err()}
+						}
+						states[node] = g.eval.getEffectiveFilterWithMap(getFilterPath(node.Path))
+					}
+				}
+				return jobResult{
+					msg:    filtersRecomputedMsg{gen: gen, states: states},
+					detail: fmt.Sprintf("%s entries checked", formatCount(len(states))),
+				}
+			}
+		},
+	}
+}
+
+// applyRecomputedFilters stores the result of a recompute job in the tree
+func (m *Model) applyRecomputedFilters(msg filtersRecomputedMsg) {
+	if msg.gen != m.filterGen {
+		// The filters changed while the job ran, its states are stale
+		m.statusMsg = "Filters changed during recompute, results discarded"
+		return
+	}
+	for node, state := range msg.states {
+		node.Filter = state
+	}
+	m.refreshView()
+}
+
+// collectNodes appends node and all of its descendants to nodes
+func collectNodes(node *FileNode, nodes []*FileNode) []*FileNode {
+	nodes = append(nodes, node)
+	for _, child := range node.Children {
+		nodes = collectNodes(child, nodes)
+	}
+	return nodes
+}
+
+// rcloneSize is the JSON printed by "rclone size --json"
+type rcloneSize struct {
+	Count int64 `json:"count"`
+	Bytes int64 `json:"bytes"`
+}
+
+// dryRunJob asks rclone how many files the current rules (or file list) let
+// through, using "rclone size" with the unsaved state, and compares that with
+// the editor's own evaluation
+func (m *Model) dryRunJob() *job {
+	return &job{
+		kind:  JobDryRun,
+		title: "rclone dry-run",
+		start: func(m *Model) jobFunc {
+			type target struct {
+				path  string
+				flag  string
+				write func(w io.Writer) error
+			}
+
+			run := m.rcloneRun
+			if run == nil {
+				run = runRclone
+			}
+			var targets []target
+			editorFiles := 0
+			ages, now := m.ageLimits.flags(), time.Now()
+			for _, top := range m.topLevelNodes() {
+				m.activateRootFor(top)
+				t := target{path: top.Path, flag: "--filter-from"}
+				if list := m.filesFrom.clone(); list != nil {
+					t.flag = "--files-from"
+					t.write = list.write
+				} else {
+					filters, order := m.filters.clone(), m.ruleOrder
+					t.write = func(w io.Writer) error {
+						return order.write(w, filters)
+					}
+				}
+				targets = append(targets, t)
+				editorFiles += countSyncedFiles(top) - countAgeExcluded(top, m.ageLimits, now)
+			}
+
+			return func(ctx context.Context) jobResult {
+				var remotes []byte
+				for _, t := range targets {
+					if !isRemotePath(t.path) {
+						continue
+					}
+					if remotes == nil {
+						out, err := run(ctx, "listremotes")
+						if err != nil {
+							return jobResult{err: err}
+						}
+						remotes = out
+					}
+					if !listedRemote(remotes, t.path) {
+						return jobResult{err: fmt.Errorf("%s is not an rclone remote, rclone can't check it",
+							t.path[:strings.Index(t.path, ":")])}
+					}
+				}
+
+				var total rcloneSize
+				for _, t := range targets {
+					size, err := rcloneSizeWith(ctx, run, t.path, t.flag, t.write, ages...)
+					if err != nil {
+						return jobResult{err: err}
+					}
+					total.Count += size.Count
+					total.Bytes += size.Bytes
+				}
+				return jobResult{detail: fmt.Sprintf("rclone would sync %s files / %s, the editor shows %s",
+					formatCount(int(total.Count)), formatSize(total.Bytes), formatCount(editorFiles))}
+			}
+		},
+	}
+}
+
+// rcloneSizeWith runs "rclone size" on path, passing a temporary file filled
+// by write with flag (--filter-from or --files-from) and any other flags
+func rcloneSizeWith(ctx context.Context, run func(ctx context.Context, args ...string) ([]byte, error),
+	path, flag string, write func(w io.Writer) error, flags ...string) (rcloneSize, error) {
+	var size rcloneSize
+
+	file, err := os.CreateTemp("", "rclone-filter-editor-*.txt")
+	if err != nil {
+		return size, err
+	}
+	defer os.Remove(file.Name())
+
+	if err := write(file); err != nil {
+		file.Close()
+		return size, err
+	}
+	if err := file.Close(); err != nil {
+		return size, err
+	}
+
+	args := append([]string{"size", "--json", flag, file.Name()}, flags...)
+	out, err := run(ctx, append(args, path)...)
+	if err != nil {
+		return size, err
+	}
+	if err := json.Unmarshal(out, &size); err != nil {
+		return size, fmt.Errorf("failed to parse rclone size output: %w", err)
+	}
+	return size, nil
+}
+
+// countSyncedFiles counts the files below node that the editor doesn't show
+// as excluded
+func countSyncedFiles(node *FileNode) int {
+	count := 0
+	for _, child := range node.Children {
+		if child.IsDir {
+			count += countSyncedFiles(child)
+		} else if child.Filter != FilterExclude {
+			count++
+		}
+	}
+	return count
+}
+
+// updateJobsPane handles keys while the jobs pane is open
+func (m Model) updateJobsPane(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		if m.jobCursor > 0 {
+			m.jobCursor--
+		}
+	case "down", "j":
+		if m.jobCursor < len(m.jobs)-1 {
+			m.jobCursor++
+		}
+	case "x":
+		if m.jobCursor >= 0 && m.jobCursor < len(m.jobs) {
+			m.cancelJob(m.jobs[m.jobCursor])
+			return m, m.dispatchJobs()
+		}
+	case "c":
+		m.clearFinishedJobs()
+	case "J", "esc", "q":
+		m.closeModal(modalJobs)
+	case "ctrl+c":
+		m.cancel()
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m Model) renderJobs() string {
+	jobsStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(currentTheme.Border).
+		Padding(1, 2)
+
+	var b strings.Builder
+	b.WriteString("Background Jobs:\n\n")
+	if len(m.jobs) == 0 {
+		b.WriteString("  No jobs yet\n")
+	}
+	for i, j := range m.jobs {
+		var icon string
+		switch j.status {
+		case JobQueued:
+			icon = "…"
+		case JobRunning:
+			icon = "⟳"
+		case JobDone:
+			icon = "✓"
+		case JobFailed:
+			icon = "✗"
+		case JobCancelled:
+			icon = "-"
+		}
+		line := fmt.Sprintf("%s #%d %-24s %s", icon, j.id, j.title, j.statusText())
+		if i == m.jobCursor {
+			line = lipgloss.NewStyle().Background(currentTheme.CursorBg).Foreground(currentTheme.CursorFg).Render(line)
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	b.WriteString("\n↑/↓ select, x cancel, c clear finished, J or Esc close")
+
+	return m.placePane(jobsStyle, b.String())
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+// testJob returns a job whose work finishes immediately with result
+func testJob(title string, result jobResult) *job {
+	return &job{
+		kind:  JobDryRun,
+		title: title,
+		start: func(m *Model) jobFunc {
+			return func(ctx context.Context) jobResult {
+				return result
+			}
+		},
+	}
+}
+
+func TestJobsRunOneAtATime(t *testing.T) {
+	model := newTestModel()
+
+	first := model.enqueue(testJob("first", jobResult{detail: "all good"}))
+	second := model.enqueue(testJob("second", jobResult{err: errors.New("boom")}))
+	if first == nil || second != nil {
+		t.Fatalf("Only the first job should start while another is running")
+	}
+	if model.jobs[0].status != JobRunning || model.jobs[1].status != JobQueued {
+		t.Fatalf("Unexpected job states %v, %v", model.jobs[0].status, model.jobs[1].status)
+	}
+
+	updated, next := model.Update(first())
+	result := updated.(Model)
+	if result.jobs[0].status != JobDone || result.jobs[1].status != JobRunning {
+		t.Errorf("Finishing a job should start the next one, got %v, %v", result.jobs[0].status, result.jobs[1].status)
+	}
+	if !strings.Contains(result.statusMsg, "first finished") || !strings.Contains(result.statusMsg, "all good") {
+		t.Errorf("Completion should be announced, got %q", result.statusMsg)
+	}
+	if next == nil {
+		t.Fatalf("The next job's work should be returned as a command")
+	}
+
+	updated, _ = result.Update(next())
+	result = updated.(Model)
+	if result.jobs[1].status != JobFailed || !strings.Contains(result.statusMsg, "boom") {
+		t.Errorf("Failed job should be reported, got %v and %q", result.jobs[1].status, result.statusMsg)
+	}
+	if running, queued := result.activeJobs(); running+queued != 0 {
+		t.Errorf("No jobs should be active, got %d running and %d queued", running, queued)
+	}
+}
+
+func TestCancelJob(t *testing.T) {
+	model := newTestModel()
+
+	started := false
+	blocking := &job{
+		kind:  JobScan,
+		title: "scan",
+		node:  &FileNode{IsDir: true, Loading: true, Children: []*FileNode{{IsDir: true, Loading: true}}},
+		start: func(m *Model) jobFunc {
+			return func(ctx context.Context) jobResult {
+				started = true
+				<-ctx.Done()
+				return jobResult{err: ctx.Err(), msg: treeReadyMsg{root: model.root, err: ctx.Err()}}
+			}
+		},
+	}
+	run := model.enqueue(blocking)
+	model.enqueue(testJob("queued", jobResult{}))
+	if !model.scanning() {
+		t.Errorf("A running scan job should show the loading screen")
+	}
+
+	// Cancelling a queued job never runs it
+	model.cancelJob(model.jobs[1])
+	if model.jobs[1].status != JobCancelled {
+		t.Errorf("Queued job should be cancelled, got %v", model.jobs[1].status)
+	}
+
+	model.cancelJob(blocking)
+	if model.scanning() {
+		t.Errorf("A cancelled scan should release the loading screen")
+	}
+	if next := model.dispatchJobs(); next != nil {
+		t.Errorf("Cancelled jobs should not be started")
+	}
+
+	updated, _ := model.Update(run())
+	result := updated.(Model)
+	if !started || blocking.status != JobCancelled {
+		t.Errorf("Cancelled job should stay cancelled after its work returns, got %v", blocking.status)
+	}
+	if blocking.node.Loading || blocking.node.Children[0].Loading {
+		t.Errorf("Directories of a cancelled scan should stop loading")
+	}
+	if !strings.Contains(result.statusMsg, "cancelled") {
+		t.Errorf("Cancellation should be announced, got %q", result.statusMsg)
+	}
+	if result.scanErr != nil {
+		t.Errorf("The result of a cancelled job should be dropped, got scan error %v", result.scanErr)
+	}
+
+	result.clearFinishedJobs()
+	if len(result.jobs) != 0 {
+		t.Errorf("Clearing should remove finished jobs, %d left", len(result.jobs))
+	}
+}
+
+func TestPruneFinishedJobs(t *testing.T) {
+	model := newTestModel()
+	for i := 0; i < maxFinishedJobs+5; i++ {
+		run := model.enqueue(testJob("job", jobResult{}))
+		updated, _ := model.Update(run())
+		*model = updated.(Model)
+	}
+	if len(model.jobs) != maxFinishedJobs {
+		t.Errorf("Expected %d finished jobs to be kept, got %d", maxFinishedJobs, len(model.jobs))
+	}
+	if model.jobs[0].id != 6 {
+		t.Errorf("The oldest jobs should be dropped first, oldest kept is #%d", model.jobs[0].id)
+	}
+}
+
+func TestRecomputeJob(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/root"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model := newTestModel()
+	hidden := &FileNode{Name: "hidden.txt", Path: "/root/dir/hidden.txt"}
+	dir := &FileNode{Name: "dir", Path: "/root/dir", IsDir: true, Children: []*FileNode{hidden}}
+	hidden.Parent = dir
+	model.root = &FileNode{Name: "root", Path: "/root", IsDir: true, Expanded: true, Children: []*FileNode{dir}}
+	dir.Parent = model.root
+	model.filters.set("dir/**", FilterExclude)
+
+	run := model.enqueue(model.recomputeJob())
+	updated, _ := model.Update(run())
+	result := updated.(Model)
+	if dir.Filter != FilterExclude || hidden.Filter != FilterExclude {
+		t.Errorf("Collapsed nodes should be recomputed, got %v and %v", dir.Filter, hidden.Filter)
+	}
+
+	// Results computed before an edit are thrown away
+	run = result.enqueue(result.recomputeJob())
+	msg := run()
+	result.toggleNode(dir)
+	updated, _ = result.Update(msg)
+	result = updated.(Model)
+	if hidden.Filter != FilterNone {
+		t.Errorf("Stale recompute should not override the edit, got %v", hidden.Filter)
+	}
+	if !strings.Contains(result.statusMsg, "discarded") {
+		t.Errorf("Discarding stale results should be reported, got %q", result.statusMsg)
+	}
+}
+
+func TestDryRunJob(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/root"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model := newTestModel()
+	model.root = &FileNode{Name: "root", Path: "/root", IsDir: true, Children: []*FileNode{
+		{Name: "a.txt", Path: "/root/a.txt", Filter: FilterInclude},
+		{Name: "b.txt", Path: "/root/b.txt", Filter: FilterExclude},
+	}}
+	model.filters.set("b.txt", FilterExclude)
+
+	var args []string
+	var rules string
+	model.rcloneRun = func(ctx context.Context, a ...string) ([]byte, error) {
+		args = a
+		data, err := os.ReadFile(a[3])
+		rules = string(data)
+		return []byte(`{"count":1,"bytes":2048,"sizeless":0}`), err
+	}
+
+	run := model.enqueue(model.dryRunJob())
+	updated, _ := model.Update(run())
+	result := updated.(Model)
+
+	if len(args) != 5 || args[0] != "size" || args[4] != "/root" {
+		t.Errorf("Unexpected rclone arguments %v", args)
+	}
+	if rules != "- b.txt\n" {
+		t.Errorf("Unsaved rules should be passed to rclone, got %q", rules)
+	}
+	if !strings.Contains(result.jobs[0].detail, "rclone would sync 1 files / 2.0 KiB, the editor shows 1") {
+		t.Errorf("Unexpected dry-run summary %q", result.jobs[0].detail)
+	}
+}
+
+func TestDryRunJobChecksRemotes(t *testing.T) {
+	for _, tt := range []struct {
+		root  string
+		sized bool
+	}{
+		{"gdrive:Photos", true},
+		// An --sftp session looks like a remote rclone doesn't know
+		{"me@nas:/photos", false},
+	} {
+		model := newTestModel()
+		model.root = &FileNode{Name: tt.root, Path: tt.root, IsDir: true}
+
+		sized := false
+		model.rcloneRun = func(ctx context.Context, a ...string) ([]byte, error) {
+			if a[0] == "listremotes" {
+				return []byte("gdrive:\nnas:\n"), nil
+			}
+			sized = true
+			return []byte(`{"count":0,"bytes":0}`), nil
+		}
+
+		run := model.enqueue(model.dryRunJob())
+		updated, _ := model.Update(run())
+		result := updated.(Model)
+		if sized != tt.sized {
+			t.Errorf("%s: rclone size run %v, want %v", tt.root, sized, tt.sized)
+		}
+		if failed := result.jobs[0].status == JobFailed; failed == tt.sized {
+			t.Errorf("%s: unexpected job status %v (%v)", tt.root, result.jobs[0].status, result.jobs[0].err)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// resolveLink returns where the directory link name in dir leads, and
+// whether it leads back to a directory it sits in. Following such a link
+// would list the same directories over and over, so it is shown but not
+// scanned. The link keeps its own path in the tree, and the patterns
+// written for it, as rclone lists it there.
+func resolveLink(dir, name string) (target string, loop bool) {
+	target, err := filepath.EvalSymlinks(filepath.Join(dir, name))
+	if err != nil {
+		// A dangling link fails when listed, which shows why
+		return "", false
+	}
+	for ancestor := dir; ; ancestor = filepath.Dir(ancestor) {
+		if real, err := filepath.EvalSymlinks(ancestor); err == nil && withinDir(real, target) {
+			return target, true
+		}
+		if filepath.Dir(ancestor) == ancestor {
+			return target, false
+		}
+	}
+}
+
+// withinDir reports whether p is dir or below it
+func withinDir(p, dir string) bool {
+	return p == dir || strings.HasPrefix(p, strings.TrimSuffix(dir, string(os.PathSeparator))+string(os.PathSeparator))
+}
+
+// linkLabel is appended to the row of a directory link, saying where it
+// leads
+func linkLabel(node *FileNode) string {
+	switch {
+	case node.LinkLoop:
+		return " → " + node.Link + ", loops back, not scanned"
+	case node.Link != "":
+		return " → " + node.Link
+	}
+	return ""
+}
@@ -0,0 +1,12 @@
+//go:build !windows
+
+package main
+
+import "io/fs"
+
+// dirLink reports whether entry stands in for a directory elsewhere. Only
+// junctions and reparse points on Windows are listed as directories, as
+// rclone skips symlinks elsewhere unless told to follow them.
+func dirLink(entry fs.DirEntry, info fs.FileInfo) bool {
+	return false
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveLink(t *testing.T) {
+	rootDir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(filepath.Join(rootDir, "a"), 0755)
+	os.MkdirAll(filepath.Join(rootDir, "b"), 0755)
+	os.Symlink(rootDir, filepath.Join(rootDir, "a", "up"))
+	os.Symlink(filepath.Join(rootDir, "b"), filepath.Join(rootDir, "a", "across"))
+	os.Symlink(filepath.Join(rootDir, "a"), filepath.Join(rootDir, "b", "back"))
+	os.Symlink(filepath.Join(rootDir, "gone"), filepath.Join(rootDir, "a", "dangling"))
+
+	tests := []struct {
+		dir, name string
+		target    string
+		loop      bool
+	}{
+		{"a", "up", rootDir, true},
+		{"a", "across", filepath.Join(rootDir, "b"), false},
+		{"b", "back", filepath.Join(rootDir, "a"), false},
+		// Reached through a/across, b/back leads back to a
+		{filepath.Join("a", "across"), "back", filepath.Join(rootDir, "a"), true},
+		{"a", "dangling", "", false},
+	}
+	for _, tt := range tests {
+		target, loop := resolveLink(filepath.Join(rootDir, tt.dir), tt.name)
+		if target != tt.target || loop != tt.loop {
+			t.Errorf("resolveLink(%s, %s) = %q, %v; want %q, %v", tt.dir, tt.name, target, loop, tt.target, tt.loop)
+		}
+	}
+}
+
+func TestScanStopsAtLinkLoop(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/data"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, apply := newScanTestModel("/data")
+	defer model.cancel()
+	model.lister = mapLister{
+		"/data": {
+			{Name: "Loop", IsDir: true, Link: `C:\data`, LinkLoop: true},
+			{Name: "Shared", IsDir: true, Link: `D:\shared`},
+		},
+		"/data/Shared": {{Name: "f.txt", Size: 10}},
+	}
+
+	if err := model.newScanner().scan(model.root); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	result := apply(*model)
+	loop, shared := result.root.Children[0], result.root.Children[1]
+	if loop.Loading || len(loop.Children) != 0 {
+		t.Errorf("Expected the loop left unscanned, got %+v", loop)
+	}
+	if len(shared.Children) != 1 || shared.Children[0].Path != "/data/Shared/f.txt" {
+		t.Fatalf("Expected the link scanned under its own path, got %+v", shared.Children)
+	}
+
+	view := result.View()
+	for _, expected := range []string{`Loop (0 B, 0 files) → C:\data, loops back, not scanned`, `Shared (10 B, 1 files) → D:\shared`} {
+		if !strings.Contains(view, expected) {
+			t.Errorf("Expected %q in:\n%s", expected, view)
+		}
+	}
+}
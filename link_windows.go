@@ -0,0 +1,25 @@
+//go:build windows
+
+package main
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// dirLink reports whether entry is a junction, a directory symlink or
+// another reparse point standing in for a directory elsewhere. Go lists
+// these as neither directories nor files, while reparse points that are
+// directories themselves, such as OneDrive folders, are listed as
+// directories.
+func dirLink(entry fs.DirEntry, info fs.FileInfo) bool {
+	if entry.IsDir() {
+		return false
+	}
+	attrs, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return false
+	}
+	const link = syscall.FILE_ATTRIBUTE_REPARSE_POINT | syscall.FILE_ATTRIBUTE_DIRECTORY
+	return attrs.FileAttributes&link == link
+}
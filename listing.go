@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// listingLister serves directory listings from a dump captured with
+// "rclone lsjson -R", so filters can be edited offline for a tree that is
+// only reachable from another machine
+type listingLister struct {
+	root string
+	dirs map[string][]dirEntry // Keyed by slash separated path relative to root, "" for root itself
+}
+
+// readListing reads an "rclone lsjson -R" dump of the tree at root
+func readListing(file, root string) (*listingLister, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var items []lsjsonItem
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("not an rclone lsjson listing: %w", err)
+	}
+	return newListingLister(root, items), nil
+}
+
+// newListingLister groups the items of a recursive listing by directory.
+// Directories missing from the dump, as with --files-only, are made up from
+// the paths of the files inside them.
+func newListingLister(root string, items []lsjsonItem) *listingLister {
+	l := &listingLister{root: root, dirs: map[string][]dirEntry{"": nil}}
+
+	var addDir func(dir string)
+	addDir = func(dir string) {
+		if _, ok := l.dirs[dir]; ok {
+			return
+		}
+		l.dirs[dir] = nil
+		parent := listingParent(dir)
+		addDir(parent)
+		l.dirs[parent] = append(l.dirs[parent], dirEntry{Name: path.Base(dir), IsDir: true})
+	}
+
+	for _, item := range items {
+		p := strings.Trim(item.Path, "/")
+		if p == "" {
+			continue
+		}
+		if item.IsDir {
+			addDir(p)
+			setDirModTime(l.dirs[listingParent(p)], path.Base(p), item)
+			continue
+		}
+		parent := listingParent(p)
+		addDir(parent)
+		l.dirs[parent] = append(l.dirs[parent], dirEntry{
+			Name:    path.Base(p),
+			Size:    item.Size,
+			ModTime: item.ModTime,
+			Hash:    preferredHash(item.Hashes),
+		})
+	}
+	return l
+}
+
+// listingParent returns the directory containing p, "" for the root
+func listingParent(p string) string {
+	parent := path.Dir(p)
+	if parent == "." {
+		return ""
+	}
+	return parent
+}
+
+// setDirModTime copies the modification time of a listed directory to its
+// entry in the parent
+func setDirModTime(entries []dirEntry, name string, item lsjsonItem) {
+	for i := range entries {
+		if entries[i].Name == name && entries[i].IsDir {
+			entries[i].ModTime = item.ModTime
+		}
+	}
+}
+
+func (l *listingLister) List(ctx context.Context, dir string) ([]dirEntry, error) {
+	var rel string
+	if isRemotePath(l.root) {
+		rel = strings.TrimPrefix(remoteRelPath(l.root, dir), "/")
+	} else {
+		r, err := filepath.Rel(l.root, dir)
+		if err != nil {
+			return nil, err
+		}
+		if r != "." {
+			rel = filepath.ToSlash(r)
+		}
+	}
+
+	entries, ok := l.dirs[rel]
+	if !ok {
+		return nil, fmt.Errorf("%s: not in the imported listing: %w", dir, os.ErrNotExist)
+	}
+	return entries, nil
+}
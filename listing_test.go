@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testListing = `[
+{"Path":"Photos","Name":"Photos","Size":-1,"MimeType":"inode/directory","ModTime":"2024-05-01T10:00:00Z","IsDir":true},
+{"Path":"Photos/a.jpg","Name":"a.jpg","Size":1200,"MimeType":"image/jpeg","ModTime":"2024-05-01T10:00:00Z","IsDir":false},
+{"Path":"Photos/2023/b.jpg","Name":"b.jpg","Size":800,"MimeType":"image/jpeg","ModTime":"2023-07-01T10:00:00Z","IsDir":false},
+{"Path":"notes.txt","Name":"notes.txt","Size":10,"MimeType":"text/plain","ModTime":"2024-01-01T10:00:00Z","IsDir":false}
+]`
+
+func TestListingLister(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "listing.json")
+	if err := os.WriteFile(file, []byte(testListing), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, root := range []string{"nas:backup", "/srv/data"} {
+		l, err := readListing(file, root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx := context.Background()
+
+		entries, err := l.List(ctx, root)
+		if err != nil || len(entries) != 2 {
+			t.Fatalf("Expected 2 entries at the root of %s, got %v (%v)", root, entries, err)
+		}
+		if !entries[0].IsDir || entries[0].Name != "Photos" || entries[0].ModTime.IsZero() {
+			t.Errorf("Expected Photos with its modification time first, got %+v", entries[0])
+		}
+
+		photos := joinChildPath(root, "Photos")
+		entries, err = l.List(ctx, photos)
+		if err != nil || len(entries) != 2 {
+			t.Fatalf("Expected a.jpg and 2023 in %s, got %v (%v)", photos, entries, err)
+		}
+		// 2023 isn't in the dump, it comes from the path of b.jpg
+		if entries[0].Name != "a.jpg" || entries[0].Size != 1200 || entries[1].Name != "2023" || !entries[1].IsDir {
+			t.Errorf("Unexpected entries in %s: %+v", photos, entries)
+		}
+
+		if _, err := l.List(ctx, joinChildPath(root, "missing")); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Expected a directory missing from the listing to not exist, got %v", err)
+		}
+	}
+}
+
+func TestReadListingRejectsOtherJSON(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "listing.json")
+	if err := os.WriteFile(file, []byte(`{"count": 3, "bytes": 100}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readListing(file, "/srv/data"); err == nil {
+		t.Errorf("Expected the output of rclone size to be rejected")
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+)
+
+// lockSuffix names the lock file next to an edited file
+const lockSuffix = ".lock"
+
+// Answers when a file is locked by another session
+const (
+	lockReadOnly = "read-only"
+	lockSteal    = "steal"
+	lockAbort    = "abort"
+)
+
+// fileLock is the lock a session holds on a file it edits, so that two
+// sessions, e.g. over SSH, don't overwrite each other's rules
+type fileLock struct {
+	path  string // Of the lock file
+	owner string // What this session wrote into it
+}
+
+// lockOwner describes this session in its lock files
+func lockOwner() string {
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s@%s pid %d since %s", name, host, os.Getpid(), time.Now().Format(time.RFC3339))
+}
+
+// staleLock reports whether the lock was left behind by a session of this
+// machine that isn't running anymore
+func staleLock(holder string) bool {
+	var who, since string
+	var pid int
+	if _, err := fmt.Sscanf(holder, "%s pid %d since %s", &who, &pid, &since); err != nil {
+		return false
+	}
+	host, _ := os.Hostname()
+	_, lockHost, _ := strings.Cut(who, "@")
+	return lockHost == host && !processAlive(pid)
+}
+
+// acquireLock locks file for this session. If another session holds the lock
+// it is returned unchanged, together with a description of that session, so
+// the caller can decide whether to steal it. Stale locks are taken over.
+func acquireLock(file, owner string) (*fileLock, string, error) {
+	lock := &fileLock{path: file + lockSuffix, owner: owner}
+	f, err := os.OpenFile(lock.path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err == nil {
+		_, err = fmt.Fprintln(f, owner)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		return lock, "", err
+	}
+	if !errors.Is(err, fs.ErrExist) {
+		return nil, "", err
+	}
+	holder, err := lock.holder()
+	if err != nil {
+		return nil, "", err
+	}
+	if staleLock(holder) {
+		return lock, "", lock.steal()
+	}
+	return lock, holder, nil
+}
+
+// holder returns who holds the lock now
+func (l *fileLock) holder() (string, error) {
+	data, err := os.ReadFile(l.path)
+	return strings.TrimSpace(string(data)), err
+}
+
+// steal takes the lock over from the session holding it
+func (l *fileLock) steal() error {
+	return writeFileAtomic(l.path, func(w io.Writer) error {
+		_, err := fmt.Fprintln(w, l.owner)
+		return err
+	})
+}
+
+// check returns an error if another session took the lock over
+func (l *fileLock) check() error {
+	holder, err := l.holder()
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if holder != l.owner {
+		return fmt.Errorf("%s took over the lock %s, not overwriting their edits", holder, l.path)
+	}
+	return nil
+}
+
+// release removes the lock, unless another session took it over
+func (l *fileLock) release() {
+	if holder, err := l.holder(); err == nil && holder == l.owner {
+		os.Remove(l.path)
+	}
+}
+
+// lockedFiles returns the files the session saves to
+func (m *Model) lockedFiles() []string {
+	if m.filesFrom != nil {
+		if m.filesFrom.path == stdioFilterFile {
+			return nil
+		}
+		return []string{m.filesFrom.path}
+	}
+	var files []string
+	for _, r := range m.roots {
+		if r.filterFile != "" && r.filterFile != stdioFilterFile {
+			files = append(files, r.filterFile)
+		}
+	}
+	return files
+}
+
+// askLock asks what to do about a file locked by another session
+func askLock(file, holder string, in io.Reader, out io.Writer) string {
+	fmt.Fprintf(out, "%s is being edited by %s.\nOpen it [r]ead-only, [s]teal the lock or [a]bort? [r/s/A] ", file, holder)
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "r", "read-only":
+		return lockReadOnly
+	case "s", "steal":
+		return lockSteal
+	}
+	return lockAbort
+}
+
+// lockFiles locks the files the session saves to. When one is locked by
+// another session, ask decides what to do; without it, or when aborting, the
+// locks taken so far are released and an error is returned.
+func (m *Model) lockFiles(ask func(file, holder string) string) (err error) {
+	defer func() {
+		if err != nil {
+			m.releaseLocks()
+		}
+	}()
+	owner := lockOwner()
+	for _, file := range m.lockedFiles() {
+		lock, holder, err := acquireLock(file, owner)
+		if err != nil {
+			return fmt.Errorf("failed to lock %s: %w", file, err)
+		}
+		if holder != "" {
+			answer := lockAbort
+			if ask != nil {
+				answer = ask(file, holder)
+			}
+			switch answer {
+			case lockReadOnly:
+				m.readOnly = true
+				continue
+			case lockSteal:
+				if err := lock.steal(); err != nil {
+					return fmt.Errorf("failed to steal the lock of %s: %w", file, err)
+				}
+			default:
+				return fmt.Errorf("%s is being edited by %s", file, holder)
+			}
+		}
+		m.locks = append(m.locks, lock)
+	}
+	return nil
+}
+
+// releaseLocks removes the locks of the session
+func (m *Model) releaseLocks() {
+	for _, lock := range m.locks {
+		lock.release()
+	}
+	m.locks = nil
+}
+
+// checkLocks returns an error if the session may not save: it was opened
+// read-only, or another session stole one of its locks
+func (m *Model) checkLocks() error {
+	if m.readOnly {
+		return errors.New("opened read-only while another session edits the file")
+	}
+	for _, lock := range m.locks {
+		if err := lock.check(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
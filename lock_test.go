@@ -0,0 +1,127 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func newLockTestModel(t *testing.T) (*Model, string) {
+	file := filepath.Join(t.TempDir(), "filter.txt")
+	m := newTestModel()
+	m.filterFile = file
+	m.roots = []*sessionRoot{{path: "/test", filterFile: file}}
+	return m, file
+}
+
+func TestLockFiles(t *testing.T) {
+	m, file := newLockTestModel(t)
+	if err := m.lockFiles(nil); err != nil {
+		t.Fatalf("Locking a free file failed: %v", err)
+	}
+	data, err := os.ReadFile(file + lockSuffix)
+	if err != nil || !strings.Contains(string(data), "pid") {
+		t.Fatalf("Expected a lock file naming the session, got %q (%v)", data, err)
+	}
+	if err := m.saveAll(); err != nil {
+		t.Errorf("Saving with the lock held failed: %v", err)
+	}
+
+	other, _ := newLockTestModel(t)
+	other.roots[0].filterFile = file
+	if err := other.lockFiles(nil); err == nil || !strings.Contains(err.Error(), "is being edited by") {
+		t.Errorf("Expected a locked file to abort without asking, got %v", err)
+	}
+
+	m.releaseLocks()
+	if _, err := os.Stat(file + lockSuffix); !os.IsNotExist(err) {
+		t.Errorf("Expected the lock to be removed on exit")
+	}
+}
+
+func TestLockFilesReadOnly(t *testing.T) {
+	m, file := newLockTestModel(t)
+	os.WriteFile(file+lockSuffix, []byte("alice@elsewhere pid 1 since 2024-05-01T12:00:00Z\n"), 0644)
+
+	asked := ""
+	err := m.lockFiles(func(f, holder string) string {
+		asked = holder
+		return lockReadOnly
+	})
+	if err != nil || !m.readOnly || len(m.locks) != 0 {
+		t.Fatalf("Expected a read-only session without locks, got %v, %v, %v", err, m.readOnly, m.locks)
+	}
+	if !strings.HasPrefix(asked, "alice@elsewhere") {
+		t.Errorf("Expected to be asked about alice's session, got %q", asked)
+	}
+	if err := m.saveAll(); err == nil {
+		t.Errorf("Expected a read-only session to refuse saving")
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("The filter file shouldn't have been written")
+	}
+	m.releaseLocks()
+	if _, err := os.Stat(file + lockSuffix); err != nil {
+		t.Errorf("Alice's lock should have stayed")
+	}
+}
+
+func TestLockFilesSteal(t *testing.T) {
+	m, file := newLockTestModel(t)
+	os.WriteFile(file+lockSuffix, []byte("alice@elsewhere pid 1 since 2024-05-01T12:00:00Z\n"), 0644)
+	if err := m.lockFiles(func(string, string) string { return lockSteal }); err != nil {
+		t.Fatalf("Stealing the lock failed: %v", err)
+	}
+	if err := m.saveAll(); err != nil {
+		t.Errorf("Saving with the stolen lock failed: %v", err)
+	}
+
+	// Alice steals it back, so her edits must not be overwritten
+	os.WriteFile(file+lockSuffix, []byte("alice@elsewhere pid 1 since 2024-05-01T13:00:00Z\n"), 0644)
+	if err := m.saveAll(); err == nil || !strings.Contains(err.Error(), "took over the lock") {
+		t.Errorf("Expected saving to be refused once the lock was taken, got %v", err)
+	}
+	m.releaseLocks()
+	if _, err := os.Stat(file + lockSuffix); err != nil {
+		t.Errorf("Alice's lock shouldn't be removed")
+	}
+}
+
+func TestStaleLock(t *testing.T) {
+	host, _ := os.Hostname()
+	if staleLock("bob@" + host + " pid " + strconv.Itoa(os.Getpid()) + " since 2024-05-01T12:00:00Z") {
+		t.Errorf("The lock of a running process isn't stale")
+	}
+	if staleLock("bob@elsewhere pid 999999999 since 2024-05-01T12:00:00Z") {
+		t.Errorf("Processes of other machines can't be checked")
+	}
+	if staleLock("garbage") {
+		t.Errorf("An unreadable lock isn't stale")
+	}
+}
+
+func TestAskLock(t *testing.T) {
+	var out strings.Builder
+	for input, want := range map[string]string{"r\n": lockReadOnly, "S\n": lockSteal, "\n": lockAbort, "x\n": lockAbort} {
+		if got := askLock("filter.txt", "alice@nas", strings.NewReader(input), &out); got != want {
+			t.Errorf("askLock(%q) = %q, want %q", input, got, want)
+		}
+	}
+	if !strings.Contains(out.String(), "filter.txt is being edited by alice@nas") {
+		t.Errorf("Unexpected prompt %q", out.String())
+	}
+}
+
+func TestLockFilesTakesOverStaleLock(t *testing.T) {
+	m, file := newLockTestModel(t)
+	host, _ := os.Hostname()
+	os.WriteFile(file+lockSuffix, []byte("bob@"+host+" pid 999999999 since 2024-05-01T12:00:00Z\n"), 0644)
+	if err := m.lockFiles(nil); err != nil || len(m.locks) != 1 {
+		t.Fatalf("Expected the lock of a gone process to be taken over, got %v", err)
+	}
+	if err := m.locks[0].check(); err != nil {
+		t.Errorf("Expected the lock to be ours: %v", err)
+	}
+}
@@ -5,17 +5,18 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
-	"regexp"
+	"runtime"
 	"sort"
 	"strings"
-	"sync"
-	"sync/atomic"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+
+	"rclone-filter-editor/rclonefilter"
 )
 
 type FilterState int
@@ -26,6 +27,16 @@ const (
 	FilterExclude
 )
 
+// Exit codes reported to the calling shell so wrapper scripts can branch on
+// the outcome of an editing session
+const (
+	exitSaved       = 0
+	exitNotSaved    = 1
+	exitScanError   = 2
+	exitFilterError = 3
+	exitCrash       = 4
+)
+
 type SortMode int
 
 const (
@@ -35,26 +46,48 @@ const (
 	SortByLastModified
 )
 
+// ViewMode limits the tree to nodes with a particular effective filter state
+type ViewMode int
+
+const (
+	ViewAll ViewMode = iota
+	ViewIncluded
+	ViewExcluded
+	ViewChanged // Entries whose state changed since the rules were loaded
+)
+
 type loadingMsg struct {
 	progress string
 	dirs     int64
 	files    int64
+	found    int64 // Directories found, of which dirs were listed
 }
 
 type treeReadyMsg struct {
 	root *FileNode
+	err  error
 }
 
 type refreshMsg struct{}
 
 type refreshDirMsg struct{}
 
+// subtreeReadyMsg is sent when a selective rescan of a single directory finishes
+type subtreeReadyMsg struct {
+	node *FileNode
+	err  error
+}
+
+// FileNode is a file or directory in the tree. Name, Path, IsDir, Size,
+// ModTime, Hash and Parent never change once the scanner has created the node;
+// everything else is owned by the event loop and only modified in Update.
 type FileNode struct {
 	Name     string
 	Path     string
 	IsDir    bool
 	Size     int64
 	ModTime  time.Time
+	Hash     string // Checksum listed by rclone with --hashes, as "type:value"
 	Children []*FileNode
 	Expanded bool
 	Filter   FilterState
@@ -62,59 +95,277 @@ type FileNode struct {
 
 	TotalSize  int64
 	TotalFiles int
+	Types      *typeSizes // Size by file type, for directories
 	Loading    bool
-	mu         sync.RWMutex
+
+	Unreadable bool   // The current user lacks permission to read it
+	ListErr    error  // Why listing the directory failed
+	Skipped    bool   // Matched --scan-exclude and was left unscanned
+	Link       string // Where a junction or other directory link leads
+	LinkLoop   bool   // Link leads back to a directory it sits in, so it isn't scanned
+
+	FilesPending bool // Scanned with --dirs-only, its files aren't listed yet
+	UnlistedDirs int  // Directories at or below it whose files aren't listed yet
 }
 
 type FilterRule struct {
-	Pattern string
-	State   FilterState
+	Pattern  string
+	State    FilterState
+	Disabled bool   // Kept in the file as a comment, see disabledRulePrefix
+	Section  string // Name of the section header above the rule, see sectionHeader
+	Include  string // File pulled in by an include directive, see includeDirective
+	Origin   string // Included file the rule comes from, read-only when set
+	Comment  string // Comment lines above the rule in the file, markers included
 }
 
 type Model struct {
-	root            *FileNode
-	cursor          int
-	visibleNodes    []*FileNode
-	filterRules     []FilterRule
-	filterMap       map[string]FilterState
-	filterMapMu     *sync.RWMutex // Protects filterMap from concurrent access
-	filterFile      string
-	showHelp        bool
-	showSaveConfirm bool
-	width           int
-	height          int
-	scrollOffset    int
-	loading         bool
-	loadProgress    string
-	scannedDirs     int64
-	scannedFiles    int64
-	ctx             context.Context
-	cancel          context.CancelFunc
-	program         *tea.Program
-	checkers        int
-	sortMode        SortMode
+	root             *FileNode
+	cursor           int
+	visibleNodes     []*FileNode
+	filters          *FilterDocument    // Rules of the active root, see activateRootFor
+	filterCache      *filterCache       // Rules deciding the filter paths, nil to evaluate them every time
+	loadReport       []filterDiagnostic // Problems found in the filter files as they were read
+	filterFile       string
+	modals           []modal // Open panes and prompts, the last one on top
+	paneScroll       int     // Lines the pane on top is scrolled by, see placePane
+	width            int
+	height           int
+	scrollOffset     int
+	loadProgress     string
+	scannedDirs      int64
+	scannedFiles     int64
+	foundDirs        int64
+	ctx              context.Context
+	cancel           context.CancelFunc
+	program          *tea.Program
+	send             func(tea.Msg)    // Overrides program.Send for background work
+	now              func() time.Time // Overrides time.Now for the times the screen shows
+	checkers         int
+	checkerLimit     *checkerLimit // Limits the listings of running scans to checkers
+	scanRate         scanRate
+	sortMode         SortMode
+	sortReverse      bool   // Sort against the direction of sortMode
+	sortCursor       int    // Row of the sort menu
+	mixedSort        bool   // Sort directories and files together instead of directories first
+	anchorPath       string // Path the cursor returns to as a refresh scans it again
+	emptyDirCursor   int
+	survivors        survivors // Included files below a directory, listed with l
+	survivorCursor   int
+	extensionMenu    *extensionMenu // Rules for the extension of a file, offered with n
+	extensionCursor  int
+	metadata         *metadataFilter // Set with --metadata-file
+	metadataChanged  bool
+	metadataCursor   int
+	metadataInput    *string // Set while typing a new metadata rule
+	metadataErr      string
+	tourPage         int
+	tourPending      bool                         // Start the tour once the first scan completes
+	tourFile         string                       // Records that the tour was taken
+	pins             map[string]bool              // Paths kept at the top of their directory
+	notes            map[string]map[string]string // Notes on entries, by root path and filter path
+	pinsFile         string                       // Where pins are kept between sessions
+	viewMode         ViewMode
+	viewMatches      map[*FileNode]bool // Nodes shown in the current view mode
+	statusMsg        string
+	pendingToggle    *FileNode   // Directory waiting for a confirming second toggle
+	pendingToggleKey string      // Key confirming pendingToggle
+	lastAction       *ruleAction // Rule change that . repeats
+	pendingSave      bool        // Save waiting for a second press despite guard rail warnings
+	quitAfterSave    bool        // The save under way was confirmed when quitting
+	countPrefix      string      // Digits typed before a motion, as in 10j
+	countGen         int         // Ignores timeouts of counts that were already used
+	pendingG         bool        // First g of gg typed
+	guards           guardRails
+	confirmFiles     int    // Changed files above which a toggle needs confirmation
+	jobs             []*job // Background jobs, oldest first
+	nextJobID        int
+	jobCursor        int
+	filterGen        int // Bumped on every filter edit to discard stale recomputes
+	rcloneRun        func(ctx context.Context, args ...string) ([]byte, error)
+	duplicates       [][]*FileNode      // Groups of likely identical files
+	duplicateOf      map[*FileNode]bool // Files that are part of a duplicate group
+	dupCursor        int
+	staleRules       []staleRule     // Rules referring to paths missing from the tree
+	keptRules        map[string]bool // Stale rules the user chose to keep
+	redundant        []redundantRule // Rules offered for coalescing before saving
+	keptRedundant    map[string]bool // Redundant rules the user chose to keep
+	conflicts        []ruleConflict  // Patterns both included and excluded, offered for resolving before saving
+	conflictCursor   int
+	keptConflicts    map[string]bool     // Conflicting patterns the user chose to save as they are
+	suggestions      []patternSuggestion // Wildcard patterns offered to replace rules (S)
+	suggestionCursor int
+	activity         []string // Summaries of the latest toggles, oldest first
+	triageCursor     int
+	remapping        *staleRule // Rule waiting for a new location to be chosen in the tree
+	remapAll         bool       // Remap every rule under the folder of remapping
+	snapshotDir      string     // Directory snapshots are stored in
+	snapshotName     string     // Name the current tree is saved under with P
+	compareTo        *snapshot  // Snapshot the tree is compared against (--compare)
+	growthCursor     int
+	expanding        *expandProgress // Expansion whose rows are still being added
+	expandGen        int
+	notify           notifier
+	shownTitle       string                  // Last terminal title set
+	estimates        map[string]sizeEstimate // Sizes shown until the exact scan is done
+	templates        []RuleTemplate
+	templateCursor   int
+	templatePrompt   *templatePrompt   // Set while typing the value of a template variable
+	depthInput       *string           // Set while typing the depth to expand the tree to
+	quickInput       *string           // Set while typing the glob of the quick filter (*)
+	quick            *quickFilter      // Glob the tree is pruned to, nil to show everything
+	progress         *progressReporter // --progress-json events, nil without the flag
+	typeAhead        *typeAhead        // Set while typing a name to jump to (f)
+	agePrompt        *agePrompt        // Set while typing the age of an age limit (a)
+	ageLimits        ageLimits         // --max-age and --min-age of the transfer (a)
+	notePrompt       *notePrompt       // Set while typing the note of a rule (c in the rules pane)
+	nodeNotePrompt   *nodeNotePrompt   // Set while typing the note on an entry (c)
+	showRules        bool              // Show the rule deciding each row's state (e)
+	toast            *toast            // Shown on the status line for a few seconds
+	toastGen         int
+	sizeDelta        *sizeDelta // Shown in the header for a few seconds after a toggle
+	sizeDeltaGen     int
+	saveErr          error                     // Why the last save failed, shown until one succeeds
+	scanLog          string                    // File the summaries of finished scans are kept in
+	locks            []*fileLock               // Held on the files the session saves to
+	readOnly         bool                      // Another session holds the lock, saving is refused
+	baseline         map[string]*Model         // Rules of each root as loaded, by root path
+	baselineStates   map[*FileNode]FilterState // States under the loaded rules, filled as needed
+	showFileTypes    bool                      // Break directory sizes down by file type
+	sizeBars         bool                      // Draw size bars in front of the rows
+	showGuides       bool                      // Join rows to their directory with lines, see treeGuides
+	hideSmall        bool                      // Hide entries smaller than minSize (H)
+	showDetails      bool                      // Show modification times and checksums of files (--hashes)
+	hashLocal        bool                      // Hash local files to match the --dest listing by checksum (--hash-local)
+	mirror           fileMirror                // Set with --local-mirror, or once --dest is listed
+	syncDest         string                    // Destination of the sync given with --dest
+	destBackend      string                    // Backend type of a remote --dest, for its name encoding
+	since            *sinceColumn              // Set with --since
+	destListing      *destListing              // Set with --dest-listing
+	pathImport       *pathImport               // Set while the list of paths to exclude is entered
+	minSize          int64
+	heat             *heatmap // Colors directories by file count or size (O)
+	rcloneCommand    string   // Template of the command shown with X
+	privilegedLister string   // Command standing in for rclone to list unreadable directories, see newPrivilegedLister
+	rcloneDest       string
+	clipboard        io.Writer // Terminal the clipboard is set through
+	rulesCursor      int
+	rulesQuery       string       // Search filtering the rules pane
+	rulesTop         *FileNode    // Root whose rules are shown
+	ruleOrder        ruleOrder    // Order the rules are saved in
+	includeParents   string       // include-parents: ask, auto or off
+	parentOffer      *parentOffer // The "+ dir/" rules offered after an include
+	orderCursor      int
+	orderPreviews    []orderPreview  // Of each of ruleOrders, while the order pane is open
+	foldedSections   map[string]bool // Sections of the rules pane showing only their header
+	issues           []*FileNode     // Unreadable entries found by the last scan
+	issueCursor      int
+	lister           lister
+	scanExclude      []string      // Patterns the scan skips (--scan-exclude)
+	showSkipped      bool          // Keep skipped directories in the tree as stubs
+	dirsOnly         bool          // Scan without files, listing them per expanded directory (--dirs-only)
+	remoteCache      *remoteLister // Set when browsing an rclone remote
+	roots            []*sessionRoot
+	toStdout         bool      // Saving prints the rules to stdout on exit
+	filesFrom        *fileList // Set when editing a --files-from list instead of rules
+	saved            bool
+	scanErr          error
+}
+
+// stdioFilterFile is the filter file name that reads rules from stdin
+const stdioFilterFile = "-"
+
+// quietMode suppresses informational output printed outside the TUI
+var quietMode bool
+
+// infoOutput receives informational messages, moved to stderr when the rules
+// themselves are printed to stdout
+var infoOutput io.Writer = os.Stdout
+
+// infof prints an informational message unless --quiet was given
+func infof(format string, args ...interface{}) {
+	if quietMode {
+		return
+	}
+	fmt.Fprintf(infoOutput, format, args...)
 }
 
 func main() {
-	var filterFile string
-	var basePath string
+	var filterFiles stringList
+	var basePaths stringList
 	var showHelp bool
 
 	var checkers int
-	flag.StringVar(&filterFile, "file", "", "Path to the rclone filter file")
-	flag.StringVar(&filterFile, "f", "", "Path to the rclone filter file (shorthand)")
-	flag.StringVar(&basePath, "path", "", "Base directory to browse (default: current directory)")
-	flag.StringVar(&basePath, "p", "", "Base directory to browse (shorthand)")
+	var configPath string
+	var toStdout bool
+	var exportMode string
+	var exportFile string
+	var filesFromPath string
+	var snapshotName string
+	var compareName string
+	var importListing string
+	var sftpSpec string
+	var scanExclude stringList
+	var showSkipped bool
+	var local localLister
+	var metadataPath string
+	var scriptPath string
+	var serveAddr string
+	var estimate bool
+	var dirsOnly bool
+	recentPath := defaultRecentFile()
+	var showHashes bool
+	var hashLocal bool
+	var mirrorDir string
+	var destListingFile string
+	var syncDest string
+	var sinceRef string
+	var renderMode string
+	var progressJSON bool
+	flag.Var(&filterFiles, "file", "Path to the rclone filter file (repeat once per --path)")
+	flag.Var(&filterFiles, "f", "Path to the rclone filter file (shorthand)")
+	flag.Var(&basePaths, "path", "Base directory to browse, repeat to open several roots (default: current directory)")
+	flag.Var(&basePaths, "p", "Base directory to browse (shorthand)")
 	flag.IntVar(&checkers, "checkers", 4, "Number of concurrent directory scanning threads")
+	flag.StringVar(&configPath, "config", defaultConfigPath(), "Path to the config file")
+	flag.BoolVar(&toStdout, "stdout", false, "Print the saved rules to stdout instead of writing the filter file")
+	flag.StringVar(&filesFromPath, "files-from", "", "Edit an rclone --files-from list instead of filter rules")
+	flag.StringVar(&exportMode, "export", "", "Print the \"included\" or \"excluded\" file paths for rclone --files-from, write the rules for --include-from or --exclude-from with \"include-exclude\", dump the tree with its states as \"csv\" or \"json\", or translate the rules for \"restic\", \"borg\" or \"rsync\", instead of editing")
+	flag.StringVar(&exportFile, "export-file", stdioFilterFile, "File to write the --export list to (- for stdout); include-exclude adds .include or .exclude to it")
+	flag.StringVar(&snapshotName, "snapshot", defaultSnapshotName(), "Name the P key saves the snapshot of directory sizes under")
+	flag.StringVar(&compareName, "compare", "", "Show how directories grew since the named snapshot")
+	flag.StringVar(&metadataPath, "metadata-file", "", "Metadata filter file to edit as well, for rclone --metadata-filter-from")
+	flag.StringVar(&sftpSpec, "sftp", "", "Browse user@host:path over SFTP, for servers without rclone")
+	flag.Var(&scanExclude, "scan-exclude", "Pattern of directories or files the scan skips, e.g. **/node_modules/** (repeatable, separate from the filter rules)")
+	flag.BoolVar(&showSkipped, "show-skipped", false, "Show the directories skipped by --scan-exclude as unscanned stubs")
+	flag.BoolVar(&dirsOnly, "dirs-only", false, "Scan only directories and list the files of each directory once it is expanded, for filtering at the directory level")
+	flag.BoolVar(&local.skipSpecial, "skip-special", false, "Leave device nodes, sockets and named pipes out of the tree, as rclone can't copy them")
+	flag.BoolVar(&local.oneFileSystem, "one-file-system", false, "Don't cross into other filesystems mounted below the root, like rclone -x")
+	flag.BoolVar(&local.oneFileSystem, "x", false, "Don't cross filesystem boundaries (shorthand)")
+	flag.StringVar(&importListing, "import-listing", "", "Build the tree from an \"rclone lsjson -R\" dump instead of scanning")
+	flag.StringVar(&scriptPath, "script", "", "Run the editor commands in a file instead of the interactive editor")
+	flag.StringVar(&serveAddr, "serve", "", "Serve an HTTP/JSON API on the address (e.g. :8080, on localhost only) instead of the interactive editor")
+	flag.BoolVar(&showHashes, "hashes", false, "List checksums of remote files and show them with modification times")
+	flag.BoolVar(&hashLocal, "hash-local", false, "With a remote --dest, hash the local files in the background to find those already there under another name")
+	flag.StringVar(&mirrorDir, "local-mirror", "", "Local copy of the tree to compare files with, marking those that differ or are missing")
+	flag.StringVar(&syncDest, "dest", "", "Destination of the sync, a remote or a local directory, to compare files with and exclude those already there (=)")
+	flag.StringVar(&renderMode, "render", "", "What the terminal can display: \"full\" (256 colors, UTF-8), \"basic\" (8 colors, ASCII) or \"auto\" to tell from TERM and the locale (default from the config, auto)")
+	flag.StringVar(&sinceRef, "since", "", "Flag the files modified since a date (2024-01-01), an age (30d) or the \"last save\" of the filter file")
+	flag.StringVar(&destListingFile, "dest-listing", "", "\"rclone lsjson -R\" dump of the sync destination, to mark what --delete-excluded would delete (A)")
+	flag.BoolVar(&progressJSON, "progress-json", false, "With --export, --script or --serve, write progress events to stderr as JSON lines")
+	flag.BoolVar(&estimate, "estimate", false, "Show directory sizes estimated from a sample first and scan exact sizes in the background")
+	flag.BoolVar(&quietMode, "quiet", false, "Suppress non-error output")
+	flag.BoolVar(&quietMode, "q", false, "Suppress non-error output (shorthand)")
 	flag.BoolVar(&showHelp, "help", false, "Show usage information")
 	flag.BoolVar(&showHelp, "h", false, "Show usage information (shorthand)")
 
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS] [FILTER_FILE] [DIRECTORY]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS] [FILTER_FILE] [DIRECTORY]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s completion bash|zsh|fish\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s lint FILTER_FILE...\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "Interactive terminal UI for editing rclone filter files.\n\n")
 		fmt.Fprintf(os.Stderr, "Arguments:\n")
 		fmt.Fprintf(os.Stderr, "  FILTER_FILE  Path to the rclone filter file (default: filter.txt)\n")
-		fmt.Fprintf(os.Stderr, "  DIRECTORY    Directory to browse (default: current directory)\n\n")
+		fmt.Fprintf(os.Stderr, "  DIRECTORY    Directory or rclone remote:path to browse (default: current directory)\n\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		flag.PrintDefaults()
 		fmt.Fprintf(os.Stderr, "\nExamples:\n")
@@ -124,6 +375,28 @@ func main() {
 		fmt.Fprintf(os.Stderr, "  %s myfilters.txt test/folder_a # Use myfilters.txt to browse test/folder_a\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s --checkers 8 -p test/folder_a # Use 8 threads to scan test/folder_a\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -f filters.txt -p /path   # Use specific filter file and path\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -p gdrive:Photos          # Browse an rclone remote\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --sftp me@nas:/volume1     # Browse a server over SFTP without rclone\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -p /data -f data.txt -p /media -f media.txt # Edit two roots side by side\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  gen-rules | %s -f - -p /data > filter.txt # Edit rules from a pipe\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -f filter.txt -p /data --export included > files.txt # List files for --files-from\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -f filter.txt -p /data --export restic --export-file restic.txt # Same rules for restic\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --files-from files.txt -p /data # Edit a file list instead of rules\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -p /data --compare 2026-09-01 # Show growth since a snapshot saved with P\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nExit codes:\n")
+		fmt.Fprintf(os.Stderr, "  %d  Filters were saved\n", exitSaved)
+		fmt.Fprintf(os.Stderr, "  %d  Quit without saving\n", exitNotSaved)
+		fmt.Fprintf(os.Stderr, "  %d  Directory could not be scanned\n", exitScanError)
+		fmt.Fprintf(os.Stderr, "  %d  Filter file could not be read\n", exitFilterError)
+	}
+
+	// "completion SHELL" prints a completion script instead of editing
+	if len(os.Args) > 1 && os.Args[1] == "completion" {
+		os.Exit(runCompletion(os.Args[2:]))
+	}
+	// "lint FILE..." checks filter files instead of editing
+	if len(os.Args) > 1 && os.Args[1] == "lint" {
+		os.Exit(runLint(os.Args[2:]))
 	}
 
 	flag.Parse()
@@ -133,437 +406,647 @@ func main() {
 		return
 	}
 
-	args := flag.Args()
-	rootPath := "."
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring config file: %v\n", err)
+		cfg = defaultConfig()
+	}
+	cfg.activate()
+	if renderMode != "" {
+		if renderMode != renderAuto && renderMode != renderFull && renderMode != renderBasic {
+			fmt.Fprintf(os.Stderr, "Error: --render must be auto, full or basic\n")
+			os.Exit(exitNotSaved)
+		}
+		cfg.Render = renderMode
+	}
 
-	// Use --path flag if provided, otherwise fall back to current logic
-	if basePath != "" {
-		rootPath = basePath
+	args := flag.Args()
+	if len(args) > 2 {
+		fmt.Fprintf(os.Stderr, "Error: too many arguments %q, expected at most FILTER_FILE and DIRECTORY\n", args[2:])
+		os.Exit(exitNotSaved)
 	}
 
-	// Handle arguments: first arg can be filter file, second can be directory
-	if filterFile == "" {
-		if len(args) > 0 {
-			// Check if the first argument is a directory - if so, use it as the path
-			// and use default filter file
-			if stat, err := os.Stat(args[0]); err == nil && stat.IsDir() && basePath == "" {
-				// Single argument is a directory, use default filter file
-				rootPath = args[0]
-				filterFile = "filter.txt"
-			} else {
-				// First argument is a filter file
-				filterFile = args[0]
-				if len(args) > 1 && basePath == "" {
-					// Only use positional directory arg if --path wasn't used
-					rootPath = args[1]
-				}
-			}
-		} else {
-			filterFile = "filter.txt"
+	var roots []*sessionRoot
+	if len(basePaths) > 1 {
+		// Every root needs its own filter file since patterns are relative to it
+		if len(filterFiles) != len(basePaths) || len(args) > 0 {
+			fmt.Fprintf(os.Stderr, "Error: with several --path roots, give one --file per root and no positional arguments\n")
+			os.Exit(exitNotSaved)
+		}
+		for i := range basePaths {
+			roots = append(roots, &sessionRoot{path: basePaths[i], filterFile: filterFiles[i]})
 		}
 	} else {
-		// If --file was used, first arg is directory (unless --path was also used)
-		if len(args) > 0 && basePath == "" {
-			rootPath = args[0]
+		if len(filterFiles) > 1 {
+			fmt.Fprintf(os.Stderr, "Error: several --file options need one --path each\n")
+			os.Exit(exitNotSaved)
+		}
+		var filterFile, basePath string
+		if len(filterFiles) == 1 {
+			filterFile = filterFiles[0]
+		}
+		if len(basePaths) == 1 {
+			basePath = basePaths[0]
+		}
+		if sftpSpec != "" {
+			if basePath != "" {
+				fmt.Fprintf(os.Stderr, "Error: --sftp names the directory to browse and can't be combined with --path\n")
+				os.Exit(exitNotSaved)
+			}
+			basePath = sftpSpec
 		}
-	}
 
-	filterRules, filterMap := loadFilterFile(filterFile)
+		rootPath := "."
 
-	// Set the global root path for filter path calculations
-	absRootPath, err := filepath.Abs(rootPath)
-	if err != nil {
-		fmt.Printf("Error getting absolute path: %v\n", err)
-		os.Exit(1)
-	}
-	globalRootPath = absRootPath
+		// Use --path flag if provided, otherwise fall back to current logic
+		if basePath != "" {
+			rootPath = basePath
+		}
 
-	ctx, cancel := context.WithCancel(context.Background())
+		// Handle arguments: first arg can be filter file, second can be directory
+		defaultFilter := false
+		if filterFile == "" {
+			if len(args) > 0 {
+				// Check if the first argument is a directory - if so, use it as the path
+				// and use default filter file
+				if stat, err := os.Stat(args[0]); err == nil && stat.IsDir() && basePath == "" {
+					// Single argument is a directory, use default filter file
+					rootPath = args[0]
+					filterFile = "filter.txt"
+					defaultFilter = true
+				} else {
+					// First argument is a filter file
+					filterFile = args[0]
+					if len(args) > 1 && basePath == "" {
+						// Only use positional directory arg if --path wasn't used
+						rootPath = args[1]
+					}
+				}
+			} else {
+				filterFile = "filter.txt"
+				defaultFilter = true
+			}
+		} else {
+			// If --file was used, first arg is directory (unless --path was also used)
+			if len(args) > 0 && basePath == "" {
+				rootPath = args[0]
+			}
+		}
 
-	if checkers < 1 {
-		checkers = 4
+		// Without a filter file, offer the ones edited before rather than
+		// silently starting a new filter.txt
+		interactive := filesFromPath == "" && exportMode == "" && scriptPath == "" && serveAddr == "" &&
+			!toStdout && isTerminal(os.Stdin) && isTerminal(os.Stdout)
+		if defaultFilter && interactive {
+			pathGiven := ""
+			if basePath != "" || len(args) > 0 {
+				pathGiven = rootPath
+			}
+			entries, err := loadRecentFiles(recentPath)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: ignoring the recent files: %v\n", err)
+			}
+			if entries = recentFilesFor(entries, pathGiven); len(entries) > 0 {
+				chosen, quit, err := pickRecentFile(entries, filterFile)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(exitNotSaved)
+				}
+				if quit {
+					os.Exit(exitNotSaved)
+				}
+				if chosen != nil {
+					filterFile, rootPath = chosen.filterFile, chosen.root
+				}
+			}
+		}
+		roots = append(roots, &sessionRoot{path: rootPath, filterFile: filterFile})
 	}
 
-	m := Model{
-		filterRules:  filterRules,
-		filterMap:    filterMap,
-		filterMapMu:  &sync.RWMutex{},
-		filterFile:   filterFile,
-		loading:      true,
-		loadProgress: "Scanning directories...",
-		ctx:          ctx,
-		cancel:       cancel,
-		checkers:     checkers,
+	var filesFrom *fileList
+	if filesFromPath != "" {
+		if len(filterFiles) > 0 || len(roots) > 1 {
+			fmt.Fprintf(os.Stderr, "Error: --files-from edits a single list and can't be combined with --file\n")
+			os.Exit(exitNotSaved)
+		}
+		filesFrom, err = readFileList(filesFromPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading file list %s: %v\n", filesFromPath, err)
+			os.Exit(exitFilterError)
+		}
+		// The list replaces the rules, there is no filter file to read
+		roots[0].filterFile = ""
+		if filesFromPath == stdioFilterFile {
+			toStdout = true
+		}
 	}
 
-	// Initialize root node immediately for UI
-	absPath, err := filepath.Abs(rootPath)
-	if err != nil {
-		fmt.Printf("Error getting absolute path: %v\n", err)
-		os.Exit(1)
-	}
-	m.root = &FileNode{
-		Name:     filepath.Base(absPath),
-		Path:     absPath,
-		IsDir:    true,
-		Expanded: true,
-		Loading:  true,
-	}
-	rootFilterPath := getFilterPath(absPath)
-	m.root.Filter = getEffectiveFilter(rootFilterPath, m.filterRules)
-	m.updateVisibleNodes()
-
-	p := tea.NewProgram(&m, tea.WithAltScreen())
-	m.program = p
-
-	// Start async tree building after program is set
-	go m.buildFileTreeAsync(rootPath)
-
-	if _, err := p.Run(); err != nil {
-		fmt.Printf("Error: %v\n", err)
-		os.Exit(1)
+	var metadata *metadataFilter
+	if metadataPath != "" {
+		metadata, err = readMetadataFilter(metadataPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading metadata filter %s: %v\n", metadataPath, err)
+			os.Exit(exitFilterError)
+		}
 	}
 
-}
-
-func (m *Model) buildFileTreeAsync(rootPath string) {
-	// Start background goroutine for breadth-first concurrent tree building
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				// Handle any panics in goroutine gracefully
-				fmt.Printf("Warning: goroutine panic during tree building: %v\n", r)
-			}
-		}()
-
-		// Check if context is already cancelled before starting
-		select {
-		case <-m.ctx.Done():
-			return
-		default:
+	var compareTo *snapshot
+	if compareName != "" {
+		compareTo, err = loadSnapshot(cfg.SnapshotDir, compareName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading snapshot %s: %v\n", compareName, err)
+			os.Exit(exitNotSaved)
 		}
+	}
 
-		m.buildTreeBreadthFirst(m.root, m.filterRules)
-
-		// Check context again before sending completion message
-		select {
-		case <-m.ctx.Done():
-			return
-		default:
-			// Send completion message only if not cancelled
-			if m.program != nil {
-				m.program.Send(treeReadyMsg{root: m.root})
-			}
+	if importListing != "" && len(roots) > 1 {
+		fmt.Fprintf(os.Stderr, "Error: --import-listing can only be used with a single root\n")
+		os.Exit(exitNotSaved)
+	}
+	if sftpSpec != "" && (len(roots) > 1 || importListing != "") {
+		fmt.Fprintf(os.Stderr, "Error: --sftp can only be used with a single root\n")
+		os.Exit(exitNotSaved)
+	}
+	var mirror fileMirror
+	if mirrorDir != "" {
+		mirror = newLocalMirror(mirrorDir)
+		if len(roots) > 1 {
+			fmt.Fprintf(os.Stderr, "Error: --local-mirror can only be used with a single root\n")
+			os.Exit(exitNotSaved)
+		}
+		if err := checkDirArg(mirrorDir); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --local-mirror: %v\n", err)
+			os.Exit(exitScanError)
 		}
-	}()
-}
-
-func (m *Model) refreshDirectory() {
-	if m.root == nil {
-		return
 	}
-
-	// Cancel any existing operations
-	m.cancel()
-
-	// Create new context for refresh operation
-	ctx, cancel := context.WithCancel(context.Background())
-	m.ctx = ctx
-	m.cancel = cancel
-
-	// Reset loading state
-	m.loading = true
-	m.loadProgress = "Refreshing directory tree..."
-	atomic.StoreInt64(&m.scannedDirs, 0)
-	atomic.StoreInt64(&m.scannedFiles, 0)
-
-	// Create new root node with same path and preserve filter state
-	rootPath := m.root.Path
-	m.root = &FileNode{
-		Name:     filepath.Base(rootPath),
-		Path:     rootPath,
-		IsDir:    true,
-		Expanded: true,
-		Loading:  true,
-	}
-	// Use the new function that considers both filterRules and filterMap
-	rootFilterPath := getFilterPath(rootPath)
-	m.root.Filter = m.getEffectiveFilterWithMap(rootFilterPath)
-	m.updateVisibleNodes()
-
-	// Start async tree building
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				// Handle any panics in goroutine gracefully
-				fmt.Printf("Warning: goroutine panic during directory refresh: %v\n", r)
+	if syncDest != "" {
+		if len(roots) > 1 || mirrorDir != "" {
+			fmt.Fprintf(os.Stderr, "Error: --dest can only be used with a single root and without --local-mirror\n")
+			os.Exit(exitNotSaved)
+		}
+		// A local destination is compared file by file like a mirror, a
+		// remote one is listed once the editor is up
+		if !isRemotePath(syncDest) {
+			if err := checkDirArg(syncDest); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: --dest: %v\n", err)
+				os.Exit(exitScanError)
 			}
-		}()
-
-		// Check if context is already cancelled before starting
-		select {
-		case <-m.ctx.Done():
-			return
-		default:
+			local := newLocalMirror(syncDest)
+			local.kind = "dest"
+			mirror = local
 		}
+		cfg.RcloneDest = syncDest
+	}
+	if hashLocal && (syncDest == "" || !isRemotePath(syncDest)) {
+		fmt.Fprintf(os.Stderr, "Error: --hash-local needs a remote --dest to match checksums with\n")
+		os.Exit(exitNotSaved)
+	}
+	var dest *destListing
+	if destListingFile != "" {
+		if len(roots) > 1 {
+			fmt.Fprintf(os.Stderr, "Error: --dest-listing can only be used with a single root\n")
+			os.Exit(exitNotSaved)
+		}
+		var err error
+		if dest, err = readDestListing(destListingFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --dest-listing: %v\n", err)
+			os.Exit(exitScanError)
+		}
+	}
 
-		m.buildTreeBreadthFirst(m.root, m.filterRules)
+	// Rules read from stdin have no file to go back to
+	for _, r := range roots {
+		if r.filterFile == stdioFilterFile {
+			toStdout = true
+		}
+	}
+	if toStdout && len(roots) > 1 {
+		fmt.Fprintf(os.Stderr, "Error: --stdout and -f - can only be used with a single root\n")
+		os.Exit(exitNotSaved)
+	}
 
-		// Check context again before sending completion message
-		select {
-		case <-m.ctx.Done():
-			return
-		default:
-			// Send completion message only if not cancelled
-			if m.program != nil {
-				m.program.Send(treeReadyMsg{root: m.root})
+	// Check the arguments before anything is read, so that a mistake is
+	// reported as such rather than as a failed scan
+	for _, r := range roots {
+		if err := checkFilterFileArg(r.filterFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitFilterError)
+		}
+		// An imported listing stands in for a tree that may only exist on
+		// another machine
+		if !isRemotePath(r.path) && importListing == "" {
+			if err := checkDirArg(r.path); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(exitScanError)
 			}
 		}
-	}()
-}
-
-// Breadth-first concurrent directory scanning
-func (m *Model) buildTreeBreadthFirst(root *FileNode, filterRules []FilterRule) {
-	// Use a queue for breadth-first traversal
-	queue := []*FileNode{root}
-
-	for len(queue) > 0 && m.ctx.Err() == nil {
-		// Process current level
-		currentLevel := queue
-		queue = nil
-
-		// Process directories at current level concurrently
-		var wg sync.WaitGroup
-		nextLevelChan := make(chan []*FileNode, len(currentLevel))
-		semaphore := make(chan struct{}, m.checkers)
+	}
 
-		for _, dir := range currentLevel {
-			if !dir.IsDir {
-				continue
+	// A mistyped path can send the scan through the whole machine
+	for _, r := range roots {
+		if isRemotePath(r.path) || importListing != "" || sftpSpec != "" {
+			continue
+		}
+		if denied, ok := deniedDir(r.path, cfg.ScanDeny); ok {
+			fmt.Fprintf(os.Stderr, "Error: %s is in %s, which scan-deny keeps from being scanned\n", r.path, denied)
+			os.Exit(exitScanError)
+		}
+		if reason := riskyRoot(r.path, cfg.WarnHomeSize); reason != "" {
+			if exportMode != "" || scriptPath != "" || serveAddr != "" || !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+				fmt.Fprintf(os.Stderr, "Warning: %s %s, scanning it may take very long\n", r.path, reason)
+			} else if !confirmScan(r.path, reason, os.Stdin, os.Stderr) {
+				os.Exit(exitNotSaved)
 			}
+		}
+		scanExclude = append(scanExclude, deniedBelow(r.path, cfg.ScanDeny)...)
+	}
 
-			wg.Add(1)
-			go func(node *FileNode) {
-				defer func() {
-					wg.Done()
-					if r := recover(); r != nil {
-						// Handle any panics in goroutine gracefully
-						fmt.Printf("Warning: goroutine panic during directory scan: %v\n", r)
-					}
-				}()
-
-				// Check context before acquiring semaphore
-				select {
-				case <-m.ctx.Done():
-					return
-				case semaphore <- struct{}{}: // Acquire
-					defer func() { <-semaphore }() // Release
-				}
-
-				// Double-check context after acquiring semaphore
-				select {
-				case <-m.ctx.Done():
-					return
-				default:
-				}
-
-				children := m.scanSingleDirectory(node, m.filterRules)
+	remote := false
+	var loadReport []filterDiagnostic
+	for _, r := range roots {
+		if r.filterFile == "" {
+			r.filters = newFilterDocument(nil)
+		} else {
+			var diagnostics []filterDiagnostic
+			r.filters, diagnostics, err = readFilterFileReport(r.filterFile)
+			loadReport = append(loadReport, diagnostics...)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading filter file %s: %v\n", r.filterFile, err)
+			os.Exit(exitFilterError)
+		}
+		r.filterCache = newFilterCache()
 
-				// Check context before sending results
-				select {
-				case <-m.ctx.Done():
-					return
-				case nextLevelChan <- children:
-				}
-			}(dir)
+		// Remote roots ("remote:path") are listed through rclone instead of the
+		// local filesystem
+		if isRemotePath(r.path) {
+			remote = true
+			continue
 		}
 
-		// Wait for all directories in current level to complete
-		go func() {
-			defer func() {
-				if r := recover(); r != nil {
-					fmt.Printf("Warning: goroutine panic during wait: %v\n", r)
-				}
-			}()
-			wg.Wait()
-			close(nextLevelChan)
-		}()
-
-		// Collect children for next level with context checking
-	levelLoop:
-		for {
-			select {
-			case <-m.ctx.Done():
-				// Drain the channel to prevent goroutine leaks with timeout
-				go func() {
-					timeout := time.NewTimer(5 * time.Second)
-					defer timeout.Stop()
-					for {
-						select {
-						case <-nextLevelChan:
-							// Drain remaining items
-						case <-timeout.C:
-							// Timeout - exit to prevent leak
-							return
-						case <-m.ctx.Done():
-							// Context cancelled - exit
-							return
-						}
-					}
-				}()
-				return
-			case children, ok := <-nextLevelChan:
-				if !ok {
-					// Channel closed - done with this level
-					break levelLoop
-				}
-				queue = append(queue, children...)
-			}
+		// Set the global root path for filter path calculations
+		r.path, err = filepath.Abs(r.path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error getting absolute path: %v\n", err)
+			os.Exit(exitScanError)
 		}
 	}
-}
-
-// Scan a single directory and return its child directories
-func (m *Model) scanSingleDirectory(node *FileNode, filterRules []FilterRule) []*FileNode {
-	select {
-	case <-m.ctx.Done():
-		return nil
-	default:
+	var since *sinceColumn
+	if sinceRef != "" {
+		var files []string
+		for _, r := range roots {
+			files = append(files, r.filterFile)
+		}
+		if since, err = parseSince(sinceRef, files, time.Now()); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --since: %v\n", err)
+			os.Exit(exitNotSaved)
+		}
+	}
+	globalRootPath = roots[0].path
+	if len(roots) > 1 {
+		for _, r := range roots {
+			sessionRootPaths = append(sessionRootPaths, r.path)
+		}
 	}
 
-	entries, err := os.ReadDir(node.Path)
+	pinsFile := defaultPinsFile()
+	pins, err := loadPins(pinsFile)
 	if err != nil {
-		node.mu.Lock()
-		node.Loading = false
-		node.mu.Unlock()
-		return nil
+		fmt.Fprintf(os.Stderr, "Warning: failed to read the pinned entries: %v\n", err)
 	}
-
-	// Update progress
-	dirs := atomic.AddInt64(&m.scannedDirs, 1)
-	if dirs%10 == 0 && m.program != nil {
-		m.program.Send(loadingMsg{
-			progress: "Scanning directories...",
-			dirs:     dirs,
-			files:    atomic.LoadInt64(&m.scannedFiles),
-		})
+	notes := make(map[string]map[string]string)
+	for _, r := range roots {
+		if toStdout {
+			break
+		}
+		if notes[r.path], err = loadNotes(notesFileFor(r.filterFile)); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to read the notes: %v\n", err)
+		}
 	}
 
-	var children []*FileNode
-	var childDirectories []*FileNode
+	ctx, cancel := context.WithCancel(context.Background())
 
-	for _, entry := range entries {
-		childPath := filepath.Join(node.Path, entry.Name())
+	if checkers < 1 {
+		checkers = 4
+	}
 
-		// Validate path to prevent traversal attacks
-		if err := validatePath(childPath, globalRootPath); err != nil {
-			continue // Skip potentially malicious paths
+	m := Model{
+		filters:      roots[0].filters,
+		filterCache:  roots[0].filterCache,
+		loadReport:   loadReport,
+		filterFile:   roots[0].filterFile,
+		loadProgress: "Scanning directories...",
+		ctx:          ctx,
+		cancel:       cancel,
+		checkers:     checkers,
+		checkerLimit: newCheckerLimit(checkers),
+		roots:        roots,
+		toStdout:     toStdout,
+		filesFrom:    filesFrom,
+		snapshotDir:  cfg.SnapshotDir,
+		snapshotName: snapshotName,
+		compareTo:    compareTo,
+		metadata:     metadata,
+
+		confirmFiles: cfg.ConfirmToggleFiles,
+		guards: guardRails{
+			maxExcludePercent: cfg.MaxExcludePercent,
+			warnIncludedSize:  cfg.WarnIncludedSize,
+		},
+		templates:        cfg.Templates,
+		ruleOrder:        cfg.RuleOrder,
+		includeParents:   cfg.IncludeParents,
+		mixedSort:        !cfg.SortDirsFirst,
+		showFileTypes:    cfg.ShowFileTypes,
+		sizeBars:         cfg.SizeBars,
+		showGuides:       cfg.TreeGuides,
+		minSize:          cfg.MinSize,
+		heat:             &heatmap{mode: cfg.Heatmap},
+		showDetails:      showHashes,
+		hashLocal:        hashLocal,
+		mirror:           mirror,
+		syncDest:         syncDest,
+		since:            since,
+		destListing:      dest,
+		rcloneCommand:    cfg.RcloneCommand,
+		privilegedLister: cfg.PrivilegedLister,
+		rcloneDest:       cfg.RcloneDest,
+		scanExclude:      scanExclude,
+		showSkipped:      showSkipped,
+		dirsOnly:         dirsOnly,
+		tourFile:         defaultTourFile(),
+		pins:             pins,
+		pinsFile:         pinsFile,
+		notes:            notes,
+		scanLog:          defaultScanLogFile(),
+		notify: notifier{
+			mode:  cfg.Notify,
+			after: cfg.NotifyAfter,
+		},
+	}
+	if importListing != "" {
+		listing, err := readListing(importListing, roots[0].path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading listing %s: %v\n", importListing, err)
+			os.Exit(exitScanError)
 		}
-
-		// Get file info to capture size and modification time
-		var modTime time.Time
-		var size int64
-		if info, err := entry.Info(); err == nil {
-			modTime = info.ModTime()
-			if !entry.IsDir() {
-				size = info.Size()
-			}
+		m.lister = listing
+	} else if sftpSpec != "" {
+		listing, err := dialSFTP(sftpSpec)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error connecting to %s: %v\n", sftpSpec, err)
+			os.Exit(exitScanError)
 		}
+		m.lister = listing
+	} else if remote {
+		m.remoteCache = newRemoteLister(cfg.RemoteCacheTTL)
+		m.remoteCache.hashes = showHashes
+		m.lister = routingLister{remote: m.remoteCache, local: local}
+	} else if local != (localLister{}) {
+		m.lister = local
+	}
 
-		child := &FileNode{
-			Name:    entry.Name(),
-			Path:    childPath,
-			IsDir:   entry.IsDir(),
-			Size:    size,
-			ModTime: modTime,
-			Parent:  node,
-		}
+	// Initialize root node immediately for UI
+	m.root = m.newRootNode()
+	for _, node := range m.topLevelNodes() {
+		m.activateRootFor(node)
+		node.Filter = m.getEffectiveFilterWithMap(getFilterPath(node.Path))
+	}
+	m.activateRootFor(m.topLevelNodes()[0])
+	m.takeBaseline()
+	m.updateVisibleNodes()
 
-		childFilterPath := getFilterPath(childPath)
-		child.Filter = m.getEffectiveFilterWithMap(childFilterPath)
+	if exportMode != "" && scriptPath != "" {
+		fmt.Fprintf(os.Stderr, "Error: --script can't be combined with --export\n")
+		os.Exit(exitNotSaved)
+	}
+	if dirsOnly && (exportMode != "" || scriptPath != "" || serveAddr != "" || estimate) {
+		fmt.Fprintf(os.Stderr, "Error: --dirs-only can't be combined with --export, --script, --serve or --estimate\n")
+		os.Exit(exitNotSaved)
+	}
+	if serveAddr != "" && (exportMode != "" || scriptPath != "" || toStdout) {
+		fmt.Fprintf(os.Stderr, "Error: --serve can't be combined with --export, --script or --stdout\n")
+		os.Exit(exitNotSaved)
+	}
+	if serveAddr != "" {
+		addr, err := serveAddress(serveAddr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitNotSaved)
+		}
+		serveAddr = addr
+	}
+	if progressJSON {
+		if exportMode == "" && scriptPath == "" && serveAddr == "" {
+			fmt.Fprintf(os.Stderr, "Error: --progress-json needs --export, --script or --serve\n")
+			os.Exit(exitNotSaved)
+		}
+		m.progress = newProgressReporter(os.Stderr)
+		m.lister = m.progress.wrap(m.lister)
+	}
+	if exportMode != "" || scriptPath != "" || serveAddr != "" {
+		// Without a screen to show them on, problems in the filter files are
+		// warnings
+		for _, d := range loadReport {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", d)
+		}
+	} else if len(loadReport) > 0 {
+		m.openModal(modalLoadReport)
+	}
+	if exportMode != "" {
+		if len(roots) > 1 {
+			fmt.Fprintf(os.Stderr, "Error: --export can only be used with a single root\n")
+			os.Exit(exitNotSaved)
+		}
+		m.progress.emit(progressEvent{Event: "started", Mode: "export"})
+		code := runExport(&m, exportMode, exportFile)
+		m.progress.finished(code)
+		os.Exit(code)
+	}
+
+	opts := []tea.ProgramOption{tea.WithAltScreen(), tea.WithoutCatchPanics()}
+	if roots[0].filterFile == stdioFilterFile || filesFromPath == stdioFilterFile {
+		// stdin carried the rules, read keys from the terminal instead
+		opts = append(opts, tea.WithInputTTY())
+	}
+	if toStdout {
+		// Keep stdout clean for the rules, draw the UI on stderr
+		opts = append(opts, tea.WithOutput(os.Stderr))
+		lipgloss.SetDefaultRenderer(lipgloss.NewRenderer(os.Stderr))
+		infoOutput = os.Stderr
+	}
+	m.notify.out = infoOutput
+	m.clipboard = infoOutput
+
+	// Only the interactive editor asks what to do about a locked file, a
+	// script or server run gives up
+	var ask func(file, holder string) string
+	if scriptPath == "" && serveAddr == "" && isTerminal(os.Stdin) {
+		ask = func(file, holder string) string { return askLock(file, holder, os.Stdin, os.Stderr) }
+	}
+	if !toStdout {
+		if err := m.lockFiles(ask); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitNotSaved)
+		}
+	}
 
-		if !entry.IsDir() {
-			files := atomic.AddInt64(&m.scannedFiles, 1)
-			if m.program != nil && files%500 == 0 {
-				m.program.Send(loadingMsg{
-					progress: "Scanning directories...",
-					dirs:     atomic.LoadInt64(&m.scannedDirs),
-					files:    files,
-				})
+	if scriptPath != "" {
+		m.progress.emit(progressEvent{Event: "started", Mode: "script"})
+		code := runScript(&m, scriptPath)
+		m.releaseLocks()
+		m.progress.finished(code)
+		os.Exit(code)
+	}
+	if serveAddr != "" {
+		m.progress.emit(progressEvent{Event: "started", Mode: "serve"})
+		code := runServe(&m, serveAddr)
+		m.releaseLocks()
+		m.progress.finished(code)
+		os.Exit(code)
+	}
+	m.tourPending = tourNeeded(m.tourFile)
+	// Sessions over SFTP or of an imported listing can't be opened again from
+	// their root path alone
+	for _, r := range roots {
+		if r.filterFile != "" && r.filterFile != stdioFilterFile && sftpSpec == "" && importListing == "" {
+			entry := recentFile{filterFile: absPath(r.filterFile), root: absPath(r.path), used: time.Now()}
+			if err := addRecentFile(recentPath, entry); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to update the recent files: %v\n", err)
 			}
-		} else {
-			child.Loading = true
-			childDirectories = append(childDirectories, child)
 		}
+	}
+	cfg.detectTheme(lipgloss.HasDarkBackground)
+	cfg.applyRenderMode(os.Getenv, runtime.GOOS)
+
+	crash := &crashHandler{output: infoOutput, stderr: os.Stderr, reportDir: defaultCrashDir(), cleanup: m.releaseLocks, exit: os.Exit}
+	p := tea.NewProgram(crashGuard{Model: &m, handler: crash}, opts...)
+	m.program = p
+	crash.program = p
+	defer crash.recover()
 
-		children = append(children, child)
+	// The scan starts from Init once the program is running
+	if estimate {
+		m.queueJob(m.estimateJob())
+	} else {
+		m.queueJob(m.scanJob())
 	}
 
-	// Sort children using the model's sort mode
-	m.sortChildren(children)
+	finalModel, err := p.Run()
+	m.releaseLocks()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitNotSaved)
+	}
 
-	// Calculate stats for this directory now that children are loaded
-	// Do this BEFORE acquiring the lock to avoid race conditions with child access
-	var totalSize int64
-	var totalFiles int
-	for _, child := range children {
-		if child.IsDir {
-			// For directories, we need to safely read their stats
-			child.mu.RLock()
-			childSize := child.TotalSize
-			childFiles := child.TotalFiles
-			child.mu.RUnlock()
-
-			totalSize += childSize
-			totalFiles += childFiles
+	if fm := asModel(finalModel); fm != nil && fm.saved && fm.toStdout {
+		if fm.filesFrom != nil {
+			err = fm.filesFrom.write(os.Stdout)
 		} else {
-			// For files, Size is immutable after creation
-			totalSize += child.Size
-			totalFiles++
+			err = fm.ruleOrder.write(os.Stdout, fm.filters)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing rules: %v\n", err)
+			os.Exit(exitNotSaved)
 		}
 	}
 
-	// Now safely update the node with all computed values
-	node.mu.Lock()
-	node.Children = children
-	node.Loading = false
-	node.TotalSize = totalSize
-	node.TotalFiles = totalFiles
-	node.mu.Unlock()
+	os.Exit(exitCodeFor(finalModel))
+}
+
+// asModel returns the Model behind the value returned by the program
+func asModel(final tea.Model) *Model {
+	switch v := final.(type) {
+	case crashGuard:
+		return asModel(v.Model)
+	case Model:
+		return &v
+	case *Model:
+		return v
+	}
+	return nil
+}
+
+// exitCodeFor maps the final state of an editing session to a process exit
+// code. A save wins over a scan error: the rules were written, so a wrapper
+// script must not treat the run as failed, and the error is still printed.
+func exitCodeFor(final tea.Model) int {
+	fm := asModel(final)
+	if fm == nil {
+		return exitNotSaved
+	}
 
-	return childDirectories
+	if fm.scanErr != nil {
+		fmt.Fprintf(os.Stderr, "Error scanning: %v\n", fm.scanErr)
+	}
+	if fm.saved {
+		if fm.filesFrom != nil {
+			infof("Saved file list to %s\n", fm.filterFileNames())
+			return exitSaved
+		}
+		infof("Saved filter rules to %s\n", fm.filterFileNames())
+		return exitSaved
+	}
+	if fm.scanErr != nil {
+		return exitScanError
+	}
+	infof("Quit without saving %s\n", fm.filterFileNames())
+	return exitNotSaved
 }
 
 func (m *Model) sortChildren(children []*FileNode) {
 	sort.Slice(children, func(i, j int) bool {
-		// Always put directories first
-		if children[i].IsDir != children[j].IsDir {
-			return children[i].IsDir
+		a, b := children[i], children[j]
+		// Pinned entries come first whatever the sort order
+		if pa, pb := m.isPinned(a), m.isPinned(b); pa != pb {
+			return pa
+		}
+		// Put directories first unless files and directories are mixed
+		if !m.mixedSort && a.IsDir != b.IsDir {
+			return a.IsDir
+		}
+		if m.sortReverse {
+			a, b = b, a
 		}
 
 		switch m.sortMode {
 		case SortByName:
-			return strings.ToLower(children[i].Name) < strings.ToLower(children[j].Name)
+			return strings.ToLower(a.Name) < strings.ToLower(b.Name)
 		case SortBySize:
-			if children[i].IsDir && children[j].IsDir {
-				return children[i].TotalSize > children[j].TotalSize
-			}
-			return children[i].Size > children[j].Size
+			return sortSize(a) > sortSize(b)
 		case SortByFileCount:
-			if children[i].IsDir && children[j].IsDir {
-				return children[i].TotalFiles > children[j].TotalFiles
+			// Files count as one, so equal counts (every pair of files
+			// among them) fall back to the name
+			if ca, cb := sortFileCount(a), sortFileCount(b); ca != cb {
+				return ca > cb
 			}
-			// For files, sort by name since they don't have file counts
-			return strings.ToLower(children[i].Name) < strings.ToLower(children[j].Name)
+			return strings.ToLower(a.Name) < strings.ToLower(b.Name)
 		case SortByLastModified:
 			// Sort by modification time (most recent first)
-			return children[i].ModTime.After(children[j].ModTime)
+			return a.ModTime.After(b.ModTime)
 		default:
-			return strings.ToLower(children[i].Name) < strings.ToLower(children[j].Name)
+			return strings.ToLower(a.Name) < strings.ToLower(b.Name)
 		}
 	})
 }
 
+// sortSize is the size a node is sorted by: the total of a directory, the
+// size of a file
+func sortSize(node *FileNode) int64 {
+	if node.IsDir {
+		return node.TotalSize
+	}
+	return node.Size
+}
+
+// sortFileCount is the number of files a node is sorted by, one for a file
+func sortFileCount(node *FileNode) int {
+	if node.IsDir {
+		return node.TotalFiles
+	}
+	return 1
+}
+
 func calculateStats(node *FileNode) (int64, int) {
 	if !node.IsDir {
 		return node.Size, 1
@@ -571,23 +1054,112 @@ func calculateStats(node *FileNode) (int64, int) {
 
 	var totalSize int64
 	var totalFiles int
+	var types typeSizes
 
 	for _, child := range node.Children {
 		size, files := calculateStats(child)
 		totalSize += size
 		totalFiles += files
+		if child.IsDir {
+			types.add(child.Types)
+		} else {
+			types[fileTypeOf(child.Name)] += child.Size
+		}
 	}
 
 	node.TotalSize = totalSize
 	node.TotalFiles = totalFiles
+	node.Types = &types
 	return totalSize, totalFiles
 }
 
-func (m *Model) updateVisibleNodes() {
+// buildVisibleNodes flattens the expanded part of the tree into visibleNodes
+func (m *Model) buildVisibleNodes() {
+	// A full rebuild includes the rows of any pending expansion
+	m.expanding = nil
 	m.visibleNodes = nil
+	m.viewMatches = nil
+	if m.quick != nil {
+		m.quick.matches = 0
+	}
+	if (m.viewMode != ViewAll || m.hideSmall || m.quick != nil) && m.root != nil {
+		m.viewMatches = make(map[*FileNode]bool)
+		m.markViewMatches(m.root)
+	}
 	m.addVisibleNodesRecursive(m.root, 0)
 }
 
+// markViewMatches records which nodes belong in the current view mode. A
+// directory is shown if it contains anything that is shown, or if it is
+// empty and matches itself. Entries hidden by H for their size are left out
+// in every mode.
+func (m *Model) markViewMatches(node *FileNode) bool {
+	if m.belowMinSize(node) {
+		return false
+	}
+	matches := false
+	if node.IsDir && len(node.Children) > 0 {
+		for _, child := range node.Children {
+			if m.markViewMatches(child) {
+				matches = true
+			}
+		}
+		// A big directory of small files still shows when only sizes filter
+		if m.viewMode == ViewAll && m.quick == nil {
+			matches = true
+		}
+	} else {
+		matches = m.stateMatchesView(node.Filter)
+		if m.quick != nil && matches {
+			matches = m.quick.quickMatches(node)
+			if matches && !node.IsDir {
+				m.quick.matches++
+			}
+		}
+	}
+	// A directory whose own state changed shows even if nothing in it did
+	if m.viewMode == ViewChanged && m.changedSinceLoad(node) {
+		matches = true
+	}
+	if matches {
+		m.viewMatches[node] = true
+	}
+	return matches
+}
+
+// stateMatchesView reports whether a node with the given state is shown in
+// the current view mode. Unmatched nodes are transferred by rclone, so they
+// count as included.
+func (m *Model) stateMatchesView(state FilterState) bool {
+	switch m.viewMode {
+	case ViewIncluded:
+		return state != FilterExclude
+	case ViewExcluded:
+		return state == FilterExclude
+	case ViewChanged:
+		// Decided per node by markViewMatches
+		return false
+	default:
+		return true
+	}
+}
+
+// refreshView rebuilds the visible nodes after filter changes when the view
+// mode depends on filter states
+func (m *Model) refreshView() {
+	if m.viewMode == ViewAll {
+		return
+	}
+	m.updateVisibleNodes()
+	if m.cursor >= len(m.visibleNodes) {
+		m.cursor = len(m.visibleNodes) - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+	m.adjustScroll()
+}
+
 func (m *Model) resortTree(node *FileNode) {
 	if node.IsDir && len(node.Children) > 0 {
 		m.sortChildren(node.Children)
@@ -598,52 +1170,122 @@ func (m *Model) resortTree(node *FileNode) {
 }
 
 func (m *Model) addVisibleNodesRecursive(node *FileNode, depth int) {
-	m.visibleNodes = append(m.visibleNodes, node)
+	if !m.isHiddenRoot(node) {
+		m.visibleNodes = append(m.visibleNodes, node)
+	}
 
 	if node.IsDir && node.Expanded {
-		node.mu.RLock()
 		children := node.Children
-		node.mu.RUnlock()
 		for _, child := range children {
+			if m.viewMatches != nil && !m.viewMatches[child] {
+				continue
+			}
 			m.addVisibleNodesRecursive(child, depth+1)
 		}
 	}
 }
 
 func (m Model) Init() tea.Cmd {
-	return tea.Tick(50*time.Millisecond, func(t time.Time) tea.Msg {
-		return refreshMsg{}
-	})
+	return tea.Batch(m.dispatchJobs(), refreshTick(), m.listSyncDest())
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
-	case loadingMsg:
-		m.loadProgress = msg.progress
-		atomic.StoreInt64(&m.scannedDirs, msg.dirs)
-		atomic.StoreInt64(&m.scannedFiles, msg.files)
+	case scanBatchMsg:
+		return m, m.applyScanBatch(msg)
+
+	case filesListedMsg:
+		m.applyFilesListed(msg)
+		return m, nil
+
+	case countTimeoutMsg:
+		m.applyCountTimeout(msg)
+		return m, nil
+
+	case typeAheadTimeoutMsg:
+		m.applyTypeAheadTimeout(msg)
+		return m, nil
+
+	case toastTimeoutMsg:
+		m.applyToastTimeout(msg)
+		return m, nil
+
+	case sizeDeltaTimeoutMsg:
+		m.applySizeDeltaTimeout(msg)
 		return m, nil
 
 	case treeReadyMsg:
-		m.loading = false
+		if msg.root != m.root {
+			// Completion of a scan that was superseded by a refresh
+			return m, nil
+		}
+		m.scanErr = msg.err
 		m.root = msg.root
+		// States of the replaced tree's nodes are of no further use
+		clear(m.baselineStates)
 		calculateStats(m.root)
+		m.issues = m.collectIssues()
+		if hint := m.permissionHint(); hint != "" && m.statusMsg == "" {
+			m.statusMsg = hint
+		}
 		m.updateVisibleNodes()
+		// Whatever the cursor reached is as close as the new tree gets
+		m.anchorPath = ""
+
+		// Rules can only be checked against a complete tree
+		if msg.err == nil {
+			m.checkRulesAfterScan()
+		}
+		m.startTourOnFirstRun()
 		return m, nil
 
-	case refreshMsg:
-		if m.loading {
-			return m, tea.Tick(50*time.Millisecond, func(t time.Time) tea.Msg {
-				return refreshMsg{}
-			})
+	case jobDoneMsg:
+		cmd, apply := m.finishJob(msg)
+		if !apply || msg.result.msg == nil {
+			return m, cmd
 		}
+		updated, resultCmd := m.Update(msg.result.msg)
+		return updated, tea.Batch(cmd, resultCmd)
+
+	case expandMsg:
+		return m, m.applyExpandStep(msg)
+
+	case filtersRecomputedMsg:
+		m.applyRecomputedFilters(msg)
+		return m, nil
+
+	case destListedMsg:
+		return m, m.applyDestListing(msg)
+
+	case localHashesMsg:
+		m.applyLocalHashes(msg)
+		return m, nil
+
+	case duplicatesFoundMsg:
+		m.applyDuplicates(msg)
 		return m, nil
 
+	case estimateReadyMsg:
+		return m, m.applyEstimate(msg)
+
+	case subtreeReadyMsg:
+		m.issues = m.collectIssues()
+		if m.clearEstimates(msg.node) && msg.err == nil {
+			m.checkRulesAfterScan()
+			m.startTourOnFirstRun()
+		}
+		m.updateVisibleNodes()
+		return m, nil
+
+	case refreshMsg:
+		titleCmd := m.updateWindowTitle()
+		if running, queued := m.activeJobs(); running+queued > 0 {
+			return m, tea.Batch(titleCmd, refreshTick())
+		}
+		return m, titleCmd
+
 	case refreshDirMsg:
-		m.refreshDirectory()
-		return m, tea.Tick(50*time.Millisecond, func(t time.Time) tea.Msg {
-			return refreshMsg{}
-		})
+		return m, tea.Batch(m.refreshDirectory(), refreshTick())
 
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
@@ -651,30 +1293,49 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case tea.KeyMsg:
-		if m.showHelp {
-			m.showHelp = false
-			return m, nil
+		if m.topModal() != modalNone {
+			return m.updateModal(msg)
+		}
+
+		// Notifications last until the next key, pending toggles until any
+		// key other than the confirming one
+		if m.pendingToggle == nil || msg.String() != m.pendingToggleKey {
+			m.pendingToggle = nil
+			m.statusMsg = ""
+		}
+		if msg.String() != "s" {
+			m.pendingSave = false
 		}
+		// Once the user moves on, a refresh no longer restores the old place
+		m.anchorPath = ""
 
-		if m.showSaveConfirm {
+		if m.remapping != nil {
 			switch msg.String() {
-			case "y", "Y":
-				saveFilterFile(m.filterFile, m.filterRules, m.filterMap)
-				m.cancel()
-				return m, tea.Quit
-			case "n", "N":
-				m.cancel()
-				return m, tea.Quit
-			case "c", "C", "escape":
-				m.showSaveConfirm = false
+			case "enter":
+				if m.cursor >= 0 && m.cursor < len(m.visibleNodes) {
+					m.finishRemap(m.visibleNodes[m.cursor])
+				}
+				return m, nil
+			case "esc":
+				m.remapping = nil
+				m.remapAll = false
+				m.setModal(modalTriage, len(m.staleRules) > 0)
 				return m, nil
 			}
-			return m, nil
+		}
+
+		if m.scanning() && msg.String() == "x" {
+			m.cancelScans()
+			return m, m.dispatchJobs()
+		}
+
+		if used, cmd := m.handleNavKey(msg.String()); used {
+			return m, cmd
 		}
 
 		switch msg.String() {
 		case "q":
-			m.showSaveConfirm = true
+			m.openModal(modalSaveConfirm)
 			return m, nil
 
 		case "ctrl+c":
@@ -682,25 +1343,18 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, tea.Quit
 
 		case "s":
-			saveFilterFile(m.filterFile, m.filterRules, m.filterMap)
-			return m, nil
-
-		case "?", "h":
-			m.showHelp = true
-			return m, nil
-
-		case "up", "k":
-			if m.cursor > 0 {
-				m.cursor--
-				m.adjustScroll()
+			if warning := m.saveWarnings(); warning != "" && !m.pendingSave {
+				m.pendingSave = true
+				m.statusMsg = "Warning: " + warning + " - press s again to save anyway"
+				return m, nil
 			}
-			return m, nil
+			return m, m.startSave()
 
-		case "down", "j":
-			if m.cursor < len(m.visibleNodes)-1 {
-				m.cursor++
-				m.adjustScroll()
-			}
+		case "w":
+			return m, m.quickSave()
+
+		case "?", "h":
+			m.openModal(modalHelp)
 			return m, nil
 
 		case "left":
@@ -723,113 +1377,425 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			return m, nil
 
-		case "right", "enter":
-			if m.cursor >= 0 && m.cursor < len(m.visibleNodes) {
-				node := m.visibleNodes[m.cursor]
-				if node.IsDir && !node.Expanded {
-					node.Expanded = true
-					m.updateVisibleNodes()
-				}
-			}
+		case "right", "enter":
+			if m.cursor >= 0 && m.cursor < len(m.visibleNodes) {
+				node := m.visibleNodes[m.cursor]
+				if node.IsDir && !node.Expanded {
+					return m, m.expandNode(m.cursor)
+				}
+			}
+			return m, nil
+
+		case "+":
+			if dir := m.selectedDir(); dir != nil {
+				return m, m.expandTree([]*FileNode{dir}, expandAll)
+			}
+			return m, nil
+
+		case "-":
+			if dir := m.selectedDir(); dir != nil {
+				m.collapseTree([]*FileNode{dir})
+			}
+			return m, nil
+
+		case ">":
+			if m.root != nil {
+				return m, m.expandTree(m.topLevelNodes(), expandAll)
+			}
+			return m, nil
+
+		case "<":
+			// The roots stay open with their first level showing
+			if m.root != nil {
+				return m, m.expandTree(m.topLevelNodes(), 1)
+			}
+			return m, nil
+
+		case "*":
+			if m.root != nil {
+				input := ""
+				m.quickInput = &input
+				m.openModal(modalQuickPrompt)
+			}
+			return m, nil
+
+		case "esc":
+			m.clearQuickFilter()
+			return m, nil
+
+		case "L":
+			if m.root != nil {
+				input := ""
+				m.depthInput = &input
+				m.openModal(modalDepthPrompt)
+			}
+			return m, nil
+
+		case " ":
+			if m.cursor >= 0 && m.cursor < len(m.visibleNodes) {
+				node := m.visibleNodes[m.cursor]
+				if !m.confirmChange(node, (node.Filter+1)%3, " ") {
+					return m, nil
+				}
+				m.toggleNode(node)
+				m.lastAction = &ruleAction{state: node.Filter}
+				m.refreshView()
+				m.warnEscapedPattern(node)
+				m.warnIncludedSize()
+			}
+			return m, m.sizeDeltaTimeout()
+
+		case ".":
+			m.repeatLastAction()
+			return m, m.sizeDeltaTimeout()
+
+		case "i":
+			if m.filesFrom != nil {
+				m.statusMsg = "Invert is not available when editing a file list"
+				return m, nil
+			}
+			if m.guardChange(m.invertSelection) {
+				m.refreshView()
+			}
+			return m, nil
+
+		case "r":
+			m.resetFilters()
+			m.refreshView()
+			return m, nil
+
+		case "v":
+			m.viewMode = (m.viewMode + 1) % 3
+			var selected *FileNode
+			if m.cursor >= 0 && m.cursor < len(m.visibleNodes) {
+				selected = m.visibleNodes[m.cursor]
+			}
+			m.updateVisibleNodes()
+			m.cursor = 0
+			if i := m.indexOfVisible(selected); i >= 0 {
+				m.cursor = i
+			}
+			m.adjustScroll()
+			return m, nil
+
+		case "b":
+			m.toggleChangesView()
+			return m, nil
+
+		case "p":
+			m.openRules()
+			return m, nil
+
+		case "y":
+			m.showFileTypes = !m.showFileTypes
+			return m, nil
+
+		case "m":
+			m.setMixedSort(!m.mixedSort)
+			return m, nil
+
+		case "o":
+			m.openSortMenu()
+			return m, nil
+
+		case "f5", "ctrl+r":
+			return m, func() tea.Msg {
+				return refreshDirMsg{}
+			}
+
+		case "F":
+			// Force a full refresh, bypassing the remote listing cache
+			if m.remoteCache != nil {
+				m.remoteCache.InvalidateAll()
+			}
+			return m, func() tea.Msg {
+				return refreshDirMsg{}
+			}
+
+		case "R":
+			if m.scanning() || m.cursor < 0 || m.cursor >= len(m.visibleNodes) {
+				return m, nil
+			}
+			return m, tea.Batch(m.rescanSubtree(m.visibleNodes[m.cursor]), refreshTick())
+
+		case "E":
+			return m, tea.Batch(m.enqueue(m.recomputeJob()), refreshTick())
+
+		case "d":
+			return m, tea.Batch(m.enqueue(m.dryRunJob()), refreshTick())
+
+		case "D":
+			if m.duplicates != nil {
+				m.openModal(modalDuplicates)
+				return m, nil
+			}
+			return m, tea.Batch(m.enqueue(m.duplicatesJob()), refreshTick())
+
+		case "P":
+			m.saveCurrentSnapshot()
+			return m, nil
+
+		case "W":
+			m.openModal(modalIssues)
+			m.issueCursor = 0
+			return m, nil
+
+		case "t":
+			m.openTemplates()
+			return m, nil
+
+		case "M":
+			if m.metadata == nil {
+				m.statusMsg = "No metadata filter, start with --metadata-file FILE"
+				return m, nil
+			}
+			m.openModal(modalMetadata)
+			m.metadataCursor = 0
+			return m, nil
+
+		case "H":
+			m.toggleHideSmall()
+			return m, nil
+
+		case "e":
+			m.toggleRuleColumn()
+			return m, nil
+
+		case "U":
+			m.toggleUnits()
+			return m, nil
+
+		case "c":
+			m.startNodeNote()
+			return m, nil
+		case "K":
+			m.togglePin()
 			return m, nil
 
-		case " ":
-			if m.cursor >= 0 && m.cursor < len(m.visibleNodes) {
-				node := m.visibleNodes[m.cursor]
-				node.Filter = (node.Filter + 1) % 3
+		case "O":
+			m.cycleHeatmap()
+			return m, nil
 
-				// Create the appropriate filter pattern
-				filterPath := getFilterPath(node.Path)
-				if node.IsDir {
-					// For directories, use /** to exclude the directory and all its contents
-					filterPath = strings.TrimSuffix(filterPath, "/") + "/**"
-				}
+		case "A":
+			m.toggleDeletions()
+			return m, nil
+
+		case "S":
+			m.openSuggestions()
+			return m, nil
 
-				// Normalize pattern to match original filter file format (without leading slash)
-				filterPath = strings.TrimPrefix(filterPath, "/")
+		case "f":
+			return m, m.startTypeAhead()
 
-				m.filterMapMu.Lock()
-				m.filterMap[filterPath] = node.Filter
-				if node.Filter == FilterNone {
-					delete(m.filterMap, filterPath)
-				}
-				m.filterMapMu.Unlock()
+		case "a":
+			m.startAgePrompt()
+			return m, nil
 
-				// Update children's filter status if this is a directory
-				if node.IsDir {
-					m.updateChildrenFilters(node)
-				}
-			}
+		case "I":
+			m.openPathImport()
 			return m, nil
 
-		case "i":
-			m.invertSelection()
+		case "[":
+			m.setCheckers(m.checkers - 1)
 			return m, nil
 
-		case "r":
-			m.resetFilters()
+		case "]":
+			m.setCheckers(m.checkers + 1)
 			return m, nil
 
-		case "1":
-			m.sortMode = SortByName
-			if m.root != nil {
-				m.resortTree(m.root)
-				m.updateVisibleNodes()
-			}
+		case "X":
+			m.openModal(modalCommand)
 			return m, nil
 
-		case "2":
-			m.sortMode = SortBySize
-			if m.root != nil {
-				m.resortTree(m.root)
-				m.updateVisibleNodes()
-			}
+		case "l":
+			m.openSurvivors()
 			return m, nil
 
-		case "3":
-			m.sortMode = SortByFileCount
-			if m.root != nil {
-				m.resortTree(m.root)
-				m.updateVisibleNodes()
+		case "n":
+			m.openExtensionMenu()
+			return m, nil
+
+		case "=":
+			m.excludeIdentical()
+			return m, nil
+
+		case "Z":
+			if m.filesFrom != nil {
+				m.statusMsg = "Empty directories can't be excluded with --files-from"
+				return m, nil
 			}
+			m.openEmptyDirs()
 			return m, nil
 
-		case "4":
-			m.sortMode = SortByLastModified
-			if m.root != nil {
-				m.resortTree(m.root)
-				m.updateVisibleNodes()
+		case "C":
+			if m.compareTo == nil {
+				m.statusMsg = "No snapshot to compare with, start with --compare NAME"
+				return m, nil
 			}
+			m.openModal(modalGrowth)
+			m.growthCursor = 0
 			return m, nil
 
-		case "f5", "ctrl+r":
-			return m, func() tea.Msg {
-				return refreshDirMsg{}
+		case "T":
+			if m.filesFrom == nil {
+				m.openTriage()
 			}
+			return m, nil
+
+		case "J":
+			m.openModal(modalJobs)
+			m.jobCursor = len(m.jobs) - 1
+			m.clampJobCursor()
+			return m, nil
 		}
 	}
 
 	return m, nil
 }
 
-func (m *Model) adjustScroll() {
-	visibleHeight := m.height - 4
-	if visibleHeight <= 0 {
-		visibleHeight = 20
+// indexOfVisible returns the index of node in visibleNodes, or -1
+func (m *Model) indexOfVisible(node *FileNode) int {
+	for i, n := range m.visibleNodes {
+		if n == node {
+			return i
+		}
+	}
+	return -1
+}
+
+// toggleNode advances the node's filter state (none → include → exclude) and
+// records the matching rule in the filter document
+func (m *Model) toggleNode(node *FileNode) {
+	if m.filesFrom != nil {
+		m.toggleListEntry(node)
+		return
+	}
+	m.applyChange(node, (node.Filter+1)%3)
+}
+
+// setNodeFilter gives node the filter state and records the matching rule in
+// the filter document. With a --files-from list, only FilterInclude lists the node.
+func (m *Model) setNodeFilter(node *FileNode, state FilterState) {
+	if m.filesFrom != nil {
+		if (node.Filter == FilterInclude) != (state == FilterInclude) {
+			m.toggleListEntry(node)
+		}
+		return
+	}
+	m.filterGen++
+	m.activateRootFor(node)
+	node.Filter = state
+
+	m.setRule(nodeRulePattern(node), node.Filter)
+
+	// Update children's filter status if this is a directory
+	if node.IsDir {
+		m.updateChildrenFilters(node)
+	}
+}
+
+// nodeRulePattern returns the rule pattern generated for a node, escaped so
+// that it matches only the node's path
+func nodeRulePattern(node *FileNode) string {
+	// Normalize pattern to match original filter file format (without leading slash)
+	pattern := escapeGlob(strings.TrimPrefix(strings.TrimSuffix(getFilterPath(node.Path), "/"), "/"))
+	if node.IsDir {
+		// For directories, use /** to exclude the directory and all its contents
+		pattern += "/**"
+	}
+	return pattern
+}
+
+// toggleImpact summarizes how many files below a directory change state
+type toggleImpact struct {
+	excludedFiles int
+	excludedSize  int64
+	includedFiles int
+	includedSize  int64
+}
+
+func (t toggleImpact) files() int {
+	return t.excludedFiles + t.includedFiles
+}
+
+func (t toggleImpact) String() string {
+	var parts []string
+	if t.excludedFiles > 0 {
+		parts = append(parts, fmt.Sprintf("excludes %s files / %s", formatCount(t.excludedFiles), formatSize(t.excludedSize)))
+	}
+	if t.includedFiles > 0 {
+		parts = append(parts, fmt.Sprintf("includes %s files / %s", formatCount(t.includedFiles), formatSize(t.includedSize)))
+	}
+	if len(parts) == 0 {
+		return "This changes no files"
+	}
+	return "This " + strings.Join(parts, " and ")
+}
+
+// previewChange computes the effect giving node newState would have on the
+// files below it, or on the file itself, without changing anything
+func (m *Model) previewChange(node *FileNode, newState FilterState) toggleImpact {
+	m.activateRootFor(node)
+	var impact toggleImpact
+	m.tryRule(nodeRulePattern(node), newState, func() {
+		if node.IsDir {
+			m.collectToggleImpact(node, &impact)
+		} else {
+			m.addFileImpact(node, &impact)
+		}
+	})
+	return impact
+}
+
+func (m *Model) collectToggleImpact(node *FileNode, impact *toggleImpact) {
+	for _, child := range node.Children {
+		if child.IsDir {
+			m.collectToggleImpact(child, impact)
+			continue
+		}
+		m.addFileImpact(child, impact)
+	}
+}
+
+// addFileImpact counts file in impact if the rules now give it another state
+func (m *Model) addFileImpact(file *FileNode, impact *toggleImpact) {
+	before := file.Filter == FilterExclude
+	after := m.getEffectiveFilterWithMap(getFilterPath(file.Path)) == FilterExclude
+	switch {
+	case after && !before:
+		impact.excludedFiles++
+		impact.excludedSize += file.Size
+	case before && !after:
+		impact.includedFiles++
+		impact.includedSize += file.Size
 	}
+}
+
+func (m *Model) adjustScroll() {
+	visibleHeight := m.listHeight()
 
 	if m.cursor < m.scrollOffset {
 		m.scrollOffset = m.cursor
-	} else if m.cursor >= m.scrollOffset+visibleHeight {
+	}
+	// Scrolling further down can pin more parents, taking rows from the list
+	for m.cursor >= m.scrollOffset+visibleHeight {
 		m.scrollOffset = m.cursor - visibleHeight + 1
+		visibleHeight = m.listHeight()
 	}
 }
 
 func (m *Model) invertSelection() {
+	m.filterGen++
+	m.finishExpand()
+
 	// Collect directories that changed so we can update their children
 	var changedDirs []*FileNode
 
 	for _, node := range m.visibleNodes {
+		if m.isHiddenRoot(node) {
+			continue
+		}
+		m.activateRootFor(node)
 		switch node.Filter {
 		case FilterNone:
 			continue
@@ -840,35 +1806,41 @@ func (m *Model) invertSelection() {
 		}
 
 		// Create the appropriate filter pattern
-		filterPath := getFilterPath(node.Path)
+		filterPath := "/" + escapeGlob(strings.TrimPrefix(getFilterPath(node.Path), "/"))
 		if node.IsDir {
 			// For directories, use /** to exclude the directory and all its contents
 			filterPath = strings.TrimSuffix(filterPath, "/") + "/**"
 			changedDirs = append(changedDirs, node)
 		}
 
-		m.filterMapMu.Lock()
-		if node.Filter == FilterNone {
-			delete(m.filterMap, filterPath)
-		} else {
-			m.filterMap[filterPath] = node.Filter
-		}
-		m.filterMapMu.Unlock()
+		m.setRule(filterPath, node.Filter)
 	}
 
-	// Pattern cache updates would go here in production
-
 	// Update children of all changed directories
 	for _, dir := range changedDirs {
+		m.activateRootFor(dir)
 		m.updateChildrenFilters(dir)
 	}
 }
 
 func (m *Model) resetFilters() {
+	m.filterGen++
+	m.finishExpand()
 	for _, node := range m.visibleNodes {
 		node.Filter = FilterNone
 	}
-	m.filterMap = make(map[string]FilterState)
+	// Clear in place, the documents are shared with the session roots
+	if m.filters == nil {
+		m.filters = newFilterDocument(nil)
+	}
+	m.filters.clearRules()
+	for _, r := range m.roots {
+		r.filters.clearRules()
+	}
+	m.clearFilterCaches()
+	if m.filesFrom != nil {
+		m.filesFrom.clear()
+	}
 }
 
 // updateChildrenFilters recursively updates the filter status of all children
@@ -889,12 +1861,10 @@ func (m *Model) updateChildrenRecursive(node *FileNode) {
 	}
 
 	// Update all direct children
-	node.mu.RLock()
 	children := node.Children
-	node.mu.RUnlock()
 
 	for _, child := range children {
-		// Update child's filter based on current filterMap and rules
+		// Update child's filter based on the current rules
 		childFilterPath := getFilterPath(child.Path)
 		child.Filter = m.getEffectiveFilterWithMap(childFilterPath)
 
@@ -917,9 +1887,7 @@ func (m *Model) reapplyFiltersToTree(node *FileNode) {
 
 	// If this is a directory, recurse to all children
 	if node.IsDir {
-		node.mu.RLock()
 		children := node.Children
-		node.mu.RUnlock()
 
 		for _, child := range children {
 			m.reapplyFiltersToTree(child)
@@ -928,88 +1896,158 @@ func (m *Model) reapplyFiltersToTree(node *FileNode) {
 }
 
 // getEffectiveFilterWithMap determines the effective filter state for a path
-// considering both the original filterRules and the current filterMap changes
+// under the current rules
 func (m *Model) getEffectiveFilterWithMap(path string) FilterState {
-	// FIXED: Check for more specific patterns in filterMap FIRST
-	// This ensures user's new patterns override existing ones correctly
-
-	var bestMatch string
-	var bestState FilterState = FilterNone
-	var foundMatch bool
-
-	// First, check all patterns in filterMap (including new user patterns)
-	m.filterMapMu.RLock()
-	for pattern, state := range m.filterMap {
-		if pattern == path || matchesRclonePattern(pattern, path) {
-			// If this is a more specific match, use it
-			if !foundMatch || len(pattern) > len(bestMatch) {
-				bestMatch = pattern
-				bestState = state
-				foundMatch = true
-			}
-		}
-	}
-	m.filterMapMu.RUnlock()
+	_, state := m.matchingRule(path)
+	return state
+}
 
-	// If we found a match in filterMap, return it
-	if foundMatch {
-		return bestState
+// matchingRule returns the rule deciding the state of a path and that state,
+// or no pattern when no rule matches
+func (m *Model) matchingRule(path string) (string, FilterState) {
+	if m.filesFrom != nil {
+		return "", m.filesFrom.state(path)
 	}
 
-	// Fallback: check original rules for patterns not in filterMap
-	for _, rule := range m.filterRules {
-		if rule.Pattern == path || matchesRclonePattern(rule.Pattern, path) {
-			// Only use this if it's not already handled by filterMap
-			m.filterMapMu.RLock()
-			_, exists := m.filterMap[rule.Pattern]
-			m.filterMapMu.RUnlock()
-			if !exists {
-				return rule.State
-			}
-		}
+	if m.filterCache == nil {
+		match := m.evaluateRules(path)
+		return match.pattern, match.state
+	}
+	match, ok := m.filterCache.entries[path]
+	if !ok {
+		match = m.evaluateRules(path)
+		m.filterCache.entries[path] = match
 	}
+	return match.pattern, match.state
+}
 
-	return FilterNone
+// evaluateRules finds the rule deciding the state of a path, going through
+// the rules in order as rclone does
+func (m *Model) evaluateRules(path string) cachedMatch {
+	pattern, state := m.filters.firstMatch(path)
+	return cachedMatch{pattern: pattern, state: state}
 }
 
 func (m Model) View() string {
-	if m.showHelp {
-		return m.renderHelp()
+	if asciiOnly {
+		return toASCII(m.view())
 	}
+	return m.view()
+}
 
-	if m.showSaveConfirm {
-		return m.renderSaveConfirm()
+func (m Model) view() string {
+	switch top := m.topModal(); {
+	case top.isPrompt() && len(m.modals) > 1:
+		// A prompt opened from a pane is typed below it
+		m.height--
+		return m.renderModal(m.modals[len(m.modals)-2]) + "\n" +
+			lipgloss.NewStyle().Foreground(currentTheme.Warning).Render(m.promptText())
+	case top != modalNone && !top.isPrompt():
+		return m.renderModal(top)
 	}
 
-	if m.loading {
+	if m.scanning() {
 		return m.renderLoading()
 	}
 
 	var b strings.Builder
 
-	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12"))
-	b.WriteString(headerStyle.Render("RClone Filter Editor"))
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(currentTheme.Header)
+	title := appTitle
+	if m.filesFrom != nil {
+		title += " - editing file list " + m.filesFrom.path
+	}
+	if m.readOnly {
+		title += " (read-only)"
+	}
+	b.WriteString(headerStyle.Render(title))
 	b.WriteString("\n")
 
-	var sortText string
-	switch m.sortMode {
-	case SortByName:
-		sortText = "Sort: Name (1)"
-	case SortBySize:
-		sortText = "Sort: Size (2)"
-	case SortByFileCount:
-		sortText = "Sort: File Count (3)"
-	case SortByLastModified:
-		sortText = "Sort: Last Modified (4)"
+	sortText := "Sort: " + m.sortLabel() + " (o)"
+	if m.mixedSort {
+		sortText += ", mixed (m)"
+	}
+
+	switch m.viewMode {
+	case ViewIncluded:
+		sortText += " | View: Included only (v)"
+	case ViewExcluded:
+		sortText += " | View: Excluded only (v)"
+	case ViewChanged:
+		sortText += " | View: Changed since load (b)"
+	}
+
+	if m.hideSmall {
+		sortText += fmt.Sprintf(" | Hiding < %s (H)", formatSize(m.minSize))
+	}
+
+	if m.heat != nil && m.heat.mode != heatOff {
+		sortText += " | Heat: " + heatModeNames[m.heat.mode] + " (O)"
+	}
+	sortText += m.quickHeader()
+	sortText += m.deletionHeader()
+	sortText += m.sinceHeader()
+	if m.syncDest != "" && m.mirror == nil {
+		sortText += " | Listing " + m.syncDest + "..."
+	}
+
+	if m.expanding != nil {
+		sortText += " | Expanding..."
 	}
 
-	b.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("8")).Render("Press ? for help, s to save, q to quit | " + sortText))
-	b.WriteString("\n\n")
+	if m.metadata != nil {
+		sortText += fmt.Sprintf(" | %d metadata rules (M)", len(m.metadata.rules))
+	}
+
+	if n := m.pendingIssues(); n > 0 {
+		sortText += fmt.Sprintf(" | ⚠ %d unreadable (W)", n)
+	}
+
+	if m.expanding != nil {
+		sortText += " | Expanding..."
+	}
+
+	if running, queued := m.activeJobs(); running+queued > 0 {
+		sortText += fmt.Sprintf(" | Jobs: %d running, %d queued (J)", running, queued)
+		if rate := m.rescanRate(); rate != "" {
+			sortText += ", " + rate
+		}
+	}
 
-	visibleHeight := m.height - 4
-	if visibleHeight <= 0 {
-		visibleHeight = 20
+	b.WriteString(lipgloss.NewStyle().Foreground(currentTheme.Muted).Render("Press ? for help, s to save, q to quit" + m.sizeDeltaHeader() + " | " + sortText))
+	b.WriteString("\n")
+	status := m.statusMsg
+	if m.topModal().isPrompt() {
+		status = m.promptText()
+	}
+	if status == "" && m.remapping != nil {
+		if m.remapAll {
+			status = fmt.Sprintf("Choose the folder %s was renamed to and press Enter, Esc cancels", m.remapping.anchor)
+		} else {
+			status = fmt.Sprintf("Choose the new location of %s and press Enter, Esc cancels", m.remapping.anchor)
+		}
+	}
+	statusStyle := lipgloss.NewStyle().Foreground(currentTheme.Warning)
+	if status == "" && m.saveErr != nil {
+		status = "Save failed: " + m.saveErr.Error() + " - w or s tries again"
+		statusStyle = statusStyle.Bold(true).Foreground(currentTheme.Exclude)
+	}
+	if status == "" && m.toast != nil {
+		status = "✓ " + m.toast.text
+		statusStyle = statusStyle.Foreground(currentTheme.Include)
+	}
+	if status == "" {
+		status = m.selectedNote()
+		statusStyle = statusStyle.Foreground(currentTheme.Header)
+	}
+	if status != "" {
+		b.WriteString(statusStyle.Render(status))
 	}
+	b.WriteString("\n")
+
+	sticky := m.stickyParents()
+	b.WriteString(m.renderSticky(sticky))
+	visibleHeight := m.screenRows() - len(sticky)
 
 	start := m.scrollOffset
 	end := start + visibleHeight
@@ -1017,17 +2055,22 @@ func (m Model) View() string {
 		end = len(m.visibleNodes)
 	}
 
+	var guides *treeGuides
+	if m.showGuides {
+		guides = m.newTreeGuides()
+	}
+	var rows []renderedRow
 	for i := start; i < end; i++ {
 		node := m.visibleNodes[i]
-		depth := getNodeDepth(node)
 
-		prefix := strings.Repeat("  ", depth)
+		prefix := strings.Repeat("  ", getNodeDepth(node))
+		if guides != nil {
+			prefix = guides.prefix(node)
+		}
 
 		var icon string
 		if node.IsDir {
-			node.mu.RLock()
 			isLoading := node.Loading
-			node.mu.RUnlock()
 			if isLoading {
 				icon = "⟳ "
 			} else if node.Expanded {
@@ -1039,42 +2082,65 @@ func (m Model) View() string {
 			icon = "  "
 		}
 
-		var filterIcon string
-		filterStyle := lipgloss.NewStyle()
-		switch node.Filter {
-		case FilterNone:
-			filterIcon = "[ ]"
-			filterStyle = filterStyle.Foreground(lipgloss.Color("8"))
-		case FilterInclude:
-			filterIcon = "[+]"
-			filterStyle = filterStyle.Foreground(lipgloss.Color("10"))
-		case FilterExclude:
-			filterIcon = "[-]"
-			filterStyle = filterStyle.Foreground(lipgloss.Color("9"))
-		}
+		filterIcon, filterStyle := stateStyle(node.Filter)
 
 		nameStyle := lipgloss.NewStyle()
 		if i == m.cursor {
-			nameStyle = nameStyle.Background(lipgloss.Color("8")).Foreground(lipgloss.Color("15"))
+			nameStyle = nameStyle.Background(currentTheme.CursorBg).Foreground(currentTheme.CursorFg)
 		}
 
-		line := fmt.Sprintf("%s%s%s %s", prefix, icon, filterStyle.Render(filterIcon), node.Name)
+		line := fmt.Sprintf("%s%s%s %s", prefix, icon, filterStyle.Render(filterIcon), m.heatName(node))
+		if m.sizeBars {
+			line = lipgloss.NewStyle().Foreground(currentTheme.Muted).Render(m.rowBar(node)) + " " + line
+		}
 
 		var stats string
-		if node.IsDir {
+		if node.Skipped {
+			stats = " (not scanned, R scans it)"
+		} else if node.IsDir {
 			stats = fmt.Sprintf(" (%s, %d files)", formatSize(node.TotalSize), node.TotalFiles)
+			if types := node.Types.breakdown(); m.showFileTypes && types != "" {
+				stats = fmt.Sprintf(" (%s, %d files: %s)", formatSize(node.TotalSize), node.TotalFiles, types)
+			}
+			if est, ok := m.estimateFor(node); ok {
+				stats = fmt.Sprintf(" (~%s, ~%d files)", formatSize(est.size), est.files)
+			}
+			if unlisted := unlistedStats(node); unlisted != "" {
+				stats = unlisted
+			}
+			if growth := m.growthLabel(node); growth != "" {
+				stats += " " + growth
+			}
 		} else {
 			stats = fmt.Sprintf(" (%s)", formatSize(node.Size))
+			if details := fileDetails(node); m.showDetails && details != "" {
+				stats = fmt.Sprintf(" (%s, %s)", formatSize(node.Size), details)
+			}
+			if m.mirror != nil {
+				stats += " " + m.mirror.compare(node).label(m.mirror.name())
+			}
+			if m.duplicateOf[node] {
+				stats += " duplicate"
+			}
+		}
+		stats += linkLabel(node)
+		stats += m.deletionLabel(node)
+		stats += m.sinceLabel(node)
+		stats += issueMarker(node)
+		stats += m.pinLabel(node)
+		stats += m.noteLabel(node)
+		if m.viewMode == ViewChanged && m.changedSinceLoad(node) {
+			was, _ := stateStyle(m.baselineState(node))
+			stats += " was " + was
 		}
 
-		if i == m.cursor {
-			b.WriteString(nameStyle.Render(line + stats))
-		} else {
-			b.WriteString(line)
-			b.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("8")).Render(stats))
+		rows = append(rows, renderedRow{line: line, stats: stats, nameStyle: nameStyle, cursor: i == m.cursor})
+		if m.showRules {
+			row := &rows[len(rows)-1]
+			row.rule, row.ruleState = m.ruleLabel(node)
 		}
-		b.WriteString("\n")
 	}
+	m.writeRows(&b, rows)
 
 	return b.String()
 }
@@ -1082,43 +2148,89 @@ func (m Model) View() string {
 func (m Model) renderHelp() string {
 	helpStyle := lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("12")).
+		BorderForeground(currentTheme.Border).
 		Padding(1, 2)
 
 	help := `Keyboard Shortcuts:
 
 Navigation:
-  ↑/↓ or j/k  Navigate up/down
+  ↑/↓ or j/k  Navigate up/down, a count moves further (10j)
+  gg / G      Go to the top / bottom, or to line N with a count
+  f           Type the start of a name to jump to it
+  Ctrl+D/U    Move down/up half a page
+  PgDn/PgUp   Move down/up a page
+  + / -       Expand / collapse everything below the selected directory
+  > / <       Expand / collapse the whole tree
+  L           Expand the whole tree to a depth
   ←           Collapse directory or go to parent
   → or Enter  Expand directory
 
 Filters:
   Space       Toggle filter (none → include → exclude)
+  .           Repeat the last toggle on the selected item
   i           Invert selection
   r           Reset all filters
+  v           Cycle view: all / included only / excluded only
+  *           Show only the files matching a glob such as *.mkv, Esc shows all
+  b           Show only what changed since the rules were loaded
+  H           Hide entries smaller than min-size (10 MiB by default)
+  e           Show the rule deciding each row's state, e.g. ←- *
+  U           Switch sizes between binary (KiB) and decimal (kB) units
+  K           Pin the selected entry to the top of its directory, or unpin it
+  c           Write a note on the selected entry, kept next to the filter file
+  O           Color directories by file count, then size (heatmap), then not
+  A           Mark what --delete-excluded deletes on the --dest-listing
+  E           Recompute filter states for the whole tree
+  d           Dry-run the rules with rclone size
+  D           Find duplicate files and review them
+  T           Review rules referring to missing paths
+  S           Suggest wildcard patterns that could replace many rules
+  p           List the rules, Space disables or enables one, t adds one,
+              Enter folds a section, K/J move one, c writes a note above one,
+              / searches them by text, + or -, is:new or is:included
+  t           Add a rule from a template
+  W           List files and directories that can't be read
+  =           Exclude the files already identical on the --dest destination
+  Z           Exclude directories left empty by the exclusions
+  l           List the files the rules keep in a partly excluded directory
+  n           Exclude or include the files with the selected file's extension,
+              in its directory or anywhere
+  x           Cancel the scan while scanning
+  a           Leave out the files older (or newer) than an age, such as 1y,
+              with --max-age (--min-age) for the whole transfer
+  M           Edit the metadata filter rules
+  I           Exclude a pasted list of paths, or one read from a file
 
 Sorting:
-  1           Sort by filename (default)
-  2           Sort by size
-  3           Sort by file count
-  4           Sort by last modified
+  o           Choose the sort order: filename (default), size, file count
+              or last modified, ascending or descending
+  m           Toggle directories first / mixed with files
+  y           Show directory sizes by file type
 
 Other:
   ? or h      Show this help
   s           Save filters to file
+  w           Save without questions, the warnings shown in a toast
   F5/Ctrl+R   Refresh directory tree
+  R           Rescan selected directory only
+  F           Force refresh, bypassing remote cache
+  P           Save a snapshot of directory sizes
+  C           Show growth since the --compare snapshot
+  J           Show background jobs
+  [ / ]       Scan with fewer / more concurrent listings
+  X           Show the rclone command running the job, c copies it
   q           Quit (asks to save)
   Ctrl+C      Quit immediately without saving
 
-Press any key to close this help`
+Press g for a guided tour, Esc to close this help; other keys close it and run their command`
 
-	return helpStyle.Render(help)
+	return m.placePane(helpStyle, help)
 }
 
 func (m Model) renderSaveConfirm() string {
 	confirmStyle := lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("11")).
+		BorderForeground(currentTheme.Warning).
 		Padding(1, 2).
 		Width(50).
 		Align(lipgloss.Center)
@@ -1127,20 +2239,26 @@ func (m Model) renderSaveConfirm() string {
 
 [Y] Yes, save and quit
 [N] No, quit without saving  
-[C] Cancel and continue editing`, m.filterFile)
+[C] Cancel and continue editing`, m.filterFileNames())
+	if warning := m.saveWarnings(); warning != "" {
+		confirm = "Warning: " + warning + "\n\n" + confirm
+		if m.pendingSave {
+			confirm += "\n\nPress Y again to save anyway"
+		}
+	}
 
-	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, confirmStyle.Render(confirm))
+	return m.placePane(confirmStyle, confirm)
 }
 
 func (m Model) renderLoading() string {
 	loadingStyle := lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("12")).
+		BorderForeground(currentTheme.Border).
 		Padding(2, 4).
 		Align(lipgloss.Center)
 
 	spinner := "⟳"
-	switch (time.Now().UnixNano() / int64(200*time.Millisecond)) % 4 {
+	switch (m.clock().UnixNano() / int64(200*time.Millisecond)) % 4 {
 	case 0:
 		spinner = "▐"
 	case 1:
@@ -1151,18 +2269,31 @@ func (m Model) renderLoading() string {
 		spinner = "▄"
 	}
 
-	dirs := atomic.LoadInt64(&m.scannedDirs)
-	files := atomic.LoadInt64(&m.scannedFiles)
+	dirs := m.scannedDirs
+	files := m.scannedFiles
+
+	// New directories keep turning up, so the bar can move back as well
+	progress := ""
+	if m.foundDirs > 0 {
+		progress = fmt.Sprintf("\n%s %d%%\nListed %d of the %d directories found so far\n",
+			lipgloss.NewStyle().Foreground(currentTheme.Header).Render(sizeBar(dirs, m.foundDirs, progressBarWidth)),
+			min(dirs, m.foundDirs)*100/m.foundDirs, dirs, m.foundDirs)
+	}
+
+	rate := m.scanRate.String()
+	if rate != "" {
+		rate = "Throughput: " + rate + "\n"
+	}
 
 	loadingText := fmt.Sprintf(`%s Loading Directory Tree...
 
 %s
-Directories: %d
+%sDirectories: %d
 Files: %d
-Threads: %d
+%sThreads: %d ([ fewer, ] more)
 
-Press Ctrl+C to cancel`,
-		spinner, m.loadProgress, dirs, files, m.checkers)
+Press x to stop scanning, J for jobs, Ctrl+C to quit`,
+		spinner, m.loadProgress, progress, dirs, files, rate, m.checkers)
 
 	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, loadingStyle.Render(loadingText))
 }
@@ -1176,17 +2307,27 @@ func getNodeDepth(node *FileNode) int {
 	return depth
 }
 
-func formatSize(size int64) string {
-	const unit = 1024
-	if size < unit {
-		return fmt.Sprintf("%d B", size)
+// formatCount formats n with thousands separators
+func formatCount(n int) string {
+	digits := fmt.Sprintf("%d", n)
+	if n < 0 {
+		return "-" + formatCount(-n)
 	}
-	div, exp := int64(unit), 0
-	for n := size / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteString(currentNumbers.Separator)
+		}
+		b.WriteRune(d)
 	}
-	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+	return b.String()
+}
+
+// formatSize writes a size in the units of currentNumbers: 1024 based ones
+// as KiB, MiB..., or 1000 based ones as kB, MB.... With FixedWidth every size
+// takes as much room as the widest, such as "1023.9 KiB".
+func formatSize(size int64) string {
+	return rclonefilter.FormatSize(size, currentNumbers.Units == unitsDecimal, currentNumbers.FixedWidth)
 }
 
 // validatePath checks if a path is safe and within allowed boundaries
@@ -1236,11 +2377,17 @@ func validateFilterFilePath(filename string) error {
 var globalRootPath string
 
 func getFilterPath(path string) string {
+	// With several roots open, paths are relative to the one containing them
+	sessionRoot := rootPathFor(path)
+	if isRemotePath(sessionRoot) {
+		return remoteRelPath(sessionRoot, path)
+	}
+
 	// Use the root path that was provided to the program
 	absPath, _ := filepath.Abs(path)
 
 	// Use global root path if set, otherwise fall back to current working directory
-	rootPath := globalRootPath
+	rootPath := sessionRoot
 	if rootPath == "" {
 		wd, err := os.Getwd()
 		if err != nil {
@@ -1255,180 +2402,71 @@ func getFilterPath(path string) string {
 		rootPath, err = filepath.Abs(rootPath)
 		if err != nil {
 			// If we can't get absolute path, use as-is
-			rootPath = globalRootPath
+			rootPath = sessionRoot
 		}
 	}
 
 	rel, err := filepath.Rel(rootPath, absPath)
 	if err != nil {
-		return filepath.ToSlash(filepath.Base(path))
+		return rcloneLocalPath(filepath.ToSlash(filepath.Base(path)))
 	}
-	return "/" + filepath.ToSlash(rel)
+	return "/" + rcloneLocalPath(filepath.ToSlash(rel))
 }
 
 // matchesRclonePattern checks if a path matches an rclone filter pattern
 func matchesRclonePattern(pattern, path string) bool {
-	// Handle empty patterns
-	if pattern == "" {
-		return false
-	}
-
-	// Remove leading '/' from pattern if present for matching
-	cleanPattern := strings.TrimPrefix(pattern, "/")
-	cleanPath := strings.TrimPrefix(path, "/")
-
-	// Special handling for /** patterns - they should match the directory itself
-	// In rclone, "TV/**" matches both "TV" (the directory) and "TV/anything" (contents)
-	if strings.HasSuffix(cleanPattern, "/**") {
-		// Extract the directory part (everything before /**)
-		dirPattern := strings.TrimSuffix(cleanPattern, "/**")
-
-		// Check if the path exactly matches the directory
-		if cleanPath == dirPattern {
-			return true
-		}
+	return rclonefilter.Match(pattern, path)
+}
 
-		// Check if the path is inside the directory (starts with dirPattern/)
-		if strings.HasPrefix(cleanPath, dirPattern+"/") {
-			return true
-		}
+// getEffectiveFilter determines the effective filter state for a path
+// using rclone's "first match wins" semantics with proper order
+func getEffectiveFilter(path string, filterRules []FilterRule) FilterState {
+	if i := rclonefilter.FirstMatch(filterRules, FilterRule.appliedPattern, path); i >= 0 {
+		return filterRules[i].State
 	}
+	return FilterNone
+}
 
-	// Convert rclone pattern to regex for other patterns
-	regex := rclonePatternToRegex(cleanPattern)
-
-	// Compile and match regex
-	re, err := regexp.Compile("^" + regex + "$")
+func loadFilterFile(filename string) *FilterDocument {
+	doc, err := readFilterFile(filename)
 	if err != nil {
-		// Fallback to exact string match if regex compilation fails
-		return cleanPattern == cleanPath
+		fmt.Printf("Warning: %v\n", err)
 	}
-
-	return re.MatchString(cleanPath)
+	return doc
 }
 
-// rclonePatternToRegex converts an rclone filter pattern to a regex pattern
-func rclonePatternToRegex(pattern string) string {
-	var result strings.Builder
-
-	i := 0
-	for i < len(pattern) {
-		switch pattern[i] {
-		case '*':
-			if i+1 < len(pattern) && pattern[i+1] == '*' {
-				// ** matches everything including directory separators
-				if i+2 < len(pattern) && pattern[i+2] == '/' {
-					// **/ should match zero or more directories
-					result.WriteString("(?:.*/)?")
-					i += 3 // Skip the '**/'
-				} else if i+2 == len(pattern) {
-					// ** at end matches everything
-					result.WriteString(".*")
-					i += 2 // Skip both '*' characters
-				} else {
-					result.WriteString(".*")
-					i += 2 // Skip both '*' characters
-				}
-			} else {
-				// * matches any sequence except directory separators
-				result.WriteString("[^/]*")
-				i++
-			}
-
-		case '?':
-			// ? matches any single character except directory separator
-			result.WriteString("[^/]")
-			i++
-		case '[':
-			// Character class - find the closing ]
-			j := i + 1
-			for j < len(pattern) && pattern[j] != ']' {
-				j++
-			}
-			if j < len(pattern) {
-				// Found closing ], copy the character class
-				result.WriteString(pattern[i : j+1])
-				i = j + 1
-			} else {
-				// No closing ], treat as literal [
-				result.WriteString("\\[")
-				i++
-			}
-		case '{':
-			// Pattern alternatives like {*.txt,*.md}
-			j := i + 1
-			braceLevel := 1
-			for j < len(pattern) && braceLevel > 0 {
-				if pattern[j] == '{' {
-					braceLevel++
-				} else if pattern[j] == '}' {
-					braceLevel--
-				}
-				j++
-			}
-			if braceLevel == 0 {
-				// Found matching closing brace
-				alternatives := pattern[i+1 : j-1]
-				parts := strings.Split(alternatives, ",")
-				result.WriteString("(?:")
-				for idx, part := range parts {
-					if idx > 0 {
-						result.WriteString("|")
-					}
-					result.WriteString(rclonePatternToRegex(part))
-				}
-				result.WriteString(")")
-				i = j
-			} else {
-				// No matching closing brace, treat as literal {
-				result.WriteString("\\{")
-				i++
-			}
-		case '.', '^', '$', '+', '(', ')', '|', '\\':
-			// Escape regex special characters
-			result.WriteString("\\")
-			result.WriteByte(pattern[i])
-			i++
-		default:
-			result.WriteByte(pattern[i])
-			i++
-		}
-	}
-
-	return result.String()
+// readFilterFile loads the rules from filename. A missing file is not an
+// error since the editor creates it on save; anything else that prevents the
+// file from being read is returned to the caller.
+func readFilterFile(filename string) (*FilterDocument, error) {
+	doc, _, err := readFilterFileReport(filename)
+	return doc, err
 }
 
-// getEffectiveFilter determines the effective filter state for a path
-// using rclone's "first match wins" semantics with proper order
-func getEffectiveFilter(path string, filterRules []FilterRule) FilterState {
-	// Process rules in order - first match wins
-	var matchedState FilterState = FilterNone
-
-	for _, rule := range filterRules {
-		if rule.Pattern == path || matchesRclonePattern(rule.Pattern, path) {
-			matchedState = rule.State
-			break
+// readFilterFileReport loads the rules from filename like readFilterFile,
+// with the problems found in its lines
+func readFilterFileReport(filename string) (*FilterDocument, []filterDiagnostic, error) {
+	// "-" reads the rules from standard input
+	if filename == stdioFilterFile {
+		doc, diagnostics, err := parseFilterRulesReport(os.Stdin)
+		if err != nil {
+			return doc, diagnostics, err
 		}
+		doc.rules, err = expandIncludes(doc.rules, filename)
+		return doc, withFile(diagnostics, "standard input"), err
 	}
 
-	// The pattern matching logic now handles /** patterns correctly,
-	// so we don't need the UI enhancement anymore - just return the matched state
-	return matchedState
-}
-
-func loadFilterFile(filename string) ([]FilterRule, map[string]FilterState) {
-	var filterRules []FilterRule
-	filterMap := make(map[string]FilterState)
-
 	// Validate filter file path
 	if err := validateFilterFilePath(filename); err != nil {
-		fmt.Printf("Security warning: %v\n", err)
-		return filterRules, filterMap
+		return newFilterDocument(nil), nil, fmt.Errorf("security error: %v", err)
 	}
 
 	file, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return newFilterDocument(nil), nil, nil
+	}
 	if err != nil {
-		return filterRules, filterMap
+		return newFilterDocument(nil), nil, err
 	}
 	defer func() {
 		if closeErr := file.Close(); closeErr != nil {
@@ -1436,109 +2474,122 @@ func loadFilterFile(filename string) ([]FilterRule, map[string]FilterState) {
 		}
 	}()
 
-	scanner := bufio.NewScanner(file)
+	doc, diagnostics, err := parseFilterRulesReport(file)
+	if err != nil {
+		return doc, diagnostics, err
+	}
+	doc.rules, err = expandIncludes(doc.rules, filename)
+	return doc, withFile(diagnostics, filename), err
+}
+
+// parseFilterRules reads filter rules in rclone's filter file format
+func parseFilterRules(r io.Reader) (*FilterDocument, error) {
+	doc, _, err := parseFilterRulesReport(r)
+	return doc, err
+}
+
+// parseFilterRulesReport reads filter rules like parseFilterRules, and
+// reports the lines rclone would refuse or read differently than intended,
+// see checkFilterLine. Comment lines are kept with the line below them.
+func parseFilterRulesReport(r io.Reader) (*FilterDocument, []filterDiagnostic, error) {
+	var filterRules []FilterRule
+	var diagnostics []filterDiagnostic
+	seen := make(map[string]int)           // Line of the first rule for each pattern
+	states := make(map[string]FilterState) // State of the last rule for each pattern
+
+	section := ""
+	var comment []string
+	add := func(rule FilterRule) {
+		rule.Section = section
+		rule.Comment = strings.Join(comment, "\n")
+		comment = nil
+		filterRules = append(filterRules, rule)
+	}
+	lineNum := 0
+	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
+		lineNum++
 		line := strings.TrimSpace(scanner.Text())
-		if line == "" || strings.HasPrefix(line, "#") {
+		if name, ok := parseSectionHeader(line); ok {
+			section = name
+			continue
+		}
+		if name, ok := parseIncludeDirective(line); ok {
+			add(FilterRule{Include: name})
+			continue
+		}
+		if rule, ok := parseDisabledRule(line); ok {
+			add(rule)
+			continue
+		}
+		if strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			comment = append(comment, line)
+			continue
+		}
+		if line == "" {
 			continue
 		}
 
-		if strings.HasPrefix(line, "+ ") {
-			path := strings.TrimPrefix(line, "+ ")
-			filterRules = append(filterRules, FilterRule{Pattern: path, State: FilterInclude})
-			filterMap[path] = FilterInclude
-		} else if strings.HasPrefix(line, "- ") {
-			path := strings.TrimPrefix(line, "- ")
-			filterRules = append(filterRules, FilterRule{Pattern: path, State: FilterExclude})
-			filterMap[path] = FilterExclude
+		rule, diagnostic := checkFilterLine(line)
+		if diagnostic != nil {
+			diagnostic.line = lineNum
+			diagnostics = append(diagnostics, *diagnostic)
+		}
+		if diagnostic != nil && diagnostic.dropped {
+			continue
+		}
+		if first, ok := seen[rule.Pattern]; ok {
+			diagnostics = append(diagnostics, duplicateDiagnostic(lineNum, line, first, states[rule.Pattern] != rule.State))
+		} else {
+			seen[rule.Pattern] = lineNum
 		}
+		states[rule.Pattern] = rule.State
+		add(rule)
 	}
 
+	doc := newFilterDocument(filterRules)
 	if err := scanner.Err(); err != nil {
-		fmt.Printf("Warning: error reading filter file: %v\n", err)
+		return doc, diagnostics, fmt.Errorf("error reading filter file: %v", err)
 	}
-
-	return filterRules, filterMap
+	return doc, diagnostics, nil
 }
 
-func saveFilterFile(filename string, filterRules []FilterRule, filterMap map[string]FilterState) error {
+func saveFilterFile(filename string, doc *FilterDocument, order ruleOrder) error {
 	// Validate filter file path
 	if err := validateFilterFilePath(filename); err != nil {
 		return fmt.Errorf("security error: %v", err)
 	}
 
-	file, err := os.Create(filename)
-	if err != nil {
-		return err
-	}
-	defer func() {
-		if closeErr := file.Close(); closeErr != nil {
-			fmt.Printf("Warning: failed to close file: %v\n", closeErr)
-		}
-	}()
+	return writeFileAtomic(filename, func(w io.Writer) error {
+		return order.write(w, doc)
+	})
+}
 
-	writer := bufio.NewWriter(file)
-	writtenPaths := make(map[string]bool)
-
-	// Build list of new rules that need to be inserted
-	newRules := make(map[string]FilterState)
-	for path, state := range filterMap {
-		// Check if this path was in the original rules
-		found := false
-		for _, rule := range filterRules {
-			if rule.Pattern == path {
-				found = true
-				break
-			}
-		}
-		if !found {
-			newRules[path] = state
+// writeFilterRules writes the rules in filter file format in the order
+// given, each after its comment. Include directives are written as they are,
+// without the rules they pulled in.
+func writeFilterRules(w io.Writer, rules []FilterRule) error {
+	writer := bufio.NewWriter(w)
+
+	// Each line starts its section with a header when it is in another
+	// section than the line before
+	section, written := "", false
+	for _, rule := range rules {
+		if rule.Origin != "" {
+			continue
 		}
-	}
-
-	// Write rules in original order, inserting new rules at appropriate positions
-	for i, rule := range filterRules {
-		// Write existing rule if it still exists in filterMap
-		if currentState, exists := filterMap[rule.Pattern]; exists {
-			switch currentState {
-			case FilterInclude:
-				fmt.Fprintf(writer, "+ %s\n", rule.Pattern)
-			case FilterExclude:
-				fmt.Fprintf(writer, "- %s\n", rule.Pattern)
-			}
-			writtenPaths[rule.Pattern] = true
-		}
-
-		// After writing this rule, check if we should insert any new rules before the next rule
-		// Insert new rules that should come before more general patterns
-		if i+1 < len(filterRules) {
-			nextRule := filterRules[i+1]
-
-			// Insert new rules that are more specific than the next rule
-			for newPath, newState := range newRules {
-				if !writtenPaths[newPath] && shouldInsertBefore(newPath, nextRule.Pattern) {
-					switch newState {
-					case FilterInclude:
-						fmt.Fprintf(writer, "+ %s\n", newPath)
-					case FilterExclude:
-						fmt.Fprintf(writer, "- %s\n", newPath)
-					}
-					writtenPaths[newPath] = true
-				}
+		if rule.Section != section && rule.Section != "" {
+			if written {
+				fmt.Fprintln(writer)
 			}
+			fmt.Fprintln(writer, sectionHeader(rule.Section))
+			section = rule.Section
 		}
-	}
-
-	// Write any remaining new rules that weren't inserted above
-	for path, state := range newRules {
-		if !writtenPaths[path] {
-			switch state {
-			case FilterInclude:
-				fmt.Fprintf(writer, "+ %s\n", path)
-			case FilterExclude:
-				fmt.Fprintf(writer, "- %s\n", path)
-			}
+		if rule.Comment != "" {
+			fmt.Fprintln(writer, rule.Comment)
 		}
+		fmt.Fprintln(writer, rule.String())
+		written = true
 	}
 
 	if err := writer.Flush(); err != nil {
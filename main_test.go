@@ -1,30 +1,49 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
 	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
 )
 
 // newTestModel creates a properly initialized Model for testing
 func newTestModel() *Model {
 	return &Model{
-		filterMap:   make(map[string]FilterState),
-		filterMapMu: &sync.RWMutex{},
+		filters: newFilterDocument(nil),
 	}
 }
 
-// newTestModelWithFilterMap creates a Model with a pre-populated filter map
-func newTestModelWithFilterMap(filterMap map[string]FilterState) *Model {
+// newTestModelWithStates creates a Model with a rule for each pattern
+func newTestModelWithStates(states map[string]FilterState) *Model {
 	return &Model{
-		filterMap:   filterMap,
-		filterMapMu: &sync.RWMutex{},
+		filters: testDocument(nil, states),
 	}
 }
 
+// testDocument is the document of rules edited to give states, the rules
+// without a state removed and the others set in the order of their patterns
+func testDocument(rules []FilterRule, states map[string]FilterState) *FilterDocument {
+	doc := newFilterDocument(slices.Clone(rules))
+	for _, rule := range rules {
+		if _, ok := states[rule.Pattern]; !ok {
+			doc.remove(rule.Pattern)
+		}
+	}
+	for _, pattern := range slices.Sorted(maps.Keys(states)) {
+		doc.set(pattern, states[pattern])
+	}
+	return doc
+}
+
 func TestFilterStateOperations(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -54,11 +73,11 @@ func TestFormatSize(t *testing.T) {
 	}{
 		{0, "0 B"},
 		{512, "512 B"},
-		{1024, "1.0 KB"},
-		{1536, "1.5 KB"},
-		{1048576, "1.0 MB"},
-		{5242880, "5.0 MB"},
-		{1073741824, "1.0 GB"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{1048576, "1.0 MiB"},
+		{5242880, "5.0 MiB"},
+		{1073741824, "1.0 GiB"},
 	}
 
 	for _, tt := range tests {
@@ -100,12 +119,12 @@ func TestLoadAndSaveFilterFile(t *testing.T) {
 		"/exclude2.txt": FilterExclude,
 	}
 
-	err := saveFilterFile(tempFile, []FilterRule{}, originalMap)
+	err := saveFilterFile(tempFile, testDocument(nil, originalMap), ruleOrder{})
 	if err != nil {
 		t.Fatalf("Failed to save filter file: %v", err)
 	}
 
-	_, loadedMap := loadFilterFile(tempFile)
+	loadedMap := loadFilterFile(tempFile).states()
 
 	if len(loadedMap) != len(originalMap) {
 		t.Errorf("Loaded map has %d entries, expected %d", len(loadedMap), len(originalMap))
@@ -206,7 +225,7 @@ func TestInvertSelection(t *testing.T) {
 	model.visibleNodes = nodes
 	for _, node := range nodes {
 		if node.Filter != FilterNone {
-			model.filterMap[getFilterPath(node.Path)] = node.Filter
+			model.filters.set(getFilterPath(node.Path), node.Filter)
 		}
 	}
 
@@ -227,7 +246,7 @@ func TestInvertSelection(t *testing.T) {
 }
 
 func TestResetFilters(t *testing.T) {
-	model := newTestModelWithFilterMap(map[string]FilterState{
+	model := newTestModelWithStates(map[string]FilterState{
 		"/file1": FilterInclude,
 		"/file2": FilterExclude,
 	})
@@ -247,33 +266,8 @@ func TestResetFilters(t *testing.T) {
 		}
 	}
 
-	if len(model.filterMap) != 0 {
-		t.Errorf("Filter map should be empty after reset, but has %d entries", len(model.filterMap))
-	}
-}
-
-func TestRclonePatternToRegex(t *testing.T) {
-	tests := []struct {
-		pattern  string
-		expected string
-	}{
-		{"*.txt", "[^/]*\\.txt"},
-		{"**", ".*"},
-		{"**/logs", "(?:.*/)?logs"},
-		{"*.{txt,md}", "[^/]*\\.(?:txt|md)"},
-		{"file?.txt", "file[^/]\\.txt"},
-		{"[abc].txt", "[abc]\\.txt"},
-		{"dir/file.txt", "dir/file\\.txt"},
-		{"**/*.go", "(?:.*/)?[^/]*\\.go"},
-		{"{dir1,dir2}/**", "(?:dir1|dir2)/.*"},
-		{"test*", "test[^/]*"},
-	}
-
-	for _, tt := range tests {
-		result := rclonePatternToRegex(tt.pattern)
-		if result != tt.expected {
-			t.Errorf("rclonePatternToRegex(%q) = %q; want %q", tt.pattern, result, tt.expected)
-		}
+	if model.filters.count() != 0 {
+		t.Errorf("Filter map should be empty after reset, but has %d entries", model.filters.count())
 	}
 }
 
@@ -403,7 +397,7 @@ func TestLoadFilterFileWithPatterns(t *testing.T) {
 	file.Close()
 
 	// Load and test
-	_, filterMap := loadFilterFile(tempFile)
+	filterMap := loadFilterFile(tempFile).states()
 
 	expectedFilters := map[string]FilterState{
 		"*.go":                     FilterInclude,
@@ -440,13 +434,13 @@ func TestSaveFilterFileWithPatterns(t *testing.T) {
 		"/exact/path.txt": FilterInclude,
 	}
 
-	err := saveFilterFile(tempFile, []FilterRule{}, filterMap)
+	err := saveFilterFile(tempFile, testDocument(nil, filterMap), ruleOrder{})
 	if err != nil {
 		t.Fatalf("Failed to save filter file: %v", err)
 	}
 
 	// Load it back and verify
-	_, loadedMap := loadFilterFile(tempFile)
+	loadedMap := loadFilterFile(tempFile).states()
 
 	if len(loadedMap) != len(filterMap) {
 		t.Errorf("Loaded map has %d entries, expected %d", len(loadedMap), len(filterMap))
@@ -571,7 +565,7 @@ func TestFilterRuleOrdering(t *testing.T) {
 	newFilterMap["temp"] = FilterExclude
 
 	// Save with new rules
-	err := saveFilterFile(tempFile, originalRules, newFilterMap)
+	err := saveFilterFile(tempFile, testDocument(originalRules, newFilterMap), ruleOrder{})
 	if err != nil {
 		t.Fatalf("Failed to save filter file: %v", err)
 	}
@@ -647,13 +641,13 @@ func TestDirectoryExclusionPattern(t *testing.T) {
 		filterPath = strings.TrimSuffix(filterPath, "/") + "/**"
 	}
 	t.Logf("Modified filter path for directory: %q", filterPath)
-	model.filterMap[filterPath] = dirNode.Filter
+	model.filters.set(filterPath, dirNode.Filter)
 
 	// Check that directory gets /** pattern
 	expectedDirPattern := filterPath // Use actual pattern instead of hardcoded
-	if _, exists := model.filterMap[expectedDirPattern]; !exists {
+	if _, exists := model.filters.lookup(expectedDirPattern); !exists {
 		t.Errorf("Expected directory filter pattern %q not found in filterMap", expectedDirPattern)
-		t.Logf("Available patterns in filterMap: %v", model.filterMap)
+		t.Logf("Available patterns in filterMap: %v", model.filters.states())
 	}
 
 	// Now test file exclusion
@@ -663,17 +657,17 @@ func TestDirectoryExclusionPattern(t *testing.T) {
 	fileFilterPath := getFilterPath(fileNode.Path)
 	t.Logf("File filter path: %q", fileFilterPath)
 	// Files should NOT get /** appended
-	model.filterMap[fileFilterPath] = fileNode.Filter
+	model.filters.set(fileFilterPath, fileNode.Filter)
 
 	// Check that file gets exact pattern (no /**)
 	expectedFilePattern := fileFilterPath // Use actual pattern
-	if _, exists := model.filterMap[expectedFilePattern]; !exists {
+	if _, exists := model.filters.lookup(expectedFilePattern); !exists {
 		t.Errorf("Expected file filter pattern %q not found in filterMap", expectedFilePattern)
 	}
 
 	// Verify no /** was added to file
 	wrongFilePattern := "/file.txt/**"
-	if _, exists := model.filterMap[wrongFilePattern]; exists {
+	if _, exists := model.filters.lookup(wrongFilePattern); exists {
 		t.Errorf("File should not have /** pattern, but found %q in filterMap", wrongFilePattern)
 	}
 
@@ -720,12 +714,12 @@ func TestSpaceKeyDirectoryExclusion(t *testing.T) {
 	if node.IsDir {
 		filterPath = strings.TrimSuffix(filterPath, "/") + "/**"
 	}
-	model.filterMap[filterPath] = node.Filter
+	model.filters.set(filterPath, node.Filter)
 
 	// Verify directory gets /** pattern
 	found := false
 	var dirPattern string
-	for pattern, state := range model.filterMap {
+	for pattern, state := range model.filters.states() {
 		if state == FilterExclude && strings.HasSuffix(pattern, "/**") {
 			found = true
 			dirPattern = pattern
@@ -734,7 +728,7 @@ func TestSpaceKeyDirectoryExclusion(t *testing.T) {
 	}
 
 	if !found {
-		t.Errorf("Directory exclusion should create a pattern ending with '/**', but patterns found: %v", model.filterMap)
+		t.Errorf("Directory exclusion should create a pattern ending with '/**', but patterns found: %v", model.filters.states())
 	} else {
 		t.Logf("✅ Directory exclusion created pattern: %q", dirPattern)
 	}
@@ -751,12 +745,12 @@ func TestSpaceKeyDirectoryExclusion(t *testing.T) {
 	if fileNodeRef.IsDir {
 		fileFilterPath = strings.TrimSuffix(fileFilterPath, "/") + "/**"
 	}
-	model.filterMap[fileFilterPath] = fileNodeRef.Filter
+	model.filters.set(fileFilterPath, fileNodeRef.Filter)
 
 	// Verify file does NOT get /** pattern
 	fileFound := false
 	var actualFilePattern string
-	for pattern, state := range model.filterMap {
+	for pattern, state := range model.filters.states() {
 		if state == FilterExclude && !strings.HasSuffix(pattern, "/**") {
 			fileFound = true
 			actualFilePattern = pattern
@@ -765,14 +759,14 @@ func TestSpaceKeyDirectoryExclusion(t *testing.T) {
 	}
 
 	if !fileFound {
-		t.Errorf("File exclusion should NOT create a pattern ending with '/**', but patterns found: %v", model.filterMap)
+		t.Errorf("File exclusion should NOT create a pattern ending with '/**', but patterns found: %v", model.filters.states())
 	} else {
 		t.Logf("✅ File exclusion created pattern: %q", actualFilePattern)
 	}
 
 	// Verify we have both patterns
-	if len(model.filterMap) != 2 {
-		t.Errorf("Expected 2 filter patterns, got %d: %v", len(model.filterMap), model.filterMap)
+	if model.filters.count() != 2 {
+		t.Errorf("Expected 2 filter patterns, got %d: %v", model.filters.count(), model.filters.states())
 	}
 }
 
@@ -807,7 +801,7 @@ func TestInvertSelectionDirectoryPattern(t *testing.T) {
 	dirPatternFound := false
 	filePatternFound := false
 
-	for pattern, state := range model.filterMap {
+	for pattern, state := range model.filters.states() {
 		if state == FilterExclude {
 			if strings.HasSuffix(pattern, "/**") {
 				dirPatternFound = true
@@ -827,8 +821,8 @@ func TestInvertSelectionDirectoryPattern(t *testing.T) {
 		t.Errorf("invertSelection should create exact pattern for files")
 	}
 
-	if len(model.filterMap) != 2 {
-		t.Errorf("Expected 2 patterns after invert, got %d: %v", len(model.filterMap), model.filterMap)
+	if model.filters.count() != 2 {
+		t.Errorf("Expected 2 patterns after invert, got %d: %v", model.filters.count(), model.filters.states())
 	}
 }
 
@@ -853,7 +847,7 @@ func TestSaveFilterFileWithDirectoryPatterns(t *testing.T) {
 	}
 
 	// Save with new directory patterns
-	err := saveFilterFile(tempFile, originalRules, filterMap)
+	err := saveFilterFile(tempFile, testDocument(originalRules, filterMap), ruleOrder{})
 	if err != nil {
 		t.Fatalf("Failed to save filter file: %v", err)
 	}
@@ -972,16 +966,62 @@ func TestSortByLastModified(t *testing.T) {
 	}
 }
 
+func TestMixedSortBySize(t *testing.T) {
+	model := newTestModel()
+	model.sortMode = SortBySize
+
+	big := &FileNode{Name: "big.iso", Size: 5000}
+	small := &FileNode{Name: "small.txt", Size: 10}
+	dir := &FileNode{Name: "docs", IsDir: true, TotalSize: 800}
+
+	children := []*FileNode{small, dir, big}
+	model.sortChildren(children)
+	if children[0] != dir || children[1] != big || children[2] != small {
+		t.Errorf("Expected the directory first, then files by size, got %s, %s, %s",
+			children[0].Name, children[1].Name, children[2].Name)
+	}
+
+	model.mixedSort = true
+	model.sortChildren(children)
+	if children[0] != big || children[1] != dir || children[2] != small {
+		t.Errorf("Expected the biggest item first regardless of type, got %s, %s, %s",
+			children[0].Name, children[1].Name, children[2].Name)
+	}
+}
+
+func TestMixedSortByFileCount(t *testing.T) {
+	model := newTestModel()
+	model.sortMode = SortByFileCount
+	model.mixedSort = true
+
+	many := &FileNode{Name: "many", IsDir: true, TotalFiles: 5}
+	one := &FileNode{Name: "one", IsDir: true, TotalFiles: 1}
+	a := &FileNode{Name: "a.txt"}
+	b := &FileNode{Name: "b.txt"}
+
+	// Every starting order ends the same way: a single file weighs as much
+	// as a directory holding one, and the name settles the tie
+	for _, children := range [][]*FileNode{
+		{one, b, many, a},
+		{a, one, b, many},
+		{b, a, one, many},
+	} {
+		model.sortChildren(children)
+		if got := childNames(&FileNode{Children: children}); got != "manya.txtb.txtone" {
+			t.Errorf("Expected many, a.txt, b.txt, one, got %s", got)
+		}
+	}
+}
+
 func TestHelpTextCompleteness(t *testing.T) {
 	model := newTestModel()
 	helpText := model.renderHelp()
 
 	// Check that all sort modes are documented
 	requiredSortHelp := []string{
-		"1           Sort by filename (default)",
-		"2           Sort by size",
-		"3           Sort by file count",
-		"4           Sort by last modified",
+		"o           Choose the sort order: filename (default), size, file count",
+		"or last modified, ascending or descending",
+		"m           Toggle directories first / mixed with files",
 	}
 
 	for _, expected := range requiredSortHelp {
@@ -1127,7 +1167,8 @@ func TestApplicationFilterBehaviorWithRealFiles(t *testing.T) {
 	}
 
 	// Load the test filter file
-	filterRules, filterMap := loadFilterFile(tempFilter)
+	doc := loadFilterFile(tempFilter)
+	filterRules, filterMap := doc.rules, doc.states()
 
 	if len(filterRules) == 0 {
 		t.Skip("filter.txt not found or empty, skipping test")
@@ -1148,8 +1189,8 @@ func TestApplicationFilterBehaviorWithRealFiles(t *testing.T) {
 	}
 
 	// Create model like the real application does
-	model := newTestModelWithFilterMap(filterMap)
-	model.filterRules = filterRules
+	model := newTestModel()
+	model.filters = doc
 
 	// Debug the loaded filterMap
 	t.Logf("Loaded filterMap has %d entries:", len(filterMap))
@@ -1298,7 +1339,7 @@ func TestModelGetEffectiveFilterWithMap(t *testing.T) {
 
 	// Create model like the real application does
 	model := newTestModel()
-	model.filterRules = filterRules
+	model.filters = newFilterDocument(filterRules)
 
 	// Set up global root path for test/folder_a
 	originalGlobalRootPath := globalRootPath
@@ -1349,7 +1390,6 @@ func TestModelGetEffectiveFilterWithMap(t *testing.T) {
 func TestChildrenFilterUpdateOnFolderChangeSimple(t *testing.T) {
 	// Create a simple test case to verify children filter updates
 	model := newTestModel()
-	model.filterRules = []FilterRule{}
 
 	// Set up global root path
 	originalGlobalRootPath := globalRootPath
@@ -1402,7 +1442,7 @@ func TestChildrenFilterUpdateOnFolderChangeSimple(t *testing.T) {
 	// Set up the parent's exclusion pattern in filterMap
 	t.Logf("\n=== Testing Filter Map Logic ===")
 	excludePattern := parentFilterPath + "/**"
-	model.filterMap[excludePattern] = FilterExclude
+	model.filters.set(excludePattern, FilterExclude)
 	t.Logf("Added pattern '%s' with state FilterExclude to filterMap", excludePattern)
 
 	// Test getEffectiveFilterWithMap directly
@@ -1417,7 +1457,6 @@ func TestChildrenFilterUpdateOnFolderChangeSimple(t *testing.T) {
 func TestChildrenFilterUpdateOnFolderChange(t *testing.T) {
 	// Create a model with a directory tree structure
 	model := newTestModel()
-	model.filterRules = []FilterRule{}
 
 	// Set up global root path
 	originalGlobalRootPath := globalRootPath
@@ -1513,10 +1552,10 @@ func TestChildrenFilterUpdateOnFolderChange(t *testing.T) {
 	// Update filterMap as the space handler would
 	filterPath := getFilterPath(parentDir.Path)
 	filterPath = strings.TrimSuffix(filterPath, "/") + "/**"
-	model.filterMap[filterPath] = FilterExclude
+	model.filters.set(filterPath, FilterExclude)
 
 	// Call updateChildrenFilters to update the children
-	t.Logf("Filter map before update: %v", model.filterMap)
+	t.Logf("Filter map before update: %v", model.filters.states())
 
 	// Debug the filter paths for each child
 	t.Logf("Parent filter path: %s", getFilterPath(parentDir.Path))
@@ -1540,7 +1579,7 @@ func TestChildrenFilterUpdateOnFolderChange(t *testing.T) {
 	}
 
 	model.updateChildrenFilters(parentDir)
-	t.Logf("Filter map after update: %v", model.filterMap)
+	t.Logf("Filter map after update: %v", model.filters.states())
 
 	// Verify that children now reflect the exclusion
 	checkNodeFilters(parentDir, 0)
@@ -1589,7 +1628,8 @@ func TestFilterWithParenthesesAndSpaces(t *testing.T) {
 	}
 
 	// Load the filter file
-	filterRules, filterMap := loadFilterFile(tempFile)
+	doc := loadFilterFile(tempFile)
+	filterRules, filterMap := doc.rules, doc.states()
 
 	t.Logf("=== Filter Rules ===")
 	for i, rule := range filterRules {
@@ -1669,3 +1709,497 @@ func TestFilterWithParenthesesAndSpaces(t *testing.T) {
 		}
 	}
 }
+
+// collectMsgs returns a send function that records messages from background
+// work, and a function to apply the recorded messages in order through Update
+func collectMsgs() (func(tea.Msg), func(Model) Model) {
+	var mu sync.Mutex
+	var msgs []tea.Msg
+	send := func(msg tea.Msg) {
+		mu.Lock()
+		msgs = append(msgs, msg)
+		mu.Unlock()
+	}
+	apply := func(m Model) Model {
+		mu.Lock()
+		pending := msgs
+		msgs = nil
+		mu.Unlock()
+		for _, msg := range pending {
+			updated, _ := m.Update(msg)
+			m = updated.(Model)
+		}
+		return m
+	}
+	return send, apply
+}
+
+// newScanTestModel creates a model rooted at dir with background messages
+// captured instead of sent to a program
+func newScanTestModel(dir string) (*Model, func(Model) Model) {
+	model := newTestModel()
+	model.ctx, model.cancel = context.WithCancel(context.Background())
+	model.checkers = 2
+	model.root = &FileNode{Name: filepath.Base(dir), Path: dir, IsDir: true, Expanded: true}
+	var apply func(Model) Model
+	model.send, apply = collectMsgs()
+	return model, apply
+}
+
+func TestScanDeliversTreeThroughMessages(t *testing.T) {
+	rootDir := t.TempDir()
+	os.MkdirAll(filepath.Join(rootDir, "a", "deep"), 0755)
+	os.WriteFile(filepath.Join(rootDir, "a", "deep", "1.txt"), []byte("1"), 0644)
+	os.WriteFile(filepath.Join(rootDir, "a", "2.txt"), []byte("22"), 0644)
+	os.WriteFile(filepath.Join(rootDir, "top.txt"), []byte("333"), 0644)
+
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = rootDir
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, apply := newScanTestModel(rootDir)
+	defer model.cancel()
+	model.filters.set("a/deep/**", FilterExclude)
+
+	if err := model.newScanner().scan(model.root); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	// Nothing is attached until the messages are processed by Update
+	if len(model.root.Children) != 0 {
+		t.Fatalf("Scanner should not modify the tree directly")
+	}
+
+	result := apply(*model)
+	if result.root.TotalFiles != 3 || result.root.TotalSize != 6 {
+		t.Errorf("Root stats = %d files, %d bytes; want 3 files, 6 bytes", result.root.TotalFiles, result.root.TotalSize)
+	}
+	dirA := result.root.Children[0]
+	if dirA.Name != "a" || dirA.Loading || dirA.TotalFiles != 2 {
+		t.Errorf("Directory a not attached correctly: %+v", dirA)
+	}
+	if deep := dirA.Children[0]; deep.Filter != FilterExclude {
+		t.Errorf("Filters should be evaluated when attaching nodes, got %v", deep.Filter)
+	}
+	if len(result.visibleNodes) != 3 {
+		t.Errorf("Expanded root should show its children, got %d visible nodes", len(result.visibleNodes))
+	}
+}
+
+func TestScanBatchesUpdates(t *testing.T) {
+	rootDir := t.TempDir()
+	for i := range 40 {
+		dir := filepath.Join(rootDir, fmt.Sprintf("d%02d", i))
+		os.MkdirAll(dir, 0755)
+		os.WriteFile(filepath.Join(dir, "f.txt"), []byte("1"), 0644)
+	}
+
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = rootDir
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	var mu sync.Mutex
+	var msgs []tea.Msg
+	model, _ := newScanTestModel(rootDir)
+	defer model.cancel()
+	model.send = func(msg tea.Msg) {
+		mu.Lock()
+		msgs = append(msgs, msg)
+		mu.Unlock()
+	}
+	start := time.Now()
+	if err := model.newScanner().scan(model.root); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	elapsed := time.Since(start)
+
+	// Everything found is delivered by the time scan returns, in batches
+	// no more often than the update interval
+	mu.Lock()
+	defer mu.Unlock()
+	listings := 0
+	var progress *loadingMsg
+	for _, msg := range msgs {
+		batch, ok := msg.(scanBatchMsg)
+		if !ok {
+			t.Fatalf("Expected only batches, got %T", msg)
+		}
+		listings += len(batch.listings)
+		if batch.progress != nil {
+			progress = batch.progress
+		}
+	}
+	if listings != 41 {
+		t.Errorf("Expected the root and 40 directories listed, got %d", listings)
+	}
+	if limit := int(elapsed/scanUpdateInterval) + 1; len(msgs) > limit {
+		t.Errorf("Expected at most %d batches in %v, got %d", limit, elapsed, len(msgs))
+	}
+	if progress == nil || progress.dirs == 0 {
+		t.Errorf("Expected the progress delivered with the listings, got %+v", progress)
+	}
+
+	result := *model
+	for _, msg := range msgs {
+		updated, _ := result.Update(msg)
+		result = updated.(Model)
+	}
+	if len(result.root.Children) != 40 || len(result.visibleNodes) != 41 {
+		t.Errorf("Expected the batches to attach and show the 40 directories, got %d rows", len(result.visibleNodes))
+	}
+}
+
+// panickingLister lists the local filesystem, except for directories named
+// boom, where it panics
+type panickingLister struct{}
+
+func (panickingLister) List(ctx context.Context, dir string) ([]dirEntry, error) {
+	if filepath.Base(dir) == "boom" {
+		panic("lister bug")
+	}
+	return localLister{}.List(ctx, dir)
+}
+
+func TestScanPanicBecomesIssue(t *testing.T) {
+	rootDir := t.TempDir()
+	os.MkdirAll(filepath.Join(rootDir, "boom"), 0755)
+	os.WriteFile(filepath.Join(rootDir, "top.txt"), []byte("1"), 0644)
+
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = rootDir
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, apply := newScanTestModel(rootDir)
+	defer model.cancel()
+	model.lister = panickingLister{}
+	if err := model.newScanner().scan(model.root); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	result := apply(*model)
+	boom := result.root.Children[0]
+	if boom.Name != "boom" || boom.Loading || boom.ListErr == nil || !strings.Contains(boom.ListErr.Error(), "lister bug") {
+		t.Errorf("Expected the panic recorded on the directory, got %+v", boom)
+	}
+}
+
+func TestScanMissingRootReportsError(t *testing.T) {
+	model, _ := newScanTestModel(filepath.Join(t.TempDir(), "missing"))
+	defer model.cancel()
+
+	if err := model.newScanner().scan(model.root); err == nil {
+		t.Errorf("Scanning a missing root should return an error")
+	}
+}
+
+func TestScanExclude(t *testing.T) {
+	rootDir := t.TempDir()
+	os.MkdirAll(filepath.Join(rootDir, "app", "node_modules", "left-pad"), 0755)
+	os.MkdirAll(filepath.Join(rootDir, ".git"), 0755)
+	os.WriteFile(filepath.Join(rootDir, "app", "node_modules", "left-pad", "index.js"), []byte("1"), 0644)
+	os.WriteFile(filepath.Join(rootDir, "app", "main.js"), []byte("22"), 0644)
+	os.WriteFile(filepath.Join(rootDir, ".git", "HEAD"), []byte("333"), 0644)
+
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = rootDir
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, apply := newScanTestModel(rootDir)
+	defer model.cancel()
+	model.scanExclude = []string{"**/node_modules/**", ".git"}
+	model.newScanner().scan(model.root)
+	result := apply(*model)
+	if len(result.root.Children) != 1 || result.root.TotalFiles != 1 {
+		t.Fatalf("Expected only app/main.js to be scanned, got %d files", result.root.TotalFiles)
+	}
+
+	// As stubs the skipped directories stay in the tree, and rescanning
+	// one lists all of it
+	model, apply = newScanTestModel(rootDir)
+	defer model.cancel()
+	model.scanExclude = []string{"**/node_modules/**", ".git"}
+	model.showSkipped = true
+	model.newScanner().scan(model.root)
+	result = apply(*model)
+	var stubs []string
+	var stub *FileNode
+	var walk func(node *FileNode)
+	walk = func(node *FileNode) {
+		if node.Skipped {
+			stubs = append(stubs, node.Name)
+			stub = node
+		}
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+	walk(result.root)
+	if strings.Join(stubs, ",") != ".git,node_modules" || result.root.TotalFiles != 1 {
+		t.Fatalf("Expected .git and node_modules as unscanned stubs, got %q", stubs)
+	}
+
+	msg := result.rescanSubtree(stub)()
+	result = apply(result)
+	updated, _ := result.Update(msg)
+	result = updated.(Model)
+	if stub.Skipped || stub.TotalFiles != 1 || stub.Children[0].Name != "left-pad" {
+		t.Errorf("Expected the rescan to list the stub, got %+v", stub)
+	}
+}
+
+func TestRescanSubtree(t *testing.T) {
+	rootDir := t.TempDir()
+	os.MkdirAll(filepath.Join(rootDir, "a", "deep"), 0755)
+	os.MkdirAll(filepath.Join(rootDir, "b"), 0755)
+	os.WriteFile(filepath.Join(rootDir, "a", "deep", "1.txt"), []byte("1"), 0644)
+	os.WriteFile(filepath.Join(rootDir, "b", "2.txt"), []byte("22"), 0644)
+
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = rootDir
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, apply := newScanTestModel(rootDir)
+	defer model.cancel()
+	model.newScanner().scan(model.root)
+	result := apply(*model)
+
+	dirA := result.root.Children[0]
+	dirB := result.root.Children[1]
+	dirA.Expanded = true
+	dirA.Children[0].Expanded = true
+	result.updateVisibleNodes()
+
+	// Change both directories on disk, but only rescan "a"
+	os.WriteFile(filepath.Join(rootDir, "a", "deep", "new.txt"), []byte("new"), 0644)
+	os.WriteFile(filepath.Join(rootDir, "b", "ignored.txt"), []byte("x"), 0644)
+
+	result.cursor = result.indexOfVisible(dirA)
+	msg := result.rescanSubtree(dirA)()
+	result = apply(result)
+	updated, _ := result.Update(msg)
+	result = updated.(Model)
+
+	if result.root.Children[1] != dirB || len(dirB.Children) != 1 {
+		t.Errorf("Sibling directory should be untouched, got %d children", len(dirB.Children))
+	}
+	if !dirA.Children[0].Expanded {
+		t.Errorf("Expanded state should be preserved inside the rescanned subtree")
+	}
+	if len(dirA.Children[0].Children) != 2 {
+		t.Errorf("Rescanned directory should pick up the new file, got %d children", len(dirA.Children[0].Children))
+	}
+	if result.root.TotalFiles != 3 {
+		t.Errorf("Root stats should be recalculated, got %d files", result.root.TotalFiles)
+	}
+	if result.visibleNodes[result.cursor] != dirA {
+		t.Errorf("Cursor should stay on the rescanned directory")
+	}
+	if running, queued := result.activeJobs(); running+queued != 0 {
+		t.Errorf("Rescan job should be finished, got %d running and %d queued", running, queued)
+	}
+}
+
+func TestExitCodeFor(t *testing.T) {
+	originalQuiet := quietMode
+	quietMode = true
+	defer func() { quietMode = originalQuiet }()
+
+	root := &FileNode{Path: "/test", IsDir: true}
+
+	tests := []struct {
+		name     string
+		model    Model
+		expected int
+	}{
+		{"saved", Model{root: root, saved: true}, exitSaved},
+		{"quit without saving", Model{root: root}, exitNotSaved},
+		{"scan error without save", Model{root: root, scanErr: os.ErrPermission}, exitScanError},
+		{"save wins over scan error", Model{root: root, saved: true, scanErr: os.ErrPermission}, exitSaved},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := exitCodeFor(tt.model); code != tt.expected {
+				t.Errorf("exitCodeFor() = %d; want %d", code, tt.expected)
+			}
+		})
+	}
+}
+
+func TestReadFilterFileErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	// A missing filter file is fine, it will be created on save
+	doc, err := readFilterFile(filepath.Join(dir, "missing.txt"))
+	if err != nil || len(doc.rules) != 0 {
+		t.Errorf("Missing filter file should load as empty without error, got %v", err)
+	}
+
+	// A directory in place of the filter file is an error
+	if _, err := readFilterFile(dir); err == nil {
+		t.Errorf("Reading a directory as a filter file should fail")
+	}
+
+	// Suspicious paths are rejected
+	if _, err := readFilterFile("../passwd"); err == nil {
+		t.Errorf("Suspicious filter file path should be rejected")
+	}
+}
+
+func TestViewModeFiltersVisibleNodes(t *testing.T) {
+	root := &FileNode{Name: "root", Path: "/root", IsDir: true, Expanded: true}
+	kept := &FileNode{Name: "kept", Path: "/root/kept", IsDir: true, Expanded: true, Parent: root}
+	dropped := &FileNode{Name: "dropped", Path: "/root/dropped", IsDir: true, Expanded: true, Parent: root, Filter: FilterExclude}
+	keptFile := &FileNode{Name: "a.txt", Path: "/root/kept/a.txt", Parent: kept, Filter: FilterInclude}
+	plainFile := &FileNode{Name: "b.txt", Path: "/root/kept/b.txt", Parent: kept}
+	droppedFile := &FileNode{Name: "c.txt", Path: "/root/dropped/c.txt", Parent: dropped, Filter: FilterExclude}
+	root.Children = []*FileNode{kept, dropped}
+	kept.Children = []*FileNode{keptFile, plainFile}
+	dropped.Children = []*FileNode{droppedFile}
+
+	model := newTestModel()
+	model.root = root
+	model.updateVisibleNodes()
+	if len(model.visibleNodes) != 6 {
+		t.Fatalf("All view should show every node, got %d", len(model.visibleNodes))
+	}
+
+	expected := map[ViewMode][]*FileNode{
+		ViewIncluded: {root, kept, keptFile, plainFile},
+		ViewExcluded: {root, dropped, droppedFile},
+		ViewAll:      {root, kept, keptFile, plainFile, dropped, droppedFile},
+	}
+
+	for _, mode := range []ViewMode{ViewIncluded, ViewExcluded, ViewAll} {
+		updated, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("v")})
+		result := updated.(Model)
+		model = &result
+		if model.viewMode != mode {
+			t.Fatalf("Expected view mode %d, got %d", mode, model.viewMode)
+		}
+		if len(model.visibleNodes) != len(expected[mode]) {
+			t.Errorf("View %d: got %d visible nodes, want %d", mode, len(model.visibleNodes), len(expected[mode]))
+			continue
+		}
+		for i, node := range expected[mode] {
+			if model.visibleNodes[i] != node {
+				t.Errorf("View %d: node %d is %s, want %s", mode, i, model.visibleNodes[i].Name, node.Name)
+			}
+		}
+	}
+}
+
+func TestToggleImpactConfirmation(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	root := &FileNode{Name: "test", Path: "/test", IsDir: true, Expanded: true}
+	big := &FileNode{Name: "big", Path: "/test/big", IsDir: true, Parent: root}
+	root.Children = []*FileNode{big}
+	for i := 0; i < 3; i++ {
+		big.Children = append(big.Children, &FileNode{
+			Name: fmt.Sprintf("%d.bin", i), Path: fmt.Sprintf("/test/big/%d.bin", i), Size: 1024, Parent: big,
+		})
+	}
+
+	model := newTestModel()
+	model.root = root
+	model.confirmFiles = 3
+	model.updateVisibleNodes()
+	model.cursor = 1
+
+	// None -> include changes nothing, so no confirmation is needed
+	space := tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")}
+	updated, _ := model.Update(space)
+	result := updated.(Model)
+	if big.Filter != FilterInclude || result.pendingToggle != nil {
+		t.Fatalf("Harmless toggle should apply immediately, got %v", big.Filter)
+	}
+
+	// Include -> exclude affects 3 files and needs a second press
+	updated, _ = result.Update(space)
+	result = updated.(Model)
+	if big.Filter != FilterInclude {
+		t.Errorf("Toggle over the threshold should wait for confirmation")
+	}
+	if !strings.Contains(result.statusMsg, "excludes 3 files / 3.0 KiB") {
+		t.Errorf("Expected impact summary, got %q", result.statusMsg)
+	}
+	if _, exists := result.filters.lookup("big/**"); !exists || result.filters.state("big/**") != FilterInclude {
+		t.Errorf("Preview must not leave changes in filterMap: %v", result.filters.states())
+	}
+
+	// Any other key cancels the pending toggle
+	updated, _ = result.Update(tea.KeyMsg{Type: tea.KeyDown})
+	result = updated.(Model)
+	if result.pendingToggle != nil || result.statusMsg != "" {
+		t.Errorf("Other keys should cancel the pending toggle")
+	}
+
+	// Two presses in a row apply it
+	updated, _ = result.Update(space)
+	updated, _ = updated.(Model).Update(space)
+	result = updated.(Model)
+	if big.Filter != FilterExclude || big.Children[0].Filter != FilterExclude {
+		t.Errorf("Confirmed toggle should exclude the directory and its files")
+	}
+}
+
+func TestFormatCount(t *testing.T) {
+	tests := []struct {
+		n        int
+		expected string
+	}{
+		{0, "0"},
+		{999, "999"},
+		{1000, "1,000"},
+		{23114, "23,114"},
+		{1234567, "1,234,567"},
+		{-4200, "-4,200"},
+	}
+
+	for _, tt := range tests {
+		if result := formatCount(tt.n); result != tt.expected {
+			t.Errorf("formatCount(%d) = %q; want %q", tt.n, result, tt.expected)
+		}
+	}
+}
+
+func TestParseAndWriteFilterRulesStreams(t *testing.T) {
+	input := "# generated\n+ keep/**\n- *\n"
+	doc, err := parseFilterRules(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parseFilterRules failed: %v", err)
+	}
+	if len(doc.rules) != 2 || doc.state("keep/**") != FilterInclude {
+		t.Fatalf("Unexpected rules: %v", doc.rules)
+	}
+
+	// The comment stays above the rule it was written over
+	doc.set("junk/**", FilterExclude)
+	var out strings.Builder
+	if err := doc.write(&out); err != nil {
+		t.Fatalf("writeFilterRules failed: %v", err)
+	}
+	expected := "# generated\n+ keep/**\n- junk/**\n- *\n"
+	if out.String() != expected {
+		t.Errorf("writeFilterRules() = %q; want %q", out.String(), expected)
+	}
+}
+
+func TestStdoutModeSkipsFileWrites(t *testing.T) {
+	model := newTestModel()
+	model.filterFile = filepath.Join(t.TempDir(), "never-written.txt")
+	model.toStdout = true
+	model.filters.set("a/**", FilterExclude)
+
+	if err := model.saveAll(); err != nil {
+		t.Fatalf("saveAll failed: %v", err)
+	}
+	if _, err := os.Stat(model.filterFile); !os.IsNotExist(err) {
+		t.Errorf("Stdout mode must not write the filter file")
+	}
+	if name := model.filterFileNames(); name != "standard output" {
+		t.Errorf("Save target should be shown as standard output, got %q", name)
+	}
+}
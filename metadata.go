@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// metadataRule is a rule of an rclone metadata filter. Its pattern matches a
+// "key=value" pair of an object's metadata with the same glob syntax as path
+// rules, e.g. "- description=*draft*".
+type metadataRule struct {
+	State   FilterState
+	Pattern string
+}
+
+func (r metadataRule) String() string {
+	if r.State == FilterInclude {
+		return "+ " + r.Pattern
+	}
+	return "- " + r.Pattern
+}
+
+// metadataFilter is a file of metadata rules for rclone's
+// --metadata-filter-from, edited next to the path rules
+type metadataFilter struct {
+	path  string
+	rules []metadataRule
+}
+
+// parseMetadataRule parses a rule such as "+ key=value". Without a sign the
+// rule excludes, as in the rule templates.
+func parseMetadataRule(line string) (metadataRule, error) {
+	rule := metadataRule{Pattern: strings.TrimSpace(line), State: FilterExclude}
+	if sign, rest, _ := strings.Cut(rule.Pattern, " "); sign == "+" || sign == "-" {
+		if sign == "+" {
+			rule.State = FilterInclude
+		}
+		rule.Pattern = strings.TrimSpace(rest)
+	}
+	if key, _, ok := strings.Cut(rule.Pattern, "="); !ok || strings.TrimSpace(key) == "" {
+		return rule, fmt.Errorf("%q is not a key=value pattern", rule.Pattern)
+	}
+	return rule, nil
+}
+
+// readMetadataFilter loads the metadata rules from path. Like a filter file,
+// a missing file is created on save.
+func readMetadataFilter(path string) (*metadataFilter, error) {
+	if err := validateFilterFilePath(path); err != nil {
+		return nil, fmt.Errorf("security error: %v", err)
+	}
+	f := &metadataFilter{path: path}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		rule, err := parseMetadataRule(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		f.rules = append(f.rules, rule)
+	}
+	return f, scanner.Err()
+}
+
+func (f *metadataFilter) write(w io.Writer) error {
+	writer := bufio.NewWriter(w)
+	for _, rule := range f.rules {
+		fmt.Fprintln(writer, rule)
+	}
+	return writer.Flush()
+}
+
+func (f *metadataFilter) save() error {
+	return writeFileAtomic(f.path, f.write)
+}
+
+// updateMetadataPane handles keys while the metadata rules are shown
+func (m Model) updateMetadataPane(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.metadataInput != nil {
+		return m.updateMetadataInput(msg)
+	}
+
+	rules := m.metadata.rules
+	switch msg.String() {
+	case "up", "k":
+		if m.metadataCursor > 0 {
+			m.metadataCursor--
+		}
+	case "down", "j":
+		if m.metadataCursor < len(rules)-1 {
+			m.metadataCursor++
+		}
+	case "K":
+		// Earlier rules win, so moving a rule changes what it overrides
+		if i := m.metadataCursor; i > 0 && i < len(rules) {
+			rules[i-1], rules[i] = rules[i], rules[i-1]
+			m.metadataCursor--
+			m.metadataChanged = true
+		}
+	case "J":
+		if i := m.metadataCursor; i < len(rules)-1 {
+			rules[i], rules[i+1] = rules[i+1], rules[i]
+			m.metadataCursor++
+			m.metadataChanged = true
+		}
+	case " ":
+		if m.metadataCursor < len(rules) {
+			rule := &rules[m.metadataCursor]
+			if rule.State == FilterInclude {
+				rule.State = FilterExclude
+			} else {
+				rule.State = FilterInclude
+			}
+			m.metadataChanged = true
+		}
+	case "d":
+		if m.metadataCursor < len(rules) {
+			m.metadata.rules = append(rules[:m.metadataCursor], rules[m.metadataCursor+1:]...)
+			m.metadataCursor = max(0, min(m.metadataCursor, len(m.metadata.rules)-1))
+			m.metadataChanged = true
+		}
+	case "a":
+		input := ""
+		m.metadataInput = &input
+	case "M", "esc", "q":
+		m.closeModal(modalMetadata)
+	case "ctrl+c":
+		m.cancel()
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+// updateMetadataInput handles typing a new metadata rule
+func (m Model) updateMetadataInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	input := *m.metadataInput
+	if used, cmd := m.editPrompt(&input, msg); used {
+		m.metadataInput = &input
+		return m, cmd
+	}
+	switch msg.Type {
+	case tea.KeyEnter:
+		rule, err := parseMetadataRule(input)
+		if err != nil {
+			m.metadataErr = err.Error()
+			return m, nil
+		}
+		// New rules go after the selected one
+		at := min(m.metadataCursor+1, len(m.metadata.rules))
+		m.metadata.rules = append(m.metadata.rules[:at], append([]metadataRule{rule}, m.metadata.rules[at:]...)...)
+		m.metadataCursor = at
+		m.metadataChanged = true
+		m.metadataInput = nil
+		m.metadataErr = ""
+		return m, nil
+	case tea.KeyEsc:
+		m.metadataInput = nil
+		m.metadataErr = ""
+	}
+	return m, nil
+}
+
+func (m Model) renderMetadata() string {
+	paneStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(currentTheme.Border).
+		Padding(1, 2)
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Metadata rules (%s):\n\n", m.metadata.path))
+	for i, rule := range m.metadata.rules {
+		_, style := stateStyle(rule.State)
+		line := style.Render(rule.String())
+		if i == m.metadataCursor {
+			line = lipgloss.NewStyle().Background(currentTheme.CursorBg).Foreground(currentTheme.CursorFg).Render(rule.String())
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	if len(m.metadata.rules) == 0 {
+		b.WriteString("No metadata rules yet\n")
+	}
+
+	b.WriteString("\n")
+	switch {
+	case m.metadataInput != nil:
+		b.WriteString(fmt.Sprintf("New rule: %s█\n", *m.metadataInput))
+		if m.metadataErr != "" {
+			b.WriteString(lipgloss.NewStyle().Foreground(currentTheme.Warning).Render(m.metadataErr))
+			b.WriteString("\n")
+		}
+		b.WriteString("Type \"+ key=value\" or \"- key=value\" (globs allowed), Enter adds, Esc cancels")
+	default:
+		b.WriteString("↑/↓ select, a add, Space include/exclude, d delete, K/J move, Esc close")
+	}
+
+	return m.placePane(paneStyle, b.String())
+}
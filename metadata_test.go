@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestParseMetadataRule(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected metadataRule
+		wantErr  bool
+	}{
+		{"+ content-type=image/*", metadataRule{FilterInclude, "content-type=image/*"}, false},
+		{"- description=*draft*", metadataRule{FilterExclude, "description=*draft*"}, false},
+		{"tier=ARCHIVE", metadataRule{FilterExclude, "tier=ARCHIVE"}, false},
+		{"+ *.jpg", metadataRule{}, true},
+		{"- =value", metadataRule{}, true},
+	}
+
+	for _, tt := range tests {
+		rule, err := parseMetadataRule(tt.line)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseMetadataRule(%q) error = %v; want error %t", tt.line, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && rule != tt.expected {
+			t.Errorf("parseMetadataRule(%q) = %+v; want %+v", tt.line, rule, tt.expected)
+		}
+	}
+}
+
+func TestEditMetadataRules(t *testing.T) {
+	dir := t.TempDir()
+	oldWd, _ := os.Getwd()
+	os.Chdir(dir)
+	defer os.Chdir(oldWd)
+
+	os.WriteFile("metadata.txt", []byte("# keep drafts out\n- description=*draft*\n+ content-type=image/*\n"), 0644)
+	metadata, err := readMetadataFilter("metadata.txt")
+	if err != nil {
+		t.Fatalf("Failed to read metadata filter: %v", err)
+	}
+	if len(metadata.rules) != 2 {
+		t.Fatalf("Expected 2 rules, got %v", metadata.rules)
+	}
+
+	model := newTestModel()
+	model.metadata = metadata
+	model.filterFile = "filter.txt"
+	model.openModal(modalMetadata)
+
+	var result tea.Model = *model
+	for _, key := range []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune("a")},
+		{Type: tea.KeyRunes, Runes: []rune("- tier=ARCHIVE")},
+		{Type: tea.KeyEnter},
+		{Type: tea.KeyRunes, Runes: []rune("K")},
+		{Type: tea.KeySpace},
+	} {
+		result, _ = result.(Model).Update(key)
+	}
+	final := result.(Model)
+	if err := final.saveAll(); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "metadata.txt"))
+	expected := "+ tier=ARCHIVE\n- description=*draft*\n+ content-type=image/*\n"
+	if string(data) != expected {
+		t.Errorf("Saved metadata rules:\n%s\nwant:\n%s", data, expected)
+	}
+}
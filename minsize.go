@@ -0,0 +1,33 @@
+package main
+
+import "fmt"
+
+// defaultMinSize is the size below which H hides entries when the config
+// file doesn't set min-size
+const defaultMinSize = 10 << 20
+
+// belowMinSize reports whether node is hidden for being smaller than the
+// threshold of H. The top of the tree and entries whose size isn't known yet
+// stay visible.
+func (m *Model) belowMinSize(node *FileNode) bool {
+	if !m.hideSmall || node.Parent == nil || node.Skipped || node.Loading {
+		return false
+	}
+	return m.shownSize(node) < m.minSize
+}
+
+// toggleHideSmall hides the entries smaller than the threshold, or shows
+// them again. They still count in the sizes and file counts of their
+// directories.
+func (m *Model) toggleHideSmall() {
+	m.hideSmall = !m.hideSmall
+	m.updateVisibleNodes()
+	m.cursor = max(0, min(m.cursor, len(m.visibleNodes)-1))
+	m.adjustScroll()
+
+	if m.hideSmall {
+		m.statusMsg = fmt.Sprintf("Hiding entries smaller than %s, H shows them again", formatSize(m.minSize))
+	} else {
+		m.statusMsg = "Showing entries of any size"
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestHideSmall(t *testing.T) {
+	root := &FileNode{Name: "test", Path: "/test", IsDir: true, Expanded: true}
+	videos := &FileNode{Name: "videos", Path: "/test/videos", IsDir: true, Expanded: true, Parent: root}
+	docs := &FileNode{Name: "docs", Path: "/test/docs", IsDir: true, Expanded: true, Parent: root}
+	movie := &FileNode{Name: "movie.mkv", Path: "/test/videos/movie.mkv", Size: 20 << 20, Parent: videos}
+	clip := &FileNode{Name: "clip.mp4", Path: "/test/videos/clip.mp4", Size: 1 << 20, Parent: videos}
+	notes := &FileNode{Name: "notes.txt", Path: "/test/docs/notes.txt", Size: 1024, Parent: docs}
+	root.Children = []*FileNode{videos, docs}
+	videos.Children = []*FileNode{movie, clip}
+	docs.Children = []*FileNode{notes}
+	calculateStats(root)
+
+	model := newTestModel()
+	model.root = root
+	model.minSize = defaultMinSize
+	model.width, model.height = 100, 20
+	model.updateVisibleNodes()
+
+	key := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("H")}
+	updated, _ := model.Update(key)
+	m := updated.(Model)
+	if len(m.visibleNodes) != 3 || m.visibleNodes[1] != videos || m.visibleNodes[2] != movie {
+		t.Fatalf("Expected only the root, videos and movie.mkv, got %d rows", len(m.visibleNodes))
+	}
+	if root.TotalSize != 21<<20+1024 || root.TotalFiles != 3 {
+		t.Errorf("Expected hidden entries to still count, got %d bytes in %d files", root.TotalSize, root.TotalFiles)
+	}
+	if view := m.View(); !strings.Contains(view, "Hiding < 10.0 MiB (H)") {
+		t.Errorf("Expected the threshold in the header:\n%s", view)
+	}
+
+	// The threshold applies in the other views as well
+	m.minSize = 512 << 10
+	m.viewMode = ViewExcluded
+	clip.Filter = FilterExclude
+	m.updateVisibleNodes()
+	if len(m.visibleNodes) != 3 || m.visibleNodes[2] != clip {
+		t.Errorf("Expected the root, videos and clip.mp4, got %d rows", len(m.visibleNodes))
+	}
+
+	m.viewMode = ViewAll
+	updated, _ = m.Update(key)
+	if m = updated.(Model); len(m.visibleNodes) != 6 {
+		t.Errorf("Expected every entry after a second H, got %d rows", len(m.visibleNodes))
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// fileMirror is a copy of the tree files are compared with: a local
+// directory given with --local-mirror, or the destination of a sync given
+// with --dest
+type fileMirror interface {
+	compare(node *FileNode) mirrorState
+	name() string // What the copy is called in the rows, e.g. "mirror"
+}
+
+// mirrorState is how a file compares with its copy in the mirror
+type mirrorState int
+
+const (
+	mirrorSame    mirrorState = iota // Same size and modification time
+	mirrorDiffers                    // Present with another size or time
+	mirrorMissing                    // Not in the mirror
+	mirrorRenamed                    // Not in the mirror, but the same checksum is under another path
+)
+
+// modifyWindow is how far apart modification times may be for a file to
+// count as unchanged, as with rclone's default for most backends
+const modifyWindow = time.Second
+
+// localMirror compares the files of the tree with a local directory holding
+// a copy of it, such as the source an rclone remote was uploaded from
+type localMirror struct {
+	dir    string
+	kind   string                    // "mirror", or "dest" for a local --dest
+	states map[*FileNode]mirrorState // Files compared so far, as rows are drawn
+}
+
+func newLocalMirror(dir string) *localMirror {
+	return &localMirror{dir: dir, kind: "mirror", states: make(map[*FileNode]mirrorState)}
+}
+
+func (lm *localMirror) name() string {
+	return lm.kind
+}
+
+// compare returns how node compares with its copy in the mirror. Sizes and
+// modification times are compared, like rclone does by default; checksums
+// would mean reading every local file.
+func (lm *localMirror) compare(node *FileNode) mirrorState {
+	if state, ok := lm.states[node]; ok {
+		return state
+	}
+	_, rel := nodeLocation(node)
+	state := mirrorMissing
+	if info, err := os.Stat(filepath.Join(lm.dir, filepath.FromSlash(rel))); err == nil && !info.IsDir() {
+		state = mirrorSame
+		diff := info.ModTime().Sub(node.ModTime)
+		if info.Size() != node.Size || diff > modifyWindow || diff < -modifyWindow {
+			state = mirrorDiffers
+		}
+	}
+	lm.states[node] = state
+	return state
+}
+
+// label is the marker shown after the size of a file, compared with the
+// copy called name
+func (s mirrorState) label(name string) string {
+	switch s {
+	case mirrorSame:
+		return "= " + name
+	case mirrorDiffers:
+		return "≠ " + name
+	case mirrorRenamed:
+		return "= " + name + ", renamed"
+	}
+	return "not in " + name
+}
+
+// fileDetails is the modification time and checksum shown after the size of
+// a file with --hashes, the checksum shortened as rows are narrow
+func fileDetails(node *FileNode) string {
+	var parts []string
+	if !node.ModTime.IsZero() {
+		parts = append(parts, node.ModTime.Local().Format("2006-01-02 15:04"))
+	}
+	if kind, value, ok := strings.Cut(node.Hash, ":"); ok {
+		if len(value) > 8 {
+			value = value[:8]
+		}
+		parts = append(parts, fmt.Sprintf("%s %s", kind, value))
+	}
+	return strings.Join(parts, ", ")
+}
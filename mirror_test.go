@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocalMirror(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "remote:backup"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	modTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	mirrorDir := t.TempDir()
+	os.MkdirAll(filepath.Join(mirrorDir, "docs"), 0755)
+	for name, content := range map[string]string{"same.txt": "abc", "changed.txt": "abcdef"} {
+		path := filepath.Join(mirrorDir, "docs", name)
+		os.WriteFile(path, []byte(content), 0644)
+		os.Chtimes(path, modTime, modTime)
+	}
+
+	root := &FileNode{Name: "backup", Path: "remote:backup", IsDir: true, Expanded: true}
+	docs := &FileNode{Name: "docs", Path: "remote:backup/docs", IsDir: true, Expanded: true, Parent: root}
+	same := &FileNode{Name: "same.txt", Path: "remote:backup/docs/same.txt", Size: 3, ModTime: modTime.Add(500 * time.Millisecond), Hash: "md5:900150983cd24fb0", Parent: docs}
+	changed := &FileNode{Name: "changed.txt", Path: "remote:backup/docs/changed.txt", Size: 3, ModTime: modTime, Parent: docs}
+	missing := &FileNode{Name: "missing.txt", Path: "remote:backup/docs/missing.txt", Size: 3, ModTime: modTime, Parent: docs}
+	root.Children = []*FileNode{docs}
+	docs.Children = []*FileNode{changed, missing, same}
+
+	lm := newLocalMirror(mirrorDir)
+	for node, want := range map[*FileNode]mirrorState{same: mirrorSame, changed: mirrorDiffers, missing: mirrorMissing} {
+		if got := lm.compare(node); got != want {
+			t.Errorf("compare(%s) = %v, want %v", node.Name, got, want)
+		}
+	}
+
+	model := newTestModel()
+	model.root = root
+	model.mirror = lm
+	model.showDetails = true
+	model.width, model.height = 120, 20
+	model.updateVisibleNodes()
+	view := model.View()
+	want := "same.txt (3 B, " + same.ModTime.Local().Format("2006-01-02 15:04") + ", md5 90015098) = mirror"
+	for _, s := range []string{want, "changed.txt (3 B, ", ") ≠ mirror", "missing.txt (3 B, "} {
+		if !strings.Contains(view, s) {
+			t.Errorf("Expected %q in the view:\n%s", s, view)
+		}
+	}
+}
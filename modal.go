@@ -0,0 +1,299 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// modal is a pane or prompt shown over the tree. Open modals are kept on a
+// stack: keys go to the one on top, and closing it returns to the one below,
+// so a prompt can be opened from a pane and come back to it.
+type modal int
+
+const (
+	modalNone modal = iota
+	modalHelp
+	modalTour
+	modalSaveConfirm
+	modalJobs
+	modalDuplicates
+	modalGrowth
+	modalTriage
+	modalTemplates
+	modalIssues
+	modalEmptyDirs
+	modalMetadata
+	modalRules
+	modalCommand
+	modalImport
+	modalCoalesce
+	modalSuggest
+	modalRuleOrder
+	modalLoadReport
+	modalSortMenu
+	modalSurvivors
+	modalExtensionMenu
+	modalParents
+	modalConflicts
+	modalTemplatePrompt   // Typing the value of a template variable
+	modalDepthPrompt      // Typing the depth to expand the tree to
+	modalFindPrompt       // Typing the start of a name to jump to
+	modalAgePrompt        // Typing the age of the files to exclude in a directory
+	modalNotePrompt       // Typing the comment written above a rule
+	modalRuleSearchPrompt // Typing the search of the rules pane
+	modalQuickPrompt      // Typing the glob the tree is pruned to
+	modalNodeNotePrompt   // Typing the note on an entry
+)
+
+// isPrompt reports whether md is typed in the status line rather than shown
+// as a pane of its own
+func (md modal) isPrompt() bool {
+	return md == modalTemplatePrompt || md == modalDepthPrompt || md == modalFindPrompt || md == modalAgePrompt || md == modalNotePrompt ||
+		md == modalRuleSearchPrompt || md == modalQuickPrompt || md == modalNodeNotePrompt
+}
+
+// openModal puts md on top of the stack, moving it there if it is already open
+func (m *Model) openModal(md modal) {
+	m.modals = append(slices.DeleteFunc(slices.Clone(m.modals), func(o modal) bool { return o == md }), md)
+	m.paneScroll = 0
+}
+
+// closeModal removes md from the stack, wherever it is
+func (m *Model) closeModal(md modal) {
+	m.modals = slices.DeleteFunc(slices.Clone(m.modals), func(o modal) bool { return o == md })
+	m.paneScroll = 0
+}
+
+// setModal opens or closes md
+func (m *Model) setModal(md modal, open bool) {
+	if open {
+		m.openModal(md)
+	} else {
+		m.closeModal(md)
+	}
+}
+
+// topModal returns the modal receiving keys, or modalNone over the tree
+func (m Model) topModal() modal {
+	if len(m.modals) == 0 {
+		return modalNone
+	}
+	return m.modals[len(m.modals)-1]
+}
+
+func (m Model) modalOpen(md modal) bool {
+	return slices.Contains(m.modals, md)
+}
+
+// updateModal hands a key to the modal on top
+func (m Model) updateModal(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	// PgUp/PgDn scroll a pane taller than the screen, unless it pages
+	// through a list of its own
+	if top := m.topModal(); !top.isPrompt() && top != modalSurvivors && m.scrollPane(msg.String()) {
+		return m, nil
+	}
+	switch m.topModal() {
+	case modalHelp:
+		return m.updateHelp(msg)
+	case modalTour:
+		return m.updateTourPane(msg)
+	case modalSaveConfirm:
+		return m.updateSaveConfirm(msg)
+	case modalJobs:
+		return m.updateJobsPane(msg)
+	case modalDuplicates:
+		return m.updateDuplicatesPane(msg)
+	case modalGrowth:
+		return m.updateGrowthPane(msg)
+	case modalTriage:
+		return m.updateTriagePane(msg)
+	case modalTemplates:
+		return m.updateTemplatesPane(msg)
+	case modalIssues:
+		return m.updateIssuesPane(msg)
+	case modalEmptyDirs:
+		return m.updateEmptyDirsPane(msg)
+	case modalMetadata:
+		return m.updateMetadataPane(msg)
+	case modalRules:
+		return m.updateRulesPane(msg)
+	case modalCommand:
+		return m.updateCommandPane(msg)
+	case modalImport:
+		return m.updateImportPane(msg)
+	case modalCoalesce:
+		return m.updateCoalescePane(msg)
+	case modalSuggest:
+		return m.updateSuggestPane(msg)
+	case modalRuleOrder:
+		return m.updateRuleOrderPane(msg)
+	case modalLoadReport:
+		return m.updateLoadReport(msg)
+	case modalSortMenu:
+		return m.updateSortMenu(msg)
+	case modalSurvivors:
+		return m.updateSurvivorsPane(msg)
+	case modalExtensionMenu:
+		return m.updateExtensionMenu(msg)
+	case modalParents:
+		return m.updateParentsPane(msg)
+	case modalConflicts:
+		return m.updateConflictsPane(msg)
+	case modalTemplatePrompt:
+		return m.updateTemplatePrompt(msg)
+	case modalDepthPrompt:
+		return m.updateDepthPrompt(msg)
+	case modalFindPrompt:
+		return m.updateFindPrompt(msg)
+	case modalAgePrompt:
+		return m.updateAgePrompt(msg)
+	case modalNotePrompt:
+		return m.updateNotePrompt(msg)
+	case modalRuleSearchPrompt:
+		return m.updateRuleSearchPrompt(msg)
+	case modalQuickPrompt:
+		return m.updateQuickPrompt(msg)
+	case modalNodeNotePrompt:
+		return m.updateNodeNotePrompt(msg)
+	}
+	return m, nil
+}
+
+// updateHelp closes the help screen. A key other than the ones closing it is
+// used as the command it stands for, as if help had not been open.
+func (m Model) updateHelp(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.closeModal(modalHelp)
+	switch msg.String() {
+	case "esc", "q", "?", "h":
+		return m, nil
+	case "g":
+		m.startTour()
+		return m, nil
+	}
+	return m.Update(msg)
+}
+
+// updateSaveConfirm handles the question asked when quitting. Saving goes
+// through the same checks as s, over the guard rail warnings with a second
+// press, and quits once the rules are written.
+func (m Model) updateSaveConfirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "y", "Y":
+		if m.saveWarnings() != "" && !m.pendingSave {
+			m.pendingSave = true
+			return m, nil
+		}
+		m.closeModal(modalSaveConfirm)
+		m.quitAfterSave = true
+		return m, m.startSave()
+	case "n", "N", "ctrl+c":
+		m.cancel()
+		return m, tea.Quit
+	case "c", "C", "esc":
+		m.closeModal(modalSaveConfirm)
+		m.pendingSave = false
+	}
+	return m, nil
+}
+
+// renderModal renders the pane of md. A prompt shows in the status line of
+// the pane below it, or of the tree.
+func (m Model) renderModal(md modal) string {
+	switch md {
+	case modalHelp:
+		return m.renderHelp()
+	case modalTour:
+		return m.renderTour()
+	case modalSaveConfirm:
+		return m.renderSaveConfirm()
+	case modalJobs:
+		return m.renderJobs()
+	case modalDuplicates:
+		return m.renderDuplicates()
+	case modalGrowth:
+		return m.renderGrowth()
+	case modalTriage:
+		return m.renderTriage()
+	case modalTemplates:
+		return m.renderTemplates()
+	case modalIssues:
+		return m.renderIssues()
+	case modalEmptyDirs:
+		return m.renderEmptyDirs()
+	case modalMetadata:
+		return m.renderMetadata()
+	case modalRules:
+		return m.renderRules()
+	case modalCommand:
+		return m.renderCommand()
+	case modalImport:
+		return m.renderImport()
+	case modalCoalesce:
+		return m.renderCoalesce()
+	case modalSuggest:
+		return m.renderSuggest()
+	case modalRuleOrder:
+		return m.renderRuleOrder()
+	case modalLoadReport:
+		return m.renderLoadReport()
+	case modalSortMenu:
+		return m.renderSortMenu()
+	case modalSurvivors:
+		return m.renderSurvivors()
+	case modalExtensionMenu:
+		return m.renderExtensionMenu()
+	case modalParents:
+		return m.renderParents()
+	case modalConflicts:
+		return m.renderConflicts()
+	}
+	return ""
+}
+
+// editPrompt handles the keys every prompt shares: Ctrl+C quits, Backspace
+// removes the last character, however many bytes it takes, and Space and
+// typed or pasted characters are added to text. It reports whether it used
+// the key, leaving Enter, Esc and the other keys to the prompt.
+func (m *Model) editPrompt(text *string, msg tea.KeyMsg) (bool, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		m.cancel()
+		return true, tea.Quit
+	case tea.KeyBackspace:
+		_, size := utf8.DecodeLastRuneInString(*text)
+		*text = (*text)[:len(*text)-size]
+	case tea.KeySpace:
+		*text += " "
+	case tea.KeyRunes:
+		*text += string(msg.Runes)
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// promptText is the status line of the prompt on top
+func (m Model) promptText() string {
+	switch {
+	case m.topModal() == modalTemplatePrompt && m.templatePrompt != nil:
+		return m.templatePrompt.promptText()
+	case m.topModal() == modalDepthPrompt && m.depthInput != nil:
+		return fmt.Sprintf("Expand to depth: %s█ (Enter to accept, Esc cancels)", *m.depthInput)
+	case m.topModal() == modalFindPrompt && m.typeAhead != nil:
+		return m.typeAhead.promptText()
+	case m.topModal() == modalAgePrompt && m.agePrompt != nil:
+		return m.agePrompt.promptText()
+	case m.topModal() == modalNotePrompt && m.notePrompt != nil:
+		return m.notePrompt.promptText()
+	case m.topModal() == modalRuleSearchPrompt:
+		return m.ruleSearchPromptText()
+	case m.topModal() == modalQuickPrompt && m.quickInput != nil:
+		return fmt.Sprintf("Show only: %s█ (a glob such as *.mkv, or a path such as TV/**/*.srt; Enter shows, Esc cancels)", *m.quickInput)
+	case m.topModal() == modalNodeNotePrompt && m.nodeNotePrompt != nil:
+		return m.nodeNotePrompt.promptText()
+	}
+	return ""
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestHelpPassesCommandsThrough(t *testing.T) {
+	model := newTestModel()
+	model.openModal(modalHelp)
+
+	updated, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("m")})
+	result := updated.(Model)
+	if result.modalOpen(modalHelp) || !result.mixedSort {
+		t.Errorf("Expected m to close the help and switch to mixed sorting")
+	}
+
+	result.openModal(modalHelp)
+	updated, _ = result.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	if result = updated.(Model); result.topModal() != modalNone {
+		t.Errorf("Expected q to only close the help, got %v open", result.modals)
+	}
+}
+
+func TestTemplatePromptOverRulesPane(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, _, small := newGuardTestModel()
+	model.filters = newFilterDocument([]FilterRule{{Pattern: "big/**", State: FilterExclude}})
+	model.templates = []RuleTemplate{{Name: "exclude-dir", Pattern: "{{dir}}/**", State: FilterExclude}}
+
+	var result tea.Model = *model
+	for _, key := range []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune("p")},
+		{Type: tea.KeyRunes, Runes: []rune("t")},
+		{Type: tea.KeyEnter},
+		{Type: tea.KeyRunes, Runes: []rune("small")},
+	} {
+		result, _ = result.Update(key)
+	}
+	m := result.(Model)
+	if want := []modal{modalRules, modalTemplatePrompt}; !slices.Equal(m.modals, want) {
+		t.Fatalf("Expected the prompt over the rules pane, got %v", m.modals)
+	}
+	if view := m.View(); !strings.Contains(view, "Rules of") || !strings.Contains(view, "dir = small") {
+		t.Errorf("Expected the prompt below the rules pane, got:\n%s", view)
+	}
+
+	// Accepting the value returns to the rules pane, which lists the new rule
+	result, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = result.(Model)
+	if m.topModal() != modalRules || small.Filter != FilterExclude {
+		t.Fatalf("Expected the rules pane back with small excluded, got %v", m.modals)
+	}
+	if rules := m.savedRules(); len(rules) != 2 || rules[1].Pattern != "small/**" {
+		t.Errorf("Expected the new rule in the pane, got %+v", rules)
+	}
+
+	result, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if m = result.(Model); m.topModal() != modalNone {
+		t.Errorf("Expected Esc to close the rules pane, got %v", m.modals)
+	}
+}
+
+func TestEditPrompt(t *testing.T) {
+	model := newTestModel()
+	text := ""
+	for _, key := range []tea.KeyMsg{
+		runeKey("Fotos"),
+		{Type: tea.KeySpace, Runes: []rune(" ")},
+		runeKey("é"),
+		{Type: tea.KeyBackspace},
+		runeKey("ü"),
+	} {
+		if used, _ := model.editPrompt(&text, key); !used {
+			t.Errorf("Expected %v to edit the text", key)
+		}
+	}
+	if text != "Fotos ü" {
+		t.Errorf("Expected a whole character removed and the space kept, got %q", text)
+	}
+	if used, _ := model.editPrompt(&text, tea.KeyMsg{Type: tea.KeyEnter}); used || text != "Fotos ü" {
+		t.Errorf("Expected Enter to be left to the prompt")
+	}
+
+	// The depth prompt keeps the digits of what is typed
+	depth := ""
+	model.depthInput = &depth
+	model.openModal(modalDepthPrompt)
+	m := pressKeys(*model, runeKey("2"), tea.KeyMsg{Type: tea.KeySpace}, runeKey("x"))
+	if got := m.promptText(); !strings.HasPrefix(got, "Expand to depth: 2█") {
+		t.Errorf("Expected only digits in the depth, got %q", got)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// countTimeout is how long a count waits for more digits or a motion before
+// it is dropped
+const countTimeout = 500 * time.Millisecond
+
+// countTimeoutMsg ends the count typed so far, unless more keys followed
+type countTimeoutMsg struct {
+	gen int
+}
+
+// screenRows is the number of rows below the header
+func (m *Model) screenRows() int {
+	if h := m.height - 4; h > 0 {
+		return h
+	}
+	return 20
+}
+
+// listHeight is the number of tree rows that fit on the screen below the
+// pinned parents of the top row
+func (m *Model) listHeight() int {
+	return m.screenRows() - len(m.stickyParents())
+}
+
+// moveCursor moves the cursor by delta rows, stopping at either end
+func (m *Model) moveCursor(delta int) {
+	m.cursor = max(0, min(m.cursor+delta, len(m.visibleNodes)-1))
+	m.adjustScroll()
+}
+
+// handleNavKey handles vim-style count prefixes ("10j") and page motions.
+// It reports whether the key was used up; other keys are handled as usual
+// once a pending count is resolved.
+func (m *Model) handleNavKey(key string) (bool, tea.Cmd) {
+	// A count can't start with 0
+	if len(key) == 1 && key[0] >= '0' && key[0] <= '9' && (m.countPrefix != "" || key != "0") {
+		m.countPrefix += key
+		m.countGen++
+		gen := m.countGen
+		return true, tea.Tick(countTimeout, func(time.Time) tea.Msg {
+			return countTimeoutMsg{gen: gen}
+		})
+	}
+
+	count, counted := 1, false
+	if n, err := strconv.Atoi(m.countPrefix); err == nil && n > 0 {
+		count, counted = n, true
+	}
+
+	if m.pendingG {
+		m.pendingG = false
+		if key == "g" {
+			m.countPrefix = ""
+			m.moveCursor(count - 1 - m.cursor)
+			return true, nil
+		}
+	}
+
+	// Pages don't shrink with the pinned parents, so they stay the same size
+	page := m.screenRows()
+	switch key {
+	case "g":
+		m.pendingG = true
+		return true, nil
+	case "up", "k":
+		m.moveCursor(-count)
+	case "down", "j":
+		m.moveCursor(count)
+	case "ctrl+u":
+		m.moveCursor(-count * max(1, page/2))
+	case "ctrl+d":
+		m.moveCursor(count * max(1, page/2))
+	case "pgup":
+		m.moveCursor(-count * page)
+	case "pgdown":
+		m.moveCursor(count * page)
+	case "G":
+		// Without a count G goes to the last row, with one to that row
+		target := len(m.visibleNodes) - 1
+		if counted {
+			target = count - 1
+		}
+		m.moveCursor(target - m.cursor)
+	default:
+		m.flushCount()
+		return false, nil
+	}
+	m.countPrefix = ""
+	return true, nil
+}
+
+// flushCount ends a pending count that wasn't followed by a motion. A lone
+// digit does nothing; the sort modes are chosen in the sort menu.
+func (m *Model) flushCount() {
+	m.countPrefix = ""
+}
+
+// applyCountTimeout ends the count once no key followed it for a while
+func (m *Model) applyCountTimeout(msg countTimeoutMsg) {
+	if msg.gen == m.countGen && m.countPrefix != "" {
+		m.flushCount()
+	}
+}
+
+// setSortMode sorts the tree by mode
+func (m *Model) setSortMode(mode SortMode) {
+	m.sortMode = mode
+	if m.root != nil {
+		m.resortTree(m.root)
+		m.updateVisibleNodes()
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func newNavTestModel(files int) Model {
+	root := &FileNode{Name: "root", Path: "/root", IsDir: true, Expanded: true}
+	for i := range files {
+		name := fmt.Sprintf("f%03d", i)
+		root.Children = append(root.Children, &FileNode{Name: name, Path: "/root/" + name, Size: int64(i), Parent: root})
+	}
+	model := newTestModel()
+	model.root = root
+	model.height = 14 // 10 rows of tree
+	model.updateVisibleNodes()
+	return *model
+}
+
+func pressKeys(model Model, keys ...tea.KeyMsg) Model {
+	for _, key := range keys {
+		updated, _ := model.Update(key)
+		model = updated.(Model)
+	}
+	return model
+}
+
+func runeKey(s string) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+func TestCountedMotions(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []tea.KeyMsg
+		want int
+	}{
+		{"count down", []tea.KeyMsg{runeKey("1"), runeKey("0"), runeKey("j")}, 10},
+		{"count stops at end", []tea.KeyMsg{runeKey("9"), runeKey("9"), runeKey("j")}, 50},
+		{"bottom", []tea.KeyMsg{runeKey("G")}, 50},
+		{"line", []tea.KeyMsg{runeKey("5"), runeKey("G")}, 4},
+		{"top", []tea.KeyMsg{runeKey("G"), runeKey("g"), runeKey("g")}, 0},
+		{"half page", []tea.KeyMsg{{Type: tea.KeyCtrlD}, {Type: tea.KeyCtrlD}}, 10},
+		{"page", []tea.KeyMsg{{Type: tea.KeyPgDown}, {Type: tea.KeyPgDown}, {Type: tea.KeyPgUp}}, 10},
+		{"counted page", []tea.KeyMsg{runeKey("3"), {Type: tea.KeyPgDown}, {Type: tea.KeyCtrlU}}, 25},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model := pressKeys(newNavTestModel(50), tt.keys...)
+			if model.cursor != tt.want {
+				t.Errorf("Expected the cursor on row %d, got %d", tt.want, model.cursor)
+			}
+			if model.cursor < model.scrollOffset || model.cursor >= model.scrollOffset+model.listHeight() {
+				t.Errorf("Expected row %d to be scrolled into view, offset is %d", model.cursor, model.scrollOffset)
+			}
+		})
+	}
+}
+
+func TestDigitAloneDoesNothing(t *testing.T) {
+	model := pressKeys(newNavTestModel(3), runeKey("2"))
+	updated, _ := model.Update(countTimeoutMsg{gen: model.countGen})
+	if result := updated.(Model); result.sortMode != SortByName || result.countPrefix != "" {
+		t.Errorf("Expected 2 alone to end the count without sorting")
+	}
+
+	// A key other than a motion ends the count at once
+	model = pressKeys(newNavTestModel(3), runeKey("3"), runeKey("v"))
+	if model.sortMode != SortByName || model.countPrefix != "" {
+		t.Errorf("Expected 3 followed by another key to end the count without sorting")
+	}
+
+	// A counted motion still moves
+	model = pressKeys(newNavTestModel(3), runeKey("2"), runeKey("j"))
+	if model.cursor != 2 {
+		t.Errorf("Expected 2j to move two rows, got row %d", model.cursor)
+	}
+}
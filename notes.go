@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// notesFileFor returns where the notes on the entries of a root are kept:
+// next to its filter file, so that they travel with it to whoever else
+// curates the rules
+func notesFileFor(filterFile string) string {
+	if filterFile == "" || filterFile == "-" {
+		return ""
+	}
+	return filterFile + ".notes"
+}
+
+// loadNotes reads the notes of a root, a JSON object from filter paths such
+// as "/Photos/old" to their text. A missing file has no notes.
+func loadNotes(path string) (map[string]string, error) {
+	notes := make(map[string]string)
+	if path == "" {
+		return notes, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return notes, nil
+	}
+	if err != nil {
+		return notes, err
+	}
+	if err := json.Unmarshal(data, &notes); err != nil {
+		return notes, fmt.Errorf("%s: %w", path, err)
+	}
+	return notes, nil
+}
+
+// saveNotes writes the notes of a root, sorted by path so that the file
+// diffs well, or removes the file once the last note is gone
+func saveNotes(path string, notes map[string]string) error {
+	if path == "" {
+		return nil
+	}
+	if len(notes) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(notes, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, "%s\n", data)
+		return err
+	})
+}
+
+// notesFile returns the notes file of the root holding path
+func (m *Model) notesFile(path string) string {
+	if m.toStdout {
+		return ""
+	}
+	root := rootPathFor(path)
+	for _, r := range m.roots {
+		if r.path == root {
+			return notesFileFor(r.filterFile)
+		}
+	}
+	return notesFileFor(m.filterFile)
+}
+
+// noteFor returns the note on node, or "" without one
+func (m *Model) noteFor(node *FileNode) string {
+	return m.notes[rootPathFor(node.Path)][getFilterPath(node.Path)]
+}
+
+// noteLabel marks rows with a note after their sizes
+func (m *Model) noteLabel(node *FileNode) string {
+	if m.noteFor(node) != "" {
+		return " noted"
+	}
+	return ""
+}
+
+// selectedNote is the note on the selected entry, shown in the status line
+// while nothing else is
+func (m *Model) selectedNote() string {
+	if node := m.selectedNode(); node != nil {
+		if note := m.noteFor(node); note != "" {
+			return "Note on " + node.Name + ": " + note
+		}
+	}
+	return ""
+}
+
+// nodeNotePrompt is the note on an entry being typed
+type nodeNotePrompt struct {
+	node  *FileNode
+	input string
+}
+
+// startNodeNote asks for the note on the selected entry, starting from the
+// one it has
+func (m *Model) startNodeNote() {
+	node := m.selectedNode()
+	if node == nil {
+		return
+	}
+	if m.readOnly {
+		m.statusMsg = "Notes can't be changed: opened read-only while another session edits the file"
+		return
+	}
+	m.nodeNotePrompt = &nodeNotePrompt{node: node, input: m.noteFor(node)}
+	m.openModal(modalNodeNotePrompt)
+}
+
+// setNote replaces the note on node, removing it when text is empty, and
+// writes the notes of its root
+func (m *Model) setNote(node *FileNode, text string) {
+	root, key := rootPathFor(node.Path), getFilterPath(node.Path)
+	if m.notes == nil {
+		m.notes = make(map[string]map[string]string)
+	}
+	if m.notes[root] == nil {
+		m.notes[root] = make(map[string]string)
+	}
+	if text == "" {
+		delete(m.notes[root], key)
+		m.statusMsg = "Removed the note on " + node.Name
+	} else {
+		m.notes[root][key] = text
+		m.statusMsg = "Noted " + node.Name
+	}
+	if file := m.notesFile(node.Path); file != "" {
+		if err := saveNotes(file, m.notes[root]); err != nil {
+			m.statusMsg += fmt.Sprintf(" (not saved: %v)", err)
+		} else {
+			m.statusMsg += " in " + file
+		}
+	}
+}
+
+// updateNodeNotePrompt handles typing the note on an entry
+func (m Model) updateNodeNotePrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := m.nodeNotePrompt
+	if used, cmd := m.editPrompt(&p.input, msg); used {
+		return m, cmd
+	}
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.nodeNotePrompt = nil
+		m.closeModal(modalNodeNotePrompt)
+		m.setNote(p.node, p.input)
+	case tea.KeyEsc:
+		m.nodeNotePrompt = nil
+		m.closeModal(modalNodeNotePrompt)
+	}
+	return m, nil
+}
+
+// promptText is the status line while the note is being typed
+func (p *nodeNotePrompt) promptText() string {
+	return fmt.Sprintf("Note on %s: %s█ (Enter to accept, empty removes it, Esc cancels)", p.node.Name, p.input)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestNotesRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "filter.txt.notes")
+	notes, err := loadNotes(path)
+	if err != nil || len(notes) != 0 {
+		t.Fatalf("Expected no notes before the file exists, got %v, %v", notes, err)
+	}
+
+	want := map[string]string{"/old": "waiting for Bob to confirm we can delete this", "/Photos/raw": "keep until the edits are done"}
+	if err := saveNotes(path, want); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Index(string(data), "/Photos/raw") > strings.Index(string(data), "/old") {
+		t.Errorf("Expected the notes sorted by path:\n%s", data)
+	}
+	got, err := loadNotes(path)
+	if err != nil || len(got) != 2 || got["/old"] != want["/old"] {
+		t.Errorf("loadNotes() = %v, %v, want %v", got, err, want)
+	}
+
+	// Without notes the file goes away
+	if err := saveNotes(path, map[string]string{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected the notes file removed once empty, got %v", err)
+	}
+
+	os.WriteFile(path, []byte("not json"), 0644)
+	if _, err := loadNotes(path); err == nil || !strings.Contains(err.Error(), path) {
+		t.Errorf("Expected an error naming the broken notes file, got %v", err)
+	}
+}
+
+func TestNodeNote(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, big, _ := newGuardTestModel()
+	model.filterFile = filepath.Join(t.TempDir(), "filter.txt")
+	model.cursor = model.indexOfVisible(big)
+
+	keys := []tea.KeyMsg{runeKey("c")}
+	for _, r := range "ask Bob" {
+		if r == ' ' {
+			keys = append(keys, tea.KeyMsg{Type: tea.KeySpace})
+		} else {
+			keys = append(keys, runeKey(string(r)))
+		}
+	}
+	m := pressKeys(*model, append(keys, tea.KeyMsg{Type: tea.KeyEnter})...)
+	if m.topModal() != modalNone {
+		t.Fatalf("Expected Enter to close the note prompt")
+	}
+	if m.noteFor(big) != "ask Bob" {
+		t.Fatalf("Expected the note on big, got %q", m.noteFor(big))
+	}
+	notes, err := loadNotes(model.filterFile + ".notes")
+	if err != nil || notes["/big"] != "ask Bob" {
+		t.Errorf("Expected the note saved next to the filter file, got %v, %v", notes, err)
+	}
+
+	// The row is marked and the status line shows the note while selected
+	m = pressKeys(m, runeKey("j"), runeKey("k"))
+	view := m.View()
+	if !strings.Contains(view, "noted") || !strings.Contains(view, "Note on big: ask Bob") {
+		t.Errorf("Expected the note on the row and in the status line:\n%s", view)
+	}
+
+	// Editing starts from the note, and emptying it removes it
+	m = pressKeys(m, runeKey("c"))
+	if m.nodeNotePrompt == nil || m.nodeNotePrompt.input != "ask Bob" {
+		t.Fatalf("Expected the prompt to start from the note, got %+v", m.nodeNotePrompt)
+	}
+	for range "ask Bob" {
+		m = pressKeys(m, tea.KeyMsg{Type: tea.KeyBackspace})
+	}
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.noteFor(big) != "" {
+		t.Errorf("Expected the note removed, got %q", m.noteFor(big))
+	}
+	if _, err := os.Stat(model.filterFile + ".notes"); !os.IsNotExist(err) {
+		t.Errorf("Expected the notes file removed with the last note, got %v", err)
+	}
+
+	m.readOnly = true
+	m = pressKeys(m, runeKey("c"))
+	if m.topModal() != modalNone || !strings.Contains(m.statusMsg, "read-only") {
+		t.Errorf("Expected notes refused while read-only, got %q", m.statusMsg)
+	}
+}
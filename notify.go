@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"runtime"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Values accepted by the notify config key
+const (
+	notifyOff     = "off"
+	notifyBell    = "bell"
+	notifyDesktop = "desktop"
+)
+
+// appTitle is the terminal title while no job is running
+const appTitle = "RClone Filter Editor"
+
+// notifier tells the user about jobs that finish after a long time, so the
+// terminal can be left alone while a slow scan runs
+type notifier struct {
+	mode  string        // notifyOff, notifyBell or notifyDesktop
+	after time.Duration // Jobs finishing sooner aren't announced
+	out   io.Writer     // Terminal the bell is rung on
+
+	// run starts the desktop notification command, replaced in tests
+	run func(name string, args ...string) error
+}
+
+// jobFinished returns the command announcing a finished job, or nil when the
+// job was quick, cancelled or notifications are off
+func (n notifier) jobFinished(j *job) tea.Cmd {
+	if n.mode == "" || n.mode == notifyOff || j.status == JobCancelled || j.elapsed() < n.after {
+		return nil
+	}
+
+	text := j.notification()
+	return func() tea.Msg {
+		if n.mode == notifyDesktop && n.desktop(text) == nil {
+			return nil
+		}
+		if n.out != nil {
+			fmt.Fprint(n.out, "\a")
+		}
+		return nil
+	}
+}
+
+// desktop shows text as a desktop notification with the platform's tool
+func (n notifier) desktop(text string) error {
+	run := n.run
+	if run == nil {
+		run = func(name string, args ...string) error {
+			return exec.Command(name, args...).Run()
+		}
+	}
+
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %q with title %q", text, appTitle)
+		return run("osascript", "-e", script)
+	case "linux", "freebsd", "openbsd", "netbsd":
+		return run("notify-send", appTitle, text)
+	}
+	return fmt.Errorf("desktop notifications are not supported on %s", runtime.GOOS)
+}
+
+// windowTitle describes the running job for the terminal title
+func (m *Model) windowTitle() string {
+	for _, j := range m.jobs {
+		if j.status != JobRunning {
+			continue
+		}
+		if j.kind == JobScan || j.kind == JobRescan {
+			return fmt.Sprintf("%s: %s dirs, %s files - %s", j.title,
+				formatCount(int(m.scannedDirs)), formatCount(int(m.scannedFiles)), appTitle)
+		}
+		return fmt.Sprintf("%s... - %s", j.title, appTitle)
+	}
+	return appTitle
+}
+
+// updateWindowTitle returns the command setting the terminal title, or nil
+// when it hasn't changed
+func (m *Model) updateWindowTitle() tea.Cmd {
+	title := m.windowTitle()
+	if title == m.shownTitle {
+		return nil
+	}
+	m.shownTitle = title
+	return tea.SetWindowTitle(title)
+}
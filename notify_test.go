@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func finishedJob(status JobStatus, took time.Duration) *job {
+	now := time.Now()
+	return &job{kind: JobDryRun, title: "Dry-run", status: status, started: now.Add(-took), finished: now}
+}
+
+func TestNotifyLongJobs(t *testing.T) {
+	var out bytes.Buffer
+	bell := notifier{mode: notifyBell, after: time.Minute, out: &out}
+
+	if cmd := bell.jobFinished(finishedJob(JobDone, time.Second)); cmd != nil {
+		t.Errorf("Quick jobs should not be announced")
+	}
+	if cmd := bell.jobFinished(finishedJob(JobCancelled, time.Hour)); cmd != nil {
+		t.Errorf("Cancelled jobs should not be announced")
+	}
+	if cmd := (notifier{mode: notifyOff, out: &out}).jobFinished(finishedJob(JobDone, time.Hour)); cmd != nil {
+		t.Errorf("Nothing should be announced with notify = off")
+	}
+
+	bell.jobFinished(finishedJob(JobFailed, 2 * time.Minute))()
+	if out.String() != "\a" {
+		t.Errorf("Expected the terminal bell, got %q", out.String())
+	}
+
+	if runtime.GOOS != "linux" {
+		return
+	}
+	out.Reset()
+	var args []string
+	desktop := notifier{mode: notifyDesktop, after: time.Minute, out: &out,
+		run: func(name string, a ...string) error {
+			args = append([]string{name}, a...)
+			return nil
+		}}
+	desktop.jobFinished(finishedJob(JobDone, 2*time.Minute))()
+	if len(args) != 3 || args[0] != "notify-send" || !strings.Contains(args[2], "Dry-run finished") {
+		t.Errorf("Unexpected notification command %q", args)
+	}
+	if out.Len() != 0 {
+		t.Errorf("The bell should only ring when the desktop notification fails")
+	}
+}
+
+func TestWindowTitle(t *testing.T) {
+	model := newTestModel()
+	if got := model.windowTitle(); got != appTitle {
+		t.Errorf("Idle title = %q", got)
+	}
+
+	model.jobs = []*job{{kind: JobScan, title: "Scan nas", status: JobRunning}}
+	model.scannedDirs = 1200
+	model.scannedFiles = 34567
+	want := "Scan nas: 1,200 dirs, 34,567 files - " + appTitle
+	if got := model.windowTitle(); got != want {
+		t.Errorf("Scan title = %q, want %q", got, want)
+	}
+
+	if cmd := model.updateWindowTitle(); cmd == nil {
+		t.Errorf("A changed title should be set")
+	}
+	if cmd := model.updateWindowTitle(); cmd != nil {
+		t.Errorf("An unchanged title should not be set again")
+	}
+}
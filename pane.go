@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// minPaneWidth is the narrowest text a pane wraps to. On a narrower screen
+// the lines are left as they are, since wrapping would leave a word a line.
+const minPaneWidth = 20
+
+// placePane renders content in style centered on the screen. The text is
+// wrapped to fit the width of the terminal, and when it is taller than the
+// screen only the lines from paneScroll on are shown, with PgUp/PgDn to
+// scroll through the rest.
+func (m Model) placePane(style lipgloss.Style, content string) string {
+	if m.width <= 0 || m.height <= 0 {
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, style.Render(content))
+	}
+
+	// A pane with a width of its own shrinks to the screen
+	if width := style.GetWidth(); width > 0 && width+style.GetHorizontalBorderSize() > m.width {
+		style = style.Width(max(m.width-style.GetHorizontalBorderSize(), 1))
+	}
+	if width := m.width - style.GetHorizontalFrameSize(); width >= minPaneWidth {
+		lines := strings.Split(content, "\n")
+		for i, line := range lines {
+			lines[i] = wrapPaneLine(line, width)
+		}
+		content = strings.Join(lines, "\n")
+	}
+
+	lines := strings.Split(content, "\n")
+	if height, total := m.height-style.GetVerticalFrameSize(), len(lines); total > height && height > 1 {
+		shown := height - 1 // Room for the line saying how to scroll
+		start := min(max(m.paneScroll, 0), total-shown)
+		end := start + shown
+		lines = append(lines[start:end:end], lipgloss.NewStyle().Foreground(currentTheme.Muted).Render(
+			fmt.Sprintf("Lines %d-%d of %d, PgUp/PgDn scroll", start+1, end, total)))
+		content = strings.Join(lines, "\n")
+	}
+
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, style.Render(content))
+}
+
+// wrapPaneLine wraps line to width, continuing under the text it starts
+// with, or under the description of a key such as "  s   Save filters"
+func wrapPaneLine(line string, width int) string {
+	parts := strings.Split(ansi.Wrap(line, width, ""), "\n")
+	if len(parts) == 1 {
+		return line
+	}
+
+	plain := ansi.Strip(line)
+	text := strings.TrimLeft(plain, " ")
+	indent := len(plain) - len(text)
+	if gap := strings.Index(text, "  "); gap > 0 {
+		desc := strings.TrimLeft(text[gap:], " ")
+		indent += ansi.StringWidth(text[:len(text)-len(desc)])
+	}
+	if indent > width/2 {
+		return strings.Join(parts, "\n")
+	}
+
+	rest := strings.Split(ansi.Wrap(strings.Join(parts[1:], " "), width-indent, ""), "\n")
+	for i, part := range rest {
+		rest[i] = strings.Repeat(" ", indent) + part
+	}
+	return parts[0] + "\n" + strings.Join(rest, "\n")
+}
+
+// scrollPane handles PgUp and PgDn over a pane taller than the screen,
+// reporting whether it used the key
+func (m *Model) scrollPane(key string) bool {
+	step := max(m.height/2, 1)
+	switch key {
+	case "pgup":
+		m.paneScroll = max(m.paneScroll-step, 0)
+	case "pgdown":
+		// Past the end placePane keeps showing the last lines, so stop at the
+		// first offset showing them rather than keep counting
+		scrolled := *m
+		scrolled.paneScroll += step
+		last := scrolled.renderModal(m.topModal())
+		for scrolled.paneScroll > m.paneScroll {
+			scrolled.paneScroll--
+			if scrolled.renderModal(m.topModal()) != last {
+				scrolled.paneScroll++
+				break
+			}
+		}
+		m.paneScroll = scrolled.paneScroll
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestPlacePaneWrapsToNarrowScreens(t *testing.T) {
+	h := newUIHarness(t, newGoldenModel(t), 50, 200)
+	h.press("?")
+	frame := h.frame()
+	for _, line := range strings.Split(frame, "\n") {
+		if width := ansi.StringWidth(line); width > 50 {
+			t.Fatalf("Expected the help within 50 columns, got %d in:\n%s", width, frame)
+		}
+	}
+	// A key's description continues under itself
+	if !strings.Contains(frame, "│    =           Exclude the files already") {
+		t.Errorf("Expected the = line in the help:\n%s", frame)
+	}
+	if !strings.Contains(frame, "│                identical on the --dest") {
+		t.Errorf("Expected the = line to wrap under its description:\n%s", frame)
+	}
+
+	h.model.openModal(modalSaveConfirm)
+	for _, line := range strings.Split(h.frame(), "\n") {
+		if width := ansi.StringWidth(line); width > 50 {
+			t.Fatalf("Expected the save question within 50 columns, got %d in:\n%s", width, h.frame())
+		}
+	}
+}
+
+func TestPlacePaneScrolls(t *testing.T) {
+	h := newUIHarness(t, newGoldenModel(t), 80, 20)
+	h.press("?")
+	frame := h.frame()
+	if strings.Count(frame, "\n") != 20 {
+		t.Fatalf("Expected the help cut to the 20 lines of the screen:\n%s", frame)
+	}
+	if !strings.Contains(frame, "Lines 1-15 of ") || !strings.Contains(frame, "Keyboard Shortcuts:") {
+		t.Fatalf("Expected the top of the help and how to scroll:\n%s", frame)
+	}
+
+	h.press("pgdown")
+	if h.model.topModal() != modalHelp {
+		t.Fatalf("Expected PgDn to scroll the help rather than close it")
+	}
+	if frame := h.frame(); !strings.Contains(frame, "Lines 11-25 of ") {
+		t.Errorf("Expected PgDn to scroll half a screen:\n%s", frame)
+	}
+
+	// PgDn stops at the end, so that PgUp starts back from there
+	for range 20 {
+		h.press("pgdown")
+	}
+	end := h.frame()
+	if !strings.Contains(end, "Press g for a guided tour") {
+		t.Fatalf("Expected the end of the help:\n%s", end)
+	}
+	h.press("pgup")
+	if h.frame() == end {
+		t.Errorf("Expected PgUp to scroll back right away from the end")
+	}
+	h.press("pgup", "pgup", "pgup", "pgup", "pgup", "pgup", "pgup", "pgup", "pgup", "pgup")
+	if !strings.Contains(h.frame(), "Lines 1-15 of ") {
+		t.Errorf("Expected PgUp back at the top:\n%s", h.frame())
+	}
+
+	// A pane opened afterwards starts at its top
+	h.press("esc", "pgdown", "?")
+	if !strings.Contains(h.frame(), "Lines 1-15 of ") {
+		t.Errorf("Expected the help to open at its top:\n%s", h.frame())
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// What include-parents does when an include lies below a directory rclone
+// wouldn't look in
+const (
+	parentsAsk  = "ask"  // Offer the rules needed in a pane
+	parentsAuto = "auto" // Add them right away
+	parentsOff  = "off"  // Leave them to the user
+)
+
+// parentOffer is the chain of "+ dir/" rules rclone needs to reach an
+// included node, because a rule such as "- *" keeps it out of a directory
+// above it
+type parentOffer struct {
+	node    *FileNode
+	dirs    []string // Filter paths of the directories, outermost first
+	blocker string   // The rule keeping rclone out, such as "- *"
+}
+
+// parentPattern is the rule pattern letting rclone into dir, such as
+// "/Photos/" for "/Photos". It is anchored, so that it doesn't match a
+// directory of the same name further down such as "/a/Photos".
+func parentPattern(dir string) string {
+	return "/" + escapeGlob(strings.TrimPrefix(dir, "/")) + "/"
+}
+
+// dirRule reports whether rclone applies pattern to directories as well as
+// files: a pattern ending in "/" or holding "**", or the catch-all "*"
+func dirRule(pattern string) bool {
+	return strings.HasSuffix(pattern, "/") || strings.Contains(pattern, "**") || pattern == "*"
+}
+
+// dirMatches reports whether the directory rule pattern matches dir, a
+// filter path such as "/Photos". As in rclone, a pattern without a slash
+// matches the name at any depth.
+func dirMatches(pattern, dir string) bool {
+	if name := strings.TrimSuffix(pattern, "/"); !strings.Contains(name, "/") && matchesRclonePattern(name, path.Base(dir)) {
+		return true
+	}
+	return matchesRclonePattern(pattern, dir+"/")
+}
+
+// dirBlocker returns where in rules the first rule rclone applies to dir is
+// when it excludes the directory, or -1 when rclone looks inside
+func dirBlocker(rules []FilterRule, dir string) int {
+	for i, rule := range rules {
+		if rule.Include != "" || rule.Disabled || !dirRule(rule.Pattern) || !dirMatches(rule.Pattern, dir) {
+			continue
+		}
+		if rule.State == FilterExclude {
+			return i
+		}
+		return -1
+	}
+	return -1
+}
+
+// parentsNeeded works out the "+ dir/" rules rclone needs to reach node
+// under the rules as they are saved, or returns nil when it already does
+func (m *Model) parentsNeeded(node *FileNode) *parentOffer {
+	var dirs []string
+	for p := node.Parent; p != nil && p.Parent != nil && p.Path != rootPathFor(p.Path); p = p.Parent {
+		dirs = append(dirs, getFilterPath(p.Path))
+	}
+	slices.Reverse(dirs)
+
+	rules := m.savedRules()
+	offer := &parentOffer{node: node}
+	for _, dir := range dirs {
+		if i := dirBlocker(rules, dir); i >= 0 {
+			if offer.blocker == "" {
+				offer.blocker = rules[i].String()
+			}
+			offer.dirs = append(offer.dirs, dir)
+		}
+	}
+	if len(offer.dirs) == 0 {
+		return nil
+	}
+	return offer
+}
+
+// addParents adds the rules of offer, each right before the rule that kept
+// rclone out of its directory, and returns them as they read in the file
+func (m *Model) addParents(offer *parentOffer) []string {
+	rules := m.savedRules()
+	var added []string
+	for _, dir := range offer.dirs {
+		i := dirBlocker(rules, dir)
+		if i < 0 {
+			continue
+		}
+		// Rules pulled in by an include go in with their directive
+		for i > 0 && rules[i].Origin != "" {
+			i--
+		}
+		rule := FilterRule{Pattern: parentPattern(dir), State: FilterInclude, Section: rules[i].Section}
+		rules = slices.Insert(rules, i, rule)
+		added = append(added, "`"+rule.String()+"`")
+	}
+
+	m.filterGen++
+	m.filters.replace(rules)
+	for _, dir := range offer.dirs {
+		m.filters.added[parentPattern(dir)] = true
+	}
+	m.filterCache.clear()
+	return added
+}
+
+// checkParents follows an include of node with the rules rclone needs to
+// look inside the directories above it, as include-parents says
+func (m *Model) checkParents(node *FileNode) {
+	if m.filesFrom != nil || m.includeParents == parentsOff {
+		return
+	}
+	offer := m.parentsNeeded(node)
+	if offer == nil {
+		return
+	}
+	if m.includeParents == parentsAuto {
+		added := m.addParents(offer)
+		note := fmt.Sprintf("added %s so that rclone looks inside", strings.Join(added, ", "))
+		m.statusMsg += "; " + note
+		m.noteActivity(note)
+		return
+	}
+	m.parentOffer = offer
+	m.openModal(modalParents)
+}
+
+// updateParentsPane handles keys while the rules an include needs are
+// offered
+func (m Model) updateParentsPane(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter", "y":
+		added := m.addParents(m.parentOffer)
+		m.statusMsg = fmt.Sprintf("Added %s so that rclone looks inside", strings.Join(added, ", "))
+		m.noteActivity("added " + strings.Join(added, ", "))
+		m.parentOffer = nil
+		m.closeModal(modalParents)
+	case "esc", "n", "q":
+		m.statusMsg = fmt.Sprintf("rclone won't reach %s while %s keeps it out", m.parentOffer.node.Name, m.parentOffer.blocker)
+		m.parentOffer = nil
+		m.closeModal(modalParents)
+	case "ctrl+c":
+		m.cancel()
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m Model) renderParents() string {
+	paneStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(currentTheme.Border).
+		Padding(1, 2)
+	includeStyle := lipgloss.NewStyle().Foreground(currentTheme.Include)
+
+	offer := m.parentOffer
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s is included, but rclone won't look inside the directories\n", strings.TrimPrefix(getFilterPath(offer.node.Path), "/"))
+	fmt.Fprintf(&b, "above it while %s keeps them out. These rules let it in:\n\n", offer.blocker)
+	for _, dir := range offer.dirs {
+		b.WriteString("  " + includeStyle.Render(FilterRule{Pattern: parentPattern(dir), State: FilterInclude}.String()) + "\n")
+	}
+	b.WriteString("\nEach goes right before the rule excluding its directory.\n")
+	b.WriteString("Enter add them, Esc leave them out (include-parents = auto adds them without asking)")
+
+	return m.placePane(paneStyle, b.String())
+}
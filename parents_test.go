@@ -0,0 +1,109 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestDirBlocker(t *testing.T) {
+	tests := []struct {
+		rules    []FilterRule
+		dir      string
+		expected int
+	}{
+		{[]FilterRule{{Pattern: "*", State: FilterExclude}}, "/Photos/2024", 0},
+		{[]FilterRule{{Pattern: "Photos/2024/a.jpg", State: FilterInclude}, {Pattern: "Photos/**", State: FilterExclude}}, "/Photos", 1},
+		{[]FilterRule{{Pattern: "Photos/", State: FilterInclude}, {Pattern: "*", State: FilterExclude}}, "/Photos", -1},
+		// The rules added for parents are anchored to the root
+		{[]FilterRule{{Pattern: parentPattern("/Photos"), State: FilterInclude}, {Pattern: "*", State: FilterExclude}}, "/Photos", -1},
+		{[]FilterRule{{Pattern: parentPattern("/Photos"), State: FilterInclude}, {Pattern: "*", State: FilterExclude}}, "/a/Photos", 1},
+		// File rules don't keep rclone out of directories
+		{[]FilterRule{{Pattern: "*.jpg", State: FilterExclude}}, "/Photos.jpg", -1},
+		{[]FilterRule{{Pattern: "Photos/**", State: FilterExclude, Disabled: true}}, "/Photos", -1},
+		{[]FilterRule{{Pattern: "Videos/**", State: FilterExclude}}, "/Photos", -1},
+	}
+	for _, tt := range tests {
+		if got := dirBlocker(tt.rules, tt.dir); got != tt.expected {
+			t.Errorf("dirBlocker(%v, %s) = %d; want %d", tt.rules, tt.dir, got, tt.expected)
+		}
+	}
+}
+
+func TestIncludeOffersParents(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, big, _ := newGuardTestModel()
+	model.includeParents = parentsAsk
+	model.filters = newFilterDocument([]FilterRule{{Pattern: "*.tmp", State: FilterExclude}, {Pattern: "*", State: FilterExclude}})
+	model.reapplyFiltersToTree(model.root)
+	big.Expanded = true
+	model.updateVisibleNodes()
+	model.cursor = model.indexOfVisible(big.Children[0])
+
+	m := pressKeys(*model, tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")})
+	if m.topModal() != modalParents {
+		t.Fatalf("Expected the parent rules offered after the include")
+	}
+	view := m.View()
+	for _, expected := range []string{"big/0.bin is included", "while - * keeps them out", "+ /big/"} {
+		if !strings.Contains(view, expected) {
+			t.Errorf("Expected %q in:\n%s", expected, view)
+		}
+	}
+
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEnter})
+	var got []string
+	for _, rule := range m.savedRules() {
+		got = append(got, rule.String())
+	}
+	if strings.Join(got, ", ") != "- *.tmp, + big/0.bin, + /big/, - *" {
+		t.Errorf("Expected + /big/ before - *, got %v", got)
+	}
+	if !m.filters.added["/big/"] || !strings.Contains(m.statusMsg, "Added `+ /big/`") {
+		t.Errorf("Expected the rule added as new, status %q", m.statusMsg)
+	}
+
+	// Leaving them out is fine too, and including the next file asks again
+	m.cursor = m.indexOfVisible(big.Children[1])
+	m.filters.remove("/big/")
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")}, tea.KeyMsg{Type: tea.KeyEsc})
+	if m.filters.index("/big/") >= 0 || !strings.Contains(m.statusMsg, "rclone won't reach 1.bin while - * keeps it out") {
+		t.Errorf("Expected no rule after Esc, status %q", m.statusMsg)
+	}
+}
+
+func TestIncludeAddsParentsAutomatically(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, big, small := newGuardTestModel()
+	model.includeParents = parentsAuto
+	model.filters = newFilterDocument([]FilterRule{{Pattern: "*.tmp", State: FilterExclude}, {Pattern: "big/**", State: FilterExclude}})
+	model.reapplyFiltersToTree(model.root)
+
+	model.applyChange(big.Children[0], FilterInclude)
+	if model.topModal() == modalParents {
+		t.Errorf("Expected no question with include-parents = auto")
+	}
+	var got []string
+	for _, rule := range model.savedRules() {
+		got = append(got, rule.String())
+	}
+	if strings.Join(got, ", ") != "- *.tmp, + big/0.bin, + /big/, - big/**" {
+		t.Errorf("Expected + /big/ before - big/**, got %v", got)
+	}
+	if !strings.HasSuffix(model.statusMsg, "; added `+ /big/` so that rclone looks inside") {
+		t.Errorf("Expected the added rule in the status line, got %q", model.statusMsg)
+	}
+
+	// Nothing keeps rclone out of small
+	model.applyChange(small.Children[0], FilterInclude)
+	if model.filters.index("/small/") >= 0 {
+		t.Errorf("Expected no rule for a directory rclone looks in")
+	}
+}
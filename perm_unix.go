@@ -0,0 +1,44 @@
+//go:build unix
+
+package main
+
+import (
+	"io/fs"
+	"os"
+	"slices"
+	"sync"
+	"syscall"
+)
+
+// userGroups returns the effective and supplementary groups of the process
+var userGroups = sync.OnceValue(func() []int {
+	groups, _ := os.Getgroups()
+	return append(groups, os.Getegid())
+})
+
+// readable reports whether the current user may read the file described by
+// info, judging by its permission bits, owner and group. Directories also
+// need the execute bit to be listed.
+func readable(info fs.FileInfo) bool {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || info.Mode()&fs.ModeSymlink != 0 {
+		return true
+	}
+	uid := os.Geteuid()
+	if uid == 0 {
+		return true
+	}
+
+	need := fs.FileMode(0o4)
+	if info.IsDir() {
+		need = 0o5
+	}
+	perm := info.Mode().Perm()
+	switch {
+	case int(st.Uid) == uid:
+		perm >>= 6
+	case slices.Contains(userGroups(), int(st.Gid)):
+		perm >>= 3
+	}
+	return perm&need == need
+}
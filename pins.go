@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// defaultPinsFile returns where pinned paths are kept between sessions
+func defaultPinsFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "rclone-filter-editor", "pins")
+}
+
+// loadPins reads the pinned paths, one full path per line, of every tree
+// edited so far. A missing file has no pins.
+func loadPins(path string) (map[string]bool, error) {
+	pins := make(map[string]bool)
+	if path == "" {
+		return pins, nil
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return pins, nil
+	}
+	if err != nil {
+		return pins, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			pins[line] = true
+		}
+	}
+	return pins, scanner.Err()
+}
+
+// savePins writes the pinned paths, sorted so that the file diffs well
+func savePins(path string, pins map[string]bool) error {
+	if path == "" {
+		return nil
+	}
+	paths := make([]string, 0, len(pins))
+	for p := range pins {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, func(w io.Writer) error {
+		for _, p := range paths {
+			if _, err := fmt.Fprintln(w, p); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// isPinned reports whether node is kept at the top of its directory
+func (m *Model) isPinned(node *FileNode) bool {
+	return node.Parent != nil && !m.isHiddenRoot(node.Parent) && m.pins[node.Path]
+}
+
+// pinLabel marks pinned rows after their sizes
+func (m *Model) pinLabel(node *FileNode) string {
+	if m.isPinned(node) {
+		return " pinned"
+	}
+	return ""
+}
+
+// togglePin pins the selected entry to the top of its directory, whatever the
+// sort order, or unpins it. Pins are remembered for the next sessions.
+func (m *Model) togglePin() {
+	node := m.selectedNode()
+	if node == nil || node.Parent == nil || m.isHiddenRoot(node.Parent) {
+		m.statusMsg = "Only entries inside a directory can be pinned"
+		return
+	}
+	if m.pins == nil {
+		m.pins = make(map[string]bool)
+	}
+	if m.pins[node.Path] {
+		delete(m.pins, node.Path)
+		m.statusMsg = "Unpinned " + node.Name
+	} else {
+		m.pins[node.Path] = true
+		m.statusMsg = "Pinned " + node.Name + " to the top of " + node.Parent.Name + ", K again unpins it"
+	}
+	m.sortChildren(node.Parent.Children)
+	m.updateVisibleNodes()
+	if i := m.indexOfVisible(node); i >= 0 {
+		m.cursor = i
+		m.adjustScroll()
+	}
+	if err := savePins(m.pinsFile, m.pins); err != nil {
+		m.statusMsg += fmt.Sprintf(" (not remembered: %v)", err)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPinKeepsEntryOnTop(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, big, small := newGuardTestModel()
+	model.pinsFile = filepath.Join(t.TempDir(), "rclone-filter-editor", "pins")
+	model.cursor = model.indexOfVisible(small)
+
+	result := pressKeys(*model, runeKey("K"))
+	if result.root.Children[0] != small || result.selectedNode() != small {
+		t.Fatalf("Expected small pinned above big with the cursor on it")
+	}
+	if view := result.View(); !strings.Contains(view, "files) pinned") {
+		t.Errorf("Expected the pinned row to be marked:\n%s", view)
+	}
+
+	// Pins stay on top whatever the sort order
+	for _, mode := range []SortMode{SortByName, SortBySize, SortByFileCount, SortByLastModified} {
+		result.setSortMode(mode)
+		if result.root.Children[0] != small {
+			t.Errorf("Expected small to stay on top in sort mode %v", mode)
+		}
+	}
+
+	pins, err := loadPins(result.pinsFile)
+	if err != nil || !pins["/test/small"] || len(pins) != 1 {
+		t.Fatalf("Expected the pin to be saved, got %v, %v", pins, err)
+	}
+
+	// A later session sorts the pinned entry first
+	next, _, _ := newGuardTestModel()
+	next.pins = pins
+	next.resortTree(next.root)
+	if next.root.Children[0].Name != "small" {
+		t.Errorf("Expected the saved pin to be used by the next session")
+	}
+
+	result.setSortMode(SortByName)
+	result = pressKeys(result, runeKey("K"))
+	if result.root.Children[0] != big || result.pins["/test/small"] {
+		t.Errorf("Expected K again to unpin small")
+	}
+	if data, _ := os.ReadFile(result.pinsFile); len(data) != 0 {
+		t.Errorf("Expected no pins left in the file, got %q", data)
+	}
+}
+
+func TestPinNeedsParent(t *testing.T) {
+	model, _, _ := newGuardTestModel()
+	model.cursor = 0
+	model.togglePin()
+	if len(model.pins) != 0 || !strings.Contains(model.statusMsg, "inside a directory") {
+		t.Errorf("Expected the root not to be pinned, status %q", model.statusMsg)
+	}
+}
+
+func TestLoadPinsMissingFile(t *testing.T) {
+	pins, err := loadPins(filepath.Join(t.TempDir(), "pins"))
+	if err != nil || len(pins) != 0 {
+		t.Errorf("Expected no pins from a missing file, got %v, %v", pins, err)
+	}
+}
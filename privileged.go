@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// newPrivilegedLister lists directories with command standing in for
+// rclone, such as "sudo -n rclone", which runs "lsjson" on each directory
+// with the rights the current user lacks. The command can't ask for a
+// password, since the screen belongs to the editor.
+func newPrivilegedLister(command string) lister {
+	argv := strings.Fields(command)
+	l := newRemoteLister(0)
+	l.run = func(ctx context.Context, args ...string) ([]byte, error) {
+		cmd := exec.CommandContext(ctx, argv[0], append(argv[1:], args...)...)
+		out, err := cmd.Output()
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("%s: %s", command, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return out, err
+	}
+	return l
+}
+
+// deniedDirs returns the local directories the scan couldn't list for lack
+// of permission and that rclone would try to read, leaving them empty in
+// the tree
+func (m *Model) deniedDirs() []*FileNode {
+	var dirs []*FileNode
+	for _, node := range m.issues {
+		if node.IsDir && node.Unreadable && node.Filter != FilterExclude && !isRemotePath(node.Path) {
+			dirs = append(dirs, node)
+		}
+	}
+	return dirs
+}
+
+// permissionHint sums up the directories left empty by permission errors,
+// with what can be done about them, or returns "" without any
+func (m *Model) permissionHint() string {
+	dirs := m.deniedDirs()
+	if len(dirs) == 0 {
+		return ""
+	}
+	hint := fmt.Sprintf("%s left empty because permission was denied", dirCount(len(dirs)))
+	if m.privilegedLister != "" {
+		return hint + ", r in the list (W) rescans them with " + m.privilegedLister
+	}
+	return hint + " (W lists them): run as a user who can read them, or set privileged-lister, such as sudo -n rclone, to rescan them"
+}
+
+// dirCount is "1 directory" or "N directories"
+func dirCount(n int) string {
+	if n == 1 {
+		return "1 directory"
+	}
+	return formatCount(n) + " directories"
+}
+
+// rescanDenied rescans the directories left empty by permission errors with
+// the privileged-lister command
+func (m *Model) rescanDenied() tea.Cmd {
+	if m.privilegedLister == "" {
+		m.statusMsg = "Set privileged-lister in the config, such as sudo -n rclone, to rescan these directories"
+		return nil
+	}
+	dirs := m.deniedDirs()
+	if len(dirs) == 0 {
+		m.statusMsg = "No directory was left empty by a permission error"
+		return nil
+	}
+	l := newPrivilegedLister(m.privilegedLister)
+	cmds := []tea.Cmd{refreshTick()}
+	for _, dir := range dirs {
+		cmds = append(cmds, m.rescanWith(dir, l, fmt.Sprintf("Rescan %s with %s", dir.Name, m.privilegedLister)))
+	}
+	m.statusMsg = fmt.Sprintf("Rescanning %s with %s", dirCount(len(dirs)), m.privilegedLister)
+	return tea.Batch(cmds...)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestPermissionHint(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, big, small := newGuardTestModel()
+	if hint := model.permissionHint(); hint != "" {
+		t.Errorf("Expected no hint without permission errors, got %q", hint)
+	}
+
+	for _, dir := range []*FileNode{big, small} {
+		dir.Unreadable, dir.ListErr = true, fs.ErrPermission
+	}
+	model.setNodeFilter(small, FilterExclude)
+	model.issues = model.collectIssues()
+	hint := model.permissionHint()
+	if !strings.Contains(hint, "1 directory left empty because permission was denied") || !strings.Contains(hint, "set privileged-lister") {
+		t.Errorf("Expected the excluded directory left out and guidance, got %q", hint)
+	}
+
+	model.privilegedLister = "sudo -n rclone"
+	if hint := model.permissionHint(); !strings.Contains(hint, "r in the list (W) rescans them with sudo -n rclone") {
+		t.Errorf("Expected the rescan offered, got %q", hint)
+	}
+
+	model.privilegedLister = ""
+	if cmd := model.rescanDenied(); cmd != nil || !strings.Contains(model.statusMsg, "Set privileged-lister") {
+		t.Errorf("Expected no rescan without a helper, got %q", model.statusMsg)
+	}
+}
+
+func TestRescanDeniedWithHelper(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The helper is a shell script")
+	}
+	rootDir := t.TempDir()
+	os.MkdirAll(filepath.Join(rootDir, "secret"), 0755)
+
+	// The helper stands in for "sudo -n rclone", noting how it was called
+	helper := filepath.Join(t.TempDir(), "helper")
+	args := helper + ".args"
+	os.WriteFile(helper, []byte("#!/bin/sh\necho \"$@\" >> "+args+"\necho '[{\"Name\":\"hidden.txt\",\"Size\":7,\"IsDir\":false}]'\n"), 0755)
+
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = rootDir
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, apply := newScanTestModel(rootDir)
+	defer model.cancel()
+	model.newScanner().scan(model.root)
+	result := apply(*model)
+
+	// As the scan leaves a directory it may not list
+	secret := result.root.Children[0]
+	secret.Unreadable, secret.ListErr = true, fs.ErrPermission
+	result.issues = result.collectIssues()
+	result.privilegedLister = helper + " --quiet"
+
+	msg := result.rescanWith(secret, newPrivilegedLister(result.privilegedLister), "")()
+	result = apply(result)
+	updated, _ := result.Update(msg)
+	result = updated.(Model)
+
+	if len(secret.Children) != 1 || secret.Children[0].Name != "hidden.txt" || secret.Unreadable {
+		t.Fatalf("Expected the helper's listing in the tree, got %+v", secret.Children)
+	}
+	if len(result.deniedDirs()) != 0 {
+		t.Errorf("Expected no denied directory left, got %d", len(result.deniedDirs()))
+	}
+	data, _ := os.ReadFile(args)
+	if want := "--quiet lsjson --no-mimetype " + secret.Path; strings.TrimSpace(string(data)) != want {
+		t.Errorf("Helper called with %q, want %q", data, want)
+	}
+}
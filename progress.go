@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// progressEvery is how many listed directories a progress event stands for
+const progressEvery = 100
+
+// progressEvent is one line written by --progress-json. Event is one of
+// "started", "scan_started", "progress", "scan_finished", "saved" and
+// "finished"; the counts are totals since the run started.
+type progressEvent struct {
+	Event string    `json:"event"`
+	Time  time.Time `json:"time"`
+	Mode  string    `json:"mode,omitempty"`  // started: export, script or serve
+	Root  string    `json:"root,omitempty"`  // scan_started, scan_finished
+	Saved []string  `json:"saved,omitempty"` // saved: the filter files written
+	Error string    `json:"error,omitempty"` // scan_finished: why the root couldn't be listed
+	Exit  *int      `json:"exit,omitempty"`  // finished: the exit code
+
+	Dirs  int64 `json:"dirs"`
+	Files int64 `json:"files"`
+	Bytes int64 `json:"bytes"`
+}
+
+// progressReporter writes the progress of a run without a screen to out as
+// JSON lines, for wrapper scripts and GUIs to show their own progress. A nil
+// reporter writes nothing.
+type progressReporter struct {
+	out io.Writer
+	now func() time.Time
+	mu  sync.Mutex
+
+	dirs, files, bytes int64
+}
+
+func newProgressReporter(out io.Writer) *progressReporter {
+	return &progressReporter{out: out, now: time.Now}
+}
+
+// emit writes e with the current counts
+func (p *progressReporter) emit(e progressEvent) {
+	if p == nil {
+		return
+	}
+	e.Time = p.now()
+	e.Dirs, e.Files, e.Bytes = atomic.LoadInt64(&p.dirs), atomic.LoadInt64(&p.files), atomic.LoadInt64(&p.bytes)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	json.NewEncoder(p.out).Encode(e)
+}
+
+// finished writes the last event of the run, with its exit code
+func (p *progressReporter) finished(code int) {
+	p.emit(progressEvent{Event: "finished", Exit: &code})
+}
+
+// wrap returns l counting what it lists for the progress events, or l
+// itself without a reporter
+func (p *progressReporter) wrap(l lister) lister {
+	if p == nil {
+		return l
+	}
+	if l == nil {
+		l = localLister{}
+	}
+	return progressLister{lister: l, progress: p}
+}
+
+// progressLister counts the directories and files a lister lists, writing a
+// progress event every progressEvery directories
+type progressLister struct {
+	lister
+	progress *progressReporter
+}
+
+func (l progressLister) List(ctx context.Context, dir string) ([]dirEntry, error) {
+	entries, err := l.lister.List(ctx, dir)
+	if err != nil {
+		return entries, err
+	}
+	p := l.progress
+	for _, entry := range entries {
+		if !entry.IsDir {
+			atomic.AddInt64(&p.files, 1)
+			atomic.AddInt64(&p.bytes, entry.Size)
+		}
+	}
+	if atomic.AddInt64(&p.dirs, 1)%progressEvery == 0 {
+		p.emit(progressEvent{Event: "progress"})
+	}
+	return entries, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestProgressEvents(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/data"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	l := mapLister{"/data": {{Name: "f.txt", Size: 10}}}
+	for i := range 120 {
+		dir := fmt.Sprintf("d%03d", i)
+		l["/data"] = append(l["/data"], dirEntry{Name: dir, IsDir: true})
+		l["/data/"+dir] = []dirEntry{{Name: "g.txt", Size: 1}}
+	}
+
+	var out bytes.Buffer
+	progress := newProgressReporter(&out)
+	progress.now = func() time.Time { return time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC) }
+
+	model, _ := newScanTestModel("/data")
+	defer model.cancel()
+	model.toStdout = true
+	model.progress = progress
+	model.lister = progress.wrap(l)
+
+	progress.emit(progressEvent{Event: "started", Mode: "script"})
+	if err := model.scanNow(); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if err := model.saveAll(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	progress.finished(exitSaved)
+
+	var events []progressEvent
+	lines := bufio.NewScanner(&out)
+	for lines.Scan() {
+		var e progressEvent
+		if err := json.Unmarshal(lines.Bytes(), &e); err != nil {
+			t.Fatalf("Expected a JSON line, got %q: %v", lines.Text(), err)
+		}
+		events = append(events, e)
+	}
+	var names []string
+	for _, e := range events {
+		names = append(names, e.Event)
+	}
+	expected := "[started scan_started progress scan_finished saved finished]"
+	if got := fmt.Sprint(names); got != expected {
+		t.Fatalf("Events = %s; want %s", got, expected)
+	}
+
+	if p := events[2]; p.Dirs != 100 {
+		t.Errorf("Expected a progress event after 100 directories, got %+v", p)
+	}
+	if f := events[3]; f.Root != "/data" || f.Dirs != 121 || f.Files != 121 || f.Bytes != 130 {
+		t.Errorf("Unexpected totals %+v", f)
+	}
+	if s := events[4]; fmt.Sprint(s.Saved) != "[standard output]" {
+		t.Errorf("Expected the saved files listed, got %v", s.Saved)
+	}
+	if e := events[5]; e.Exit == nil || *e.Exit != exitSaved || !e.Time.Equal(progress.now()) {
+		t.Errorf("Expected the exit code in the last event, got %+v", e)
+	}
+}
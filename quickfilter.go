@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// quickFilter prunes the tree to the files matching a glob and the
+// directories holding them, for exploring. It only changes what is shown,
+// never the rules.
+type quickFilter struct {
+	glob    string
+	matches int         // Files shown, counted as the rows are built
+	opened  []*FileNode // Directories opened to show the matches, closed again when cleared
+}
+
+// quickMatches reports whether node matches the glob of the quick filter. As
+// in rclone, a glob without a slash matches the name at any depth.
+func (q *quickFilter) quickMatches(node *FileNode) bool {
+	if !strings.Contains(q.glob, "/") {
+		return matchesRclonePattern(q.glob, node.Name)
+	}
+	return matchesRclonePattern(q.glob, getFilterPath(node.Path))
+}
+
+// applyQuickFilter shows only the files matching glob, opening the
+// directories on the way to them
+func (m *Model) applyQuickFilter(glob string) {
+	m.clearQuickFilter()
+	q := &quickFilter{glob: glob}
+	var walk func(node *FileNode) bool
+	walk = func(node *FileNode) bool {
+		found := false
+		for _, child := range node.Children {
+			if child.IsDir {
+				found = walk(child) || found
+			} else if q.quickMatches(child) {
+				found = true
+			}
+		}
+		if found && !node.Expanded {
+			node.Expanded = true
+			q.opened = append(q.opened, node)
+		}
+		return found
+	}
+	if m.root != nil {
+		walk(m.root)
+	}
+
+	m.quick = q
+	m.refreshQuickView()
+	if q.matches == 0 {
+		m.statusMsg = fmt.Sprintf("No file matches %s", glob)
+	} else {
+		m.statusMsg = fmt.Sprintf("Showing the %s matching %s, Esc shows everything again", fileCount(q.matches), glob)
+	}
+}
+
+// clearQuickFilter shows the whole tree again, closing the directories the
+// quick filter opened
+func (m *Model) clearQuickFilter() {
+	if m.quick == nil {
+		return
+	}
+	for _, dir := range m.quick.opened {
+		dir.Expanded = false
+	}
+	m.quick = nil
+	m.refreshQuickView()
+}
+
+// refreshQuickView rebuilds the rows after the quick filter changed, keeping
+// the cursor on the tree
+func (m *Model) refreshQuickView() {
+	m.updateVisibleNodes()
+	m.cursor = max(0, min(m.cursor, len(m.visibleNodes)-1))
+	m.adjustScroll()
+}
+
+// quickHeader is the part of the header naming the quick filter
+func (m *Model) quickHeader() string {
+	if m.quick == nil {
+		return ""
+	}
+	return fmt.Sprintf(" | Only %s: %s (Esc)", m.quick.glob, fileCount(m.quick.matches))
+}
+
+// updateQuickPrompt handles typing the glob of the quick filter
+func (m Model) updateQuickPrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	input := *m.quickInput
+	if used, cmd := m.editPrompt(&input, msg); used {
+		m.quickInput = &input
+		return m, cmd
+	}
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.quickInput = nil
+		m.closeModal(modalQuickPrompt)
+		if input == "" {
+			m.clearQuickFilter()
+			return m, nil
+		}
+		m.applyQuickFilter(input)
+		return m, nil
+	case tea.KeyEsc:
+		m.quickInput = nil
+		m.closeModal(modalQuickPrompt)
+	}
+	return m, nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestQuickFilter(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, big, small := newGuardTestModel()
+	small.Children[1].Name = "9.mkv"
+	rules := model.filters.count()
+
+	m := pressKeys(*model, runeKey("*"), runeKey("*.mkv"), tea.KeyMsg{Type: tea.KeyEnter})
+	var names []string
+	for _, node := range m.visibleNodes {
+		names = append(names, node.Name)
+	}
+	if strings.Join(names, " ") != "test small 9.mkv" {
+		t.Errorf("Expected only the match and its directories, got %v", names)
+	}
+	if !strings.Contains(m.View(), "Only *.mkv: 1 file (Esc)") {
+		t.Errorf("Expected the quick filter in the header:\n%s", m.View())
+	}
+	if m.filters.count() != rules {
+		t.Errorf("Expected the rules left alone")
+	}
+
+	// A glob with a slash matches the path
+	m = pressKeys(m, runeKey("*"), runeKey("big/[0-2].bin"), tea.KeyMsg{Type: tea.KeyEnter})
+	if len(m.visibleNodes) != 5 || m.quick.matches != 3 || small.Expanded {
+		t.Errorf("Expected big with three files and small closed again, got %d rows", len(m.visibleNodes))
+	}
+
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEsc})
+	if m.quick != nil || big.Expanded || len(m.visibleNodes) != 3 {
+		t.Errorf("Expected Esc to show the tree as it was, got %d rows", len(m.visibleNodes))
+	}
+
+	m = pressKeys(m, runeKey("*"), runeKey("*.iso"), tea.KeyMsg{Type: tea.KeyEnter})
+	if m.statusMsg != "No file matches *.iso" {
+		t.Errorf("Unexpected status %q", m.statusMsg)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// toastDuration is how long a toast stays on the status line
+const toastDuration = 3 * time.Second
+
+// toastTimeoutMsg takes the toast down, unless a newer one replaced it
+type toastTimeoutMsg struct {
+	gen int
+}
+
+// toast is a short note on the status line that, unlike the status message,
+// lasts a few seconds whatever keys are pressed
+type toast struct {
+	text string
+	gen  int
+}
+
+// showToast puts text on the status line for toastDuration
+func (m *Model) showToast(text string) tea.Cmd {
+	m.toastGen++
+	m.toast = &toast{text: text, gen: m.toastGen}
+	gen := m.toastGen
+	return tea.Tick(toastDuration, func(time.Time) tea.Msg {
+		return toastTimeoutMsg{gen: gen}
+	})
+}
+
+// applyToastTimeout takes the toast down once its time is up
+func (m *Model) applyToastTimeout(msg toastTimeoutMsg) {
+	if m.toast != nil && m.toast.gen == msg.gen {
+		m.toast = nil
+	}
+}
+
+// savedCount describes what a save writes, such as "42 rules"
+func (m *Model) savedCount() string {
+	n, what := m.filters.count(), "rule"
+	if m.filesFrom != nil {
+		n, what = len(m.filesFrom.entries), "path"
+	} else if len(m.roots) > 1 {
+		n = 0
+		for _, r := range m.roots {
+			n += r.filters.count()
+		}
+	}
+	if n == 1 {
+		return "1 " + what
+	}
+	return formatCount(n) + " " + what + "s"
+}
+
+// quickSave writes the rules for w straight away and confirms it with a
+// toast, which also carries what s would have asked about: the rules to
+// review and the guard rail warnings
+func (m *Model) quickSave() tea.Cmd {
+	m.pendingSave = false
+	note, warning := m.reviewNote(), m.saveWarnings()
+	if err := m.saveAll(); err != nil {
+		m.saveFailed(err)
+		return nil
+	}
+	m.saved = true
+	m.saveErr = nil
+	text := fmt.Sprintf("Saved %s to %s at %s%s", m.savedCount(), m.filterFileNames(), m.clock().Format("15:04"), note)
+	if warning != "" {
+		text += " - warning: " + warning
+	}
+	return m.showToast(text)
+}
+
+// reviewNote lists the rules s would have offered to review before saving,
+// or is empty when there are none
+func (m *Model) reviewNote() string {
+	var parts []string
+	switch n := len(m.findRuleConflicts()); n {
+	case 0:
+	case 1:
+		parts = append(parts, "1 pattern both included and excluded")
+	default:
+		parts = append(parts, fmt.Sprintf("%d patterns both included and excluded", n))
+	}
+	switch n := len(m.findRedundantRules()); n {
+	case 0:
+	case 1:
+		parts = append(parts, "1 redundant rule")
+	default:
+		parts = append(parts, fmt.Sprintf("%d redundant rules", n))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " - " + strings.Join(parts, " and ") + ", s reviews them"
+}
+
+// saveFailed reports a failed save. The error stays on the status line until
+// a save succeeds, rather than going away with the next key.
+func (m *Model) saveFailed(err error) {
+	m.saveErr = err
+	m.toast = nil
+	m.statusMsg = "Save failed: " + err.Error()
+}
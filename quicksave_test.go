@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestQuickSave(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, _, _ := newGuardTestModel()
+	model.filterFile = filepath.Join(t.TempDir(), "filter.txt")
+	model.filters.set("big/**", FilterExclude)
+	model.filters.set("*.tmp", FilterExclude)
+
+	updated, cmd := model.Update(runeKey("w"))
+	m := updated.(Model)
+	if cmd == nil || m.toast == nil || !m.saved {
+		t.Fatalf("Expected w to save and show a toast")
+	}
+	if !strings.HasPrefix(m.toast.text, "Saved 2 rules to "+model.filterFile+" at ") {
+		t.Errorf("Unexpected toast %q", m.toast.text)
+	}
+	if data, err := os.ReadFile(model.filterFile); err != nil || !strings.Contains(string(data), "- big/**") {
+		t.Errorf("Expected the rules to be written, got %q (%v)", data, err)
+	}
+
+	// The toast outlasts keys, and goes once its time is up
+	m = pressKeys(m, runeKey("j"))
+	if !strings.Contains(m.View(), "✓ Saved 2 rules") {
+		t.Errorf("Expected the toast to stay after a key")
+	}
+	m.applyToastTimeout(toastTimeoutMsg{gen: m.toast.gen - 1})
+	if m.toast == nil {
+		t.Errorf("An older toast's timeout shouldn't take down the current one")
+	}
+	m.applyToastTimeout(toastTimeoutMsg{gen: m.toast.gen})
+	if m.toast != nil {
+		t.Errorf("Expected the toast to go after its timeout")
+	}
+}
+
+func TestQuickSaveFailure(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, _, _ := newGuardTestModel()
+	dir := t.TempDir()
+	model.filterFile = filepath.Join(dir, "missing", "filter.txt")
+	model.filters.set("big/**", FilterExclude)
+
+	m := pressKeys(*model, runeKey("w"))
+	if m.saveErr == nil || m.saved || m.toast != nil {
+		t.Fatalf("Expected the save to fail")
+	}
+	// Unlike other messages, the error stays after the next key
+	m = pressKeys(m, runeKey("j"))
+	if m.statusMsg != "" || !strings.Contains(m.View(), "Save failed: ") {
+		t.Errorf("Expected the save error to stay on screen:\n%s", m.View())
+	}
+
+	m.filterFile = filepath.Join(dir, "filter.txt")
+	m = pressKeys(m, runeKey("w"))
+	if m.saveErr != nil || strings.Contains(m.View(), "Save failed") {
+		t.Errorf("Expected a successful save to clear the error")
+	}
+}
+
+func TestQuickSaveReportsRulesToReview(t *testing.T) {
+	model, _ := newConflictTestModel(t)
+
+	// Rather than offering the conflicting rules, w saves them as they are
+	// and says s would review them
+	m := pressKeys(*model, runeKey("w"))
+	if m.topModal() != modalNone || !m.saved {
+		t.Fatalf("Expected w to save without opening a pane, got %v", m.topModal())
+	}
+	if m.toast == nil || !strings.HasPrefix(m.toast.text, "Saved 3 rules to ") ||
+		!strings.Contains(m.toast.text, " - 1 pattern both included and excluded, s reviews them") {
+		t.Errorf("Expected the conflict in the toast, got %+v", m.toast)
+	}
+	data, _ := os.ReadFile(m.filterFile)
+	if !strings.Contains(string(data), "+ big/**") || !strings.Contains(string(data), "- big/**") {
+		t.Errorf("Expected both rules saved, got %q", data)
+	}
+}
+
+func TestQuickSaveShowsWarnings(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, big, _ := newGuardTestModel()
+	model.filterFile = filepath.Join(t.TempDir(), "filter.txt")
+	model.guards.maxExcludePercent = 50
+	model.applyChange(big, FilterExclude)
+
+	// Unlike s, w doesn't wait for a second press, but still says why s would
+	m := pressKeys(*model, runeKey("w"))
+	if !m.saved || m.toast == nil || !strings.HasSuffix(m.toast.text, " - warning: 80% of all files are excluded (limit 50%)") {
+		t.Errorf("Expected the save with the warning in the toast, got %+v", m.toast)
+	}
+}
@@ -0,0 +1,13 @@
+package rclonefilter
+
+import "time"
+
+// ListingItem is one element of the JSON array printed by "rclone lsjson"
+type ListingItem struct {
+	Path    string
+	Name    string
+	Size    int64
+	ModTime time.Time
+	IsDir   bool
+	Hashes  map[string]string // With --hash, by hash type
+}
@@ -0,0 +1,203 @@
+// Package rclonefilter reads rclone filter files and matches paths against
+// their patterns. It has no dependency on the terminal, so that the web
+// viewer can evaluate filters and show sizes the way the editor does.
+package rclonefilter
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Match reports whether path, relative to the root of the filters, matches
+// an rclone filter pattern
+func Match(pattern, path string) bool {
+	// Handle empty patterns
+	if pattern == "" {
+		return false
+	}
+
+	// Remove leading '/' from pattern if present for matching
+	cleanPattern := strings.TrimPrefix(pattern, "/")
+	cleanPath := strings.TrimPrefix(path, "/")
+
+	// Special handling for /** patterns - they should match the directory itself
+	// In rclone, "TV/**" matches both "TV" (the directory) and "TV/anything" (contents)
+	if strings.HasSuffix(cleanPattern, "/**") {
+		// Extract the directory part (everything before /**)
+		dirPattern := strings.TrimSuffix(cleanPattern, "/**")
+
+		for _, dir := range []string{dirPattern, Unescape(dirPattern)} {
+			// Check if the path exactly matches the directory
+			if cleanPath == dir {
+				return true
+			}
+
+			// Check if the path is inside the directory (starts with dir/)
+			if strings.HasPrefix(cleanPath, dir+"/") {
+				return true
+			}
+		}
+
+		// A wildcard directory such as "Shows/*/Extras/**" matches the
+		// directories it names as well
+		if re, err := regexp.Compile("^" + PatternToRegex(dirPattern) + "$"); err == nil && re.MatchString(cleanPath) {
+			return true
+		}
+	}
+
+	// Convert rclone pattern to regex for other patterns
+	regex := PatternToRegex(cleanPattern)
+
+	// Compile and match regex
+	re, err := regexp.Compile("^" + regex + "$")
+	if err != nil {
+		// Fallback to exact string match if regex compilation fails
+		return cleanPattern == cleanPath
+	}
+
+	return re.MatchString(cleanPath)
+}
+
+// MatchEnd reports whether rclone applies pattern to path. A pattern
+// starting with "/" is matched from the root as by Match, and any other
+// against the end of the path, so that "*.tmp" matches "docs/a.tmp" too.
+func MatchEnd(pattern, path string) bool {
+	if strings.HasPrefix(pattern, "/") {
+		return Match(pattern, path)
+	}
+	path = strings.TrimPrefix(path, "/")
+	for {
+		if Match(pattern, path) {
+			return true
+		}
+		i := strings.Index(path, "/")
+		if i < 0 {
+			return false
+		}
+		path = path[i+1:]
+	}
+}
+
+// PatternToRegex converts an rclone filter pattern to a regular expression,
+// without the anchors
+func PatternToRegex(pattern string) string {
+	var result strings.Builder
+
+	i := 0
+	for i < len(pattern) {
+		switch pattern[i] {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				// ** matches everything including directory separators
+				if i+2 < len(pattern) && pattern[i+2] == '/' {
+					// **/ should match zero or more directories
+					result.WriteString("(?:.*/)?")
+					i += 3 // Skip the '**/'
+				} else if i+2 == len(pattern) {
+					// ** at end matches everything
+					result.WriteString(".*")
+					i += 2 // Skip both '*' characters
+				} else {
+					result.WriteString(".*")
+					i += 2 // Skip both '*' characters
+				}
+			} else {
+				// * matches any sequence except directory separators
+				result.WriteString("[^/]*")
+				i++
+			}
+
+		case '?':
+			// ? matches any single character except directory separator
+			result.WriteString("[^/]")
+			i++
+		case '[':
+			// Character class - find the closing ]
+			j := i + 1
+			for j < len(pattern) && pattern[j] != ']' {
+				j++
+			}
+			if j < len(pattern) {
+				// Found closing ], copy the character class
+				result.WriteString(pattern[i : j+1])
+				i = j + 1
+			} else {
+				// No closing ], treat as literal [
+				result.WriteString("\\[")
+				i++
+			}
+		case '{':
+			// Pattern alternatives like {*.txt,*.md}
+			j := i + 1
+			braceLevel := 1
+			for j < len(pattern) && braceLevel > 0 {
+				if pattern[j] == '{' {
+					braceLevel++
+				} else if pattern[j] == '}' {
+					braceLevel--
+				}
+				j++
+			}
+			if braceLevel == 0 {
+				// Found matching closing brace
+				alternatives := pattern[i+1 : j-1]
+				parts := strings.Split(alternatives, ",")
+				result.WriteString("(?:")
+				for idx, part := range parts {
+					if idx > 0 {
+						result.WriteString("|")
+					}
+					result.WriteString(PatternToRegex(part))
+				}
+				result.WriteString(")")
+				i = j
+			} else {
+				// No matching closing brace, treat as literal {
+				result.WriteString("\\{")
+				i++
+			}
+		case '\\':
+			// A backslash makes the next character literal
+			if i+1 < len(pattern) {
+				r, size := utf8.DecodeRuneInString(pattern[i+1:])
+				result.WriteString(regexp.QuoteMeta(string(r)))
+				i += 1 + size
+				continue
+			}
+			result.WriteString("\\\\")
+			i++
+		case '.', '^', '$', '+', '(', ')', '|':
+			// Escape regex special characters
+			result.WriteString("\\")
+			result.WriteByte(pattern[i])
+			i++
+		default:
+			result.WriteByte(pattern[i])
+			i++
+		}
+	}
+
+	return result.String()
+}
+
+// Unescape returns the path a pattern with backslash escapes stands for,
+// undoing the escapes the editor writes. Unescaped glob characters are left
+// as they are.
+func Unescape(pattern string) string {
+	if !strings.ContainsAny(pattern, `\[`) {
+		return pattern
+	}
+	pattern = strings.ReplaceAll(pattern, "[ ]", " ")
+	var b strings.Builder
+	escaped := false
+	for _, r := range pattern {
+		if r == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+		escaped = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
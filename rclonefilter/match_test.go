@@ -0,0 +1,48 @@
+package rclonefilter
+
+import "testing"
+
+func TestPatternToRegex(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		expected string
+	}{
+		{"*.txt", "[^/]*\\.txt"},
+		{"**", ".*"},
+		{"**/logs", "(?:.*/)?logs"},
+		{"*.{txt,md}", "[^/]*\\.(?:txt|md)"},
+		{"file?.txt", "file[^/]\\.txt"},
+		{"[abc].txt", "[abc]\\.txt"},
+		{"dir/file.txt", "dir/file\\.txt"},
+		{"**/*.go", "(?:.*/)?[^/]*\\.go"},
+		{"{dir1,dir2}/**", "(?:dir1|dir2)/.*"},
+		{"test*", "test[^/]*"},
+	}
+
+	for _, tt := range tests {
+		result := PatternToRegex(tt.pattern)
+		if result != tt.expected {
+			t.Errorf("PatternToRegex(%q) = %q; want %q", tt.pattern, result, tt.expected)
+		}
+	}
+}
+
+func TestMatchEnd(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		matches bool
+	}{
+		{"*.tmp", "/a.tmp", true},
+		{"*.tmp", "/docs/a.tmp", true},
+		{"docs/**", "/work/docs/a.txt", true},
+		{"docs/**", "/mydocs/a.txt", false},
+		{"/*.tmp", "/docs/a.tmp", false},
+		{"/docs/**", "/work/docs/a.txt", false},
+	}
+	for _, tt := range tests {
+		if got := MatchEnd(tt.pattern, tt.path); got != tt.matches {
+			t.Errorf("MatchEnd(%q, %q) = %t, want %t", tt.pattern, tt.path, got, tt.matches)
+		}
+	}
+}
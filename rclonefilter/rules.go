@@ -0,0 +1,67 @@
+package rclonefilter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Rule is a line of an rclone filter file
+type Rule struct {
+	Include bool
+	Pattern string
+}
+
+func (r Rule) String() string {
+	if r.Include {
+		return "+ " + r.Pattern
+	}
+	return "- " + r.Pattern
+}
+
+// Parse reads the rules of a filter file the way the editor does: "+ " and
+// "- " lines are rules, "+foo" is read as "+ foo", and "#" and ";" start
+// comments, so rules the editor keeps disabled are skipped. Other lines,
+// "!" among them, are left out, and the numbers of those lines returned.
+func Parse(r io.Reader) (rules []Rule, skipped []int, err error) {
+	lineNum := 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+		case len(line) > 1 && (line[0] == '+' || line[0] == '-'):
+			pattern, ok := strings.CutPrefix(line[1:], " ")
+			if !ok {
+				pattern = strings.TrimSpace(pattern)
+			}
+			rules = append(rules, Rule{Include: line[0] == '+', Pattern: pattern})
+		default:
+			skipped = append(skipped, lineNum)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error reading filter file: %v", err)
+	}
+	return rules, skipped, nil
+}
+
+// FirstMatch returns the position of the first rule matching path, which is
+// the one rclone applies, or -1 when none does. The editor and the viewer
+// both decide paths with it. pattern gives the pattern of a rule, or false
+// for a rule that isn't in effect.
+func FirstMatch[R any](rules []R, pattern func(R) (string, bool), path string) int {
+	for i, rule := range rules {
+		if p, ok := pattern(rule); ok && (p == path || Match(p, path)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// RulePattern is the pattern of a rule read by Parse, for FirstMatch
+func RulePattern(r Rule) (string, bool) {
+	return r.Pattern, true
+}
@@ -0,0 +1,45 @@
+package rclonefilter
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	rules, skipped, err := Parse(strings.NewReader("- *.tmp\n# a comment\n\n!\n+Photos/**\n; - old\n*.bak\n-\n- *\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Rule{{Pattern: "*.tmp"}, {Include: true, Pattern: "Photos/**"}, {Pattern: "*"}}
+	if !slices.Equal(rules, want) {
+		t.Errorf("Parse() = %v, want %v", rules, want)
+	}
+	if !slices.Equal(skipped, []int{4, 7, 8}) {
+		t.Errorf("Parse() skipped lines %v, want [4 7 8]", skipped)
+	}
+}
+
+func TestFirstMatch(t *testing.T) {
+	rules := []Rule{{Include: true, Pattern: "Photos/2024/**"}, {Pattern: "Photos/**"}, {Include: true, Pattern: "Photos/2023/**"}, {Pattern: "notes.txt"}, {Include: true, Pattern: "notes.txt"}}
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/Photos/2024/a.jpg", 0},
+		{"/Photos/a.jpg", 1},
+		// A rule after a broader one is never reached
+		{"/Photos/2023/a.jpg", 1},
+		{"/notes.txt", 3},
+		{"/other.txt", -1},
+	}
+	for _, tt := range tests {
+		if got := FirstMatch(rules, RulePattern, tt.path); got != tt.want {
+			t.Errorf("FirstMatch(%q) = %d, want %d", tt.path, got, tt.want)
+		}
+	}
+	excludes := func(r Rule) (string, bool) { return r.Pattern, !r.Include }
+	if got := FirstMatch(rules, excludes, "/notes.txt"); got != 3 {
+		t.Errorf("FirstMatch() skipping rules not in effect = %d, want 3", got)
+	}
+}
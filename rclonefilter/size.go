@@ -0,0 +1,28 @@
+package rclonefilter
+
+import "fmt"
+
+// FormatSize writes a size in 1024 based units as KiB, MiB..., or with
+// decimal in 1000 based ones as kB, MB.... With fixedWidth every size takes
+// as much room as the widest, such as "1023.9 KiB".
+func FormatSize(size int64, decimal, fixedWidth bool) string {
+	unit, prefixes, suffix := int64(1024), "KMGTPE", "iB"
+	if decimal {
+		unit, prefixes, suffix = 1000, "kMGTPE", "B"
+	}
+	if size < unit {
+		if fixedWidth {
+			return fmt.Sprintf("%6d %-*s", size, len(suffix)+1, "B")
+		}
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := unit, 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	if fixedWidth {
+		return fmt.Sprintf("%6.1f %c%s", float64(size)/float64(div), prefixes[exp], suffix)
+	}
+	return fmt.Sprintf("%.1f %c%s", float64(size)/float64(div), prefixes[exp], suffix)
+}
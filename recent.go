@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// maxRecentFiles is how many filter files the recent list remembers
+const maxRecentFiles = 10
+
+// recentFile is a filter file opened in an earlier session, with the root
+// it was edited for
+type recentFile struct {
+	filterFile string
+	root       string
+	used       time.Time
+}
+
+// defaultRecentFile returns where the recent list is kept
+func defaultRecentFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "rclone-filter-editor", "recent")
+}
+
+// absPath makes a local path absolute so that entries don't depend on the
+// directory the editor was started in. Remote paths are kept as they are.
+func absPath(path string) string {
+	if isRemotePath(path) {
+		return path
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// loadRecentFiles reads the recent list, most recent first. Each line holds
+// the time, the filter file and the root, separated by tabs. A missing list
+// is empty.
+func loadRecentFiles(path string) ([]recentFile, error) {
+	if path == "" {
+		return nil, nil
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []recentFile
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 3 {
+			continue
+		}
+		used, err := time.Parse(time.RFC3339, fields[0])
+		if err != nil {
+			continue
+		}
+		entries = append(entries, recentFile{used: used, filterFile: fields[1], root: fields[2]})
+	}
+	return entries, scanner.Err()
+}
+
+// addRecentFile puts entry at the top of the recent list, dropping an older
+// entry for the same filter file and root and the oldest beyond the limit
+func addRecentFile(path string, entry recentFile) error {
+	if path == "" {
+		return nil
+	}
+	entries, err := loadRecentFiles(path)
+	if err != nil {
+		return err
+	}
+	list := []recentFile{entry}
+	for _, e := range entries {
+		if (e.filterFile != entry.filterFile || e.root != entry.root) && len(list) < maxRecentFiles {
+			list = append(list, e)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, func(w io.Writer) error {
+		for _, e := range list {
+			if _, err := fmt.Fprintf(w, "%s\t%s\t%s\n", e.used.Format(time.RFC3339), e.filterFile, e.root); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// recentFilesFor returns the entries whose filter file still exists, only
+// those edited for root when one is given
+func recentFilesFor(entries []recentFile, root string) []recentFile {
+	var result []recentFile
+	for _, e := range entries {
+		if root != "" && e.root != absPath(root) {
+			continue
+		}
+		if _, err := os.Stat(e.filterFile); err == nil {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// recentPicker asks which recent filter file to open when none was given.
+// The first row starts with the default filter file instead.
+type recentPicker struct {
+	entries     []recentFile
+	defaultFile string
+	cursor      int
+	chosen      *recentFile
+	done        bool // Enter or Esc was pressed, rather than Ctrl+C
+}
+
+func (p recentPicker) Init() tea.Cmd {
+	return nil
+}
+
+func (p recentPicker) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return p, nil
+	}
+	switch key.String() {
+	case "up", "k":
+		if p.cursor > 0 {
+			p.cursor--
+		}
+	case "down", "j":
+		if p.cursor < len(p.entries) {
+			p.cursor++
+		}
+	case "enter":
+		if p.cursor > 0 {
+			p.chosen = &p.entries[p.cursor-1]
+		}
+		p.done = true
+		return p, tea.Quit
+	case "esc":
+		p.done = true
+		return p, tea.Quit
+	case "ctrl+c", "q":
+		return p, tea.Quit
+	}
+	return p, nil
+}
+
+func (p recentPicker) View() string {
+	rows := []string{fmt.Sprintf("Start with %s", p.defaultFile)}
+	for _, e := range p.entries {
+		rows = append(rows, fmt.Sprintf("%s  for %s  (%s)", e.filterFile, e.root, e.used.Local().Format("2006-01-02 15:04")))
+	}
+
+	var b strings.Builder
+	b.WriteString(lipgloss.NewStyle().Bold(true).Foreground(currentTheme.Header).Render("No filter file given, open a recent one?"))
+	b.WriteString("\n\n")
+	for i, row := range rows {
+		if i == p.cursor {
+			row = lipgloss.NewStyle().Background(currentTheme.CursorBg).Foreground(currentTheme.CursorFg).Render("> " + row)
+		} else {
+			row = "  " + row
+		}
+		b.WriteString(row + "\n")
+	}
+	b.WriteString(lipgloss.NewStyle().Foreground(currentTheme.Muted).Render("\n↑/↓ select, Enter open, Esc start with the default, q quit"))
+	b.WriteString("\n")
+	return b.String()
+}
+
+// pickRecentFile shows the picker. It returns the chosen entry, nil to start
+// with the default filter file, and quit when the user left instead.
+func pickRecentFile(entries []recentFile, defaultFile string) (chosen *recentFile, quit bool, err error) {
+	final, err := tea.NewProgram(recentPicker{entries: entries, defaultFile: defaultFile}).Run()
+	if err != nil {
+		return nil, false, err
+	}
+	p := final.(recentPicker)
+	return p.chosen, !p.done, nil
+}
+
+// isTerminal reports whether f is a terminal rather than a pipe or file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestRecentFiles(t *testing.T) {
+	dir := t.TempDir()
+	listPath := filepath.Join(dir, "config", "recent")
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	var files []string
+	for i := 0; i < maxRecentFiles+2; i++ {
+		file := filepath.Join(dir, fmt.Sprintf("filter%d.txt", i))
+		os.WriteFile(file, nil, 0644)
+		files = append(files, file)
+		if err := addRecentFile(listPath, recentFile{filterFile: file, root: "/data", used: start.Add(time.Duration(i) * time.Hour)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Opening one again moves it to the top
+	addRecentFile(listPath, recentFile{filterFile: files[5], root: "/data", used: start.Add(24 * time.Hour)})
+	addRecentFile(listPath, recentFile{filterFile: files[0], root: "gdrive:Photos", used: start.Add(25 * time.Hour)})
+
+	entries, err := loadRecentFiles(listPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != maxRecentFiles || entries[0].root != "gdrive:Photos" || entries[1].filterFile != files[5] || !entries[1].used.Equal(start.Add(24*time.Hour)) {
+		t.Fatalf("Unexpected recent files %+v", entries)
+	}
+
+	os.Remove(files[11])
+	if got := recentFilesFor(entries, ""); len(got) != maxRecentFiles-1 {
+		t.Errorf("Expected the removed filter file to be left out, got %d entries", len(got))
+	}
+	if got := recentFilesFor(entries, "gdrive:Photos"); len(got) != 1 || got[0].filterFile != files[0] {
+		t.Errorf("Expected only the entry for the given root, got %+v", got)
+	}
+}
+
+func TestRecentPicker(t *testing.T) {
+	entries := []recentFile{{filterFile: "/a/filter.txt", root: "/a"}, {filterFile: "/b/filter.txt", root: "/b"}}
+	press := func(keys ...tea.KeyMsg) recentPicker {
+		var p tea.Model = recentPicker{entries: entries, defaultFile: "filter.txt"}
+		for _, key := range keys {
+			p, _ = p.Update(key)
+		}
+		return p.(recentPicker)
+	}
+	down := tea.KeyMsg{Type: tea.KeyDown}
+
+	if p := press(down, down, tea.KeyMsg{Type: tea.KeyEnter}); !p.done || p.chosen == nil || p.chosen.root != "/b" {
+		t.Errorf("Expected the second entry to be chosen, got %+v", p.chosen)
+	}
+	if p := press(down, tea.KeyMsg{Type: tea.KeyEsc}); !p.done || p.chosen != nil {
+		t.Errorf("Expected Esc to start with the default filter file")
+	}
+	if p := press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")}); p.done {
+		t.Errorf("Expected q to quit")
+	}
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"os/exec"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"rclone-filter-editor/rclonefilter"
+)
+
+// dirEntry is a single entry of a directory listing, independent of whether
+// it came from the local filesystem or an rclone remote
+type dirEntry struct {
+	Name    string
+	IsDir   bool
+	Size    int64
+	ModTime time.Time
+	Hash    string // Checksum listed by rclone, such as "md5:0cc175b9..."
+
+	Unreadable bool // The current user lacks permission to read it
+
+	Link     string // Where a junction or other directory link leads
+	LinkLoop bool   // Link leads back to a directory it sits in
+}
+
+// lister reads the entries of a single directory
+type lister interface {
+	List(ctx context.Context, dir string) ([]dirEntry, error)
+}
+
+// localLister lists directories on the local filesystem
+type localLister struct {
+	skipSpecial   bool // Leave out devices, sockets and named pipes (--skip-special)
+	oneFileSystem bool // Leave out directories on other filesystems (--one-file-system)
+}
+
+// specialFileTypes are the file types --skip-special leaves out, which rclone
+// can't copy and reading them may hang
+const specialFileTypes = fs.ModeDevice | fs.ModeCharDevice | fs.ModeSocket | fs.ModeNamedPipe | fs.ModeIrregular
+
+// dirLister is a lister that can list only the subdirectories of a
+// directory, faster than listing everything, for --dirs-only
+type dirLister interface {
+	ListDirs(ctx context.Context, dir string) ([]dirEntry, error)
+}
+
+func (l localLister) List(ctx context.Context, dir string) ([]dirEntry, error) {
+	return l.list(dir, false)
+}
+
+// ListDirs lists the subdirectories of dir without reading the details of
+// its files, which takes a system call per file
+func (l localLister) ListDirs(ctx context.Context, dir string) ([]dirEntry, error) {
+	return l.list(dir, true)
+}
+
+func (l localLister) list(dir string, dirsOnly bool) ([]dirEntry, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Directories on another device than dir are mount points
+	dev, checkDev := uint64(0), false
+	if l.oneFileSystem {
+		if info, err := os.Stat(dir); err == nil {
+			dev, checkDev = deviceOf(info)
+		}
+	}
+
+	result := make([]dirEntry, 0, len(entries))
+	for _, entry := range entries {
+		info, infoErr := entry.Info()
+		isDir := entry.IsDir() || infoErr == nil && dirLink(entry, info)
+		if dirsOnly && !isDir {
+			continue
+		}
+		if l.skipSpecial && !isDir && entry.Type()&specialFileTypes != 0 {
+			continue
+		}
+		if checkDev && entry.IsDir() {
+			if info, err := entry.Info(); err == nil {
+				if d, ok := deviceOf(info); ok && d != dev {
+					continue
+				}
+			}
+		}
+		e := dirEntry{Name: entry.Name(), IsDir: isDir}
+		// Get file info to capture size and modification time
+		if infoErr == nil {
+			e.ModTime = info.ModTime()
+			if !isDir {
+				e.Size = info.Size()
+			}
+			e.Unreadable = !readable(info)
+		}
+		if isDir && !entry.IsDir() {
+			e.Link, e.LinkLoop = resolveLink(dir, entry.Name())
+		}
+		result = append(result, e)
+	}
+	return result, nil
+}
+
+// isRemotePath reports whether p refers to an rclone remote ("remote:path")
+// rather than a local directory. Single letter prefixes are treated as
+// Windows drive letters.
+func isRemotePath(p string) bool {
+	i := strings.Index(p, ":")
+	if i < 2 {
+		return false
+	}
+	return !strings.ContainsAny(p[:i], `/\`)
+}
+
+// listedRemote reports whether the "remote:" prefix of p is one of the
+// remotes printed by "rclone listremotes". Paths of --sftp sessions
+// ("user@host:path") look like remote paths but rclone doesn't know them.
+func listedRemote(listing []byte, p string) bool {
+	name := p[:strings.Index(p, ":")+1]
+	for _, line := range strings.Split(string(listing), "\n") {
+		if strings.TrimSpace(line) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// joinRemotePath appends name to a remote directory path
+func joinRemotePath(dir, name string) string {
+	if strings.HasSuffix(dir, ":") || strings.HasSuffix(dir, "/") {
+		return dir + name
+	}
+	return dir + "/" + name
+}
+
+// remoteRelPath returns p relative to the remote root, with a leading slash
+func remoteRelPath(root, p string) string {
+	rel := strings.TrimPrefix(p, root)
+	return "/" + strings.TrimPrefix(path.Clean("/"+rel), "/")
+}
+
+// lsjsonItem is one element of the JSON array printed by "rclone lsjson",
+// as read by the web viewer too
+type lsjsonItem = rclonefilter.ListingItem
+
+// hashPreference is the order checksums are picked in when rclone lists
+// several for a file
+var hashPreference = []string{"md5", "sha1", "sha256", "dropbox", "quickxor", "whirlpool", "crc32"}
+
+// preferredHash picks one of the checksums listed for a file, as "type:value"
+func preferredHash(hashes map[string]string) string {
+	for _, kind := range hashPreference {
+		if v := hashes[kind]; v != "" {
+			return kind + ":" + v
+		}
+	}
+	for _, kind := range slices.Sorted(maps.Keys(hashes)) {
+		if v := hashes[kind]; v != "" {
+			return kind + ":" + v
+		}
+	}
+	return ""
+}
+
+type cachedListing struct {
+	entries []dirEntry
+	fetched time.Time
+}
+
+// remoteLister lists directories on an rclone remote using "rclone lsjson".
+// Listings are cached per directory for ttl so that rescans don't hit rate
+// limited providers again.
+type remoteLister struct {
+	ttl    time.Duration
+	hashes bool // List checksums too, which can be slow on some backends
+
+	mu    sync.Mutex
+	cache map[string]cachedListing
+
+	// run executes rclone and returns its stdout, replaceable in tests
+	run func(ctx context.Context, args ...string) ([]byte, error)
+	now func() time.Time
+}
+
+func newRemoteLister(ttl time.Duration) *remoteLister {
+	return &remoteLister{
+		ttl:   ttl,
+		cache: make(map[string]cachedListing),
+		run:   runRclone,
+		now:   time.Now,
+	}
+}
+
+func runRclone(ctx context.Context, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "rclone", args...)
+	out, err := cmd.Output()
+	if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+		return nil, fmt.Errorf("rclone %s: %s", args[0], strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return out, err
+}
+
+func (r *remoteLister) List(ctx context.Context, dir string) ([]dirEntry, error) {
+	r.mu.Lock()
+	cached, ok := r.cache[dir]
+	r.mu.Unlock()
+	if ok && r.now().Sub(cached.fetched) < r.ttl {
+		return cached.entries, nil
+	}
+
+	args := []string{"lsjson", "--no-mimetype", dir}
+	if r.hashes {
+		args = append(args, "--hash")
+	}
+	out, err := r.run(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []lsjsonItem
+	if err := json.Unmarshal(out, &items); err != nil {
+		return nil, fmt.Errorf("failed to parse rclone lsjson output: %w", err)
+	}
+
+	entries := make([]dirEntry, 0, len(items))
+	for _, item := range items {
+		entries = append(entries, dirEntry{
+			Name:    item.Name,
+			IsDir:   item.IsDir,
+			Size:    item.Size,
+			ModTime: item.ModTime,
+			Hash:    preferredHash(item.Hashes),
+		})
+	}
+
+	r.mu.Lock()
+	r.cache[dir] = cachedListing{entries: entries, fetched: r.now()}
+	r.mu.Unlock()
+
+	return entries, nil
+}
+
+// Invalidate drops the cached listings of dir and everything below it
+func (r *remoteLister) Invalidate(dir string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key := range r.cache {
+		if key == dir || strings.HasPrefix(key, strings.TrimSuffix(dir, "/")+"/") ||
+			(strings.HasSuffix(dir, ":") && strings.HasPrefix(key, dir)) {
+			delete(r.cache, key)
+		}
+	}
+}
+
+// InvalidateAll drops every cached listing
+func (r *remoteLister) InvalidateAll() {
+	r.mu.Lock()
+	r.cache = make(map[string]cachedListing)
+	r.mu.Unlock()
+}
+
+// routingLister sends remote paths to the remote lister and everything else
+// to the local filesystem, for sessions mixing both kinds of roots
+type routingLister struct {
+	remote *remoteLister
+	local  localLister
+}
+
+func (r routingLister) List(ctx context.Context, dir string) ([]dirEntry, error) {
+	if isRemotePath(dir) {
+		return r.remote.List(ctx, dir)
+	}
+	return r.local.List(ctx, dir)
+}
+
+// ListDirs lists local directories without their files. Remote listings are
+// cached whole, so they are listed as usual and the files dropped by the
+// scanner.
+func (r routingLister) ListDirs(ctx context.Context, dir string) ([]dirEntry, error) {
+	if isRemotePath(dir) {
+		return r.remote.List(ctx, dir)
+	}
+	return r.local.ListDirs(ctx, dir)
+}
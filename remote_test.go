@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestIsRemotePath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{"gdrive:", true},
+		{"gdrive:Photos/2024", true},
+		{"/home/user/data", false},
+		{"relative/dir", false},
+		{`C:\Users\me`, false},
+		{"./odd:name", false},
+	}
+
+	for _, tt := range tests {
+		if result := isRemotePath(tt.path); result != tt.expected {
+			t.Errorf("isRemotePath(%q) = %t; want %t", tt.path, result, tt.expected)
+		}
+	}
+}
+
+func TestRemoteFilterPath(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	tests := []struct {
+		root     string
+		path     string
+		expected string
+	}{
+		{"gdrive:", "gdrive:Photos", "/Photos"},
+		{"gdrive:", joinRemotePath("gdrive:", "Photos"), "/Photos"},
+		{"gdrive:Media", joinRemotePath("gdrive:Media", "TV/Show"), "/TV/Show"},
+		{"gdrive:Media", "gdrive:Media", "/"},
+	}
+
+	for _, tt := range tests {
+		globalRootPath = tt.root
+		if result := getFilterPath(tt.path); result != tt.expected {
+			t.Errorf("getFilterPath(%q) with root %q = %q; want %q", tt.path, tt.root, result, tt.expected)
+		}
+	}
+}
+
+func TestRemoteListerCache(t *testing.T) {
+	calls := 0
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	r := newRemoteLister(time.Minute)
+	r.now = func() time.Time { return now }
+	r.run = func(ctx context.Context, args ...string) ([]byte, error) {
+		calls++
+		return []byte(`[
+			{"Path":"a.txt","Name":"a.txt","Size":10,"ModTime":"2024-01-01T10:00:00Z","IsDir":false},
+			{"Path":"sub","Name":"sub","Size":-1,"ModTime":"2024-01-01T10:00:00Z","IsDir":true}
+		]`), nil
+	}
+
+	entries, err := r.List(context.Background(), "remote:dir")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Size != 10 || !entries[1].IsDir {
+		t.Errorf("Unexpected entries: %+v", entries)
+	}
+
+	// Within the TTL the listing comes from the cache
+	now = now.Add(30 * time.Second)
+	r.List(context.Background(), "remote:dir")
+	if calls != 1 {
+		t.Errorf("Expected cached listing within TTL, rclone called %d times", calls)
+	}
+
+	// After the TTL rclone is asked again
+	now = now.Add(time.Minute)
+	r.List(context.Background(), "remote:dir")
+	if calls != 2 {
+		t.Errorf("Expected fresh listing after TTL, rclone called %d times", calls)
+	}
+
+	// Invalidation forces a refresh of the directory and its children only
+	r.List(context.Background(), "remote:dir/sub")
+	r.List(context.Background(), "remote:other")
+	r.Invalidate("remote:dir")
+	if _, ok := r.cache["remote:dir/sub"]; ok {
+		t.Errorf("Invalidate should drop listings below the directory")
+	}
+	if _, ok := r.cache["remote:other"]; !ok {
+		t.Errorf("Invalidate should keep unrelated listings")
+	}
+
+	r.InvalidateAll()
+	if len(r.cache) != 0 {
+		t.Errorf("InvalidateAll should empty the cache")
+	}
+}
+
+func TestRemoteListerHashes(t *testing.T) {
+	var gotArgs []string
+	r := newRemoteLister(time.Minute)
+	r.hashes = true
+	r.run = func(ctx context.Context, args ...string) ([]byte, error) {
+		gotArgs = args
+		return []byte(`[{"Path":"a.txt","Name":"a.txt","Size":1,"IsDir":false,"Hashes":{"sha1":"86f7e437","md5":"0cc175b9c0f1b6a8"}}]`), nil
+	}
+	entries, err := r.List(context.Background(), "remote:dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(gotArgs, "--hash") {
+		t.Errorf("Expected rclone to be asked for checksums, got %q", gotArgs)
+	}
+	if entries[0].Hash != "md5:0cc175b9c0f1b6a8" {
+		t.Errorf("Expected the md5 checksum to be picked, got %q", entries[0].Hash)
+	}
+	if got := preferredHash(map[string]string{"xxh3": "ab", "blake3": "cd"}); got != "blake3:cd" {
+		t.Errorf("Expected unknown types to be picked by name, got %q", got)
+	}
+}
+
+func TestLocalListerSkipSpecial(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "file.txt"), []byte("x"), 0644)
+	listener, err := net.Listen("unix", filepath.Join(dir, "app.sock"))
+	if err != nil {
+		t.Skipf("Unix sockets aren't available: %v", err)
+	}
+	defer listener.Close()
+
+	names := func(l localLister) []string {
+		entries, err := l.List(context.Background(), dir)
+		if err != nil {
+			t.Fatalf("Listing failed: %v", err)
+		}
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name)
+		}
+		slices.Sort(names)
+		return names
+	}
+	if got := names(localLister{}); !slices.Equal(got, []string{"app.sock", "file.txt"}) {
+		t.Errorf("Without --skip-special got %v", got)
+	}
+	if got := names(localLister{skipSpecial: true}); !slices.Equal(got, []string{"file.txt"}) {
+		t.Errorf("With --skip-special got %v", got)
+	}
+}
+
+func TestLocalListerOneFileSystem(t *testing.T) {
+	root, err1 := os.Stat("/")
+	proc, err2 := os.Stat("/proc")
+	if err1 != nil || err2 != nil {
+		t.Skip("No /proc to test with")
+	}
+	rootDev, ok1 := deviceOf(root)
+	procDev, ok2 := deviceOf(proc)
+	if !ok1 || !ok2 || rootDev == procDev {
+		t.Skip("/proc is not a separate filesystem here")
+	}
+
+	contains := func(l localLister) bool {
+		entries, err := l.List(context.Background(), "/")
+		if err != nil {
+			t.Fatalf("Listing / failed: %v", err)
+		}
+		return slices.ContainsFunc(entries, func(e dirEntry) bool { return e.Name == "proc" })
+	}
+	if !contains(localLister{}) {
+		t.Errorf("Expected /proc to be listed by default")
+	}
+	if contains(localLister{oneFileSystem: true}) {
+		t.Errorf("Expected --one-file-system to leave /proc out")
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import "strings"
+
+// ruleAction is a change made to the rule of a node, which . repeats on the
+// selected node, like the . of vim
+type ruleAction struct {
+	state FilterState
+}
+
+// describeAction says what a does to node, e.g. "exclude Photos/raw/**"
+func (m *Model) describeAction(a ruleAction, node *FileNode) string {
+	if m.filesFrom != nil {
+		name := strings.TrimPrefix(getFilterPath(node.Path), "/")
+		if a.state == FilterInclude {
+			return "list " + name
+		}
+		return "unlist " + name
+	}
+	pattern := nodeRulePattern(node)
+	switch a.state {
+	case FilterInclude:
+		return "include " + pattern
+	case FilterExclude:
+		return "exclude " + pattern
+	}
+	return "clear the rule " + pattern
+}
+
+// repeatLastAction applies the last rule change to the selected node, with
+// the same guard rails as the change itself
+func (m *Model) repeatLastAction() {
+	node := m.selectedNode()
+	switch {
+	case m.lastAction == nil:
+		m.statusMsg = "Nothing to repeat yet, . repeats the last Space"
+		return
+	case node == nil || m.isHiddenRoot(node):
+		return
+	}
+
+	a := *m.lastAction
+	if !m.confirmChange(node, a.state, ".") {
+		return
+	}
+	m.applyChange(node, a.state)
+	m.refreshView()
+	if m.filesFrom != nil {
+		m.statusMsg = "Repeated: " + m.describeAction(a, node)
+	} else {
+		m.statusMsg = "Repeated: " + m.statusMsg
+	}
+	m.warnIncludedSize()
+}
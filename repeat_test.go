@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestRepeatLastAction(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, big, small := newGuardTestModel()
+	model.confirmFiles = 5
+	model.cursor = 2
+	space := tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")}
+	repeat := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(".")}
+
+	var result tea.Model = *model
+	for _, key := range []tea.KeyMsg{repeat, space, space, {Type: tea.KeyUp}, repeat} {
+		result, _ = result.Update(key)
+	}
+	m := result.(Model)
+	if small.Filter != FilterExclude {
+		t.Fatalf("Expected small to be excluded, got %v", small.Filter)
+	}
+
+	// big holds 8 files, so the repeat waits for a second .
+	if big.Filter != FilterNone || !strings.Contains(m.statusMsg, "press . again") {
+		t.Fatalf("Expected the repeat to ask for confirmation, got %q", m.statusMsg)
+	}
+	result, _ = m.Update(repeat)
+	m = result.(Model)
+	if big.Filter != FilterExclude || m.filters.state("big/**") != FilterExclude {
+		t.Errorf("Expected . to exclude big as well, got %v", big.Filter)
+	}
+	if m.statusMsg != "Repeated: added `- big/**` — 8 files, 8.0 KiB now excluded" {
+		t.Errorf("Unexpected status %q", m.statusMsg)
+	}
+}
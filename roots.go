@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// sessionRoot is one directory tree opened in the editor, together with the
+// filter file whose patterns are relative to it
+type sessionRoot struct {
+	path        string
+	node        *FileNode
+	filterFile  string
+	filters     *FilterDocument
+	filterCache *filterCache
+}
+
+// sessionRootPaths holds the absolute paths of all roots when more than one
+// was opened. Filter paths are computed relative to the root containing them.
+var sessionRootPaths []string
+
+// rootPathFor returns the root directory that filter paths for path are
+// relative to
+func rootPathFor(path string) string {
+	best := globalRootPath
+	bestLen := -1
+	for _, root := range sessionRootPaths {
+		if path == root || strings.HasPrefix(path, strings.TrimSuffix(root, "/")+"/") ||
+			(isRemotePath(root) && strings.HasSuffix(root, ":") && strings.HasPrefix(path, root)) {
+			if len(root) > bestLen {
+				best = root
+				bestLen = len(root)
+			}
+		}
+	}
+	return best
+}
+
+// stringList is a flag.Value collecting every occurrence of a repeated flag
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ", ")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// multiRoot reports whether several roots are shown under a hidden top node
+func (m *Model) multiRoot() bool {
+	return len(m.roots) > 1
+}
+
+// newRootNode builds a fresh tree root for the session: the single root
+// directory, or a hidden node holding one child per root
+func (m *Model) newRootNode() *FileNode {
+	if !m.multiRoot() {
+		var path string
+		if len(m.roots) == 1 {
+			path = m.roots[0].path
+		} else {
+			path = m.root.Path
+		}
+		node := &FileNode{
+			Name:     rootNodeName(path),
+			Path:     path,
+			IsDir:    true,
+			Expanded: true,
+			Loading:  true,
+		}
+		if len(m.roots) == 1 {
+			m.roots[0].node = node
+		}
+		return node
+	}
+
+	top := &FileNode{Name: "", IsDir: true, Expanded: true}
+	for _, r := range m.roots {
+		r.node = &FileNode{
+			Name:     rootNodeName(r.path),
+			Path:     r.path,
+			IsDir:    true,
+			Expanded: true,
+			Loading:  true,
+		}
+		top.Children = append(top.Children, r.node)
+	}
+	return top
+}
+
+// isHiddenRoot reports whether node is the hidden node above several roots
+func (m *Model) isHiddenRoot(node *FileNode) bool {
+	return m.multiRoot() && node == m.root
+}
+
+// topLevelNodes returns the directories scanned as roots of the session
+func (m *Model) topLevelNodes() []*FileNode {
+	if !m.multiRoot() {
+		return []*FileNode{m.root}
+	}
+	return m.root.Children
+}
+
+// activateRootFor makes the filter set of the root containing node the one
+// used by filter evaluation and editing
+func (m *Model) activateRootFor(node *FileNode) {
+	if !m.multiRoot() || node == nil {
+		return
+	}
+	for node.Parent != nil {
+		node = node.Parent
+	}
+	for _, r := range m.roots {
+		if r.node == node {
+			m.filters = r.filters
+			m.filterCache = r.filterCache
+			m.filterFile = r.filterFile
+			return
+		}
+	}
+}
+
+// filterFileNames lists the filter files edited in this session
+func (m *Model) filterFileNames() string {
+	return strings.Join(m.filterFilePaths(), ", ")
+}
+
+// filterFilePaths returns the filter files edited in this session
+func (m *Model) filterFilePaths() []string {
+	if m.toStdout {
+		return []string{"standard output"}
+	}
+	if m.filesFrom != nil {
+		return []string{m.filesFrom.path}
+	}
+	if len(m.roots) < 2 {
+		return []string{m.filterFile}
+	}
+	var names []string
+	for _, r := range m.roots {
+		names = append(names, r.filterFile)
+	}
+	return names
+}
+
+// save writes the filter files and reports the outcome in the status line
+func (m *Model) save() bool {
+	if err := m.saveAll(); err != nil {
+		m.saveFailed(err)
+		return false
+	}
+	m.saved = true
+	m.saveErr = nil
+	m.statusMsg = "Saved " + m.filterFileNames()
+	return true
+}
+
+// saveAll writes the filter file of every root, and the metadata filter if
+// it was edited
+func (m *Model) saveAll() (err error) {
+	defer func() {
+		if err == nil {
+			m.progress.emit(progressEvent{Event: "saved", Saved: m.filterFilePaths()})
+		}
+	}()
+	if err := m.checkLocks(); err != nil {
+		return err
+	}
+	if m.metadata != nil && m.metadataChanged {
+		if err := m.metadata.save(); err != nil {
+			return fmt.Errorf("%s: %w", m.metadata.path, err)
+		}
+		m.metadataChanged = false
+	}
+	if m.toStdout {
+		// The rules are printed once the UI has exited
+		return nil
+	}
+	if m.filesFrom != nil {
+		return m.filesFrom.save()
+	}
+	if len(m.roots) < 2 {
+		return saveFilterFile(m.filterFile, m.filters, m.ruleOrder)
+	}
+	for _, r := range m.roots {
+		if err := saveFilterFile(r.filterFile, r.filters, m.ruleOrder); err != nil {
+			return fmt.Errorf("%s: %w", r.filterFile, err)
+		}
+	}
+	return nil
+}
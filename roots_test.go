@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestMultiRootSession(t *testing.T) {
+	dataDir := t.TempDir()
+	mediaDir := t.TempDir()
+	os.MkdirAll(filepath.Join(dataDir, "docs"), 0755)
+	os.WriteFile(filepath.Join(dataDir, "docs", "a.txt"), []byte("a"), 0644)
+	os.MkdirAll(filepath.Join(mediaDir, "movies"), 0755)
+	os.WriteFile(filepath.Join(mediaDir, "movies", "b.mkv"), []byte("bb"), 0644)
+
+	originalGlobalRootPath, originalSessionRootPaths := globalRootPath, sessionRootPaths
+	globalRootPath = dataDir
+	sessionRootPaths = []string{dataDir, mediaDir}
+	defer func() { globalRootPath, sessionRootPaths = originalGlobalRootPath, originalSessionRootPaths }()
+
+	dataFilter := filepath.Join(t.TempDir(), "data.txt")
+	mediaFilter := filepath.Join(t.TempDir(), "media.txt")
+
+	model, apply := newScanTestModel(dataDir)
+	defer model.cancel()
+	model.roots = []*sessionRoot{
+		{path: dataDir, filterFile: dataFilter, filters: newFilterDocument(nil)},
+		{path: mediaDir, filterFile: mediaFilter, filters: newTestModelWithStates(map[string]FilterState{"movies/**": FilterExclude}).filters},
+	}
+	model.root = model.newRootNode()
+	s := model.newScanner()
+	for _, node := range model.topLevelNodes() {
+		if err := s.scan(node); err != nil {
+			t.Fatalf("Scan of %s failed: %v", node.Path, err)
+		}
+	}
+	result := apply(*model)
+
+	// Both roots are shown as top-level rows, the hidden node above them is not
+	if result.visibleNodes[0].Path != dataDir || getNodeDepth(result.visibleNodes[0]) != 0 {
+		t.Fatalf("First row should be the first root, got %q", result.visibleNodes[0].Path)
+	}
+	mediaRoot := result.roots[1].node
+	if result.indexOfVisible(mediaRoot) < 0 {
+		t.Fatalf("Second root should be visible")
+	}
+
+	// Each root evaluates its own filter file relative to its own base
+	movies := mediaRoot.Children[0]
+	if movies.Filter != FilterExclude {
+		t.Errorf("movies should be excluded by the media filter file, got %v", movies.Filter)
+	}
+	if path := getFilterPath(movies.Path); path != "/movies" {
+		t.Errorf("Filter path should be relative to its own root, got %q", path)
+	}
+
+	// Toggling a node records the rule in the filter set of its root
+	docs := result.roots[0].node.Children[0]
+	result.cursor = result.indexOfVisible(docs)
+	updated, _ := result.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")})
+	result = updated.(Model)
+	if result.roots[0].filters.state("docs/**") != FilterInclude {
+		t.Errorf("Rule should be added to the data filter set: %v", result.roots[0].filters.states())
+	}
+	if _, exists := result.roots[1].filters.lookup("docs/**"); exists {
+		t.Errorf("Rule must not leak into the media filter set")
+	}
+
+	if err := result.saveAll(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	data, _ := os.ReadFile(dataFilter)
+	media, _ := os.ReadFile(mediaFilter)
+	if strings.TrimSpace(string(data)) != "+ docs/**" || strings.TrimSpace(string(media)) != "- movies/**" {
+		t.Errorf("Each root should be saved to its own file, got %q and %q", data, media)
+	}
+}
+
+func TestRootPathFor(t *testing.T) {
+	originalGlobalRootPath, originalSessionRootPaths := globalRootPath, sessionRootPaths
+	defer func() { globalRootPath, sessionRootPaths = originalGlobalRootPath, originalSessionRootPaths }()
+
+	globalRootPath = "/data"
+	sessionRootPaths = nil
+	if root := rootPathFor("/media/x"); root != "/data" {
+		t.Errorf("Single root sessions should always use the global root, got %q", root)
+	}
+
+	sessionRootPaths = []string{"/data", "/media", "/data2", "gdrive:"}
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/data/docs/a.txt", "/data"},
+		{"/data2/x", "/data2"},
+		{"/media", "/media"},
+		{"gdrive:Photos", "gdrive:"},
+	}
+	for _, tt := range tests {
+		if root := rootPathFor(tt.path); root != tt.expected {
+			t.Errorf("rootPathFor(%q) = %q; want %q", tt.path, root, tt.expected)
+		}
+	}
+}
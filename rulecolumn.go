@@ -0,0 +1,118 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// ruleArrow starts the rule column, pointing back at the row it explains
+const ruleArrow = "←"
+
+// renderedRow is a row of the tree, written once the width of the rule
+// column is known
+type renderedRow struct {
+	line      string // Indentation, icons and name
+	stats     string // Sizes and labels after the name
+	rule      string // Rule column, see ruleLabel
+	ruleState FilterState
+	nameStyle lipgloss.Style
+	cursor    bool
+}
+
+// ruleLabel is the short form of the rule deciding the state of node, such
+// as "←- *" or "←+ dir1/**", and its state, or "" when no rule matches. The
+// rules of the root containing node are used, without changing the active
+// root.
+func (m Model) ruleLabel(node *FileNode) (string, FilterState) {
+	m.activateRootFor(node)
+	pattern, state := m.matchingRule(getFilterPath(node.Path))
+	if pattern == "" {
+		return "", state
+	}
+	sign := "-"
+	if state == FilterInclude {
+		sign = "+"
+	}
+	return ruleArrow + sign + " " + pattern, state
+}
+
+// toggleRuleColumn shows or hides the rule deciding each row's state
+func (m *Model) toggleRuleColumn() {
+	if m.filesFrom != nil {
+		m.statusMsg = "A --files-from list has no rules to show"
+		return
+	}
+	m.showRules = !m.showRules
+	if m.showRules {
+		m.statusMsg = "Showing the rule deciding each row, e hides them"
+	} else {
+		m.statusMsg = "Rule column hidden"
+	}
+}
+
+// rowsFit reports whether the rows fit the width of the screen with their
+// stats and rules or without them. Rows too long for it with the name alone
+// are left out, as nothing can be dropped to fit them.
+func (m Model) rowsFit(rows []renderedRow, stats, rules bool) bool {
+	if m.width <= 0 {
+		return true
+	}
+	for _, row := range rows {
+		width := lipgloss.Width(row.line)
+		if width > m.width {
+			continue
+		}
+		if stats {
+			width += lipgloss.Width(row.stats)
+		}
+		if rules && row.rule != "" {
+			width += 2 + lipgloss.Width(row.rule)
+		}
+		if width > m.width {
+			return false
+		}
+	}
+	return true
+}
+
+// writeRows writes the rows of the tree. The rules line up in a column after
+// the longest row, or as far right as they fit on the screen. On a screen
+// too narrow for them the stats are dropped, then the rules, so that the
+// names stay readable; they come back once the screen is wide enough.
+func (m Model) writeRows(b *strings.Builder, rows []renderedRow) {
+	if !m.rowsFit(rows, true, true) {
+		showRules := m.rowsFit(rows, false, true)
+		for i := range rows {
+			rows[i].stats = ""
+			if !showRules {
+				rows[i].rule = ""
+			}
+		}
+	}
+
+	column := 0
+	for _, row := range rows {
+		if row.rule != "" {
+			column = max(column, lipgloss.Width(row.line+row.stats))
+		}
+	}
+	for _, row := range rows {
+		pad := ""
+		if row.rule != "" {
+			width := lipgloss.Width(row.line + row.stats)
+			pad = strings.Repeat(" ", max(min(column, m.width-lipgloss.Width(row.rule)-2)-width, 0)+2)
+		}
+		if row.cursor {
+			b.WriteString(row.nameStyle.Render(row.line + row.stats + pad + row.rule))
+		} else {
+			b.WriteString(row.line)
+			b.WriteString(lipgloss.NewStyle().Foreground(currentTheme.Muted).Render(row.stats + pad))
+			if row.rule != "" {
+				_, style := stateStyle(row.ruleState)
+				b.WriteString(style.Render(row.rule))
+			}
+		}
+		b.WriteString("\n")
+	}
+}
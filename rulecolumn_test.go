@@ -0,0 +1,102 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestRuleColumn(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, big, small := newGuardTestModel()
+	model.width = 120
+	model.filters.set("*", FilterExclude)
+	model.filters.set("big/**", FilterInclude)
+	model.reapplyFiltersToTree(model.root)
+
+	if label, state := model.ruleLabel(big); label != "←+ big/**" || state != FilterInclude {
+		t.Errorf("ruleLabel(big) = %q, %v", label, state)
+	}
+	if label, _ := model.ruleLabel(small); label != "←- *" {
+		t.Errorf("ruleLabel(small) = %q", label)
+	}
+	model.filters.set("*", FilterNone)
+	if label, _ := model.ruleLabel(small); label != "" {
+		t.Errorf("Expected no label without a matching rule, got %q", label)
+	}
+	model.filters.set("*", FilterExclude)
+
+	if strings.Contains(model.View(), ruleArrow) {
+		t.Errorf("The rule column should be hidden by default")
+	}
+	m := pressKeys(*model, runeKey("e"))
+	view := m.View()
+	var bigLine, smallLine string
+	for _, line := range strings.Split(view, "\n") {
+		switch {
+		case strings.Contains(line, "] big"):
+			bigLine = line
+		case strings.Contains(line, "] small"):
+			smallLine = line
+		}
+	}
+	if !strings.HasSuffix(strings.TrimRight(bigLine, " "), "←+ big/**") || !strings.HasSuffix(strings.TrimRight(smallLine, " "), "←- *") {
+		t.Fatalf("Expected the rules at the end of the rows, got:\n%s", view)
+	}
+	if strings.Index(bigLine, ruleArrow) != strings.Index(smallLine, ruleArrow) {
+		t.Errorf("Expected the rules to line up:\n%s\n%s", bigLine, smallLine)
+	}
+
+	m = pressKeys(m, runeKey("e"))
+	if m.showRules || strings.Contains(m.View(), ruleArrow) {
+		t.Errorf("Expected e to hide the rule column again")
+	}
+}
+
+func TestColumnsDropOnNarrowScreens(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, _, _ := newGuardTestModel()
+	model.filters.set("big/**", FilterInclude)
+	model.reapplyFiltersToTree(model.root)
+	model.showRules = true
+
+	rowOf := func(m *Model, name string) string {
+		for _, line := range strings.Split(m.View(), "\n") {
+			if strings.Contains(line, "] "+name) {
+				return line
+			}
+		}
+		t.Fatalf("No row for %s in:\n%s", name, m.View())
+		return ""
+	}
+
+	model.width = 120
+	if row := rowOf(model, "big"); !strings.Contains(row, "files)") || !strings.Contains(row, "←+ big/**") {
+		t.Fatalf("Expected stats and rule on a wide screen, got %q", row)
+	}
+
+	// Too narrow for both, the stats go first
+	model.width = lipgloss.Width(rowOf(model, "big")) - 4
+	if row := rowOf(model, "big"); strings.Contains(row, "files)") || !strings.Contains(row, "←+ big/**") {
+		t.Errorf("Expected only the stats dropped at %d columns, got %q", model.width, row)
+	}
+
+	// Then the rules
+	model.width = 14
+	if row := rowOf(model, "big"); strings.Contains(row, ruleArrow) || !strings.Contains(row, "big") {
+		t.Errorf("Expected the name alone at %d columns, got %q", model.width, row)
+	}
+
+	// Both come back once the screen is wide again
+	model.width = 120
+	if row := rowOf(model, "big"); !strings.Contains(row, "files)") || !strings.Contains(row, ruleArrow) {
+		t.Errorf("Expected stats and rule back after resizing, got %q", row)
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// ruleOrder is a way of ordering the rules when they are saved. Rules are
+// only moved within a section, and never past an include directive, whose
+// rules have to stay where the directive is.
+type ruleOrder struct {
+	name        string
+	description string
+	// compare sorts the rules, or is nil to keep the order of the file and
+	// insert new rules where shouldInsertBefore puts them
+	compare func(a, b FilterRule) int
+}
+
+// ruleOrders are the orders rule-order and the order pane choose from, the
+// default first
+var ruleOrders = []ruleOrder{
+	{
+		name:        "preserve",
+		description: "Keep the order of the file, inserting new rules before broader ones",
+	},
+	{
+		name:        "specificity",
+		description: "Most specific first: deeper paths before shallower ones, catch-alls such as * last",
+		compare:     compareSpecificity,
+	},
+	{
+		name:        "alphabetical",
+		description: "Includes before excludes, each sorted by pattern",
+		compare:     compareAlphabetical,
+	},
+}
+
+// findRuleOrder returns the order called name
+func findRuleOrder(name string) (ruleOrder, error) {
+	for _, o := range ruleOrders {
+		if o.name == name {
+			return o, nil
+		}
+	}
+	return ruleOrder{}, fmt.Errorf("%q (use preserve, specificity or alphabetical)", name)
+}
+
+// String is the name of the order, preserve for the zero order
+func (o ruleOrder) String() string {
+	if o.name == "" {
+		return ruleOrders[0].name
+	}
+	return o.name
+}
+
+// arrange returns the rules of doc in the order they are saved, including the
+// disabled ones and the rules of included files
+func (o ruleOrder) arrange(doc *FilterDocument) []FilterRule {
+	rules := slices.Clone(doc.rules)
+	if o.compare == nil {
+		return rules
+	}
+
+	// Sort each run of the file's own rules within a section
+	start := 0
+	for i := 1; i <= len(rules); i++ {
+		if i < len(rules) && rules[i].isOwnRule() && rules[start].isOwnRule() && rules[i].Section == rules[start].Section {
+			continue
+		}
+		slices.SortStableFunc(rules[start:i], o.compare)
+		start = i
+	}
+	return rules
+}
+
+// write writes the rules of doc in filter file format in this order
+func (o ruleOrder) write(w io.Writer, doc *FilterDocument) error {
+	return writeFilterRules(w, o.arrange(doc))
+}
+
+// patternSpecificity ranks how narrow a pattern is: the number of path
+// segments without wildcards, then the number of segments, then the fewer
+// wildcards the better
+func patternSpecificity(pattern string) (literal, segments, wildcards int) {
+	for _, segment := range strings.Split(strings.Trim(pattern, "/"), "/") {
+		if segment == "" {
+			continue
+		}
+		segments++
+		if n := strings.Count(segment, "*") + strings.Count(segment, "?") + strings.Count(segment, "["); n > 0 {
+			wildcards += n
+		} else {
+			literal++
+		}
+	}
+	return literal, segments, wildcards
+}
+
+// compareSpecificity puts the more specific of two rules first, so that an
+// exception to a broader rule is read by rclone before it
+func compareSpecificity(a, b FilterRule) int {
+	aLiteral, aSegments, aWildcards := patternSpecificity(a.Pattern)
+	bLiteral, bSegments, bWildcards := patternSpecificity(b.Pattern)
+	switch {
+	case aLiteral != bLiteral:
+		return bLiteral - aLiteral
+	case aSegments != bSegments:
+		return bSegments - aSegments
+	case aWildcards != bWildcards:
+		return aWildcards - bWildcards
+	case len(a.Pattern) != len(b.Pattern):
+		// "*.jpg" is narrower than "*"
+		return len(b.Pattern) - len(a.Pattern)
+	}
+	return strings.Compare(a.Pattern, b.Pattern)
+}
+
+// compareAlphabetical puts includes before excludes, each sorted by pattern
+func compareAlphabetical(a, b FilterRule) int {
+	if a.State != b.State {
+		if a.State == FilterInclude {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(strings.ToLower(a.Pattern), strings.ToLower(b.Pattern))
+}
+
+// orderPreview is what saving in an order would do to the active root's rules
+type orderPreview struct {
+	rules      []FilterRule
+	moved      int // Rules at another place than in the preserved order
+	mismatches int // Files rclone would treat differently than the tree shows
+}
+
+// previewOrder arranges the active root's rules in o and checks the files
+// below top against them with rclone's first match wins
+func (m *Model) previewOrder(o ruleOrder, top *FileNode) orderPreview {
+	p := orderPreview{rules: o.arrange(m.filters)}
+	preserved := ruleOrder{}.arrange(m.filters)
+	for i, rule := range p.rules {
+		if i < len(preserved) && rule != preserved[i] {
+			p.moved++
+		}
+	}
+	for _, node := range collectNodes(top, nil) {
+		if node.IsDir {
+			continue
+		}
+		state := getEffectiveFilter(getFilterPath(node.Path), p.rules)
+		if (state == FilterExclude) != (node.Filter == FilterExclude) {
+			p.mismatches++
+		}
+	}
+	return p
+}
+
+// openRuleOrder shows the orders to save the rules in, starting on the one in
+// use. Each order is previewed once here, as that checks every file of the
+// tree, and the rules can't change while the pane is open.
+func (m *Model) openRuleOrder() {
+	m.orderCursor = 0
+	for i, o := range ruleOrders {
+		if o.name == m.ruleOrder.String() {
+			m.orderCursor = i
+		}
+	}
+	m.activateRootFor(m.rulesTop)
+	m.orderPreviews = make([]orderPreview, len(ruleOrders))
+	for i, o := range ruleOrders {
+		m.orderPreviews[i] = m.previewOrder(o, m.rulesTop)
+	}
+	m.openModal(modalRuleOrder)
+}
+
+// updateRuleOrderPane handles keys while the order pane is open
+func (m Model) updateRuleOrderPane(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		if m.orderCursor > 0 {
+			m.orderCursor--
+		}
+	case "down", "j":
+		if m.orderCursor < len(ruleOrders)-1 {
+			m.orderCursor++
+		}
+	case "enter":
+		m.ruleOrder = ruleOrders[m.orderCursor]
+		m.closeModal(modalRuleOrder)
+		m.orderPreviews = nil
+		m.statusMsg = "Rules are saved in " + m.ruleOrder.name + " order"
+	case "esc", "q", "o":
+		m.closeModal(modalRuleOrder)
+		m.orderPreviews = nil
+	case "ctrl+c":
+		m.cancel()
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m Model) renderRuleOrder() string {
+	paneStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(currentTheme.Border).
+		Padding(1, 2)
+	muted := lipgloss.NewStyle().Foreground(currentTheme.Muted)
+
+	m.activateRootFor(m.rulesTop)
+	var b strings.Builder
+	fmt.Fprintf(&b, "Order to save the rules of %s in:\n\n", m.filterFile)
+	var selected orderPreview
+	for i, o := range ruleOrders {
+		var p orderPreview
+		if i < len(m.orderPreviews) {
+			p = m.orderPreviews[i]
+		}
+		if i == m.orderCursor {
+			selected = p
+		}
+		summary := fmt.Sprintf("%d rules moved", p.moved)
+		if p.mismatches > 0 {
+			summary += fmt.Sprintf(", %s files rclone would treat differently than shown", formatCount(p.mismatches))
+		}
+		line := fmt.Sprintf("%-13s %s", o.name, o.description)
+		if o.name == m.ruleOrder.String() {
+			line += " (in use)"
+		}
+		style := lipgloss.NewStyle()
+		if i == m.orderCursor {
+			style = style.Background(currentTheme.CursorBg).Foreground(currentTheme.CursorFg)
+		}
+		b.WriteString(style.Render(line) + "\n")
+		b.WriteString(muted.Render("              "+summary) + "\n")
+	}
+
+	// Preview the start of the file in the selected order
+	visibleHeight := max(m.height-12-2*len(ruleOrders), 5)
+	lines := make([]string, 0, len(selected.rules))
+	for _, rule := range selected.rules {
+		if rule.Origin == "" {
+			lines = append(lines, rule.String())
+		}
+	}
+	if len(lines) > visibleHeight {
+		lines = append(lines[:visibleHeight-1], fmt.Sprintf("... and %d more", len(lines)-visibleHeight+1))
+	}
+	b.WriteString("\nPreview:\n")
+	b.WriteString(strings.Join(lines, "\n"))
+	b.WriteString("\n\nrclone uses the first rule matching a path, so an order can change what is synced.\n")
+	b.WriteString("↑/↓ select, Enter save in this order, Esc close")
+
+	return m.placePane(paneStyle, b.String())
+}
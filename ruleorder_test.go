@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestRuleOrders(t *testing.T) {
+	input := "- *\n- Photos/**\n# --- Keep ---\n- *.tmp\n+ Photos/2024/**\n#include shared.txt\n- cache/**\n"
+	doc, err := parseFilterRules(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc.set("Photos/2024/best.jpg", FilterInclude)
+
+	tests := []struct {
+		order    string
+		expected string
+	}{
+		// A new rule goes before the first rule covering it, here the catch-all
+		{"preserve", "+ Photos/2024/best.jpg\n- *\n- Photos/**\n\n# --- Keep ---\n- *.tmp\n+ Photos/2024/**\n#include shared.txt\n- cache/**\n"},
+		// Rules move within their section and not past the include directive
+		{"specificity", "+ Photos/2024/best.jpg\n- Photos/**\n- *\n\n# --- Keep ---\n+ Photos/2024/**\n- *.tmp\n#include shared.txt\n- cache/**\n"},
+		{"alphabetical", "+ Photos/2024/best.jpg\n- *\n- Photos/**\n\n# --- Keep ---\n+ Photos/2024/**\n- *.tmp\n#include shared.txt\n- cache/**\n"},
+	}
+	for _, tt := range tests {
+		order, err := findRuleOrder(tt.order)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		if err := order.write(&out, doc); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != tt.expected {
+			t.Errorf("%s order wrote\n%s\nwant\n%s", tt.order, out.String(), tt.expected)
+		}
+	}
+
+	if _, err := findRuleOrder("random"); err == nil {
+		t.Errorf("Expected an unknown order to be refused")
+	}
+}
+
+func TestCompareSpecificity(t *testing.T) {
+	patterns := []string{"**", "*", "*.jpg", "Photos/**", "Photos/*.jpg", "Photos/2024/**", "/Photos/2024/a.jpg"}
+	for i := 1; i < len(patterns); i++ {
+		a := FilterRule{Pattern: patterns[i]}
+		b := FilterRule{Pattern: patterns[i-1]}
+		if compareSpecificity(a, b) >= 0 {
+			t.Errorf("Expected %q to sort before %q", a.Pattern, b.Pattern)
+		}
+	}
+}
+
+func TestPreviewOrderCountsMismatches(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, big, _ := newGuardTestModel()
+	model.filters = newFilterDocument([]FilterRule{{Pattern: "big/**", State: FilterExclude}, {Pattern: "big/0.bin", State: FilterInclude}})
+	model.reapplyFiltersToTree(model.root)
+	// Both the tree and rclone read big/** first
+	if big.Children[0].Filter != FilterExclude {
+		t.Fatalf("Expected the tree to show the first rule")
+	}
+
+	// Sorting by specificity puts the exception first, which changes the file
+	if p := model.previewOrder(ruleOrders[0], model.root); p.moved != 0 || p.mismatches != 0 {
+		t.Errorf("preserve: moved %d, mismatches %d; want 0 and 0", p.moved, p.mismatches)
+	}
+	if p := model.previewOrder(ruleOrders[1], model.root); p.moved != 2 || p.mismatches != 1 {
+		t.Errorf("specificity: moved %d, mismatches %d; want 2 and 1", p.moved, p.mismatches)
+	}
+}
+
+func TestRuleOrderPane(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, _, _ := newGuardTestModel()
+	model.filterFile = filepath.Join(t.TempDir(), "filter.txt")
+	model.filters = newFilterDocument([]FilterRule{{Pattern: "*", State: FilterExclude}, {Pattern: "big/**", State: FilterInclude}})
+
+	m := pressKeys(*model, runeKey("p"), runeKey("o"))
+	if !m.modalOpen(modalRuleOrder) {
+		t.Fatalf("Expected o to open the order pane from the rules pane")
+	}
+	if view := m.View(); !strings.Contains(view, "preserve") || !strings.Contains(view, "(in use)") || !strings.Contains(view, "Preview:") {
+		t.Errorf("Expected the orders and a preview, got:\n%s", view)
+	}
+	// The previews are worked out once when the pane opens, not per frame
+	if len(m.orderPreviews) != len(ruleOrders) || m.orderPreviews[1].moved != 2 {
+		t.Fatalf("Expected the previews computed on opening, got %+v", m.orderPreviews)
+	}
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyDown}, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.ruleOrder.String() != "specificity" || m.modalOpen(modalRuleOrder) {
+		t.Fatalf("Expected Enter to choose the specificity order, got %s", m.ruleOrder)
+	}
+	if m.orderPreviews != nil {
+		t.Errorf("Expected the previews dropped with the pane")
+	}
+
+	if err := m.saveAll(); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(m.filterFile)
+	if string(data) != "+ big/**\n- *\n" {
+		t.Errorf("Expected the rules saved most specific first, got %q", data)
+	}
+}
+
+func TestLoadConfigRuleOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	os.WriteFile(path, []byte("rule-order = alphabetical\n"), 0644)
+
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.RuleOrder.String() != "alphabetical" || defaultConfig().RuleOrder.String() != "preserve" {
+		t.Errorf("RuleOrder = %s; want alphabetical", cfg.RuleOrder)
+	}
+}
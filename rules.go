@@ -0,0 +1,426 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// disabledRulePrefix marks a rule that was switched off in the rules pane. It
+// is saved as a comment, which rclone skips, and read back as a disabled rule:
+//
+//	# disabled: - Photos/raw/**
+const disabledRulePrefix = "# disabled: "
+
+// sectionHeaderLine matches the comment starting a section of rules, which the
+// rules pane can fold and switch off as a whole:
+//
+//	# --- Photos ---
+var sectionHeaderLine = regexp.MustCompile(`^#\s*-{3,}\s*(.*?)\s*-{3,}$`)
+
+// parseSectionHeader returns the name of the section a header line starts
+func parseSectionHeader(line string) (string, bool) {
+	match := sectionHeaderLine.FindStringSubmatch(line)
+	if match == nil || match[1] == "" {
+		return "", false
+	}
+	return match[1], true
+}
+
+// sectionHeader is the line a section is saved with
+func sectionHeader(name string) string {
+	return "# --- " + name + " ---"
+}
+
+func (r FilterRule) String() string {
+	if r.Include != "" {
+		return includeDirective + r.Include
+	}
+	line := "- " + r.Pattern
+	if r.State == FilterInclude {
+		line = "+ " + r.Pattern
+	}
+	if r.Disabled {
+		line = disabledRulePrefix + line
+	}
+	return line
+}
+
+// parseDisabledRule parses a line written for a disabled rule
+func parseDisabledRule(line string) (FilterRule, bool) {
+	rest, ok := strings.CutPrefix(line, disabledRulePrefix)
+	if !ok {
+		return FilterRule{}, false
+	}
+	if pattern, ok := strings.CutPrefix(rest, "+ "); ok {
+		return FilterRule{Pattern: pattern, State: FilterInclude, Disabled: true}, true
+	}
+	if pattern, ok := strings.CutPrefix(rest, "- "); ok {
+		return FilterRule{Pattern: pattern, State: FilterExclude, Disabled: true}, true
+	}
+	return FilterRule{}, false
+}
+
+// savedRules returns the active root's rules in the order they are saved,
+// including the new ones and the disabled ones
+func (m *Model) savedRules() []FilterRule {
+	return m.ruleOrder.arrange(m.filters)
+}
+
+// setRuleDisabled switches a rule of the active root off or back on. The
+// rules are first put in their saved order, so that a new rule keeps its
+// place while it is disabled.
+func (m *Model) setRuleDisabled(pattern string, disabled bool) {
+	rules := m.savedRules()
+	i := slices.IndexFunc(rules, func(r FilterRule) bool { return r.Pattern == pattern && r.isOwnRule() })
+	if i < 0 || rules[i].Disabled == disabled {
+		return
+	}
+
+	m.filterGen++
+	rules[i].Disabled = disabled
+	m.filters.replace(rules)
+	// The rules may have been put in another order
+	m.filterCache.clear()
+}
+
+// moveRule moves one of the file's own rules a place up (step -1) or down
+// (step 1), over an include directive together with the rules it pulled in.
+// The rule joins the section of the rule it passes. It returns where the
+// rule is now, or -1 when it didn't move.
+func (m *Model) moveRule(rule FilterRule, step int) int {
+	if m.ruleOrder.compare != nil {
+		m.statusMsg = fmt.Sprintf("Rules are saved %s, switch the order to keep them as written (o) to move them", m.ruleOrder)
+		return -1
+	}
+	if !rule.isOwnRule() {
+		m.statusMsg = "Only the file's own rules can be moved"
+		return -1
+	}
+	rules := m.filters.rules
+	i := slices.Index(rules, rule)
+	if i < 0 {
+		return -1
+	}
+	j := i + step
+	if step < 0 {
+		for j >= 0 && rules[j].Origin != "" {
+			j--
+		}
+	} else {
+		for j+1 < len(rules) && rules[j+1].Origin != "" {
+			j++
+		}
+	}
+	if j < 0 || j >= len(rules) {
+		return -1
+	}
+
+	m.filterGen++
+	section := rules[j].Section
+	m.filters.move(i, j)
+	m.filters.rules[j].Section = section
+	m.filterCache.clear()
+	return j
+}
+
+// notePrompt asks for the comment written above a rule
+type notePrompt struct {
+	rule  FilterRule
+	input string
+}
+
+// updateNotePrompt handles typing the note of a rule
+func (m Model) updateNotePrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := m.notePrompt
+	if used, cmd := m.editPrompt(&p.input, msg); used {
+		return m, cmd
+	}
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.notePrompt = nil
+		m.closeModal(modalNotePrompt)
+		if i := slices.Index(m.filters.rules, p.rule); i >= 0 {
+			m.filterGen++
+			m.filters.annotate(i, p.input)
+		}
+	case tea.KeyEsc:
+		m.notePrompt = nil
+		m.closeModal(modalNotePrompt)
+	}
+	return m, nil
+}
+
+// promptText is the status line while the note is being typed
+func (p *notePrompt) promptText() string {
+	return fmt.Sprintf("Note above %s: %s█ (Enter to accept, empty removes it, Esc cancels)", p.rule, p.input)
+}
+
+// openRules shows the rules of the root containing the selected node
+func (m *Model) openRules() {
+	if m.filesFrom != nil {
+		m.statusMsg = "A file list has no rules"
+		return
+	}
+	top := m.selectedNode()
+	if top == nil || m.isHiddenRoot(top) {
+		top = m.topLevelNodes()[0]
+	}
+	for top.Parent != nil {
+		top = top.Parent
+	}
+	m.rulesTop = top
+	m.activateRootFor(top)
+	if len(m.savedRules()) == 0 {
+		m.statusMsg = "No rules yet"
+		return
+	}
+	m.openModal(modalRules)
+	m.rulesCursor = 0
+	m.rulesQuery = ""
+}
+
+// ruleRow is a line of the rules pane: the header of a section, or a rule
+type ruleRow struct {
+	section string
+	header  bool
+	rule    int // Index into the saved rules, the first of the section on headers
+}
+
+// ruleRows lists the lines of the rules pane, without the rules of folded
+// sections. While a search is on, only the matching rules are listed,
+// folded or not, under the headers of their sections.
+func (m *Model) ruleRows(rules []FilterRule) []ruleRow {
+	var rows []ruleRow
+	for i, rule := range rules {
+		if m.rulesQuery != "" && !m.ruleMatches(rule, m.rulesQuery) {
+			continue
+		}
+		if rule.Section != "" && (len(rows) == 0 || rows[len(rows)-1].section != rule.Section) {
+			rows = append(rows, ruleRow{section: rule.Section, header: true, rule: i})
+		}
+		if rule.Section == "" || !m.foldedSections[rule.Section] || m.rulesQuery != "" {
+			rows = append(rows, ruleRow{section: rule.Section, rule: i})
+		}
+	}
+	return rows
+}
+
+// setSectionFolded folds a section of the rules pane or unfolds it
+func (m *Model) setSectionFolded(section string, folded bool) {
+	if m.foldedSections == nil {
+		m.foldedSections = make(map[string]bool)
+	}
+	m.foldedSections[section] = folded
+}
+
+// toggleSection disables every rule of a section, or enables them all again
+// when they are all disabled already
+func (m *Model) toggleSection(rules []FilterRule, section string) {
+	disable := slices.ContainsFunc(rules, func(r FilterRule) bool { return r.Section == section && r.isOwnRule() && !r.Disabled })
+	for _, rule := range rules {
+		if rule.Section == section && rule.isOwnRule() {
+			m.setRuleDisabled(rule.Pattern, disable)
+		}
+	}
+}
+
+// updateRulesPane handles keys while the rules pane is open
+func (m Model) updateRulesPane(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.activateRootFor(m.rulesTop)
+	rules := m.savedRules()
+	rows := m.ruleRows(rules)
+	var row *ruleRow
+	if m.rulesCursor < len(rows) {
+		row = &rows[m.rulesCursor]
+	}
+
+	switch msg.String() {
+	case "up", "k":
+		if m.rulesCursor > 0 {
+			m.rulesCursor--
+		}
+	case "down", "j":
+		if m.rulesCursor < len(rows)-1 {
+			m.rulesCursor++
+		}
+	case " ":
+		if row == nil {
+			break
+		}
+		if row.header {
+			m.toggleSection(rules, row.section)
+		} else if rule := rules[row.rule]; !rule.isOwnRule() {
+			m.statusMsg = fmt.Sprintf("Rules from %s are read-only, edit that file to change them", rule.Include+rule.Origin)
+			break
+		} else {
+			m.setRuleDisabled(rules[row.rule].Pattern, !rules[row.rule].Disabled)
+		}
+		// Show the effect of the rule right away
+		m.reapplyFiltersToTree(m.rulesTop)
+		m.updateVisibleNodes()
+	case "enter":
+		if row != nil && row.header {
+			m.setSectionFolded(row.section, !m.foldedSections[row.section])
+		}
+	case "right", "l":
+		if row != nil && row.header {
+			m.setSectionFolded(row.section, false)
+		}
+	case "left", "h":
+		if row == nil || row.section == "" {
+			break
+		}
+		m.setSectionFolded(row.section, true)
+		// Move up to the header of the folded section
+		for i := m.rulesCursor; i >= 0; i-- {
+			if rows[i].header {
+				m.rulesCursor = i
+				break
+			}
+		}
+	case "K", "J":
+		if row == nil || row.header {
+			break
+		}
+		step := 1
+		if msg.String() == "K" {
+			step = -1
+		}
+		if i := m.moveRule(rules[row.rule], step); i >= 0 {
+			m.rulesCursor = slices.IndexFunc(m.ruleRows(m.savedRules()), func(r ruleRow) bool { return !r.header && r.rule == i })
+			m.reapplyFiltersToTree(m.rulesTop)
+			m.updateVisibleNodes()
+		}
+	case "c":
+		if row == nil || row.header {
+			break
+		}
+		if rule := rules[row.rule]; rule.Origin != "" {
+			m.statusMsg = fmt.Sprintf("Rules from %s are read-only, edit that file to change them", rule.Origin)
+		} else {
+			m.notePrompt = &notePrompt{rule: rule, input: strings.ReplaceAll(rule.note(), "\n", " ")}
+			m.openModal(modalNotePrompt)
+		}
+	case "t":
+		m.openTemplates()
+	case "o":
+		m.openRuleOrder()
+	case "/":
+		m.openModal(modalRuleSearchPrompt)
+	case "esc":
+		// A search is cleared before the pane is closed
+		if m.rulesQuery != "" {
+			m.rulesQuery = ""
+			m.rulesCursor = 0
+			break
+		}
+		m.closeModal(modalRules)
+	case "p", "q":
+		m.closeModal(modalRules)
+	case "ctrl+c":
+		m.cancel()
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m Model) renderRules() string {
+	paneStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(currentTheme.Border).
+		Padding(1, 2)
+
+	m.activateRootFor(m.rulesTop)
+	rules := m.savedRules()
+	var lines []string
+	for i, row := range m.ruleRows(rules) {
+		var line string
+		style := lipgloss.NewStyle().Foreground(currentTheme.Exclude)
+		if row.header {
+			count, disabled := 0, 0
+			for _, rule := range rules {
+				if rule.Section == row.section {
+					count++
+					if rule.Disabled {
+						disabled++
+					}
+				}
+			}
+			marker := "▼"
+			if m.foldedSections[row.section] {
+				marker = "▶"
+			}
+			line = fmt.Sprintf("%s %s  (%d rules", marker, row.section, count)
+			if disabled > 0 {
+				line += fmt.Sprintf(", %d disabled", disabled)
+			}
+			line += ")"
+			style = style.Foreground(currentTheme.Header).Bold(true)
+		} else {
+			rule := rules[row.rule]
+			line = rule.String()
+			if rule.Comment != "" {
+				line += "  " + strings.ReplaceAll(rule.Comment, "\n", " ")
+			}
+			if row.section != "" {
+				line = "  " + line
+			}
+			if isEscapedPattern(rule.Pattern) {
+				line += "  (escaped for rclone)"
+			}
+			if rule.Origin != "" {
+				line = "  " + line + "  (from " + rule.Origin + ", read-only)"
+			}
+			_, style = stateStyle(rule.State)
+			if rule.Disabled || rule.Include != "" {
+				style = style.Foreground(currentTheme.Muted)
+			}
+		}
+		if i == m.rulesCursor {
+			style = lipgloss.NewStyle().Background(currentTheme.CursorBg).Foreground(currentTheme.CursorFg)
+		}
+		lines = append(lines, style.Render(line))
+	}
+
+	// Keep the selected rule in view when the list is taller than the screen
+	recent := m.activity[max(0, len(m.activity)-recentActivityRows):]
+	visibleHeight := m.height - 12
+	if len(recent) > 0 {
+		visibleHeight -= len(recent) + 2
+	}
+	if visibleHeight < 5 {
+		visibleHeight = 20
+	}
+	start := 0
+	if m.rulesCursor >= visibleHeight {
+		start = m.rulesCursor - visibleHeight + 1
+	}
+	end := min(start+visibleHeight, len(lines))
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Rules of %s, in the order rclone reads them:\n\n", m.filterFile))
+	if m.rulesQuery != "" {
+		fmt.Fprintf(&b, "%d of %d rules match %q (/ changes the search, Esc clears it)\n\n", m.ruleMatchCount(rules), len(rules), m.rulesQuery)
+	}
+	b.WriteString(strings.Join(lines[start:end], "\n"))
+	if len(recent) > 0 {
+		b.WriteString("\n\nRecent changes:")
+		muted := lipgloss.NewStyle().Foreground(currentTheme.Muted)
+		for _, summary := range recent {
+			b.WriteString("\n" + muted.Render("  "+summary))
+		}
+	}
+	b.WriteString("\n\nDisabled rules are saved as comments and can be enabled again.\n")
+	b.WriteString("Comments like \"# --- Photos ---\" start sections; Enter folds one, Space on it switches it all.\n")
+	if slices.ContainsFunc(rules, func(r FilterRule) bool { return r.Include != "" }) {
+		b.WriteString("Rules pulled in by \"#include file\" are read-only here, edit their file to change them.\n")
+	}
+	fmt.Fprintf(&b, "↑/↓ select, / search, Space disable/enable, K/J move, c note, t add from a template, o order (%s), Esc close", m.ruleOrder)
+
+	return m.placePane(paneStyle, b.String())
+}
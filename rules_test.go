@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestDisabledRulesRoundTrip(t *testing.T) {
+	input := "- big/**\n# disabled: + big/keep.bin\n# a comment\n- small/**\n"
+	doc, err := parseFilterRules(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := doc.rules
+	if len(rules) != 3 || !rules[1].Disabled || rules[1].State != FilterInclude {
+		t.Fatalf("Expected the disabled rule to be read in place, got %+v", rules)
+	}
+	if _, ok := doc.lookup("big/keep.bin"); ok {
+		t.Errorf("A disabled rule should not be active")
+	}
+	if state := getEffectiveFilter("big/keep.bin", rules); state != FilterExclude {
+		t.Errorf("Expected the disabled include to be skipped, got %v", state)
+	}
+
+	var out bytes.Buffer
+	if err := doc.write(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != input {
+		t.Errorf("Expected\n%s\ngot\n%s", input, out.String())
+	}
+}
+
+func TestRulesPaneDisablesRule(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, big, _ := newGuardTestModel()
+	model.filters = newFilterDocument([]FilterRule{{Pattern: "small/**", State: FilterExclude}})
+	model.setNodeFilter(big, FilterExclude)
+	model.reapplyFiltersToTree(model.root)
+
+	keys := []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune("p")},
+		{Type: tea.KeyDown}, // big/** is new and saved last
+		{Type: tea.KeySpace},
+	}
+	m := *model
+	for _, key := range keys {
+		updated, _ := m.Update(key)
+		m = updated.(Model)
+	}
+	if !m.modalOpen(modalRules) {
+		t.Fatalf("Expected the rules pane to open")
+	}
+	if big.Filter != FilterNone || big.Children[0].Filter != FilterNone {
+		t.Errorf("Expected big to be transferred with its rule disabled, got %v", big.Filter)
+	}
+
+	var out bytes.Buffer
+	m.filters.write(&out)
+	if want := "- small/**\n# disabled: - big/**\n"; out.String() != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, out.String())
+	}
+
+	// Enabling it again restores the exclusion
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeySpace})
+	if m = updated.(Model); big.Filter != FilterExclude || m.savedRules()[1].Disabled {
+		t.Errorf("Expected big to be excluded again")
+	}
+}
+
+func TestRuleSections(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	input := "- *.tmp\n\n# --- Big ---\n- big/0.bin\n- big/1.bin\n# --- Small ---\n- small/**\n"
+	doc, err := parseFilterRules(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rules := doc.rules; rules[0].Section != "" || rules[1].Section != "Big" || rules[3].Section != "Small" {
+		t.Fatalf("Expected the rules to be read with their sections, got %+v", doc.rules)
+	}
+
+	model, big, small := newGuardTestModel()
+	model.filters = doc
+	model.reapplyFiltersToTree(model.root)
+
+	// Space on the Big header disables both of its rules, Enter folds it
+	m := *model
+	for _, key := range []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune("p")},
+		{Type: tea.KeyDown},
+		{Type: tea.KeySpace},
+		{Type: tea.KeyEnter},
+		{Type: tea.KeyDown},
+	} {
+		updated, _ := m.Update(key)
+		m = updated.(Model)
+	}
+	if big.Children[0].Filter != FilterNone || small.Filter != FilterExclude {
+		t.Errorf("Expected only the rules of Big to be disabled")
+	}
+	if rows := m.ruleRows(m.savedRules()); len(rows) != 4 || !rows[1].header || m.rulesCursor != 2 {
+		t.Errorf("Expected the folded section to show its header only, got %+v at %d", rows, m.rulesCursor)
+	}
+	if view := m.View(); !strings.Contains(view, "▶ Big  (2 rules, 2 disabled)") {
+		t.Errorf("Expected the folded header in the pane:\n%s", view)
+	}
+
+	// New rules join the section of the rule before them
+	m.setNodeFilter(big.Children[5], FilterInclude)
+	var out bytes.Buffer
+	m.filters.write(&out)
+	want := "- *.tmp\n\n# --- Big ---\n# disabled: - big/0.bin\n# disabled: - big/1.bin\n\n# --- Small ---\n- small/**\n+ big/5.bin\n"
+	if out.String() != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, out.String())
+	}
+}
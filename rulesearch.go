@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ruleMatches reports whether rule matches the search of the rules pane. All
+// of its words must match: "+" or "-" keeps the rules of one sign, "is:new",
+// "is:file", "is:included" and "is:disabled" keep rules by where they come
+// from, and any other word is looked for in the pattern, ignoring case.
+func (m *Model) ruleMatches(rule FilterRule, query string) bool {
+	for _, word := range strings.Fields(query) {
+		var ok bool
+		switch word {
+		case "+":
+			ok = rule.Include == "" && rule.State == FilterInclude
+		case "-":
+			ok = rule.Include == "" && rule.State == FilterExclude
+		case "is:new":
+			ok = rule.isOwnRule() && m.filters.added[rule.Pattern]
+		case "is:file":
+			ok = rule.isOwnRule() && !m.filters.added[rule.Pattern]
+		case "is:included":
+			ok = !rule.isOwnRule()
+		case "is:disabled":
+			ok = rule.Disabled
+		default:
+			ok = strings.Contains(strings.ToLower(rule.Pattern+rule.Include), strings.ToLower(word))
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// ruleMatchCount counts the rules matching the search of the rules pane
+func (m *Model) ruleMatchCount(rules []FilterRule) int {
+	n := 0
+	for _, rule := range rules {
+		if m.ruleMatches(rule, m.rulesQuery) {
+			n++
+		}
+	}
+	return n
+}
+
+// updateRuleSearchPrompt handles typing the search of the rules pane, which
+// filters the rules as it is typed
+func (m Model) updateRuleSearchPrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if used, cmd := m.editPrompt(&m.rulesQuery, msg); used {
+		m.rulesCursor = 0
+		return m, cmd
+	}
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.closeModal(modalRuleSearchPrompt)
+	case tea.KeyEsc:
+		m.rulesQuery = ""
+		m.closeModal(modalRuleSearchPrompt)
+	}
+	return m, nil
+}
+
+// ruleSearchPromptText is the status line while the search is typed
+func (m Model) ruleSearchPromptText() string {
+	return fmt.Sprintf("Search rules: %s█ (text, + or -, is:new, is:file, is:included, is:disabled; Enter keeps it, Esc clears it)", m.rulesQuery)
+}
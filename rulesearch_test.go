@@ -0,0 +1,83 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestRuleMatches(t *testing.T) {
+	model := newTestModel()
+	model.filters = newFilterDocument([]FilterRule{
+		{Pattern: "Photos/**", State: FilterInclude},
+		{Pattern: "photos/raw/**", State: FilterExclude, Disabled: true},
+		{Pattern: "*.tmp", State: FilterExclude, Origin: "common.txt"},
+	})
+	model.filters.set("Videos/**", FilterExclude)
+
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{"photos", "Photos/** photos/raw/**"},
+		{"- photos", "photos/raw/**"},
+		{"+", "Photos/**"},
+		{"is:new", "Videos/**"},
+		{"is:file -", "photos/raw/**"},
+		{"is:included", "*.tmp"},
+		{"is:disabled", "photos/raw/**"},
+		{"music", ""},
+	}
+	for _, tt := range tests {
+		var matched []string
+		for _, rule := range model.filters.rules {
+			if model.ruleMatches(rule, tt.query) {
+				matched = append(matched, rule.Pattern)
+			}
+		}
+		if got := strings.Join(matched, " "); got != tt.expected {
+			t.Errorf("ruleMatches(%q) = %q; want %q", tt.query, got, tt.expected)
+		}
+	}
+}
+
+func TestRulesPaneSearch(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, _, _ := newGuardTestModel()
+	model.filters = newFilterDocument([]FilterRule{
+		{Pattern: "big/0.bin", State: FilterInclude, Section: "Keep"},
+		{Pattern: "big/**", State: FilterExclude, Section: "Keep"},
+		{Pattern: "small/8.bin", State: FilterExclude},
+	})
+	model.setSectionFolded("Keep", true)
+
+	m := pressKeys(*model, runeKey("p"), runeKey("/"), runeKey("bin"))
+	view := m.View()
+	for _, expected := range []string{"2 of 3 rules match \"bin\"", "  + big/0.bin", "- small/8.bin", "Search rules: bin"} {
+		if !strings.Contains(view, expected) {
+			t.Errorf("Expected %q while searching, folded section or not:\n%s", expected, view)
+		}
+	}
+	if strings.Contains(view, "big/**") {
+		t.Errorf("Expected big/** to be left out")
+	}
+
+	// Enter keeps the search, and the keys of the pane act on the matches
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEnter}, runeKey("j"), runeKey("j"), tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")})
+	if !m.filters.rules[2].Disabled {
+		t.Errorf("Expected Space to disable small/8.bin, got %+v", m.filters.rules)
+	}
+
+	// Esc clears the search before closing the pane
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEsc})
+	if m.rulesQuery != "" || m.topModal() != modalRules {
+		t.Errorf("Expected Esc to clear the search and keep the pane open")
+	}
+	if m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEsc}); m.topModal() == modalRules {
+		t.Errorf("Expected a second Esc to close the pane")
+	}
+}
@@ -0,0 +1,613 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// dirScannedMsg delivers the listing of a single directory from the scanner.
+// The children are freshly created nodes that the scanner never modifies
+// again; they are attached to the tree inside Update, so the tree is only
+// ever mutated on the bubbletea event loop.
+type dirScannedMsg struct {
+	parent   *FileNode
+	children []*FileNode
+	err      error
+	dirsOnly bool // The files of parent were left out
+}
+
+// scanUpdateInterval is how often a scan delivers what it found. A fast
+// local scan lists thousands of directories a second, and sending each on
+// its own would flood the event loop and stall the spinner, so listings and
+// progress are batched to about ten messages a second instead.
+const scanUpdateInterval = 100 * time.Millisecond
+
+// scanBatchMsg delivers what a scan found since the last batch: listings in
+// the order the directories were read, and the latest progress, if any
+type scanBatchMsg struct {
+	listings []dirScannedMsg
+	progress *loadingMsg
+}
+
+// scanner walks a directory tree in the background. Everything it needs is
+// copied in when it is created, and results are only reported through send,
+// so it shares no mutable state with the Model.
+type scanner struct {
+	ctx      context.Context
+	lister   lister
+	checkers *checkerLimit // Shared with the model, changed at runtime
+	rootPath string
+	send     func(tea.Msg)
+	exclude  []string // --scan-exclude patterns
+	stubs    bool     // Deliver skipped directories as unscanned nodes
+	dirsOnly bool     // Leave out files, they are listed per directory once expanded
+
+	dirs   int64
+	files  int64
+	size   int64 // Of the files listed
+	errors int64 // Directories that couldn't be listed
+	found  int64 // Directories found so far, listed or not
+
+	started time.Time
+
+	mu      sync.Mutex   // Guards pending and flushAt
+	pending scanBatchMsg // Not delivered yet
+	flushAt *time.Timer  // Delivers pending, once something is
+	flushMu sync.Mutex   // Keeps batches in order when flushes overlap
+}
+
+// newScanner creates a scanner for the model's current context and settings
+func (m *Model) newScanner() *scanner {
+	l := m.lister
+	if l == nil {
+		l = localLister{}
+	}
+	checkers := m.checkerLimit
+	if checkers == nil {
+		checkers = newCheckerLimit(m.checkers)
+	}
+	return &scanner{
+		ctx:      m.ctx,
+		lister:   l,
+		checkers: checkers,
+		send:     m.sender(),
+		exclude:  m.scanExclude,
+		stubs:    m.showSkipped,
+		dirsOnly: m.dirsOnly,
+		started:  time.Now(),
+	}
+}
+
+// sender returns the function background work uses to deliver messages to
+// the event loop
+func (m *Model) sender() func(tea.Msg) {
+	if m.send != nil {
+		return m.send
+	}
+	if m.program != nil {
+		return m.program.Send
+	}
+	return func(tea.Msg) {}
+}
+
+// clock returns the time the screen shows, which tests can fix with now
+func (m *Model) clock() time.Time {
+	if m.now != nil {
+		return m.now()
+	}
+	return time.Now()
+}
+
+// deliver queues msg for the next batch unless the scan has been cancelled.
+// Listings are kept in order, while progress only keeps the latest.
+func (s *scanner) deliver(msg tea.Msg) {
+	if s.ctx.Err() != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch msg := msg.(type) {
+	case dirScannedMsg:
+		s.pending.listings = append(s.pending.listings, msg)
+	case loadingMsg:
+		s.pending.progress = &msg
+	}
+	if s.flushAt == nil {
+		s.flushAt = time.AfterFunc(scanUpdateInterval, s.flush)
+	}
+}
+
+// flush sends what is pending as one batch. scan flushes before returning,
+// so that the listings reach the event loop before the message saying the
+// scan is done.
+func (s *scanner) flush() {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	s.mu.Lock()
+	batch := s.pending
+	s.pending = scanBatchMsg{}
+	if s.flushAt != nil {
+		s.flushAt.Stop()
+		s.flushAt = nil
+	}
+	s.mu.Unlock()
+
+	if s.ctx.Err() != nil || len(batch.listings) == 0 && batch.progress == nil {
+		return
+	}
+	s.send(batch)
+}
+
+// scanJob creates the job that scans every root of the session into the
+// current tree and reports back with a treeReadyMsg
+func (m *Model) scanJob() *job {
+	root := m.root
+	var names []string
+	for _, node := range m.topLevelNodes() {
+		names = append(names, node.Name)
+	}
+
+	return &job{
+		kind:  JobScan,
+		title: "Scan " + strings.Join(names, ", "),
+		node:  root,
+		start: func(m *Model) jobFunc {
+			m.loadProgress = "Scanning directories..."
+			m.scannedDirs = 0
+			m.scannedFiles = 0
+			m.foundDirs = 0
+
+			s := m.newScanner()
+			topLevel := m.topLevelNodes()
+			return func(ctx context.Context) jobResult {
+				s.ctx = ctx
+				var err error
+				for _, node := range topLevel {
+					if scanErr := s.scan(node); scanErr != nil && err == nil {
+						err = fmt.Errorf("%s: %w", node.Path, scanErr)
+					}
+				}
+				return jobResult{msg: treeReadyMsg{root: root, err: err}, detail: s.summary(), err: err}
+			}
+		},
+	}
+}
+
+// refreshDirectory replaces the tree with a fresh one and queues a full scan,
+// cancelling any scan of the old tree
+func (m *Model) refreshDirectory() tea.Cmd {
+	if m.root == nil {
+		return nil
+	}
+	m.cancelScans()
+	m.clearDuplicates()
+	m.estimates = nil
+	if node := m.selectedNode(); node != nil {
+		m.anchorPath = node.Path
+	}
+
+	// Create new root node with same path and preserve filter state
+	m.root = m.newRootNode()
+	for _, node := range m.topLevelNodes() {
+		// Use the new function that considers all the rules
+		m.activateRootFor(node)
+		node.Filter = m.getEffectiveFilterWithMap(getFilterPath(node.Path))
+	}
+	m.updateVisibleNodes()
+
+	return m.enqueue(m.scanJob())
+}
+
+// rescanSubtree queues a rescan of only the given directory (or the parent of
+// a file), leaving the rest of the tree untouched. The job reports back with
+// a subtreeReadyMsg.
+func (m *Model) rescanSubtree(node *FileNode) tea.Cmd {
+	return m.rescanWith(node, nil, "")
+}
+
+// rescanWith is rescanSubtree listing with l rather than the session's
+// lister when l is set, under its own job title
+func (m *Model) rescanWith(node *FileNode, l lister, title string) tea.Cmd {
+	if node == nil {
+		return nil
+	}
+	if !node.IsDir {
+		node = node.Parent
+		if node == nil {
+			return nil
+		}
+	}
+
+	if title == "" {
+		title = "Rescan " + node.Name
+	}
+	return m.enqueue(&job{
+		kind:  JobRescan,
+		title: title,
+		node:  node,
+		start: func(m *Model) jobFunc {
+			node.Loading = true
+			m.clearDuplicates()
+
+			// The user knows this directory changed, don't serve it from the cache
+			if m.remoteCache != nil {
+				m.remoteCache.Invalidate(node.Path)
+			}
+
+			s := m.newScanner()
+			if l != nil {
+				s.lister = l
+			}
+			if node.Skipped {
+				// Asked for by name, so scan all of it
+				s.exclude = nil
+			}
+			return func(ctx context.Context) jobResult {
+				s.ctx = ctx
+				err := s.scan(node)
+				return jobResult{msg: subtreeReadyMsg{node: node, err: err}, detail: s.summary(), err: err}
+			}
+		},
+	})
+}
+
+// summary describes how much the scanner has listed so far, how fast and
+// with how many checkers, for tuning them and for reports of slow scans
+func (s *scanner) summary() string {
+	dirs, files := atomic.LoadInt64(&s.dirs), atomic.LoadInt64(&s.files)
+	text := fmt.Sprintf("%s directories, %s files, %s", formatCount(int(dirs)), formatCount(int(files)), formatSize(atomic.LoadInt64(&s.size)))
+	switch errors := atomic.LoadInt64(&s.errors); errors {
+	case 0:
+	case 1:
+		text += ", 1 error"
+	default:
+		text += fmt.Sprintf(", %s errors", formatCount(int(errors)))
+	}
+	if elapsed := time.Since(s.started).Seconds(); elapsed > 0 && !s.started.IsZero() {
+		text += fmt.Sprintf(", %s dirs/s, %s files/s", formatCount(int(float64(dirs)/elapsed+0.5)), formatCount(int(float64(files)/elapsed+0.5)))
+	}
+	if s.checkers != nil {
+		text += fmt.Sprintf(" with %d checkers", s.checkers.current())
+	}
+	return text
+}
+
+// scan lists root and everything below it breadth-first, using as many
+// concurrent listings as the checker limit allows at the time. Returns the
+// error from reading root itself; errors further down the tree are reported
+// per directory.
+func (s *scanner) scan(root *FileNode) error {
+	if !root.IsDir || root.LinkLoop {
+		return nil
+	}
+	s.rootPath = rootPathFor(root.Path)
+	atomic.AddInt64(&s.found, 1)
+	defer s.flush()
+
+	// Read the starting directory synchronously so a failure can be reported
+	queue, err := s.scanDirectory(root)
+	if err != nil {
+		return err
+	}
+
+	for len(queue) > 0 && s.ctx.Err() == nil {
+		// Process directories at current level concurrently. The channel is
+		// large enough for every worker, so nobody blocks on cancellation.
+		var wg sync.WaitGroup
+		nextLevel := make(chan []*FileNode, len(queue))
+
+		for _, dir := range queue {
+			wg.Add(1)
+			go func(node *FileNode) {
+				defer func() {
+					// The screen belongs to the editor, so a panic is reported
+					// as an error of the directory, listed with the issues (W)
+					if r := recover(); r != nil {
+						atomic.AddInt64(&s.errors, 1)
+						s.deliver(dirScannedMsg{parent: node, err: fmt.Errorf("scan failed: %v", r)})
+					}
+					wg.Done()
+				}()
+
+				if !s.checkers.acquire(s.ctx) {
+					return
+				}
+				defer s.checkers.release()
+
+				children, _ := s.scanDirectory(node)
+				nextLevel <- children
+			}(dir)
+		}
+
+		wg.Wait()
+		close(nextLevel)
+
+		queue = nil
+		for children := range nextLevel {
+			queue = append(queue, children...)
+		}
+	}
+
+	return nil
+}
+
+// scanExcluded reports whether a --scan-exclude pattern matches filterPath.
+// As in rclone, a pattern without a slash matches the name at any depth, and
+// "dir/**" matches the directory itself, so that it isn't listed at all.
+func scanExcluded(patterns []string, filterPath string) bool {
+	name := path.Base(filterPath)
+	for _, pattern := range patterns {
+		if !strings.Contains(pattern, "/") && matchesRclonePattern(pattern, name) {
+			return true
+		}
+		if matchesRclonePattern(pattern, filterPath) || matchesRclonePattern(pattern, filterPath+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// scanDirectory lists a single directory, delivers its children to the event
+// loop and returns the child directories that still need scanning. Only the
+// immutable fields (Path, IsDir) of node are read.
+func (s *scanner) scanDirectory(node *FileNode) ([]*FileNode, error) {
+	if err := s.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	list := s.lister.List
+	if l, ok := s.lister.(dirLister); ok && s.dirsOnly {
+		list = l.ListDirs
+	}
+	entries, err := list(s.ctx, node.Path)
+	if err != nil {
+		atomic.AddInt64(&s.errors, 1)
+		s.deliver(dirScannedMsg{parent: node, err: err})
+		return nil, err
+	}
+
+	// Update progress
+	dirs := atomic.AddInt64(&s.dirs, 1)
+	if dirs%10 == 0 {
+		s.deliver(loadingMsg{
+			progress: "Scanning directories...",
+			dirs:     dirs,
+			files:    atomic.LoadInt64(&s.files),
+			found:    atomic.LoadInt64(&s.found),
+		})
+	}
+
+	var children []*FileNode
+	var childDirectories []*FileNode
+
+	for _, entry := range entries {
+		if s.dirsOnly && !entry.IsDir {
+			continue
+		}
+		child, ok := s.childNode(node, entry)
+		if !ok {
+			continue // Skip potentially malicious paths
+		}
+
+		if scanExcluded(s.exclude, getFilterPath(child.Path)) {
+			if entry.IsDir && s.stubs {
+				child.Skipped = true
+				children = append(children, child)
+			}
+			continue
+		}
+
+		if !entry.IsDir {
+			files := atomic.AddInt64(&s.files, 1)
+			atomic.AddInt64(&s.size, entry.Size)
+			if files%500 == 0 {
+				s.deliver(loadingMsg{
+					progress: "Scanning directories...",
+					dirs:     atomic.LoadInt64(&s.dirs),
+					files:    files,
+					found:    atomic.LoadInt64(&s.found),
+				})
+			}
+		} else if !entry.LinkLoop {
+			child.Loading = true
+			childDirectories = append(childDirectories, child)
+			atomic.AddInt64(&s.found, 1)
+		}
+
+		children = append(children, child)
+	}
+
+	s.deliver(dirScannedMsg{parent: node, children: children, dirsOnly: s.dirsOnly})
+
+	return childDirectories, nil
+}
+
+// childNode creates the node of an entry listed in node, or reports false if
+// its path would leave the root
+func (s *scanner) childNode(node *FileNode, entry dirEntry) (*FileNode, bool) {
+	childPath := joinChildPath(node.Path, entry.Name)
+
+	// Validate path to prevent traversal attacks
+	if err := validatePath(childPath, s.rootPath); err != nil {
+		return nil, false
+	}
+
+	return &FileNode{
+		Name:    entry.Name,
+		Path:    childPath,
+		IsDir:   entry.IsDir,
+		Size:    entry.Size,
+		ModTime: entry.ModTime,
+		Hash:    entry.Hash,
+		Parent:  node,
+
+		Unreadable: entry.Unreadable,
+		Link:       entry.Link,
+		LinkLoop:   entry.LinkLoop,
+	}, true
+}
+
+// applyProgress shows how far a scan has got on the loading screen
+func (m *Model) applyProgress(msg loadingMsg) {
+	m.loadProgress = msg.progress
+	m.scannedDirs = msg.dirs
+	m.scannedFiles = msg.files
+	m.foundDirs = msg.found
+	m.scanRate.sample(time.Now(), msg.dirs, msg.files)
+}
+
+// applyDirScan attaches a single scanned directory listing to the tree and
+// rebuilds the rows if the directory is open. With --dirs-only it returns the
+// command listing the files of the directory.
+func (m *Model) applyDirScan(msg dirScannedMsg) tea.Cmd {
+	if !m.attachListing(msg) {
+		return nil
+	}
+	m.updateVisibleNodes()
+	return m.listFiles(msg.parent)
+}
+
+// applyScanBatch attaches the listings of a batch and shows the latest
+// progress. The rows are rebuilt once for the whole batch rather than once
+// per directory, which is what keeps a fast scan from stalling the screen.
+func (m *Model) applyScanBatch(msg scanBatchMsg) tea.Cmd {
+	var shown []*FileNode
+	for _, listing := range msg.listings {
+		if m.attachListing(listing) {
+			shown = append(shown, listing.parent)
+		}
+	}
+	if msg.progress != nil {
+		m.applyProgress(*msg.progress)
+	}
+	if len(shown) == 0 {
+		return nil
+	}
+	m.updateVisibleNodes()
+	var cmds []tea.Cmd
+	for _, dir := range shown {
+		cmds = append(cmds, m.listFiles(dir))
+	}
+	return tea.Batch(cmds...)
+}
+
+// attachListing puts a scanned listing into the tree and reports whether
+// its directory is open on the screen, so that the rows need rebuilding. It
+// runs on the event loop, so it is the only place scanned nodes become
+// visible.
+func (m *Model) attachListing(msg dirScannedMsg) bool {
+	parent := msg.parent
+	parent.Loading = false
+	parent.ListErr = nil
+	if msg.err != nil {
+		// A cancelled scan isn't a problem with the directory itself
+		if !errors.Is(msg.err, context.Canceled) {
+			parent.ListErr = msg.err
+		}
+		if errors.Is(msg.err, fs.ErrPermission) {
+			parent.Unreadable = true
+		}
+		return false
+	}
+	parent.Unreadable = false
+	parent.Skipped = false
+	m.activateRootFor(parent)
+
+	// Carry over the expanded state of directories that were already shown,
+	// so rescanning a subtree doesn't collapse it
+	expanded := make(map[string]bool)
+	for _, old := range parent.Children {
+		if old.IsDir && old.Expanded {
+			expanded[old.Name] = true
+		}
+	}
+
+	for _, child := range msg.children {
+		child.Filter = m.getEffectiveFilterWithMap(getFilterPath(child.Path))
+		// After a refresh, reopen the directories leading to the old place
+		if expanded[child.Name] || (child.IsDir && child.Path != m.anchorPath && pathContains(child.Path, m.anchorPath)) {
+			child.Expanded = true
+		}
+	}
+	m.sortChildren(msg.children)
+	parent.Children = msg.children
+	parent.FilesPending = msg.dirsOnly
+
+	// Recompute totals for the directory and its ancestors
+	for node := parent; node != nil; node = node.Parent {
+		sumChildStats(node)
+	}
+
+	return m.isShown(parent) && parent.Expanded
+}
+
+// sumChildStats sets the totals of a directory from its direct children
+func sumChildStats(node *FileNode) {
+	var totalSize int64
+	var totalFiles int
+	var types typeSizes
+	unlisted := 0
+	if node.FilesPending {
+		unlisted = 1
+	}
+	for _, child := range node.Children {
+		if child.IsDir {
+			totalSize += child.TotalSize
+			totalFiles += child.TotalFiles
+			types.add(child.Types)
+			unlisted += child.UnlistedDirs
+		} else {
+			totalSize += child.Size
+			totalFiles++
+			types[fileTypeOf(child.Name)] += child.Size
+		}
+	}
+	node.TotalSize = totalSize
+	node.TotalFiles = totalFiles
+	node.Types = &types
+	node.UnlistedDirs = unlisted
+}
+
+// isShown reports whether node is part of the current tree and all of its
+// ancestors are expanded
+func (m *Model) isShown(node *FileNode) bool {
+	for n := node.Parent; n != nil; n = n.Parent {
+		if !n.Expanded {
+			return false
+		}
+		node = n
+	}
+	for _, top := range m.topLevelNodes() {
+		if node == top {
+			return true
+		}
+	}
+	return false
+}
+
+// rootNodeName returns the display name of the root directory node
+func rootNodeName(rootPath string) string {
+	if isRemotePath(rootPath) {
+		return rootPath
+	}
+	return filepath.Base(rootPath)
+}
+
+// joinChildPath builds the path of an entry inside dir
+func joinChildPath(dir, name string) string {
+	if isRemotePath(dir) {
+		return joinRemotePath(dir, name)
+	}
+	return filepath.Join(dir, name)
+}
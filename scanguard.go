@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultScanDeny are the pseudo filesystems of Linux, which are endless to
+// scan and never worth backing up
+var defaultScanDeny = []string{"/proc", "/sys", "/dev"}
+
+// defaultWarnHomeSize is the space used on the disk of a home directory above
+// which scanning the home directory asks first
+const defaultWarnHomeSize = 100 << 30
+
+// insideDir reports whether path is dir or below it. Both are absolute.
+func insideDir(path, dir string) bool {
+	return path == dir || strings.HasPrefix(path, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))
+}
+
+// deniedDir returns the scan-deny entry that dir is or is inside of
+func deniedDir(dir string, deny []string) (string, bool) {
+	abs := absPath(dir)
+	for _, d := range deny {
+		if insideDir(abs, absPath(d)) {
+			return d, true
+		}
+	}
+	return "", false
+}
+
+// deniedBelow turns the scan-deny entries inside root into --scan-exclude
+// patterns, so that scanning "/" passes over /proc and /sys
+func deniedBelow(root string, deny []string) []string {
+	abs := absPath(root)
+	var patterns []string
+	for _, d := range deny {
+		if d = absPath(d); d == abs || !insideDir(d, abs) {
+			continue
+		}
+		rel, err := filepath.Rel(abs, d)
+		if err != nil {
+			continue
+		}
+		patterns = append(patterns, "/"+escapeGlob(filepath.ToSlash(rel))+"/**")
+	}
+	return patterns
+}
+
+// riskyRoot tells why scanning root is likely a mistake: it is the root of
+// the filesystem, or a home directory on a disk using more than
+// warnHomeSize. It returns "" for other directories.
+func riskyRoot(root string, warnHomeSize int64) string {
+	abs := absPath(root)
+	if abs == filepath.VolumeName(abs)+string(filepath.Separator) {
+		return "is the root of the filesystem"
+	}
+	home, err := os.UserHomeDir()
+	if err != nil || warnHomeSize == 0 || abs != absPath(home) {
+		return ""
+	}
+	if used, ok := diskUsed(abs); ok && used > warnHomeSize {
+		return fmt.Sprintf("is your home directory, on a disk using %s", formatSize(used))
+	}
+	return ""
+}
+
+// confirmScan asks whether to scan a risky root anyway, reading the answer
+// from in
+func confirmScan(root, reason string, in io.Reader, out io.Writer) bool {
+	fmt.Fprintf(out, "%s %s, scanning it may take very long. Scan it anyway? [y/N] ", root, reason)
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDeniedDir(t *testing.T) {
+	deny := []string{"/proc", "/srv/archive/"}
+	tests := []struct {
+		dir    string
+		denied string
+	}{
+		{"/proc", "/proc"},
+		{"/proc/1/fd", "/proc"},
+		{"/srv/archive/old", "/srv/archive/"},
+		{"/processes", ""},
+		{"/srv", ""},
+	}
+	for _, tt := range tests {
+		denied, ok := deniedDir(tt.dir, deny)
+		if denied != tt.denied || ok != (tt.denied != "") {
+			t.Errorf("deniedDir(%q) = %q, %v; want %q", tt.dir, denied, ok, tt.denied)
+		}
+	}
+
+	if got := deniedBelow("/", defaultScanDeny); strings.Join(got, " ") != "/proc/** /sys/** /dev/**" {
+		t.Errorf("Denied directories below / = %v", got)
+	}
+	if got := deniedBelow("/srv", deny); strings.Join(got, " ") != "/archive/**" {
+		t.Errorf("Denied directories below /srv = %v", got)
+	}
+	if got := deniedBelow("/home", deny); got != nil {
+		t.Errorf("Nothing is denied below /home, got %v", got)
+	}
+
+	// The scan skips them like --scan-exclude patterns
+	if !scanExcluded(deniedBelow("/", defaultScanDeny), "/proc") || scanExcluded(deniedBelow("/", defaultScanDeny), "/home") {
+		t.Errorf("Expected /proc and only /proc to be skipped")
+	}
+}
+
+func TestRiskyRoot(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	if reason := riskyRoot("/", defaultWarnHomeSize); reason != "is the root of the filesystem" {
+		t.Errorf("Expected / to be risky, got %q", reason)
+	}
+	if reason := riskyRoot(filepath.Join(home, "Photos"), 1); reason != "" {
+		t.Errorf("A directory below home should be scanned, got %q", reason)
+	}
+	if reason := riskyRoot(home, 0); reason != "" {
+		t.Errorf("warn-home-size = 0 should turn the question off, got %q", reason)
+	}
+	if _, ok := diskUsed(home); ok {
+		if reason := riskyRoot(home, 1); !strings.HasPrefix(reason, "is your home directory, on a disk using ") {
+			t.Errorf("Expected the home directory to be risky, got %q", reason)
+		}
+	}
+}
+
+func TestConfirmScan(t *testing.T) {
+	for answer, want := range map[string]bool{"y\n": true, "YES\n": true, "n\n": false, "\n": false, "": false} {
+		var out strings.Builder
+		if got := confirmScan("/", "is the root of the filesystem", strings.NewReader(answer), &out); got != want {
+			t.Errorf("Answer %q = %v; want %v", answer, got, want)
+		}
+		if !strings.Contains(out.String(), "Scan it anyway? [y/N]") {
+			t.Errorf("Unexpected question %q", out.String())
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maxScanLogLines is how many finished scans the scan log keeps
+const maxScanLogLines = 200
+
+// defaultScanLogFile returns where the summaries of finished scans are kept
+func defaultScanLogFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "rclone-filter-editor", "scans.log")
+}
+
+// scanLogLine is the line a finished scan is logged with, tab separated:
+// when it finished, what it scanned, how long it took, its summary and
+// the error it failed with
+func scanLogLine(j *job) string {
+	line := strings.Join([]string{j.finished.Format(time.RFC3339), j.title, j.elapsed().String(), j.detail}, "\t")
+	if j.err != nil {
+		line += "\t" + j.err.Error()
+	}
+	return line
+}
+
+// appendScanLog adds line to the scan log at path, dropping the oldest lines
+// beyond maxScanLogLines
+func appendScanLog(path, line string) error {
+	var lines []string
+	file, err := os.Open(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return err
+	default:
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		file.Close()
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	}
+	lines = append(lines, line)
+	lines = lines[max(0, len(lines)-maxScanLogLines):]
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, func(w io.Writer) error {
+		for _, l := range lines {
+			if _, err := fmt.Fprintln(w, l); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// logScan records the summary of a full scan that ran to the end, so that it
+// can be looked up after the status line moved on
+func (m *Model) logScan(j *job) {
+	if m.scanLog == "" || j.kind != JobScan || (j.status != JobDone && j.status != JobFailed) {
+		return
+	}
+	if err := appendScanLog(m.scanLog, scanLogLine(j)); err != nil {
+		j.detail += " (not logged: " + err.Error() + ")"
+	}
+}
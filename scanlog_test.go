@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// failingLister lists the local filesystem, except for directories named fail
+type failingLister struct {
+	fail string
+}
+
+func (l failingLister) List(ctx context.Context, dir string) ([]dirEntry, error) {
+	if filepath.Base(dir) == l.fail {
+		return nil, errors.New("input/output error")
+	}
+	return localLister{}.List(ctx, dir)
+}
+
+func TestScanSummary(t *testing.T) {
+	rootDir := t.TempDir()
+	os.MkdirAll(filepath.Join(rootDir, "a", "broken"), 0755)
+	os.WriteFile(filepath.Join(rootDir, "a", "2.txt"), make([]byte, 2048), 0644)
+	os.WriteFile(filepath.Join(rootDir, "top.txt"), make([]byte, 1024), 0644)
+
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = rootDir
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, _ := newScanTestModel(rootDir)
+	defer model.cancel()
+	model.lister = failingLister{fail: "broken"}
+
+	s := model.newScanner()
+	s.scan(model.root)
+	summary := s.summary()
+	pattern := `^2 directories, 2 files, 3\.0 KiB, 1 error, [\d,]+ dirs/s, [\d,]+ files/s with 2 checkers$`
+	if !regexp.MustCompile(pattern).MatchString(summary) {
+		t.Errorf("Summary = %q; want it to match %s", summary, pattern)
+	}
+}
+
+func TestScanLog(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "config", "scans.log")
+	model := newTestModel()
+	model.scanLog = logFile
+
+	started := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	done := &job{kind: JobScan, title: "Scan data", status: JobDone, detail: "2 directories, 2 files", started: started, finished: started.Add(1500 * time.Millisecond)}
+	model.logScan(done)
+	failed := &job{kind: JobScan, title: "Scan data", status: JobFailed, err: errors.New("permission denied"), started: started, finished: started.Add(time.Second)}
+	model.logScan(failed)
+	// Rescans and cancelled scans aren't logged
+	model.logScan(&job{kind: JobRescan, title: "Rescan a", status: JobDone})
+	model.logScan(&job{kind: JobScan, title: "Scan data", status: JobCancelled})
+
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("Expected the scan log to be written: %v", err)
+	}
+	want := "2024-05-01T12:00:01Z\tScan data\t1.5s\t2 directories, 2 files\n" +
+		"2024-05-01T12:00:01Z\tScan data\t1s\t\tpermission denied\n"
+	if string(data) != want {
+		t.Errorf("Scan log = %q; want %q", data, want)
+	}
+
+	// Only the latest scans are kept
+	for range maxScanLogLines {
+		model.logScan(done)
+	}
+	data, _ = os.ReadFile(logFile)
+	if lines := strings.Count(string(data), "\n"); lines != maxScanLogLines || strings.Contains(string(data), "permission denied") {
+		t.Errorf("Expected the log to keep the last %d scans, got %d lines", maxScanLogLines, lines)
+	}
+}
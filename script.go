@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// scriptCommand is one line of a --script file:
+//
+//	# Comments and blank lines are skipped
+//	expand Photos/2024
+//	exclude Photos/2024/raw
+//	include Photos/2024/raw/keep.dng
+//	clear Documents
+//	select Documents
+//	key space
+//	save
+//
+// Paths are relative to the root, and start with the root's name when the
+// session has several roots.
+type scriptCommand struct {
+	line int
+	name string
+	arg  string
+}
+
+// scriptCommands maps each command to the kind of argument it takes: a path
+// (the root when left out), a key, or nothing
+var scriptCommands = map[string]string{
+	"expand":   "path",
+	"collapse": "path",
+	"select":   "path",
+	"include":  "path",
+	"exclude":  "path",
+	"clear":    "path",
+	"key":      "key",
+	"save":     "",
+}
+
+// scriptKeys are the key names the key command accepts besides single
+// characters
+var scriptKeys = map[string]tea.KeyType{
+	"space":     tea.KeySpace,
+	"enter":     tea.KeyEnter,
+	"esc":       tea.KeyEsc,
+	"up":        tea.KeyUp,
+	"down":      tea.KeyDown,
+	"left":      tea.KeyLeft,
+	"right":     tea.KeyRight,
+	"backspace": tea.KeyBackspace,
+	"tab":       tea.KeyTab,
+}
+
+// readScript parses the commands of a script file, checking all of them
+// before anything runs
+func readScript(path string) ([]scriptCommand, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var commands []scriptCommand
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		name, arg, _ := strings.Cut(text, " ")
+		arg = strings.TrimSpace(arg)
+		kind, ok := scriptCommands[name]
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: unknown command %q", path, line, name)
+		}
+		if kind == "" && arg != "" {
+			return nil, fmt.Errorf("%s:%d: %s takes no argument", path, line, name)
+		}
+		if _, named := scriptKeys[arg]; kind == "key" && !named && len([]rune(arg)) != 1 {
+			return nil, fmt.Errorf("%s:%d: unknown key %q", path, line, arg)
+		}
+		commands = append(commands, scriptCommand{line: line, name: name, arg: arg})
+	}
+	return commands, scanner.Err()
+}
+
+// scanNow scans every root without the event loop, applying the listings
+// the way Update does
+func (m *Model) scanNow() error {
+	var mu sync.Mutex
+	var listings []dirScannedMsg
+	m.send = func(msg tea.Msg) {
+		if batch, ok := msg.(scanBatchMsg); ok {
+			mu.Lock()
+			listings = append(listings, batch.listings...)
+			mu.Unlock()
+		}
+	}
+
+	s := m.newScanner()
+	var err error
+	for _, top := range m.topLevelNodes() {
+		m.progress.emit(progressEvent{Event: "scan_started", Root: top.Path})
+		scanErr := s.scan(top)
+		finished := progressEvent{Event: "scan_finished", Root: top.Path}
+		if scanErr != nil {
+			finished.Error = scanErr.Error()
+			if err == nil {
+				err = fmt.Errorf("%s: %w", top.Path, scanErr)
+			}
+		}
+		m.progress.emit(finished)
+	}
+	for _, listing := range listings {
+		m.applyDirScan(listing)
+	}
+	calculateStats(m.root)
+	m.issues = m.collectIssues()
+	m.updateVisibleNodes()
+	return err
+}
+
+// scriptNode finds the node at a script path
+func (m *Model) scriptNode(p string) (*FileNode, error) {
+	p = strings.Trim(p, "/")
+	node := m.root
+	if m.multiRoot() {
+		// The hidden node above the roots has no filter file of its own,
+		// so every path has to name a root
+		node = nil
+		var names []string
+		for _, top := range m.topLevelNodes() {
+			names = append(names, top.Name)
+		}
+		if p == "" || p == "." {
+			return nil, fmt.Errorf("the path has to start with one of the roots: %s", strings.Join(names, ", "))
+		}
+		for _, top := range m.topLevelNodes() {
+			if rest, ok := strings.CutPrefix(p, top.Name); ok && (rest == "" || rest[0] == '/') {
+				node, p = top, strings.TrimPrefix(rest, "/")
+				break
+			}
+		}
+		if node == nil {
+			return nil, fmt.Errorf("%q is not below any root", p)
+		}
+	}
+
+	if p == "" || p == "." {
+		return node, nil
+	}
+	for _, name := range strings.Split(p, "/") {
+		var next *FileNode
+		for _, child := range node.Children {
+			if child.Name == name {
+				next = child
+				break
+			}
+		}
+		if next == nil {
+			return nil, fmt.Errorf("no such path %q", p)
+		}
+		node = next
+	}
+	return node, nil
+}
+
+// runCommand runs a single script command against the model
+func (m *Model) runCommand(c scriptCommand) error {
+	if c.name == "save" {
+		var err error
+		if m.toStdout && m.filesFrom == nil {
+			if m.multiRoot() {
+				// Only the active root's rules would reach stdout
+				return fmt.Errorf("--stdout can only print the rules of a single root")
+			}
+			err = m.ruleOrder.write(os.Stdout, m.filters)
+		} else {
+			err = m.saveAll()
+		}
+		m.saved = err == nil
+		return err
+	}
+
+	if c.name == "key" {
+		msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(c.arg)}
+		if keyType, ok := scriptKeys[c.arg]; ok {
+			msg = tea.KeyMsg{Type: keyType}
+		}
+		// Commands from the key, such as background jobs, aren't run
+		updated, _ := m.Update(msg)
+		*m = updated.(Model)
+		return nil
+	}
+
+	node, err := m.scriptNode(c.arg)
+	if err != nil {
+		return err
+	}
+	switch c.name {
+	case "expand", "collapse":
+		if !node.IsDir {
+			return fmt.Errorf("%q is not a directory", c.arg)
+		}
+		m.revealNode(node)
+		node.Expanded = c.name == "expand"
+		m.updateVisibleNodes()
+	case "select":
+		m.revealNode(node)
+	case "include":
+		m.setNodeFilter(node, FilterInclude)
+	case "exclude":
+		m.setNodeFilter(node, FilterExclude)
+	case "clear":
+		m.setNodeFilter(node, FilterNone)
+	}
+	m.refreshView()
+	return nil
+}
+
+// runScript scans the tree, replays the commands of a --script file and
+// returns the process exit code
+func runScript(m *Model, path string) int {
+	commands, err := readScript(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading script: %v\n", err)
+		return exitNotSaved
+	}
+	if err := m.scanNow(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error scanning: %v\n", err)
+		return exitScanError
+	}
+
+	for _, c := range commands {
+		if err := m.runCommand(c); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s:%d: %s: %v\n", path, c.line, c.name, err)
+			return exitNotSaved
+		}
+	}
+	return exitCodeFor(m)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadScriptErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"unknown command", "frobnicate a\n"},
+		{"argument to save", "save now\n"},
+		{"unknown key", "key hyper\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "script.txt")
+			os.WriteFile(path, []byte(tt.content), 0644)
+			if _, err := readScript(path); err == nil {
+				t.Errorf("Expected an error for %q", tt.content)
+			}
+		})
+	}
+}
+
+func TestRunScript(t *testing.T) {
+	rootDir := t.TempDir()
+	os.MkdirAll(filepath.Join(rootDir, "photos", "raw"), 0755)
+	os.WriteFile(filepath.Join(rootDir, "photos", "raw", "a.dng"), []byte("a"), 0644)
+	os.WriteFile(filepath.Join(rootDir, "photos", "b.jpg"), []byte("b"), 0644)
+	os.WriteFile(filepath.Join(rootDir, "notes.txt"), []byte("n"), 0644)
+
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = rootDir
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	script := filepath.Join(t.TempDir(), "script.txt")
+	os.WriteFile(script, []byte(`# Keep photos but not the raw files
+include photos
+exclude photos/raw
+
+# The same as Space on notes.txt
+select notes.txt
+key space
+save
+`), 0644)
+
+	model, _ := newScanTestModel(rootDir)
+	defer model.cancel()
+	model.filterFile = filepath.Join(t.TempDir(), "filter.txt")
+
+	if code := runScript(model, script); code != exitSaved {
+		t.Fatalf("Expected exit code %d, got %d", exitSaved, code)
+	}
+	data, err := os.ReadFile(model.filterFile)
+	if err != nil {
+		t.Fatalf("Failed to read saved rules: %v", err)
+	}
+	for _, rule := range []string{"+ photos/**", "- photos/raw/**", "+ notes.txt"} {
+		if !strings.Contains(string(data), rule) {
+			t.Errorf("Expected %q in the saved rules:\n%s", rule, data)
+		}
+	}
+}
+
+func TestRunCommandMultiRoot(t *testing.T) {
+	model := newTestModel()
+	model.roots = []*sessionRoot{
+		{path: "/data", filters: newFilterDocument(nil)},
+		{path: "/media", filters: newFilterDocument(nil)},
+	}
+	model.root = model.newRootNode()
+
+	// The hidden node above the roots can't be given a rule
+	for _, arg := range []string{"", ".", "/"} {
+		err := model.runCommand(scriptCommand{name: "include", arg: arg})
+		if err == nil || !strings.Contains(err.Error(), "data, media") {
+			t.Errorf("include %q should ask for a root, got %v", arg, err)
+		}
+	}
+	if node, err := model.scriptNode("media"); err != nil || node != model.roots[1].node {
+		t.Errorf("A root should be found by its name, got %v", err)
+	}
+
+	// Printing only the active root's rules would lose the others
+	model.toStdout = true
+	if err := model.runCommand(scriptCommand{name: "save"}); err == nil || model.saved {
+		t.Errorf("save --stdout should fail with several roots")
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// The API of --serve drives the same model as the interactive editor over
+// HTTP, for web frontends and scripts. Paths are relative to the root, as in
+// --script files, and start with the root's name when the session has several
+// roots; an empty path is the root itself.
+//
+//	GET  /tree?path=P&depth=N  the node at P, with its children N levels down (default 1)
+//	PUT  /filter?path=P        set the state of P to the "state" of the JSON body
+//	GET  /rules?path=P         the rules of the root holding P, in saved order
+//	POST /save                 write the filter files
+//
+// Errors come back as {"error": "..."} with a matching status code.
+
+// filterStateNames are the states as they appear in the API
+var filterStateNames = map[FilterState]string{
+	FilterNone:    "none",
+	FilterInclude: "include",
+	FilterExclude: "exclude",
+}
+
+// apiNode is a node of the tree as returned by the API
+type apiNode struct {
+	Name     string     `json:"name"`
+	Path     string     `json:"path"`
+	Dir      bool       `json:"dir"`
+	Size     int64      `json:"size"`
+	Files    int        `json:"files,omitempty"`
+	State    string     `json:"state"`
+	Children []*apiNode `json:"children,omitempty"`
+}
+
+// apiRule is a filter rule as returned by the API
+type apiRule struct {
+	Pattern  string `json:"pattern"`
+	State    string `json:"state"`
+	Disabled bool   `json:"disabled,omitempty"`
+	Origin   string `json:"origin,omitempty"` // Included file the rule comes from
+}
+
+// apiServer serves the API. The model isn't safe for concurrent use, so
+// requests take turns.
+type apiServer struct {
+	mu sync.Mutex
+	m  *Model
+}
+
+func (s *apiServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /tree", s.handleTree)
+	mux.HandleFunc("PUT /filter", s.handleFilter)
+	mux.HandleFunc("GET /rules", s.handleRules)
+	mux.HandleFunc("POST /save", s.handleSave)
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// toAPINode converts node and depth levels of children below it
+func toAPINode(node *FileNode, depth int) *apiNode {
+	_, rel := nodeLocation(node)
+	n := &apiNode{
+		Name:  node.Name,
+		Path:  rel,
+		Dir:   node.IsDir,
+		Size:  node.Size,
+		State: filterStateNames[node.Filter],
+	}
+	if node.IsDir {
+		n.Size, n.Files = node.TotalSize, node.TotalFiles
+	}
+	if depth > 0 {
+		for _, child := range node.Children {
+			n.Children = append(n.Children, toAPINode(child, depth-1))
+		}
+	}
+	return n
+}
+
+// lookup finds the node named by the path parameter of r
+func (s *apiServer) lookup(w http.ResponseWriter, r *http.Request) (*FileNode, bool) {
+	node, err := s.m.scriptNode(r.URL.Query().Get("path"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return nil, false
+	}
+	return node, true
+}
+
+func (s *apiServer) handleTree(w http.ResponseWriter, r *http.Request) {
+	depth := 1
+	if v := r.URL.Query().Get("depth"); v != "" {
+		d, err := strconv.Atoi(v)
+		if err != nil || d < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid depth %q", v))
+			return
+		}
+		depth = d
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if node, ok := s.lookup(w, r); ok {
+		writeJSON(w, http.StatusOK, toAPINode(node, depth))
+	}
+}
+
+func (s *apiServer) handleFilter(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		State string `json:"state"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	state, found := FilterNone, false
+	for st, name := range filterStateNames {
+		if name == body.State {
+			state, found = st, true
+		}
+	}
+	if !found {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("state must be none, include or exclude, not %q", body.State))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	node, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	if s.m.isHiddenRoot(node) {
+		writeError(w, http.StatusBadRequest, errors.New("the roots of a session with several roots need a path"))
+		return
+	}
+	s.m.setNodeFilter(node, state)
+	s.m.refreshView()
+	writeJSON(w, http.StatusOK, toAPINode(node, 0))
+}
+
+func (s *apiServer) handleRules(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	node, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	if s.m.filesFrom != nil {
+		writeError(w, http.StatusBadRequest, errors.New("a file list has no rules"))
+		return
+	}
+	if s.m.isHiddenRoot(node) {
+		node = s.m.topLevelNodes()[0]
+	}
+	s.m.activateRootFor(node)
+
+	rules := []apiRule{}
+	for _, rule := range s.m.savedRules() {
+		if rule.Include != "" {
+			continue
+		}
+		rules = append(rules, apiRule{Pattern: rule.Pattern, State: filterStateNames[rule.State], Disabled: rule.Disabled, Origin: rule.Origin})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"file": s.m.filterFile, "rules": rules})
+}
+
+func (s *apiServer) handleSave(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.m.saveAll(); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.m.saved = true
+	writeJSON(w, http.StatusOK, map[string]any{"saved": s.m.filterFileNames()})
+}
+
+// serveAddress is the address --serve listens on. The API can rewrite the
+// filter files and has no authentication, so a bare ":port" listens on the
+// loopback interface only; other interfaces have to be named.
+func serveAddress(addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid --serve address %q, use [host]:port", addr)
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// runServe scans the tree and serves the API on addr until it fails,
+// returning the process exit code
+func runServe(m *Model, addr string) int {
+	if err := m.scanNow(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error scanning: %v\n", err)
+		return exitScanError
+	}
+
+	infof("Serving the API on %s\n", addr)
+	s := &apiServer{m: m}
+	if err := http.ListenAndServe(addr, s.handler()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+	return exitNotSaved
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestServeAPI(t *testing.T) {
+	rootDir := t.TempDir()
+	os.MkdirAll(filepath.Join(rootDir, "cache"), 0755)
+	os.WriteFile(filepath.Join(rootDir, "cache", "tmp.bin"), []byte("xx"), 0644)
+	os.WriteFile(filepath.Join(rootDir, "notes.txt"), []byte("x"), 0644)
+
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = rootDir
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model := newTestModel()
+	model.ctx, model.cancel = context.WithCancel(context.Background())
+	defer model.cancel()
+	model.root = &FileNode{Name: filepath.Base(rootDir), Path: rootDir, IsDir: true, Expanded: true}
+	model.filterFile = filepath.Join(t.TempDir(), "filter.txt")
+	if err := model.scanNow(); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer((&apiServer{m: model}).handler())
+	defer server.Close()
+
+	call := func(method, path, body string, want int, v any) {
+		t.Helper()
+		req, _ := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Fatalf("%s %s: status %d, want %d", method, path, resp.StatusCode, want)
+		}
+		if v != nil {
+			json.NewDecoder(resp.Body).Decode(v)
+		}
+	}
+
+	var tree apiNode
+	call("GET", "/tree", "", http.StatusOK, &tree)
+	if tree.Size != 3 || tree.Files != 2 || len(tree.Children) != 2 || tree.Children[0].Children != nil {
+		t.Errorf("Unexpected tree %+v", tree)
+	}
+
+	var node apiNode
+	call("PUT", "/filter?path=cache", `{"state": "exclude"}`, http.StatusOK, &node)
+	if node.Path != "cache" || node.State != "exclude" {
+		t.Errorf("Expected cache to be excluded, got %+v", node)
+	}
+	call("GET", "/tree?path=cache", "", http.StatusOK, &node)
+	if node.Children[0].State != "exclude" {
+		t.Errorf("Expected the file in cache to be excluded, got %+v", node.Children[0])
+	}
+	call("PUT", "/filter?path=cache", `{"state": "hidden"}`, http.StatusBadRequest, nil)
+	call("GET", "/tree?path=missing", "", http.StatusNotFound, nil)
+
+	var rules struct {
+		Rules []apiRule `json:"rules"`
+	}
+	call("GET", "/rules", "", http.StatusOK, &rules)
+	if len(rules.Rules) != 1 || rules.Rules[0] != (apiRule{Pattern: "cache/**", State: "exclude"}) {
+		t.Errorf("Unexpected rules %+v", rules.Rules)
+	}
+
+	call("POST", "/save", "", http.StatusOK, nil)
+	if data, _ := os.ReadFile(model.filterFile); string(data) != "- cache/**\n" {
+		t.Errorf("Expected the rule to be saved, got %q", data)
+	}
+}
+
+func TestServeAddress(t *testing.T) {
+	for addr, want := range map[string]string{
+		":8080":          "127.0.0.1:8080",
+		"localhost:8080": "localhost:8080",
+		"0.0.0.0:8080":   "0.0.0.0:8080",
+		"[::1]:8080":     "[::1]:8080",
+	} {
+		if got, err := serveAddress(addr); err != nil || got != want {
+			t.Errorf("serveAddress(%q) = %q, %v; want %q", addr, got, err, want)
+		}
+	}
+	if _, err := serveAddress("8080"); err == nil {
+		t.Errorf("Expected a port without the colon to be refused")
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// sftpTarget is a server and directory given to --sftp as
+// "[user@]host[:port]:path", like the targets of scp
+type sftpTarget struct {
+	user string
+	host string
+	port string
+	path string
+}
+
+// parseSFTPTarget parses an --sftp argument. The user defaults to the local
+// one and the port to 22; a relative path starts in the user's home.
+func parseSFTPTarget(spec string) (sftpTarget, error) {
+	t := sftpTarget{port: "22"}
+	rest := spec
+	if u, after, ok := strings.Cut(rest, "@"); ok {
+		t.user, rest = u, after
+	}
+	host, p, ok := strings.Cut(rest, ":")
+	if !ok || host == "" {
+		return t, fmt.Errorf("%q is not user@host:path", spec)
+	}
+	t.host = host
+	if port, after, ok := strings.Cut(p, ":"); ok && port != "" && strings.Trim(port, "0123456789") == "" {
+		t.port, p = port, after
+	}
+	t.path = p
+
+	if t.user == "" {
+		current, err := user.Current()
+		if err != nil {
+			return t, fmt.Errorf("no user in %q: %v", spec, err)
+		}
+		t.user = current.Username
+	}
+	return t, nil
+}
+
+// sftpLister lists directories of a server over SFTP, for machines without
+// rclone. Tree paths keep the --sftp prefix ("user@host:") so that they are
+// handled like those of rclone remotes.
+type sftpLister struct {
+	prefix string
+	client *sftp.Client
+}
+
+func (l *sftpLister) List(ctx context.Context, dir string) ([]dirEntry, error) {
+	p := strings.TrimPrefix(dir, l.prefix)
+	if p == "" {
+		p = "."
+	}
+	infos, err := l.client.ReadDirContext(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]dirEntry, 0, len(infos))
+	for _, info := range infos {
+		e := dirEntry{Name: info.Name(), IsDir: info.IsDir(), ModTime: info.ModTime()}
+		if !info.IsDir() {
+			e.Size = info.Size()
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// dialSFTP connects to the server of an --sftp argument. Keys come from the
+// SSH agent and the usual files in ~/.ssh, and the server must be listed in
+// ~/.ssh/known_hosts.
+func dialSFTP(spec string) (*sftpLister, error) {
+	t, err := parseSFTPTarget(spec)
+	if err != nil {
+		return nil, err
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	hostKeys, err := knownhosts.New(filepath.Join(home, ".ssh", "known_hosts"))
+	if err != nil {
+		return nil, fmt.Errorf("reading known hosts: %v", err)
+	}
+
+	var signers []ssh.Signer
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			if agentSigners, err := agent.NewClient(conn).Signers(); err == nil {
+				signers = append(signers, agentSigners...)
+			}
+		}
+	}
+	for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+		data, err := os.ReadFile(filepath.Join(home, ".ssh", name))
+		if err != nil {
+			continue
+		}
+		// Keys with a passphrase need the agent
+		if signer, err := ssh.ParsePrivateKey(data); err == nil {
+			signers = append(signers, signer)
+		}
+	}
+	if len(signers) == 0 {
+		return nil, fmt.Errorf("no SSH key found, start ssh-agent or add a key to ~/.ssh")
+	}
+
+	conn, err := ssh.Dial("tcp", net.JoinHostPort(t.host, t.port), &ssh.ClientConfig{
+		User:            t.user,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signers...)},
+		HostKeyCallback: hostKeys,
+	})
+	if err != nil {
+		return nil, err
+	}
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &sftpLister{prefix: strings.TrimSuffix(spec, t.path), client: client}, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/pkg/sftp"
+)
+
+func TestParseSFTPTarget(t *testing.T) {
+	tests := []struct {
+		spec string
+		want sftpTarget
+	}{
+		{"me@nas:/volume1", sftpTarget{user: "me", host: "nas", port: "22", path: "/volume1"}},
+		{"me@nas:2222:/volume1", sftpTarget{user: "me", host: "nas", port: "2222", path: "/volume1"}},
+		{"me@nas:photos", sftpTarget{user: "me", host: "nas", port: "22", path: "photos"}},
+		{"me@nas:", sftpTarget{user: "me", host: "nas", port: "22", path: ""}},
+	}
+	for _, tt := range tests {
+		got, err := parseSFTPTarget(tt.spec)
+		if err != nil || got != tt.want {
+			t.Errorf("parseSFTPTarget(%q) = %+v, %v, want %+v", tt.spec, got, err, tt.want)
+		}
+	}
+
+	for _, spec := range []string{"nas", "me@:/data"} {
+		if _, err := parseSFTPTarget(spec); err == nil {
+			t.Errorf("Expected an error for %q", spec)
+		}
+	}
+}
+
+// newPipeSFTPClient returns a client talking to an in-process SFTP server
+// over pipes, serving the local filesystem
+func newPipeSFTPClient(t *testing.T) *sftp.Client {
+	clientRead, serverWrite := io.Pipe()
+	serverRead, clientWrite := io.Pipe()
+	server, err := sftp.NewServer(struct {
+		io.Reader
+		io.WriteCloser
+	}{serverRead, serverWrite})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve()
+
+	client, err := sftp.NewClientPipe(clientRead, clientWrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The client waits for the server to hang up
+	t.Cleanup(func() {
+		serverWrite.Close()
+		client.Close()
+	})
+	return client
+}
+
+func TestSFTPLister(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "photos"), 0755)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("hello"), 0644)
+
+	l := &sftpLister{prefix: "me@nas:", client: newPipeSFTPClient(t)}
+	entries, err := l.List(context.Background(), "me@nas:"+dir)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	if len(entries) != 2 || entries[0].Name != "notes.txt" || entries[0].Size != 5 || !entries[1].IsDir {
+		t.Errorf("Unexpected entries %+v", entries)
+	}
+
+	if _, err := l.List(context.Background(), "me@nas:"+filepath.Join(dir, "missing")); !os.IsNotExist(err) {
+		t.Errorf("Expected a missing directory to be reported as such, got %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// sinceColumn flags the files modified after a reference time given with
+// --since, and counts them in the directories above, so that the rules of an
+// incremental backup can focus on what changed. Only modification times are
+// compared, no file is read.
+type sinceColumn struct {
+	cutoff time.Time
+	label  string // The reference for the header, e.g. "2024-01-01"
+
+	// Counts by directory, worked out again once the tree changes
+	key    sinceKey
+	counts map[*FileNode]int
+}
+
+// sinceKey is the tree the counts were worked out for
+type sinceKey struct {
+	root  *FileNode
+	files int
+}
+
+// sinceLayouts are the dates and times --since takes, in local time
+var sinceLayouts = []string{"2006-01-02", "2006-01-02 15:04", "2006-01-02T15:04:05Z07:00"}
+
+// parseSince parses the reference of --since: a date such as 2024-01-01, a
+// date and time, an age before now such as 30d, or "last save" for the time
+// the filter files were last written
+func parseSince(s string, filterFiles []string, now time.Time) (*sinceColumn, error) {
+	s = strings.TrimSpace(s)
+	if s == "last save" || s == "last-save" {
+		var saved time.Time
+		for _, file := range filterFiles {
+			if file == "" || file == stdioFilterFile {
+				continue
+			}
+			info, err := os.Stat(file)
+			if err != nil {
+				return nil, fmt.Errorf("no saved filter file: %w", err)
+			}
+			// With several roots, the files changed since any of them
+			// was saved
+			if saved.IsZero() || info.ModTime().Before(saved) {
+				saved = info.ModTime()
+			}
+		}
+		if saved.IsZero() {
+			return nil, fmt.Errorf("\"last save\" needs a filter file (-f)")
+		}
+		return &sinceColumn{cutoff: saved, label: "last save (" + saved.Format("2006-01-02 15:04") + ")"}, nil
+	}
+	for _, layout := range sinceLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return &sinceColumn{cutoff: t, label: s}, nil
+		}
+	}
+	if age, err := parseAge(s); err == nil {
+		return &sinceColumn{cutoff: now.Add(-age), label: s + " ago"}, nil
+	}
+	return nil, fmt.Errorf("invalid time %q, use a date such as 2024-01-01, an age such as 30d, or \"last save\"", s)
+}
+
+// modified reports whether a file changed after the cutoff. Files without a
+// modification time are left unflagged.
+func (c *sinceColumn) modified(node *FileNode) bool {
+	return !node.IsDir && node.ModTime.After(c.cutoff)
+}
+
+// sinceCount returns how many files below dir changed after the cutoff,
+// counting the whole tree again once it changed
+func (m *Model) sinceCount(dir *FileNode) int {
+	c := m.since
+	key := sinceKey{root: m.root}
+	for _, top := range m.topLevelNodes() {
+		key.files += top.TotalFiles
+	}
+	if c.counts == nil || c.key != key {
+		c.key = key
+		c.counts = make(map[*FileNode]int)
+		for _, top := range m.topLevelNodes() {
+			for _, node := range collectNodes(top, nil) {
+				if !c.modified(node) {
+					continue
+				}
+				for parent := node.Parent; parent != nil; parent = parent.Parent {
+					c.counts[parent]++
+				}
+			}
+		}
+	}
+	return c.counts[dir]
+}
+
+// sinceLabel is the marker shown after the size of node with --since
+func (m *Model) sinceLabel(node *FileNode) string {
+	if m.since == nil {
+		return ""
+	}
+	if !node.IsDir {
+		if m.since.modified(node) {
+			return " modified"
+		}
+		return ""
+	}
+	if n := m.sinceCount(node); n > 0 {
+		return fmt.Sprintf(" %s modified", formatCount(n))
+	}
+	return ""
+}
+
+// sinceHeader is the part of the header counting the files modified since
+// the reference
+func (m *Model) sinceHeader() string {
+	if m.since == nil || m.root == nil {
+		return ""
+	}
+	total := 0
+	for _, top := range m.topLevelNodes() {
+		total += m.sinceCount(top)
+	}
+	return fmt.Sprintf(" | %s modified since %s", fileCount(total), m.since.label)
+}
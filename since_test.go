@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.Local)
+	for input, want := range map[string]time.Time{
+		"2024-01-01":       time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local),
+		"2024-01-01 08:30": time.Date(2024, 1, 1, 8, 30, 0, 0, time.Local),
+		"30d":              now.AddDate(0, 0, -30),
+	} {
+		if got, err := parseSince(input, nil, now); err != nil || !got.cutoff.Equal(want) {
+			t.Errorf("parseSince(%q) = %v, %v; want %v", input, got, err, want)
+		}
+	}
+
+	filterFile := filepath.Join(t.TempDir(), "filter.txt")
+	saved := time.Date(2025, 6, 1, 9, 0, 0, 0, time.Local)
+	os.WriteFile(filterFile, []byte("- *\n"), 0644)
+	os.Chtimes(filterFile, saved, saved)
+	got, err := parseSince("last save", []string{filterFile}, now)
+	if err != nil || !got.cutoff.Equal(saved) || got.label != "last save (2025-06-01 09:00)" {
+		t.Errorf("Expected the time the filter file was saved, got %+v, %v", got, err)
+	}
+
+	for _, input := range []string{"yesterday", "2024-13-01"} {
+		if _, err := parseSince(input, nil, now); err == nil {
+			t.Errorf("Expected an error for %q", input)
+		}
+	}
+	if _, err := parseSince("last save", []string{""}, now); err == nil {
+		t.Errorf("Expected \"last save\" to need a filter file")
+	}
+}
+
+func TestSinceColumn(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, big, small := newGuardTestModel()
+	cutoff := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, file := range append(big.Children, small.Children...) {
+		file.ModTime = cutoff.AddDate(0, 0, i-7) // 8.bin and 9.bin are newer
+	}
+	model.root.TotalFiles = 10
+	model.since = &sinceColumn{cutoff: cutoff, label: "2024-01-01"}
+	small.Expanded = true
+	model.updateVisibleNodes()
+
+	view := model.View()
+	for _, expected := range []string{
+		"| 2 files modified since 2024-01-01",
+		"small (0 B, 0 files) 2 modified",
+		"9.bin (1.0 KiB) modified",
+	} {
+		if !strings.Contains(view, expected) {
+			t.Errorf("Expected %q in the view:\n%s", expected, view)
+		}
+	}
+	if label := model.sinceLabel(big); label != "" {
+		t.Errorf("Expected nothing flagged in big, got %q", label)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// sizeDeltaDuration is how long the header shows what the last change did to
+// the included total
+const sizeDeltaDuration = 3 * time.Second
+
+// sizeDelta is the change a toggle made to the total size of the included
+// files, shown in the header next to the new total so that each keystroke
+// shows its effect on what will be synced
+type sizeDelta struct {
+	delta int64
+	total int64 // Included size after the change
+	gen   int
+}
+
+// sizeDeltaTimeoutMsg takes the delta down, unless a newer change replaced it
+type sizeDeltaTimeoutMsg struct {
+	gen int
+}
+
+// noteSizeDelta keeps what a change did to the included total, for the
+// header to show until sizeDeltaTimeout takes it down
+func (m *Model) noteSizeDelta(impact toggleImpact) {
+	delta := impact.includedSize - impact.excludedSize
+	if delta == 0 {
+		return
+	}
+	m.sizeDeltaGen++
+	m.sizeDelta = &sizeDelta{delta: delta, total: m.treeTotals().includedSize, gen: m.sizeDeltaGen}
+}
+
+// sizeDeltaTimeout takes the delta down after sizeDeltaDuration
+func (m *Model) sizeDeltaTimeout() tea.Cmd {
+	if m.sizeDelta == nil {
+		return nil
+	}
+	gen := m.sizeDelta.gen
+	return tea.Tick(sizeDeltaDuration, func(time.Time) tea.Msg {
+		return sizeDeltaTimeoutMsg{gen: gen}
+	})
+}
+
+func (m *Model) applySizeDeltaTimeout(msg sizeDeltaTimeoutMsg) {
+	if m.sizeDelta != nil && m.sizeDelta.gen == msg.gen {
+		m.sizeDelta = nil
+	}
+}
+
+// sizeDeltaHeader is the part of the header showing the included total with
+// the last change to it, such as "Included: 1.2 TiB (-48.2 GiB)"
+func (m *Model) sizeDeltaHeader() string {
+	if m.sizeDelta == nil {
+		return ""
+	}
+	return fmt.Sprintf(" | Included: %s (%s)", formatSize(m.sizeDelta.total), formatGrowth(m.sizeDelta.delta))
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestSizeDeltaAfterToggle(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, _, small := newGuardTestModel()
+	model.cursor = model.indexOfVisible(small)
+	space := tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")}
+
+	// Including changes nothing that is synced, excluding takes 2 KiB off
+	updated, cmd := model.Update(space)
+	m := updated.(Model)
+	if m.sizeDelta != nil {
+		t.Errorf("Expected no delta for a toggle that leaves the total alone, got %+v", m.sizeDelta)
+	}
+	updated, cmd = m.Update(space)
+	m = updated.(Model)
+	if !strings.Contains(m.View(), "quit | Included: 8.0 KiB (-2.0 KiB) | Sort") {
+		t.Errorf("Expected the delta next to the included total:\n%s", m.View())
+	}
+	if cmd == nil {
+		t.Fatalf("Expected a command taking the delta down")
+	}
+	first := m.sizeDelta.gen
+
+	// A newer change outlives the timeout of the one before
+	updated, _ = m.Update(space)
+	m = updated.(Model)
+	updated, _ = m.Update(sizeDeltaTimeoutMsg{gen: first})
+	m = updated.(Model)
+	if m.sizeDelta == nil || !strings.Contains(m.View(), "Included: 10.0 KiB (+2.0 KiB)") {
+		t.Fatalf("Expected the newer delta to stay:\n%s", m.View())
+	}
+	updated, _ = m.Update(sizeDeltaTimeoutMsg{gen: m.sizeDelta.gen})
+	if m = updated.(Model); strings.Contains(m.View(), "Included:") {
+		t.Errorf("Expected the delta gone after its time")
+	}
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// snapshot records the size and file count of every directory of a scan, so
+// that a later scan can show which directories grew since
+type snapshot struct {
+	Name  string         `json:"name"`
+	Taken time.Time      `json:"taken"`
+	Roots []snapshotRoot `json:"roots"`
+}
+
+// snapshotRoot holds the directories of one root, keyed by their path
+// relative to the root ("" for the root itself)
+type snapshotRoot struct {
+	Path string                 `json:"path"`
+	Dirs map[string]snapshotDir `json:"dirs"`
+}
+
+type snapshotDir struct {
+	Size  int64 `json:"size"`
+	Files int   `json:"files"`
+}
+
+// defaultSnapshotName names snapshots after the day they were taken
+func defaultSnapshotName() string {
+	return time.Now().Format("2006-01-02")
+}
+
+// snapshotFile returns the file a named snapshot is stored in
+func snapshotFile(dir, name string) (string, error) {
+	if dir == "" {
+		return "", fmt.Errorf("no snapshot directory, set snapshot-dir in the config file")
+	}
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("invalid snapshot name %q", name)
+	}
+	return filepath.Join(dir, name+".json"), nil
+}
+
+// saveSnapshot writes snap to the snapshot directory, replacing an older
+// snapshot of the same name
+func saveSnapshot(dir string, snap *snapshot) error {
+	filename, err := snapshotFile(dir, snap.Name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(data, '\n'), 0o644)
+}
+
+// loadSnapshot reads a named snapshot from the snapshot directory
+func loadSnapshot(dir, name string) (*snapshot, error) {
+	filename, err := snapshotFile(dir, name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return &snap, nil
+}
+
+// nodeLocation returns the top-level node a node belongs to and its path
+// relative to it
+func nodeLocation(node *FileNode) (*FileNode, string) {
+	var names []string
+	for node.Parent != nil {
+		names = append(names, node.Name)
+		node = node.Parent
+	}
+	slices.Reverse(names)
+	return node, strings.Join(names, "/")
+}
+
+// takeSnapshot records the directories of the current tree
+func (m *Model) takeSnapshot(name string) *snapshot {
+	snap := &snapshot{Name: name, Taken: time.Now()}
+	for _, top := range m.topLevelNodes() {
+		root := snapshotRoot{Path: top.Path, Dirs: make(map[string]snapshotDir)}
+		for _, node := range collectNodes(top, nil) {
+			if !node.IsDir {
+				continue
+			}
+			_, rel := nodeLocation(node)
+			root.Dirs[rel] = snapshotDir{Size: node.TotalSize, Files: node.TotalFiles}
+		}
+		snap.Roots = append(snap.Roots, root)
+	}
+	return snap
+}
+
+// snapshotEntry looks up a directory in the snapshot being compared against.
+// ok is false when there is no snapshot or it doesn't cover the directory's
+// root; directories created since the snapshot are reported as isNew.
+func (m *Model) snapshotEntry(node *FileNode) (entry snapshotDir, isNew, ok bool) {
+	if m.compareTo == nil || !node.IsDir {
+		return snapshotDir{}, false, false
+	}
+	top, rel := nodeLocation(node)
+	for _, root := range m.compareTo.Roots {
+		if root.Path == top.Path {
+			entry, found := root.Dirs[rel]
+			return entry, !found, true
+		}
+	}
+	return snapshotDir{}, false, false
+}
+
+// formatGrowth formats a change in size with its sign
+func formatGrowth(delta int64) string {
+	if delta < 0 {
+		return "-" + formatSize(-delta)
+	}
+	return "+" + formatSize(delta)
+}
+
+// growthLabel describes how a directory changed since the snapshot, or
+// returns "" when it didn't or isn't covered by the snapshot
+func (m *Model) growthLabel(node *FileNode) string {
+	entry, isNew, ok := m.snapshotEntry(node)
+	switch {
+	case !ok:
+		return ""
+	case isNew:
+		return "new"
+	case node.TotalSize == entry.Size:
+		return ""
+	}
+	return formatGrowth(node.TotalSize - entry.Size)
+}
+
+// dirGrowth is a directory that changed size since the snapshot
+type dirGrowth struct {
+	node   *FileNode
+	before snapshotDir
+	isNew  bool
+	delta  int64
+}
+
+// growthList returns the directories that changed since the snapshot, the
+// ones that grew most first
+func (m *Model) growthList() []dirGrowth {
+	var list []dirGrowth
+	for _, top := range m.topLevelNodes() {
+		for _, node := range collectNodes(top, nil) {
+			entry, isNew, ok := m.snapshotEntry(node)
+			if !ok || node.TotalSize == entry.Size {
+				continue
+			}
+			list = append(list, dirGrowth{
+				node:   node,
+				before: entry,
+				isNew:  isNew,
+				delta:  node.TotalSize - entry.Size,
+			})
+		}
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].delta != list[j].delta {
+			return list[i].delta > list[j].delta
+		}
+		return list[i].node.Path < list[j].node.Path
+	})
+	return list
+}
+
+// saveCurrentSnapshot stores the current tree under the session's snapshot
+// name
+func (m *Model) saveCurrentSnapshot() {
+	snap := m.takeSnapshot(m.snapshotName)
+	if err := saveSnapshot(m.snapshotDir, snap); err != nil {
+		m.statusMsg = fmt.Sprintf("Error saving snapshot: %v", err)
+		return
+	}
+	var dirs int
+	for _, root := range snap.Roots {
+		dirs += len(root.Dirs)
+	}
+	m.statusMsg = fmt.Sprintf("Saved snapshot %s (%s directories)", snap.Name, formatCount(dirs))
+}
+
+// updateGrowthPane handles keys while the growth pane is open
+func (m Model) updateGrowthPane(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	list := m.growthList()
+	var selected *FileNode
+	if m.growthCursor >= 0 && m.growthCursor < len(list) {
+		selected = list[m.growthCursor].node
+	}
+
+	switch msg.String() {
+	case "up", "k":
+		if m.growthCursor > 0 {
+			m.growthCursor--
+		}
+	case "down", "j":
+		if m.growthCursor < len(list)-1 {
+			m.growthCursor++
+		}
+	case "e":
+		if selected != nil && selected.Filter != FilterExclude {
+			m.activateRootFor(selected)
+			if blocked := m.toggleBlocked(m.previewChange(selected, FilterExclude)); blocked != "" {
+				m.statusMsg = blocked
+				break
+			}
+			m.setNodeFilter(selected, FilterExclude)
+			m.refreshView()
+		}
+	case "enter":
+		if selected != nil {
+			m.closeModal(modalGrowth)
+			m.revealNode(selected)
+		}
+	case "C", "esc", "q":
+		m.closeModal(modalGrowth)
+	case "ctrl+c":
+		m.cancel()
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m Model) renderGrowth() string {
+	paneStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(currentTheme.Border).
+		Padding(1, 2)
+
+	list := m.growthList()
+	var lines []string
+	for i, g := range list {
+		glyph, style := stateStyle(g.node.Filter)
+
+		// Name the root for its own row, and for every row with several roots
+		top, rel := nodeLocation(g.node)
+		if rel == "" || m.multiRoot() {
+			rel = strings.TrimSuffix(top.Name+"/"+rel, "/")
+		}
+		change := fmt.Sprintf("%s, %+d files", formatGrowth(g.delta), g.node.TotalFiles-g.before.Files)
+		if g.isNew {
+			change = fmt.Sprintf("new, %s in %d files", formatSize(g.node.TotalSize), g.node.TotalFiles)
+		}
+		line := fmt.Sprintf("%s %s  %s", style.Render(glyph), rel, change)
+		if i == m.growthCursor {
+			line = lipgloss.NewStyle().Background(currentTheme.CursorBg).Foreground(currentTheme.CursorFg).Render(line)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "No directory changed size")
+	}
+
+	// Keep the selected directory in view when the list is taller than the screen
+	visibleHeight := m.height - 10
+	if visibleHeight < 5 {
+		visibleHeight = 20
+	}
+	start := 0
+	if m.growthCursor >= visibleHeight {
+		start = m.growthCursor - visibleHeight + 1
+	}
+	end := start + visibleHeight
+	if end > len(lines) {
+		end = len(lines)
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Changes since snapshot %s (%s):\n\n", m.compareTo.Name, m.compareTo.Taken.Format("2006-01-02 15:04")))
+	b.WriteString(strings.Join(lines[start:end], "\n"))
+	b.WriteString("\n\n↑/↓ select, e exclude directory, Enter show in tree, C or Esc close")
+
+	return m.placePane(paneStyle, b.String())
+}
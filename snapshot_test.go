@@ -0,0 +1,99 @@
+package main
+
+import (
+	"testing"
+)
+
+// newSnapshotTestModel returns a model over /root with files in "photos" and
+// "docs"
+func newSnapshotTestModel() (*Model, *FileNode, *FileNode) {
+	root := &FileNode{Name: "root", Path: "/root", IsDir: true, Expanded: true}
+	photos := &FileNode{Name: "photos", Path: "/root/photos", IsDir: true, Parent: root}
+	docs := &FileNode{Name: "docs", Path: "/root/docs", IsDir: true, Parent: root}
+	photos.Children = []*FileNode{{Name: "a.jpg", Path: "/root/photos/a.jpg", Size: 1000, Parent: photos}}
+	docs.Children = []*FileNode{{Name: "b.txt", Path: "/root/docs/b.txt", Size: 10, Parent: docs}}
+	root.Children = []*FileNode{docs, photos}
+	calculateStats(root)
+
+	model := newTestModel()
+	model.root = root
+	model.updateVisibleNodes()
+	return model, photos, docs
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	model, _, _ := newSnapshotTestModel()
+
+	if err := saveSnapshot(dir, model.takeSnapshot("last-month")); err != nil {
+		t.Fatalf("Failed to save snapshot: %v", err)
+	}
+	snap, err := loadSnapshot(dir, "last-month")
+	if err != nil {
+		t.Fatalf("Failed to load snapshot: %v", err)
+	}
+
+	if snap.Name != "last-month" || len(snap.Roots) != 1 || snap.Roots[0].Path != "/root" {
+		t.Fatalf("Unexpected snapshot %+v", snap)
+	}
+	dirs := snap.Roots[0].Dirs
+	if dirs[""] != (snapshotDir{Size: 1010, Files: 2}) || dirs["photos"] != (snapshotDir{Size: 1000, Files: 1}) {
+		t.Errorf("Unexpected directories %+v", dirs)
+	}
+
+	for _, name := range []string{"", "../escape", "a/b", ".hidden"} {
+		if _, err := snapshotFile(dir, name); err == nil {
+			t.Errorf("Expected snapshot name %q to be rejected", name)
+		}
+	}
+	if _, err := loadSnapshot(dir, "missing"); err == nil {
+		t.Errorf("Expected an error for a missing snapshot")
+	}
+}
+
+func TestGrowthSinceSnapshot(t *testing.T) {
+	model, photos, docs := newSnapshotTestModel()
+	model.compareTo = model.takeSnapshot("before")
+
+	// Photos grows, docs shrinks and a new directory appears
+	photos.Children = append(photos.Children, &FileNode{Name: "b.jpg", Path: "/root/photos/b.jpg", Size: 5000, Parent: photos})
+	docs.Children = nil
+	videos := &FileNode{Name: "videos", Path: "/root/videos", IsDir: true, Parent: model.root}
+	videos.Children = []*FileNode{{Name: "c.mp4", Path: "/root/videos/c.mp4", Size: 300, Parent: videos}}
+	model.root.Children = append(model.root.Children, videos)
+	calculateStats(model.root)
+
+	if got := model.growthLabel(photos); got != "+4.9 KiB" {
+		t.Errorf("Expected photos to show its growth, got %q", got)
+	}
+	if got := model.growthLabel(videos); got != "new" {
+		t.Errorf("Expected videos to show as new, got %q", got)
+	}
+	if got := model.growthLabel(photos.Children[0]); got != "" {
+		t.Errorf("Files should have no growth label, got %q", got)
+	}
+
+	list := model.growthList()
+	var order []string
+	for _, g := range list {
+		order = append(order, g.node.Name)
+	}
+	want := []string{"root", "photos", "videos", "docs"}
+	if len(order) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, order)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("Expected %v, got %v", want, order)
+		}
+	}
+	if list[3].delta != -10 || list[1].before.Files != 1 {
+		t.Errorf("Unexpected growth entries %+v", list)
+	}
+
+	// Snapshots of other roots don't apply
+	model.compareTo.Roots[0].Path = "/elsewhere"
+	if got := model.growthLabel(photos); got != "" || len(model.growthList()) != 0 {
+		t.Errorf("Expected no comparison against another root, got %q", got)
+	}
+}
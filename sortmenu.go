@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// sortChoice is a sort mode as the sort menu and the header show it
+type sortChoice struct {
+	mode       SortMode
+	name       string
+	descending bool // The mode's own direction, before sortReverse
+}
+
+// sortChoices are the rows of the sort menu, in the order of SortMode
+var sortChoices = []sortChoice{
+	{SortByName, "Name", false},
+	{SortBySize, "Size", true},
+	{SortByFileCount, "File Count", true},
+	{SortByLastModified, "Last Modified", true},
+}
+
+// sortArrow is ↑ for ascending and ↓ for descending order
+func sortArrow(descending bool) string {
+	if descending {
+		return "↓"
+	}
+	return "↑"
+}
+
+// sortLabel names the sort in use with its direction, such as "Size ↓"
+func (m *Model) sortLabel() string {
+	for _, c := range sortChoices {
+		if c.mode == m.sortMode {
+			return c.name + " " + sortArrow(c.descending != m.sortReverse)
+		}
+	}
+	return ""
+}
+
+// setSortReverse sorts the tree against the direction of its sort mode, or
+// back along it
+func (m *Model) setSortReverse(reverse bool) {
+	m.sortReverse = reverse
+	if m.root != nil {
+		m.resortTree(m.root)
+		m.updateVisibleNodes()
+	}
+}
+
+// setMixedSort lists directories and files together, or directories first
+func (m *Model) setMixedSort(mixed bool) {
+	m.mixedSort = mixed
+	if m.root != nil {
+		m.resortTree(m.root)
+		m.updateVisibleNodes()
+	}
+}
+
+// openSortMenu shows the sort modes, starting on the one in use
+func (m *Model) openSortMenu() {
+	m.sortCursor = int(m.sortMode)
+	m.openModal(modalSortMenu)
+}
+
+// updateSortMenu handles keys while the sort menu is open. The row after the
+// sort modes switches between directories first and mixed listing.
+func (m Model) updateSortMenu(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		if m.sortCursor > 0 {
+			m.sortCursor--
+		}
+	case "down", "j":
+		if m.sortCursor < len(sortChoices) {
+			m.sortCursor++
+		}
+	case "enter", " ":
+		if m.sortCursor == len(sortChoices) {
+			m.setMixedSort(!m.mixedSort)
+			break
+		}
+		if mode := sortChoices[m.sortCursor].mode; mode == m.sortMode {
+			// Choosing the sort in use turns it around
+			m.setSortReverse(!m.sortReverse)
+		} else {
+			m.sortReverse = false
+			m.setSortMode(mode)
+		}
+		m.closeModal(modalSortMenu)
+	case "r", "left", "right":
+		m.setSortReverse(!m.sortReverse)
+	case "esc", "q", "o":
+		m.closeModal(modalSortMenu)
+	case "ctrl+c":
+		m.cancel()
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m Model) renderSortMenu() string {
+	paneStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(currentTheme.Border).
+		Padding(1, 2)
+
+	var rows []string
+	for _, c := range sortChoices {
+		marker := "  "
+		descending := c.descending
+		if c.mode == m.sortMode {
+			marker = "● "
+			descending = descending != m.sortReverse
+		}
+		rows = append(rows, fmt.Sprintf("%s%-14s %s", marker, c.name, sortArrow(descending)))
+	}
+	grouping := "Directories first"
+	if m.mixedSort {
+		grouping = "Directories mixed with files"
+	}
+	rows = append(rows, "  "+grouping)
+
+	var b strings.Builder
+	b.WriteString("Sort by:\n\n")
+	for i, row := range rows {
+		style := lipgloss.NewStyle()
+		if i == m.sortCursor {
+			style = style.Background(currentTheme.CursorBg).Foreground(currentTheme.CursorFg)
+		}
+		if i == len(sortChoices) {
+			b.WriteString("\n")
+		}
+		b.WriteString(style.Render(row) + "\n")
+	}
+	b.WriteString("\n↑/↓ select, Enter sort (again to reverse), r reverse, Esc close")
+
+	return m.placePane(paneStyle, b.String())
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func newSortTestModel() Model {
+	root := &FileNode{Name: "root", Path: "/root", IsDir: true, Expanded: true}
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, name := range []string{"b", "a", "c"} {
+		root.Children = append(root.Children, &FileNode{
+			Name: name, Path: "/root/" + name, Parent: root,
+			Size: int64(100 * (i + 1)), ModTime: base.AddDate(0, 0, i),
+		})
+	}
+	model := newTestModel()
+	model.root = root
+	model.resortTree(root)
+	model.updateVisibleNodes()
+	return *model
+}
+
+func childNames(node *FileNode) string {
+	var names []string
+	for _, child := range node.Children {
+		names = append(names, child.Name)
+	}
+	return strings.Join(names, "")
+}
+
+func TestSortMenuChoosesMode(t *testing.T) {
+	enter := tea.KeyMsg{Type: tea.KeyEnter}
+	model := pressKeys(newSortTestModel(), runeKey("o"))
+	if model.topModal() != modalSortMenu || model.sortCursor != 0 {
+		t.Fatalf("Expected o to open the sort menu on the sort in use")
+	}
+
+	model = pressKeys(model, runeKey("j"), enter)
+	if model.sortMode != SortBySize || model.topModal() == modalSortMenu {
+		t.Fatalf("Expected Enter on the second row to sort by size and close the menu")
+	}
+	if got := childNames(model.root); got != "cab" {
+		t.Errorf("Expected the largest first, got %s", got)
+	}
+	if !strings.Contains(model.View(), "Sort: Size ↓ (o)") {
+		t.Errorf("Expected the header to show the sort and its direction")
+	}
+
+	// Choosing the sort in use again reverses it
+	model = pressKeys(model, runeKey("o"), enter)
+	if !model.sortReverse || childNames(model.root) != "bac" {
+		t.Errorf("Expected the smallest first after choosing size again, got %s", childNames(model.root))
+	}
+	if !strings.Contains(model.View(), "Sort: Size ↑ (o)") {
+		t.Errorf("Expected the header to show the reversed direction")
+	}
+
+	// Another mode starts in its own direction
+	model = pressKeys(model, runeKey("o"), runeKey("k"), enter)
+	if model.sortMode != SortByName || model.sortReverse || childNames(model.root) != "abc" {
+		t.Errorf("Expected name order a to z, got %s", childNames(model.root))
+	}
+}
+
+func TestSortMenuReverseAndGrouping(t *testing.T) {
+	root := &FileNode{Name: "root", Path: "/root", IsDir: true, Expanded: true}
+	root.Children = []*FileNode{
+		{Name: "a", Path: "/root/a", Parent: root},
+		{Name: "b", Path: "/root/b", IsDir: true, Parent: root},
+	}
+	model := newSortTestModel()
+	model.root = root
+
+	// r reverses without closing, directories stay first
+	model = pressKeys(model, runeKey("o"), runeKey("r"))
+	if model.topModal() != modalSortMenu || !model.sortReverse || childNames(root) != "ba" {
+		t.Fatalf("Expected r to reverse the names and keep the directory first, got %s", childNames(root))
+	}
+	model = pressKeys(model, runeKey("r"))
+	if model.sortReverse {
+		t.Errorf("Expected r again to restore the direction")
+	}
+
+	// The last row toggles mixing directories with files
+	model = pressKeys(model, runeKey("j"), runeKey("j"), runeKey("j"), runeKey("j"), runeKey("j"), tea.KeyMsg{Type: tea.KeyEnter})
+	if model.sortCursor != len(sortChoices) || !model.mixedSort || childNames(root) != "ab" {
+		t.Errorf("Expected the grouping row to mix directories with files, got %s", childNames(root))
+	}
+	if model.topModal() != modalSortMenu {
+		t.Errorf("Expected the menu to stay open after changing the grouping")
+	}
+	if !strings.Contains(model.renderSortMenu(), "Directories mixed with files") {
+		t.Errorf("Expected the menu to show the grouping in use")
+	}
+	model = pressKeys(model, tea.KeyMsg{Type: tea.KeyEsc})
+	if model.topModal() == modalSortMenu {
+		t.Errorf("Expected Esc to close the menu")
+	}
+}
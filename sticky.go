@@ -0,0 +1,50 @@
+package main
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// stickyParents returns the directories the top row of the tree is inside,
+// outermost first. They stay pinned above the tree while it scrolls, so
+// that deep in a subtree it is clear where the rows are. Up to a third of
+// the screen goes to them, keeping the innermost when there are more.
+func (m *Model) stickyParents() []*FileNode {
+	if m.scrollOffset <= 0 || m.scrollOffset >= len(m.visibleNodes) {
+		return nil
+	}
+	var parents []*FileNode
+	for p := m.visibleNodes[m.scrollOffset].Parent; p != nil; p = p.Parent {
+		// Every shown row has its parents shown above it
+		if !m.isHiddenRoot(p) {
+			parents = append(parents, p)
+		}
+	}
+	slices.Reverse(parents)
+	if limit := m.screenRows() / 3; len(parents) > limit {
+		parents = parents[len(parents)-limit:]
+	}
+	return parents
+}
+
+// renderSticky draws the pinned parents, indented like their rows and
+// without their stats
+func (m *Model) renderSticky(parents []*FileNode) string {
+	style := lipgloss.NewStyle().Foreground(currentTheme.Header).Bold(true)
+	var guides *treeGuides
+	if m.showGuides {
+		guides = m.newTreeGuides()
+	}
+	var b strings.Builder
+	for _, node := range parents {
+		prefix := strings.Repeat("  ", getNodeDepth(node))
+		if guides != nil {
+			prefix = guides.prefix(node)
+		}
+		b.WriteString(style.Render(prefix + "▼ " + node.Name))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestStickyParents(t *testing.T) {
+	root := &FileNode{Name: "root", Path: "/root", IsDir: true, Expanded: true}
+	photos := &FileNode{Name: "Photos", Path: "/root/Photos", IsDir: true, Expanded: true, Parent: root}
+	album := &FileNode{Name: "2024", Path: "/root/Photos/2024", IsDir: true, Expanded: true, Parent: photos}
+	for i := range 30 {
+		name := fmt.Sprintf("img%02d.jpg", i)
+		album.Children = append(album.Children, &FileNode{Name: name, Path: album.Path + "/" + name, Parent: album})
+	}
+	photos.Children = []*FileNode{album}
+	root.Children = []*FileNode{photos}
+
+	model := newTestModel()
+	model.root = root
+	model.width, model.height = 80, 14 // 10 rows below the header
+	model.updateVisibleNodes()
+
+	if parents := model.stickyParents(); parents != nil {
+		t.Errorf("Nothing should be pinned at the top of the tree, got %d rows", len(parents))
+	}
+
+	// Deep in the album its parents stay on screen above the rows
+	m := pressKeys(*model, runeKey("2"), runeKey("0"), runeKey("j"))
+	parents := m.stickyParents()
+	if len(parents) != 3 || parents[0] != root || parents[2] != album {
+		t.Fatalf("Expected root, Photos and 2024 to be pinned, got %d rows", len(parents))
+	}
+	if m.cursor >= m.scrollOffset+m.listHeight() || m.listHeight() != 7 {
+		t.Errorf("Expected the cursor in view below the pinned rows, offset %d, %d rows", m.scrollOffset, m.listHeight())
+	}
+	view := m.View()
+	if !strings.Contains(view, "    ▼ 2024\n") || !strings.Contains(view, "img17.jpg") {
+		t.Errorf("Expected the pinned parents above the rows:\n%s", view)
+	}
+	if lines := strings.Count(view, "\n"); lines > m.height {
+		t.Errorf("Expected the view to fit %d rows, got %d", m.height, lines)
+	}
+
+	// At most a third of the rows go to parents, the innermost are kept
+	m.height = 8
+	if parents := m.stickyParents(); len(parents) != 1 || parents[0] != album {
+		t.Errorf("Expected only 2024 to be pinned on a small screen, got %d rows", len(parents))
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// minSuggestedRules is the number of rules a wildcard pattern has to stand
+// for before it is suggested
+const minSuggestedRules = 3
+
+// patternSuggestion is a wildcard rule that can replace several rules made
+// for single paths, such as "Shows/*/Extras/**" for the Extras of every show
+type patternSuggestion struct {
+	top      *FileNode
+	pattern  string
+	state    FilterState
+	replaces []string // Rules the pattern stands for, sorted
+	accepted bool
+}
+
+// wildcardCandidates groups the literal rules of rules that differ in a
+// single path segment by the pattern with a "*" in that segment
+func wildcardCandidates(rules map[string]FilterState) map[string][]string {
+	groups := make(map[string][]string)
+	for pattern, state := range rules {
+		anchor, ok := literalAnchor(pattern)
+		if !ok {
+			continue
+		}
+		segments := strings.Split(strings.TrimPrefix(anchor, "/"), "/")
+		for i := range segments {
+			escaped := make([]string, len(segments))
+			for j, s := range segments {
+				escaped[j] = escapeGlob(s)
+			}
+			escaped[i] = "*"
+			wildcard := strings.Join(escaped, "/")
+			if strings.HasSuffix(pattern, "/**") {
+				wildcard += "/**"
+			}
+			// Rules of the other sign can't share a pattern
+			key := FilterRule{Pattern: wildcard, State: state}.String()
+			groups[key] = append(groups[key], pattern)
+		}
+	}
+	return groups
+}
+
+// findPatternSuggestions lists wildcard patterns that can replace at least
+// minSuggestedRules rules without changing the state of anything in the
+// tree. A rule is only offered in the largest group it belongs to.
+func (m *Model) findPatternSuggestions() []patternSuggestion {
+	if m.filesFrom != nil || m.root == nil {
+		return nil
+	}
+	var suggestions []patternSuggestion
+	for _, top := range m.topLevelNodes() {
+		m.activateRootFor(top)
+		nodes := collectNodes(top, nil)
+		work := m.filters.clone()
+
+		groups := wildcardCandidates(work.states())
+		keys := slices.SortedFunc(maps.Keys(groups), func(a, b string) int {
+			return cmp.Or(cmp.Compare(len(groups[b]), len(groups[a])), strings.Compare(a, b))
+		})
+		used := make(map[string]bool)
+		for _, key := range keys {
+			replaces := slices.DeleteFunc(slices.Clone(groups[key]), func(p string) bool { return used[p] })
+			if len(replaces) < minSuggestedRules {
+				continue
+			}
+			slices.Sort(replaces)
+			s := patternSuggestion{top: top, pattern: key[2:], state: work.state(replaces[0]), replaces: replaces}
+			if _, exists := work.lookup(s.pattern); exists {
+				continue
+			}
+			if !m.sameStatesWith(work, s, nodes) {
+				continue
+			}
+			for _, p := range replaces {
+				used[p] = true
+				work.remove(p)
+			}
+			work.set(s.pattern, s.state)
+			suggestions = append(suggestions, s)
+		}
+	}
+	return suggestions
+}
+
+// sameStatesWith reports whether replacing the rules of s in rules by its
+// pattern leaves every node with the same state
+func (m *Model) sameStatesWith(rules *FilterDocument, s patternSuggestion, nodes []*FileNode) bool {
+	with := rules.clone()
+	for _, p := range s.replaces {
+		with.remove(p)
+	}
+	with.set(s.pattern, s.state)
+	for _, node := range nodes[1:] {
+		path := getFilterPath(node.Path)
+		before, beforeState := rules.firstMatch(path)
+		after, afterState := with.firstMatch(path)
+		if (before == "") != (after == "") || beforeState != afterState {
+			return false
+		}
+	}
+	return true
+}
+
+// openSuggestions shows the wildcard patterns that could replace rules, if
+// there are any
+func (m *Model) openSuggestions() {
+	m.suggestions = m.findPatternSuggestions()
+	m.suggestionCursor = 0
+	if len(m.suggestions) == 0 {
+		m.statusMsg = "No rules to consolidate into wildcard patterns"
+		return
+	}
+	m.openModal(modalSuggest)
+}
+
+// applySuggestions replaces the rules of the accepted suggestions by their
+// patterns, checking each again against the rules as they are by then, and
+// returns the number of suggestions applied
+func (m *Model) applySuggestions() int {
+	applied := 0
+	for _, s := range m.suggestions {
+		if !s.accepted {
+			continue
+		}
+		m.activateRootFor(s.top)
+		if !m.sameStatesWith(m.filters, s, collectNodes(s.top, nil)) {
+			continue
+		}
+		for _, p := range s.replaces {
+			m.deleteRule(p)
+		}
+		m.setRule(s.pattern, s.state)
+		applied++
+		m.reapplyFiltersToTree(s.top)
+	}
+	m.updateVisibleNodes()
+	return applied
+}
+
+// updateSuggestPane handles keys while the suggested patterns are reviewed
+func (m Model) updateSuggestPane(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		if m.suggestionCursor > 0 {
+			m.suggestionCursor--
+		}
+	case "down", "j":
+		if m.suggestionCursor < len(m.suggestions)-1 {
+			m.suggestionCursor++
+		}
+	case " ":
+		if m.suggestionCursor < len(m.suggestions) {
+			m.suggestions[m.suggestionCursor].accepted = !m.suggestions[m.suggestionCursor].accepted
+		}
+	case "a":
+		all := !slices.ContainsFunc(m.suggestions, func(s patternSuggestion) bool { return !s.accepted })
+		for i := range m.suggestions {
+			m.suggestions[i].accepted = !all
+		}
+	case "enter":
+		m.closeModal(modalSuggest)
+		var count int
+		if !m.guardChange(func() { count = m.applySuggestions() }) {
+			m.suggestions = nil
+			return m, nil
+		}
+		if count == 1 {
+			m.statusMsg = "Replaced rules with 1 wildcard pattern"
+		} else {
+			m.statusMsg = fmt.Sprintf("Replaced rules with %d wildcard patterns", count)
+		}
+		m.suggestions = nil
+	case "esc", "q", "S":
+		m.closeModal(modalSuggest)
+		m.suggestions = nil
+	case "ctrl+c":
+		m.cancel()
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m Model) renderSuggest() string {
+	paneStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(currentTheme.Border).
+		Padding(1, 2)
+
+	var lines []string
+	for i, s := range m.suggestions {
+		mark := "[ ]"
+		if s.accepted {
+			mark = "[x]"
+		}
+		line := fmt.Sprintf("%s %s  (replaces %d rules)", mark, FilterRule{Pattern: s.pattern, State: s.state}, len(s.replaces))
+		if m.multiRoot() {
+			line += "  in " + s.top.Name
+		}
+		if i == m.suggestionCursor {
+			line = lipgloss.NewStyle().Background(currentTheme.CursorBg).Foreground(currentTheme.CursorFg).Render(line)
+		}
+		lines = append(lines, line)
+	}
+
+	// Show the rules of the selected suggestion below the list
+	var detail []string
+	if m.suggestionCursor < len(m.suggestions) {
+		muted := lipgloss.NewStyle().Foreground(currentTheme.Muted)
+		s := m.suggestions[m.suggestionCursor]
+		for _, p := range s.replaces {
+			detail = append(detail, muted.Render("  "+FilterRule{Pattern: p, State: s.state}.String()))
+		}
+	}
+	visibleHeight := m.height - 14 - len(lines)
+	if visibleHeight < 3 {
+		visibleHeight = 10
+	}
+	if len(detail) > visibleHeight {
+		detail = append(detail[:visibleHeight-1], fmt.Sprintf("  ... and %d more", len(detail)-visibleHeight+1))
+	}
+
+	var b strings.Builder
+	b.WriteString("Wildcard patterns that could replace rules made for single paths:\n\n")
+	b.WriteString(strings.Join(lines, "\n"))
+	b.WriteString("\n\nThe selected pattern stands for:\n")
+	b.WriteString(strings.Join(detail, "\n"))
+	b.WriteString("\n\nEach keeps every file in the tree as it is now, new paths matching it follow it too.\n")
+	b.WriteString("↑/↓ select, Space accept, a accept all, Enter apply the accepted, Esc cancel")
+
+	return m.placePane(paneStyle, b.String())
+}
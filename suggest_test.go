@@ -0,0 +1,103 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// newShowsTestModel builds /test/Shows with an episode and an Extras
+// directory for each of the shows
+func newShowsTestModel(shows ...string) (*Model, map[string]*FileNode) {
+	root := &FileNode{Name: "test", Path: "/test", IsDir: true, Expanded: true}
+	showsDir := &FileNode{Name: "Shows", Path: "/test/Shows", IsDir: true, Parent: root}
+	root.Children = []*FileNode{showsDir}
+	extras := make(map[string]*FileNode)
+	for _, name := range shows {
+		show := &FileNode{Name: name, Path: "/test/Shows/" + name, IsDir: true, Parent: showsDir}
+		extra := &FileNode{Name: "Extras", Path: show.Path + "/Extras", IsDir: true, Parent: show}
+		extra.Children = []*FileNode{{Name: "trailer.mkv", Path: extra.Path + "/trailer.mkv", Size: 100, Parent: extra}}
+		show.Children = []*FileNode{extra, {Name: "e01.mkv", Path: show.Path + "/e01.mkv", Size: 1000, Parent: show}}
+		showsDir.Children = append(showsDir.Children, show)
+		extras[name] = extra
+	}
+	calculateStats(root)
+
+	model := newTestModel()
+	model.root = root
+	model.width, model.height = 100, 40
+	model.updateVisibleNodes()
+	return model, extras
+}
+
+func TestFindPatternSuggestions(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, extras := newShowsTestModel("A", "B", "C", "D")
+	for _, name := range []string{"A", "B", "C"} {
+		model.setNodeFilter(extras[name], FilterExclude)
+	}
+	// The Extras of D would be excluded by the wildcard too
+	if suggestions := model.findPatternSuggestions(); len(suggestions) != 0 {
+		t.Errorf("Expected no suggestion that changes D, got %+v", suggestions)
+	}
+
+	model.setNodeFilter(extras["D"], FilterExclude)
+	suggestions := model.findPatternSuggestions()
+	if len(suggestions) != 1 {
+		t.Fatalf("Expected one suggestion, got %+v", suggestions)
+	}
+	s := suggestions[0]
+	if s.pattern != "Shows/*/Extras/**" || s.state != FilterExclude || len(s.replaces) != 4 {
+		t.Errorf("Unexpected suggestion %+v", s)
+	}
+
+	// Too few rules aren't worth a pattern
+	model, extras = newShowsTestModel("A", "B")
+	model.setNodeFilter(extras["A"], FilterExclude)
+	model.setNodeFilter(extras["B"], FilterExclude)
+	if suggestions := model.findPatternSuggestions(); len(suggestions) != 0 {
+		t.Errorf("Expected no suggestion for two rules, got %+v", suggestions)
+	}
+}
+
+func TestApplyPatternSuggestions(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, extras := newShowsTestModel("A", "B", "C")
+	for _, extra := range extras {
+		model.setNodeFilter(extra, FilterExclude)
+	}
+
+	m := pressKeys(*model, runeKey("S"))
+	if m.topModal() != modalSuggest {
+		t.Fatalf("Expected the suggestions to be shown, status %q", m.statusMsg)
+	}
+	if view := m.View(); !strings.Contains(view, "[ ] - Shows/*/Extras/**  (replaces 3 rules)") {
+		t.Errorf("Expected the suggestion in the pane:\n%s", view)
+	}
+
+	// Nothing is applied until it is accepted
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.filters.count() != 3 || m.statusMsg != "Replaced rules with 0 wildcard patterns" {
+		t.Errorf("Expected the rules to stay, got %v (%s)", m.filters.states(), m.statusMsg)
+	}
+
+	m = pressKeys(m, runeKey("S"), runeKey(" "), tea.KeyMsg{Type: tea.KeyEnter})
+	if m.filters.count() != 1 || m.filters.state("Shows/*/Extras/**") != FilterExclude {
+		t.Errorf("Expected only the wildcard rule, got %v", m.filters.states())
+	}
+	if m.statusMsg != "Replaced rules with 1 wildcard pattern" {
+		t.Errorf("Unexpected status %q", m.statusMsg)
+	}
+	for name, extra := range extras {
+		if extra.Filter != FilterExclude || extra.Children[0].Filter != FilterExclude {
+			t.Errorf("The Extras of %s should still be excluded", name)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// survivorLimit is how many of the included files below a directory the
+// preview lists
+const survivorLimit = 200
+
+// survivors are the files below a directory that the rules let through
+type survivors struct {
+	dir      *FileNode
+	files    []*FileNode // The first survivorLimit included files, in tree order
+	included int
+	excluded int
+	pending  int // Directories whose files aren't listed yet, with --dirs-only
+}
+
+// findSurvivors walks the tree below dir in the order it is shown, counting
+// the included and excluded files and keeping the first included ones
+func findSurvivors(dir *FileNode) survivors {
+	s := survivors{dir: dir}
+	var walk func(node *FileNode)
+	walk = func(node *FileNode) {
+		if node.FilesPending {
+			s.pending++
+		}
+		for _, child := range node.Children {
+			switch {
+			case child.IsDir:
+				walk(child)
+			case child.Filter == FilterExclude:
+				s.excluded++
+			default:
+				s.included++
+				if len(s.files) < survivorLimit {
+					s.files = append(s.files, child)
+				}
+			}
+		}
+	}
+	walk(dir)
+	return s
+}
+
+// openSurvivors lists the included files below the selected directory when
+// the rules keep some of its files and not others. Otherwise the status line
+// says which way all of them go.
+func (m *Model) openSurvivors() {
+	node := m.selectedNode()
+	if node == nil || !node.IsDir {
+		m.statusMsg = "Select a directory to list the files the rules keep in it"
+		return
+	}
+	s := findSurvivors(node)
+	switch {
+	case s.included == 0 && s.excluded == 0:
+		m.statusMsg = fmt.Sprintf("%s has no files", node.Name)
+	case s.excluded == 0:
+		m.statusMsg = fmt.Sprintf("All %s files below %s are included", formatCount(s.included), node.Name)
+	case s.included == 0:
+		m.statusMsg = fmt.Sprintf("All %s files below %s are excluded", formatCount(s.excluded), node.Name)
+	default:
+		m.survivors = s
+		m.survivorCursor = 0
+		m.openModal(modalSurvivors)
+	}
+}
+
+// updateSurvivorsPane handles keys while the included files are listed
+func (m Model) updateSurvivorsPane(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	files := m.survivors.files
+	switch msg.String() {
+	case "up", "k":
+		if m.survivorCursor > 0 {
+			m.survivorCursor--
+		}
+	case "down", "j":
+		if m.survivorCursor < len(files)-1 {
+			m.survivorCursor++
+		}
+	case "pgup":
+		m.survivorCursor = max(0, m.survivorCursor-m.survivorsHeight())
+	case "pgdown":
+		m.survivorCursor = max(0, min(len(files)-1, m.survivorCursor+m.survivorsHeight()))
+	case "enter":
+		if m.survivorCursor < len(files) {
+			m.closeModal(modalSurvivors)
+			m.revealNode(files[m.survivorCursor])
+		}
+	case "l", "esc", "q":
+		m.closeModal(modalSurvivors)
+	case "ctrl+c":
+		m.cancel()
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+// survivorsHeight is how many files the pane shows at once
+func (m Model) survivorsHeight() int {
+	visibleHeight := m.height - 12
+	if visibleHeight < 5 {
+		visibleHeight = 20
+	}
+	return visibleHeight
+}
+
+func (m Model) renderSurvivors() string {
+	paneStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(currentTheme.Border).
+		Padding(1, 2)
+
+	s := m.survivors
+	dirLen := len(s.dir.Path)
+	var lines []string
+	for i, node := range s.files {
+		line := fmt.Sprintf("%s %s  (%s)", currentGlyphs.Include, strings.TrimPrefix(node.Path[dirLen:], "/"), formatSize(node.Size))
+		if i == m.survivorCursor {
+			line = lipgloss.NewStyle().Background(currentTheme.CursorBg).Foreground(currentTheme.CursorFg).Render(line)
+		}
+		lines = append(lines, line)
+	}
+
+	// Keep the selected file in view when the list is taller than the screen
+	visibleHeight := m.survivorsHeight()
+	start := 0
+	if m.survivorCursor >= visibleHeight {
+		start = m.survivorCursor - visibleHeight + 1
+	}
+	end := min(start+visibleHeight, len(lines))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s of %s files below %s are included:\n\n",
+		formatCount(s.included), formatCount(s.included+s.excluded), s.dir.Name)
+	b.WriteString(strings.Join(lines[start:end], "\n"))
+	b.WriteString("\n\n")
+	if s.included > len(s.files) {
+		fmt.Fprintf(&b, "Showing the first %s.\n", formatCount(len(s.files)))
+	}
+	if s.pending > 0 {
+		fmt.Fprintf(&b, "The files of %s directories aren't listed yet.\n", formatCount(s.pending))
+	}
+	b.WriteString("↑/↓ select, Enter show in tree, Esc close")
+
+	return m.placePane(paneStyle, b.String())
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func newSurvivorsTestModel() (*Model, *FileNode) {
+	root := &FileNode{Name: "root", Path: "/root", IsDir: true, Expanded: true}
+	photos := &FileNode{Name: "photos", Path: "/root/photos", IsDir: true, Parent: root}
+	raw := &FileNode{Name: "raw", Path: "/root/photos/raw", IsDir: true, Parent: photos}
+	root.Children = []*FileNode{photos}
+	photos.Children = []*FileNode{
+		raw,
+		{Name: "a.jpg", Path: "/root/photos/a.jpg", Size: 1024, Parent: photos},
+		{Name: "b.tmp", Path: "/root/photos/b.tmp", Filter: FilterExclude, Parent: photos},
+	}
+	raw.Children = []*FileNode{
+		{Name: "c.cr2", Path: "/root/photos/raw/c.cr2", Filter: FilterInclude, Parent: raw},
+		{Name: "d.cr2", Path: "/root/photos/raw/d.cr2", Filter: FilterExclude, Parent: raw},
+	}
+	model := newTestModel()
+	model.root = root
+	model.updateVisibleNodes()
+	return model, photos
+}
+
+func TestFindSurvivors(t *testing.T) {
+	_, photos := newSurvivorsTestModel()
+	s := findSurvivors(photos)
+	if s.included != 2 || s.excluded != 2 {
+		t.Errorf("Expected 2 included and 2 excluded files, got %d and %d", s.included, s.excluded)
+	}
+	if len(s.files) != 2 || s.files[0].Name != "c.cr2" || s.files[1].Name != "a.jpg" {
+		t.Errorf("Expected the included files in tree order, got %v", s.files)
+	}
+}
+
+func TestSurvivorsPane(t *testing.T) {
+	model, photos := newSurvivorsTestModel()
+	model.cursor = model.indexOfVisible(photos)
+
+	result := pressKeys(*model, runeKey("l"))
+	if result.topModal() != modalSurvivors {
+		t.Fatalf("Expected l on a partly excluded directory to list its included files")
+	}
+	view := result.renderSurvivors()
+	for _, expected := range []string{"2 of 4 files below photos are included", "raw/c.cr2", "a.jpg  (1.0 KiB)"} {
+		if !strings.Contains(view, expected) {
+			t.Errorf("Expected the pane to show %q, got:\n%s", expected, view)
+		}
+	}
+	if strings.Contains(view, "b.tmp") || strings.Contains(view, "d.cr2") {
+		t.Errorf("Expected the excluded files to be left out")
+	}
+
+	// Enter shows the selected file in the tree, expanding its parents
+	result = pressKeys(result, runeKey("j"), tea.KeyMsg{Type: tea.KeyEnter})
+	if result.topModal() == modalSurvivors || result.selectedNode().Name != "a.jpg" {
+		t.Errorf("Expected Enter to close the pane on a.jpg")
+	}
+}
+
+func TestSurvivorsOfUniformDirectory(t *testing.T) {
+	model, photos := newSurvivorsTestModel()
+	raw := photos.Children[0]
+	raw.Children = raw.Children[:1]
+	photos.Expanded = true
+	model.updateVisibleNodes()
+	model.cursor = model.indexOfVisible(raw)
+
+	result := pressKeys(*model, runeKey("l"))
+	if result.topModal() == modalSurvivors || result.statusMsg != "All 1 files below raw are included" {
+		t.Errorf("Expected the status line to say all files are included, got %q", result.statusMsg)
+	}
+}
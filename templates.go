@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// RuleTemplate is a rule with {{variable}} placeholders, defined in the
+// [templates] section of the config file:
+//
+//	[templates]
+//	exclude-year = "Photos/{{year}}/**"
+//	keep-album = "+ Photos/{{year}}/{{album}}/**"
+//
+// Like in a filter file, the pattern may start with "+ " or "- "; a bare
+// pattern excludes.
+type RuleTemplate struct {
+	Name    string
+	Pattern string
+	State   FilterState
+}
+
+// templateVar matches a {{variable}} placeholder
+var templateVar = regexp.MustCompile(`{{\s*([A-Za-z0-9_-]+)\s*}}`)
+
+// parseRuleTemplate parses the value of a template from the config file
+func parseRuleTemplate(name, value string) (RuleTemplate, error) {
+	t := RuleTemplate{Name: name, Pattern: strings.TrimSpace(value), State: FilterExclude}
+	if sign, rest, _ := strings.Cut(t.Pattern, " "); sign == "+" || sign == "-" {
+		if sign == "+" {
+			t.State = FilterInclude
+		}
+		t.Pattern = strings.TrimSpace(rest)
+	}
+	if t.Pattern == "" {
+		return t, fmt.Errorf("empty pattern")
+	}
+	return t, nil
+}
+
+// variables returns the names of the template's placeholders in the order
+// they first appear
+func (t RuleTemplate) variables() []string {
+	var vars []string
+	seen := make(map[string]bool)
+	for _, match := range templateVar.FindAllStringSubmatch(t.Pattern, -1) {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			vars = append(vars, match[1])
+		}
+	}
+	return vars
+}
+
+// expand fills in the placeholders with values
+func (t RuleTemplate) expand(values map[string]string) string {
+	return templateVar.ReplaceAllStringFunc(t.Pattern, func(placeholder string) string {
+		return values[templateVar.FindStringSubmatch(placeholder)[1]]
+	})
+}
+
+// String formats the template like a rule in a filter file
+func (t RuleTemplate) String() string {
+	if t.State == FilterInclude {
+		return "+ " + t.Pattern
+	}
+	return "- " + t.Pattern
+}
+
+// templatePrompt asks for the values of a template's variables one by one
+type templatePrompt struct {
+	template RuleTemplate
+	vars     []string
+	values   map[string]string
+	input    string
+}
+
+// openTemplates shows the template menu, over the rules pane when it is open
+func (m *Model) openTemplates() {
+	switch {
+	case m.filesFrom != nil:
+		m.statusMsg = "Rule templates can't be used with --files-from"
+	case len(m.templates) == 0:
+		m.statusMsg = "No rule templates, add them to the [templates] section of the config file"
+	default:
+		m.openModal(modalTemplates)
+		m.templateCursor = 0
+	}
+}
+
+// startTemplate applies a template without variables right away, or starts
+// prompting for their values
+func (m *Model) startTemplate(t RuleTemplate) {
+	vars := t.variables()
+	if len(vars) == 0 {
+		m.applyTemplate(t, nil)
+		return
+	}
+	m.templatePrompt = &templatePrompt{template: t, vars: vars, values: make(map[string]string)}
+	m.openModal(modalTemplatePrompt)
+}
+
+// applyTemplate adds the rule made from a template to the rules of the root
+// under the cursor and re-applies them to that root's tree
+func (m *Model) applyTemplate(t RuleTemplate, values map[string]string) {
+	pattern := t.expand(values)
+
+	top := m.root
+	if m.cursor >= 0 && m.cursor < len(m.visibleNodes) {
+		top, _ = nodeLocation(m.visibleNodes[m.cursor])
+	}
+	// Templates used from the rules pane add to the rules shown there
+	if m.modalOpen(modalRules) {
+		top = m.rulesTop
+	}
+	applied := m.guardChange(func() {
+		m.activateRootFor(top)
+		m.filterGen++
+		m.setRule(pattern, t.State)
+		m.reapplyFiltersToTree(top)
+	})
+	if !applied {
+		return
+	}
+	m.refreshView()
+	m.statusMsg = "Added rule " + RuleTemplate{Pattern: pattern, State: t.State}.String()
+}
+
+// updateTemplatesPane handles keys while the template menu is open
+func (m Model) updateTemplatesPane(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		if m.templateCursor > 0 {
+			m.templateCursor--
+		}
+	case "down", "j":
+		if m.templateCursor < len(m.templates)-1 {
+			m.templateCursor++
+		}
+	case "enter":
+		m.closeModal(modalTemplates)
+		if m.templateCursor < len(m.templates) {
+			m.startTemplate(m.templates[m.templateCursor])
+		}
+	case "t", "esc", "q":
+		m.closeModal(modalTemplates)
+	case "ctrl+c":
+		m.cancel()
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+// updateTemplatePrompt handles typing the value of a template variable
+func (m Model) updateTemplatePrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := m.templatePrompt
+	if used, cmd := m.editPrompt(&p.input, msg); used {
+		return m, cmd
+	}
+	switch msg.Type {
+	case tea.KeyEnter:
+		if p.input == "" {
+			return m, nil
+		}
+		p.values[p.vars[len(p.values)]] = p.input
+		p.input = ""
+		if len(p.values) == len(p.vars) {
+			m.templatePrompt = nil
+			m.closeModal(modalTemplatePrompt)
+			m.applyTemplate(p.template, p.values)
+		}
+	case tea.KeyEsc:
+		m.templatePrompt = nil
+		m.closeModal(modalTemplatePrompt)
+	}
+	return m, nil
+}
+
+// promptText is the status line while a template variable is being typed
+func (p *templatePrompt) promptText() string {
+	return fmt.Sprintf("%s: %s = %s█ (Enter to accept, Esc cancels)",
+		p.template.Name, p.vars[len(p.values)], p.input)
+}
+
+func (m Model) renderTemplates() string {
+	paneStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(currentTheme.Border).
+		Padding(1, 2)
+
+	var b strings.Builder
+	b.WriteString("Rule Templates:\n\n")
+	for i, t := range m.templates {
+		line := fmt.Sprintf("%-20s %s", t.Name, t)
+		if i == m.templateCursor {
+			line = lipgloss.NewStyle().Background(currentTheme.CursorBg).Foreground(currentTheme.CursorFg).Render(line)
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	b.WriteString("\n↑/↓ select, Enter use template, Esc close")
+
+	return m.placePane(paneStyle, b.String())
+}
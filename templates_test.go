@@ -0,0 +1,106 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestParseRuleTemplate(t *testing.T) {
+	tests := []struct {
+		value   string
+		pattern string
+		state   FilterState
+	}{
+		{"Photos/{{year}}/**", "Photos/{{year}}/**", FilterExclude},
+		{"+ Photos/{{year}}/{{album}}/**", "Photos/{{year}}/{{album}}/**", FilterInclude},
+		{"- *.{{ext}}", "*.{{ext}}", FilterExclude},
+	}
+
+	for _, tt := range tests {
+		tmpl, err := parseRuleTemplate("test", tt.value)
+		if err != nil {
+			t.Fatalf("parseRuleTemplate(%q) failed: %v", tt.value, err)
+		}
+		if tmpl.Pattern != tt.pattern || tmpl.State != tt.state {
+			t.Errorf("parseRuleTemplate(%q) = %q, %v; want %q, %v", tt.value, tmpl.Pattern, tmpl.State, tt.pattern, tt.state)
+		}
+	}
+
+	if _, err := parseRuleTemplate("empty", "+ "); err == nil {
+		t.Errorf("Expected an error for a template without pattern")
+	}
+
+	tmpl, _ := parseRuleTemplate("album", "{{ year }}/{{album}}/{{year}}-*")
+	vars := tmpl.variables()
+	if len(vars) != 2 || vars[0] != "year" || vars[1] != "album" {
+		t.Errorf("Unexpected variables %v", vars)
+	}
+	if got := tmpl.expand(map[string]string{"year": "2019", "album": "Rome"}); got != "2019/Rome/2019-*" {
+		t.Errorf("Unexpected expansion %q", got)
+	}
+}
+
+func TestLoadConfigTemplates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	os.WriteFile(path, []byte("[templates]\nexclude-year = \"Photos/{{year}}/**\"\nadd-album = \"+ Albums/{{name}}/**\"\n"), 0644)
+
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if len(cfg.Templates) != 2 || cfg.Templates[0].Name != "add-album" || cfg.Templates[1].Name != "exclude-year" {
+		t.Fatalf("Expected both templates sorted by name, got %+v", cfg.Templates)
+	}
+
+	os.WriteFile(path, []byte("[templates]\nbroken = \"- \"\n"), 0644)
+	if _, err := loadConfig(path); err == nil {
+		t.Errorf("Expected an error for an empty template")
+	}
+}
+
+func TestUseTemplate(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/root"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	root := &FileNode{Name: "root", Path: "/root", IsDir: true, Expanded: true}
+	photos := &FileNode{Name: "Photos", Path: "/root/Photos", IsDir: true, Parent: root, Expanded: true}
+	year := &FileNode{Name: "2019", Path: "/root/Photos/2019", IsDir: true, Parent: photos}
+	year.Children = []*FileNode{{Name: "a.jpg", Path: "/root/Photos/2019/a.jpg", Parent: year}}
+	photos.Children = []*FileNode{year}
+	root.Children = []*FileNode{photos}
+
+	model := newTestModel()
+	model.root = root
+	model.templates = []RuleTemplate{{Name: "exclude-year", Pattern: "Photos/{{year}}/**", State: FilterExclude}}
+	model.updateVisibleNodes()
+
+	var result tea.Model = *model
+	for _, key := range []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune("t")},
+		{Type: tea.KeyEnter},
+		{Type: tea.KeyRunes, Runes: []rune("2018")},
+		{Type: tea.KeyBackspace},
+		{Type: tea.KeyRunes, Runes: []rune("9")},
+	} {
+		result, _ = result.Update(key)
+	}
+	if p := result.(Model).templatePrompt; p == nil || p.input != "2019" {
+		t.Fatalf("Expected the prompt to hold the typed value, got %+v", p)
+	}
+
+	result, _ = result.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	final := result.(Model)
+	if final.templatePrompt != nil {
+		t.Errorf("The prompt should close once every variable has a value")
+	}
+	if final.filters.state("Photos/2019/**") != FilterExclude {
+		t.Errorf("Expected the expanded rule to be added, got %v", final.filters.states())
+	}
+	if year.Filter != FilterExclude || year.Children[0].Filter != FilterExclude {
+		t.Errorf("The new rule should apply to the tree")
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// Render modes say how much the terminal is trusted to display: 256 colors
+// and UTF-8, or 8 colors and ASCII as on bare consoles and old PuTTY
+// sessions, or whatever TERM and the locale tell
+const (
+	renderAuto  = "auto"
+	renderFull  = "full"
+	renderBasic = "basic"
+)
+
+// termCaps is what the terminal can display
+type termCaps struct {
+	colors256 bool
+	utf8      bool
+}
+
+// asciiOnly is set for terminals without UTF-8, whose views are drawn with
+// ASCII stand-ins for arrows, box drawing and other symbols
+var asciiOnly bool
+
+// basicTerms are the terminals that only draw ASCII reliably, whatever the
+// locale says
+var basicTerms = []string{"linux", "dumb", "ansi", "cons25", "vt52", "vt100", "vt102", "vt220"}
+
+// detectTermCaps tells from the environment what the terminal can display.
+// Colors come from TERM and COLORTERM, UTF-8 from the locale; without any
+// locale setting, as over many SSH sessions, UTF-8 is assumed. On Windows
+// the console handles both.
+func detectTermCaps(getenv func(string) string, goos string) termCaps {
+	term := getenv("TERM")
+	if goos == "windows" && term == "" {
+		return termCaps{colors256: true, utf8: true}
+	}
+
+	caps := termCaps{utf8: true}
+	colorterm := strings.ToLower(getenv("COLORTERM"))
+	caps.colors256 = strings.Contains(term, "256color") || strings.Contains(term, "direct") ||
+		colorterm == "truecolor" || colorterm == "24bit"
+
+	for _, key := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if locale := getenv(key); locale != "" {
+			locale = strings.ToLower(locale)
+			caps.utf8 = strings.Contains(locale, "utf-8") || strings.Contains(locale, "utf8")
+			break
+		}
+	}
+	for _, basic := range basicTerms {
+		if term == basic {
+			caps.utf8 = false
+		}
+	}
+	return caps
+}
+
+// applyRenderMode degrades the colors and characters the editor draws with
+// to what the terminal can display. On 8 colors an automatic theme becomes
+// the basic one, and other themes have their colors mapped to the nearest
+// of the 8.
+func (c *Config) applyRenderMode(getenv func(string) string, goos string) {
+	caps := termCaps{colors256: true, utf8: true}
+	switch c.Render {
+	case renderBasic:
+		caps = termCaps{}
+	case renderAuto:
+		caps = detectTermCaps(getenv, goos)
+	}
+
+	asciiOnly = !caps.utf8
+	switch {
+	case !caps.colors256:
+		lipgloss.SetColorProfile(termenv.ANSI)
+		if c.Theme == themeAuto {
+			currentTheme = themes["basic"]
+		}
+	case c.Render == renderFull && lipgloss.ColorProfile() > termenv.ANSI256:
+		lipgloss.SetColorProfile(termenv.ANSI256)
+	}
+}
+
+// asciiFallbacks are the ASCII stand-ins of the symbols the views draw,
+// each as wide as the symbol so that panes keep their borders in line
+var asciiFallbacks = map[rune]rune{
+	'↑': '^', '↓': 'v', '→': '>', '←': '<',
+	'▼': 'v', '▶': '>', '●': '*', '…': '.', '—': '-',
+	'✓': '+', '✗': 'x', '⚠': '!', '⟳': '*', '≥': '>', '≠': '!',
+	'█': '#', '▏': '|', '▎': '|', '▍': '|', '▌': '|', '▋': '|', '▊': '|', '▉': '|',
+	'▐': '|', '▀': '-', '▄': '_',
+	'│': '|', '├': '|', '└': '`', '─': '-',
+	'╭': '+', '╮': '+', '╰': '+', '╯': '+', '┌': '+', '┐': '+', '┘': '+',
+}
+
+// toASCII replaces what a terminal without UTF-8 can't draw: the symbols of
+// the views by their stand-ins, and anything else, such as accented names,
+// by a question mark
+func toASCII(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x80 {
+			return r
+		}
+		if ascii, ok := asciiFallbacks[r]; ok {
+			return ascii
+		}
+		return '?'
+	}, s)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+func TestDetectTermCaps(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		goos     string
+		expected termCaps
+	}{
+		{"xterm with UTF-8", map[string]string{"TERM": "xterm-256color", "LANG": "en_US.UTF-8"}, "linux", termCaps{colors256: true, utf8: true}},
+		{"truecolor", map[string]string{"TERM": "xterm", "COLORTERM": "truecolor"}, "linux", termCaps{colors256: true, utf8: true}},
+		{"old PuTTY", map[string]string{"TERM": "xterm", "LANG": "en_GB.ISO-8859-1"}, "linux", termCaps{}},
+		{"C locale", map[string]string{"TERM": "screen", "LC_ALL": "C", "LANG": "en_US.UTF-8"}, "linux", termCaps{}},
+		{"LC_CTYPE over LANG", map[string]string{"TERM": "xterm-256color", "LC_CTYPE": "de_DE.utf8", "LANG": "C"}, "linux", termCaps{colors256: true, utf8: true}},
+		{"Linux console", map[string]string{"TERM": "linux", "LANG": "en_US.UTF-8"}, "linux", termCaps{}},
+		{"no locale", map[string]string{"TERM": "xterm"}, "linux", termCaps{utf8: true}},
+		{"Windows console", map[string]string{}, "windows", termCaps{colors256: true, utf8: true}},
+	}
+	for _, tt := range tests {
+		getenv := func(key string) string { return tt.env[key] }
+		if got := detectTermCaps(getenv, tt.goos); got != tt.expected {
+			t.Errorf("%s: detectTermCaps = %+v; want %+v", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestApplyRenderMode(t *testing.T) {
+	originalTheme, originalProfile := currentTheme, lipgloss.ColorProfile()
+	defer func() {
+		currentTheme, asciiOnly = originalTheme, false
+		lipgloss.SetColorProfile(originalProfile)
+	}()
+	getenv := func(key string) string {
+		return map[string]string{"TERM": "linux", "LANG": "C"}[key]
+	}
+
+	cfg := defaultConfig()
+	cfg.applyRenderMode(getenv, "linux")
+	if !asciiOnly || currentTheme != themes["basic"] || lipgloss.ColorProfile() != termenv.ANSI {
+		t.Errorf("Expected a bare console to get 8 colors and ASCII")
+	}
+
+	// The override trusts the terminal whatever it says
+	cfg.Render = renderFull
+	currentTheme = themes["default"]
+	cfg.applyRenderMode(getenv, "linux")
+	if asciiOnly || currentTheme != themes["default"] || lipgloss.ColorProfile() != termenv.ANSI256 {
+		t.Errorf("Expected --render full to keep 256 colors and UTF-8")
+	}
+}
+
+func TestASCIIView(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/test"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+	defer func() { asciiOnly = false }()
+
+	model, _, small := newGuardTestModel()
+	model.showGuides = true
+	small.Expanded = true
+	small.Children[0].Name = "café.bin"
+	model.updateVisibleNodes()
+	model.width, model.height = 80, 24
+
+	asciiOnly = true
+	view := model.View()
+	for _, r := range view {
+		if r >= utf8.RuneSelf {
+			t.Fatalf("Expected only ASCII, got %q in:\n%s", r, view)
+		}
+	}
+	for _, expected := range []string{"v [ ] test", "| > [ ] big", "  |   [ ] caf?.bin"} {
+		if !strings.Contains(view, expected) {
+			t.Errorf("Expected %q in:\n%s", expected, view)
+		}
+	}
+
+	// Panes keep their borders in line
+	model.openModal(modalHelp)
+	lines := strings.Split(model.View(), "\n")
+	if !strings.HasPrefix(strings.TrimLeft(lines[0], " "), "+---") || len(lines[0]) != len(lines[len(lines)-1]) {
+		t.Errorf("Expected the help border drawn in ASCII:\n%s\n%s", lines[0], lines[len(lines)-1])
+	}
+}
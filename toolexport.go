@@ -0,0 +1,292 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Values of --export that translate the rules for other backup tools
+const (
+	exportRestic = "restic"
+	exportBorg   = "borg"
+	exportRsync  = "rsync"
+)
+
+// backupTool translates the rules into the exclude or filter file of another
+// backup tool, for backing up the same tree with it as with rclone
+type backupTool struct {
+	name  string
+	usage string // Command line reading the file, %[1]s being its name and %[2]s the root
+	local bool   // The tool only backs up local directories
+	// translate returns the lines of the file for rules in the order rclone
+	// reads them, with warnings where the tool can't do what rclone does
+	translate func(rules []FilterRule, root string) (lines, warnings []string)
+}
+
+var backupTools = []backupTool{
+	{name: exportRestic, usage: "restic backup %[2]s --exclude-file %[1]s", local: true, translate: resticRules},
+	{name: exportBorg, usage: "borg create --patterns-from %[1]s REPO::ARCHIVE", local: true, translate: borgRules},
+	{name: exportRsync, usage: "rsync -a --filter='merge %[1]s' %[2]s/ DEST", translate: rsyncRules},
+}
+
+// findBackupTool returns the tool --export mode translates the rules for
+func findBackupTool(mode string) (backupTool, bool) {
+	for _, tool := range backupTools {
+		if tool.name == mode {
+			return tool, true
+		}
+	}
+	return backupTool{}, false
+}
+
+// toolRules returns the rules rclone reads, with the alternatives of
+// patterns such as "*.{jpg,png}" written as a rule each, which none of the
+// tools support. Regular expressions can't be translated and are left out.
+func toolRules(rules []FilterRule) ([]FilterRule, []string) {
+	var out []FilterRule
+	var warnings []string
+	for _, rule := range rules {
+		if rule.Disabled || rule.Include != "" {
+			continue
+		}
+		if strings.Contains(rule.Pattern, "{{") {
+			warnings = append(warnings, fmt.Sprintf("%s: regular expressions can't be translated; left out", rule))
+			continue
+		}
+		for _, pattern := range expandAlternatives(rule.Pattern) {
+			rule.Pattern = pattern
+			out = append(out, rule)
+		}
+	}
+	return out, warnings
+}
+
+// expandAlternatives returns the patterns a pattern with {a,b} alternatives
+// stands for, such as "*.jpg" and "*.png" for "*.{jpg,png}". Rules of the
+// same sign one after the other match what the alternatives do.
+func expandAlternatives(pattern string) []string {
+	start, depth := -1, 0
+	var commas []int
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '{':
+			if depth == 0 {
+				start = i
+			}
+			depth++
+		case ',':
+			if depth == 1 {
+				commas = append(commas, i)
+			}
+		case '}':
+			if depth == 0 {
+				continue
+			}
+			if depth--; depth > 0 {
+				continue
+			}
+			prefix, suffix := pattern[:start], pattern[i+1:]
+			var expanded []string
+			from := start + 1
+			for _, to := range append(commas, i) {
+				expanded = append(expanded, expandAlternatives(prefix+pattern[from:to]+suffix)...)
+				from = to + 1
+			}
+			return expanded
+		}
+	}
+	return []string{pattern}
+}
+
+// escapesToClasses writes the characters escaped with a backslash as
+// character classes, as "[*]" for "\*", for tools that only take a backslash
+// as an escape in some patterns
+func escapesToClasses(pattern string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] == '\\' && i+1 < len(pattern) {
+			i++
+			b.WriteString("[" + string(pattern[i]) + "]")
+			continue
+		}
+		b.WriteByte(pattern[i])
+	}
+	return b.String()
+}
+
+// includeParents returns the directories above what an include pattern
+// matches, such as "a" and "a/b" for "a/b/**", anchored as the pattern is.
+// complete is false when a "**" in the middle leaves them open.
+func includeParents(pattern string) (parents []string, complete bool) {
+	anchor := ""
+	if strings.HasPrefix(pattern, "/") {
+		anchor = "/"
+	}
+	segments := strings.Split(strings.Trim(pattern, "/"), "/")
+	for k := 1; k < len(segments); k++ {
+		if segments[k-1] == "**" {
+			return parents, false
+		}
+		parents = append(parents, anchor+strings.Join(segments[:k], "/"))
+	}
+	return parents, true
+}
+
+// shadowingExclude returns the first rule after the include at i that
+// excludes a directory above what it matches. Tools that don't look inside
+// an excluded directory never reach the files the include is for.
+func shadowingExclude(rules []FilterRule, i int) (FilterRule, bool) {
+	parents, _ := includeParents(rules[i].Pattern)
+	for _, rule := range rules[i+1:] {
+		if rule.State != FilterExclude {
+			continue
+		}
+		if len(parents) == 0 {
+			// An include of the top level needs "- *" or the like to be cut off
+			segments := strings.Split(strings.TrimSuffix(rule.Pattern, "/"), "/")
+			if last := segments[len(segments)-1]; last == "*" || last == "**" {
+				return rule, true
+			}
+		}
+		for _, parent := range parents {
+			if matchesRclonePattern(rule.Pattern, "/"+strings.TrimPrefix(parent, "/")) {
+				return rule, true
+			}
+		}
+	}
+	return FilterRule{}, false
+}
+
+// resticRules writes an --exclude-file for restic. restic uses the last
+// pattern matching a path rather than the first, so the rules are written in
+// reverse, includes as negations. Patterns anchored with "/" are made
+// absolute, as restic matches the full paths of the files.
+func resticRules(rules []FilterRule, root string) (lines, warnings []string) {
+	lines = append(lines, "# restic reads these the other way round from rclone: the last matching line wins")
+	for i := len(rules) - 1; i >= 0; i-- {
+		rule := rules[i]
+		pattern := strings.TrimSuffix(rule.Pattern, "/")
+		if strings.HasPrefix(pattern, "/") {
+			pattern = strings.TrimSuffix(root, "/") + pattern
+		}
+		if rule.State == FilterInclude {
+			pattern = "!" + pattern
+		}
+		lines = append(lines, pattern)
+	}
+	for i, rule := range rules {
+		if rule.State != FilterInclude {
+			continue
+		}
+		if exclude, ok := shadowingExclude(rules, i); ok {
+			warnings = append(warnings, fmt.Sprintf("%s: restic doesn't look inside the directories %s excludes, so files below them stay excluded", rule, exclude))
+		}
+	}
+	return lines, warnings
+}
+
+// borgRules writes a --patterns-from file for borg, which like rclone uses
+// the first matching pattern and looks inside excluded directories for
+// included files. Patterns are matched from the start of the path, so they
+// are written below the root, "**/" standing in for rclone's match at any
+// depth.
+func borgRules(rules []FilterRule, root string) (lines, warnings []string) {
+	lines = append(lines, "P sh", "R "+root)
+	base := strings.Trim(root, "/")
+	if base != "" {
+		base += "/"
+	}
+	for _, rule := range rules {
+		pattern := escapesToClasses(strings.TrimSuffix(rule.Pattern, "/"))
+		if strings.HasPrefix(pattern, "/") {
+			pattern = base + strings.TrimPrefix(pattern, "/")
+		} else {
+			pattern = base + "**/" + pattern
+		}
+		sign := "-"
+		if rule.State == FilterInclude {
+			sign = "+"
+		}
+		lines = append(lines, sign+" "+pattern)
+	}
+	return lines, nil
+}
+
+// rsyncRules writes a merge file for rsync --filter, whose rules read like
+// rclone's. rsync doesn't look inside an excluded directory though, so the
+// directories above an include are included before it, as "+ a/" for
+// "+ a/b/**", for the exclude rules after it not to cut them off.
+func rsyncRules(rules []FilterRule, root string) (lines, warnings []string) {
+	added := make(map[string]bool)
+	for i, rule := range rules {
+		pattern := escapesToClasses(rule.Pattern)
+		if rule.State == FilterExclude {
+			lines = append(lines, "- "+pattern)
+			continue
+		}
+		exclude, shadowed := shadowingExclude(rules, i)
+		parents, complete := includeParents(pattern)
+		switch {
+		case !shadowed:
+		case len(parents) == 0:
+			warnings = append(warnings, fmt.Sprintf("%s: rsync doesn't look inside the directories %s excludes; add \"+ */\" before it and use rsync --prune-empty-dirs", rule, exclude))
+		case !complete:
+			warnings = append(warnings, fmt.Sprintf("%s: rsync doesn't look inside the directories %s excludes, and the ones below ** can't be listed", rule, exclude))
+		}
+		if shadowed {
+			for _, parent := range parents {
+				if !added[parent] {
+					added[parent] = true
+					lines = append(lines, "+ "+parent+"/")
+				}
+			}
+		}
+		lines = append(lines, "+ "+pattern)
+	}
+	return lines, warnings
+}
+
+// runToolExport writes the rules translated for tool to output ("-" for
+// stdout), warning on stderr where the tool differs from rclone, and returns
+// the process exit code
+func runToolExport(m *Model, tool backupTool, output string) int {
+	if m.filesFrom != nil {
+		fmt.Fprintf(os.Stderr, "Error: --export %s translates filter rules, not a --files-from list\n", tool.name)
+		return exitNotSaved
+	}
+	if tool.local && isRemotePath(m.root.Path) {
+		fmt.Fprintf(os.Stderr, "Error: %s only backs up local directories, not %s\n", tool.name, m.root.Path)
+		return exitNotSaved
+	}
+
+	rules, warnings := toolRules(m.savedRules())
+	lines, toolWarnings := tool.translate(rules, m.root.Path)
+	for _, warning := range append(warnings, toolWarnings...) {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(line + "\n")
+	}
+	if output == stdioFilterFile {
+		if _, err := os.Stdout.WriteString(b.String()); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing export: %v\n", err)
+			return exitNotSaved
+		}
+		return exitSaved
+	}
+	if err := validateFilterFilePath(output); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid export file: %v\n", err)
+		return exitNotSaved
+	}
+	if err := os.WriteFile(output, []byte(b.String()), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitNotSaved
+	}
+	infof("Wrote the rules for %s to %s, use it with %s\n", tool.name, output, fmt.Sprintf(tool.usage, output, m.root.Path))
+	return exitSaved
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExpandAlternatives(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		expected []string
+	}{
+		{"*.jpg", []string{"*.jpg"}},
+		{"*.{jpg,png}", []string{"*.jpg", "*.png"}},
+		{"{a,b}/{c,d}", []string{"a/c", "a/d", "b/c", "b/d"}},
+		{"x{a,{b,c}}", []string{"xa", "xb", "xc"}},
+		{`\{a,b\}`, []string{`\{a,b\}`}},
+		{"open{", []string{"open{"}},
+	}
+	for _, tc := range tests {
+		if got := expandAlternatives(tc.pattern); !slices.Equal(got, tc.expected) {
+			t.Errorf("expandAlternatives(%q) = %q; want %q", tc.pattern, got, tc.expected)
+		}
+	}
+}
+
+func TestToolRulesLeavesOutRegexAndDisabled(t *testing.T) {
+	rules, warnings := toolRules([]FilterRule{
+		{Pattern: "*.{tmp,bak}", State: FilterExclude},
+		{Pattern: "{{.*\\.log}}", State: FilterExclude},
+		{Pattern: "old/**", State: FilterExclude, Disabled: true},
+		{Pattern: "", Include: "common.txt"},
+	})
+	if len(rules) != 2 || rules[0].Pattern != "*.tmp" || rules[1].Pattern != "*.bak" {
+		t.Errorf("Expected the alternatives as two rules, got %v", rules)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "regular expressions") {
+		t.Errorf("Expected a warning about the regular expression, got %q", warnings)
+	}
+}
+
+// toolTestRules keep a directory of an excluded one, as the editor writes
+// them, and exclude temporary files anywhere
+var toolTestRules = []FilterRule{
+	{Pattern: "/cache/keep/**", State: FilterInclude},
+	{Pattern: "/cache/**", State: FilterExclude},
+	{Pattern: `*\[old\].tmp`, State: FilterExclude},
+	{Pattern: "*.tmp", State: FilterExclude},
+}
+
+func TestResticRules(t *testing.T) {
+	lines, warnings := resticRules(toolTestRules, "/data")
+	expected := []string{
+		lines[0],
+		"*.tmp",
+		`*\[old\].tmp`,
+		"/data/cache/**",
+		"!/data/cache/keep/**",
+	}
+	if !slices.Equal(lines, expected) || !strings.HasPrefix(lines[0], "#") {
+		t.Errorf("resticRules = %q; want %q", lines, expected)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "+ /cache/keep/**") || !strings.Contains(warnings[0], "- /cache/**") {
+		t.Errorf("Expected a warning about the include below the excluded directory, got %q", warnings)
+	}
+}
+
+func TestBorgRules(t *testing.T) {
+	lines, warnings := borgRules(toolTestRules, "/data")
+	expected := []string{
+		"P sh",
+		"R /data",
+		"+ data/cache/keep/**",
+		"- data/cache/**",
+		"- data/**/*[[]old[]].tmp",
+		"- data/**/*.tmp",
+	}
+	if !slices.Equal(lines, expected) {
+		t.Errorf("borgRules = %q; want %q", lines, expected)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("Expected borg to look inside excluded directories, got %q", warnings)
+	}
+}
+
+func TestRsyncRules(t *testing.T) {
+	lines, warnings := rsyncRules(toolTestRules, "/data")
+	expected := []string{
+		"+ /cache/",
+		"+ /cache/keep/",
+		"+ /cache/keep/**",
+		"- /cache/**",
+		"- *[[]old[]].tmp",
+		"- *.tmp",
+	}
+	if !slices.Equal(lines, expected) {
+		t.Errorf("rsyncRules = %q; want %q", lines, expected)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("Expected the parent directories to make up for the exclude, got %q", warnings)
+	}
+
+	// An include of files at any depth can't be helped by listing directories
+	lines, warnings = rsyncRules([]FilterRule{
+		{Pattern: "*.jpg", State: FilterInclude},
+		{Pattern: "*", State: FilterExclude},
+	}, "/data")
+	if !slices.Equal(lines, []string{"+ *.jpg", "- *"}) {
+		t.Errorf("rsyncRules = %q; want the rules as they are", lines)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], `"+ */"`) {
+		t.Errorf("Expected a warning suggesting + */, got %q", warnings)
+	}
+
+	// Without an exclude after it, an include needs nothing more
+	lines, _ = rsyncRules([]FilterRule{{Pattern: "/a/b/**", State: FilterInclude}}, "/data")
+	if !slices.Equal(lines, []string{"+ /a/b/**"}) {
+		t.Errorf("rsyncRules = %q; want the include alone", lines)
+	}
+}
+
+func TestRunToolExport(t *testing.T) {
+	model := newTestModelWithStates(map[string]FilterState{"cache/**": FilterExclude})
+	model.root = &FileNode{Name: "data", Path: "/data", IsDir: true}
+	output := filepath.Join(t.TempDir(), "borg.txt")
+	tool, _ := findBackupTool(exportBorg)
+	if code := runToolExport(model, tool, output); code != exitSaved {
+		t.Fatalf("runToolExport returned %d", code)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "P sh\nR /data\n- data/**/cache/**\n" {
+		t.Errorf("Wrote %q", data)
+	}
+
+	model.root.Path = "gdrive:Photos"
+	if code := runToolExport(model, tool, output); code != exitNotSaved {
+		t.Errorf("Expected borg to refuse a remote, got exit code %d", code)
+	}
+}
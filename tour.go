@@ -0,0 +1,207 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// tourStep is one page of the guided tour
+type tourStep struct {
+	title string
+	text  string
+}
+
+// defaultTourFile returns the file recording that the tour was taken, so it
+// only starts by itself on the first run
+func defaultTourFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "rclone-filter-editor", "tour-seen")
+}
+
+// tourNeeded reports whether the tour hasn't been taken yet
+func tourNeeded(file string) bool {
+	if file == "" {
+		return false
+	}
+	_, err := os.Stat(file)
+	return errors.Is(err, fs.ErrNotExist)
+}
+
+// startTourOnFirstRun opens the tour once the first scan has completed, so
+// its examples come from the loaded tree
+func (m *Model) startTourOnFirstRun() {
+	if !m.tourPending {
+		return
+	}
+	m.tourPending = false
+	m.startTour()
+}
+
+func (m *Model) startTour() {
+	m.openModal(modalTour)
+	m.tourPage = 0
+}
+
+// endTour closes the tour and records that it was taken
+func (m *Model) endTour() {
+	m.closeModal(modalTour)
+	if m.tourFile == "" {
+		return
+	}
+	// Failing to record it only means the tour shows again next time
+	if err := os.MkdirAll(filepath.Dir(m.tourFile), 0o755); err == nil {
+		os.WriteFile(m.tourFile, nil, 0o644)
+	}
+}
+
+// tourExamples picks a directory, one of its subdirectories and a file from
+// the tree to use in the tour's examples, with made up names for what the
+// tree lacks
+func (m *Model) tourExamples() (dir, sub, file string) {
+	dir, sub, file = "Photos/**", "Photos/Albums/**", "notes.txt"
+	var dirNode, fileNode *FileNode
+	for _, top := range m.topLevelNodes() {
+		for _, node := range collectNodes(top, nil) {
+			switch {
+			case node.Parent == nil:
+			case node.IsDir && dirNode == nil:
+				dirNode = node
+			case !node.IsDir && (fileNode == nil || getNodeDepth(node) < getNodeDepth(fileNode)):
+				// A file near the top is the easiest to recognize
+				fileNode = node
+			}
+		}
+	}
+	if dirNode != nil {
+		dir = nodeRulePattern(dirNode)
+		sub = strings.TrimSuffix(dir, "**") + "Albums/**"
+		for _, child := range dirNode.Children {
+			if child.IsDir {
+				sub = nodeRulePattern(child)
+				break
+			}
+		}
+	}
+	if fileNode != nil {
+		file = nodeRulePattern(fileNode)
+	}
+	return dir, sub, file
+}
+
+// tourSteps builds the pages of the tour, with examples from the loaded tree
+func (m *Model) tourSteps() []tourStep {
+	dir, sub, file := m.tourExamples()
+	var root string
+	if m.root != nil {
+		root = m.root.Path
+	}
+	if m.multiRoot() {
+		root = fmt.Sprintf("%d roots", len(m.roots))
+	}
+
+	return []tourStep{
+		{"Welcome", fmt.Sprintf(`This short tour shows how what you select in
+the tree of %s becomes rclone filter rules.
+
+Each row starts with its state:
+  %s  no rule, rclone copies it
+  %s  included
+  %s  excluded`, root, currentGlyphs.None, currentGlyphs.Include, currentGlyphs.Exclude)},
+
+		{"Toggling", `Move with ↑/↓ and open directories with → or Enter.
+
+Space cycles the selected entry through
+no rule → include → exclude. Toggling a
+directory applies to everything inside it.`},
+
+		{"Rules", fmt.Sprintf(`Every toggle writes one rule. Excluding a
+directory writes
+
+  - %s
+
+where /** matches every path below it at any
+depth, so one rule covers the whole folder.
+Including a single file writes
+
+  + %s`, dir, file)},
+
+		{"Rule order", fmt.Sprintf(`rclone reads the rules from the top and the
+first one that matches decides. So
+
+  + %s
+  - %s
+
+keeps the subfolder and drops the rest. In the
+opposite order the exclude would match first
+and drop the subfolder too.
+
+The editor writes rules for deeper paths before
+those of their parents and keeps the order of
+the rules already in the filter file.`, sub, dir)},
+
+		{"Saving", fmt.Sprintf(`s writes the rules to %s,
+q asks whether to save before quitting.
+
+Use the file with rclone:
+
+  rclone sync --filter-from FILE source: dest:`, m.filterFileNames())},
+
+		{"That's it", `Press ? for the list of keys. The help also
+starts this tour again with g.`},
+	}
+}
+
+// updateTourPane handles keys while the tour is shown
+func (m Model) updateTourPane(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	last := len(m.tourSteps()) - 1
+	switch msg.String() {
+	case "right", "l", "enter", " ", "n":
+		if m.tourPage < last {
+			m.tourPage++
+		} else {
+			m.endTour()
+		}
+	case "left", "h", "backspace", "p":
+		if m.tourPage > 0 {
+			m.tourPage--
+		}
+	case "esc", "q":
+		m.endTour()
+	case "ctrl+c":
+		m.cancel()
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m Model) renderTour() string {
+	paneStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(currentTheme.Border).
+		Padding(1, 2)
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(currentTheme.Header)
+	mutedStyle := lipgloss.NewStyle().Foreground(currentTheme.Muted)
+
+	steps := m.tourSteps()
+	step := steps[min(m.tourPage, len(steps)-1)]
+
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(step.title))
+	b.WriteString(mutedStyle.Render(fmt.Sprintf("  (%d/%d)", m.tourPage+1, len(steps))))
+	b.WriteString("\n\n")
+	b.WriteString(step.text)
+	b.WriteString("\n\n")
+	b.WriteString(mutedStyle.Render("→/Enter next, ← back, Esc ends the tour"))
+
+	return m.placePane(paneStyle, b.String())
+}
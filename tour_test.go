@@ -0,0 +1,58 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestTourExamplesComeFromTree(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/root"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, _ := newTriageTestModel()
+	dir, sub, file := model.tourExamples()
+	// docs has no subdirectories, so the subfolder is made up
+	if dir != "docs/**" || sub != "docs/Albums/**" || file != "photos/a.jpg" {
+		t.Errorf("Unexpected examples %q, %q, %q", dir, sub, file)
+	}
+
+	steps := model.tourSteps()
+	if !strings.Contains(steps[2].text, "- docs/**") {
+		t.Errorf("Expected the rules page to show the rule for docs, got:\n%s", steps[2].text)
+	}
+}
+
+func TestTourFirstRun(t *testing.T) {
+	model := newTestModel()
+	model.root = &FileNode{Name: "root", Path: "/root", IsDir: true, Expanded: true}
+	model.tourFile = filepath.Join(t.TempDir(), "rclone-filter-editor", "tour-seen")
+	model.tourPending = tourNeeded(model.tourFile)
+	if !model.tourPending {
+		t.Fatalf("Expected the tour to be needed before it was taken")
+	}
+
+	model.startTourOnFirstRun()
+	if !model.modalOpen(modalTour) {
+		t.Fatalf("Expected the tour to start after the first scan")
+	}
+
+	updated, _ := model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	result := updated.(Model)
+	if result.modalOpen(modalTour) {
+		t.Errorf("Expected Esc to end the tour")
+	}
+	if tourNeeded(model.tourFile) {
+		t.Errorf("Expected the tour to be recorded as taken")
+	}
+
+	// The help starts it again
+	result.openModal(modalHelp)
+	updated, _ = result.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("g")})
+	if result = updated.(Model); !result.modalOpen(modalTour) || result.tourPage != 0 {
+		t.Errorf("Expected g in the help to start the tour again")
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Values of --export dumping the whole tree rather than a file list
+const (
+	exportCSV  = "csv"
+	exportJSON = "json"
+)
+
+// treeRow is an entry of the tree as --export csv and json write it
+type treeRow struct {
+	Path    string `json:"path"` // Relative to the root
+	Dir     bool   `json:"dir"`
+	Size    int64  `json:"size"` // Of everything below, for directories
+	ModTime string `json:"mtime,omitempty"`
+	State   string `json:"state"`
+	Rule    string `json:"rule,omitempty"` // Rule deciding the state
+}
+
+// treeColumns are the header of --export csv
+var treeColumns = []string{"path", "dir", "size", "mtime", "state", "rule"}
+
+// treeRows walks the tree below root and lists every directory and file with
+// its state, worked out as the editor does, parents before their children
+func (m *Model) treeRows(ctx context.Context, root string) ([]treeRow, error) {
+	l := m.lister
+	if l == nil {
+		l = localLister{}
+	}
+	rootPath := rootPathFor(root)
+
+	var rows []treeRow
+	var walk func(dir string) (int64, error)
+	walk = func(dir string) (int64, error) {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		entries, err := l.List(ctx, dir)
+		if err != nil {
+			return 0, err
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Name < entries[j].Name
+		})
+
+		var total int64
+		for _, entry := range entries {
+			childPath := joinChildPath(dir, entry.Name)
+			if !isRemotePath(childPath) {
+				if err := validatePath(childPath, rootPath); err != nil {
+					continue
+				}
+			}
+			filterPath := getFilterPath(childPath)
+			rule, state := m.matchingRule(filterPath)
+			row := treeRow{
+				Path:  strings.TrimPrefix(filterPath, "/"),
+				Dir:   entry.IsDir,
+				Size:  entry.Size,
+				State: filterStateNames[state],
+				Rule:  rule,
+			}
+			if !entry.ModTime.IsZero() {
+				row.ModTime = entry.ModTime.UTC().Format(time.RFC3339)
+			}
+			rows = append(rows, row)
+
+			if entry.IsDir {
+				i := len(rows) - 1
+				size, err := walk(childPath)
+				if err != nil {
+					return 0, fmt.Errorf("%s: %w", childPath, err)
+				}
+				rows[i].Size = size
+				row.Size = size
+			}
+			total += row.Size
+		}
+		return total, nil
+	}
+
+	if _, err := walk(root); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// exportTree writes the tree below root as CSV or JSON, for spreadsheets
+// and jq
+func (m *Model) exportTree(ctx context.Context, w io.Writer, root, mode string) error {
+	rows, err := m.treeRows(ctx, root)
+	if err != nil {
+		return err
+	}
+	if mode == exportJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if rows == nil {
+			rows = []treeRow{}
+		}
+		return enc.Encode(rows)
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write(treeColumns)
+	for _, row := range rows {
+		cw.Write([]string{row.Path, strconv.FormatBool(row.Dir), strconv.FormatInt(row.Size, 10), row.ModTime, row.State, row.Rule})
+	}
+	cw.Flush()
+	return cw.Error()
+}
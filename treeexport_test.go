@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportTree(t *testing.T) {
+	rootDir := t.TempDir()
+	os.MkdirAll(filepath.Join(rootDir, "cache", "keep"), 0755)
+	os.WriteFile(filepath.Join(rootDir, "cache", "tmp.bin"), []byte("xxx"), 0644)
+	os.WriteFile(filepath.Join(rootDir, "cache", "keep", "notes, v2.txt"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(rootDir, "top.txt"), []byte("xx"), 0644)
+	mtime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	os.Chtimes(filepath.Join(rootDir, "top.txt"), mtime, mtime)
+
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = rootDir
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model := newTestModelWithStates(map[string]FilterState{
+		"cache/**":      FilterExclude,
+		"cache/keep/**": FilterInclude,
+	})
+
+	var b strings.Builder
+	if err := model.exportTree(context.Background(), &b, rootDir, exportCSV); err != nil {
+		t.Fatalf("CSV export failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 6 || lines[0] != "path,dir,size,mtime,state,rule" {
+		t.Fatalf("Unexpected CSV:\n%s", b.String())
+	}
+	if !strings.HasPrefix(lines[1], "cache,true,4,") || !strings.HasSuffix(lines[1], ",exclude,cache/**") {
+		t.Errorf("Directory row = %q", lines[1])
+	}
+	if !strings.HasPrefix(lines[3], `"cache/keep/notes, v2.txt",false,1,`) || !strings.HasSuffix(lines[3], ",include,cache/keep/**") {
+		t.Errorf("Quoted file row = %q", lines[3])
+	}
+	if lines[5] != "top.txt,false,2,2024-05-01T12:00:00Z,none," {
+		t.Errorf("Row without a rule = %q", lines[5])
+	}
+
+	b.Reset()
+	if err := model.exportTree(context.Background(), &b, rootDir, exportJSON); err != nil {
+		t.Fatalf("JSON export failed: %v", err)
+	}
+	var rows []treeRow
+	if err := json.Unmarshal([]byte(b.String()), &rows); err != nil {
+		t.Fatalf("Invalid JSON: %v\n%s", err, b.String())
+	}
+	if len(rows) != 5 {
+		t.Fatalf("Unexpected rows %+v", rows)
+	}
+	for _, row := range rows {
+		if row.Path == "cache/tmp.bin" && (row.State != "exclude" || row.Rule != "cache/**" || row.Size != 3) {
+			t.Errorf("Unexpected row %+v", row)
+		}
+	}
+}
+
+func TestExportTreeEmpty(t *testing.T) {
+	rootDir := t.TempDir()
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = rootDir
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	var b strings.Builder
+	if err := newTestModel().exportTree(context.Background(), &b, rootDir, exportJSON); err != nil {
+		t.Fatalf("JSON export failed: %v", err)
+	}
+	if b.String() != "[]\n" {
+		t.Errorf("An empty tree should be an empty array, got %q", b.String())
+	}
+}
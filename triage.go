@@ -0,0 +1,317 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// staleRule is a loaded rule that refers to a path missing from the tree,
+// typically left behind when directories were moved or renamed
+type staleRule struct {
+	top     *FileNode // Top-level node of the root the rule belongs to
+	pattern string
+	anchor  string // Path the rule refers to, relative to the root
+}
+
+// ruleAnchor returns the path a rule pattern refers to: the pattern itself
+// for literal paths, or the directory before the first wildcard. Patterns
+// that only match names anywhere in the tree, like "*.tmp", have no anchor.
+func ruleAnchor(pattern string) (string, bool) {
+	p := strings.TrimPrefix(pattern, "/")
+	if i := strings.IndexAny(p, "*?[{"); i >= 0 {
+		slash := strings.LastIndex(p[:i], "/")
+		if slash < 0 {
+			return "", false
+		}
+		return p[:slash], true
+	}
+
+	p = strings.TrimSuffix(p, "/")
+	if p == "" {
+		return "", false
+	}
+	return p, true
+}
+
+// retargetPattern replaces the anchor at the start of pattern, keeping a
+// leading slash and everything after the anchor
+func retargetPattern(pattern, oldAnchor, newAnchor string) string {
+	lead := ""
+	p := pattern
+	if strings.HasPrefix(p, "/") {
+		lead = "/"
+		p = p[1:]
+	}
+	rest := strings.TrimPrefix(p, oldAnchor)
+	if newAnchor == "" {
+		rest = strings.TrimPrefix(rest, "/")
+	}
+	return lead + newAnchor + rest
+}
+
+// findStaleRules returns the active rules whose anchor is missing from the
+// scanned tree, skipping rules the user chose to keep
+func (m *Model) findStaleRules() []staleRule {
+	var stale []staleRule
+	for _, top := range m.topLevelNodes() {
+		m.activateRootFor(top)
+		paths := treePaths(top)
+
+		for _, rule := range m.filters.rules {
+			if !rule.isActive() || m.keptRules[rule.Pattern] {
+				continue
+			}
+			anchor, ok := ruleAnchor(rule.Pattern)
+			if !ok || paths[anchor] {
+				continue
+			}
+			stale = append(stale, staleRule{top: top, pattern: rule.Pattern, anchor: anchor})
+		}
+	}
+	return stale
+}
+
+// treePaths returns the paths of the nodes below top, relative to the root
+func treePaths(top *FileNode) map[string]bool {
+	paths := make(map[string]bool)
+	for _, node := range collectNodes(top, nil) {
+		paths[strings.TrimPrefix(getFilterPath(node.Path), "/")] = true
+	}
+	return paths
+}
+
+// renamedFolder returns the outermost directory of the rule's anchor that is
+// missing from the tree, which is the folder that was renamed or moved
+func renamedFolder(rule staleRule) string {
+	paths := treePaths(rule.top)
+	parts := strings.Split(rule.anchor, "/")
+	for i := 1; i < len(parts); i++ {
+		if prefix := strings.Join(parts[:i], "/"); !paths[prefix] {
+			return prefix
+		}
+	}
+	return rule.anchor
+}
+
+// underPrefix reports whether anchor is prefix or a path below it
+func underPrefix(anchor, prefix string) bool {
+	return anchor == prefix || strings.HasPrefix(anchor, prefix+"/")
+}
+
+// renameInRules points every active rule under prefix at newPrefix, keeping
+// the order and type of the rules, and returns the number of rules rewritten
+func (m *Model) renameInRules(prefix, newPrefix string) int {
+	var patterns []string
+	for _, rule := range m.filters.rules {
+		if !rule.isActive() {
+			continue
+		}
+		if anchor, ok := ruleAnchor(rule.Pattern); ok && underPrefix(anchor, prefix) {
+			patterns = append(patterns, rule.Pattern)
+		}
+	}
+
+	for _, pattern := range patterns {
+		m.replaceRule(pattern, retargetPattern(pattern, prefix, newPrefix))
+	}
+	return len(patterns)
+}
+
+// deleteRule removes a rule from the active filter set
+func (m *Model) deleteRule(pattern string) {
+	m.filterGen++
+	m.filters.rules = slices.DeleteFunc(m.filters.rules, func(rule FilterRule) bool {
+		return rule.Pattern == pattern && rule.isOwnRule()
+	})
+	delete(m.filters.added, pattern)
+	m.filterCache.clear()
+}
+
+// replaceRule changes the pattern of a rule in the active filter set, keeping
+// its position and type. A rule that already exists with the new pattern
+// wins over the replaced one.
+func (m *Model) replaceRule(pattern, newPattern string) {
+	if _, ok := m.filters.lookup(pattern); !ok || pattern == newPattern {
+		return
+	}
+	if _, exists := m.filters.lookup(newPattern); exists {
+		m.deleteRule(pattern)
+		return
+	}
+
+	m.filterGen++
+	for i, rule := range m.filters.rules {
+		if rule.Pattern == pattern && rule.isOwnRule() {
+			m.filters.rules[i].Pattern = newPattern
+		}
+	}
+	// The rule keeps its place under the new pattern
+	delete(m.filters.added, pattern)
+	m.filterCache.clear()
+}
+
+// resolveStaleRule applies a triage decision, re-evaluates the filters of the
+// rule's root and refreshes the list of stale rules
+func (m *Model) resolveStaleRule(rule staleRule, resolve func()) {
+	m.activateRootFor(rule.top)
+	resolve()
+	m.reapplyFiltersToTree(rule.top)
+	m.updateVisibleNodes()
+	m.adjustScroll()
+
+	m.staleRules = m.findStaleRules()
+	if m.triageCursor >= len(m.staleRules) {
+		m.triageCursor = len(m.staleRules) - 1
+	}
+	if m.triageCursor < 0 {
+		m.triageCursor = 0
+	}
+}
+
+// finishRemap points the rule being remapped at the node chosen in the tree,
+// or every rule under the renamed folder when remapping them all
+func (m *Model) finishRemap(target *FileNode) {
+	rule := *m.remapping
+	m.remapping = nil
+	remapAll := m.remapAll
+	m.remapAll = false
+
+	top := target
+	for top.Parent != nil {
+		top = top.Parent
+	}
+	if top != rule.top {
+		m.statusMsg = "The new location must be in the same root as the rule"
+		m.openModal(modalTriage)
+		return
+	}
+
+	newAnchor := strings.TrimPrefix(getFilterPath(target.Path), "/")
+	if remapAll {
+		var count int
+		m.resolveStaleRule(rule, func() {
+			count = m.renameInRules(rule.anchor, newAnchor)
+		})
+		m.statusMsg = fmt.Sprintf("Renamed %s to %s in %s rules", rule.anchor, newAnchor, formatCount(count))
+		m.setModal(modalTriage, len(m.staleRules) > 0)
+		return
+	}
+
+	newPattern := retargetPattern(rule.pattern, rule.anchor, newAnchor)
+	m.resolveStaleRule(rule, func() {
+		m.replaceRule(rule.pattern, newPattern)
+	})
+	m.statusMsg = fmt.Sprintf("Remapped %s to %s", rule.pattern, newPattern)
+	m.setModal(modalTriage, len(m.staleRules) > 0)
+}
+
+// checkRulesAfterScan opens the triage pane when rules refer to paths that a
+// complete scan didn't find
+func (m *Model) checkRulesAfterScan() {
+	if m.filesFrom != nil {
+		return
+	}
+	m.staleRules = m.findStaleRules()
+	m.triageCursor = 0
+	m.setModal(modalTriage, len(m.staleRules) > 0)
+}
+
+// openTriage shows the rules that refer to missing paths, if there are any
+func (m *Model) openTriage() {
+	m.staleRules = m.findStaleRules()
+	m.triageCursor = 0
+	if len(m.staleRules) == 0 {
+		m.statusMsg = "All rules refer to paths in the tree"
+		return
+	}
+	m.openModal(modalTriage)
+}
+
+// updateTriagePane handles keys while the stale rule triage pane is open
+func (m Model) updateTriagePane(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var rule *staleRule
+	if m.triageCursor >= 0 && m.triageCursor < len(m.staleRules) {
+		rule = &m.staleRules[m.triageCursor]
+	}
+
+	switch msg.String() {
+	case "up", "k":
+		if m.triageCursor > 0 {
+			m.triageCursor--
+		}
+	case "down", "j":
+		if m.triageCursor < len(m.staleRules)-1 {
+			m.triageCursor++
+		}
+	case " ":
+		if rule != nil {
+			if m.keptRules == nil {
+				m.keptRules = make(map[string]bool)
+			}
+			m.keptRules[rule.pattern] = true
+			m.resolveStaleRule(*rule, func() {})
+		}
+	case "d":
+		if rule != nil {
+			m.resolveStaleRule(*rule, func() {
+				m.deleteRule(rule.pattern)
+			})
+		}
+	case "r":
+		if rule != nil {
+			m.remapping = rule
+			m.closeModal(modalTriage)
+			return m, nil
+		}
+	case "R":
+		if rule != nil {
+			m.remapping = &staleRule{top: rule.top, pattern: rule.pattern, anchor: renamedFolder(*rule)}
+			m.remapAll = true
+			m.closeModal(modalTriage)
+			return m, nil
+		}
+	case "esc", "q", "T":
+		m.closeModal(modalTriage)
+	case "ctrl+c":
+		m.cancel()
+		return m, tea.Quit
+	}
+
+	if len(m.staleRules) == 0 {
+		m.closeModal(modalTriage)
+	}
+	return m, nil
+}
+
+func (m Model) renderTriage() string {
+	paneStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(currentTheme.Warning).
+		Padding(1, 2)
+
+	var b strings.Builder
+	b.WriteString("These rules refer to paths that are not in the tree:\n\n")
+	for i, rule := range m.staleRules {
+		m.activateRootFor(rule.top)
+		sign := "+"
+		if m.filters.state(rule.pattern) == FilterExclude {
+			sign = "-"
+		}
+		line := fmt.Sprintf("%s %s  (missing: %s)", sign, rule.pattern, rule.anchor)
+		if m.multiRoot() {
+			line += "  in " + rule.top.Name
+		}
+		if i == m.triageCursor {
+			line = lipgloss.NewStyle().Background(currentTheme.CursorBg).Foreground(currentTheme.CursorFg).Render(line)
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	b.WriteString("\n↑/↓ select, Space keep, d delete, r remap to a path in the tree,\nR remap every rule under the renamed folder, Esc close")
+
+	return m.placePane(paneStyle, b.String())
+}
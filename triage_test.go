@@ -0,0 +1,168 @@
+package main
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestRuleAnchor(t *testing.T) {
+	tests := []struct {
+		pattern string
+		anchor  string
+		ok      bool
+	}{
+		{"photos/2020/**", "photos/2020", true},
+		{"/docs/report.pdf", "docs/report.pdf", true},
+		{"cache/", "cache", true},
+		{"build/*.o", "build", true},
+		{"*.tmp", "", false},
+		{"**", "", false},
+	}
+
+	for _, tt := range tests {
+		anchor, ok := ruleAnchor(tt.pattern)
+		if anchor != tt.anchor || ok != tt.ok {
+			t.Errorf("ruleAnchor(%q) = %q, %v; want %q, %v", tt.pattern, anchor, ok, tt.anchor, tt.ok)
+		}
+	}
+
+	if got := retargetPattern("/old/a/**", "old/a", "new"); got != "/new/**" {
+		t.Errorf("retargetPattern kept %q", got)
+	}
+	if got := retargetPattern("old/*.txt", "old", ""); got != "*.txt" {
+		t.Errorf("Retargeting to the root should drop the separator, got %q", got)
+	}
+}
+
+// newTriageTestModel returns a model over /root with directories "photos",
+// "photos/raw" and "docs", and rules of which two refer to a missing "pictures" directory
+func newTriageTestModel() (*Model, *FileNode) {
+	root := &FileNode{Name: "root", Path: "/root", IsDir: true, Expanded: true}
+	photos := &FileNode{Name: "photos", Path: "/root/photos", IsDir: true, Parent: root}
+	docs := &FileNode{Name: "docs", Path: "/root/docs", IsDir: true, Parent: root}
+	raw := &FileNode{Name: "raw", Path: "/root/photos/raw", IsDir: true, Parent: photos}
+	photos.Children = []*FileNode{{Name: "a.jpg", Path: "/root/photos/a.jpg", Parent: photos}, raw}
+	root.Children = []*FileNode{docs, photos}
+
+	model := newTestModel()
+	model.root = root
+	model.filters = newFilterDocument([]FilterRule{
+		{Pattern: "pictures/raw/**", State: FilterExclude},
+		{Pattern: "docs/**", State: FilterInclude},
+		{Pattern: "*.tmp", State: FilterExclude},
+		{Pattern: "pictures/**", State: FilterInclude},
+	})
+	model.updateVisibleNodes()
+	return model, photos
+}
+
+func TestFindStaleRulesAndDelete(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/root"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, _ := newTriageTestModel()
+	model.openTriage()
+	if !model.modalOpen(modalTriage) || len(model.staleRules) != 2 {
+		t.Fatalf("Expected 2 stale rules, got %d", len(model.staleRules))
+	}
+	if model.staleRules[0].pattern != "pictures/raw/**" || model.staleRules[1].anchor != "pictures" {
+		t.Errorf("Unexpected stale rules %+v", model.staleRules)
+	}
+
+	// Keep the first, delete the second
+	updated, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("j")})
+	updated, _ = updated.(Model).Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("k")})
+	if updated.(Model).triageCursor != 0 || len(updated.(Model).staleRules) != 2 {
+		t.Fatalf("j and k should only move the cursor")
+	}
+	updated, _ = updated.(Model).Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")})
+	updated, _ = updated.(Model).Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	result := updated.(Model)
+
+	if result.modalOpen(modalTriage) || len(result.staleRules) != 0 {
+		t.Errorf("Triage should close once every rule is resolved")
+	}
+	if _, exists := result.filters.lookup("pictures/**"); exists {
+		t.Errorf("Deleted rule should be gone from the rules")
+	}
+	if rules := result.filters.rules; len(rules) != 3 || rules[0].Pattern != "pictures/raw/**" {
+		t.Errorf("Kept rule should stay in place, got %+v", rules)
+	}
+	if stale := result.findStaleRules(); len(stale) != 0 {
+		t.Errorf("Kept rules should not be reported again, got %+v", stale)
+	}
+}
+
+func TestRemapStaleRule(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/root"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, photos := newTriageTestModel()
+	model.openTriage()
+
+	// Remap "pictures/raw/**" to the photos directory chosen in the tree
+	updated, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	result := updated.(Model)
+	if result.modalOpen(modalTriage) || result.remapping == nil {
+		t.Fatalf("Remapping should return to the tree to choose a location")
+	}
+	result.cursor = result.indexOfVisible(photos)
+	updated, _ = result.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	result = updated.(Model)
+
+	if result.remapping != nil {
+		t.Errorf("Choosing a location should finish the remap")
+	}
+	if rule := result.filters.rules[0]; rule.Pattern != "photos/**" || rule.State != FilterExclude {
+		t.Errorf("Remapped rule should keep its position and type, got %+v", rule)
+	}
+	if result.filters.state("photos/**") != FilterExclude || photos.Children[0].Filter != FilterExclude {
+		t.Errorf("Remapped rule should take effect in the tree")
+	}
+	if !result.modalOpen(modalTriage) || len(result.staleRules) != 1 {
+		t.Errorf("Remaining stale rules should be shown again, got %d", len(result.staleRules))
+	}
+}
+
+func TestRenameInRules(t *testing.T) {
+	originalGlobalRootPath := globalRootPath
+	globalRootPath = "/root"
+	defer func() { globalRootPath = originalGlobalRootPath }()
+
+	model, photos := newTriageTestModel()
+	model.filters.insertAt(len(model.filters.rules), FilterRule{Pattern: "/picturesque/*.png", State: FilterExclude})
+	model.openTriage()
+
+	// "pictures/raw" is missing because "pictures" itself was renamed
+	updated, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("R")})
+	result := updated.(Model)
+	if result.remapping == nil || result.remapping.anchor != "pictures" || !result.remapAll {
+		t.Fatalf("Expected every rule under pictures to be remapped, got %+v", result.remapping)
+	}
+	result.cursor = result.indexOfVisible(photos)
+	updated, _ = result.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	result = updated.(Model)
+
+	want := []FilterRule{
+		{Pattern: "photos/raw/**", State: FilterExclude},
+		{Pattern: "docs/**", State: FilterInclude},
+		{Pattern: "*.tmp", State: FilterExclude},
+		{Pattern: "photos/**", State: FilterInclude},
+		{Pattern: "/picturesque/*.png", State: FilterExclude},
+	}
+	rules := result.filters.rules
+	if len(rules) != len(want) {
+		t.Fatalf("Expected %d rules, got %+v", len(want), rules)
+	}
+	for i, rule := range want {
+		if rules[i] != rule {
+			t.Errorf("Rule %d = %+v, want %+v", i, rules[i], rule)
+		}
+	}
+	if result.remapAll || len(result.staleRules) != 1 || result.staleRules[0].anchor != "picturesque" {
+		t.Errorf("Only the rule for the unrelated folder should be left, got %+v", result.staleRules)
+	}
+}
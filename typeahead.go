@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// typeAheadTimeout is how long the find prompt waits for the next character
+// before it closes
+const typeAheadTimeout = time.Second
+
+// typeAheadTimeoutMsg closes the find prompt, unless more characters were
+// typed since
+type typeAheadTimeoutMsg struct {
+	gen int
+}
+
+// typeAhead is the state of the find prompt opened with f
+type typeAhead struct {
+	prefix string
+	miss   bool // No visible name starts with prefix
+	gen    int
+}
+
+// startTypeAhead opens the find prompt
+func (m *Model) startTypeAhead() tea.Cmd {
+	m.typeAhead = &typeAhead{}
+	m.openModal(modalFindPrompt)
+	return m.typeAheadTick()
+}
+
+// typeAheadTick restarts the timeout of the find prompt
+func (m *Model) typeAheadTick() tea.Cmd {
+	m.typeAhead.gen++
+	gen := m.typeAhead.gen
+	return tea.Tick(typeAheadTimeout, func(time.Time) tea.Msg {
+		return typeAheadTimeoutMsg{gen: gen}
+	})
+}
+
+// applyTypeAheadTimeout closes the find prompt once nothing was typed for a
+// while
+func (m *Model) applyTypeAheadTimeout(msg typeAheadTimeoutMsg) {
+	if m.typeAhead != nil && msg.gen == m.typeAhead.gen {
+		m.endTypeAhead()
+	}
+}
+
+func (m *Model) endTypeAhead() {
+	m.typeAhead = nil
+	m.closeModal(modalFindPrompt)
+}
+
+// jumpToPrefix selects the first visible node from row from on, wrapping
+// around, whose name starts with prefix regardless of case, and reports
+// whether there is one
+func (m *Model) jumpToPrefix(prefix string, from int) bool {
+	n := len(m.visibleNodes)
+	for i := range n {
+		row := (from + i) % n
+		if node := m.visibleNodes[row]; len(node.Name) >= len(prefix) && strings.EqualFold(node.Name[:len(prefix)], prefix) {
+			m.cursor = row
+			m.adjustScroll()
+			return true
+		}
+	}
+	return false
+}
+
+// findPrefix moves to the node matching the typed prefix. The selected node
+// stays while it still matches; typing the same letter again moves on to the
+// next name starting with it, as in file managers.
+func (m *Model) findPrefix() {
+	t := m.typeAhead
+	t.miss = false
+	if t.prefix == "" || m.jumpToPrefix(t.prefix, m.cursor) {
+		return
+	}
+	first, size := utf8.DecodeRuneInString(t.prefix)
+	if strings.Count(t.prefix, string(first))*size == len(t.prefix) && m.jumpToPrefix(string(first), m.cursor+1) {
+		t.prefix = string(first)
+		return
+	}
+	t.miss = true
+}
+
+// updateFindPrompt handles keys while the find prompt is open. Keys that
+// aren't typed into it close it and are used as usual.
+func (m Model) updateFindPrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	t := m.typeAhead
+	if used, cmd := m.editPrompt(&t.prefix, msg); used {
+		if cmd != nil {
+			return m, cmd
+		}
+		m.findPrefix()
+		return m, m.typeAheadTick()
+	}
+	switch msg.Type {
+	case tea.KeyEnter, tea.KeyEsc:
+		m.endTypeAhead()
+		return m, nil
+	}
+	m.endTypeAhead()
+	return m.Update(msg)
+}
+
+// promptText is the status line while the find prompt is open
+func (t *typeAhead) promptText() string {
+	text := fmt.Sprintf("Find: %s█", t.prefix)
+	if t.miss {
+		text += " (no match)"
+	}
+	return text
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func newTypeAheadTestModel(names ...string) *Model {
+	root := &FileNode{Name: "test", Path: "/test", IsDir: true, Expanded: true}
+	for _, name := range names {
+		root.Children = append(root.Children, &FileNode{Name: name, Path: "/test/" + name, Parent: root})
+	}
+	model := newTestModel()
+	model.root = root
+	model.width, model.height = 80, 20
+	model.updateVisibleNodes()
+	return model
+}
+
+func TestTypeAheadFind(t *testing.T) {
+	model := newTypeAheadTestModel("Music", "Photos", "photos-old", "Pictures", "Videos")
+	name := func(m Model) string { return m.visibleNodes[m.cursor].Name }
+
+	m := pressKeys(*model, runeKey("f"), runeKey("p"))
+	if name(m) != "Photos" || m.topModal() != modalFindPrompt {
+		t.Fatalf("Expected f p to select Photos, got %s", name(m))
+	}
+	// The selection stays while it matches
+	m = pressKeys(m, runeKey("h"), runeKey("o"), runeKey("t"), runeKey("o"), runeKey("s"))
+	if name(m) != "Photos" {
+		t.Errorf("Expected Photos to stay selected, got %s", name(m))
+	}
+	m = pressKeys(m, runeKey("-"))
+	if name(m) != "photos-old" {
+		t.Errorf("Expected photos- to select photos-old, got %s", name(m))
+	}
+	if got := m.promptText(); got != "Find: photos-█" {
+		t.Errorf("Prompt = %q", got)
+	}
+
+	// An unknown prefix keeps the selection
+	m = pressKeys(m, runeKey("x"))
+	if name(m) != "photos-old" || m.promptText() != "Find: photos-x█ (no match)" {
+		t.Errorf("Expected no move on a miss, got %s, %q", name(m), m.promptText())
+	}
+
+	// Enter closes the prompt, other keys close it and work as usual
+	m = pressKeys(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.topModal() != modalNone {
+		t.Errorf("Expected Enter to close the prompt")
+	}
+	m = pressKeys(m, runeKey("f"), runeKey("v"), tea.KeyMsg{Type: tea.KeyUp})
+	if name(m) != "Pictures" || m.topModal() != modalNone {
+		t.Errorf("Expected ↑ to close the prompt and move up from Videos, got %s", name(m))
+	}
+}
+
+func TestTypeAheadRepeatedLetter(t *testing.T) {
+	model := newTypeAheadTestModel("Music", "Photos", "photos-old", "Pictures", "Videos")
+
+	m := pressKeys(*model, runeKey("f"), runeKey("p"), runeKey("p"), runeKey("p"))
+	if got := m.visibleNodes[m.cursor].Name; got != "Pictures" {
+		t.Errorf("Expected p p p to cycle to Pictures, got %s", got)
+	}
+	m = pressKeys(m, runeKey("p"))
+	if got := m.visibleNodes[m.cursor].Name; got != "Photos" {
+		t.Errorf("Expected the cycle to wrap around to Photos, got %s", got)
+	}
+}
+
+func TestTypeAheadTimeout(t *testing.T) {
+	model := newTypeAheadTestModel("Music", "Photos")
+	m := pressKeys(*model, runeKey("f"), runeKey("p"))
+
+	stale, _ := m.Update(typeAheadTimeoutMsg{gen: 1})
+	if stale.(Model).topModal() != modalFindPrompt {
+		t.Errorf("A timeout from before the last key should not close the prompt")
+	}
+	updated, _ := m.Update(typeAheadTimeoutMsg{gen: m.typeAhead.gen})
+	if updated.(Model).topModal() != modalNone {
+		t.Errorf("Expected the prompt to close after the timeout")
+	}
+}
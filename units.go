@@ -0,0 +1,40 @@
+package main
+
+import "fmt"
+
+// Units sizes are shown in
+const (
+	unitsBinary  = "binary"  // 1024 based, as KiB, MiB, GiB...
+	unitsDecimal = "decimal" // 1000 based, as kB, MB, GB...
+)
+
+// numberFormat is how sizes and counts are written throughout the UI
+type numberFormat struct {
+	Units      string
+	Separator  string // Between groups of three digits of counts, "" for none
+	FixedWidth bool   // Pad sizes to one width, so that they line up in columns
+}
+
+var defaultNumberFormat = numberFormat{Units: unitsBinary, Separator: ","}
+
+// currentNumbers is used by formatSize and formatCount
+var currentNumbers = defaultNumberFormat
+
+// parseUnits checks the value of size-units
+func parseUnits(v string) (string, error) {
+	if v != unitsBinary && v != unitsDecimal {
+		return "", fmt.Errorf("%q (use binary or decimal)", v)
+	}
+	return v, nil
+}
+
+// toggleUnits switches sizes between binary and decimal units
+func (m *Model) toggleUnits() {
+	if currentNumbers.Units == unitsBinary {
+		currentNumbers.Units = unitsDecimal
+		m.statusMsg = "Sizes in decimal units (1 kB = 1000 bytes), U switches back"
+	} else {
+		currentNumbers.Units = unitsBinary
+		m.statusMsg = "Sizes in binary units (1 KiB = 1024 bytes)"
+	}
+}
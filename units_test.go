@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFormatSizeUnits(t *testing.T) {
+	original := currentNumbers
+	defer func() { currentNumbers = original }()
+
+	tests := []struct {
+		format   numberFormat
+		size     int64
+		expected string
+	}{
+		{numberFormat{Units: unitsDecimal}, 999, "999 B"},
+		{numberFormat{Units: unitsDecimal}, 1000, "1.0 kB"},
+		{numberFormat{Units: unitsDecimal}, 1536, "1.5 kB"},
+		{numberFormat{Units: unitsDecimal}, 5_000_000_000, "5.0 GB"},
+		{numberFormat{Units: unitsBinary, FixedWidth: true}, 512, "   512 B  "},
+		{numberFormat{Units: unitsBinary, FixedWidth: true}, 1536, "   1.5 KiB"},
+		{numberFormat{Units: unitsBinary, FixedWidth: true}, 1023 << 20, "1023.0 MiB"},
+		{numberFormat{Units: unitsDecimal, FixedWidth: true}, 12, "    12 B "},
+		{numberFormat{Units: unitsDecimal, FixedWidth: true}, 42_100_000, "  42.1 MB"},
+	}
+	for _, tt := range tests {
+		currentNumbers = tt.format
+		if got := formatSize(tt.size); got != tt.expected {
+			t.Errorf("formatSize(%d) with %+v = %q; want %q", tt.size, tt.format, got, tt.expected)
+		}
+	}
+}
+
+func TestFormatCountSeparator(t *testing.T) {
+	original := currentNumbers
+	defer func() { currentNumbers = original }()
+
+	currentNumbers.Separator = "."
+	if got := formatCount(-1234567); got != "-1.234.567" {
+		t.Errorf("formatCount = %q; want -1.234.567", got)
+	}
+	currentNumbers.Separator = ""
+	if got := formatCount(1234567); got != "1234567" {
+		t.Errorf("formatCount = %q; want 1234567", got)
+	}
+}
+
+func TestLoadConfigNumbers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	os.WriteFile(path, []byte("size-units = decimal\nthousands-separator = \" \"\nfixed-width-sizes = true\n"), 0644)
+
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	expected := numberFormat{Units: unitsDecimal, Separator: " ", FixedWidth: true}
+	if cfg.Numbers != expected {
+		t.Errorf("Numbers = %+v; want %+v", cfg.Numbers, expected)
+	}
+
+	original := currentNumbers
+	defer func() { currentNumbers = original }()
+	cfg.activate()
+	if got := formatCount(1024); got != "1 024" {
+		t.Errorf("activate should switch the number format, got %q", got)
+	}
+	if defaultConfig().Numbers != defaultNumberFormat {
+		t.Errorf("Expected binary units with a comma by default")
+	}
+}
+
+func TestToggleUnits(t *testing.T) {
+	original := currentNumbers
+	defer func() { currentNumbers = original }()
+	currentNumbers = defaultNumberFormat
+
+	m := newTestModel()
+	result := pressKeys(*m, runeKey("U"))
+	if formatSize(2000) != "2.0 kB" || result.statusMsg == "" {
+		t.Errorf("U should switch to decimal units, got %q", formatSize(2000))
+	}
+	pressKeys(result, runeKey("U"))
+	if formatSize(2048) != "2.0 KiB" {
+		t.Errorf("U again should switch back to binary units, got %q", formatSize(2048))
+	}
+}
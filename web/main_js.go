@@ -0,0 +1,21 @@
+//go:build js && wasm
+
+package main
+
+import "syscall/js"
+
+// main makes renderFilterTree(listing, filters) available to the page, which
+// returns {html} or {error}, and keeps running for the page to call it
+func main() {
+	js.Global().Set("renderFilterTree", js.FuncOf(func(this js.Value, args []js.Value) any {
+		if len(args) != 2 {
+			return map[string]any{"error": "renderFilterTree takes the listing and the filter file"}
+		}
+		out, err := render(args[0].String(), args[1].String())
+		if err != nil {
+			return map[string]any{"error": err.Error()}
+		}
+		return map[string]any{"html": out}
+	}))
+	select {}
+}
@@ -0,0 +1,14 @@
+//go:build !(js && wasm)
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// main explains how to build the viewer, which only runs in a browser
+func main() {
+	fmt.Fprintln(os.Stderr, "The web viewer runs in a browser, build it with: GOOS=js GOARCH=wasm go build -o web/viewer.wasm ./web")
+	os.Exit(2)
+}
@@ -0,0 +1,189 @@
+// Command web is a read-only viewer of what a filter file does to a tree,
+// built for the browser with GOOS=js GOARCH=wasm. It shows the tree of an
+// "rclone lsjson -R" dump colored by the rules of a filter file, for sharing
+// the effect of the filters with people who won't install the editor.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"rclone-filter-editor/rclonefilter"
+)
+
+// viewNode is a file or directory of the tree as rclone would filter it
+type viewNode struct {
+	name     string
+	path     string // Relative to the root, with a leading slash
+	isDir    bool
+	size     int64
+	children []*viewNode
+
+	rule     int // Position of the rule deciding it, -1 for none
+	excluded bool
+
+	files, includedFiles int   // Below a directory
+	includedSize         int64 // Below a directory
+}
+
+// buildTree arranges the entries of a listing as a tree. Directories that
+// only show up in the paths of their contents are added too.
+func buildTree(items []rclonefilter.ListingItem) *viewNode {
+	root := &viewNode{path: "/", isDir: true}
+	dirs := map[string]*viewNode{"": root}
+	var dirFor func(p string) *viewNode
+	dirFor = func(p string) *viewNode {
+		if dir, ok := dirs[p]; ok {
+			return dir
+		}
+		parent := dirFor(parentPath(p))
+		dir := &viewNode{name: path.Base(p), path: "/" + p, isDir: true}
+		parent.children = append(parent.children, dir)
+		dirs[p] = dir
+		return dir
+	}
+	for _, item := range items {
+		p := strings.Trim(item.Path, "/")
+		if item.IsDir {
+			dirFor(p)
+			continue
+		}
+		parent := dirFor(parentPath(p))
+		parent.children = append(parent.children, &viewNode{name: path.Base(p), path: "/" + p, size: item.Size})
+	}
+	sortTree(root)
+	return root
+}
+
+// parentPath returns the directory holding p, "" for the root
+func parentPath(p string) string {
+	if dir := path.Dir(p); dir != "." {
+		return dir
+	}
+	return ""
+}
+
+// sortTree lists directories first, each group by name
+func sortTree(node *viewNode) {
+	slices.SortFunc(node.children, func(a, b *viewNode) int {
+		if a.isDir != b.isDir {
+			if a.isDir {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.name, b.name)
+	})
+	for _, child := range node.children {
+		sortTree(child)
+	}
+}
+
+// evaluate applies rules to node and everything below it as rclone does,
+// and counts the included files of each directory. The first rule matching
+// decides, with patterns not starting with "/" matched against the end of
+// the path. What no rule matches below an excluded directory is left out
+// with it.
+func evaluate(node *viewNode, rules []rclonefilter.Rule, blocked bool) {
+	node.rule = -1
+	if node.path != "/" {
+		node.rule = slices.IndexFunc(rules, func(r rclonefilter.Rule) bool {
+			return rclonefilter.MatchEnd(r.Pattern, node.path)
+		})
+	}
+	if node.rule >= 0 {
+		node.excluded = !rules[node.rule].Include
+	} else {
+		node.excluded = blocked
+	}
+	if !node.isDir {
+		return
+	}
+	node.files, node.includedFiles, node.includedSize = 0, 0, 0
+	for _, child := range node.children {
+		evaluate(child, rules, node.excluded)
+		if child.isDir {
+			node.files += child.files
+			node.includedFiles += child.includedFiles
+			node.includedSize += child.includedSize
+			continue
+		}
+		node.files++
+		if !child.excluded {
+			node.includedFiles++
+			node.includedSize += child.size
+		}
+	}
+}
+
+// render builds the tree of a listing, applies the rules of a filter file
+// and returns it as HTML
+func render(listingJSON, filterText string) (string, error) {
+	var items []rclonefilter.ListingItem
+	if err := json.Unmarshal([]byte(listingJSON), &items); err != nil {
+		return "", fmt.Errorf("not an rclone lsjson listing: %w", err)
+	}
+	rules, skipped, err := rclonefilter.Parse(strings.NewReader(filterText))
+	if err != nil {
+		return "", err
+	}
+	root := buildTree(items)
+	evaluate(root, rules, false)
+	return renderHTML(root, rules, skipped), nil
+}
+
+// renderHTML writes the tree as nested lists, each directory a <details>
+// element that opens like a directory of the editor, below the lines of the
+// filter file that were left out
+func renderHTML(root *viewNode, rules []rclonefilter.Rule, skipped []int) string {
+	var b strings.Builder
+	if len(skipped) > 0 {
+		lines := make([]string, len(skipped))
+		for i, n := range skipped {
+			lines[i] = strconv.Itoa(n)
+		}
+		fmt.Fprintf(&b, "<p class=\"error\">Left out lines %s of the filter file, which are not rules</p>\n", strings.Join(lines, ", "))
+	}
+	fmt.Fprintf(&b, "<p class=\"summary\">%d of %d files included, %s</p>\n", root.includedFiles, root.files, formatSize(root.includedSize))
+	b.WriteString("<ul class=\"tree\">\n")
+	for _, child := range root.children {
+		writeNode(&b, child, rules)
+	}
+	b.WriteString("</ul>\n")
+	return b.String()
+}
+
+func writeNode(b *strings.Builder, node *viewNode, rules []rclonefilter.Rule) {
+	state, title := "none", "no rule matches, rclone copies it"
+	switch {
+	case node.excluded && node.rule < 0:
+		state, title = "exclude", "left out with the directory above it"
+	case node.rule >= 0:
+		state, title = "include", "included by "+rules[node.rule].String()
+		if node.excluded {
+			state, title = "exclude", "excluded by "+rules[node.rule].String()
+		}
+	}
+	label := fmt.Sprintf("<span class=\"%s\" title=\"%s\">%s</span>", state, html.EscapeString(title), html.EscapeString(node.name))
+
+	if !node.isDir {
+		fmt.Fprintf(b, "<li>%s <span class=\"stats\">(%s)</span></li>\n", label, formatSize(node.size))
+		return
+	}
+	fmt.Fprintf(b, "<li><details><summary>%s/ <span class=\"stats\">(%d of %d files, %s)</span></summary>\n<ul>\n",
+		label, node.includedFiles, node.files, formatSize(node.includedSize))
+	for _, child := range node.children {
+		writeNode(b, child, rules)
+	}
+	b.WriteString("</ul></details></li>\n")
+}
+
+// formatSize formats a size with binary units, as the editor does by default
+func formatSize(size int64) string {
+	return rclonefilter.FormatSize(size, false, false)
+}
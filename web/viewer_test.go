@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const testListing = `[
+	{"Path": "Photos", "Name": "Photos", "Size": -1, "IsDir": true},
+	{"Path": "Photos/a.jpg", "Name": "a.jpg", "Size": 2048, "IsDir": false},
+	{"Path": "Photos/a.tmp", "Name": "a.tmp", "Size": 10, "IsDir": false},
+	{"Path": "cache/blob", "Name": "blob", "Size": 5, "IsDir": false},
+	{"Path": "notes.txt", "Name": "notes.txt", "Size": 1, "IsDir": false}
+]`
+
+func TestRender(t *testing.T) {
+	// The first rule matching wins, as in rclone and the editor
+	out, err := render(testListing, "+ Photos/*.jpg\n- Photos/**\n- cache\n*.bak\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`Left out lines 4 of the filter file, which are not rules`,
+		`2 of 4 files included, 2.0 KiB`,
+		`<span class="include" title="included by + Photos/*.jpg">a.jpg</span>`,
+		`<span class="exclude" title="excluded by - Photos/**">a.tmp</span>`,
+		`<span class="exclude" title="excluded by - cache">cache</span>`,
+		`<span class="exclude" title="left out with the directory above it">blob</span>`,
+		`<span class="none" title="no rule matches, rclone copies it">notes.txt</span>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("render() is missing %s in:\n%s", want, out)
+		}
+	}
+	// Directories come first, including "cache" only seen in the path of its file
+	if strings.Index(out, ">cache<") > strings.Index(out, ">notes.txt<") {
+		t.Errorf("render() doesn't list directories before files:\n%s", out)
+	}
+}
+
+func TestRenderMatchesLikeRclone(t *testing.T) {
+	// A pattern without a leading slash matches in every directory, and is
+	// read before the include below it
+	out, err := render(testListing, "- *.tmp\n+ Photos/**\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<span class="exclude" title="excluded by - *.tmp">a.tmp</span>`,
+		`<span class="include" title="included by + Photos/**">a.jpg</span>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("render() is missing %s in:\n%s", want, out)
+		}
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	if _, err := render("not json", ""); err == nil {
+		t.Error("render() of a broken listing succeeded")
+	}
+}